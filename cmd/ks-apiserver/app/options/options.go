@@ -23,11 +23,15 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/cluster"
 
 	"kubesphere.io/kubesphere/pkg/apiserver"
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing/store"
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication/identityprovider"
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/token"
 	"kubesphere.io/kubesphere/pkg/apiserver/options"
 	"kubesphere.io/kubesphere/pkg/config"
 	"kubesphere.io/kubesphere/pkg/models/auth"
 	"kubesphere.io/kubesphere/pkg/models/registries/imagesearch"
+	resourcev1alpha3 "kubesphere.io/kubesphere/pkg/models/resources/v1alpha3"
+	"kubesphere.io/kubesphere/pkg/models/resources/v1alpha3/resource"
 	resourcev1beta1 "kubesphere.io/kubesphere/pkg/models/resources/v1beta1"
 	"kubesphere.io/kubesphere/pkg/scheme"
 	genericoptions "kubesphere.io/kubesphere/pkg/server/options"
@@ -92,7 +96,7 @@ func (s *APIServerOptions) NewAPIServer(ctx context.Context) (*apiserver.APIServ
 	}); err != nil {
 		return nil, fmt.Errorf("unable to create controller runtime cluster: %v", err)
 	} else {
-		apiServer.RuntimeCache = c.GetCache()
+		apiServer.RuntimeCache = resourcev1alpha3.NewIndexedCache(c.GetCache())
 		key := "involvedObject.name"
 		indexerFunc := func(obj client.Object) []string {
 			e := obj.(*corev1.Event)
@@ -101,6 +105,9 @@ func (s *APIServerOptions) NewAPIServer(ctx context.Context) (*apiserver.APIServ
 		if err = apiServer.RuntimeCache.IndexField(ctx, &corev1.Event{}, key, indexerFunc); err != nil {
 			klog.Fatalf("unable to create index field: %v", err)
 		}
+		if err = resource.AddIndexes(ctx, apiServer.RuntimeCache); err != nil {
+			return nil, fmt.Errorf("unable to create resource indexes: %v", err)
+		}
 		apiServer.RuntimeClient = c.GetClient()
 	}
 
@@ -125,6 +132,17 @@ func (s *APIServerOptions) NewAPIServer(ctx context.Context) (*apiserver.APIServ
 		return nil, fmt.Errorf("unable to create issuer: %v", err)
 	}
 
+	keyringSource := token.NewKeyringSource(apiServer.TokenOperator.Keyring(), s.AuthenticationOptions.Issuer.Keyring.KeyGracePeriod())
+	if err := keyringSource.WatchSecrets(ctx, apiServer.RuntimeCache); err != nil {
+		return nil, fmt.Errorf("unable to watch signing keys: %v", err)
+	}
+
+	if s.AuditingOptions.Enable && s.AuditingOptions.StoreOptions.Path != "" {
+		if apiServer.AuditingStore, err = store.Open(&s.AuditingOptions.StoreOptions); err != nil {
+			return nil, fmt.Errorf("unable to open auditing store: %v", err)
+		}
+	}
+
 	k8sVersionInfo, err := apiServer.K8sClient.Discovery().ServerVersion()
 	if err != nil {
 		return nil, fmt.Errorf("unable to fetch k8s version info: %v", err)
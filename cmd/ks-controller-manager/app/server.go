@@ -21,6 +21,7 @@ import (
 	"kubesphere.io/kubesphere/cmd/ks-controller-manager/app/options"
 	"kubesphere.io/kubesphere/pkg/config"
 	"kubesphere.io/kubesphere/pkg/controller"
+	"kubesphere.io/kubesphere/pkg/controller/accessrequest"
 	"kubesphere.io/kubesphere/pkg/controller/application"
 	"kubesphere.io/kubesphere/pkg/controller/certificatesigningrequest"
 	"kubesphere.io/kubesphere/pkg/controller/cluster"
@@ -33,6 +34,8 @@ import (
 	"kubesphere.io/kubesphere/pkg/controller/extension"
 	"kubesphere.io/kubesphere/pkg/controller/globalrole"
 	"kubesphere.io/kubesphere/pkg/controller/globalrolebinding"
+	"kubesphere.io/kubesphere/pkg/controller/group"
+	"kubesphere.io/kubesphere/pkg/controller/groupbinding"
 	"kubesphere.io/kubesphere/pkg/controller/job"
 	"kubesphere.io/kubesphere/pkg/controller/k8sapplication"
 	"kubesphere.io/kubesphere/pkg/controller/ksserviceaccount"
@@ -82,11 +85,15 @@ func init() {
 	runtime.Must(controller.Register(&role.Reconciler{}))
 	runtime.Must(controller.Register(&rolebinding.Reconciler{}))
 	runtime.Must(controller.Register(&roletemplate.Reconciler{}))
+	runtime.Must(controller.Register(&accessrequest.Reconciler{}))
 	runtime.Must(controller.Register(&namespace.Reconciler{}))
 	// user management
 	runtime.Must(controller.Register(&user.Reconciler{}))
 	runtime.Must(controller.Register(&user.Webhook{}))
 	runtime.Must(controller.Register(&loginrecord.Reconciler{}))
+	runtime.Must(controller.Register(&group.Reconciler{}))
+	runtime.Must(controller.Register(&group.IdentityProviderGroupReconciler{}))
+	runtime.Must(controller.Register(&groupbinding.Reconciler{}))
 	// multi cluster
 	runtime.Must(controller.Register(&cluster.Reconciler{}))
 	runtime.Must(controller.Register(&cluster.Webhook{}))
@@ -98,6 +105,7 @@ func init() {
 	runtime.Must(controller.Register(&ksserviceaccount.Reconciler{}))
 	runtime.Must(controller.Register(&ksserviceaccount.Webhook{}))
 	runtime.Must(controller.Register(&secret.ServiceAccountSecretReconciler{}))
+	runtime.Must(controller.Register(&secret.SigningKeyReconciler{}))
 	// additional capabilities
 	runtime.Must(controller.Register(&serviceaccount.Reconciler{}))
 	runtime.Must(controller.Register(&job.Reconciler{}))
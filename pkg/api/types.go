@@ -14,6 +14,58 @@ import (
 type ListResult struct {
 	Items      []runtime.Object `json:"items"`
 	TotalItems int              `json:"totalItems"`
+	// Continue is the token to retrieve the next page, it's empty if it's the last page
+	Continue string `json:"continue,omitempty"`
+}
+
+// AggregationResult is the counts of the objects grouped by the values of a field or a label.
+type AggregationResult struct {
+	Buckets []AggregationBucket `json:"buckets"`
+	// TotalItems is the number of the objects aggregated, an object is counted in every bucket of its values
+	TotalItems int `json:"totalItems"`
+}
+
+type AggregationBucket struct {
+	// Value is the value of the field or label, the objects without the field or label are grouped into the empty value
+	Value string `json:"value"`
+	Count int    `json:"count"`
+	// Requests is the sum of the resource requests of the containers of the objects
+	Requests corev1.ResourceList `json:"requests,omitempty"`
+}
+
+// FederatedListResult is the result of listing the objects across the clusters, the items are sorted and paginated
+// globally and labeled with the clusters they belong to, the clusters failed are reported along with the others.
+type FederatedListResult struct {
+	Items      []runtime.Object `json:"items"`
+	TotalItems int              `json:"totalItems"`
+	Clusters   []ClusterResult  `json:"clusters"`
+}
+
+// ClusterResult is the result of listing the objects in a cluster.
+type ClusterResult struct {
+	Cluster    string `json:"cluster"`
+	TotalItems int    `json:"totalItems"`
+	// Error is the reason the objects of the cluster are not listed, the objects of the cluster are excluded
+	Error string `json:"error,omitempty"`
+}
+
+// SearchResult is the objects matching the search terms, ranked by the relevance.
+type SearchResult struct {
+	Items      []SearchHit `json:"items"`
+	TotalItems int         `json:"totalItems"`
+}
+
+type SearchHit struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Resource   string `json:"resource"`
+	Workspace  string `json:"workspace,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+	Alias      string `json:"alias,omitempty"`
+	Score      int    `json:"score"`
+	// Matches is the fields of the object matching the search terms, e.g. name, labels
+	Matches []string `json:"matches"`
 }
 
 type ResourceQuota struct {
@@ -76,4 +128,5 @@ const (
 	TagComponentStatus                = "Component Status"
 	TagUserRelatedResources           = "User Related Resources"
 	TagPlatformConfigurations         = "Platform Configurations"
+	TagAuditing                       = "Auditing"
 )
@@ -20,6 +20,7 @@ import (
 	urlruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/apimachinery/pkg/util/sets"
 	k8sversion "k8s.io/apimachinery/pkg/version"
+	"k8s.io/apiserver/pkg/authentication/authenticator"
 	unionauth "k8s.io/apiserver/pkg/authentication/request/union"
 	"k8s.io/klog/v2"
 	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
@@ -32,8 +33,10 @@ import (
 	openapiv2 "kubesphere.io/kubesphere/kube/pkg/openapi/v2"
 	openapiv3 "kubesphere.io/kubesphere/kube/pkg/openapi/v3"
 	"kubesphere.io/kubesphere/pkg/apiserver/auditing"
+	auditingstore "kubesphere.io/kubesphere/pkg/apiserver/auditing/store"
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication/authenticators/basic"
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication/authenticators/jwt"
+	scimauth "kubesphere.io/kubesphere/pkg/apiserver/authentication/authenticators/scim"
 	oauth2 "kubesphere.io/kubesphere/pkg/apiserver/authentication/oauth"
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication/request/anonymous"
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication/request/basictoken"
@@ -44,6 +47,7 @@ import (
 	"kubesphere.io/kubesphere/pkg/apiserver/authorization/path"
 	"kubesphere.io/kubesphere/pkg/apiserver/authorization/rbac"
 	unionauthorizer "kubesphere.io/kubesphere/pkg/apiserver/authorization/union"
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/workspacescope"
 	"kubesphere.io/kubesphere/pkg/apiserver/filters"
 	"kubesphere.io/kubesphere/pkg/apiserver/metrics"
 	"kubesphere.io/kubesphere/pkg/apiserver/options"
@@ -51,6 +55,7 @@ import (
 	"kubesphere.io/kubesphere/pkg/apiserver/rest"
 	openapicontroller "kubesphere.io/kubesphere/pkg/controller/openapi"
 	appv2 "kubesphere.io/kubesphere/pkg/kapis/application/v2"
+	auditingv1alpha1 "kubesphere.io/kubesphere/pkg/kapis/auditing/v1alpha1"
 	clusterkapisv1alpha1 "kubesphere.io/kubesphere/pkg/kapis/cluster/v1alpha1"
 	configv1alpha2 "kubesphere.io/kubesphere/pkg/kapis/config/v1alpha2"
 	gatewayv1alpha2 "kubesphere.io/kubesphere/pkg/kapis/gateway/v1alpha2"
@@ -60,6 +65,8 @@ import (
 	packagev1alpha1 "kubesphere.io/kubesphere/pkg/kapis/package/v1alpha1"
 	resourcesv1alpha2 "kubesphere.io/kubesphere/pkg/kapis/resources/v1alpha2"
 	resourcev1alpha3 "kubesphere.io/kubesphere/pkg/kapis/resources/v1alpha3"
+	scimv2 "kubesphere.io/kubesphere/pkg/kapis/scim/v2"
+	"kubesphere.io/kubesphere/pkg/kapis/search"
 	"kubesphere.io/kubesphere/pkg/kapis/static"
 	tenantapiv1alpha3 "kubesphere.io/kubesphere/pkg/kapis/tenant/v1alpha3"
 	tenantapiv1beta1 "kubesphere.io/kubesphere/pkg/kapis/tenant/v1beta1"
@@ -69,12 +76,14 @@ import (
 	"kubesphere.io/kubesphere/pkg/models/auth"
 	"kubesphere.io/kubesphere/pkg/models/iam/am"
 	"kubesphere.io/kubesphere/pkg/models/iam/im"
+	"kubesphere.io/kubesphere/pkg/models/iam/scim"
 	resourcev1beta1 "kubesphere.io/kubesphere/pkg/models/resources/v1beta1"
 	"kubesphere.io/kubesphere/pkg/server/healthz"
 	"kubesphere.io/kubesphere/pkg/simple/client/cache"
 	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
 	overviewclient "kubesphere.io/kubesphere/pkg/simple/client/overview"
 	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
+	"kubesphere.io/kubesphere/pkg/utils/iputil"
 )
 
 type APIServer struct {
@@ -101,6 +110,9 @@ type APIServer struct {
 
 	ClusterClient clusterclient.Interface
 
+	// AuditingStore keeps auditing events locally, nil if the store is disabled
+	AuditingStore auditingstore.Interface
+
 	ResourceManager resourcev1beta1.ResourceManager
 
 	K8sVersionInfo *k8sversion.Info
@@ -119,6 +131,7 @@ func (s *APIServer) PrepareRun(stopCh <-chan struct{}) error {
 	})
 	s.installDynamicResourceAPI()
 	s.installKubeSphereAPIs()
+	s.installAuditingAPI(stopCh)
 	s.installMetricsAPI()
 	s.installHealthz()
 	s.installLivez()
@@ -157,6 +170,14 @@ func (s *APIServer) installOpenAPI() error {
 	return openapicontroller.SharedOpenAPIController.WatchOpenAPIChanges(context.Background(), s.RuntimeCache, s.openAPIV2Service, s.openAPIV3Service)
 }
 
+func (s *APIServer) installAuditingAPI(stopCh <-chan struct{}) {
+	if s.AuditingStore == nil {
+		return
+	}
+	s.AuditingStore.Start(stopCh)
+	urlruntime.Must(auditingv1alpha1.NewHandler(s.AuditingStore).AddToContainer(s.container))
+}
+
 func (s *APIServer) installMetricsAPI() {
 	metrics.Install(s.container)
 }
@@ -168,6 +189,8 @@ func (s *APIServer) installKubeSphereAPIs() {
 	imOperator := im.NewOperator(s.RuntimeClient, s.ResourceManager, s.AuthenticationOptions)
 	amOperator := am.NewOperator(s.ResourceManager)
 	rbacAuthorizer := rbac.NewRBACAuthorizer(amOperator)
+	totpOperator := auth.NewTOTPOperator(s.RuntimeClient, s.CacheClient, s.AuthenticationOptions)
+	loginRecorder := auth.NewLoginRecorder(s.RuntimeClient)
 	counter := overviewclient.New(s.RuntimeClient)
 	counter.RegisterResource(overviewclient.NewDefaultRegisterOptions(s.K8sVersion)...)
 
@@ -180,17 +203,22 @@ func (s *APIServer) installKubeSphereAPIs() {
 		tenantapiv1beta1.NewHandler(s.RuntimeClient, s.K8sVersion, s.ClusterClient, amOperator, imOperator, rbacAuthorizer, counter),
 		terminalv1alpha2.NewHandler(s.K8sClient, rbacAuthorizer, s.K8sClient.Config(), s.TerminalOptions),
 		clusterkapisv1alpha1.NewHandler(s.RuntimeClient),
-		iamapiv1beta1.NewHandler(imOperator, amOperator),
+		iamapiv1beta1.NewHandler(imOperator, amOperator, totpOperator, s.TokenOperator, loginRecorder),
 		oauth.NewHandler(imOperator, s.TokenOperator, auth.NewPasswordAuthenticator(s.RuntimeClient, s.AuthenticationOptions),
-			auth.NewOAuthAuthenticator(s.RuntimeClient),
-			auth.NewLoginRecorder(s.RuntimeClient), s.AuthenticationOptions,
-			oauth2.NewOAuthClientGetter(s.RuntimeClient)),
+			auth.NewOAuthAuthenticator(s.RuntimeClient, s.CacheClient),
+			loginRecorder, s.AuthenticationOptions,
+			oauth2.NewOAuthClientGetter(s.RuntimeClient), totpOperator, auth.NewDeviceAuthorizationOperator(s.CacheClient)),
 		version.NewHandler(s.K8sVersionInfo),
 		packagev1alpha1.NewHandler(s.RuntimeCache),
 		gatewayv1alpha2.NewHandler(s.RuntimeCache),
 		appv2.NewHandler(s.RuntimeClient, s.ClusterClient, s.S3Options),
 		workloadtemplatev1alpha1.NewHandler(s.RuntimeClient, s.K8sVersion, rbacAuthorizer),
 		static.NewHandler(s.CacheClient),
+		search.NewHandler(s.RuntimeCache, rbacAuthorizer),
+	}
+
+	if s.AuthenticationOptions.SCIMOptions.Enable {
+		handlers = append(handlers, scimv2.NewHandler(scim.NewOperator(imOperator, s.RuntimeClient, s.AuthenticationOptions.SCIMOptions)))
 	}
 
 	for _, handler := range handlers {
@@ -248,6 +276,7 @@ func (s *APIServer) buildHandlerChain(handler http.Handler, stopCh <-chan struct
 			clusterv1alpha1.Resource(clusterv1alpha1.ResourcesPluralLabel),
 			resourcev1alpha3.Resource(clusterv1alpha1.ResourcesPluralCluster),
 			resourcev1alpha3.Resource(clusterv1alpha1.ResourcesPluralLabel),
+			auditingv1alpha1.Resource(auditingv1alpha1.ResourcesPluralEvent),
 		},
 	}
 
@@ -256,8 +285,10 @@ func (s *APIServer) buildHandlerChain(handler http.Handler, stopCh <-chan struct
 	handler = filters.WithReverseProxy(handler, s.RuntimeCache)
 	handler = filters.WithJSBundle(handler, s.RuntimeCache)
 
+	var auditor auditing.Auditing
 	if s.AuditingOptions.Enable {
-		handler = filters.WithAuditing(handler, auditing.NewAuditing(s.K8sClient, s.AuditingOptions, stopCh))
+		auditor = auditing.NewAuditing(s.K8sClient, s.AuditingOptions, s.AuditingStore, stopCh)
+		handler = filters.WithAuditing(handler, auditor)
 	}
 
 	var authorizers authorizer.Authorizer
@@ -269,23 +300,42 @@ func (s *APIServer) buildHandlerChain(handler http.Handler, stopCh <-chan struct
 	default:
 		fallthrough
 	case authorization.RBAC:
-		excludedPaths := []string{"/oauth/*", "/dist/*", "/.well-known/openid-configuration", "/version", "/metrics", "/livez", "/healthz", "/openapi/v2", "/openapi/v3"}
+		excludedPaths := []string{"/oauth/*", "/scim/*", "/dist/*", "/.well-known/openid-configuration", "/version", "/metrics", "/livez", "/healthz", "/openapi/v2", "/openapi/v3"}
 		pathAuthorizer, _ := path.NewAuthorizer(excludedPaths)
 		amOperator := am.NewReadOnlyOperator(s.ResourceManager)
-		authorizers = unionauthorizer.New(pathAuthorizer, rbac.NewRBACAuthorizer(amOperator))
+		rbacAuthorizer, err := rbac.NewIndexedRBACAuthorizer(context.Background(), amOperator, s.RuntimeCache, s.AuthorizationOptions.DecisionCacheTTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create rbac authorizer: %s", err)
+		}
+		authorizers = unionauthorizer.New(pathAuthorizer, workspacescope.NewAuthorizer(amOperator), rbacAuthorizer)
 	}
 
 	handler = filters.WithAuthorization(handler, authorizers)
 	handler = filters.WithMulticluster(handler, s.ClusterClient, s.MultiClusterOptions)
+	handler = filters.WithFederation(handler, s.ClusterClient, requestInfoResolver)
 
 	// authenticators are unordered
-	authn := unionauth.New(anonymous.NewAuthenticator(),
+	authenticators := []authenticator.Request{anonymous.NewAuthenticator(),
 		basictoken.New(basic.NewBasicAuthenticator(
 			auth.NewPasswordAuthenticator(s.RuntimeClient, s.AuthenticationOptions),
-			auth.NewLoginRecorder(s.RuntimeClient))),
-		bearertoken.New(jwt.NewTokenAuthenticator(s.RuntimeCache, s.TokenOperator, s.MultiClusterOptions.ClusterRole)))
+			auth.NewLoginRecorder(s.RuntimeClient),
+			auth.NewTOTPOperator(s.RuntimeClient, s.CacheClient, s.AuthenticationOptions))),
+		bearertoken.New(jwt.NewTokenAuthenticator(s.RuntimeCache, s.TokenOperator, s.MultiClusterOptions.ClusterRole)),
+	}
+	if s.AuthenticationOptions.SCIMOptions.Enable {
+		// the SCIM endpoints are protected by the dedicated bearer token of the identity provider
+		authenticators = append(authenticators, bearertoken.New(scimauth.NewTokenAuthenticator(s.AuthenticationOptions.SCIMOptions)))
+	}
+	authn := unionauth.New(authenticators...)
 
 	handler = filters.WithAuthentication(handler, authn)
+	if throttleOptions := s.AuthenticationOptions.LoginThrottle; throttleOptions.Enable {
+		trustedProxies, err := iputil.ParseCIDRs(throttleOptions.TrustedProxies)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxies: %s", err)
+		}
+		handler = filters.WithLoginThrottle(handler, auth.NewLoginThrottle(s.CacheClient, &s.AuthenticationOptions.LoginThrottle), trustedProxies, auditor)
+	}
 	handler = filters.WithRequestInfo(handler, requestInfoResolver)
 	return handler, nil
 }
@@ -32,7 +32,11 @@ import (
 	"kubesphere.io/api/iam/v1beta1"
 
 	"kubesphere.io/kubesphere/pkg/apiserver/auditing/internal"
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing/kafka"
 	"kubesphere.io/kubesphere/pkg/apiserver/auditing/log"
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing/otlp"
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing/store"
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing/syslog"
 	"kubesphere.io/kubesphere/pkg/apiserver/auditing/webhook"
 	"kubesphere.io/kubesphere/pkg/apiserver/request"
 	"kubesphere.io/kubesphere/pkg/constants"
@@ -58,8 +62,10 @@ type auditing struct {
 	k8sClient  k8s.Client
 	stopCh     <-chan struct{}
 	auditLevel audit.Level
+	policy     *policySource
+	redactor   *redactor
 	events     chan *Event
-	backend    []internal.Backend
+	deliveries []delivery
 
 	hostname string
 	hostIP   string
@@ -69,7 +75,9 @@ type auditing struct {
 	eventBatchInterval time.Duration
 }
 
-func NewAuditing(kubernetesClient k8s.Client, opts *Options, stopCh <-chan struct{}) Auditing {
+// NewAuditing creates the auditing which sends events to the configured backends,
+// and to the event store if it is not nil.
+func NewAuditing(kubernetesClient k8s.Client, opts *Options, eventStore store.Interface, stopCh <-chan struct{}) Auditing {
 
 	a := &auditing{
 		k8sClient:          kubernetesClient,
@@ -92,16 +100,63 @@ func NewAuditing(kubernetesClient k8s.Client, opts *Options, stopCh <-chan struc
 
 	a.cluster = a.getClusterName()
 
+	a.policy = newPolicySource(kubernetesClient, opts, defaultPolicy(a.getAuditLevel()))
+	a.policy.Start(stopCh)
+
+	redactor, err := newRedactor(opts.RedactionRules)
+	if err != nil {
+		klog.Errorf("invalid audit redaction rules, only the built-in rules are used: %s", err)
+		redactor, _ = newRedactor(nil)
+	}
+	a.redactor = redactor
+
+	registerMetrics()
+
 	if opts.WebhookOptions.WebhookUrl != "" {
-		a.backend = append(a.backend, webhook.NewBackend(opts.WebhookOptions.WebhookUrl,
-			opts.WebhookOptions.EventSendersNum))
+		sendersNum := opts.WebhookOptions.EventSendersNum
+		if sendersNum == 0 {
+			sendersNum = webhook.DefaultSendersNum
+		}
+		a.addBackend("webhook", webhook.NewBackend(opts.WebhookOptions.WebhookUrl), sendersNum, &opts.SpoolOptions)
 	}
 
 	if opts.LogOptions.Path != "" {
-		a.backend = append(a.backend, log.NewBackend(opts.LogOptions.Path,
+		a.addBackend("log", log.NewBackend(opts.LogOptions.Path,
 			opts.LogOptions.MaxAge,
 			opts.LogOptions.MaxBackups,
-			opts.LogOptions.MaxSize))
+			opts.LogOptions.MaxSize), 1, &opts.SpoolOptions)
+	}
+
+	if len(opts.KafkaOptions.Brokers) > 0 {
+		if b, err := kafka.NewBackend(&opts.KafkaOptions); err != nil {
+			klog.Errorf("failed to create auditing kafka backend: %s", err)
+		} else {
+			a.addBackend("kafka", b, 1, &opts.SpoolOptions)
+		}
+	}
+
+	if opts.SyslogOptions.Address != "" {
+		if b, err := syslog.NewBackend(&opts.SyslogOptions); err != nil {
+			klog.Errorf("failed to create auditing syslog backend: %s", err)
+		} else {
+			a.addBackend("syslog", b, 1, &opts.SpoolOptions)
+		}
+	}
+
+	if opts.OTLPOptions.Endpoint != "" {
+		if b, err := otlp.NewBackend(&opts.OTLPOptions); err != nil {
+			klog.Errorf("failed to create auditing otlp backend: %s", err)
+		} else {
+			a.addBackend("otlp", b, 1, &opts.SpoolOptions)
+		}
+	}
+
+	if eventStore != nil {
+		a.addBackend("store", eventStore, 1, &opts.SpoolOptions)
+	}
+
+	for _, d := range a.deliveries {
+		d.Start(stopCh)
 	}
 
 	go a.Start()
@@ -109,6 +164,24 @@ func NewAuditing(kubernetesClient k8s.Client, opts *Options, stopCh <-chan struc
 	return a
 }
 
+// addBackend delivers events to the backend through a spool on disk if the spool is enabled,
+// otherwise events are buffered in memory and sent by the given number of workers.
+func (a *auditing) addBackend(name string, backend internal.Backend, workers int, spoolOptions *SpoolOptions) {
+	if reflect2.IsNil(backend) {
+		return
+	}
+
+	if spoolOptions.Path != "" {
+		d, err := newSpoolDelivery(name, backend, spoolOptions, a.eventBatchSize)
+		if err == nil {
+			a.deliveries = append(a.deliveries, d)
+			return
+		}
+		klog.Errorf("failed to open auditing spool for backend %s, events are buffered in memory: %s", name, err)
+	}
+	a.deliveries = append(a.deliveries, newMemoryDelivery(name, backend, workers))
+}
+
 func getHostIP() string {
 	addrs, err := net.InterfaceAddrs()
 	hostip := ""
@@ -149,9 +222,12 @@ func (a *auditing) getAuditLevel() audit.Level {
 }
 
 func (a *auditing) Enabled() bool {
-
-	level := a.getAuditLevel()
-	return !level.Less(audit.LevelMetadata)
+	for _, rule := range a.policy.Policy().Rules {
+		if rule.Level.GreaterOrEqual(audit.LevelMetadata) {
+			return true
+		}
+	}
+	return false
 }
 
 // If the request is not a standard request, or a resource request,
@@ -184,7 +260,6 @@ func (a *auditing) LogRequestObject(req *http.Request, info *request.RequestInfo
 		Event: audit.Event{
 			RequestURI:               info.Path,
 			Verb:                     info.Verb,
-			Level:                    a.getAuditLevel(),
 			AuditID:                  types.UID(uuid.New().String()),
 			Stage:                    audit.StageResponseComplete,
 			ImpersonatedUser:         nil,
@@ -220,6 +295,20 @@ func (a *auditing) LogRequestObject(req *http.Request, info *request.RequestInfo
 		}
 	}
 
+	a.getWorkspace(e)
+
+	// The audit level and the stages to record are decided by the first policy rule matching the request.
+	auditConfig := a.policy.Policy().Evaluate(e, info)
+	if auditConfig.Level == audit.LevelNone {
+		return nil
+	}
+	e.Level = auditConfig.Level
+	e.omitStages = auditConfig.OmitStages
+
+	if hasStage(e.omitStages, audit.StageRequestReceived) && hasStage(e.omitStages, audit.StageResponseComplete) {
+		return nil
+	}
+
 	if a.needAnalyzeRequestBody(e, req) {
 		body, err := io.ReadAll(req.Body)
 		if err != nil {
@@ -231,10 +320,12 @@ func (a *auditing) LogRequestObject(req *http.Request, info *request.RequestInfo
 
 		if e.Level.GreaterOrEqual(audit.LevelRequest) {
 			e.RequestObject = &runtime.Unknown{Raw: body}
+			e.requestContentType = req.Header.Get("Content-Type")
 		}
 
 		// For resource creating request, get resource name from the request body.
-		if info.Verb == "create" {
+		// The name of the subresource creating request is in the path.
+		if info.Verb == "create" && info.Subresource == "" {
 			obj := &Object{}
 			if err := json.Unmarshal(body, obj); err == nil {
 				e.ObjectRef.Name = obj.Name
@@ -254,7 +345,22 @@ func (a *auditing) LogRequestObject(req *http.Request, info *request.RequestInfo
 		}
 	}
 
-	a.getWorkspace(e)
+	// for recording approve and deny access requests
+	if e.ObjectRef.Resource == "accessrequests" && (e.ObjectRef.Subresource == "approve" || e.ObjectRef.Subresource == "deny") {
+		e.Verb = e.ObjectRef.Subresource
+	}
+
+	if !hasStage(e.omitStages, audit.StageRequestReceived) {
+		received := *e
+		received.Stage = audit.StageRequestReceived
+		received.StageTimestamp = metav1.NowMicro()
+		a.cacheEvent(received)
+	}
+
+	// There is no need to capture the response if the ResponseComplete stage is omitted.
+	if hasStage(e.omitStages, audit.StageResponseComplete) {
+		return nil
+	}
 
 	return e
 }
@@ -322,6 +428,7 @@ func (a *auditing) cacheEvent(e Event) {
 		return
 	case <-time.After(CacheTimeout):
 		klog.V(8).Infof("cache audit event %s timeout", e.AuditID)
+		eventsDropped.WithLabelValues("", dropReasonQueueFull).Inc()
 		break
 	}
 }
@@ -342,12 +449,8 @@ func (a *auditing) Start() {
 			continue
 		}
 
-		for _, b := range a.backend {
-			if reflect2.IsNil(b) {
-				continue
-			}
-
-			b.ProcessEvents(byteEvents...)
+		for _, d := range a.deliveries {
+			d.Enqueue(byteEvents)
 		}
 	}
 }
@@ -378,6 +481,8 @@ func (a *auditing) getEvents() ([]*Event, bool) {
 func (a *auditing) eventToBytes(events []*Event) [][]byte {
 	var res [][]byte
 	for _, event := range events {
+		// Sensitive fields must be masked before events reach the backends.
+		a.redactor.Redact(event)
 		bs, err := json.Marshal(event)
 		if err != nil {
 			// Normally, the serialization failure is caused by the failure of ResponseObject serialization.
@@ -391,6 +496,7 @@ func (a *auditing) eventToBytes(events []*Event) [][]byte {
 
 		if err != nil {
 			klog.Errorf("serialize audit event error: %s", err)
+			eventsDropped.WithLabelValues("", dropReasonEncode).Inc()
 			continue
 		}
 
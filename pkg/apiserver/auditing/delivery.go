@@ -0,0 +1,258 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package auditing
+
+import (
+	"errors"
+	"io"
+	"math"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing/internal"
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing/spool"
+)
+
+const (
+	// DefaultQueueCapacity is the maximum number of event batches buffered in memory for each backend.
+	DefaultQueueCapacity = 100
+
+	DefaultRetryInitialInterval = time.Second
+	DefaultRetryMaxInterval     = time.Minute * 5
+	// DefaultRetryMaxAttempts retries the events for about an hour with the default intervals.
+	DefaultRetryMaxAttempts = 20
+
+	deadLetterSuffix = ".deadletter"
+)
+
+// delivery delivers events to a backend asynchronously.
+type delivery interface {
+	Enqueue(events [][]byte)
+	Start(stopCh <-chan struct{})
+}
+
+// memoryDelivery buffers events in memory, events are dropped if the
+// buffer is full or the backend fails to process them.
+type memoryDelivery struct {
+	name    string
+	backend internal.Backend
+	workers int
+	queue   chan [][]byte
+}
+
+func newMemoryDelivery(name string, backend internal.Backend, workers int) delivery {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &memoryDelivery{
+		name:    name,
+		backend: backend,
+		workers: workers,
+		queue:   make(chan [][]byte, DefaultQueueCapacity),
+	}
+}
+
+func (d *memoryDelivery) Enqueue(events [][]byte) {
+	select {
+	case d.queue <- events:
+		eventsQueued.WithLabelValues(d.name).Add(float64(len(events)))
+	default:
+		klog.Errorf("auditing backend %s queue is full, drop %d events", d.name, len(events))
+		eventsDropped.WithLabelValues(d.name, dropReasonQueueFull).Add(float64(len(events)))
+	}
+}
+
+func (d *memoryDelivery) Start(stopCh <-chan struct{}) {
+	var wg sync.WaitGroup
+	for i := 0; i < d.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.worker(stopCh)
+		}()
+	}
+	go func() {
+		wg.Wait()
+		closeBackend(d.name, d.backend)
+	}()
+}
+
+func (d *memoryDelivery) worker(stopCh <-chan struct{}) {
+	for {
+		select {
+		case events := <-d.queue:
+			eventsQueued.WithLabelValues(d.name).Add(-float64(len(events)))
+			if err := d.backend.ProcessEvents(events...); err != nil {
+				klog.Errorf("auditing backend %s failed to process %d events: %s", d.name, len(events), err)
+				eventsDropped.WithLabelValues(d.name, dropReasonSendFailed).Add(float64(len(events)))
+				continue
+			}
+			eventsSent.WithLabelValues(d.name).Add(float64(len(events)))
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// spoolDelivery writes events to a spool on disk before delivering them,
+// events are retried with exponential backoff until the backend accepts them,
+// or moved to the dead-letter spool after the maximum attempts, so that they don't block the events after them.
+type spoolDelivery struct {
+	name        string
+	backend     internal.Backend
+	spool       *spool.Spool
+	deadLetter  *spool.Spool
+	batchSize   int
+	backoff     wait.Backoff
+	maxAttempts int
+	notify      chan struct{}
+}
+
+func newSpoolDelivery(name string, backend internal.Backend, opts *SpoolOptions, batchSize int) (delivery, error) {
+	s, err := spool.Open(filepath.Join(opts.Path, name), int64(opts.MaxSize)*1024*1024)
+	if err != nil {
+		return nil, err
+	}
+	deadLetter, err := spool.Open(filepath.Join(opts.Path, name+deadLetterSuffix), int64(opts.MaxSize)*1024*1024)
+	if err != nil {
+		_ = s.Close()
+		return nil, err
+	}
+
+	d := &spoolDelivery{
+		name:        name,
+		backend:     backend,
+		spool:       s,
+		deadLetter:  deadLetter,
+		batchSize:   batchSize,
+		maxAttempts: opts.RetryMaxAttempts,
+		backoff: wait.Backoff{
+			Duration: opts.RetryInitialInterval,
+			Factor:   2,
+			Jitter:   0.1,
+			Steps:    math.MaxInt32,
+			Cap:      opts.RetryMaxInterval,
+		},
+		notify: make(chan struct{}, 1),
+	}
+	if d.backoff.Duration == 0 {
+		d.backoff.Duration = DefaultRetryInitialInterval
+	}
+	if d.backoff.Cap == 0 {
+		d.backoff.Cap = DefaultRetryMaxInterval
+	}
+	if d.maxAttempts == 0 {
+		d.maxAttempts = DefaultRetryMaxAttempts
+	}
+	eventsQueued.WithLabelValues(name).Set(float64(s.Len()))
+	return d, nil
+}
+
+func (d *spoolDelivery) Enqueue(events [][]byte) {
+	if err := d.spool.Append(events...); err != nil {
+		reason := dropReasonSpoolError
+		if errors.Is(err, spool.ErrFull) {
+			reason = dropReasonSpoolFull
+		}
+		klog.Errorf("auditing backend %s failed to spool %d events: %s", d.name, len(events), err)
+		eventsDropped.WithLabelValues(d.name, reason).Add(float64(len(events)))
+		return
+	}
+	eventsQueued.WithLabelValues(d.name).Set(float64(d.spool.Len()))
+
+	select {
+	case d.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (d *spoolDelivery) Start(stopCh <-chan struct{}) {
+	go d.worker(stopCh)
+}
+
+func (d *spoolDelivery) worker(stopCh <-chan struct{}) {
+	defer func() {
+		if err := d.spool.Close(); err != nil {
+			klog.Errorf("failed to close auditing spool of backend %s: %s", d.name, err)
+		}
+		if err := d.deadLetter.Close(); err != nil {
+			klog.Errorf("failed to close auditing dead-letter spool of backend %s: %s", d.name, err)
+		}
+		closeBackend(d.name, d.backend)
+	}()
+
+	backoff := d.backoff
+	attempts := 0
+	for {
+		events, err := d.spool.Peek(d.batchSize)
+		if err != nil {
+			klog.Errorf("failed to read auditing spool of backend %s: %s", d.name, err)
+		}
+
+		if len(events) == 0 {
+			select {
+			case <-d.notify:
+				continue
+			case <-stopCh:
+				return
+			}
+		}
+
+		if err := d.backend.ProcessEvents(events...); err != nil {
+			attempts++
+			if attempts < d.maxAttempts {
+				delay := backoff.Step()
+				klog.Errorf("auditing backend %s failed to process %d events, retry in %s: %s", d.name, len(events), delay, err)
+				eventsRetried.WithLabelValues(d.name).Add(float64(len(events)))
+				select {
+				case <-time.After(delay):
+					continue
+				case <-stopCh:
+					return
+				}
+			}
+			klog.Errorf("auditing backend %s failed to process %d events after %d attempts, move them to the dead-letter spool: %s",
+				d.name, len(events), attempts, err)
+			d.moveToDeadLetter(events)
+		} else {
+			eventsSent.WithLabelValues(d.name).Add(float64(len(events)))
+		}
+
+		if err := d.spool.Commit(); err != nil {
+			klog.Errorf("failed to commit auditing spool of backend %s: %s", d.name, err)
+		}
+		eventsQueued.WithLabelValues(d.name).Set(float64(d.spool.Len()))
+		backoff = d.backoff
+		attempts = 0
+	}
+}
+
+// moveToDeadLetter keeps the events which can't be delivered in the dead-letter spool for the operators to inspect.
+func (d *spoolDelivery) moveToDeadLetter(events [][]byte) {
+	reason := dropReasonDeadLetter
+	if err := d.deadLetter.Append(events...); err != nil {
+		reason = dropReasonSpoolError
+		if errors.Is(err, spool.ErrFull) {
+			reason = dropReasonSpoolFull
+		}
+		klog.Errorf("auditing backend %s failed to spool %d events to the dead-letter spool: %s", d.name, len(events), err)
+	}
+	eventsDropped.WithLabelValues(d.name, reason).Add(float64(len(events)))
+}
+
+// closeBackend closes the backend once the delivery is stopped, if the backend holds resources to be released.
+func closeBackend(name string, backend internal.Backend) {
+	if closer, ok := backend.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			klog.Errorf("failed to close auditing backend %s: %s", name, err)
+		}
+	}
+}
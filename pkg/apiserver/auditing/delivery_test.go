@@ -0,0 +1,149 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package auditing
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeBackend struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	received []string
+}
+
+func (b *fakeBackend) ProcessEvents(events ...[]byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.attempts++
+	if b.failures > 0 {
+		b.failures--
+		return fmt.Errorf("backend unavailable")
+	}
+	for _, event := range events {
+		b.received = append(b.received, string(event))
+	}
+	return nil
+}
+
+func (b *fakeBackend) snapshot() (int, []string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.attempts, append([]string{}, b.received...)
+}
+
+func TestSpoolDeliveryRetry(t *testing.T) {
+	backend := &fakeBackend{failures: 2}
+	d, err := newSpoolDelivery("fake", backend, &SpoolOptions{
+		Path:                 t.TempDir(),
+		RetryInitialInterval: time.Millisecond,
+		RetryMaxInterval:     time.Millisecond * 10,
+	}, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	d.Enqueue([][]byte{[]byte("event-0"), []byte("event-1")})
+	d.Start(stopCh)
+	d.Enqueue([][]byte{[]byte("event-2")})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, received := backend.snapshot(); len(received) == 3 {
+			break
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	attempts, received := backend.snapshot()
+	if len(received) != 3 || received[0] != "event-0" || received[2] != "event-2" {
+		t.Fatalf("received events = %v, want event-0, event-1 and event-2", received)
+	}
+	if attempts < 3 {
+		t.Errorf("attempts = %d, want at least 3", attempts)
+	}
+	if n := d.(*spoolDelivery).spool.Len(); n != 0 {
+		t.Errorf("spool length = %d, want 0", n)
+	}
+}
+
+func TestSpoolDeliveryDeadLetter(t *testing.T) {
+	backend := &fakeBackend{failures: 2}
+	d, err := newSpoolDelivery("fake", backend, &SpoolOptions{
+		Path:                 t.TempDir(),
+		RetryInitialInterval: time.Millisecond,
+		RetryMaxInterval:     time.Millisecond * 10,
+		RetryMaxAttempts:     2,
+	}, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stopCh := make(chan struct{})
+	d.Enqueue([][]byte{[]byte("event-0"), []byte("event-1")})
+	d.Start(stopCh)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, received := backend.snapshot(); len(received) == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	close(stopCh)
+
+	// event-0 failing all the attempts doesn't block event-1
+	attempts, received := backend.snapshot()
+	if len(received) != 1 || received[0] != "event-1" {
+		t.Fatalf("received events = %v, want event-1", received)
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d, want 3", attempts)
+	}
+	deadLetter := d.(*spoolDelivery).deadLetter
+	if n := deadLetter.Len(); n != 1 {
+		t.Fatalf("dead-letter spool length = %d, want 1", n)
+	}
+}
+
+type closingBackend struct {
+	fakeBackend
+	closed chan struct{}
+}
+
+func (b *closingBackend) Close() error {
+	close(b.closed)
+	return nil
+}
+
+func TestDeliveryClosesBackend(t *testing.T) {
+	memory := &closingBackend{closed: make(chan struct{})}
+	spooled := &closingBackend{closed: make(chan struct{})}
+	d, err := newSpoolDelivery("fake", spooled, &SpoolOptions{Path: t.TempDir()}, 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	stopCh := make(chan struct{})
+	newMemoryDelivery("fake", memory, 2).Start(stopCh)
+	d.Start(stopCh)
+	close(stopCh)
+
+	for name, backend := range map[string]*closingBackend{"memory": memory, "spool": spooled} {
+		select {
+		case <-backend.closed:
+		case <-time.After(5 * time.Second):
+			t.Errorf("the backend of the %s delivery is not closed after stopped", name)
+		}
+	}
+}
@@ -22,6 +22,11 @@ type Event struct {
 	HostIP   string
 
 	audit.Event
+
+	// The stages which should not be recorded, decided by the audit policy.
+	omitStages []audit.Stage
+	// The content type of the request body, used to locate the fields to redact.
+	requestContentType string
 }
 
 type Object struct {
@@ -6,6 +6,23 @@
 
 package internal
 
+// Backend is a destination of the auditing events, a backend holding resources, such as
+// connections or buffered events, implements io.Closer, which is called once it's stopped.
 type Backend interface {
-	ProcessEvents(events ...[]byte)
+	// ProcessEvents delivers the events to the backend, an error is returned
+	// if the events are not accepted, so that they can be retried.
+	ProcessEvents(events ...[]byte) error
+}
+
+// Chunk splits the events into batches of at most size events.
+func Chunk(events [][]byte, size int) [][][]byte {
+	if size <= 0 || len(events) <= size {
+		return [][][]byte{events}
+	}
+	var chunks [][][]byte
+	for len(events) > size {
+		chunks = append(chunks, events[:size])
+		events = events[size:]
+	}
+	return append(chunks, events)
 }
@@ -0,0 +1,155 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing/internal"
+)
+
+const (
+	DefaultTimeout      = time.Second * 10
+	DefaultBatchSize    = 100
+	DefaultClientID     = "ks-apiserver"
+	DefaultRequiredAcks = -1
+
+	SASLMechanismPlain       = "PLAIN"
+	SASLMechanismSCRAMSHA256 = "SCRAM-SHA-256"
+	SASLMechanismSCRAMSHA512 = "SCRAM-SHA-512"
+)
+
+type Options struct {
+	// Brokers is the list of bootstrap brokers in the form of host:port.
+	Brokers []string `json:"brokers,omitempty" yaml:"brokers,omitempty"`
+	Topic   string   `json:"topic,omitempty" yaml:"topic,omitempty"`
+	// RequiredAcks is the number of acknowledgements the leader must receive, 1 or -1 (all in-sync replicas),
+	// defaults to -1 if unset. 0 isn't supported since the events lost by the brokers can't be retried.
+	RequiredAcks *int `json:"requiredAcks,omitempty" yaml:"requiredAcks,omitempty"`
+	// The maximum number of events sent in one produce request.
+	BatchSize int           `json:"batchSize,omitempty" yaml:"batchSize,omitempty"`
+	Timeout   time.Duration `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	ClientID  string        `json:"clientID,omitempty" yaml:"clientID,omitempty"`
+	// Username and Password enable SASL authentication.
+	Username string `json:"username,omitempty" yaml:"username,omitempty"`
+	Password string `json:"password,omitempty" yaml:"password,omitempty"`
+	// SASLMechanism is PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512, defaults to PLAIN if unset.
+	SASLMechanism string              `json:"saslMechanism,omitempty" yaml:"saslMechanism,omitempty"`
+	TLS           internal.TLSOptions `json:"tls,omitempty" yaml:"tls,omitempty"`
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+	if len(o.Brokers) == 0 {
+		return nil
+	}
+	if o.Topic == "" {
+		errs = append(errs, fmt.Errorf("auditing kafka topic must be specified"))
+	}
+	if o.RequiredAcks != nil && *o.RequiredAcks != 1 && *o.RequiredAcks != -1 {
+		errs = append(errs, fmt.Errorf("auditing kafka required acks must be 1 or -1"))
+	}
+	if _, err := o.mechanism(); err != nil {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// mechanism returns the SASL mechanism, or nil if SASL is not enabled.
+func (o *Options) mechanism() (sasl.Mechanism, error) {
+	if o.Username == "" {
+		return nil, nil
+	}
+	switch o.SASLMechanism {
+	case "", SASLMechanismPlain:
+		return plain.Mechanism{Username: o.Username, Password: o.Password}, nil
+	case SASLMechanismSCRAMSHA256:
+		return scram.Mechanism(scram.SHA256, o.Username, o.Password)
+	case SASLMechanismSCRAMSHA512:
+		return scram.Mechanism(scram.SHA512, o.Username, o.Password)
+	default:
+		return nil, fmt.Errorf("auditing kafka SASL mechanism %s is not supported", o.SASLMechanism)
+	}
+}
+
+// backend produces auditing events to a kafka topic, the events are spread over the partitions in turn.
+type backend struct {
+	writer  *kafka.Writer
+	timeout time.Duration
+}
+
+func NewBackend(opts *Options) (internal.Backend, error) {
+	o := *opts
+	if o.Timeout == 0 {
+		o.Timeout = DefaultTimeout
+	}
+	if o.BatchSize == 0 {
+		o.BatchSize = DefaultBatchSize
+	}
+	if o.ClientID == "" {
+		o.ClientID = DefaultClientID
+	}
+	if o.RequiredAcks == nil {
+		requiredAcks := DefaultRequiredAcks
+		o.RequiredAcks = &requiredAcks
+	}
+
+	tlsConfig, err := o.TLS.Config()
+	if err != nil {
+		return nil, err
+	}
+	mechanism, err := o.mechanism()
+	if err != nil {
+		return nil, err
+	}
+
+	return &backend{
+		writer: &kafka.Writer{
+			Addr:      kafka.TCP(o.Brokers...),
+			Topic:     o.Topic,
+			Balancer:  &kafka.RoundRobin{},
+			BatchSize: o.BatchSize,
+			// the events are batched by the delivery already, they are flushed without waiting for more
+			BatchTimeout: time.Millisecond,
+			// the events failed to produce are retried by the delivery
+			MaxAttempts:  1,
+			RequiredAcks: kafka.RequiredAcks(*o.RequiredAcks),
+			ReadTimeout:  o.Timeout,
+			WriteTimeout: o.Timeout,
+			Transport: &kafka.Transport{
+				ClientID:    o.ClientID,
+				DialTimeout: o.Timeout,
+				TLS:         tlsConfig,
+				SASL:        mechanism,
+			},
+		},
+		timeout: o.Timeout,
+	}, nil
+}
+
+func (b *backend) ProcessEvents(events ...[]byte) error {
+	messages := make([]kafka.Message, 0, len(events))
+	for _, event := range events {
+		messages = append(messages, kafka.Message{Value: event})
+	}
+	// bounds the whole delivery, including the metadata lookup and the connections to the brokers
+	ctx, cancel := context.WithTimeout(context.Background(), 2*b.timeout)
+	defer cancel()
+	return b.writer.WriteMessages(ctx, messages...)
+}
+
+// Close flushes the pending batches and closes the connections to the brokers.
+func (b *backend) Close() error {
+	return b.writer.Close()
+}
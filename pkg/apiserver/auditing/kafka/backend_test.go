@@ -0,0 +1,324 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package kafka
+
+import (
+	"errors"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go/protocol"
+	"github.com/segmentio/kafka-go/protocol/apiversions"
+	"github.com/segmentio/kafka-go/protocol/metadata"
+	"github.com/segmentio/kafka-go/protocol/produce"
+	"github.com/segmentio/kafka-go/protocol/saslauthenticate"
+	"github.com/segmentio/kafka-go/protocol/saslhandshake"
+)
+
+const (
+	errorCodeUnsupportedSASLMechanism = 33
+	errorCodeSASLAuthenticationFailed = 58
+)
+
+// record is a record produced to the fake broker.
+type record struct {
+	topic     string
+	partition int32
+	key       []byte
+	value     string
+}
+
+// fakeBroker is an in-process kafka broker, which serves a topic with two partitions
+// over the kafka protocol and records the records produced to it.
+type fakeBroker struct {
+	t        *testing.T
+	listener net.Listener
+	topic    string
+	username string
+	password string
+
+	mu      sync.Mutex
+	records []record
+}
+
+func newFakeBroker(t *testing.T, topic, username, password string) *fakeBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b := &fakeBroker{t: t, listener: listener, topic: topic, username: username, password: password}
+	go b.serve()
+	t.Cleanup(func() { _ = listener.Close() })
+	return b
+}
+
+func (b *fakeBroker) serve() {
+	for {
+		c, err := b.listener.Accept()
+		if err != nil {
+			return
+		}
+		go b.handle(c)
+	}
+}
+
+func (b *fakeBroker) handle(c net.Conn) {
+	defer c.Close()
+	authenticated := b.username == ""
+	for {
+		apiVersion, correlationID, _, msg, err := protocol.ReadRequest(c)
+		// the client closes the connections once it's done
+		if err != nil {
+			return
+		}
+
+		var response protocol.Message
+		switch request := msg.(type) {
+		case *apiversions.Request:
+			response = b.apiVersions()
+		case *saslhandshake.Request:
+			response = &saslhandshake.Response{Mechanisms: []string{SASLMechanismPlain}}
+			if request.Mechanism != SASLMechanismPlain {
+				response = &saslhandshake.Response{ErrorCode: errorCodeUnsupportedSASLMechanism, Mechanisms: []string{SASLMechanismPlain}}
+			}
+		case *saslauthenticate.Request:
+			if string(request.AuthBytes) == "\x00"+b.username+"\x00"+b.password {
+				authenticated = true
+				response = &saslauthenticate.Response{}
+			} else {
+				response = &saslauthenticate.Response{ErrorCode: errorCodeSASLAuthenticationFailed, ErrorMessage: "invalid credentials"}
+			}
+		default:
+			if !authenticated {
+				return
+			}
+			switch request := msg.(type) {
+			case *metadata.Request:
+				response = b.metadata()
+			case *produce.Request:
+				response = b.produce(request)
+			default:
+				b.t.Errorf("unexpected request %T", msg)
+				return
+			}
+		}
+
+		if err = protocol.WriteResponse(c, apiVersion, correlationID, response); err != nil {
+			return
+		}
+	}
+}
+
+func (b *fakeBroker) apiVersions() *apiversions.Response {
+	response := &apiversions.Response{}
+	for _, key := range []protocol.ApiKey{protocol.ApiVersions, protocol.Metadata, protocol.Produce, protocol.SaslHandshake, protocol.SaslAuthenticate} {
+		maxVersion := key.MaxVersion()
+		// the authentication bytes are framed by the SaslAuthenticate requests since v1
+		if key == protocol.SaslHandshake {
+			maxVersion = 1
+		}
+		response.ApiKeys = append(response.ApiKeys, apiversions.ApiKeyResponse{
+			ApiKey:     int16(key),
+			MinVersion: key.MinVersion(),
+			MaxVersion: maxVersion,
+		})
+	}
+	return response
+}
+
+func (b *fakeBroker) metadata() *metadata.Response {
+	host, port, _ := net.SplitHostPort(b.listener.Addr().String())
+	portNum, _ := strconv.Atoi(port)
+	topic := metadata.ResponseTopic{Name: b.topic}
+	for partition := int32(0); partition < 2; partition++ {
+		topic.Partitions = append(topic.Partitions, metadata.ResponsePartition{
+			PartitionIndex: partition,
+			LeaderID:       1,
+			ReplicaNodes:   []int32{1},
+			IsrNodes:       []int32{1},
+		})
+	}
+	return &metadata.Response{
+		Brokers:      []metadata.ResponseBroker{{NodeID: 1, Host: host, Port: int32(portNum)}},
+		ControllerID: 1,
+		Topics:       []metadata.ResponseTopic{topic},
+	}
+}
+
+func (b *fakeBroker) produce(request *produce.Request) *produce.Response {
+	response := &produce.Response{}
+	for _, topic := range request.Topics {
+		responseTopic := produce.ResponseTopic{Topic: topic.Topic}
+		for _, partition := range topic.Partitions {
+			if err := b.append(topic.Topic, partition.Partition, partition.RecordSet.Records); err != nil {
+				b.t.Errorf("malformed record set: %v", err)
+			}
+			responseTopic.Partitions = append(responseTopic.Partitions, produce.ResponsePartition{
+				Partition:     partition.Partition,
+				LogAppendTime: -1,
+			})
+		}
+		response.Topics = append(response.Topics, responseTopic)
+	}
+	return response
+}
+
+func (b *fakeBroker) append(topic string, partition int32, records protocol.RecordReader) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for {
+		r, err := records.ReadRecord()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		key, err := protocol.ReadAll(r.Key)
+		if err != nil {
+			return err
+		}
+		value, err := protocol.ReadAll(r.Value)
+		if err != nil {
+			return err
+		}
+		b.records = append(b.records, record{topic: topic, partition: partition, key: key, value: string(value)})
+	}
+}
+
+func TestBackend(t *testing.T) {
+	broker := newFakeBroker(t, "kubesphere-audit", "audit", "P@88w0rd")
+
+	b, err := NewBackend(&Options{
+		Brokers:   []string{broker.listener.Addr().String()},
+		Topic:     "kubesphere-audit",
+		BatchSize: 2,
+		Timeout:   5 * time.Second,
+		Username:  "audit",
+		Password:  "P@88w0rd",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer b.(io.Closer).Close()
+
+	if err = b.ProcessEvents([]byte(`{"AuditID":"1"}`), []byte(`{"AuditID":"2"}`), []byte(`{"AuditID":"3"}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	partitions := map[int32]int{}
+	var values []string
+	for _, r := range broker.records {
+		if r.topic != "kubesphere-audit" {
+			t.Errorf("record produced to topic %s, want kubesphere-audit", r.topic)
+		}
+		if r.key != nil {
+			t.Errorf("record produced with key %q, want no key", r.key)
+		}
+		partitions[r.partition]++
+		values = append(values, r.value)
+	}
+	sort.Strings(values)
+	want := []string{`{"AuditID":"1"}`, `{"AuditID":"2"}`, `{"AuditID":"3"}`}
+	if len(values) != len(want) {
+		t.Fatalf("got records %v, want %v", values, want)
+	}
+	for i := range want {
+		if values[i] != want[i] {
+			t.Errorf("got records %v, want %v", values, want)
+			break
+		}
+	}
+	if len(partitions) != 2 {
+		t.Errorf("records are not spread over the partitions: %v", partitions)
+	}
+}
+
+func TestBackendAuthenticationFailed(t *testing.T) {
+	broker := newFakeBroker(t, "kubesphere-audit", "audit", "P@88w0rd")
+
+	b, err := NewBackend(&Options{
+		Brokers:  []string{broker.listener.Addr().String()},
+		Topic:    "kubesphere-audit",
+		Timeout:  time.Second,
+		Username: "audit",
+		Password: "wrong",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer b.(io.Closer).Close()
+
+	if err = b.ProcessEvents([]byte(`{}`)); err == nil {
+		t.Fatalf("expected error, didn't get any")
+	}
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	if len(broker.records) != 0 {
+		t.Errorf("expected no records without authentication, got %v", broker.records)
+	}
+}
+
+func TestBackendUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	addr := listener.Addr().String()
+	_ = listener.Close()
+
+	b, err := NewBackend(&Options{
+		Brokers:       []string{addr},
+		Topic:         "kubesphere-audit",
+		Timeout:       time.Second,
+		Username:      "audit",
+		Password:      "P@88w0rd",
+		SASLMechanism: SASLMechanismSCRAMSHA512,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the events are returned to the delivery to be retried
+	if err = b.ProcessEvents([]byte(`{}`)); err == nil {
+		t.Fatalf("expected error, didn't get any")
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	acks := func(n int) *int { return &n }
+	tests := []struct {
+		name          string
+		requiredAcks  *int
+		saslMechanism string
+		valid         bool
+	}{
+		{name: "unset", valid: true},
+		{name: "leader", requiredAcks: acks(1), valid: true},
+		{name: "all in-sync replicas", requiredAcks: acks(-1), valid: true},
+		{name: "no acknowledgement", requiredAcks: acks(0)},
+		{name: "invalid", requiredAcks: acks(2)},
+		{name: "plain", saslMechanism: SASLMechanismPlain, valid: true},
+		{name: "scram", saslMechanism: SASLMechanismSCRAMSHA256, valid: true},
+		{name: "unsupported mechanism", saslMechanism: "GSSAPI"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opts := &Options{Brokers: []string{"kafka:9092"}, Topic: "kubesphere-audit", RequiredAcks: test.requiredAcks,
+				Username: "audit", Password: "P@88w0rd", SASLMechanism: test.saslMechanism}
+			if errs := opts.Validate(); (len(errs) == 0) != test.valid {
+				t.Errorf("Validate() = %v, want valid %v", errs, test.valid)
+			}
+		})
+	}
+}
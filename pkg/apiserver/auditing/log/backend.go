@@ -85,11 +85,13 @@ func (b *backend) ensureLogFile() error {
 	return f.Close()
 }
 
-func (b *backend) ProcessEvents(events ...[]byte) {
-	for _, event := range events {
+func (b *backend) ProcessEvents(events ...[]byte) error {
+	for i, event := range events {
 		if _, err := fmt.Fprint(b.writer, string(event)+"\n"); err != nil {
 			klog.Errorf("Log audit event error, %s. affecting audit event: %v\nImpacted event:\n", err, event)
 			klog.Error(string(event))
+			return fmt.Errorf("log audit event %d of %d error, %s", i+1, len(events), err)
 		}
 	}
+	return nil
 }
@@ -0,0 +1,70 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package auditing
+
+import (
+	"sync"
+
+	compbasemetrics "k8s.io/component-base/metrics"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/metrics"
+)
+
+const (
+	dropReasonQueueFull  = "queue_full"
+	dropReasonSpoolFull  = "spool_full"
+	dropReasonSpoolError = "spool_error"
+	dropReasonSendFailed = "send_failed"
+	dropReasonEncode     = "encode_failed"
+	dropReasonDeadLetter = "dead_letter"
+)
+
+var (
+	registerMetricsOnce sync.Once
+
+	eventsQueued = compbasemetrics.NewGaugeVec(
+		&compbasemetrics.GaugeOpts{
+			Name:           "ks_auditing_events_queued",
+			Help:           "Number of auditing events waiting to be delivered to each backend.",
+			StabilityLevel: compbasemetrics.ALPHA,
+		},
+		[]string{"backend"},
+	)
+
+	eventsSent = compbasemetrics.NewCounterVec(
+		&compbasemetrics.CounterOpts{
+			Name:           "ks_auditing_events_sent_total",
+			Help:           "Counter of auditing events delivered to each backend.",
+			StabilityLevel: compbasemetrics.ALPHA,
+		},
+		[]string{"backend"},
+	)
+
+	eventsDropped = compbasemetrics.NewCounterVec(
+		&compbasemetrics.CounterOpts{
+			Name:           "ks_auditing_events_dropped_total",
+			Help:           "Counter of auditing events dropped before delivered, broken out for each backend and reason.",
+			StabilityLevel: compbasemetrics.ALPHA,
+		},
+		[]string{"backend", "reason"},
+	)
+
+	eventsRetried = compbasemetrics.NewCounterVec(
+		&compbasemetrics.CounterOpts{
+			Name:           "ks_auditing_events_retried_total",
+			Help:           "Counter of auditing events retried after failing to be delivered to each backend.",
+			StabilityLevel: compbasemetrics.ALPHA,
+		},
+		[]string{"backend"},
+	)
+)
+
+func registerMetrics() {
+	registerMetricsOnce.Do(func() {
+		metrics.Registry.MustRegister(eventsQueued, eventsSent, eventsDropped, eventsRetried)
+	})
+}
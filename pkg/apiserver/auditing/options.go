@@ -7,11 +7,17 @@
 package auditing
 
 import (
+	"fmt"
 	"time"
 
 	"k8s.io/apiserver/pkg/apis/audit"
 
 	"github.com/spf13/pflag"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing/kafka"
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing/otlp"
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing/store"
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing/syslog"
 )
 
 type WebhookOptions struct {
@@ -27,6 +33,23 @@ type LogOptions struct {
 	MaxSize    int    `json:"maxSize" yaml:"maxSize"`
 }
 
+// SpoolOptions enables at-least-once delivery, events are written to a spool
+// on disk for each backend, and retried until the backend accepts them.
+type SpoolOptions struct {
+	// Path of the spool directory, the spool is disabled if it is empty.
+	Path string `json:"path" yaml:"path"`
+	// The maximum size in megabytes of the spool of each backend,
+	// new events are dropped when it is reached. 0 means no limit.
+	MaxSize int `json:"maxSize" yaml:"maxSize"`
+	// The initial interval of retrying events that fail to be delivered,
+	// the interval doubles on each failure until RetryMaxInterval.
+	RetryInitialInterval time.Duration `json:"retryInitialInterval" yaml:"retryInitialInterval"`
+	RetryMaxInterval     time.Duration `json:"retryMaxInterval" yaml:"retryMaxInterval"`
+	// The maximum attempts of delivering events, the events still failing are moved to
+	// the dead-letter spool of the backend. 0 means DefaultRetryMaxAttempts.
+	RetryMaxAttempts int `json:"retryMaxAttempts" yaml:"retryMaxAttempts"`
+}
+
 type Options struct {
 	Enable     bool        `json:"enable" yaml:"enable"`
 	AuditLevel audit.Level `json:"auditLevel" yaml:"auditLevel"`
@@ -34,9 +57,25 @@ type Options struct {
 	EventBatchSize int `json:"eventBatchSize" yaml:"eventBatchSize"`
 	// The batch interval of auditing events.
 	EventBatchInterval time.Duration `json:"eventBatchInterval" yaml:"eventBatchInterval"`
+	// PolicyFile is the path to the file that defines the audit policy,
+	// the file is reloaded when it changes.
+	PolicyFile string `json:"policyFile,omitempty" yaml:"policyFile,omitempty"`
+	// PolicyConfigMap is the name of the ConfigMap in the kubesphere-system namespace
+	// that defines the audit policy under the key policy.yaml.
+	PolicyConfigMap string `json:"policyConfigMap,omitempty" yaml:"policyConfigMap,omitempty"`
+	// The interval of checking the policy file for changes.
+	PolicyReloadInterval time.Duration `json:"policyReloadInterval,omitempty" yaml:"policyReloadInterval,omitempty"`
+	// RedactionRules mask sensitive fields of request and response bodies in addition to the built-in rules.
+	RedactionRules []RedactionRule `json:"redactionRules,omitempty" yaml:"redactionRules,omitempty"`
 
 	WebhookOptions WebhookOptions `json:"webhookOptions" yaml:"webhookOptions"`
 	LogOptions     LogOptions     `json:"logOptions" yaml:"logOptions"`
+	SpoolOptions   SpoolOptions   `json:"spoolOptions" yaml:"spoolOptions"`
+	KafkaOptions   kafka.Options  `json:"kafkaOptions,omitempty" yaml:"kafkaOptions,omitempty"`
+	SyslogOptions  syslog.Options `json:"syslogOptions,omitempty" yaml:"syslogOptions,omitempty"`
+	OTLPOptions    otlp.Options   `json:"otlpOptions,omitempty" yaml:"otlpOptions,omitempty"`
+	// StoreOptions keeps auditing events locally, to be queried through the auditing API.
+	StoreOptions store.Options `json:"storeOptions,omitempty" yaml:"storeOptions,omitempty"`
 }
 
 func NewAuditingOptions() *Options {
@@ -45,6 +84,20 @@ func NewAuditingOptions() *Options {
 
 func (s *Options) Validate() []error {
 	errs := make([]error, 0)
+	if s.PolicyFile != "" && s.PolicyConfigMap != "" {
+		errs = append(errs, fmt.Errorf("auditing policy file and policy configmap are mutually exclusive"))
+	}
+	errs = append(errs, ValidateRedactionRules(s.RedactionRules)...)
+	if s.SpoolOptions.MaxSize < 0 {
+		errs = append(errs, fmt.Errorf("auditing spool max size must not be negative"))
+	}
+	if s.SpoolOptions.RetryMaxAttempts < 0 {
+		errs = append(errs, fmt.Errorf("auditing retry max attempts must not be negative"))
+	}
+	errs = append(errs, s.KafkaOptions.Validate()...)
+	errs = append(errs, s.SyslogOptions.Validate()...)
+	errs = append(errs, s.OTLPOptions.Validate()...)
+	errs = append(errs, s.StoreOptions.Validate()...)
 	return errs
 }
 
@@ -54,6 +107,12 @@ func (s *Options) AddFlags(fs *pflag.FlagSet, c *Options) {
 		"The batch size of auditing events.")
 	fs.DurationVar(&s.EventBatchInterval, "auditing-event-batch-interval", c.EventBatchInterval,
 		"The batch interval of auditing events.")
+	fs.StringVar(&s.PolicyFile, "auditing-policy-file", c.PolicyFile,
+		"Path to the file that defines the audit policy. The file is reloaded when it changes.")
+	fs.StringVar(&s.PolicyConfigMap, "auditing-policy-configmap", c.PolicyConfigMap,
+		"Name of the ConfigMap in the kubesphere-system namespace that defines the audit policy.")
+	fs.DurationVar(&s.PolicyReloadInterval, "auditing-policy-reload-interval", c.PolicyReloadInterval,
+		"The interval of checking the audit policy file for changes.")
 
 	fs.StringVar(&s.WebhookOptions.WebhookUrl, "auditing-webhook-url", c.WebhookOptions.WebhookUrl, "Auditing wehook url")
 	fs.IntVar(&s.WebhookOptions.EventSendersNum, "auditing-event-senders-num", c.WebhookOptions.EventSendersNum,
@@ -67,4 +126,31 @@ func (s *Options) AddFlags(fs *pflag.FlagSet, c *Options) {
 		"The maximum number of old audit log files to retain. Setting a value of 0 will mean there's no restriction on the number of files.")
 	fs.IntVar(&s.LogOptions.MaxSize, "audit-log-maxsize", s.LogOptions.MaxSize,
 		"The maximum size in megabytes of the audit log file before it gets rotated.")
+
+	fs.StringVar(&s.SpoolOptions.Path, "auditing-spool-path", c.SpoolOptions.Path,
+		"If set, auditing events are spooled on disk under this directory and retried until delivered.")
+	fs.IntVar(&s.SpoolOptions.MaxSize, "auditing-spool-maxsize", c.SpoolOptions.MaxSize,
+		"The maximum size in megabytes of the auditing spool of each backend. 0 means no limit.")
+	fs.DurationVar(&s.SpoolOptions.RetryInitialInterval, "auditing-retry-initial-interval", c.SpoolOptions.RetryInitialInterval,
+		"The initial interval of retrying auditing events that fail to be delivered.")
+	fs.DurationVar(&s.SpoolOptions.RetryMaxInterval, "auditing-retry-max-interval", c.SpoolOptions.RetryMaxInterval,
+		"The maximum interval of retrying auditing events that fail to be delivered.")
+	fs.IntVar(&s.SpoolOptions.RetryMaxAttempts, "auditing-retry-max-attempts", c.SpoolOptions.RetryMaxAttempts,
+		"The maximum attempts of delivering auditing events before they are moved to the dead-letter spool.")
+
+	fs.StringSliceVar(&s.KafkaOptions.Brokers, "auditing-kafka-brokers", c.KafkaOptions.Brokers,
+		"If set, auditing events are produced to kafka through these bootstrap brokers.")
+	fs.StringVar(&s.KafkaOptions.Topic, "auditing-kafka-topic", c.KafkaOptions.Topic,
+		"The kafka topic which auditing events are produced to.")
+	fs.StringVar(&s.SyslogOptions.Network, "auditing-syslog-network", c.SyslogOptions.Network,
+		"The network of the syslog server, one of udp, tcp or tls.")
+	fs.StringVar(&s.SyslogOptions.Address, "auditing-syslog-address", c.SyslogOptions.Address,
+		"If set, auditing events are sent to the syslog server at this address as RFC 5424 messages.")
+	fs.StringVar(&s.OTLPOptions.Endpoint, "auditing-otlp-endpoint", c.OTLPOptions.Endpoint,
+		"If set, auditing events are exported as OpenTelemetry logs to this OTLP/HTTP endpoint.")
+
+	fs.StringVar(&s.StoreOptions.Path, "auditing-store-path", c.StoreOptions.Path,
+		"If set, auditing events are stored under this directory and can be queried through the auditing API.")
+	fs.DurationVar(&s.StoreOptions.Retention, "auditing-store-retention", c.StoreOptions.Retention,
+		"How long auditing events are kept in the local store.")
 }
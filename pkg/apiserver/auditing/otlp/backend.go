@@ -0,0 +1,268 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package otlp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing/internal"
+)
+
+const (
+	DefaultTimeout     = time.Second * 10
+	DefaultBatchSize   = 100
+	DefaultServiceName = "ks-apiserver"
+
+	scopeName = "kubesphere.io/auditing"
+	// severity number of INFO in the OpenTelemetry log data model
+	severityNumberInfo = 9
+)
+
+type Options struct {
+	// Endpoint is the OTLP/HTTP logs endpoint, e.g. http://otel-collector:4318/v1/logs.
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	// Headers are sent with every export request, e.g. for authentication.
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	// Compression is gzip or none.
+	Compression string `json:"compression,omitempty" yaml:"compression,omitempty"`
+	ServiceName string `json:"serviceName,omitempty" yaml:"serviceName,omitempty"`
+	// The maximum number of events exported in one request.
+	BatchSize int                 `json:"batchSize,omitempty" yaml:"batchSize,omitempty"`
+	Timeout   time.Duration       `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	TLS       internal.TLSOptions `json:"tls,omitempty" yaml:"tls,omitempty"`
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+	if o.Endpoint == "" {
+		return nil
+	}
+	if o.Compression != "" && o.Compression != "gzip" && o.Compression != "none" {
+		errs = append(errs, fmt.Errorf("auditing otlp compression must be gzip or none"))
+	}
+	return errs
+}
+
+// backend exports auditing events as OpenTelemetry log records with the OTLP/HTTP JSON encoding.
+type backend struct {
+	opts     Options
+	client   *http.Client
+	resource resource
+}
+
+func NewBackend(opts *Options) (internal.Backend, error) {
+	b := &backend{opts: *opts}
+	if b.opts.Timeout == 0 {
+		b.opts.Timeout = DefaultTimeout
+	}
+	if b.opts.BatchSize == 0 {
+		b.opts.BatchSize = DefaultBatchSize
+	}
+	if b.opts.ServiceName == "" {
+		b.opts.ServiceName = DefaultServiceName
+	}
+
+	tlsConfig, err := b.opts.TLS.Config()
+	if err != nil {
+		return nil, err
+	}
+	b.client = &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsConfig, Proxy: http.ProxyFromEnvironment},
+		Timeout:   b.opts.Timeout,
+	}
+
+	hostname, _ := os.Hostname()
+	b.resource = resource{Attributes: []keyValue{
+		stringAttribute("service.name", b.opts.ServiceName),
+		stringAttribute("host.name", hostname),
+	}}
+	return b, nil
+}
+
+// The OTLP JSON encoding of the logs data model,
+// see https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/logs/v1/logs.proto
+type exportLogsServiceRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeLogs struct {
+	Scope      scope       `json:"scope"`
+	LogRecords []logRecord `json:"logRecords"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type logRecord struct {
+	TimeUnixNano         string     `json:"timeUnixNano"`
+	ObservedTimeUnixNano string     `json:"observedTimeUnixNano"`
+	SeverityNumber       int        `json:"severityNumber"`
+	SeverityText         string     `json:"severityText"`
+	Body                 anyValue   `json:"body"`
+	Attributes           []keyValue `json:"attributes,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string `json:"stringValue,omitempty"`
+	IntValue    *string `json:"intValue,omitempty"`
+}
+
+func stringAttribute(key, value string) keyValue {
+	return keyValue{Key: key, Value: anyValue{StringValue: &value}}
+}
+
+func intAttribute(key string, value int64) keyValue {
+	v := strconv.FormatInt(value, 10)
+	return keyValue{Key: key, Value: anyValue{IntValue: &v}}
+}
+
+// event holds the fields of an auditing event that are exported as log attributes.
+type event struct {
+	AuditID    string
+	Verb       string
+	Stage      string
+	RequestURI string
+	Workspace  string
+	Cluster    string
+	User       struct {
+		Username string `json:"username"`
+	}
+	ObjectRef *struct {
+		APIGroup  string
+		Resource  string
+		Namespace string
+		Name      string
+	}
+	ResponseStatus *struct {
+		Code int64 `json:"code"`
+	}
+	StageTimestamp time.Time
+}
+
+func (b *backend) logRecord(data []byte, observed time.Time) logRecord {
+	body := string(data)
+	record := logRecord{
+		TimeUnixNano:         strconv.FormatInt(observed.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(observed.UnixNano(), 10),
+		SeverityNumber:       severityNumberInfo,
+		SeverityText:         "INFO",
+		Body:                 anyValue{StringValue: &body},
+	}
+
+	e := &event{}
+	if err := json.Unmarshal(data, e); err != nil {
+		return record
+	}
+	if !e.StageTimestamp.IsZero() {
+		record.TimeUnixNano = strconv.FormatInt(e.StageTimestamp.UnixNano(), 10)
+	}
+	attributes := []keyValue{
+		stringAttribute("kubesphere.audit.id", e.AuditID),
+		stringAttribute("kubesphere.audit.verb", e.Verb),
+		stringAttribute("kubesphere.audit.stage", e.Stage),
+		stringAttribute("kubesphere.audit.request_uri", e.RequestURI),
+		stringAttribute("kubesphere.audit.user", e.User.Username),
+		stringAttribute("kubesphere.audit.workspace", e.Workspace),
+		stringAttribute("kubesphere.audit.cluster", e.Cluster),
+	}
+	if e.ObjectRef != nil {
+		attributes = append(attributes,
+			stringAttribute("kubesphere.audit.object.api_group", e.ObjectRef.APIGroup),
+			stringAttribute("kubesphere.audit.object.resource", e.ObjectRef.Resource),
+			stringAttribute("kubesphere.audit.object.namespace", e.ObjectRef.Namespace),
+			stringAttribute("kubesphere.audit.object.name", e.ObjectRef.Name))
+	}
+	if e.ResponseStatus != nil {
+		attributes = append(attributes, intAttribute("kubesphere.audit.response_code", e.ResponseStatus.Code))
+	}
+	record.Attributes = attributes
+	return record
+}
+
+func (b *backend) ProcessEvents(events ...[]byte) error {
+	for _, chunk := range internal.Chunk(events, b.opts.BatchSize) {
+		if err := b.export(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *backend) export(events [][]byte) error {
+	now := time.Now()
+	records := make([]logRecord, 0, len(events))
+	for _, e := range events {
+		records = append(records, b.logRecord(e, now))
+	}
+	data, err := json.Marshal(exportLogsServiceRequest{ResourceLogs: []resourceLogs{{
+		Resource:  b.resource,
+		ScopeLogs: []scopeLogs{{Scope: scope{Name: scopeName}, LogRecords: records}},
+	}}})
+	if err != nil {
+		return err
+	}
+
+	var body io.Reader = bytes.NewReader(data)
+	if b.opts.Compression == "gzip" {
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err = writer.Write(data); err != nil {
+			return err
+		}
+		if err = writer.Close(); err != nil {
+			return err
+		}
+		body = &buf
+	}
+
+	req, err := http.NewRequest(http.MethodPost, b.opts.Endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if b.opts.Compression == "gzip" {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for key, value := range b.opts.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("export audit events error, %s", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("export audit events error[%d]", resp.StatusCode)
+	}
+	return nil
+}
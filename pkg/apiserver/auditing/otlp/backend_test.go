@@ -0,0 +1,97 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package otlp
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBackend(t *testing.T) {
+	var requests []exportLogsServiceRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Content-Encoding") != "gzip" {
+			t.Errorf("Content-Encoding = %q, want gzip", r.Header.Get("Content-Encoding"))
+		}
+		reader, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		request := exportLogsServiceRequest{}
+		if err = json.NewDecoder(reader).Decode(&request); err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		requests = append(requests, request)
+	}))
+	defer server.Close()
+
+	b, err := NewBackend(&Options{
+		Endpoint:    server.URL + "/v1/logs",
+		Headers:     map[string]string{"Authorization": "Bearer token"},
+		Compression: "gzip",
+		BatchSize:   2,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	event := `{"AuditID":"1","Verb":"create","User":{"username":"admin"},"ObjectRef":{"Resource":"users","Name":"tester"},` +
+		`"ResponseStatus":{"code":201},"StageTimestamp":"2024-01-01T00:00:00.000000Z"}`
+	if err = b.ProcessEvents([]byte(event), []byte(`{"AuditID":"2"}`), []byte(`not json`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("got %d export requests, want 2", len(requests))
+	}
+	record := requests[0].ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if *record.Body.StringValue != event {
+		t.Errorf("body = %s, want %s", *record.Body.StringValue, event)
+	}
+	if record.TimeUnixNano != "1704067200000000000" {
+		t.Errorf("timeUnixNano = %s, want the stage timestamp", record.TimeUnixNano)
+	}
+	attributes := map[string]anyValue{}
+	for _, attribute := range record.Attributes {
+		attributes[attribute.Key] = attribute.Value
+	}
+	if v := attributes["kubesphere.audit.user"].StringValue; v == nil || *v != "admin" {
+		t.Errorf("user attribute = %v, want admin", v)
+	}
+	if v := attributes["kubesphere.audit.response_code"].IntValue; v == nil || *v != "201" {
+		t.Errorf("response code attribute = %v, want 201", v)
+	}
+
+	invalid := requests[1].ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if *invalid.Body.StringValue != "not json" || len(invalid.Attributes) != 0 {
+		t.Errorf("unexpected record of an invalid event: %+v", invalid)
+	}
+}
+
+func TestBackendRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	b, err := NewBackend(&Options{Endpoint: server.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = b.ProcessEvents([]byte(`{}`)); err == nil {
+		t.Fatalf("expected error, didn't get any")
+	}
+}
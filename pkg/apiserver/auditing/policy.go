@@ -0,0 +1,285 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package auditing
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apiserver/pkg/apis/audit"
+	"sigs.k8s.io/yaml"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
+)
+
+// Policy defines how different kinds of requests are audited.
+// It follows the Kubernetes audit policy, and additionally allows
+// rules to match on the workspace and cluster of a request.
+type Policy struct {
+	// Rules specify the audit Level a request should be recorded at.
+	// A request may match multiple rules, in which case the FIRST matching rule is used.
+	// Requests that match no rule are not audited.
+	Rules []PolicyRule `json:"rules" yaml:"rules"`
+	// OmitStages is a list of stages for which no events are created.
+	// It is merged with the OmitStages of every rule.
+	OmitStages []audit.Stage `json:"omitStages,omitempty" yaml:"omitStages,omitempty"`
+}
+
+// PolicyRule maps requests to an audit Level.
+// Requests must match every non-empty field of the rule.
+type PolicyRule struct {
+	// The Level that requests matching this rule are recorded at.
+	Level audit.Level `json:"level" yaml:"level"`
+	// The users (by authenticated user name) this rule applies to.
+	Users []string `json:"users,omitempty" yaml:"users,omitempty"`
+	// The user groups this rule applies to.
+	UserGroups []string `json:"userGroups,omitempty" yaml:"userGroups,omitempty"`
+	// The verbs that match this rule.
+	Verbs []string `json:"verbs,omitempty" yaml:"verbs,omitempty"`
+	// Resources that this rule matches, only resource requests match.
+	Resources []GroupResources `json:"resources,omitempty" yaml:"resources,omitempty"`
+	// Namespaces that this rule matches, only resource requests match.
+	// The empty string "" matches non-namespaced resources.
+	Namespaces []string `json:"namespaces,omitempty" yaml:"namespaces,omitempty"`
+	// Workspaces that this rule matches.
+	// The empty string "" matches requests that do not belong to any workspace.
+	Workspaces []string `json:"workspaces,omitempty" yaml:"workspaces,omitempty"`
+	// Clusters that this rule matches.
+	Clusters []string `json:"clusters,omitempty" yaml:"clusters,omitempty"`
+	// NonResourceURLs is a set of URL paths that should be audited, only non-resource requests match.
+	// `*`s are allowed, but only as the full, final step in the path.
+	NonResourceURLs []string `json:"nonResourceURLs,omitempty" yaml:"nonResourceURLs,omitempty"`
+	// OmitStages is a list of stages for which no events are created.
+	OmitStages []audit.Stage `json:"omitStages,omitempty" yaml:"omitStages,omitempty"`
+}
+
+// GroupResources represents resource kinds in an API group.
+type GroupResources struct {
+	// Group is the name of the API group that contains the resources.
+	// The empty string represents the core API group.
+	Group string `json:"group,omitempty" yaml:"group,omitempty"`
+	// Resources is a list of resources this rule applies to.
+	// `pods/log`, `*`, `pods/*` and `*/scale` are supported.
+	Resources []string `json:"resources,omitempty" yaml:"resources,omitempty"`
+	// ResourceNames is a list of resource instance names that the policy matches.
+	ResourceNames []string `json:"resourceNames,omitempty" yaml:"resourceNames,omitempty"`
+}
+
+// RequestAuditConfig is the result of evaluating a policy against a request.
+type RequestAuditConfig struct {
+	Level      audit.Level
+	OmitStages []audit.Stage
+}
+
+// defaultPolicy records every request at the given level, only the ResponseComplete stage is recorded.
+func defaultPolicy(level audit.Level) *Policy {
+	return &Policy{
+		Rules:      []PolicyRule{{Level: level}},
+		OmitStages: []audit.Stage{audit.StageRequestReceived},
+	}
+}
+
+// LoadPolicy decodes a YAML or JSON encoded policy and validates it.
+func LoadPolicy(data []byte) (*Policy, error) {
+	policy := &Policy{}
+	if err := yaml.UnmarshalStrict(data, policy); err != nil {
+		return nil, fmt.Errorf("failed to decode audit policy: %v", err)
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+var validLevels = []string{
+	string(audit.LevelNone),
+	string(audit.LevelMetadata),
+	string(audit.LevelRequest),
+	string(audit.LevelRequestResponse),
+}
+
+var validStages = []string{
+	string(audit.StageRequestReceived),
+	string(audit.StageResponseComplete),
+}
+
+func (p *Policy) Validate() error {
+	if len(p.Rules) == 0 {
+		return fmt.Errorf("audit policy must have at least one rule")
+	}
+	if err := validateStages(p.OmitStages); err != nil {
+		return err
+	}
+	for i, rule := range p.Rules {
+		if !sliceutil.HasString(validLevels, string(rule.Level)) {
+			return fmt.Errorf("rules[%d]: invalid audit level %q", i, rule.Level)
+		}
+		if len(rule.NonResourceURLs) > 0 && (len(rule.Resources) > 0 || len(rule.Namespaces) > 0) {
+			return fmt.Errorf("rules[%d]: rules cannot apply to both regular resources and non-resource URLs", i)
+		}
+		for _, url := range rule.NonResourceURLs {
+			if index := strings.Index(url, "*"); index >= 0 && index != len(url)-1 {
+				return fmt.Errorf("rules[%d]: non-resource URL %q may only have '*' at the end", i, url)
+			}
+		}
+		for _, gr := range rule.Resources {
+			if len(gr.ResourceNames) > 0 && len(gr.Resources) == 0 {
+				return fmt.Errorf("rules[%d]: resource names require resources to be specified", i)
+			}
+		}
+		if err := validateStages(rule.OmitStages); err != nil {
+			return fmt.Errorf("rules[%d]: %v", i, err)
+		}
+	}
+	return nil
+}
+
+func validateStages(stages []audit.Stage) error {
+	for _, stage := range stages {
+		if !sliceutil.HasString(validStages, string(stage)) {
+			return fmt.Errorf("invalid audit stage %q", stage)
+		}
+	}
+	return nil
+}
+
+// Evaluate returns the audit config of the first rule matching the request.
+// Requests that match no rule are recorded at LevelNone.
+func (p *Policy) Evaluate(e *Event, info *request.RequestInfo) RequestAuditConfig {
+	for i := range p.Rules {
+		rule := &p.Rules[i]
+		if rule.matches(e, info) {
+			return RequestAuditConfig{
+				Level:      rule.Level,
+				OmitStages: unionStages(p.OmitStages, rule.OmitStages),
+			}
+		}
+	}
+	return RequestAuditConfig{Level: audit.LevelNone, OmitStages: p.OmitStages}
+}
+
+func (r *PolicyRule) matches(e *Event, info *request.RequestInfo) bool {
+	if len(r.Users) > 0 && !sliceutil.HasString(r.Users, e.User.Username) {
+		return false
+	}
+	if len(r.UserGroups) > 0 {
+		matched := false
+		for _, group := range e.User.Groups {
+			if sliceutil.HasString(r.UserGroups, group) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(r.Verbs) > 0 && !sliceutil.HasString(r.Verbs, info.Verb) {
+		return false
+	}
+	if len(r.Workspaces) > 0 && !sliceutil.HasString(r.Workspaces, e.Workspace) {
+		return false
+	}
+	if len(r.Clusters) > 0 && !sliceutil.HasString(r.Clusters, e.Cluster) {
+		return false
+	}
+	if len(r.Namespaces) > 0 || len(r.Resources) > 0 {
+		return r.matchesResource(info)
+	}
+	if len(r.NonResourceURLs) > 0 {
+		return r.matchesNonResource(info)
+	}
+	return true
+}
+
+func (r *PolicyRule) matchesNonResource(info *request.RequestInfo) bool {
+	if info.IsResourceRequest {
+		return false
+	}
+	for _, spec := range r.NonResourceURLs {
+		if pathMatches(info.Path, spec) {
+			return true
+		}
+	}
+	return false
+}
+
+func pathMatches(path, spec string) bool {
+	if spec == "*" || spec == path {
+		return true
+	}
+	return strings.HasSuffix(spec, "*") && strings.HasPrefix(path, strings.TrimRight(spec, "*"))
+}
+
+func (r *PolicyRule) matchesResource(info *request.RequestInfo) bool {
+	if !info.IsResourceRequest {
+		return false
+	}
+	// Non-namespaced resources use the empty string.
+	if len(r.Namespaces) > 0 && !sliceutil.HasString(r.Namespaces, info.Namespace) {
+		return false
+	}
+	if len(r.Resources) == 0 {
+		return true
+	}
+
+	return groupResourcesMatch(r.Resources, info.APIGroup, info.Resource, info.Subresource, info.Name)
+}
+
+// groupResourcesMatch checks whether the resource matches any of the group resources.
+func groupResourcesMatch(groupResources []GroupResources, group, resource, subresource, name string) bool {
+	combinedResource := resource
+	if subresource != "" {
+		combinedResource = resource + "/" + subresource
+	}
+
+	for _, gr := range groupResources {
+		if gr.Group != group {
+			continue
+		}
+		if len(gr.Resources) == 0 {
+			return true
+		}
+		if len(gr.ResourceNames) > 0 && !sliceutil.HasString(gr.ResourceNames, name) {
+			continue
+		}
+		for _, res := range gr.Resources {
+			if res == combinedResource || res == "*" {
+				return true
+			}
+			if subresource != "" && strings.HasPrefix(res, "*/") && subresource == strings.TrimPrefix(res, "*/") {
+				return true
+			}
+			if strings.HasSuffix(res, "/*") && resource == strings.TrimSuffix(res, "/*") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func unionStages(stageLists ...[]audit.Stage) []audit.Stage {
+	var result []audit.Stage
+	for _, stages := range stageLists {
+		for _, stage := range stages {
+			if !hasStage(result, stage) {
+				result = append(result, stage)
+			}
+		}
+	}
+	return result
+}
+
+func hasStage(stages []audit.Stage, stage audit.Stage) bool {
+	for _, s := range stages {
+		if s == stage {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package auditing
+
+import (
+	"bytes"
+	"os"
+	"sync/atomic"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/informers"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/simple/client/k8s"
+)
+
+const (
+	// PolicyConfigMapKey is the key of the policy in the audit policy ConfigMap.
+	PolicyConfigMapKey          = "policy.yaml"
+	DefaultPolicyReloadInterval = time.Second * 10
+)
+
+// policySource holds the audit policy in effect, the policy is reloaded
+// when the policy file or the policy ConfigMap changes.
+type policySource struct {
+	k8sClient      k8s.Client
+	file           string
+	configMap      string
+	reloadInterval time.Duration
+	// fallback is used when no policy is configured or the configured policy is removed.
+	fallback *Policy
+
+	policy   atomic.Pointer[Policy]
+	lastData []byte
+}
+
+func newPolicySource(k8sClient k8s.Client, opts *Options, fallback *Policy) *policySource {
+	s := &policySource{
+		k8sClient:      k8sClient,
+		file:           opts.PolicyFile,
+		configMap:      opts.PolicyConfigMap,
+		reloadInterval: opts.PolicyReloadInterval,
+		fallback:       fallback,
+	}
+	if s.reloadInterval == 0 {
+		s.reloadInterval = DefaultPolicyReloadInterval
+	}
+	s.policy.Store(fallback)
+	if s.file != "" {
+		s.loadFile()
+	}
+	return s
+}
+
+// Policy returns the audit policy in effect.
+func (s *policySource) Policy() *Policy {
+	return s.policy.Load()
+}
+
+func (s *policySource) Start(stopCh <-chan struct{}) {
+	if s.file != "" {
+		go wait.Until(s.loadFile, s.reloadInterval, stopCh)
+	}
+	if s.configMap != "" {
+		s.watchConfigMap(stopCh)
+	}
+}
+
+func (s *policySource) loadFile() {
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		klog.Errorf("failed to read audit policy file %s: %s", s.file, err)
+		return
+	}
+	if bytes.Equal(data, s.lastData) {
+		return
+	}
+	s.lastData = data
+	s.load(data, s.file)
+}
+
+func (s *policySource) load(data []byte, source string) {
+	policy, err := LoadPolicy(data)
+	if err != nil {
+		// keep the policy in effect if the new one is invalid
+		klog.Errorf("failed to load audit policy from %s: %s", source, err)
+		return
+	}
+	s.policy.Store(policy)
+	klog.Infof("audit policy loaded from %s with %d rules", source, len(policy.Rules))
+}
+
+func (s *policySource) watchConfigMap(stopCh <-chan struct{}) {
+	factory := informers.NewSharedInformerFactoryWithOptions(s.k8sClient, 0,
+		informers.WithNamespace(constants.KubeSphereNamespace),
+		informers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", s.configMap).String()
+		}))
+
+	source := constants.KubeSphereNamespace + "/" + s.configMap
+	onChange := func(obj interface{}) {
+		if cm, ok := obj.(*corev1.ConfigMap); ok {
+			s.load([]byte(cm.Data[PolicyConfigMapKey]), source)
+		}
+	}
+	_, err := factory.Core().V1().ConfigMaps().Informer().AddEventHandler(toolscache.ResourceEventHandlerFuncs{
+		AddFunc: onChange,
+		UpdateFunc: func(_, new interface{}) {
+			onChange(new)
+		},
+		DeleteFunc: func(_ interface{}) {
+			klog.Infof("audit policy %s deleted, fallback to the default policy", source)
+			s.policy.Store(s.fallback)
+		},
+	})
+	if err != nil {
+		klog.Errorf("failed to watch audit policy %s: %s", source, err)
+		return
+	}
+	factory.Start(stopCh)
+}
@@ -0,0 +1,155 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package auditing
+
+import (
+	"testing"
+
+	"k8s.io/apiserver/pkg/apis/audit"
+	k8srequest "k8s.io/apiserver/pkg/endpoints/request"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+)
+
+const testPolicy = `
+omitStages:
+- RequestReceived
+rules:
+- level: None
+  verbs: ["get", "list", "watch"]
+- level: RequestResponse
+  resources:
+  - group: iam.kubesphere.io
+    resources: ["users/password"]
+- level: Request
+  workspaces: ["finance"]
+  clusters: ["host"]
+- level: None
+  nonResourceURLs: ["/healthz*", "/metrics"]
+- level: Metadata
+  userGroups: ["system:authenticated"]
+  omitStages:
+  - ResponseComplete
+`
+
+func TestPolicyEvaluate(t *testing.T) {
+	policy, err := LoadPolicy([]byte(testPolicy))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	resourceRequest := func(verb, group, resource, subresource string) *request.RequestInfo {
+		return &request.RequestInfo{RequestInfo: &k8srequest.RequestInfo{
+			IsResourceRequest: true,
+			Verb:              verb,
+			APIGroup:          group,
+			Resource:          resource,
+			Subresource:       subresource,
+		}}
+	}
+	nonResourceRequest := func(verb, path string) *request.RequestInfo {
+		return &request.RequestInfo{RequestInfo: &k8srequest.RequestInfo{Verb: verb, Path: path}}
+	}
+	event := func(workspace, cluster string, groups ...string) *Event {
+		e := &Event{Workspace: workspace, Cluster: cluster}
+		e.User.Username = "admin"
+		e.User.Groups = groups
+		return e
+	}
+
+	tests := []struct {
+		name       string
+		event      *Event
+		info       *request.RequestInfo
+		level      audit.Level
+		omitStages []audit.Stage
+	}{
+		{
+			name:       "read requests are not audited",
+			event:      event("finance", "host"),
+			info:       resourceRequest("list", "", "pods", ""),
+			level:      audit.LevelNone,
+			omitStages: []audit.Stage{audit.StageRequestReceived},
+		},
+		{
+			name:       "subresource match",
+			event:      event("", "host"),
+			info:       resourceRequest("update", "iam.kubesphere.io", "users", "password"),
+			level:      audit.LevelRequestResponse,
+			omitStages: []audit.Stage{audit.StageRequestReceived},
+		},
+		{
+			name:       "workspace and cluster match",
+			event:      event("finance", "host"),
+			info:       resourceRequest("delete", "apps", "deployments", ""),
+			level:      audit.LevelRequest,
+			omitStages: []audit.Stage{audit.StageRequestReceived},
+		},
+		{
+			name:       "non-resource url match",
+			event:      event("", "host"),
+			info:       nonResourceRequest("post", "/healthz/ready"),
+			level:      audit.LevelNone,
+			omitStages: []audit.Stage{audit.StageRequestReceived},
+		},
+		{
+			name:       "user group match with omit stages merged",
+			event:      event("devops", "host", "system:authenticated"),
+			info:       resourceRequest("delete", "apps", "deployments", ""),
+			level:      audit.LevelMetadata,
+			omitStages: []audit.Stage{audit.StageRequestReceived, audit.StageResponseComplete},
+		},
+		{
+			name:       "no rule matches",
+			event:      event("devops", "member"),
+			info:       resourceRequest("delete", "apps", "deployments", ""),
+			level:      audit.LevelNone,
+			omitStages: []audit.Stage{audit.StageRequestReceived},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := policy.Evaluate(tt.event, tt.info)
+			if got.Level != tt.level {
+				t.Errorf("Evaluate() level = %v, want %v", got.Level, tt.level)
+			}
+			if len(got.OmitStages) != len(tt.omitStages) {
+				t.Fatalf("Evaluate() omitStages = %v, want %v", got.OmitStages, tt.omitStages)
+			}
+			for _, stage := range tt.omitStages {
+				if !hasStage(got.OmitStages, stage) {
+					t.Errorf("Evaluate() omitStages = %v, want %v", got.OmitStages, tt.omitStages)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  string
+		wantErr bool
+	}{
+		{"valid", testPolicy, false},
+		{"empty", "rules: []", true},
+		{"invalid level", "rules:\n- level: Everything", true},
+		{"invalid stage", "rules:\n- level: None\n  omitStages: [Panic]", true},
+		{"unknown field", "rules:\n- level: None\n  user: [admin]", true},
+		{"inner star", "rules:\n- level: None\n  nonResourceURLs: [\"/foo*bar\"]", true},
+		{"resources and non-resource urls", "rules:\n- level: None\n  nonResourceURLs: [\"/foo\"]\n  namespaces: [\"\"]", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadPolicy([]byte(tt.policy))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadPolicy() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,322 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package auditing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/klog/v2"
+)
+
+// MaskedValue replaces the value of sensitive fields.
+const MaskedValue = "******"
+
+// RedactionRule masks sensitive fields in the request and response bodies of audit events.
+type RedactionRule struct {
+	// Resources that this rule applies to.
+	Resources []GroupResources `json:"resources,omitempty" yaml:"resources,omitempty"`
+	// NonResourceURLs that this rule applies to,
+	// `*`s are allowed, but only as the full, final step in the path.
+	NonResourceURLs []string `json:"nonResourceURLs,omitempty" yaml:"nonResourceURLs,omitempty"`
+	// Paths are JSONPath expressions of the fields to mask, e.g. `$.spec.password`,
+	// `$.items[*].data.*`, `$..token` or `$.metadata.annotations['kubectl.kubernetes.io/last-applied-configuration']`.
+	// `$` masks the whole body.
+	Paths []string `json:"paths" yaml:"paths"`
+}
+
+const lastAppliedConfigPath = "$..annotations['kubectl.kubernetes.io/last-applied-configuration']"
+
+// builtinRedactionRules covers the credentials of known KubeSphere and core types.
+var builtinRedactionRules = []RedactionRule{
+	{
+		Resources: []GroupResources{{Group: "iam.kubesphere.io", Resources: []string{"users"}}},
+		Paths:     []string{"$.spec.password", "$.items[*].spec.password", lastAppliedConfigPath},
+	},
+	{
+		Resources: []GroupResources{{Group: "iam.kubesphere.io", Resources: []string{"users/password"}}},
+		Paths:     []string{"$.password", "$.currentPassword"},
+	},
+	{
+		Resources: []GroupResources{{Group: "iam.kubesphere.io", Resources: []string{"users/totp"}}},
+		Paths:     []string{"$.authKey", "$.otp", "$.currentOTP", "$.recoveryCodes"},
+	},
+	{
+		Resources: []GroupResources{
+			{Group: "resources.kubesphere.io", Resources: []string{"users/kubeconfig"}},
+			{Group: "cluster.kubesphere.io", Resources: []string{"clusters/kubeconfig"}},
+		},
+		Paths: []string{"$"},
+	},
+	{
+		Resources: []GroupResources{{Group: "cluster.kubesphere.io", Resources: []string{"clusters"}}},
+		Paths:     []string{"$.spec.connection.kubeconfig", "$.items[*].spec.connection.kubeconfig", lastAppliedConfigPath},
+	},
+	{
+		Resources: []GroupResources{{Group: "", Resources: []string{"secrets"}}},
+		Paths: []string{"$.data.*", "$.stringData.*", "$.items[*].data.*", "$.items[*].stringData.*",
+			lastAppliedConfigPath},
+	},
+	{
+		Resources: []GroupResources{{Group: "", Resources: []string{"serviceaccounts/token"}}},
+		Paths:     []string{"$.status.token"},
+	},
+	{
+		NonResourceURLs: []string{"/oauth/*"},
+		Paths: []string{"$.password", "$.client_secret", "$.code", "$.code_verifier",
+			"$.access_token", "$.refresh_token", "$.id_token", "$.token", "$.spec.token", "$.otp", "$.mfa_token",
+			"$.subject_token", "$.actor_token", "$.device_code"},
+	},
+	{
+		NonResourceURLs: []string{"/scim/*"},
+		Paths:           []string{"$.password", "$.Operations[*].value.password"},
+	},
+}
+
+type compiledRedactionRule struct {
+	RedactionRule
+	paths [][]pathSegment
+}
+
+type redactor struct {
+	rules []compiledRedactionRule
+}
+
+// newRedactor creates a redactor with the built-in rules followed by the given rules.
+func newRedactor(rules []RedactionRule) (*redactor, error) {
+	r := &redactor{}
+	for _, rule := range append(append([]RedactionRule{}, builtinRedactionRules...), rules...) {
+		compiled := compiledRedactionRule{RedactionRule: rule}
+		for _, path := range rule.Paths {
+			segments, err := parseJSONPath(path)
+			if err != nil {
+				return nil, err
+			}
+			compiled.paths = append(compiled.paths, segments)
+		}
+		r.rules = append(r.rules, compiled)
+	}
+	return r, nil
+}
+
+// ValidateRedactionRules checks whether the paths of the rules are valid JSONPath expressions.
+func ValidateRedactionRules(rules []RedactionRule) []error {
+	var errs []error
+	for i, rule := range rules {
+		if len(rule.Paths) == 0 {
+			errs = append(errs, fmt.Errorf("redactionRules[%d]: paths must be specified", i))
+		}
+		for _, path := range rule.Paths {
+			if _, err := parseJSONPath(path); err != nil {
+				errs = append(errs, fmt.Errorf("redactionRules[%d]: %v", i, err))
+			}
+		}
+	}
+	return errs
+}
+
+func (r *compiledRedactionRule) matches(e *Event) bool {
+	if e.ObjectRef != nil && e.ObjectRef.Resource != "" {
+		return groupResourcesMatch(r.Resources, e.ObjectRef.APIGroup, e.ObjectRef.Resource,
+			e.ObjectRef.Subresource, e.ObjectRef.Name)
+	}
+	for _, spec := range r.NonResourceURLs {
+		if pathMatches(e.RequestURI, spec) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact masks the sensitive fields in the request and response bodies of the event.
+// The bodies are replaced rather than modified, since they may be shared between events.
+func (r *redactor) Redact(e *Event) {
+	if e.RequestObject == nil && e.ResponseObject == nil {
+		return
+	}
+
+	var paths [][]pathSegment
+	for i := range r.rules {
+		if r.rules[i].matches(e) {
+			paths = append(paths, r.rules[i].paths...)
+		}
+	}
+	if len(paths) == 0 {
+		return
+	}
+
+	if e.RequestObject != nil {
+		e.RequestObject = &runtime.Unknown{Raw: maskBody(e.RequestObject.Raw, e.requestContentType, paths)}
+	}
+	if e.ResponseObject != nil {
+		e.ResponseObject = &runtime.Unknown{Raw: maskBody(e.ResponseObject.Raw, "", paths)}
+	}
+}
+
+var maskedBody, _ = json.Marshal(MaskedValue)
+
+// maskBody masks the fields of a JSON or form encoded body,
+// bodies in other formats are masked entirely since the fields can't be located.
+func maskBody(raw []byte, contentType string, paths [][]pathSegment) []byte {
+	for _, segments := range paths {
+		if len(segments) == 0 {
+			return maskedBody
+		}
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/x-www-form-urlencoded" {
+		values, err := url.ParseQuery(string(raw))
+		if err != nil {
+			return maskedBody
+		}
+		for _, segments := range paths {
+			if len(segments) == 1 && values.Has(segments[0].key) {
+				values.Set(segments[0].key, MaskedValue)
+			}
+		}
+		return []byte(values.Encode())
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var obj interface{}
+	if err := decoder.Decode(&obj); err != nil {
+		return maskedBody
+	}
+	for _, segments := range paths {
+		obj = maskValue(obj, segments)
+	}
+	masked, err := json.Marshal(obj)
+	if err != nil {
+		klog.Errorf("failed to encode masked audit event body: %s", err)
+		return maskedBody
+	}
+	return masked
+}
+
+// pathSegment is a step of a JSONPath expression.
+type pathSegment struct {
+	// key of the object field, empty for wildcards and indexes.
+	key string
+	// index of the array element, -1 if not an index.
+	index int
+	// wildcard matches all fields or elements.
+	wildcard bool
+	// recursive matches the segment at any depth.
+	recursive bool
+}
+
+// parseJSONPath parses the subset of JSONPath used by redaction rules:
+// `.key`, `..key`, `.*`, `[*]`, `[n]` and `['key']`.
+func parseJSONPath(path string) ([]pathSegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("invalid JSONPath %q: must start with '$'", path)
+	}
+
+	var segments []pathSegment
+	rest := path[1:]
+	for len(rest) > 0 {
+		segment := pathSegment{index: -1}
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			segment.recursive = true
+			rest = rest[2:]
+		case rest[0] == '.':
+			rest = rest[1:]
+		case rest[0] == '[':
+		default:
+			return nil, fmt.Errorf("invalid JSONPath %q: unexpected character %q", path, rest[0])
+		}
+
+		if strings.HasPrefix(rest, "[") {
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSONPath %q: missing ']'", path)
+			}
+			selector := rest[1:end]
+			rest = rest[end+1:]
+			switch {
+			case selector == "*":
+				segment.wildcard = true
+			case len(selector) >= 2 && (selector[0] == '\'' || selector[0] == '"') && selector[len(selector)-1] == selector[0]:
+				segment.key = selector[1 : len(selector)-1]
+			default:
+				index, err := strconv.Atoi(selector)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid JSONPath %q: invalid selector [%s]", path, selector)
+				}
+				segment.index = index
+			}
+		} else {
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			segment.key = rest[:end]
+			rest = rest[end:]
+			if segment.key == "*" {
+				segment.key = ""
+				segment.wildcard = true
+			} else if segment.key == "" {
+				return nil, fmt.Errorf("invalid JSONPath %q: empty field name", path)
+			}
+		}
+		segments = append(segments, segment)
+	}
+	return segments, nil
+}
+
+// maskValue replaces the values located by the segments with MaskedValue.
+func maskValue(value interface{}, segments []pathSegment) interface{} {
+	if len(segments) == 0 {
+		return MaskedValue
+	}
+
+	segment, rest := segments[0], segments[1:]
+	if segment.recursive {
+		direct := segment
+		direct.recursive = false
+		value = maskValue(value, append([]pathSegment{direct}, rest...))
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for key, child := range v {
+				v[key] = maskValue(child, segments)
+			}
+		case []interface{}:
+			for i, child := range v {
+				v[i] = maskValue(child, segments)
+			}
+		}
+		return value
+	}
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if segment.wildcard {
+			for key, child := range v {
+				v[key] = maskValue(child, rest)
+			}
+		} else if child, ok := v[segment.key]; ok && segment.index < 0 {
+			v[segment.key] = maskValue(child, rest)
+		}
+	case []interface{}:
+		if segment.wildcard {
+			for i, child := range v {
+				v[i] = maskValue(child, rest)
+			}
+		} else if segment.index >= 0 && segment.index < len(v) {
+			v[segment.index] = maskValue(v[segment.index], rest)
+		}
+	}
+	return value
+}
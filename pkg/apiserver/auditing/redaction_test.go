@@ -0,0 +1,204 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package auditing
+
+import (
+	"encoding/json"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/apis/audit"
+)
+
+func TestRedact(t *testing.T) {
+	r, err := newRedactor([]RedactionRule{{
+		Resources: []GroupResources{{Group: "apps", Resources: []string{"deployments"}}},
+		Paths:     []string{"$.spec.template.spec.containers[*].env[*].value"},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name               string
+		objectRef          *audit.ObjectReference
+		requestURI         string
+		requestContentType string
+		request            string
+		response           string
+		wantRequest        string
+		wantResponse       string
+	}{
+		{
+			name:         "user password",
+			objectRef:    &audit.ObjectReference{APIGroup: "iam.kubesphere.io", Resource: "users"},
+			request:      `{"metadata":{"name":"admin"},"spec":{"email":"admin@kubesphere.io","password":"P@88w0rd"}}`,
+			response:     `{"items":[{"spec":{"password":"$2a$10$hash"}}],"totalItems":1}`,
+			wantRequest:  `{"metadata":{"name":"admin"},"spec":{"email":"admin@kubesphere.io","password":"******"}}`,
+			wantResponse: `{"items":[{"spec":{"password":"******"}}],"totalItems":1}`,
+		},
+		{
+			name:         "reset password",
+			objectRef:    &audit.ObjectReference{APIGroup: "iam.kubesphere.io", Resource: "users", Subresource: "password"},
+			request:      `{"currentPassword":"old","password":"new"}`,
+			wantRequest:  `{"currentPassword":"******","password":"******"}`,
+			response:     `{}`,
+			wantResponse: `{}`,
+		},
+		{
+			name:         "kubeconfig",
+			objectRef:    &audit.ObjectReference{APIGroup: "resources.kubesphere.io", Resource: "users", Subresource: "kubeconfig"},
+			response:     "apiVersion: v1\nkind: Config",
+			wantResponse: `"******"`,
+		},
+		{
+			name:      "secret",
+			objectRef: &audit.ObjectReference{Resource: "secrets"},
+			request: `{"metadata":{"annotations":{"kubectl.kubernetes.io/last-applied-configuration":"{}","foo":"bar"}},` +
+				`"data":{"password":"cGFzc3dvcmQ=","user":"YWRtaW4="}}`,
+			wantRequest: `{"data":{"password":"******","user":"******"},` +
+				`"metadata":{"annotations":{"foo":"bar","kubectl.kubernetes.io/last-applied-configuration":"******"}}}`,
+		},
+		{
+			name:               "oauth token",
+			requestURI:         "/oauth/token",
+			requestContentType: "application/x-www-form-urlencoded",
+			request:            "grant_type=password&password=P%4088w0rd&username=admin",
+			response:           `{"access_token":"eyJhbGciOiJIUzI1NiJ9","expires_in":7200,"token_type":"Bearer"}`,
+			wantRequest:        "grant_type=password&password=%2A%2A%2A%2A%2A%2A&username=admin",
+			wantResponse:       `{"access_token":"******","expires_in":7200,"token_type":"Bearer"}`,
+		},
+		{
+			name:         "totp auth key",
+			objectRef:    &audit.ObjectReference{APIGroup: "iam.kubesphere.io", Resource: "users", Subresource: "totp"},
+			response:     `{"authKey":"otpauth://totp/KubeSphere:admin?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"}`,
+			wantResponse: `{"authKey":"******"}`,
+		},
+		{
+			name:         "totp bind",
+			objectRef:    &audit.ObjectReference{APIGroup: "iam.kubesphere.io", Resource: "users", Subresource: "totp"},
+			request:      `{"currentOTP":"abcd-efgh","otp":"123456"}`,
+			wantRequest:  `{"currentOTP":"******","otp":"******"}`,
+			response:     `{"recoveryCodes":["abcd-efgh","ijkl-mnop"]}`,
+			wantResponse: `{"recoveryCodes":"******"}`,
+		},
+		{
+			name:               "oauth mfa",
+			requestURI:         "/oauth/token",
+			requestContentType: "application/x-www-form-urlencoded",
+			request:            "grant_type=otp&mfa_token=abcdef&otp=123456",
+			wantRequest:        "grant_type=otp&mfa_token=%2A%2A%2A%2A%2A%2A&otp=%2A%2A%2A%2A%2A%2A",
+		},
+		{
+			name:               "oauth token exchange",
+			requestURI:         "/oauth/token",
+			requestContentType: "application/x-www-form-urlencoded",
+			request:            "actor_token=eyJhY3Rvcg&grant_type=urn%3Aietf%3Aparams%3Aoauth%3Agrant-type%3Atoken-exchange&subject_token=eyJzdWJqZWN0",
+			wantRequest:        "actor_token=%2A%2A%2A%2A%2A%2A&grant_type=urn%3Aietf%3Aparams%3Aoauth%3Agrant-type%3Atoken-exchange&subject_token=%2A%2A%2A%2A%2A%2A",
+		},
+		{
+			name:               "oauth device authorization",
+			requestURI:         "/oauth/device_authorization",
+			requestContentType: "application/x-www-form-urlencoded",
+			request:            "client_id=kubesphere",
+			wantRequest:        "client_id=kubesphere",
+			response:           `{"device_code":"GmRhmhcxhwAzkoEqiMEg","expires_in":600,"user_code":"WDJB-MJHT"}`,
+			wantResponse:       `{"device_code":"******","expires_in":600,"user_code":"WDJB-MJHT"}`,
+		},
+		{
+			name:               "oauth device code",
+			requestURI:         "/oauth/token",
+			requestContentType: "application/x-www-form-urlencoded",
+			request:            "device_code=GmRhmhcxhwAzkoEqiMEg&grant_type=urn%3Aietf%3Aparams%3Aoauth%3Agrant-type%3Adevice_code",
+			wantRequest:        "device_code=%2A%2A%2A%2A%2A%2A&grant_type=urn%3Aietf%3Aparams%3Aoauth%3Agrant-type%3Adevice_code",
+		},
+		{
+			name:               "scim user",
+			requestURI:         "/scim/v2/Users",
+			requestContentType: "application/scim+json",
+			request:            `{"password":"P@88w0rd","userName":"tester"}`,
+			wantRequest:        `{"password":"******","userName":"tester"}`,
+		},
+		{
+			name:               "scim patch user",
+			requestURI:         "/scim/v2/Users/tester",
+			requestContentType: "application/scim+json",
+			request:            `{"Operations":[{"op":"replace","value":{"active":false,"password":"P@88w0rd"}}]}`,
+			wantRequest:        `{"Operations":[{"op":"replace","value":{"active":false,"password":"******"}}]}`,
+		},
+		{
+			name:         "user defined rule",
+			objectRef:    &audit.ObjectReference{APIGroup: "apps", Resource: "deployments"},
+			request:      `{"spec":{"template":{"spec":{"containers":[{"env":[{"name":"TOKEN","value":"secret"}]}]}}}}`,
+			wantRequest:  `{"spec":{"template":{"spec":{"containers":[{"env":[{"name":"TOKEN","value":"******"}]}]}}}}`,
+			response:     `not json`,
+			wantResponse: `"******"`,
+		},
+		{
+			name:         "no rule matches",
+			objectRef:    &audit.ObjectReference{Resource: "configmaps"},
+			request:      `{"data":{"foo":"bar"}}`,
+			wantRequest:  `{"data":{"foo":"bar"}}`,
+			response:     `{"data":{"foo":"bar"}}`,
+			wantResponse: `{"data":{"foo":"bar"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &Event{requestContentType: tt.requestContentType}
+			e.ObjectRef = tt.objectRef
+			e.RequestURI = tt.requestURI
+			if tt.request != "" {
+				e.RequestObject = &runtime.Unknown{Raw: []byte(tt.request)}
+			}
+			if tt.response != "" {
+				e.ResponseObject = &runtime.Unknown{Raw: []byte(tt.response)}
+			}
+			r.Redact(e)
+			if tt.request != "" && string(e.RequestObject.Raw) != tt.wantRequest {
+				t.Errorf("Redact() request = %s, want %s", e.RequestObject.Raw, tt.wantRequest)
+			}
+			if tt.response != "" && string(e.ResponseObject.Raw) != tt.wantResponse {
+				t.Errorf("Redact() response = %s, want %s", e.ResponseObject.Raw, tt.wantResponse)
+			}
+			if _, err := json.Marshal(e); err != nil && tt.requestContentType == "" {
+				t.Errorf("failed to encode redacted event: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseJSONPath(t *testing.T) {
+	tests := []struct {
+		path    string
+		want    int
+		wantErr bool
+	}{
+		{"$", 0, false},
+		{"$.spec.password", 2, false},
+		{"$.items[*].data.*", 4, false},
+		{"$..token", 1, false},
+		{"$.metadata.annotations['kubectl.kubernetes.io/last-applied-configuration']", 3, false},
+		{"$.items[0].spec", 3, false},
+		{"spec.password", 0, true},
+		{"$.spec.", 0, true},
+		{"$.items[-1]", 0, true},
+		{"$.items[*", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := parseJSONPath(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseJSONPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.want {
+				t.Errorf("parseJSONPath() = %v, want %d segments", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,464 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package spool
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	DefaultSegmentSize = 8 * 1024 * 1024
+
+	segmentSuffix = ".seg"
+	cursorFile    = "cursor"
+	headerSize    = 8
+	maxRecordSize = 64 * 1024 * 1024
+)
+
+var (
+	ErrFull   = errors.New("spool is full")
+	ErrClosed = errors.New("spool is closed")
+)
+
+// position locates a record in the spool.
+type position struct {
+	segment uint64
+	offset  int64
+}
+
+// Spool is a write-ahead queue persisted on disk.
+// Records are appended to segment files, and removed once they are committed.
+// Each record is stored with its length and checksum, a partially written
+// record left by a crash is discarded when the spool is opened, and a corrupt
+// record found by Peek is discarded along with the records after it in the segment.
+type Spool struct {
+	mu     sync.Mutex
+	closed bool
+
+	dir         string
+	maxSize     int64
+	segmentSize int64
+
+	// ids of the segment files, in ascending order
+	segments []uint64
+	writer   *os.File
+	tail     int64
+
+	// cursor is the position of the first uncommitted record
+	cursor position
+	// peeked is the position after the records returned by the last Peek
+	peeked      position
+	peekedCount int
+
+	count int
+	size  int64
+}
+
+// Open opens the spool in the directory, the spool refuses new records
+// once the uncommitted records exceed maxSize bytes. maxSize <= 0 means no limit.
+func Open(dir string, maxSize int64) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	s := &Spool{dir: dir, maxSize: maxSize, segmentSize: DefaultSegmentSize}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Spool) load() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), segmentSuffix) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(entry.Name(), segmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		s.segments = append(s.segments, id)
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i] < s.segments[j] })
+
+	if err := s.readCursor(); err != nil {
+		return err
+	}
+
+	// remove the segments which are committed entirely
+	for len(s.segments) > 0 && s.segments[0] < s.cursor.segment {
+		if err := os.Remove(s.segmentPath(s.segments[0])); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		s.segments = s.segments[1:]
+	}
+	if len(s.segments) == 0 {
+		s.segments = []uint64{s.cursor.segment}
+	}
+	if s.cursor.segment < s.segments[0] {
+		s.cursor = position{segment: s.segments[0]}
+	}
+
+	end, err := s.recount()
+	if err != nil {
+		return err
+	}
+	s.tail = end
+
+	tail := s.segments[len(s.segments)-1]
+	writer, err := os.OpenFile(s.segmentPath(tail), os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	// discard the partially written record
+	if err = writer.Truncate(s.tail); err != nil {
+		_ = writer.Close()
+		return err
+	}
+	if _, err = writer.Seek(s.tail, io.SeekStart); err != nil {
+		_ = writer.Close()
+		return err
+	}
+	s.writer = writer
+	s.peeked = s.cursor
+	return nil
+}
+
+// recount counts the valid records from the cursor, and returns the end of the last valid record of the tail segment.
+func (s *Spool) recount() (int64, error) {
+	s.count, s.size = 0, 0
+	var end int64
+	for _, id := range s.segments {
+		offset := int64(0)
+		if id == s.cursor.segment {
+			offset = s.cursor.offset
+		}
+		count, segmentEnd, err := s.scan(id, offset)
+		if err != nil {
+			return 0, err
+		}
+		s.count += count
+		s.size += segmentEnd - offset
+		end = segmentEnd
+	}
+	return end, nil
+}
+
+// scan counts the valid records of the segment from the offset,
+// and returns the end of the last valid record.
+func (s *Spool) scan(id uint64, offset int64) (int, int64, error) {
+	f, err := os.Open(s.segmentPath(id))
+	if os.IsNotExist(err) {
+		return 0, offset, nil
+	}
+	if err != nil {
+		return 0, offset, err
+	}
+	defer f.Close()
+
+	if _, err = f.Seek(offset, io.SeekStart); err != nil {
+		return 0, offset, err
+	}
+	reader := bufio.NewReader(f)
+	count := 0
+	for {
+		record, err := readRecord(reader)
+		if err != nil {
+			return count, offset, nil
+		}
+		count++
+		offset += int64(headerSize + len(record))
+	}
+}
+
+func (s *Spool) segmentPath(id uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", id, segmentSuffix))
+}
+
+func (s *Spool) readCursor() error {
+	data, err := os.ReadFile(filepath.Join(s.dir, cursorFile))
+	if os.IsNotExist(err) {
+		if len(s.segments) > 0 {
+			s.cursor = position{segment: s.segments[0]}
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if len(data) != 16 {
+		return fmt.Errorf("invalid spool cursor in %s", s.dir)
+	}
+	s.cursor = position{
+		segment: binary.BigEndian.Uint64(data[:8]),
+		offset:  int64(binary.BigEndian.Uint64(data[8:])),
+	}
+	return nil
+}
+
+func (s *Spool) writeCursor() error {
+	data := make([]byte, 16)
+	binary.BigEndian.PutUint64(data[:8], s.cursor.segment)
+	binary.BigEndian.PutUint64(data[8:], uint64(s.cursor.offset))
+
+	tmp := filepath.Join(s.dir, cursorFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(s.dir, cursorFile))
+}
+
+func readRecord(reader io.Reader) ([]byte, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:4])
+	if length > maxRecordSize {
+		return nil, fmt.Errorf("invalid spool record length %d", length)
+	}
+	record := make([]byte, length)
+	if _, err := io.ReadFull(reader, record); err != nil {
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(record) != binary.BigEndian.Uint32(header[4:]) {
+		return nil, fmt.Errorf("spool record checksum mismatch")
+	}
+	return record, nil
+}
+
+// Append writes the records to the spool and syncs them to disk,
+// either all the records are appended or none of them.
+func (s *Spool) Append(records ...[]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	var size int64
+	for _, record := range records {
+		size += int64(headerSize + len(record))
+	}
+	if s.maxSize > 0 && s.size+size > s.maxSize {
+		return ErrFull
+	}
+
+	buf := make([]byte, 0, size)
+	header := make([]byte, headerSize)
+	for _, record := range records {
+		binary.BigEndian.PutUint32(header[:4], uint32(len(record)))
+		binary.BigEndian.PutUint32(header[4:], crc32.ChecksumIEEE(record))
+		buf = append(buf, header...)
+		buf = append(buf, record...)
+	}
+
+	if _, err := s.writer.Write(buf); err != nil {
+		// drop the partially written records
+		_ = s.writer.Truncate(s.tail)
+		_, _ = s.writer.Seek(s.tail, io.SeekStart)
+		return err
+	}
+	if err := s.writer.Sync(); err != nil {
+		return err
+	}
+	s.tail += size
+	s.size += size
+	s.count += len(records)
+
+	if s.tail >= s.segmentSize {
+		return s.rotate()
+	}
+	return nil
+}
+
+func (s *Spool) rotate() error {
+	id := s.segments[len(s.segments)-1] + 1
+	writer, err := os.OpenFile(s.segmentPath(id), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	_ = s.writer.Close()
+	s.writer = writer
+	s.tail = 0
+	s.segments = append(s.segments, id)
+	return nil
+}
+
+// Peek returns at most max records from the head of the spool without removing them,
+// the records are removed by Commit.
+func (s *Spool) Peek(max int) ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrClosed
+	}
+	var records [][]byte
+	pos := s.cursor
+	for len(records) < max {
+		f, err := os.Open(s.segmentPath(pos.segment))
+		if err != nil {
+			return nil, err
+		}
+		if _, err = f.Seek(pos.offset, io.SeekStart); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		reader := bufio.NewReader(f)
+		var readErr error
+		for len(records) < max {
+			record, err := readRecord(reader)
+			if err != nil {
+				readErr = err
+				break
+			}
+			records = append(records, record)
+			pos.offset += int64(headerSize + len(record))
+		}
+		_ = f.Close()
+
+		// the records after a corrupt or truncated record can't be located,
+		// so they are discarded, otherwise the spool would be stuck at it
+		if readErr != nil && readErr != io.EOF {
+			if err = s.discard(pos); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(records) >= max || pos.segment == s.segments[len(s.segments)-1] {
+			break
+		}
+		// move to the next segment
+		pos = position{segment: s.nextSegment(pos.segment)}
+	}
+
+	s.peeked = pos
+	s.peekedCount = len(records)
+	return records, nil
+}
+
+// discard truncates the segment at the position of the corrupt record.
+func (s *Spool) discard(pos position) error {
+	if pos.segment == s.segments[len(s.segments)-1] {
+		if err := s.writer.Truncate(pos.offset); err != nil {
+			return err
+		}
+		if _, err := s.writer.Seek(pos.offset, io.SeekStart); err != nil {
+			return err
+		}
+		s.tail = pos.offset
+	} else if err := os.Truncate(s.segmentPath(pos.segment), pos.offset); err != nil {
+		return err
+	}
+	_, err := s.recount()
+	return err
+}
+
+func (s *Spool) nextSegment(id uint64) uint64 {
+	for _, segment := range s.segments {
+		if segment > id {
+			return segment
+		}
+	}
+	return id
+}
+
+// Commit removes the records returned by the last Peek.
+func (s *Spool) Commit() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	if s.peekedCount == 0 {
+		return nil
+	}
+
+	var committed int64
+	for _, id := range s.segments {
+		if id >= s.peeked.segment {
+			break
+		}
+		info, err := os.Stat(s.segmentPath(id))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if info != nil {
+			committed += info.Size()
+		}
+		if id == s.cursor.segment {
+			committed -= s.cursor.offset
+		}
+	}
+	if s.peeked.segment == s.cursor.segment {
+		committed += s.peeked.offset - s.cursor.offset
+	} else {
+		committed += s.peeked.offset
+	}
+
+	s.cursor = s.peeked
+	if err := s.writeCursor(); err != nil {
+		return err
+	}
+
+	for len(s.segments) > 1 && s.segments[0] < s.cursor.segment {
+		if err := os.Remove(s.segmentPath(s.segments[0])); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		s.segments = s.segments[1:]
+	}
+
+	s.count -= s.peekedCount
+	s.size -= committed
+	if s.size < 0 {
+		s.size = 0
+	}
+	s.peekedCount = 0
+	return nil
+}
+
+// Len returns the number of uncommitted records.
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// Size returns the bytes of uncommitted records.
+func (s *Spool) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// Close closes the spool, the records can't be appended or read after it.
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.writer.Close()
+}
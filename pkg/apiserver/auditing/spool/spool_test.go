@@ -0,0 +1,201 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package spool
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestSpool(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.segmentSize = 64
+
+	for i := 0; i < 10; i++ {
+		if err = s.Append([]byte(fmt.Sprintf("event-%d", i))); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if s.Len() != 10 {
+		t.Fatalf("Len() = %d, want 10", s.Len())
+	}
+
+	records, err := s.Peek(4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 4 || string(records[0]) != "event-0" || string(records[3]) != "event-3" {
+		t.Fatalf("Peek() = %q", records)
+	}
+	// records are not removed before committed
+	records, _ = s.Peek(4)
+	if string(records[0]) != "event-0" {
+		t.Fatalf("Peek() = %q", records)
+	}
+	if err = s.Commit(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Len() != 6 {
+		t.Fatalf("Len() = %d, want 6", s.Len())
+	}
+	if err = s.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the uncommitted records survive a restart
+	s, err = Open(dir, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Len() != 6 {
+		t.Fatalf("Len() = %d after reopen, want 6", s.Len())
+	}
+	records, _ = s.Peek(100)
+	if len(records) != 6 || string(records[0]) != "event-4" || string(records[5]) != "event-9" {
+		t.Fatalf("Peek() = %q", records)
+	}
+	if err = s.Commit(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Len() != 0 || s.Size() != 0 {
+		t.Fatalf("Len() = %d, Size() = %d, want empty spool", s.Len(), s.Size())
+	}
+	records, _ = s.Peek(100)
+	if len(records) != 0 {
+		t.Fatalf("Peek() = %q, want empty", records)
+	}
+	_ = s.Close()
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) > 2 {
+		t.Errorf("committed segments are not removed: %v", entries)
+	}
+}
+
+func TestSpoolMaxSize(t *testing.T) {
+	s, err := Open(t.TempDir(), 2*(headerSize+5))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+
+	if err = s.Append([]byte("event"), []byte("event")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = s.Append([]byte("event")); err != ErrFull {
+		t.Fatalf("Append() error = %v, want %v", err, ErrFull)
+	}
+	_, _ = s.Peek(1)
+	if err = s.Commit(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = s.Append([]byte("event")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSpoolTruncatedRecord(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = s.Append([]byte("event-0")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// simulate a crash in the middle of writing a record
+	if _, err = s.writer.Write([]byte{0, 0, 0, 7, 1, 2}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_ = s.Close()
+
+	s, err = Open(dir, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+	if err = s.Append([]byte("event-1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records, _ := s.Peek(10)
+	if len(records) != 2 || string(records[1]) != "event-1" {
+		t.Fatalf("Peek() = %q", records)
+	}
+}
+
+func TestSpoolCorruptRecord(t *testing.T) {
+	s, err := Open(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer s.Close()
+	if err = s.Append([]byte("event-0")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// simulate a record corrupted on disk, which is followed by a valid record
+	corrupt := []byte{0, 0, 0, 7, 1, 2, 3, 4, 'e', 'v', 'e', 'n', 't', '-', 'x'}
+	if _, err = s.writer.Write(corrupt); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	s.tail += int64(len(corrupt))
+	if err = s.Append([]byte("event-1")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	records, err := s.Peek(10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(records) != 1 || string(records[0]) != "event-0" {
+		t.Fatalf("Peek() = %q", records)
+	}
+	if s.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", s.Len())
+	}
+	if err = s.Commit(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the spool isn't stuck at the corrupt record
+	if err = s.Append([]byte("event-2")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	records, _ = s.Peek(10)
+	if len(records) != 1 || string(records[0]) != "event-2" {
+		t.Fatalf("Peek() = %q", records)
+	}
+	if err = s.Commit(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Len() != 0 || s.Size() != 0 {
+		t.Fatalf("Len() = %d, Size() = %d, want empty spool", s.Len(), s.Size())
+	}
+}
+
+func TestSpoolClosed(t *testing.T) {
+	s, err := Open(t.TempDir(), 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = s.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = s.Close(); err != nil {
+		t.Fatalf("Close() error = %v on the closed spool", err)
+	}
+	if err = s.Append([]byte("event")); err != ErrClosed {
+		t.Fatalf("Append() error = %v, want %v", err, ErrClosed)
+	}
+	if _, err = s.Peek(1); err != ErrClosed {
+		t.Fatalf("Peek() error = %v, want %v", err, ErrClosed)
+	}
+}
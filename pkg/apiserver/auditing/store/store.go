@@ -0,0 +1,454 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing/internal"
+)
+
+const (
+	DefaultRetention = 7 * 24 * time.Hour
+	DefaultLimit     = 10
+
+	// events are stored in one segment file per hour
+	segmentDuration = time.Hour
+	segmentLayout   = "2006010215"
+	segmentSuffix   = ".log"
+	purgeInterval   = time.Minute
+)
+
+type Options struct {
+	// Path of the directory which stores auditing events, the store is disabled if it is empty.
+	Path string `json:"path,omitempty" yaml:"path,omitempty"`
+	// Retention is how long events are kept, older events are deleted.
+	Retention time.Duration `json:"retention,omitempty" yaml:"retention,omitempty"`
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+	if o.Retention < 0 {
+		errs = append(errs, fmt.Errorf("auditing store retention must not be negative"))
+	}
+	return errs
+}
+
+// Query filters the stored events, an empty filter matches any value.
+type Query struct {
+	Users         []string
+	Verbs         []string
+	Resources     []string
+	Workspaces    []string
+	Clusters      []string
+	ResponseCodes []int32
+	// StartTime and EndTime bound the stage timestamp of events, both inclusive.
+	StartTime time.Time
+	EndTime   time.Time
+
+	Offset int
+	Limit  int
+}
+
+type Result struct {
+	Items      []json.RawMessage `json:"items"`
+	TotalItems int               `json:"totalItems"`
+}
+
+// Interface is a local store of auditing events.
+// It receives events as an auditing backend, and serves queries of them.
+type Interface interface {
+	internal.Backend
+	// Query returns the events matching the query, the newest first.
+	Query(q *Query) (*Result, error)
+	// Start deletes the events out of the retention window periodically.
+	Start(stopCh <-chan struct{})
+}
+
+// entry indexes an event stored in a segment.
+type entry struct {
+	offset int64
+	length int
+	// unix nanoseconds of the stage timestamp
+	timestamp    int64
+	user         string
+	verb         string
+	resource     string
+	workspace    string
+	cluster      string
+	responseCode int32
+}
+
+type segment struct {
+	name    string
+	start   time.Time
+	entries []entry
+}
+
+// store appends events as json lines to hourly segment files, and keeps an index
+// of the filterable fields of every event in memory, so queries only read matched events.
+type store struct {
+	dir       string
+	retention time.Duration
+
+	mu       sync.RWMutex
+	segments []*segment
+	writer   *os.File
+	// interned field values shared by the index entries
+	strings map[string]string
+}
+
+func Open(opts *Options) (Interface, error) {
+	if err := os.MkdirAll(opts.Path, 0700); err != nil {
+		return nil, err
+	}
+	s := &store{dir: opts.Path, retention: opts.Retention, strings: map[string]string{}}
+	if s.retention == 0 {
+		s.retention = DefaultRetention
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// event holds the indexed fields of an auditing event.
+type event struct {
+	Verb      string
+	Workspace string
+	Cluster   string
+	User      struct {
+		Username string `json:"username"`
+	}
+	ObjectRef *struct {
+		Resource string
+	}
+	ResponseStatus *struct {
+		Code int32 `json:"code"`
+	}
+	StageTimestamp time.Time
+}
+
+func (s *store) newEntry(data []byte, offset int64, received time.Time) (entry, error) {
+	e := &event{}
+	if err := json.Unmarshal(data, e); err != nil {
+		return entry{}, err
+	}
+	en := entry{
+		offset:    offset,
+		length:    len(data),
+		timestamp: received.UnixNano(),
+		user:      s.intern(e.User.Username),
+		verb:      s.intern(e.Verb),
+		workspace: s.intern(e.Workspace),
+		cluster:   s.intern(e.Cluster),
+	}
+	if !e.StageTimestamp.IsZero() {
+		en.timestamp = e.StageTimestamp.UnixNano()
+	}
+	if e.ObjectRef != nil {
+		en.resource = s.intern(e.ObjectRef.Resource)
+	}
+	if e.ResponseStatus != nil {
+		en.responseCode = e.ResponseStatus.Code
+	}
+	return en, nil
+}
+
+func (s *store) intern(value string) string {
+	if v, ok := s.strings[value]; ok {
+		return v
+	}
+	s.strings[value] = value
+	return value
+}
+
+func (s *store) load() error {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		start, err := time.ParseInLocation(segmentLayout, strings.TrimSuffix(name, segmentSuffix), time.UTC)
+		if err != nil {
+			continue
+		}
+		seg := &segment{name: name, start: start}
+		if err = s.loadSegment(seg); err != nil {
+			return err
+		}
+		s.segments = append(s.segments, seg)
+	}
+	sort.Slice(s.segments, func(i, j int) bool {
+		return s.segments[i].start.Before(s.segments[j].start)
+	})
+	return nil
+}
+
+// loadSegment rebuilds the index of the segment, a partially written
+// line left by a crash is truncated.
+func (s *store) loadSegment(seg *segment) error {
+	file, err := os.OpenFile(filepath.Join(s.dir, seg.name), os.O_RDWR, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	var offset int64
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			if len(line) > 0 {
+				klog.Warningf("truncate the partially written auditing event in %s", seg.name)
+				return file.Truncate(offset)
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		data := line[:len(line)-1]
+		if en, err := s.newEntry(data, offset, seg.start); err == nil {
+			seg.entries = append(seg.entries, en)
+		}
+		offset += int64(len(line))
+	}
+}
+
+func (s *store) ProcessEvents(events ...[]byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now().UTC()
+	seg, err := s.currentSegment(now)
+	if err != nil {
+		return err
+	}
+	info, err := s.writer.Stat()
+	if err != nil {
+		return err
+	}
+	offset := info.Size()
+
+	var buf bytes.Buffer
+	var entries []entry
+	for _, data := range events {
+		en, err := s.newEntry(data, offset+int64(buf.Len()), now)
+		if err != nil {
+			klog.V(4).Infof("skip storing invalid auditing event: %s", err)
+			continue
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+		entries = append(entries, en)
+	}
+	if _, err = s.writer.Write(buf.Bytes()); err != nil {
+		// discard what may have been written, the events are retried by the delivery
+		_ = s.writer.Truncate(offset)
+		return err
+	}
+	seg.entries = append(seg.entries, entries...)
+	return nil
+}
+
+func (s *store) currentSegment(now time.Time) (*segment, error) {
+	start := now.Truncate(segmentDuration)
+	if n := len(s.segments); n > 0 && s.writer != nil && s.segments[n-1].start.Equal(start) {
+		return s.segments[n-1], nil
+	}
+
+	if s.writer != nil {
+		_ = s.writer.Close()
+		s.writer = nil
+	}
+	name := start.Format(segmentLayout) + segmentSuffix
+	writer, err := os.OpenFile(filepath.Join(s.dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	s.writer = writer
+
+	// reopen the last segment after restarts
+	if n := len(s.segments); n > 0 && s.segments[n-1].start.Equal(start) {
+		return s.segments[n-1], nil
+	}
+	seg := &segment{name: name, start: start}
+	s.segments = append(s.segments, seg)
+	return seg, nil
+}
+
+type match struct {
+	segment *segment
+	entry   *entry
+}
+
+func (s *store) Query(q *Query) (*Result, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	f := newFilter(q)
+	var matches []match
+	for _, seg := range s.segments {
+		if !q.EndTime.IsZero() && seg.start.After(q.EndTime) {
+			continue
+		}
+		for i := range seg.entries {
+			if f.matches(&seg.entries[i]) {
+				matches = append(matches, match{segment: seg, entry: &seg.entries[i]})
+			}
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].entry.timestamp > matches[j].entry.timestamp
+	})
+
+	result := &Result{Items: []json.RawMessage{}, TotalItems: len(matches)}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if q.Offset >= len(matches) {
+		return result, nil
+	}
+	matches = matches[q.Offset:]
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	files := map[string]*os.File{}
+	defer func() {
+		for _, file := range files {
+			_ = file.Close()
+		}
+	}()
+	for _, m := range matches {
+		file, ok := files[m.segment.name]
+		if !ok {
+			var err error
+			if file, err = os.Open(filepath.Join(s.dir, m.segment.name)); err != nil {
+				return nil, err
+			}
+			files[m.segment.name] = file
+		}
+		data := make([]byte, m.entry.length)
+		if _, err := file.ReadAt(data, m.entry.offset); err != nil {
+			return nil, err
+		}
+		result.Items = append(result.Items, data)
+	}
+	return result, nil
+}
+
+type filter struct {
+	users, verbs, resources, workspaces, clusters sets.Set[string]
+	responseCodes                                 sets.Set[int32]
+	start, end                                    int64
+}
+
+func newFilter(q *Query) *filter {
+	f := &filter{
+		users:         sets.New(q.Users...),
+		verbs:         sets.New(q.Verbs...),
+		resources:     sets.New(q.Resources...),
+		workspaces:    sets.New(q.Workspaces...),
+		clusters:      sets.New(q.Clusters...),
+		responseCodes: sets.New(q.ResponseCodes...),
+	}
+	if !q.StartTime.IsZero() {
+		f.start = q.StartTime.UnixNano()
+	}
+	if !q.EndTime.IsZero() {
+		f.end = q.EndTime.UnixNano()
+	}
+	return f
+}
+
+func (f *filter) matches(e *entry) bool {
+	if f.start != 0 && e.timestamp < f.start {
+		return false
+	}
+	if f.end != 0 && e.timestamp > f.end {
+		return false
+	}
+	return matchString(f.users, e.user) &&
+		matchString(f.verbs, e.verb) &&
+		matchString(f.resources, e.resource) &&
+		matchString(f.workspaces, e.workspace) &&
+		matchString(f.clusters, e.cluster) &&
+		(f.responseCodes.Len() == 0 || f.responseCodes.Has(e.responseCode))
+}
+
+func matchString(values sets.Set[string], value string) bool {
+	return values.Len() == 0 || values.Has(value)
+}
+
+func (s *store) Start(stopCh <-chan struct{}) {
+	go wait.Until(s.purge, purgeInterval, stopCh)
+}
+
+// purge deletes the segments whose events are all out of the retention window.
+func (s *store) purge() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deadline := time.Now().Add(-s.retention)
+	purged := 0
+	for len(s.segments) > 0 {
+		seg := s.segments[0]
+		if !seg.start.Add(segmentDuration).Before(deadline) {
+			break
+		}
+		if len(s.segments) == 1 && s.writer != nil {
+			_ = s.writer.Close()
+			s.writer = nil
+		}
+		if err := os.Remove(filepath.Join(s.dir, seg.name)); err != nil && !os.IsNotExist(err) {
+			klog.Errorf("failed to delete expired auditing events %s: %s", seg.name, err)
+			break
+		}
+		s.segments = s.segments[1:]
+		purged++
+	}
+	if purged > 0 {
+		s.reintern()
+	}
+}
+
+// reintern rebuilds the interned values from the remaining entries, so the
+// values only referenced by the purged segments can be released.
+func (s *store) reintern() {
+	s.strings = map[string]string{}
+	for _, seg := range s.segments {
+		for i := range seg.entries {
+			en := &seg.entries[i]
+			en.user = s.intern(en.user)
+			en.verb = s.intern(en.verb)
+			en.resource = s.intern(en.resource)
+			en.workspace = s.intern(en.workspace)
+			en.cluster = s.intern(en.cluster)
+		}
+	}
+}
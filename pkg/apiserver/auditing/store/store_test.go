@@ -0,0 +1,169 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newEvent(id, user, verb, resource, workspace string, code int, timestamp time.Time) []byte {
+	return []byte(fmt.Sprintf(`{"AuditID":%q,"Verb":%q,"Workspace":%q,"Cluster":"host","User":{"username":%q},`+
+		`"ObjectRef":{"Resource":%q},"ResponseStatus":{"code":%d},"StageTimestamp":%q}`,
+		id, verb, workspace, user, resource, code, timestamp.UTC().Format(time.RFC3339Nano)))
+}
+
+func auditIDs(t *testing.T, result *Result) []string {
+	var ids []string
+	for _, item := range result.Items {
+		e := struct{ AuditID string }{}
+		if err := json.Unmarshal(item, &e); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ids = append(ids, e.AuditID)
+	}
+	return ids
+}
+
+func TestQuery(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(&Options{Path: dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Now()
+	if err = s.ProcessEvents(
+		newEvent("1", "admin", "create", "users", "", 201, now.Add(-3*time.Minute)),
+		newEvent("2", "alice", "delete", "deployments", "ws1", 200, now.Add(-2*time.Minute)),
+		newEvent("3", "bob", "update", "deployments", "ws2", 403, now.Add(-time.Minute)),
+		[]byte(`invalid`),
+		newEvent("4", "alice", "create", "deployments", "ws1", 201, now),
+	); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		query *Query
+		total int
+		want  []string
+	}{
+		{name: "all", query: &Query{}, total: 4, want: []string{"4", "3", "2", "1"}},
+		{name: "limit and offset", query: &Query{Offset: 1, Limit: 2}, total: 4, want: []string{"3", "2"}},
+		{name: "user", query: &Query{Users: []string{"alice"}}, total: 2, want: []string{"4", "2"}},
+		{name: "workspace and verb", query: &Query{Workspaces: []string{"ws1"}, Verbs: []string{"delete"}}, total: 1, want: []string{"2"}},
+		{name: "resource and code", query: &Query{Resources: []string{"deployments"}, ResponseCodes: []int32{403}}, total: 1, want: []string{"3"}},
+		{name: "time range", query: &Query{StartTime: now.Add(-150 * time.Second), EndTime: now.Add(-30 * time.Second)}, total: 2, want: []string{"3", "2"}},
+		{name: "no match", query: &Query{Clusters: []string{"member"}}, total: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := s.Query(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.TotalItems != tt.total {
+				t.Errorf("total = %d, want %d", result.TotalItems, tt.total)
+			}
+			if got := auditIDs(t, result); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("items = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// the index is rebuilt after reopening, a partially written event is discarded
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	file, err := os.OpenFile(files[0], os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, _ = file.WriteString(`{"AuditID":"5"`)
+	_ = file.Close()
+
+	s, err = Open(&Options{Path: dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = s.ProcessEvents(newEvent("6", "admin", "get", "users", "", 200, now.Add(time.Minute))); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := s.Query(&Query{Users: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := auditIDs(t, result); fmt.Sprint(got) != "[6 1]" {
+		t.Errorf("items = %v, want [6 1]", got)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour).UTC()
+	name := old.Truncate(segmentDuration).Format(segmentLayout) + segmentSuffix
+	if err := os.WriteFile(filepath.Join(dir, name), append(newEvent("1", "admin", "get", "users", "", 200, old), '\n'), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s, err := Open(&Options{Path: dir, Retention: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = s.ProcessEvents(newEvent("2", "admin", "get", "users", "", 200, time.Now())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s.(*store).purge()
+
+	result, err := s.Query(&Query{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := auditIDs(t, result); fmt.Sprint(got) != "[2]" {
+		t.Errorf("items = %v, want [2]", got)
+	}
+	if _, err = os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+		t.Errorf("expired segment %s is not deleted", name)
+	}
+}
+
+func TestPurgeInternedValues(t *testing.T) {
+	dir := t.TempDir()
+	old := time.Now().Add(-48 * time.Hour).UTC()
+	name := old.Truncate(segmentDuration).Format(segmentLayout) + segmentSuffix
+	if err := os.WriteFile(filepath.Join(dir, name), append(newEvent("1", "jane", "delete", "roles", "", 200, old), '\n'), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	s, err := Open(&Options{Path: dir, Retention: 24 * time.Hour})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = s.ProcessEvents(newEvent("2", "admin", "get", "users", "", 200, time.Now())); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := s.(*store).strings["jane"]; !ok {
+		t.Fatalf("expected the user jane to be interned")
+	}
+
+	s.(*store).purge()
+
+	for _, value := range []string{"jane", "delete", "roles"} {
+		if _, ok := s.(*store).strings[value]; ok {
+			t.Errorf("expected %q only referenced by the purged segment to be released", value)
+		}
+	}
+	for _, value := range []string{"admin", "get", "users"} {
+		if _, ok := s.(*store).strings[value]; !ok {
+			t.Errorf("expected %q to be kept interned", value)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package syslog
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing/internal"
+)
+
+const (
+	DefaultTimeout   = time.Second * 10
+	DefaultBatchSize = 100
+	DefaultAppName   = "ks-apiserver"
+	// DefaultFacility is the "log audit" facility defined in RFC 5424.
+	DefaultFacility = 13
+
+	// severity informational
+	severity = 6
+	msgID    = "audit"
+	// RFC 5424 allows at most 6 digits of fractional seconds
+	timestampFormat = "2006-01-02T15:04:05.000000Z07:00"
+)
+
+type Options struct {
+	// Network is one of udp, tcp or tls.
+	Network string `json:"network,omitempty" yaml:"network,omitempty"`
+	// Address of the syslog server in the form of host:port.
+	Address string `json:"address,omitempty" yaml:"address,omitempty"`
+	// Facility of the messages between 0 (kern) and 23 (local7), defaults to the "log audit" facility (13) if unset.
+	Facility *int   `json:"facility,omitempty" yaml:"facility,omitempty"`
+	AppName  string `json:"appName,omitempty" yaml:"appName,omitempty"`
+	// The maximum number of events written to the connection at once.
+	BatchSize int                 `json:"batchSize,omitempty" yaml:"batchSize,omitempty"`
+	Timeout   time.Duration       `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	TLS       internal.TLSOptions `json:"tls,omitempty" yaml:"tls,omitempty"`
+}
+
+func (o *Options) Validate() []error {
+	var errs []error
+	if o.Address == "" {
+		return nil
+	}
+	switch o.Network {
+	case "", "udp", "tcp", "tls":
+	default:
+		errs = append(errs, fmt.Errorf("auditing syslog network must be one of udp, tcp or tls"))
+	}
+	if o.Facility != nil && (*o.Facility < 0 || *o.Facility > 23) {
+		errs = append(errs, fmt.Errorf("auditing syslog facility must be between 0 and 23"))
+	}
+	return errs
+}
+
+// backend sends auditing events to a syslog server as RFC 5424 messages.
+// Messages are framed by octet counting (RFC 6587) over TCP and TLS (RFC 5425),
+// and sent one per datagram over UDP (RFC 5426).
+type backend struct {
+	opts      Options
+	tlsConfig *tls.Config
+	hostname  string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+func NewBackend(opts *Options) (internal.Backend, error) {
+	b := &backend{opts: *opts}
+	if b.opts.Network == "" {
+		b.opts.Network = "tcp"
+	}
+	if b.opts.Facility == nil {
+		facility := DefaultFacility
+		b.opts.Facility = &facility
+	}
+	if b.opts.AppName == "" {
+		b.opts.AppName = DefaultAppName
+	}
+	if b.opts.BatchSize == 0 {
+		b.opts.BatchSize = DefaultBatchSize
+	}
+	if b.opts.Timeout == 0 {
+		b.opts.Timeout = DefaultTimeout
+	}
+
+	if b.opts.Network == "tls" {
+		b.opts.TLS.Enabled = true
+		tlsConfig, err := b.opts.TLS.Config()
+		if err != nil {
+			return nil, err
+		}
+		b.tlsConfig = tlsConfig
+	}
+
+	b.hostname, _ = os.Hostname()
+	if b.hostname == "" {
+		b.hostname = "-"
+	}
+	return b, nil
+}
+
+func (b *backend) ProcessEvents(events ...[]byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.conn == nil {
+		if err := b.connect(); err != nil {
+			return err
+		}
+	}
+
+	for _, chunk := range internal.Chunk(events, b.opts.BatchSize) {
+		if err := b.write(chunk); err != nil {
+			_ = b.conn.Close()
+			b.conn = nil
+			return fmt.Errorf("write syslog messages error, %s", err)
+		}
+	}
+	return nil
+}
+
+func (b *backend) connect() error {
+	dialer := &net.Dialer{Timeout: b.opts.Timeout}
+	var conn net.Conn
+	var err error
+	switch b.opts.Network {
+	case "tls":
+		conn, err = tls.DialWithDialer(dialer, "tcp", b.opts.Address, b.tlsConfig)
+	default:
+		conn, err = dialer.Dial(b.opts.Network, b.opts.Address)
+	}
+	if err != nil {
+		return fmt.Errorf("connect to syslog server %s error, %s", b.opts.Address, err)
+	}
+	b.conn = conn
+	return nil
+}
+
+func (b *backend) write(events [][]byte) error {
+	if err := b.conn.SetWriteDeadline(time.Now().Add(b.opts.Timeout)); err != nil {
+		return err
+	}
+
+	if b.opts.Network == "udp" {
+		for _, event := range events {
+			if _, err := b.conn.Write(b.format(event, time.Now())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for _, event := range events {
+		message := b.format(event, time.Now())
+		buf.WriteString(strconv.Itoa(len(message)))
+		buf.WriteByte(' ')
+		buf.Write(message)
+	}
+	_, err := b.conn.Write(buf.Bytes())
+	return err
+}
+
+// format encodes the event as the MSG of a RFC 5424 message without structured data:
+// <PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (b *backend) format(event []byte, timestamp time.Time) []byte {
+	header := fmt.Sprintf("<%d>1 %s %s %s %d %s - ",
+		*b.opts.Facility*8+severity,
+		timestamp.UTC().Format(timestampFormat),
+		b.hostname,
+		b.opts.AppName,
+		os.Getpid(),
+		msgID)
+	return append([]byte(header), event...)
+}
@@ -0,0 +1,148 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package syslog
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing/internal"
+)
+
+var messagePattern = regexp.MustCompile(`^<\d+>1 \S+ \S+ ks-apiserver \d+ audit - (.*)$`)
+
+func selfSignedCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "syslog"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// readFrame reads an octet counting framed message.
+func readFrame(reader *bufio.Reader) (string, error) {
+	length, err := reader.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(length))
+	if err != nil {
+		return "", err
+	}
+	message := make([]byte, n)
+	_, err = io.ReadFull(reader, message)
+	return string(message), err
+}
+
+func TestTLSBackend(t *testing.T) {
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{selfSignedCertificate(t)}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer listener.Close()
+
+	messages := make(chan string, 10)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		for {
+			message, err := readFrame(reader)
+			if err != nil {
+				return
+			}
+			messages <- message
+		}
+	}()
+
+	b, err := NewBackend(&Options{
+		Network:   "tls",
+		Address:   listener.Addr().String(),
+		BatchSize: 1,
+		TLS:       internal.TLSOptions{InsecureSkipVerify: true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = b.ProcessEvents([]byte(`{"AuditID":"1"}`), []byte(`{"AuditID":"2"}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, want := range []string{`{"AuditID":"1"}`, `{"AuditID":"2"}`} {
+		select {
+		case message := <-messages:
+			match := messagePattern.FindStringSubmatch(message)
+			if match == nil || match[1] != want {
+				t.Errorf("message = %q, want RFC 5424 message of %s", message, want)
+			}
+			// the log audit facility by default
+			if !strings.HasPrefix(message, "<110>1 ") {
+				t.Errorf("message = %q, want the priority of the log audit facility", message)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for message %s", want)
+		}
+	}
+}
+
+func TestUDPBackend(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	// the kern facility (0) can be selected
+	kern := 0
+	b, err := NewBackend(&Options{Network: "udp", Address: conn.LocalAddr().String(), Facility: &kern})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = b.ProcessEvents([]byte(`{"AuditID":"1"}`)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	buf := make([]byte, 4096)
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if match := messagePattern.FindStringSubmatch(string(buf[:n])); match == nil || match[1] != `{"AuditID":"1"}` {
+		t.Errorf("message = %q, want RFC 5424 message", buf[:n])
+	}
+	if !strings.HasPrefix(string(buf[:n]), "<6>1 ") {
+		t.Errorf("message = %q, want the priority of the kern facility", buf[:n])
+	}
+}
@@ -8,8 +8,8 @@ package webhook
 
 import (
 	"bytes"
-	"context"
 	"crypto/tls"
+	"fmt"
 	"net/http"
 	"time"
 
@@ -19,7 +19,6 @@ import (
 )
 
 const (
-	GetSenderTimeout  = time.Second
 	SendTimeout       = time.Second * 3
 	DefaultSendersNum = 100
 
@@ -27,31 +26,24 @@ const (
 )
 
 type backend struct {
-	url              string
-	senderCh         chan interface{}
-	client           http.Client
-	sendTimeout      time.Duration
-	getSenderTimeout time.Duration
+	url         string
+	client      http.Client
+	sendTimeout time.Duration
 }
 
-func NewBackend(url string, sendersNum int) internal.Backend {
+// NewBackend creates a backend which posts auditing events to the webhook,
+// the events are sent synchronously, concurrency is controlled by the caller.
+func NewBackend(url string) internal.Backend {
 
 	b := backend{
-		url:              url,
-		getSenderTimeout: GetSenderTimeout,
-		sendTimeout:      SendTimeout,
+		url:         url,
+		sendTimeout: SendTimeout,
 	}
 
 	if len(b.url) == 0 {
 		b.url = WebhookURL
 	}
 
-	num := sendersNum
-	if num == 0 {
-		num = DefaultSendersNum
-	}
-	b.senderCh = make(chan interface{}, num)
-
 	b.client = http.Client{
 		Transport: &http.Transport{
 			TLSClientConfig: &tls.Config{
@@ -64,67 +56,27 @@ func NewBackend(url string, sendersNum int) internal.Backend {
 	return &b
 }
 
-func (b *backend) ProcessEvents(events ...[]byte) {
-	go b.sendEvents(events...)
-}
-
-func (b *backend) sendEvents(events ...[]byte) {
-	ctx, cancel := context.WithTimeout(context.Background(), b.sendTimeout)
-	defer cancel()
-
-	stopCh := make(chan struct{})
-	skipReturnSender := false
-
-	send := func() {
-		ctx, cancel := context.WithTimeout(context.Background(), b.getSenderTimeout)
-		defer cancel()
-
-		select {
-		case <-ctx.Done():
-			klog.Error("Get auditing event sender timeout")
-			skipReturnSender = true
-			return
-		case b.senderCh <- struct{}{}:
-		}
-
-		start := time.Now()
-		defer func() {
-			stopCh <- struct{}{}
-			klog.V(8).Infof("send %d auditing events used %d", len(events), time.Since(start).Milliseconds())
-		}()
-
-		var body bytes.Buffer
-		for _, event := range events {
-			if _, err := body.Write(event); err != nil {
-				klog.Errorf("send auditing event error %s", err)
-				return
-			}
-		}
-
-		response, err := b.client.Post(b.url, "application/json", &body)
-		if err != nil {
-			klog.Errorf("send audit events error, %s", err)
-			return
-		}
-		defer response.Body.Close()
+func (b *backend) ProcessEvents(events ...[]byte) error {
+	start := time.Now()
+	defer func() {
+		klog.V(8).Infof("send %d auditing events used %d", len(events), time.Since(start).Milliseconds())
+	}()
 
-		if response.StatusCode != http.StatusOK {
-			klog.Errorf("send audit events error[%d]", response.StatusCode)
-			return
+	var body bytes.Buffer
+	for _, event := range events {
+		if _, err := body.Write(event); err != nil {
+			return fmt.Errorf("send auditing event error %s", err)
 		}
 	}
 
-	go send()
-
-	defer func() {
-		if !skipReturnSender {
-			<-b.senderCh
-		}
-	}()
+	response, err := b.client.Post(b.url, "application/json", &body)
+	if err != nil {
+		return fmt.Errorf("send audit events error, %s", err)
+	}
+	defer response.Body.Close()
 
-	select {
-	case <-ctx.Done():
-		klog.Error("send audit events timeout")
-	case <-stopCh:
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("send audit events error[%d]", response.StatusCode)
 	}
+	return nil
 }
@@ -28,12 +28,14 @@ import (
 type basicAuthenticator struct {
 	authenticator auth.PasswordAuthenticator
 	loginRecorder auth.LoginRecorder
+	totpOperator  auth.TOTPOperator
 }
 
-func NewBasicAuthenticator(authenticator auth.PasswordAuthenticator, loginRecorder auth.LoginRecorder) basictoken.Password {
+func NewBasicAuthenticator(authenticator auth.PasswordAuthenticator, loginRecorder auth.LoginRecorder, totpOperator auth.TOTPOperator) basictoken.Password {
 	return &basicAuthenticator{
 		authenticator: authenticator,
 		loginRecorder: loginRecorder,
+		totpOperator:  totpOperator,
 	}
 }
 
@@ -52,6 +54,20 @@ func (t *basicAuthenticator) AuthenticatePassword(ctx context.Context, username,
 		}
 		return nil, false, err
 	}
+	if len(authenticated.GetExtra()[iamv1beta1.ExtraPasswordResetRequired]) > 0 &&
+		!auth.IsPasswordResetRequest(ctx, authenticated.GetName()) {
+		return nil, false, auth.PasswordResetRequiredError
+	}
+	// the one-time password can't be passed by basic authentication
+	if t.totpOperator != nil {
+		enabled, err := t.totpOperator.Enabled(ctx, authenticated.GetName())
+		if err != nil {
+			return nil, false, err
+		}
+		if enabled {
+			return nil, false, auth.MFARequiredError
+		}
+	}
 	return &authenticator.Response{
 		User: &user.DefaultInfo{
 			Name:   authenticated.GetName(),
@@ -8,6 +8,7 @@ package jwt
 
 import (
 	"context"
+	"fmt"
 
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apiserver/pkg/authentication/authenticator"
@@ -18,9 +19,12 @@ import (
 	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
 	runtimecache "sigs.k8s.io/controller-runtime/pkg/cache"
 
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/oauth"
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication/token"
 	"kubesphere.io/kubesphere/pkg/models/auth"
+	"kubesphere.io/kubesphere/pkg/models/iam/group"
 	"kubesphere.io/kubesphere/pkg/utils/serviceaccount"
+	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
 )
 
 // TokenAuthenticator implements kubernetes token authenticate interface with our custom logic.
@@ -42,13 +46,24 @@ func NewTokenAuthenticator(cache runtimecache.Cache, tokenOperator auth.TokenMan
 	}
 }
 
-func (t *tokenAuthenticator) AuthenticateToken(ctx context.Context, token string) (*authenticator.Response, bool, error) {
-	verified, err := t.tokenOperator.Verify(token)
+func (t *tokenAuthenticator) AuthenticateToken(ctx context.Context, tokenStr string) (*authenticator.Response, bool, error) {
+	verified, err := t.tokenOperator.Verify(tokenStr)
 	if err != nil {
 		klog.Warning(err)
 		return nil, false, err
 	}
 
+	// the MFA token is only exchanged for tokens by the otp grant
+	if verified.TokenType == token.MFAToken {
+		return nil, false, fmt.Errorf("invalid token type %s", verified.TokenType)
+	}
+
+	// the token exchanged for other audiences
+	if verified.TokenType == token.AccessToken && len(verified.Audience) > 0 &&
+		!sliceutil.HasString(verified.Audience, token.DefaultAudience) {
+		return nil, false, fmt.Errorf("invalid token audience %v", verified.Audience)
+	}
+
 	if serviceaccount.IsServiceAccountToken(verified.Subject) {
 		if t.clusterRole == string(clusterv1alpha1.ClusterRoleHost) {
 			_, err = t.validateServiceAccount(ctx, verified)
@@ -67,6 +82,25 @@ func (t *tokenAuthenticator) AuthenticateToken(ctx context.Context, token string
 		}, true, nil
 	}
 
+	// the identity of an OAuth client issued by the client_credentials grant
+	if oauth.IsClientIdentity(verified.User.GetName()) {
+		return &authenticator.Response{
+			User: &user.DefaultInfo{
+				Name:   verified.User.GetName(),
+				Groups: []string{user.AllAuthenticated},
+				Extra:  restrictedExtra(verified.User),
+			},
+		}, true, nil
+	}
+
+	// only the TOTP of its own can be enrolled until TOTP is enabled
+	if len(verified.User.GetExtra()[iamv1beta1.ExtraMFAEnrollmentRequired]) > 0 &&
+		!auth.IsMFAEnrollmentRequest(ctx, verified.User.GetName()) {
+		return nil, false, auth.MFAEnrollmentRequiredError
+	}
+
+	authenticationMethods := verified.User.GetExtra()[iamv1beta1.ExtraAuthenticationMethods]
+	groups := []string{user.AllAuthenticated}
 	if t.clusterRole == string(clusterv1alpha1.ClusterRoleHost) {
 		userInfo := &iamv1beta1.User{}
 		if err := t.cache.Get(ctx, types.NamespacedName{Name: verified.User.GetName()}, userInfo); err != nil {
@@ -77,17 +111,45 @@ func (t *tokenAuthenticator) AuthenticateToken(ctx context.Context, token string
 		if userInfo.Status.State == iamv1beta1.UserDisabled {
 			return nil, false, auth.AccountIsNotActiveError
 		}
+		// tokens issued before enabling TOTP are no longer accepted
+		if userInfo.Annotations[iamv1beta1.TOTPAuthKeyRefAnnotation] != "" && len(authenticationMethods) == 0 {
+			return nil, false, auth.MFARequiredError
+		}
+		// only the password of its own can be changed until the password is reset
+		if userInfo.Status.State == iamv1beta1.UserPasswordResetRequired && !auth.IsPasswordResetRequest(ctx, userInfo.Name) {
+			return nil, false, auth.PasswordResetRequiredError
+		}
+		// the groups are resolved by the GroupBindings, the groups of the user are writable by the user itself
+		userGroups, err := group.ListUserGroups(ctx, t.cache, userInfo.Name)
+		if err != nil {
+			return nil, false, err
+		}
+		groups = append(groups, userGroups...)
 	}
 
 	return &authenticator.Response{
 		User: &user.DefaultInfo{
-			Name: verified.User.GetName(),
-			// TODO(wenhaozhou) Add user`s groups(can be searched by GroupBinding)
-			Groups: []string{user.AllAuthenticated},
+			Name:   verified.User.GetName(),
+			Groups: groups,
+			Extra:  restrictedExtra(verified.User),
 		},
 	}, true, nil
 }
 
+// restrictedExtra returns the extra of the token that matters to authorization.
+func restrictedExtra(verified user.Info) map[string][]string {
+	var extra map[string][]string
+	for _, key := range []string{iamv1beta1.ExtraAuthenticationMethods, iamv1beta1.ExtraWorkspaceScope} {
+		if values := verified.GetExtra()[key]; len(values) > 0 {
+			if extra == nil {
+				extra = map[string][]string{}
+			}
+			extra[key] = values
+		}
+	}
+	return extra
+}
+
 func (t *tokenAuthenticator) validateServiceAccount(ctx context.Context, verify *token.VerifiedResponse) (*corev1alpha1.ServiceAccount, error) {
 	// Ensure the relative service account exist
 	name, namespace := serviceaccount.SplitUsername(verify.Username)
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package jwt
+
+import (
+	"net/http"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/token"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+	"kubesphere.io/kubesphere/pkg/models/auth"
+)
+
+type fakeTokenOperator struct {
+	auth.TokenManagementInterface
+	verified *token.VerifiedResponse
+}
+
+func (f *fakeTokenOperator) Verify(string) (*token.VerifiedResponse, error) {
+	return f.verified, nil
+}
+
+func TestMFAEnrollmentRequired(t *testing.T) {
+	authenticator := NewTokenAuthenticator(nil, &fakeTokenOperator{verified: &token.VerifiedResponse{
+		User: &user.DefaultInfo{
+			Name:  "tester",
+			Extra: map[string][]string{iamv1beta1.ExtraMFAEnrollmentRequired: {"true"}},
+		},
+		Claims: token.Claims{TokenType: token.AccessToken},
+	}}, string(clusterv1alpha1.ClusterRoleMember))
+	resolver := &request.RequestInfoFactory{
+		APIPrefixes:          sets.New("api", "apis", "kapis", "kapi"),
+		GrouplessAPIPrefixes: sets.New("api", "kapi"),
+	}
+
+	tests := []struct {
+		method  string
+		path    string
+		allowed bool
+	}{
+		{method: http.MethodGet, path: "/kapis/iam.kubesphere.io/v1beta1/users/tester/totp", allowed: true},
+		{method: http.MethodGet, path: "/kapis/iam.kubesphere.io/v1beta1/users/tester/totp/authkey", allowed: true},
+		{method: http.MethodPost, path: "/kapis/iam.kubesphere.io/v1beta1/users/tester/totp/bind", allowed: true},
+		{method: http.MethodGet, path: "/oauth/logout", allowed: true},
+		{method: http.MethodGet, path: "/kapis/iam.kubesphere.io/v1beta1/users/admin/totp"},
+		{method: http.MethodGet, path: "/kapis/iam.kubesphere.io/v1beta1/users/tester"},
+		{method: http.MethodGet, path: "/kapis/tenant.kubesphere.io/v1beta1/workspaces"},
+		{method: http.MethodGet, path: "/api/v1/namespaces"},
+	}
+	for _, test := range tests {
+		t.Run(test.method+" "+test.path, func(t *testing.T) {
+			req, err := http.NewRequest(test.method, test.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := resolver.NewRequestInfo(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, ok, err := authenticator.AuthenticateToken(request.WithRequestInfo(req.Context(), info), "token")
+			if test.allowed && (!ok || err != nil) {
+				t.Errorf("expected the request to be allowed, got %v", err)
+			}
+			if !test.allowed && err != auth.MFAEnrollmentRequiredError {
+				t.Errorf("expected the request to be rejected, got %v", err)
+			}
+		})
+	}
+}
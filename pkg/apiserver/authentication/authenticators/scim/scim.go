@@ -0,0 +1,40 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package scim
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"k8s.io/apiserver/pkg/authentication/authenticator"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication"
+)
+
+// User is the name of the SCIM client authenticated by the bearer token,
+// it's only allowed to access the SCIM API.
+const User = "system:scim"
+
+type tokenAuthenticator struct {
+	bearerToken []byte
+}
+
+// NewTokenAuthenticator returns the authenticator of the SCIM client, which presents the dedicated
+// bearer token rather than the tokens issued by KubeSphere.
+func NewTokenAuthenticator(options authentication.SCIMOptions) authenticator.Token {
+	return &tokenAuthenticator{bearerToken: []byte(options.BearerToken)}
+}
+
+func (t *tokenAuthenticator) AuthenticateToken(_ context.Context, token string) (*authenticator.Response, bool, error) {
+	if len(t.bearerToken) == 0 || subtle.ConstantTimeCompare(t.bearerToken, []byte(token)) != 1 {
+		return nil, false, nil
+	}
+	return &authenticator.Response{
+		User: &user.DefaultInfo{Name: User},
+	}, true, nil
+}
@@ -67,6 +67,9 @@ type Configuration struct {
 
 	// The options of identify provider
 	ProviderOptions options.DynamicOptions `json:"provider" yaml:"provider"`
+
+	// The mapping from the groups of the End-User at the identity provider to the KubeSphere groups
+	GroupMapping *GroupMapping `json:"groupMapping,omitempty" yaml:"groupMapping"`
 }
 
 type ConfigurationGetter interface {
@@ -7,14 +7,28 @@
 package identityprovider
 
 import (
+	"errors"
+
 	"kubesphere.io/kubesphere/pkg/server/options"
 )
 
+// ErrorUserNotFound is returned by LookupGroups if the End-User no longer exists at the identity provider,
+// which is distinguished from the other errors such as the credentials or the connections failed.
+var ErrorUserNotFound = errors.New("the user was not found in the Identity provider")
+
 type GenericProvider interface {
 	// Authenticate from remote server
 	Authenticate(username string, password string) (Identity, error)
 }
 
+// GroupsLookupProvider is implemented by the generic providers which look up the groups of the End-User
+// without the credentials, so that the groups are synchronized periodically.
+type GroupsLookupProvider interface {
+	// LookupGroups returns the groups of the End-User identified by the user ID of Identity,
+	// ErrorUserNotFound is returned if the End-User doesn't exist.
+	LookupGroups(userID string) ([]string, error)
+}
+
 type GenericProviderFactory interface {
 	// Type unique type of the provider
 	Type() string
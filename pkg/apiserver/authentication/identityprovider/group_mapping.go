@@ -0,0 +1,114 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package identityprovider
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/klog/v2"
+)
+
+var invalidGroupNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// GroupMapping maps the groups of the End-User at the identity provider to the KubeSphere groups,
+// the users are bound to the mapped groups on login, and periodically if SyncPeriod is set.
+type GroupMapping struct {
+	// Rules to map the external groups, the external groups matched by no rule are ignored.
+	Rules []GroupMappingRule `json:"rules" yaml:"rules"`
+
+	// SyncPeriod is the interval to synchronize the groups of the mapped users without login,
+	// it only takes effect for the providers which can look up the groups, such as LDAP.
+	SyncPeriod time.Duration `json:"syncPeriod,omitempty" yaml:"syncPeriod"`
+}
+
+type GroupMappingRule struct {
+	// ExternalGroup is the group at the identity provider, e.g. the DN of an LDAP group, or the value of the
+	// groups claim. The wildcard "*" matches any sequence of characters, e.g. "cn=*,ou=groups,dc=kubesphere,dc=io".
+	ExternalGroup string `json:"externalGroup" yaml:"externalGroup"`
+
+	// Group is the name of the KubeSphere group, each "*" is replaced with the characters matched by the
+	// wildcard at the same position of the ExternalGroup, e.g. "ldap-*". Defaults to the external group.
+	// The name is converted to a valid label value, since the group is referred to by labels.
+	Group string `json:"group,omitempty" yaml:"group"`
+
+	// Workspace of the group, the group is created in the workspace if it doesn't exist.
+	Workspace string `json:"workspace,omitempty" yaml:"workspace"`
+}
+
+// MappedGroup is a KubeSphere group mapped from an external group.
+type MappedGroup struct {
+	Name          string
+	Workspace     string
+	ExternalGroup string
+}
+
+// Map returns the KubeSphere groups mapped from the external groups, each rule matching an external group maps it.
+func (m *GroupMapping) Map(externalGroups []string) []MappedGroup {
+	if m == nil {
+		return nil
+	}
+	mapped := make([]MappedGroup, 0)
+	seen := make(map[string]bool)
+	for _, rule := range m.Rules {
+		pattern, err := rule.compile()
+		if err != nil {
+			klog.Warningf("invalid group mapping rule %s: %s", rule.ExternalGroup, err)
+			continue
+		}
+		for _, externalGroup := range externalGroups {
+			matches := pattern.FindStringSubmatch(externalGroup)
+			if matches == nil {
+				continue
+			}
+			name := externalGroup
+			if rule.Group != "" {
+				name = expandWildcards(rule.Group, matches[1:])
+			}
+			name = normalizeGroupName(name)
+			if name == "" || seen[name] {
+				continue
+			}
+			seen[name] = true
+			mapped = append(mapped, MappedGroup{Name: name, Workspace: rule.Workspace, ExternalGroup: externalGroup})
+		}
+	}
+	return mapped
+}
+
+func (r GroupMappingRule) compile() (*regexp.Regexp, error) {
+	parts := strings.Split(r.ExternalGroup, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+	return regexp.Compile("^" + strings.Join(parts, "(.*)") + "$")
+}
+
+func expandWildcards(group string, matches []string) string {
+	var builder strings.Builder
+	i := 0
+	for _, c := range group {
+		if c == '*' && i < len(matches) {
+			builder.WriteString(matches[i])
+			i++
+			continue
+		}
+		builder.WriteRune(c)
+	}
+	return builder.String()
+}
+
+// normalizeGroupName converts the name to a valid DNS-1123 label, which is also a valid label value.
+func normalizeGroupName(name string) string {
+	name = invalidGroupNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	if len(name) > validation.DNS1123LabelMaxLength {
+		name = name[:validation.DNS1123LabelMaxLength]
+	}
+	return strings.Trim(name, "-")
+}
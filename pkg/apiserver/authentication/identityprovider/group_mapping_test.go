@@ -0,0 +1,57 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package identityprovider
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestGroupMapping(t *testing.T) {
+	configuration := &Configuration{}
+	err := yaml.Unmarshal([]byte(`
+name: ldap
+type: LDAPIdentityProvider
+groupMapping:
+  syncPeriod: 30m
+  rules:
+  - externalGroup: "cn=*,ou=groups,dc=kubesphere,dc=io"
+    group: "ldap-*"
+    workspace: system-workspace
+  - externalGroup: "/Platform Admins"
+    group: platform-admins
+  - externalGroup: "Developers"
+`), configuration)
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, configuration.GroupMapping.SyncPeriod)
+
+	mapped := configuration.GroupMapping.Map([]string{
+		"cn=Cluster_Admins,ou=groups,dc=kubesphere,dc=io",
+		"cn=devs,ou=people,dc=kubesphere,dc=io",
+		"/Platform Admins",
+		"Developers",
+		"Testers",
+	})
+	assert.Equal(t, []MappedGroup{
+		{Name: "ldap-cluster-admins", Workspace: "system-workspace", ExternalGroup: "cn=Cluster_Admins,ou=groups,dc=kubesphere,dc=io"},
+		{Name: "platform-admins", ExternalGroup: "/Platform Admins"},
+		{Name: "developers", ExternalGroup: "Developers"},
+	}, mapped)
+
+	// the groups mapped to the same name are deduplicated, and long names are truncated
+	mapping := &GroupMapping{Rules: []GroupMappingRule{{ExternalGroup: "*"}}}
+	mapped = mapping.Map([]string{"Admins", "admins", strings.Repeat("a", 100)})
+	assert.Len(t, mapped, 2)
+	assert.Equal(t, "admins", mapped[0].Name)
+	assert.Equal(t, strings.Repeat("a", 63), mapped[1].Name)
+
+	assert.Nil(t, (*GroupMapping)(nil).Map([]string{"admins"}))
+}
@@ -17,3 +17,10 @@ type Identity interface {
 	// GetEmail optional
 	GetEmail() string
 }
+
+// GroupsIdentity is implemented by the identities carrying the groups of the End-User at the identity provider,
+// such as the groups claim of OIDC, or the memberOf attribute of LDAP.
+type GroupsIdentity interface {
+	Identity
+	GetGroups() []string
+}
@@ -6,6 +6,8 @@
 
 package identityprovider
 
+import "fmt"
+
 var (
 	oauthProviderFactories   = make(map[string]OAuthProviderFactory)
 	genericProviderFactories = make(map[string]GenericProviderFactory)
@@ -20,3 +22,12 @@ func RegisterOAuthProviderFactory(factory OAuthProviderFactory) {
 func RegisterGenericProviderFactory(factory GenericProviderFactory) {
 	genericProviderFactories[factory.Type()] = factory
 }
+
+// NewGenericProvider creates the generic identity provider with the configuration
+func NewGenericProvider(configuration *Configuration) (GenericProvider, error) {
+	factory, ok := genericProviderFactories[configuration.Type]
+	if !ok {
+		return nil, fmt.Errorf("generic identity provider %s with type %s is not supported", configuration.Name, configuration.Type)
+	}
+	return factory.Create(configuration.ProviderOptions)
+}
@@ -10,6 +10,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/base64"
+	goerrors "errors"
 	"fmt"
 	"net"
 	"net/url"
@@ -24,6 +25,7 @@ import (
 
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication/identityprovider"
 	"kubesphere.io/kubesphere/pkg/server/options"
+	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
 )
 
 const (
@@ -92,6 +94,7 @@ func (l *ldapProviderFactory) Create(opts options.DynamicOptions) (identityprovi
 type ldapIdentity struct {
 	Username string
 	Email    string
+	Groups   []string
 }
 
 func (l *ldapIdentity) GetUserID() string {
@@ -106,6 +109,10 @@ func (l *ldapIdentity) GetEmail() string {
 	return l.Email
 }
 
+func (l *ldapIdentity) GetGroups() []string {
+	return l.Groups
+}
+
 func (l ldapProvider) Authenticate(username string, password string) (identityprovider.Identity, error) {
 	conn, err := l.newConn()
 	if err != nil {
@@ -116,15 +123,75 @@ func (l ldapProvider) Authenticate(username string, password string) (identitypr
 	conn.SetTimeout(time.Duration(l.ReadTimeout) * time.Millisecond)
 	defer conn.Close()
 
+	entry, err := l.searchUser(conn, username)
+	if err != nil {
+		if goerrors.Is(err, identityprovider.ErrorUserNotFound) {
+			return nil, errors.NewUnauthorized(err.Error())
+		}
+		return nil, err
+	}
+
+	if err = conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			klog.V(4).Infof("ldap: %v", err)
+			return nil, errors.NewUnauthorized("ldap: incorrect password")
+		}
+		klog.Error(err)
+		return nil, err
+	}
+
+	// search the groups as the manager, the user may not have the permission
 	if err = conn.Bind(l.ManagerDN, l.ManagerPassword); err != nil {
 		klog.Error(err)
 		return nil, err
 	}
+	groups, err := l.searchGroups(conn, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	email := entry.GetAttributeValue(l.MailAttribute)
+	uid := entry.GetAttributeValue(l.LoginAttribute)
+	return &ldapIdentity{
+		Username: uid,
+		Email:    email,
+		Groups:   groups,
+	}, nil
+}
+
+// LookupGroups returns the groups of the user, the user ID is the value of the login attribute.
+func (l ldapProvider) LookupGroups(userID string) ([]string, error) {
+	conn, err := l.newConn()
+	if err != nil {
+		klog.Error(err)
+		return nil, err
+	}
+
+	conn.SetTimeout(time.Duration(l.ReadTimeout) * time.Millisecond)
+	defer conn.Close()
+
+	entry, err := l.searchUser(conn, userID)
+	if err != nil {
+		return nil, err
+	}
+	return l.searchGroups(conn, entry)
+}
+
+// searchUser binds as the manager, and returns the only entry of the user.
+func (l ldapProvider) searchUser(conn *ldap.Conn, username string) (*ldap.Entry, error) {
+	if err := conn.Bind(l.ManagerDN, l.ManagerPassword); err != nil {
+		klog.Error(err)
+		return nil, err
+	}
 
 	filter := fmt.Sprintf("(%s=%s)", l.LoginAttribute, ldap.EscapeFilter(username))
 	if l.UserSearchFilter != "" {
 		filter = fmt.Sprintf("(&%s%s)", filter, l.UserSearchFilter)
 	}
+	attributes := []string{l.LoginAttribute, l.MailAttribute}
+	if l.UserMemberAttribute != "" {
+		attributes = append(attributes, l.UserMemberAttribute)
+	}
 	result, err := conn.Search(&ldap.SearchRequest{
 		BaseDN:       l.UserSearchBase,
 		Scope:        ldap.ScopeWholeSubtree,
@@ -133,7 +200,7 @@ func (l ldapProvider) Authenticate(username string, password string) (identitypr
 		TimeLimit:    0,
 		TypesOnly:    false,
 		Filter:       filter,
-		Attributes:   []string{l.LoginAttribute, l.MailAttribute},
+		Attributes:   attributes,
 	})
 	if err != nil {
 		klog.Error(err)
@@ -141,7 +208,7 @@ func (l ldapProvider) Authenticate(username string, password string) (identitypr
 	}
 
 	if len(result.Entries) == 0 {
-		return nil, errors.NewUnauthorized(fmt.Sprintf("ldap: no results returned for filter: %v", filter))
+		return nil, fmt.Errorf("ldap: no results returned for filter: %v: %w", filter, identityprovider.ErrorUserNotFound)
 	}
 
 	if len(result.Entries) > 1 {
@@ -149,21 +216,41 @@ func (l ldapProvider) Authenticate(username string, password string) (identitypr
 	}
 
 	// len(result.Entries) == 1
-	entry := result.Entries[0]
-	if err = conn.Bind(entry.DN, password); err != nil {
-		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
-			klog.V(4).Infof("ldap: %v", err)
-			return nil, errors.NewUnauthorized("ldap: incorrect password")
-		}
+	return result.Entries[0], nil
+}
+
+// searchGroups returns the DNs of the groups which the user is a member of, from the member attribute of the user,
+// e.g. memberOf, and the groups whose member attribute contains the DN of the user, e.g. member.
+func (l ldapProvider) searchGroups(conn *ldap.Conn, entry *ldap.Entry) ([]string, error) {
+	groups := make([]string, 0)
+	if l.UserMemberAttribute != "" {
+		groups = append(groups, entry.GetAttributeValues(l.UserMemberAttribute)...)
+	}
+	if l.GroupSearchBase == "" || l.GroupMemberAttribute == "" {
+		return groups, nil
+	}
+
+	filter := fmt.Sprintf("(%s=%s)", l.GroupMemberAttribute, ldap.EscapeFilter(entry.DN))
+	if l.GroupSearchFilter != "" {
+		filter = fmt.Sprintf("(&%s%s)", filter, l.GroupSearchFilter)
+	}
+	result, err := conn.Search(&ldap.SearchRequest{
+		BaseDN:       l.GroupSearchBase,
+		Scope:        ldap.ScopeWholeSubtree,
+		DerefAliases: ldap.NeverDerefAliases,
+		Filter:       filter,
+		Attributes:   []string{"dn"},
+	})
+	if err != nil {
 		klog.Error(err)
 		return nil, err
 	}
-	email := entry.GetAttributeValue(l.MailAttribute)
-	uid := entry.GetAttributeValue(l.LoginAttribute)
-	return &ldapIdentity{
-		Username: uid,
-		Email:    email,
-	}, nil
+	for _, group := range result.Entries {
+		if !sliceutil.HasString(groups, group.DN) {
+			groups = append(groups, group.DN)
+		}
+	}
+	return groups, nil
 }
 
 func (l *ldapProvider) newConn() (*ldap.Conn, error) {
@@ -8,6 +8,7 @@ package identityprovider
 
 import (
 	"net/http"
+	"time"
 
 	"kubesphere.io/kubesphere/pkg/server/options"
 )
@@ -17,6 +18,30 @@ type OAuthProvider interface {
 	IdentityExchangeCallback(req *http.Request) (Identity, error)
 }
 
+// LoginRedirector is implemented by the OAuth providers which start the login on the server side, such as SAML.
+type LoginRedirector interface {
+	// LoginRedirect returns the URL to redirect the user agent to, if the callback request doesn't carry
+	// the authentication response of the remote server. The cookies binding the login to the user agent
+	// are set to the response.
+	LoginRedirect(w http.ResponseWriter, req *http.Request) (redirectURL string, ok bool, err error)
+}
+
+// AssertedIdentity is implemented by the identities asserted by the remote server, such as SAML,
+// an assertion is only accepted once until it expires, so that a captured assertion can't be replayed.
+type AssertedIdentity interface {
+	Identity
+	// GetAssertionID returns the unique identifier of the assertion.
+	GetAssertionID() string
+	// GetAssertionExpiry returns the time the assertion expires.
+	GetAssertionExpiry() time.Time
+}
+
+// MetadataProvider is implemented by the OAuth providers which publish the metadata of KubeSphere,
+// the metadata is registered at the remote server.
+type MetadataProvider interface {
+	Metadata() ([]byte, error)
+}
+
 type OAuthProviderFactory interface {
 	// Type unique type of the provider
 	Type() string
@@ -72,6 +72,9 @@ type oidcProvider struct {
 	// Configurable key which contains the preferred username claims
 	PreferredUsernameKey string `json:"preferredUsernameKey" yaml:"preferredUsernameKey"`
 
+	// Configurable key which contains the groups claims
+	GroupsKey string `json:"groupsKey" yaml:"groupsKey"`
+
 	Provider     *oidc.Provider        `json:"-" yaml:"-"`
 	OAuth2Config *oauth2.Config        `json:"-" yaml:"-"`
 	Verifier     *oidc.IDTokenVerifier `json:"-" yaml:"-"`
@@ -107,6 +110,8 @@ type oidcIdentity struct {
 	// Its value MUST conform to the RFC 5322 [RFC5322] addr-spec syntax.
 	// The RP MUST NOT rely upon this value being unique.
 	Email string `json:"email"`
+	// Groups of the End-User at the Issuer, it's not a standard claim.
+	Groups []string `json:"groups"`
 }
 
 func (o oidcIdentity) GetUserID() string {
@@ -121,6 +126,10 @@ func (o oidcIdentity) GetEmail() string {
 	return o.Email
 }
 
+func (o oidcIdentity) GetGroups() []string {
+	return o.Groups
+}
+
 type oidcProviderFactory struct {
 }
 
@@ -290,9 +299,32 @@ func (o *oidcProvider) IdentityExchangeCallback(req *http.Request) (identityprov
 		preferredUsername, _ = claims["name"].(string)
 	}
 
+	groupsKey := "groups"
+	if o.GroupsKey != "" {
+		groupsKey = o.GroupsKey
+	}
+
 	return &oidcIdentity{
 		Sub:               subject,
 		PreferredUsername: preferredUsername,
 		Email:             email,
+		Groups:            groupsClaim(claims[groupsKey]),
 	}, nil
 }
+
+// groupsClaim returns the groups from the claim, which is either a string array or a single string.
+func groupsClaim(claim interface{}) []string {
+	switch value := claim.(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		groups := make([]string, 0, len(value))
+		for _, item := range value {
+			if group, ok := item.(string); ok {
+				groups = append(groups, group)
+			}
+		}
+		return groups
+	}
+	return nil
+}
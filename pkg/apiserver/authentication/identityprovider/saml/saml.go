@@ -0,0 +1,421 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package saml
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	gosaml "github.com/crewjam/saml"
+	"github.com/mitchellh/mapstructure"
+	dsig "github.com/russellhaering/goxmldsig"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/identityprovider"
+	"kubesphere.io/kubesphere/pkg/server/options"
+)
+
+const (
+	metadataFetchTimeout = 30 * time.Second
+
+	defaultEmailAttribute  = "email"
+	defaultGroupsAttribute = "groups"
+
+	// relayStateCookie binds the RelayState to the user agent starting the login
+	relayStateCookie = "kubesphere-saml-relay-state"
+	// relayStateMaxAge is the time the user has to log in at the identity provider
+	relayStateMaxAge = 10 * time.Minute
+)
+
+func init() {
+	identityprovider.RegisterOAuthProviderFactory(&samlProviderFactory{})
+}
+
+type samlProvider struct {
+	// EntityID is the unique identifier of KubeSphere at the identity provider,
+	// defaults to the metadata URL, which is the RedirectURL followed by /metadata.
+	EntityID string `json:"entityID" yaml:"entityID"`
+
+	// RedirectURL is the Assertion Consumer Service URL,
+	// the identity provider posts the SAML response to it, such as https://ks-console/oauth/callback/{name}.
+	RedirectURL string `json:"redirectURL" yaml:"redirectURL"`
+
+	// IDPMetadataURL is the URL of the identity provider metadata.
+	IDPMetadataURL string `json:"idpMetadataURL" yaml:"idpMetadataURL"`
+
+	// IDPMetadata is the XML of the identity provider metadata, it takes precedence over the IDPMetadataURL.
+	IDPMetadata string `json:"idpMetadata" yaml:"idpMetadata"`
+
+	// Certificate and PrivateKey are the PEM encoded RSA key pair of KubeSphere, they are published in the
+	// metadata, and used to sign the authentication requests and decrypt the encrypted assertions.
+	Certificate string `json:"certificate" yaml:"certificate"`
+	PrivateKey  string `json:"-" yaml:"privateKey"`
+
+	// RelayStateSecret signs the RelayState of the SP-initiated login, so that any replica of ks-apiserver
+	// can verify it. It's required if the Certificate and PrivateKey are not configured, otherwise the
+	// RelayState is signed with a key derived from the PrivateKey.
+	RelayStateSecret string `json:"-" yaml:"relayStateSecret"`
+
+	// SignRequest signs the authentication requests, the PrivateKey is required.
+	SignRequest bool `json:"signRequest" yaml:"signRequest"`
+
+	// AllowIDPInitiated accepts the unsolicited responses of the IdP-initiated login.
+	AllowIDPInitiated bool `json:"allowIDPInitiated" yaml:"allowIDPInitiated"`
+
+	// NameIDFormat is the format of the name identifier requested in the authentication requests.
+	NameIDFormat string `json:"nameIDFormat" yaml:"nameIDFormat"`
+
+	// UsernameAttribute is the name or the friendly name of the attribute which contains the username,
+	// the name identifier is used if it's empty.
+	UsernameAttribute string `json:"usernameAttribute" yaml:"usernameAttribute"`
+
+	// EmailAttribute is the name or the friendly name of the attribute which contains the email, defaults to email.
+	EmailAttribute string `json:"emailAttribute" yaml:"emailAttribute"`
+
+	// GroupsAttribute is the name or the friendly name of the attribute which contains the groups, defaults to groups.
+	GroupsAttribute string `json:"groupsAttribute" yaml:"groupsAttribute"`
+
+	// Used to turn off TLS certificate checks
+	InsecureSkipVerify bool `json:"insecureSkipVerify" yaml:"insecureSkipVerify"`
+
+	serviceProvider *gosaml.ServiceProvider
+	// relayStateKey signs the request ID carried by the RelayState of the SP-initiated login.
+	relayStateKey []byte
+}
+
+type samlIdentity struct {
+	NameID   string   `json:"nameID"`
+	Username string   `json:"username"`
+	Email    string   `json:"email"`
+	Groups   []string `json:"groups"`
+
+	assertionID     string
+	assertionExpiry time.Time
+}
+
+func (s samlIdentity) GetUserID() string {
+	return s.NameID
+}
+
+func (s samlIdentity) GetUsername() string {
+	return s.Username
+}
+
+func (s samlIdentity) GetEmail() string {
+	return s.Email
+}
+
+func (s samlIdentity) GetGroups() []string {
+	return s.Groups
+}
+
+func (s samlIdentity) GetAssertionID() string {
+	return s.assertionID
+}
+
+func (s samlIdentity) GetAssertionExpiry() time.Time {
+	return s.assertionExpiry
+}
+
+type samlProviderFactory struct {
+}
+
+func (f *samlProviderFactory) Type() string {
+	return "SAMLIdentityProvider"
+}
+
+func (f *samlProviderFactory) Create(opts options.DynamicOptions) (identityprovider.OAuthProvider, error) {
+	var provider samlProvider
+	if err := mapstructure.Decode(opts, &provider); err != nil {
+		return nil, err
+	}
+	if provider.EmailAttribute == "" {
+		provider.EmailAttribute = defaultEmailAttribute
+	}
+	if provider.GroupsAttribute == "" {
+		provider.GroupsAttribute = defaultGroupsAttribute
+	}
+
+	acsURL, err := url.Parse(provider.RedirectURL)
+	if err != nil || provider.RedirectURL == "" {
+		return nil, fmt.Errorf("saml: invalid redirectURL %q", provider.RedirectURL)
+	}
+	metadataURL := *acsURL
+	metadataURL.Path = strings.TrimSuffix(metadataURL.Path, "/") + "/metadata"
+
+	httpClient := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: provider.InsecureSkipVerify},
+		},
+	}
+	idpMetadata, err := provider.loadIDPMetadata(httpClient)
+	if err != nil {
+		return nil, err
+	}
+
+	sp := &gosaml.ServiceProvider{
+		EntityID:          provider.EntityID,
+		HTTPClient:        httpClient,
+		MetadataURL:       metadataURL,
+		AcsURL:            *acsURL,
+		IDPMetadata:       idpMetadata,
+		AuthnNameIDFormat: gosaml.NameIDFormat(provider.NameIDFormat),
+		AllowIDPInitiated: provider.AllowIDPInitiated,
+	}
+	if provider.Certificate != "" || provider.PrivateKey != "" {
+		if sp.Certificate, sp.Key, err = parseKeyPair(provider.Certificate, provider.PrivateKey); err != nil {
+			return nil, err
+		}
+	}
+	// the request ID is signed with the configured key, so that any replica of ks-apiserver can verify it
+	switch {
+	case provider.RelayStateSecret != "":
+		digest := sha256.Sum256([]byte(provider.RelayStateSecret))
+		provider.relayStateKey = digest[:]
+	case sp.Key != nil:
+		digest := sha256.Sum256(x509.MarshalPKCS1PrivateKey(sp.Key))
+		provider.relayStateKey = digest[:]
+	default:
+		return nil, errors.New("saml: either the certificate and privateKey or the relayStateSecret is required")
+	}
+	if provider.SignRequest {
+		if sp.Key == nil {
+			return nil, errors.New("saml: the privateKey is required to sign the authentication requests")
+		}
+		sp.SignatureMethod = dsig.RSASHA256SignatureMethod
+	}
+	provider.serviceProvider = sp
+	return &provider, nil
+}
+
+// IdentityExchangeCallback handles the SAML response posted to the Assertion Consumer Service,
+// see also https://docs.oasis-open.org/security/saml/v2.0/saml-bindings-2.0-os.pdf section 3.5
+func (s *samlProvider) IdentityExchangeCallback(req *http.Request) (identityprovider.Identity, error) {
+	if err := req.ParseForm(); err != nil {
+		return nil, fmt.Errorf("saml: failed to parse form: %v", err)
+	}
+	if req.PostForm.Get("SAMLResponse") == "" {
+		return nil, errors.New("saml: the SAMLResponse is missing")
+	}
+
+	var possibleRequestIDs []string
+	// the response is only accepted in response to the request sent by the same user agent
+	if requestID, ok := s.verifyRelayState(req.PostForm.Get("RelayState")); ok {
+		if cookie, err := req.Cookie(relayStateCookie); err == nil &&
+			hmac.Equal([]byte(cookie.Value), []byte(req.PostForm.Get("RelayState"))) {
+			possibleRequestIDs = append(possibleRequestIDs, requestID)
+		}
+	}
+	assertion, err := s.serviceProvider.ParseResponse(req, possibleRequestIDs)
+	if err != nil {
+		var invalidResponseError *gosaml.InvalidResponseError
+		if errors.As(err, &invalidResponseError) {
+			return nil, fmt.Errorf("saml: invalid response: %v", invalidResponseError.PrivateErr)
+		}
+		return nil, fmt.Errorf("saml: invalid response: %v", err)
+	}
+	return s.identityFrom(assertion)
+}
+
+// LoginRedirect starts the SP-initiated login, the user agent is redirected to the identity provider
+// with the authentication request if the callback request doesn't carry the SAML response.
+func (s *samlProvider) LoginRedirect(w http.ResponseWriter, req *http.Request) (string, bool, error) {
+	if req.Method != http.MethodGet || req.URL.Query().Get("SAMLResponse") != "" {
+		return "", false, nil
+	}
+	sp := s.serviceProvider
+	authnRequest, err := sp.MakeAuthenticationRequest(sp.GetSSOBindingLocation(gosaml.HTTPRedirectBinding),
+		gosaml.HTTPRedirectBinding, gosaml.HTTPPostBinding)
+	if err != nil {
+		return "", false, fmt.Errorf("saml: failed to make authentication request: %v", err)
+	}
+	relayState := s.signRelayState(authnRequest.ID)
+	redirectURL, err := authnRequest.Redirect(relayState, sp)
+	if err != nil {
+		return "", false, fmt.Errorf("saml: failed to make authentication request: %v", err)
+	}
+	// the response is posted by the identity provider across the sites
+	secure := sp.AcsURL.Scheme == "https"
+	sameSite := http.SameSiteLaxMode
+	if secure {
+		sameSite = http.SameSiteNoneMode
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     relayStateCookie,
+		Value:    relayState,
+		Path:     sp.AcsURL.Path,
+		MaxAge:   int(relayStateMaxAge.Seconds()),
+		Secure:   secure,
+		HttpOnly: true,
+		SameSite: sameSite,
+	})
+	return redirectURL.String(), true, nil
+}
+
+// Metadata returns the service provider metadata, which is registered at the identity provider.
+func (s *samlProvider) Metadata() ([]byte, error) {
+	data, err := xml.MarshalIndent(s.serviceProvider.Metadata(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("saml: failed to marshal metadata: %v", err)
+	}
+	return data, nil
+}
+
+func (s *samlProvider) identityFrom(assertion *gosaml.Assertion) (identityprovider.Identity, error) {
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" {
+		return nil, errors.New("saml: the name identifier is missing")
+	}
+	identity := &samlIdentity{
+		NameID:          assertion.Subject.NameID.Value,
+		Username:        assertion.Subject.NameID.Value,
+		assertionID:     assertion.ID,
+		assertionExpiry: assertionExpiry(assertion),
+	}
+	for _, statement := range assertion.AttributeStatements {
+		for _, attribute := range statement.Attributes {
+			if len(attribute.Values) == 0 {
+				continue
+			}
+			switch {
+			case s.UsernameAttribute != "" && matchAttribute(attribute, s.UsernameAttribute):
+				identity.Username = attribute.Values[0].Value
+			case matchAttribute(attribute, s.EmailAttribute):
+				identity.Email = attribute.Values[0].Value
+			case matchAttribute(attribute, s.GroupsAttribute):
+				for _, value := range attribute.Values {
+					identity.Groups = append(identity.Groups, value.Value)
+				}
+			}
+		}
+	}
+	return identity, nil
+}
+
+func (s *samlProvider) loadIDPMetadata(httpClient *http.Client) (*gosaml.EntityDescriptor, error) {
+	if s.IDPMetadata != "" {
+		return parseMetadata([]byte(s.IDPMetadata))
+	}
+	if s.IDPMetadataURL == "" {
+		return nil, errors.New("saml: either idpMetadata or idpMetadataURL is required")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), metadataFetchTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.IDPMetadataURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("saml: invalid idpMetadataURL: %v", err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("saml: failed to fetch idp metadata: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("saml: failed to fetch idp metadata: unexpected status %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("saml: failed to fetch idp metadata: %v", err)
+	}
+	return parseMetadata(data)
+}
+
+// signRelayState binds the request ID to the RelayState, the SAML response is
+// accepted only if it's in response to the request ID carried by the RelayState.
+func (s *samlProvider) signRelayState(requestID string) string {
+	mac := hmac.New(sha256.New, s.relayStateKey)
+	mac.Write([]byte(requestID))
+	return requestID + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *samlProvider) verifyRelayState(relayState string) (string, bool) {
+	requestID, _, found := strings.Cut(relayState, ".")
+	if !found || !hmac.Equal([]byte(s.signRelayState(requestID)), []byte(relayState)) {
+		return "", false
+	}
+	return requestID, true
+}
+
+// assertionExpiry returns the latest time the assertion is valid until.
+func assertionExpiry(assertion *gosaml.Assertion) time.Time {
+	expiry := assertion.IssueInstant.Add(gosaml.MaxIssueDelay)
+	if assertion.Conditions != nil && assertion.Conditions.NotOnOrAfter.After(expiry) {
+		expiry = assertion.Conditions.NotOnOrAfter
+	}
+	for _, confirmation := range assertion.Subject.SubjectConfirmations {
+		if data := confirmation.SubjectConfirmationData; data != nil && data.NotOnOrAfter.After(expiry) {
+			expiry = data.NotOnOrAfter
+		}
+	}
+	// the assertion is accepted within the clock skew after it expires
+	return expiry.Add(gosaml.MaxClockSkew)
+}
+
+func matchAttribute(attribute gosaml.Attribute, name string) bool {
+	return attribute.Name == name || attribute.FriendlyName == name
+}
+
+// parseMetadata parses the identity provider metadata, which is either an EntityDescriptor,
+// or an EntitiesDescriptor containing the EntityDescriptor of the identity provider.
+func parseMetadata(data []byte) (*gosaml.EntityDescriptor, error) {
+	entity := &gosaml.EntityDescriptor{}
+	err := xml.Unmarshal(data, entity)
+	if err == nil {
+		return entity, nil
+	}
+	entities := &gosaml.EntitiesDescriptor{}
+	if xml.Unmarshal(data, entities) != nil {
+		return nil, fmt.Errorf("saml: failed to parse idp metadata: %v", err)
+	}
+	for i, e := range entities.EntityDescriptors {
+		if len(e.IDPSSODescriptors) > 0 {
+			return &entities.EntityDescriptors[i], nil
+		}
+	}
+	return nil, errors.New("saml: no entity found with IDPSSODescriptor")
+}
+
+func parseKeyPair(certificateData, privateKeyData string) (*x509.Certificate, *rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(certificateData))
+	if block == nil {
+		return nil, nil, errors.New("saml: certificate not in pem format")
+	}
+	certificate, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("saml: failed to parse certificate: %v", err)
+	}
+	block, _ = pem.Decode([]byte(privateKeyData))
+	if block == nil {
+		return nil, nil, errors.New("saml: private key not in pem format")
+	}
+	var privateKey interface{}
+	if block.Type == "RSA PRIVATE KEY" {
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	} else {
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("saml: failed to parse private key: %v", err)
+	}
+	rsaPrivateKey, ok := privateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, nil, errors.New("saml: the private key must be an RSA key")
+	}
+	return certificate, rsaPrivateKey, nil
+}
@@ -0,0 +1,258 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package saml
+
+import (
+	cryptorand "crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"encoding/xml"
+	"html"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	gosaml "github.com/crewjam/saml"
+	"github.com/stretchr/testify/assert"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/identityprovider"
+	"kubesphere.io/kubesphere/pkg/server/options"
+)
+
+const callbackURL = "https://ks-console.kubesphere.io/oauth/callback/saml"
+
+var samlResponsePattern = regexp.MustCompile(`name="SAMLResponse" value="([^"]+)"`)
+
+type fakeServiceProviders map[string]*gosaml.EntityDescriptor
+
+func (f fakeServiceProviders) GetServiceProvider(_ *http.Request, serviceProviderID string) (*gosaml.EntityDescriptor, error) {
+	if sp, ok := f[serviceProviderID]; ok {
+		return sp, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+type fakeSessions struct {
+	session *gosaml.Session
+}
+
+func (f fakeSessions) GetSession(_ http.ResponseWriter, _ *http.Request, _ *gosaml.IdpAuthnRequest) *gosaml.Session {
+	return f.session
+}
+
+func newKeyPair(t *testing.T, commonName string) (*rsa.PrivateKey, *x509.Certificate) {
+	privateKey, err := rsa.GenerateKey(cryptorand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(cryptorand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return privateKey, certificate
+}
+
+// newTestProviders returns a SAML provider of KubeSphere, and the identity provider it trusts.
+func newTestProviders(t *testing.T, opts options.DynamicOptions) (*samlProvider, *gosaml.IdentityProvider) {
+	idpKey, idpCertificate := newKeyPair(t, "idp.kubesphere.io")
+	idp := &gosaml.IdentityProvider{
+		Key:         idpKey,
+		Certificate: idpCertificate,
+		MetadataURL: url.URL{Scheme: "https", Host: "idp.kubesphere.io", Path: "/metadata"},
+		SSOURL:      url.URL{Scheme: "https", Host: "idp.kubesphere.io", Path: "/sso"},
+		SessionProvider: fakeSessions{session: &gosaml.Session{
+			ID:         "session",
+			CreateTime: time.Now(),
+			ExpireTime: time.Now().Add(time.Hour),
+			Index:      "1",
+			NameID:     "jane.doe@kubesphere.io",
+			UserName:   "jane",
+			UserEmail:  "jane.doe@kubesphere.io",
+			Groups:     []string{"developers", "admins"},
+		}},
+	}
+	idpMetadata, err := xml.Marshal(idp.Metadata())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts["redirectURL"] = callbackURL
+	opts["idpMetadata"] = string(idpMetadata)
+	if _, ok := opts["relayStateSecret"]; !ok {
+		spKey, spCertificate := newKeyPair(t, "ks-console.kubesphere.io")
+		opts["certificate"] = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: spCertificate.Raw}))
+		opts["privateKey"] = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(spKey)}))
+	}
+	opts["usernameAttribute"] = "uid"
+	opts["emailAttribute"] = "eduPersonPrincipalName"
+	opts["groupsAttribute"] = "eduPersonAffiliation"
+
+	provider, err := (&samlProviderFactory{}).Create(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp := provider.(*samlProvider)
+	spMetadata, err := sp.Metadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	entity, err := parseMetadata(spMetadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp.ServiceProviderProvider = fakeServiceProviders{entity.EntityID: entity}
+	return sp, idp
+}
+
+func postResponse(samlResponse, relayState string, cookies ...*http.Cookie) *http.Request {
+	form := url.Values{"SAMLResponse": {samlResponse}, "RelayState": {relayState}}
+	req := httptest.NewRequest(http.MethodPost, callbackURL, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	return req
+}
+
+// startLogin starts the SP-initiated login, and returns the response of the identity provider to be posted
+// to the callback, and the cookies set by the login redirect.
+func startLogin(t *testing.T, sp *samlProvider, idp *gosaml.IdentityProvider) (gosaml.IdpAuthnRequestForm, []*http.Cookie) {
+	recorder := httptest.NewRecorder()
+	redirectURL, redirect, err := sp.LoginRedirect(recorder, httptest.NewRequest(http.MethodGet, callbackURL, nil))
+	assert.NoError(t, err)
+	assert.True(t, redirect)
+	assert.True(t, strings.HasPrefix(redirectURL, idp.SSOURL.String()))
+
+	authnRequest, err := gosaml.NewIdpAuthnRequest(idp, httptest.NewRequest(http.MethodGet, redirectURL, nil))
+	assert.NoError(t, err)
+	assert.NoError(t, authnRequest.Validate())
+	session := idp.SessionProvider.GetSession(nil, nil, authnRequest)
+	assert.NoError(t, gosaml.DefaultAssertionMaker{}.MakeAssertion(authnRequest, session))
+	form, err := authnRequest.PostBinding()
+	assert.NoError(t, err)
+	assert.Equal(t, callbackURL, form.URL)
+
+	if sp.serviceProvider.Key != nil {
+		// the assertion is encrypted with the certificate in the metadata
+		assert.NotNil(t, authnRequest.ResponseEl.FindElement(".//EncryptedAssertion"))
+	}
+	return form, recorder.Result().Cookies()
+}
+
+func assertIdentity(t *testing.T, identity identityprovider.Identity) {
+	assert.Equal(t, "jane.doe@kubesphere.io", identity.GetUserID())
+	assert.Equal(t, "jane", identity.GetUsername())
+	assert.Equal(t, "jane.doe@kubesphere.io", identity.GetEmail())
+	assert.Equal(t, []string{"developers", "admins"}, identity.(*samlIdentity).GetGroups())
+}
+
+func TestSPInitiatedLogin(t *testing.T) {
+	sp, idp := newTestProviders(t, options.DynamicOptions{"signRequest": true})
+	form, cookies := startLogin(t, sp, idp)
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, relayStateCookie, cookies[0].Name)
+	assert.True(t, cookies[0].Secure)
+	assert.Equal(t, http.SameSiteNoneMode, cookies[0].SameSite)
+
+	identity, err := sp.IdentityExchangeCallback(postResponse(form.SAMLResponse, form.RelayState, cookies...))
+	assert.NoError(t, err)
+	assertIdentity(t, identity)
+	asserted := identity.(identityprovider.AssertedIdentity)
+	assert.NotEmpty(t, asserted.GetAssertionID())
+	assert.True(t, asserted.GetAssertionExpiry().After(time.Now()))
+
+	// the response isn't accepted without the RelayState binding the request ID
+	_, err = sp.IdentityExchangeCallback(postResponse(form.SAMLResponse, "forged", cookies...))
+	assert.Error(t, err)
+
+	// the response isn't accepted by another user agent
+	_, err = sp.IdentityExchangeCallback(postResponse(form.SAMLResponse, form.RelayState))
+	assert.Error(t, err)
+
+	// the callback request carrying the response isn't redirected
+	_, redirect, err := sp.LoginRedirect(httptest.NewRecorder(), postResponse(form.SAMLResponse, form.RelayState))
+	assert.NoError(t, err)
+	assert.False(t, redirect)
+}
+
+func TestRelayStateSecret(t *testing.T) {
+	sp, idp := newTestProviders(t, options.DynamicOptions{"relayStateSecret": "secret"})
+	form, cookies := startLogin(t, sp, idp)
+
+	// the RelayState signed by a replica is verified by another replica sharing the secret
+	another, err := (&samlProviderFactory{}).Create(options.DynamicOptions{
+		"redirectURL":       callbackURL,
+		"idpMetadata":       sp.IDPMetadata,
+		"relayStateSecret":  "secret",
+		"usernameAttribute": "uid",
+		"emailAttribute":    "eduPersonPrincipalName",
+		"groupsAttribute":   "eduPersonAffiliation",
+	})
+	assert.NoError(t, err)
+	identity, err := another.IdentityExchangeCallback(postResponse(form.SAMLResponse, form.RelayState, cookies...))
+	assert.NoError(t, err)
+	assertIdentity(t, identity)
+
+	// either the key pair or the secret is required to sign the RelayState
+	_, err = (&samlProviderFactory{}).Create(options.DynamicOptions{"redirectURL": callbackURL, "idpMetadata": sp.IDPMetadata})
+	assert.Error(t, err)
+}
+
+func TestIDPInitiatedLogin(t *testing.T) {
+	for _, allowed := range []bool{true, false} {
+		sp, idp := newTestProviders(t, options.DynamicOptions{"allowIDPInitiated": allowed})
+
+		recorder := httptest.NewRecorder()
+		idp.ServeIDPInitiated(recorder, httptest.NewRequest(http.MethodGet, idp.SSOURL.String(), nil),
+			sp.serviceProvider.Metadata().EntityID, "")
+		matches := samlResponsePattern.FindStringSubmatch(recorder.Body.String())
+		if len(matches) != 2 {
+			t.Fatalf("unexpected idp response: %s", recorder.Body.String())
+		}
+
+		identity, err := sp.IdentityExchangeCallback(postResponse(html.UnescapeString(matches[1]), ""))
+		if !allowed {
+			assert.Error(t, err)
+			continue
+		}
+		assert.NoError(t, err)
+		assertIdentity(t, identity)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	sp, _ := newTestProviders(t, options.DynamicOptions{})
+	data, err := sp.Metadata()
+	assert.NoError(t, err)
+
+	metadata := &gosaml.EntityDescriptor{}
+	assert.NoError(t, xml.Unmarshal(data, metadata))
+	assert.Equal(t, callbackURL+"/metadata", metadata.EntityID)
+	assert.Equal(t, callbackURL, metadata.SPSSODescriptors[0].AssertionConsumerServices[0].Location)
+	assert.NotEmpty(t, metadata.SPSSODescriptors[0].KeyDescriptors)
+
+	_, err = (&samlProviderFactory{}).Create(options.DynamicOptions{"redirectURL": callbackURL})
+	assert.Error(t, err)
+}
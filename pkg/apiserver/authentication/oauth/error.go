@@ -73,6 +73,42 @@ const (
 	// Error HTTP status code cannot be returned to the client
 	// via an HTTP redirect.)
 	ServerError ErrorType = "server_error"
+
+	// MFARequired
+	// The End-User has been authenticated by the first factor, the client must continue
+	// with the otp grant, passing the mfa_token of the response and a one-time password.
+	MFARequired ErrorType = "mfa_required"
+
+	// InvalidTarget
+	// The authorization server is unwilling or unable to issue a token for the requested audience,
+	// scope or workspace, https://datatracker.ietf.org/doc/html/rfc8693#section-2.2.2
+	InvalidTarget ErrorType = "invalid_target"
+
+	// UnsupportedTokenType
+	// The authorization server does not support the revocation of the presented token type,
+	// https://datatracker.ietf.org/doc/html/rfc7009#section-2.2.1
+	UnsupportedTokenType ErrorType = "unsupported_token_type"
+
+	// The following error types are defined in https://datatracker.ietf.org/doc/html/rfc8628#section-3.5
+
+	// AuthorizationPending
+	// The authorization request is still pending as the end user hasn't
+	// yet completed the user-interaction steps.
+	AuthorizationPending ErrorType = "authorization_pending"
+
+	// SlowDown
+	// A variant of "authorization_pending", the authorization request is
+	// still pending and polling should continue, but the interval MUST
+	// be increased by 5 seconds for this and all subsequent requests.
+	SlowDown ErrorType = "slow_down"
+
+	// AccessDenied
+	// The authorization request was denied.
+	AccessDenied ErrorType = "access_denied"
+
+	// ExpiredToken
+	// The "device_code" has expired, and the device authorization session has concluded.
+	ExpiredToken ErrorType = "expired_token"
 )
 
 func NewError(errorType ErrorType, description string) *Error {
@@ -18,6 +18,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	errorsutil "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/klog/v2"
+	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"kubesphere.io/kubesphere/pkg/constants"
@@ -29,6 +30,7 @@ const (
 	GrantMethodPrompt = "prompt"
 	GrantMethodDeny   = "deny"
 
+	ClientIdentityPrefix  = "kubesphere:oauthclient:"
 	ConfigTypeOAuthClient = "oauthclient"
 	SecretTypeOAuthClient = "config.kubesphere.io/" + ConfigTypeOAuthClient
 	SecretDataKey         = "configuration.yaml"
@@ -53,6 +55,13 @@ type Client struct {
 	// Trusted indicates whether the client is considered a trusted client.
 	Trusted bool `json:"trusted" yaml:"trusted"`
 
+	// Public indicates the client can't keep the secret confidential, such as a CLI or a single-page application.
+	// Public clients are allowed to omit the client_secret, and must use PKCE (RFC 7636) with the authorization code grant.
+	Public bool `json:"public,omitempty" yaml:"public,omitempty"`
+
+	// ClientCredentials enables the client_credentials grant for the client, it's disabled if not specified.
+	ClientCredentials *ClientCredentials `json:"clientCredentials,omitempty" yaml:"clientCredentials,omitempty"`
+
 	// GrantMethod determines how grant requests for this client should be handled. If no method is provided,
 	// the cluster default grant handling method will be used. Valid grant handling methods are:
 	//   - auto: Always approves grant requests, useful for trusted clients.
@@ -81,6 +90,28 @@ type Client struct {
 	AccessTokenInactivityTimeoutSeconds int64 `json:"accessTokenInactivityTimeoutSeconds,omitempty" yaml:"accessTokenInactivityTimeoutSeconds,omitempty"`
 }
 
+// ClientCredentials determines the identity of the tokens issued by the client_credentials grant.
+type ClientCredentials struct {
+	// ServiceAccount is the KubeSphere ServiceAccount the tokens are issued to, in the format of namespace/name.
+	// The tokens are issued to the identity of the client itself, kubesphere:oauthclient:<name>,
+	// if it's not specified, the identity can be granted roles as a user.
+	ServiceAccount string `json:"serviceAccount,omitempty" yaml:"serviceAccount,omitempty"`
+}
+
+// ClientIdentity returns the username of the tokens issued to the client by the client_credentials grant.
+func (c *Client) ClientIdentity() string {
+	if c.ClientCredentials != nil && c.ClientCredentials.ServiceAccount != "" {
+		namespace, name, _ := strings.Cut(c.ClientCredentials.ServiceAccount, "/")
+		return fmt.Sprintf(corev1alpha1.ServiceAccountTokenSubFormat, namespace, name)
+	}
+	return ClientIdentityPrefix + c.Name
+}
+
+// IsClientIdentity checks whether the username is the identity of an OAuth client.
+func IsClientIdentity(username string) bool {
+	return strings.HasPrefix(username, ClientIdentityPrefix)
+}
+
 type ClientGetter interface {
 	GetOAuthClient(ctx context.Context, name string) (*Client, error)
 	ListOAuthClients(ctx context.Context) ([]*Client, error)
@@ -152,6 +183,18 @@ func ValidateClient(client Client) error {
 		validationErrors = append(validationErrors, fmt.Errorf("invalid access token max age: %d, the minimum value can only be 600", client.AccessTokenMaxAgeSeconds))
 	}
 
+	// Validate client credentials.
+	if client.ClientCredentials != nil {
+		if client.Public {
+			validationErrors = append(validationErrors, fmt.Errorf("public clients can't use client credentials"))
+		}
+		if sa := client.ClientCredentials.ServiceAccount; sa != "" {
+			if namespace, name, found := strings.Cut(sa, "/"); !found || namespace == "" || name == "" {
+				validationErrors = append(validationErrors, fmt.Errorf("invalid service account: %s, the format is namespace/name", sa))
+			}
+		}
+	}
+
 	// Aggregate validation errors and return.
 	return errorsutil.NewAggregate(validationErrors)
 }
@@ -29,6 +29,15 @@ const (
 	GrantTypeCode              = "code"
 	GrantTypeAuthorizationCode = "authorization_code"
 	GrantTypeOTP               = "otp"
+	GrantTypeClientCredentials = "client_credentials"
+	// GrantTypeTokenExchange https://datatracker.ietf.org/doc/html/rfc8693#section-2.1
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	// TokenTypeAccessToken and TokenTypeJWT are the token type identifiers of token exchange,
+	// https://datatracker.ietf.org/doc/html/rfc8693#section-3
+	TokenTypeAccessToken = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeJWT         = "urn:ietf:params:oauth:token-type:jwt"
+	// GrantTypeDeviceCode https://datatracker.ietf.org/doc/html/rfc8628#section-3.4
+	GrantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
 )
 
 var ValidScopes = []string{ScopeOpenID, ScopeEmail, ScopeProfile}
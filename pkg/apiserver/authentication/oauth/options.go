@@ -62,6 +62,39 @@ type IssuerOptions struct {
 	// This should be values of a few seconds, and we don’t recommend using more than 30 seconds for this purpose,
 	// as this would rather indicate problems with the server, rather than a common clock skew.
 	MaximumClockSkew time.Duration `json:"maximumClockSkew" yaml:"maximumClockSkew"`
+
+	// Keyring rotates the keys signing the tokens, the keys are stored in Secrets.
+	Keyring KeyringOptions `json:"keyring,omitempty" yaml:"keyring,omitempty"`
+}
+
+// DefaultKeyGracePeriod is longer than the default lifetime of the tokens.
+const DefaultKeyGracePeriod = 24 * time.Hour
+
+type KeyringOptions struct {
+	// Algorithm of the generated signing keys, RS256, ES256 or EdDSA, defaults to RS256.
+	Algorithm string `json:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+
+	// RotationPeriod is the time a key signs tokens before a new key is generated,
+	// zero disables the rotation, the keys can still be rotated manually.
+	RotationPeriod time.Duration `json:"rotationPeriod,omitempty" yaml:"rotationPeriod,omitempty"`
+
+	// GracePeriod is the time a retired key keeps verifying the tokens signed by it,
+	// it should be longer than the lifetime of the tokens. Defaults to 24 hours.
+	GracePeriod time.Duration `json:"gracePeriod,omitempty" yaml:"gracePeriod,omitempty"`
+}
+
+// LegacyTokenWindow returns the time the access and refresh tokens signed by the JWT secret before
+// the upgrade are still accepted after the first key of the keyring is created, which outlasts the lifetime of the tokens.
+func (o *IssuerOptions) LegacyTokenWindow() time.Duration {
+	return max(o.AccessTokenMaxAge+o.AccessTokenInactivityTimeout, o.Keyring.KeyGracePeriod())
+}
+
+// KeyGracePeriod returns the grace period of the retired keys.
+func (o *KeyringOptions) KeyGracePeriod() time.Duration {
+	if o.GracePeriod > 0 {
+		return o.GracePeriod
+	}
+	return DefaultKeyGracePeriod
 }
 
 type IdentityProviderOptions struct {
@@ -110,6 +143,63 @@ type Token struct {
 
 	// ExpiresIn is the optional expiration second of the access token.
 	ExpiresIn int `json:"expires_in,omitempty"`
+
+	// IssuedTokenType is the type of the issued token in the token exchange response.
+	IssuedTokenType string `json:"issued_token_type,omitempty"`
+
+	// Scope is the scope of the access token if it's different from the requested one.
+	Scope string `json:"scope,omitempty"`
+}
+
+// Introspection is the response of the token introspection endpoint,
+// https://datatracker.ietf.org/doc/html/rfc7662#section-2.2
+type Introspection struct {
+	// Active indicates whether the token is currently active, the other fields are omitted if it's not.
+	Active bool `json:"active"`
+
+	// Scope is a space-separated list of the scopes associated with the token.
+	Scope string `json:"scope,omitempty"`
+
+	// ClientID is the client the token is issued to.
+	ClientID string `json:"client_id,omitempty"`
+
+	// Username is the resource owner who authorized the token.
+	Username string `json:"username,omitempty"`
+
+	// TokenType is the type of the token, such as Bearer.
+	TokenType string `json:"token_type,omitempty"`
+
+	// Expiry, IssuedAt and NotBefore are the seconds since the epoch.
+	Expiry    int64 `json:"exp,omitempty"`
+	IssuedAt  int64 `json:"iat,omitempty"`
+	NotBefore int64 `json:"nbf,omitempty"`
+
+	Subject  string   `json:"sub,omitempty"`
+	Audience []string `json:"aud,omitempty"`
+	Issuer   string   `json:"iss,omitempty"`
+}
+
+// DeviceAuthorization is the response of the device authorization endpoint,
+// https://datatracker.ietf.org/doc/html/rfc8628#section-3.2
+type DeviceAuthorization struct {
+	// DeviceCode is the device verification code, the client polls the token endpoint with it.
+	DeviceCode string `json:"device_code"`
+
+	// UserCode is the end-user verification code.
+	UserCode string `json:"user_code"`
+
+	// VerificationURI is the end-user verification URI on the authorization server.
+	VerificationURI string `json:"verification_uri"`
+
+	// VerificationURIComplete is the verification URI that includes the user code,
+	// which is designed for non-textual transmission, such as a QR code.
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+
+	// ExpiresIn is the lifetime in seconds of the device code and user code.
+	ExpiresIn int `json:"expires_in"`
+
+	// Interval is the minimum amount of time in seconds that the client SHOULD wait between polling requests.
+	Interval int `json:"interval,omitempty"`
 }
 
 func NewIssuerOptions() *IssuerOptions {
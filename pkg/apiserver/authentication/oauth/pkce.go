@@ -0,0 +1,78 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+
+	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
+)
+
+// Proof Key for Code Exchange by OAuth Public Clients
+// https://datatracker.ietf.org/doc/html/rfc7636
+const (
+	CodeChallengeMethodPlain = "plain"
+	CodeChallengeMethodS256  = "S256"
+
+	minCodeVerifierLength = 43
+	maxCodeVerifierLength = 128
+	// the S256 code challenge is the base64url encoded SHA-256 digest without padding
+	s256CodeChallengeLength = 43
+)
+
+// ValidCodeChallengeMethods only contains S256, the plain code challenge is the code_verifier itself,
+// which can be read from the authorization code since the code is signed but not encrypted.
+var ValidCodeChallengeMethods = []string{CodeChallengeMethodS256}
+
+// ValidateCodeChallenge validates the code_challenge and code_challenge_method of an authorization request.
+// The code_challenge_method is required, since it defaults to plain which is not supported.
+func ValidateCodeChallenge(challenge, method string) error {
+	if method == "" {
+		return fmt.Errorf("code_challenge_method is required")
+	}
+	if !sliceutil.HasString(ValidCodeChallengeMethods, method) {
+		return fmt.Errorf("unsupported code_challenge_method %s", method)
+	}
+	if len(challenge) != s256CodeChallengeLength || !isValidCodeVerifier(challenge) {
+		return fmt.Errorf("invalid code_challenge")
+	}
+	return nil
+}
+
+// VerifyCodeVerifier verifies the code_verifier of a token request against the code_challenge
+// of the authorization request.
+func VerifyCodeVerifier(verifier, challenge, method string) bool {
+	if !isValidCodeVerifier(verifier) {
+		return false
+	}
+	if method != CodeChallengeMethodS256 {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
+
+// isValidCodeVerifier checks code-verifier = 43*128unreserved,
+// unreserved = ALPHA / DIGIT / "-" / "." / "_" / "~"
+func isValidCodeVerifier(verifier string) bool {
+	if len(verifier) < minCodeVerifierLength || len(verifier) > maxCodeVerifierLength {
+		return false
+	}
+	for _, c := range verifier {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9':
+		case c == '-', c == '.', c == '_', c == '~':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package oauth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVerifyCodeVerifier(t *testing.T) {
+	// https://datatracker.ietf.org/doc/html/rfc7636#appendix-B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		method    string
+		want      bool
+	}{
+		{name: "S256", verifier: verifier, challenge: challenge, method: CodeChallengeMethodS256, want: true},
+		{name: "S256 mismatch", verifier: verifier + "a", challenge: challenge, method: CodeChallengeMethodS256, want: false},
+		{name: "plain", verifier: verifier, challenge: verifier, method: CodeChallengeMethodPlain, want: false},
+		{name: "plain by default", verifier: verifier, challenge: verifier, want: false},
+		{name: "too short", verifier: "short", challenge: challenge, method: CodeChallengeMethodS256, want: false},
+		{name: "invalid characters", verifier: strings.Repeat("+", 43), challenge: challenge, method: CodeChallengeMethodS256, want: false},
+		{name: "unsupported method", verifier: verifier, challenge: verifier, method: "S512", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifyCodeVerifier(tt.verifier, tt.challenge, tt.method); got != tt.want {
+				t.Errorf("VerifyCodeVerifier() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateCodeChallenge(t *testing.T) {
+	if err := ValidateCodeChallenge("E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", CodeChallengeMethodS256); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateCodeChallenge("E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", "S512"); err == nil {
+		t.Errorf("expected error for unsupported method, didn't get any")
+	}
+	if err := ValidateCodeChallenge("", CodeChallengeMethodS256); err == nil {
+		t.Errorf("expected error for empty challenge, didn't get any")
+	}
+	// the plain challenge is the code_verifier, which can be read from the authorization code
+	if err := ValidateCodeChallenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk", CodeChallengeMethodPlain); err == nil {
+		t.Errorf("expected error for plain method, didn't get any")
+	}
+	if err := ValidateCodeChallenge("E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", ""); err == nil {
+		t.Errorf("expected error for missing method, didn't get any")
+	}
+}
@@ -8,6 +8,7 @@ package authentication
 
 import (
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/spf13/pflag"
@@ -17,7 +18,9 @@ import (
 	_ "kubesphere.io/kubesphere/pkg/apiserver/authentication/identityprovider/github"
 	_ "kubesphere.io/kubesphere/pkg/apiserver/authentication/identityprovider/ldap"
 	_ "kubesphere.io/kubesphere/pkg/apiserver/authentication/identityprovider/oidc"
+	_ "kubesphere.io/kubesphere/pkg/apiserver/authentication/identityprovider/saml"
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication/oauth"
+	"kubesphere.io/kubesphere/pkg/utils/iputil"
 )
 
 type Options struct {
@@ -37,9 +40,85 @@ type Options struct {
 	LoginHistoryMaximumEntries int `json:"loginHistoryMaximumEntries,omitempty" yaml:"loginHistoryMaximumEntries,omitempty"`
 	// allow multiple users login from different location at the same time
 	MultipleLogin bool `json:"multipleLogin" yaml:"multipleLogin"`
+	// SessionIdleTimeout expires the login sessions without activity for the duration, 0 means no idle timeout.
+	SessionIdleTimeout time.Duration `json:"sessionIdleTimeout" yaml:"sessionIdleTimeout"`
 
 	// Issuer defines options needed for integrated oauth plugins
 	Issuer *oauth.IssuerOptions `json:"issuer" yaml:"issuer"`
+
+	// MultiFactorAuthOptions defines the policy of the TOTP second factor of local accounts
+	MultiFactorAuthOptions MultiFactorAuthOptions `json:"multiFactorAuth" yaml:"multiFactorAuth"`
+
+	// SCIMOptions defines the SCIM 2.0 endpoint the identity providers push the users and groups to
+	SCIMOptions SCIMOptions `json:"scim" yaml:"scim"`
+
+	// PasswordPolicy defines the rules of the passwords of local accounts
+	PasswordPolicy PasswordPolicyOptions `json:"passwordPolicy" yaml:"passwordPolicy"`
+
+	// LoginThrottle blocks the source IPs that fail to log in too many times, regardless of the usernames
+	LoginThrottle LoginThrottleOptions `json:"loginThrottle" yaml:"loginThrottle"`
+}
+
+// LoginThrottleOptions defines the brute-force protection of /oauth/token, /oauth/authenticate and basic authentication.
+// A source IP, or the network it belongs to, is blocked for BlockDuration once the failed login attempts
+// from it reach the maximum in the sliding Window, which slides by a tenth of its length.
+// The failures of a source IP are forgotten once a user logs in from it.
+type LoginThrottleOptions struct {
+	Enable bool          `json:"enable" yaml:"enable"`
+	Window time.Duration `json:"window" yaml:"window"`
+	// MaxFailuresPerIP limits the failed login attempts from a single IP.
+	MaxFailuresPerIP int `json:"maxFailuresPerIP" yaml:"maxFailuresPerIP"`
+	// MaxFailuresPerCIDR limits the failed login attempts from the network of CIDRPrefixIPv4 or CIDRPrefixIPv6,
+	// 0 means the networks are not throttled.
+	MaxFailuresPerCIDR int           `json:"maxFailuresPerCIDR" yaml:"maxFailuresPerCIDR"`
+	CIDRPrefixIPv4     int           `json:"cidrPrefixIPv4" yaml:"cidrPrefixIPv4"`
+	CIDRPrefixIPv6     int           `json:"cidrPrefixIPv6" yaml:"cidrPrefixIPv6"`
+	BlockDuration      time.Duration `json:"blockDuration" yaml:"blockDuration"`
+	// TrustedProxies are the CIDRs of the proxies whose X-Forwarded-For header is honored.
+	// The address of the peer is the client if there is no trusted proxy.
+	// IMPORTANT: ks-console proxies the login requests, so its pod address MUST be trusted, otherwise the failures
+	// of all the users are counted for the console, and everyone is blocked once the maximum is reached.
+	// Only the pod CIDR of ks-console and the ingress controller should be trusted, any client in a trusted network
+	// can claim a different address on each request to evade the throttle.
+	TrustedProxies []string `json:"trustedProxies,omitempty" yaml:"trustedProxies,omitempty"`
+}
+
+type PasswordPolicyOptions struct {
+	// MinLength is the minimum length of the passwords.
+	MinLength int `json:"minLength" yaml:"minLength"`
+	// The character classes the passwords must contain.
+	RequireUppercase bool `json:"requireUppercase" yaml:"requireUppercase"`
+	RequireLowercase bool `json:"requireLowercase" yaml:"requireLowercase"`
+	RequireDigit     bool `json:"requireDigit" yaml:"requireDigit"`
+	RequireSymbol    bool `json:"requireSymbol" yaml:"requireSymbol"`
+	// DictionaryCheck rejects the commonly used passwords, the passwords containing the username
+	// and the passwords containing any word of the Dictionary.
+	DictionaryCheck bool     `json:"dictionaryCheck" yaml:"dictionaryCheck"`
+	Dictionary      []string `json:"dictionary,omitempty" yaml:"dictionary,omitempty"`
+	// HistoryCount disallows reusing the last N passwords, 0 means the history is not kept.
+	HistoryCount int `json:"historyCount" yaml:"historyCount"`
+	// MaxAge requires the users to change the password at next login once the password is older than MaxAge,
+	// 0 means the passwords never expire.
+	MaxAge time.Duration `json:"maxAge" yaml:"maxAge"`
+}
+
+type MultiFactorAuthOptions struct {
+	// Required requires all users to log in with a one-time password. MFA can also be required
+	// for the members of a workspace with the iam.kubesphere.io/mfa-required annotation.
+	// Users who have not enrolled are asked to enroll after logging in.
+	Required bool `json:"required" yaml:"required"`
+	// Issuer is the name of the account displayed in authenticator apps.
+	Issuer string `json:"issuer" yaml:"issuer"`
+}
+
+type SCIMOptions struct {
+	// Enable serves the SCIM 2.0 API at /scim/v2.
+	Enable bool `json:"enable" yaml:"enable"`
+	// BearerToken authenticates the SCIM client, it should be a random string of at least 32 characters.
+	BearerToken string `json:"-" yaml:"bearerToken"`
+	// IdentityProvider is the name of the identity provider the provisioned users log in with.
+	// The externalId of the users is mapped to the identity at the identity provider.
+	IdentityProvider string `json:"identityProvider,omitempty" yaml:"identityProvider,omitempty"`
 }
 
 func NewOptions() *Options {
@@ -50,6 +129,22 @@ func NewOptions() *Options {
 		LoginHistoryMaximumEntries:      100,
 		Issuer:                          oauth.NewIssuerOptions(),
 		MultipleLogin:                   false,
+		MultiFactorAuthOptions:          MultiFactorAuthOptions{Issuer: "KubeSphere"},
+		// the same as the validation of the User CRD
+		PasswordPolicy: PasswordPolicyOptions{
+			MinLength:        8,
+			RequireUppercase: true,
+			RequireLowercase: true,
+			RequireDigit:     true,
+		},
+		LoginThrottle: LoginThrottleOptions{
+			Window:             time.Minute * 10,
+			MaxFailuresPerIP:   20,
+			MaxFailuresPerCIDR: 100,
+			CIDRPrefixIPv4:     24,
+			CIDRPrefixIPv6:     64,
+			BlockDuration:      time.Minute * 30,
+		},
 	}
 }
 
@@ -61,6 +156,31 @@ func (options *Options) Validate() []error {
 	if options.AuthenticateRateLimiterMaxTries > options.LoginHistoryMaximumEntries {
 		errs = append(errs, errors.New("authenticateRateLimiterMaxTries MUST not be greater than loginHistoryMaximumEntries"))
 	}
+	if options.SessionIdleTimeout < 0 {
+		errs = append(errs, errors.New("sessionIdleTimeout MUST not be negative"))
+	}
+	if options.PasswordPolicy.MinLength > 64 {
+		errs = append(errs, errors.New("passwordPolicy.minLength MUST not be greater than 64"))
+	}
+	if options.PasswordPolicy.HistoryCount < 0 || options.PasswordPolicy.MaxAge < 0 {
+		errs = append(errs, errors.New("passwordPolicy.historyCount and passwordPolicy.maxAge MUST not be negative"))
+	}
+	if options.LoginThrottle.Enable {
+		throttle := options.LoginThrottle
+		if throttle.Window <= 0 || throttle.BlockDuration <= 0 || throttle.MaxFailuresPerIP <= 0 {
+			errs = append(errs, errors.New("loginThrottle.window, loginThrottle.blockDuration and loginThrottle.maxFailuresPerIP MUST be positive"))
+		}
+		if throttle.MaxFailuresPerCIDR > 0 && (throttle.CIDRPrefixIPv4 <= 0 || throttle.CIDRPrefixIPv4 > 32 ||
+			throttle.CIDRPrefixIPv6 <= 0 || throttle.CIDRPrefixIPv6 > 128) {
+			errs = append(errs, errors.New("loginThrottle.cidrPrefixIPv4 and loginThrottle.cidrPrefixIPv6 MUST be valid prefix lengths"))
+		}
+		if _, err := iputil.ParseCIDRs(throttle.TrustedProxies); err != nil {
+			errs = append(errs, fmt.Errorf("invalid loginThrottle.trustedProxies: %s", err))
+		}
+	}
+	if options.SCIMOptions.Enable && len(options.SCIMOptions.BearerToken) < 32 {
+		errs = append(errs, errors.New("SCIM bearer token MUST be at least 32 characters"))
+	}
 	return errs
 }
 
@@ -68,9 +188,13 @@ func (options *Options) AddFlags(fs *pflag.FlagSet, s *Options) {
 	fs.IntVar(&options.AuthenticateRateLimiterMaxTries, "authenticate-rate-limiter-max-retries", s.AuthenticateRateLimiterMaxTries, "")
 	fs.DurationVar(&options.AuthenticateRateLimiterDuration, "authenticate-rate-limiter-duration", s.AuthenticateRateLimiterDuration, "")
 	fs.BoolVar(&options.MultipleLogin, "multiple-login", s.MultipleLogin, "Allow multiple login with the same account, disable means only one user can login at the same time.")
+	fs.DurationVar(&options.SessionIdleTimeout, "session-idle-timeout", s.SessionIdleTimeout, "session-idle-timeout expires the login sessions without activity for the duration, 0 means no idle timeout.")
 	fs.StringVar(&options.Issuer.JWTSecret, "jwt-secret", s.Issuer.JWTSecret, "Secret to sign jwt token, must not be empty.")
 	fs.DurationVar(&options.LoginHistoryRetentionPeriod, "login-history-retention-period", s.LoginHistoryRetentionPeriod, "login-history-retention-period defines how long login history should be kept.")
 	fs.IntVar(&options.LoginHistoryMaximumEntries, "login-history-maximum-entries", s.LoginHistoryMaximumEntries, "login-history-maximum-entries defines how many entries of login history should be kept.")
 	fs.DurationVar(&options.Issuer.AccessTokenMaxAge, "access-token-max-age", s.Issuer.AccessTokenMaxAge, "access-token-max-age control the lifetime of access tokens, 0 means no expiration.")
+	fs.BoolVar(&options.LoginThrottle.Enable, "login-throttle", s.LoginThrottle.Enable, "Block the source IPs that fail to log in too many times, regardless of the usernames.")
+	fs.StringSliceVar(&options.LoginThrottle.TrustedProxies, "trusted-proxies", s.LoginThrottle.TrustedProxies, "The CIDRs of the proxies whose X-Forwarded-For header is honored by the login throttle.")
+	fs.BoolVar(&options.MultiFactorAuthOptions.Required, "mfa-required", s.MultiFactorAuthOptions.Required, "Require all users to log in with a one-time password.")
 	fs.DurationVar(&options.Issuer.MaximumClockSkew, "maximum-clock-skew", s.Issuer.MaximumClockSkew, "The maximum time difference between the system clocks of the ks-apiserver that issued a JWT and the ks-apiserver that verified the JWT.")
 }
@@ -32,12 +32,20 @@ const (
 	StaticToken       Type   = "static_token"
 	AuthorizationCode Type   = "code"
 	IDToken           Type   = "id_token"
+	MFAToken          Type   = "mfa_token"
 	headerKeyID       string = "kid"
 	headerAlgorithm   string = "alg"
 )
 
+// DefaultAudience identifies ks-apiserver, access tokens with audiences are only accepted
+// by ks-apiserver if it's one of them.
+const DefaultAudience = "kubesphere"
+
 type Type string
 
+// ErrNoSigningKey is returned if the tokens are issued before the shared signing key is loaded.
+var ErrNoSigningKey = errors.New("jwt: the signing key is not loaded yet")
+
 type IssueRequest struct {
 	User      user.Info
 	ExpiresIn time.Duration
@@ -53,6 +61,8 @@ type VerifiedResponse struct {
 type Keys struct {
 	SigningKey    *jose.JSONWebKey
 	SigningKeyPub *jose.JSONWebKey
+	// VerificationKeys are the public keys of the keyring, including the active signing key.
+	VerificationKeys []jose.JSONWebKey
 }
 
 // Issuer issues token to user, tokens are required to perform mutating requests to resources
@@ -65,6 +75,9 @@ type Issuer interface {
 
 	// Keys hold encryption and signing keys.
 	Keys() *Keys
+
+	// Keyring holds the keys signing the tokens.
+	Keyring() *Keyring
 }
 
 type Claims struct {
@@ -80,6 +93,14 @@ type Claims struct {
 	// Used for issuing authorization code
 	// Scopes can be used to request that specific sets of information be made available as Claim Values.
 	Scopes []string `json:"scopes,omitempty"`
+	// CodeChallenge and CodeChallengeMethod bind the authorization code to the code_verifier of the client (RFC 7636).
+	CodeChallenge       string `json:"code_challenge,omitempty"`
+	CodeChallengeMethod string `json:"code_challenge_method,omitempty"`
+	// ClientID is the client the token is issued to.
+	ClientID string `json:"client_id,omitempty"`
+	// SessionID identifies the login session the token belongs to, the token is
+	// invalidated as soon as the session is revoked or expired.
+	SessionID string `json:"sid,omitempty"`
 
 	// The following is well-known ID Token fields
 
@@ -100,10 +121,10 @@ type Claims struct {
 type issuer struct {
 	// Issuer Identifier
 	url string
-	// signing access_token and refresh_token
+	// signing the static tokens of the service accounts
 	secret []byte
-	// signing id_token
-	signKey *Keys
+	// signing the other tokens
+	keyring *Keyring
 	// Token verification maximum time difference
 	maximumClockSkew time.Duration
 }
@@ -142,18 +163,35 @@ func (s *issuer) IssueTo(request *IssueRequest) (string, error) {
 	if len(request.Scopes) > 0 {
 		claims.Scopes = request.Scopes
 	}
+	if request.ClientID != "" {
+		claims.ClientID = request.ClientID
+	}
+	if request.SessionID != "" {
+		claims.SessionID = request.SessionID
+	}
+	if request.CodeChallenge != "" {
+		claims.CodeChallenge = request.CodeChallenge
+		claims.CodeChallengeMethod = request.CodeChallengeMethod
+	}
 	if request.ExpiresIn > 0 {
 		claims.ExpiresAt = jwt.NewNumericDate(issueAt.Add(request.ExpiresIn))
 	}
 
 	var token string
 	var err error
-	if request.TokenType == IDToken {
-		t := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
-		t.Header[headerKeyID] = s.signKey.SigningKey.KeyID
-		token, err = t.SignedString(s.signKey.SigningKey.Key)
-	} else {
+	if request.TokenType == StaticToken {
+		// the static tokens of the service accounts never expire, they are signed by the JWT secret
+		// so that they outlive the rotations of the keyring
 		token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.secret)
+	} else {
+		signingKey := s.keyring.SigningKey()
+		if signingKey == nil {
+			klog.Warning(ErrNoSigningKey)
+			return "", ErrNoSigningKey
+		}
+		t := jwt.NewWithClaims(signingKey.signingMethod(), claims)
+		t.Header[headerKeyID] = signingKey.KeyID
+		token, err = t.SignedString(signingKey.PrivateKey)
 	}
 	if err != nil {
 		klog.Warningf("jwt: failed to issue token: %v", err)
@@ -163,17 +201,28 @@ func (s *issuer) IssueTo(request *IssueRequest) (string, error) {
 }
 
 func (s *issuer) Verify(token string) (*VerifiedResponse, error) {
-	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}),
+	parser := jwt.NewParser(jwt.WithValidMethods(append([]string{jwt.SigningMethodHS256.Alg()}, SupportedSigningAlgorithms...)),
 		jwt.WithoutClaimsValidation())
 
 	var claims Claims
-	_, err := parser.ParseWithClaims(token, &claims, s.keyFunc)
+	parsed, err := parser.ParseWithClaims(token, &claims, s.keyFunc)
 	if err != nil {
 		klog.Warningf("jwt: failed to parse token: %v", err)
 		return nil, err
 	}
 
 	now := time.Now()
+	// only the static tokens are signed by the JWT secret, the others are signed by the keyring,
+	// except the ones issued before the upgrade, which are accepted until the deadline recorded
+	// by the keyring, the same for all the replicas regardless of when they started
+	legacyTokenDeadline := s.keyring.LegacyTokenDeadline()
+	if parsed.Method.Alg() == jwt.SigningMethodHS256.Alg() && claims.TokenType != StaticToken &&
+		!legacyTokenDeadline.IsZero() && !now.Before(legacyTokenDeadline) {
+		err = fmt.Errorf("jwt: %s must be signed by the keyring", claims.TokenType)
+		klog.Warning(err)
+		return nil, err
+	}
+
 	if !claims.VerifyExpiresAt(now, false) {
 		delta := now.Sub(claims.ExpiresAt.Time)
 		err = fmt.Errorf("jwt: token is expired by %v", delta)
@@ -201,7 +250,16 @@ func (s *issuer) Verify(token string) (*VerifiedResponse, error) {
 }
 
 func (s *issuer) Keys() *Keys {
-	return s.signKey
+	keys := &Keys{VerificationKeys: s.keyring.PublicKeys()}
+	if signingKey := s.keyring.SigningKey(); signingKey != nil {
+		keys.SigningKey = signingKey.privateJSONWebKey()
+		keys.SigningKeyPub = signingKey.publicJSONWebKey()
+	}
+	return keys
+}
+
+func (s *issuer) Keyring() *Keyring {
+	return s.keyring
 }
 
 func (s *issuer) keyFunc(token *jwt.Token) (i interface{}, err error) {
@@ -209,25 +267,18 @@ func (s *issuer) keyFunc(token *jwt.Token) (i interface{}, err error) {
 	switch alg {
 	case jwt.SigningMethodHS256.Alg():
 		return s.secret, nil
-	case jwt.SigningMethodRS256.Alg():
-		return s.signKey.SigningKey.Key, nil
+	case jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg(), jwt.SigningMethodEdDSA.Alg():
+		keyID, _ := token.Header[headerKeyID].(string)
+		key := s.keyring.VerificationKey(keyID)
+		if key == nil || key.Algorithm != alg {
+			return nil, fmt.Errorf("unknown signing key %s", keyID)
+		}
+		return key.PublicKey, nil
 	default:
 		return nil, fmt.Errorf("unexpect signature algorithm %v", token.Header[headerAlgorithm])
 	}
 }
 
-func loadPrivateKey(data []byte) (*rsa.PrivateKey, error) {
-	block, _ := pem.Decode(data)
-	if block == nil {
-		return nil, errors.New("private key not in pem format")
-	}
-	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
-	if err != nil {
-		return nil, fmt.Errorf("failed to key file: %v", err)
-	}
-	return key, nil
-}
-
 func generatePrivateKeyData() ([]byte, error) {
 	privateKey, err := rsa.GenerateKey(cryptorand.Reader, 2048)
 	if err != nil {
@@ -243,8 +294,10 @@ func generatePrivateKeyData() ([]byte, error) {
 	return pemData, nil
 }
 
-func loadSignKey(config *oauth.IssuerOptions) (*rsa.PrivateKey, string, error) {
-	var signKey *rsa.PrivateKey
+// loadSignKey loads the static sign key of the keyring, nil if it's not configured.
+// A key is never generated, otherwise the replicas would sign the tokens with their own keys
+// that are unknown to each other.
+func loadSignKey(config *oauth.IssuerOptions) (*Key, error) {
 	var signKeyData []byte
 	var err error
 
@@ -252,39 +305,33 @@ func loadSignKey(config *oauth.IssuerOptions) (*rsa.PrivateKey, string, error) {
 		signKeyData, err = os.ReadFile(config.SignKey)
 		if err != nil {
 			klog.Errorf("issuer: failed to read private key file %s: %v", config.SignKey, err)
-			return nil, "", err
+			return nil, err
 		}
 	} else if config.SignKeyData != "" {
 		signKeyData, err = base64.StdEncoding.DecodeString(config.SignKeyData)
 		if err != nil {
 			klog.Errorf("issuer: failed to decode sign key data: %s", err)
-			return nil, "", err
+			return nil, err
 		}
 	}
-
-	// automatically generate private key
 	if len(signKeyData) == 0 {
-		signKeyData, err = generatePrivateKeyData()
-		if err != nil {
-			klog.Errorf("issuer: failed to generate private key: %v", err)
-			return nil, "", err
-		}
+		return nil, nil
 	}
 
-	if len(signKeyData) > 0 {
-		signKey, err = loadPrivateKey(signKeyData)
-		if err != nil {
-			klog.Errorf("issuer: failed to load private key from data: %v", err)
-		}
+	signKey, err := ParseSigningKey(signKeyData)
+	if err != nil {
+		klog.Errorf("issuer: failed to load private key from data: %v", err)
+		return nil, err
 	}
-
-	keyID := fmt.Sprint(fnv32a(signKeyData))
-	return signKey, keyID, nil
+	// The key ID of the static key is kept for the ID tokens issued by the previous versions.
+	signKey.KeyID = fmt.Sprint(fnv32a(signKeyData))
+	return signKey, nil
 }
 
+// NewIssuer creates an issuer with the static sign key, the keys rotated
+// by the keyring controller are loaded into the keyring by the KeyringSource.
 func NewIssuer(config *oauth.IssuerOptions) (Issuer, error) {
-	// TODO(hongming) automatically rotates keys
-	signKey, keyID, err := loadSignKey(config)
+	signKey, err := loadSignKey(config)
 	if err != nil {
 		return nil, err
 	}
@@ -292,20 +339,7 @@ func NewIssuer(config *oauth.IssuerOptions) (Issuer, error) {
 		url:              config.URL,
 		secret:           []byte(config.JWTSecret),
 		maximumClockSkew: config.MaximumClockSkew,
-		signKey: &Keys{
-			SigningKey: &jose.JSONWebKey{
-				Key:       signKey,
-				KeyID:     keyID,
-				Algorithm: jwt.SigningMethodRS256.Alg(),
-				Use:       "sig",
-			},
-			SigningKeyPub: &jose.JSONWebKey{
-				Key:       signKey.Public(),
-				KeyID:     keyID,
-				Algorithm: jwt.SigningMethodRS256.Alg(),
-				Use:       "sig",
-			},
-		},
+		keyring:          NewKeyring(signKey),
 	}, nil
 }
 
@@ -12,7 +12,6 @@ import (
 	"testing"
 	"time"
 
-	"github.com/go-jose/go-jose/v4"
 	"github.com/golang-jwt/jwt/v4"
 	"github.com/stretchr/testify/assert"
 	"k8s.io/apiserver/pkg/authentication/user"
@@ -63,7 +62,7 @@ func TestNewIssuer(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	signKey, keyID, err := loadSignKey(config)
+	signKey, err := loadSignKey(config)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -72,28 +71,21 @@ func TestNewIssuer(t *testing.T) {
 		url:              config.URL,
 		secret:           []byte(config.JWTSecret),
 		maximumClockSkew: config.MaximumClockSkew,
-		signKey: &Keys{
-			SigningKey: &jose.JSONWebKey{
-				Key:       signKey,
-				KeyID:     keyID,
-				Algorithm: jwt.SigningMethodRS256.Alg(),
-				Use:       "sig",
-			},
-			SigningKeyPub: &jose.JSONWebKey{
-				Key:       signKey.Public(),
-				KeyID:     keyID,
-				Algorithm: jwt.SigningMethodRS256.Alg(),
-				Use:       "sig",
-			},
-		},
+		keyring:          NewKeyring(signKey),
 	}
 	if !reflect.DeepEqual(got, want) {
 		t.Errorf("NewIssuerOptions() got = %v, want %v", got, want)
 		return
 	}
+
+	keys := got.Keys()
+	assert.Equal(t, signKey.KeyID, keys.SigningKey.KeyID)
+	assert.Equal(t, jwt.SigningMethodRS256.Alg(), keys.SigningKeyPub.Algorithm)
+	assert.Equal(t, signKey.PublicKey, keys.SigningKeyPub.Key)
+	assert.Len(t, keys.VerificationKeys, 1)
 }
 
-func TestNewIssuerGenerateSignKey(t *testing.T) {
+func TestNewIssuerWithoutSignKey(t *testing.T) {
 	config := &oauth.IssuerOptions{
 		URL:              "https://ks-console.kubesphere-system.svc",
 		MaximumClockSkew: 10 * time.Second,
@@ -105,12 +97,45 @@ func TestNewIssuerGenerateSignKey(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	iss := got.(*issuer)
-	assert.NotNil(t, iss.signKey)
-	assert.NotNil(t, iss.signKey.SigningKey)
-	assert.NotNil(t, iss.signKey.SigningKeyPub)
-	assert.NotNil(t, iss.signKey.SigningKey.KeyID)
-	assert.NotNil(t, iss.signKey.SigningKeyPub.KeyID)
+	// no token is signed until the shared signing key is loaded
+	keys := got.Keys()
+	assert.NotNil(t, keys)
+	assert.Nil(t, keys.SigningKey)
+	assert.Nil(t, keys.SigningKeyPub)
+	assert.Empty(t, keys.VerificationKeys)
+	request := &IssueRequest{User: &user.DefaultInfo{Name: "user1"}, Claims: Claims{TokenType: AccessToken}}
+	_, err = got.IssueTo(request)
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+
+	key := newTestKey(t, "RS256", time.Now())
+	got.Keyring().Update([]*Key{key})
+	keys = got.Keys()
+	assert.NotNil(t, keys.SigningKey)
+	assert.Equal(t, key.KeyID, keys.SigningKeyPub.KeyID)
+	_, err = got.IssueTo(request)
+	assert.NoError(t, err)
+}
+
+func TestNewIssuerOnStartup(t *testing.T) {
+	got, err := NewIssuer(&oauth.IssuerOptions{URL: "kubesphere", JWTSecret: "kubesphere"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := &IssueRequest{User: &user.DefaultInfo{Name: "user1"}, Claims: Claims{TokenType: AccessToken}}
+
+	// without an active key, the pending key signs the tokens rather than failing the logins
+	pending := newTestKey(t, "RS256", time.Now().Add(time.Minute))
+	got.Keyring().Update([]*Key{pending})
+	token, err := got.IssueTo(request)
+	assert.NoError(t, err)
+	verified, err := got.Verify(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", verified.Username)
+
+	// the active key takes over once it's loaded
+	active := newTestKey(t, "ES256", time.Now())
+	got.Keyring().Update([]*Key{pending, active})
+	assert.Equal(t, active.KeyID, got.Keys().SigningKey.KeyID)
 }
 
 func Test_issuer_IssueTo(t *testing.T) {
@@ -191,6 +216,7 @@ func Test_issuer_IssueTo(t *testing.T) {
 				url:              tt.fields.url,
 				secret:           tt.fields.secret,
 				maximumClockSkew: tt.fields.maximumClockSkew,
+				keyring:          NewKeyring(newTestKey(t, "RS256", time.Now())),
 			}
 			token, err := s.IssueTo(tt.args.request)
 			if (err != nil) != tt.wantErr {
@@ -214,6 +240,73 @@ func Test_issuer_IssueTo(t *testing.T) {
 	}
 }
 
+func TestIssueToSignedByKeyring(t *testing.T) {
+	iss := newTestIssuer(t)
+	active := newTestKey(t, "ES256", time.Now())
+	iss.keyring.Update([]*Key{active})
+
+	for _, tokenType := range []Type{AccessToken, RefreshToken, IDToken} {
+		signed, err := iss.IssueTo(&IssueRequest{
+			User:      &user.DefaultInfo{Name: "user1"},
+			Claims:    Claims{TokenType: tokenType},
+			ExpiresIn: time.Hour,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, _, err := jwt.NewParser().ParseUnverified(signed, &Claims{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, active.Algorithm, parsed.Header[headerAlgorithm], tokenType)
+		assert.Equal(t, active.KeyID, parsed.Header[headerKeyID], tokenType)
+		_, err = iss.Verify(signed)
+		assert.NoError(t, err, tokenType)
+	}
+
+	// the static tokens of the service accounts are signed by the JWT secret
+	static, err := iss.IssueTo(&IssueRequest{
+		User:   &user.DefaultInfo{Name: "kubesphere:serviceaccount:default:test"},
+		Claims: Claims{TokenType: StaticToken},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verified, err := iss.Verify(static)
+	assert.NoError(t, err)
+	assert.Equal(t, StaticToken, verified.TokenType)
+
+}
+
+func TestVerifyLegacyToken(t *testing.T) {
+	iss := newTestIssuer(t)
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, Claims{
+		Username:         "admin",
+		TokenType:        AccessToken,
+		RegisteredClaims: jwt.RegisteredClaims{IssuedAt: jwt.NewNumericDate(time.Now())},
+	}).SignedString(iss.secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the access tokens signed by the JWT secret before the upgrade are accepted until the deadline
+	// recorded by the keyring, the earliest one of the keys applies
+	first := newTestKey(t, "RS256", time.Now().Add(-time.Hour))
+	first.LegacyTokenDeadline = time.Now().Add(time.Hour)
+	second := newTestKey(t, "RS256", time.Now())
+	second.LegacyTokenDeadline = time.Now().Add(2 * time.Hour)
+	iss.keyring.Update([]*Key{first, second})
+	verified, err := iss.Verify(legacy)
+	assert.NoError(t, err)
+	assert.Equal(t, "admin", verified.Username)
+
+	// and rejected once the deadline is reached
+	first.LegacyTokenDeadline = time.Now()
+	iss.keyring.Update([]*Key{first, second})
+	_, err = iss.Verify(legacy)
+	assert.Error(t, err)
+}
+
 func Test_issuer_Verify(t *testing.T) {
 	type fields struct {
 		url              string
@@ -266,6 +359,7 @@ func Test_issuer_Verify(t *testing.T) {
 				url:              tt.fields.url,
 				secret:           tt.fields.secret,
 				maximumClockSkew: tt.fields.maximumClockSkew,
+				keyring:          NewKeyring(newTestKey(t, "RS256", time.Now())),
 			}
 			got, err := s.Verify(tt.args.token)
 			if (err != nil) != tt.wantErr {
@@ -321,7 +415,9 @@ func Test_issuer_keyFunc(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			s, err := NewIssuer(oauth.NewIssuerOptions())
+			options := oauth.NewIssuerOptions()
+			options.SignKeyData = base64.StdEncoding.EncodeToString([]byte(privateKeyData))
+			s, err := NewIssuer(options)
 			if err != nil {
 				t.Error(err)
 				return
@@ -0,0 +1,252 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package token
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	cryptorand "crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// SupportedSigningAlgorithms are the algorithms of the keys signing the tokens.
+var SupportedSigningAlgorithms = []string{
+	jwt.SigningMethodRS256.Alg(),
+	jwt.SigningMethodES256.Alg(),
+	jwt.SigningMethodEdDSA.Alg(),
+}
+
+// Key is a key of the keyring.
+type Key struct {
+	KeyID     string
+	Algorithm string
+	// PrivateKey is nil if the key is only used to verify tokens.
+	PrivateKey crypto.Signer
+	PublicKey  crypto.PublicKey
+	// ActivatedAt is the time the key starts signing tokens, the key only verifies tokens before it.
+	// It orders the keys, the newest key activated which is not retired signs the tokens.
+	ActivatedAt time.Time
+	// RetiredAt is the time the key stops signing tokens, zero means the key is not retired.
+	RetiredAt time.Time
+	// NotAfter is the time the key stops verifying tokens, zero means never.
+	NotAfter time.Time
+	// LegacyTokenDeadline is the time the access and refresh tokens signed by the JWT secret stop being accepted,
+	// it's recorded once when the first key is created, and carried over by the keys created afterwards.
+	LegacyTokenDeadline time.Time
+}
+
+func (k *Key) signingMethod() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+func (k *Key) expired(now time.Time) bool {
+	return !k.NotAfter.IsZero() && now.After(k.NotAfter)
+}
+
+func (k *Key) privateJSONWebKey() *jose.JSONWebKey {
+	return &jose.JSONWebKey{Key: k.PrivateKey, KeyID: k.KeyID, Algorithm: k.Algorithm, Use: "sig"}
+}
+
+func (k *Key) publicJSONWebKey() *jose.JSONWebKey {
+	return &jose.JSONWebKey{Key: k.PublicKey, KeyID: k.KeyID, Algorithm: k.Algorithm, Use: "sig"}
+}
+
+// Keyring holds the keys signing the tokens. The newest key activated which is not retired is the active
+// signing key, the retired keys keep verifying the tokens signed by them until the end of the grace period,
+// so that a rotation doesn't invalidate the outstanding tokens. The static key configured in the issuer
+// options signs the tokens until any key is activated, and keeps verifying tokens afterwards. If there is
+// neither a static key nor an active key, the pending key activated first signs the tokens rather than failing
+// the logins, it's loaded by the replicas already. No token is signed if there is no key at all.
+type Keyring struct {
+	mutex  sync.RWMutex
+	static *Key
+	keys   []*Key
+}
+
+func NewKeyring(static *Key) *Keyring {
+	return &Keyring{static: static}
+}
+
+// Update replaces the keys loaded from the key source, the static key is retained.
+func (r *Keyring) Update(keys []*Key) {
+	sorted := make([]*Key, len(keys))
+	copy(sorted, keys)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ActivatedAt.Equal(sorted[j].ActivatedAt) {
+			return sorted[i].KeyID > sorted[j].KeyID
+		}
+		return sorted[i].ActivatedAt.After(sorted[j].ActivatedAt)
+	})
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.keys = sorted
+}
+
+// SigningKey returns the active signing key, nil if there is none.
+func (r *Keyring) SigningKey() *Key {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	now := time.Now()
+	for _, key := range r.keys {
+		if key.PrivateKey != nil && key.RetiredAt.IsZero() && !now.Before(key.ActivatedAt) {
+			return key
+		}
+	}
+	if r.static != nil {
+		return r.static
+	}
+	for i := len(r.keys) - 1; i >= 0; i-- {
+		if r.keys[i].PrivateKey != nil && r.keys[i].RetiredAt.IsZero() {
+			return r.keys[i]
+		}
+	}
+	return nil
+}
+
+// VerificationKey returns the key of the key ID which hasn't expired, the key ID is optional
+// for the tokens signed by the static key.
+func (r *Keyring) VerificationKey(keyID string) *Key {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	if r.static != nil && (keyID == "" || keyID == r.static.KeyID) {
+		return r.static
+	}
+	now := time.Now()
+	for _, key := range r.keys {
+		if key.KeyID == keyID && !key.expired(now) {
+			return key
+		}
+	}
+	return nil
+}
+
+// LegacyTokenDeadline returns the earliest deadline of the tokens signed by the JWT secret recorded by the keys,
+// zero if there is none.
+func (r *Keyring) LegacyTokenDeadline() time.Time {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var deadline time.Time
+	for _, key := range r.keys {
+		if !key.LegacyTokenDeadline.IsZero() && (deadline.IsZero() || key.LegacyTokenDeadline.Before(deadline)) {
+			deadline = key.LegacyTokenDeadline
+		}
+	}
+	return deadline
+}
+
+// PublicKeys returns the keys verifying the tokens, the active signing key goes first.
+func (r *Keyring) PublicKeys() []jose.JSONWebKey {
+	active := r.SigningKey()
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	keys := make([]jose.JSONWebKey, 0, len(r.keys)+1)
+	if active != nil {
+		keys = append(keys, *active.publicJSONWebKey())
+	}
+	now := time.Now()
+	for _, key := range r.keys {
+		if key != active && !key.expired(now) {
+			keys = append(keys, *key.publicJSONWebKey())
+		}
+	}
+	if r.static != nil && r.static != active {
+		keys = append(keys, *r.static.publicJSONWebKey())
+	}
+	return keys
+}
+
+// ParseSigningKey parses a PEM encoded RSA, ECDSA P-256 or Ed25519 private key,
+// the key ID is the JWK thumbprint of the public key.
+func ParseSigningKey(data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("private key not in pem format")
+	}
+
+	var privateKey interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %v", err)
+	}
+
+	key := &Key{}
+	switch k := privateKey.(type) {
+	case *rsa.PrivateKey:
+		key.Algorithm = jwt.SigningMethodRS256.Alg()
+		key.PrivateKey = k
+	case *ecdsa.PrivateKey:
+		if k.Curve != elliptic.P256() {
+			return nil, fmt.Errorf("unsupported elliptic curve %s", k.Curve.Params().Name)
+		}
+		key.Algorithm = jwt.SigningMethodES256.Alg()
+		key.PrivateKey = k
+	case ed25519.PrivateKey:
+		key.Algorithm = jwt.SigningMethodEdDSA.Alg()
+		key.PrivateKey = k
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", privateKey)
+	}
+	key.PublicKey = key.PrivateKey.Public()
+
+	thumbprint, err := key.publicJSONWebKey().Thumbprint(crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute key id: %v", err)
+	}
+	key.KeyID = base64.RawURLEncoding.EncodeToString(thumbprint)
+	return key, nil
+}
+
+// GenerateSigningKeyData generates a PEM encoded private key of the algorithm.
+func GenerateSigningKeyData(algorithm string) ([]byte, error) {
+	switch algorithm {
+	case "", jwt.SigningMethodRS256.Alg():
+		return generatePrivateKeyData()
+	case jwt.SigningMethodES256.Alg():
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), cryptorand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate private key: %v", err)
+		}
+		return marshalPKCS8PrivateKey(privateKey)
+	case jwt.SigningMethodEdDSA.Alg():
+		_, privateKey, err := ed25519.GenerateKey(cryptorand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate private key: %v", err)
+		}
+		return marshalPKCS8PrivateKey(privateKey)
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm %s", algorithm)
+	}
+}
+
+func marshalPKCS8PrivateKey(privateKey interface{}) ([]byte, error) {
+	data, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: data}), nil
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package token
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	runtimecache "sigs.k8s.io/controller-runtime/pkg/cache"
+
+	"kubesphere.io/kubesphere/pkg/constants"
+)
+
+const (
+	// SecretTypeSigningKey is the type of the Secrets holding the keys of the keyring,
+	// the Secrets are in the kubesphere-system namespace.
+	SecretTypeSigningKey corev1.SecretType = "iam.kubesphere.io/signing-key"
+	// SecretDataKeySigningKey is the key of the PEM encoded private key in the Secret.
+	SecretDataKeySigningKey = "key"
+)
+
+// IsSigningKeySecret returns whether the Secret holds a key of the keyring.
+func IsSigningKeySecret(secret *corev1.Secret) bool {
+	return secret.Namespace == constants.KubeSphereNamespace && secret.Type == SecretTypeSigningKey
+}
+
+// KeyFromSecret parses the key in the Secret, the key starts signing tokens at the activation time,
+// and stops verifying tokens at the end of the grace period after it's retired. The activation time is
+// annotated rather than taken from the creation timestamp, which differs between the copies of the key
+// in the member clusters.
+func KeyFromSecret(secret *corev1.Secret, gracePeriod time.Duration) (*Key, error) {
+	key, err := ParseSigningKey(secret.Data[SecretDataKeySigningKey])
+	if err != nil {
+		return nil, err
+	}
+	key.ActivatedAt = secret.CreationTimestamp.Time
+	if activatedAt := secret.Annotations[iamv1beta1.SigningKeyActivatedAtAnnotation]; activatedAt != "" {
+		if key.ActivatedAt, err = time.Parse(time.RFC3339, activatedAt); err != nil {
+			return nil, fmt.Errorf("invalid annotation %s: %v", iamv1beta1.SigningKeyActivatedAtAnnotation, err)
+		}
+	}
+	if retiredAt := secret.Annotations[iamv1beta1.SigningKeyRetiredAtAnnotation]; retiredAt != "" {
+		if key.RetiredAt, err = time.Parse(time.RFC3339, retiredAt); err != nil {
+			return nil, fmt.Errorf("invalid annotation %s: %v", iamv1beta1.SigningKeyRetiredAtAnnotation, err)
+		}
+		key.NotAfter = key.RetiredAt.Add(gracePeriod)
+	}
+	if deadline := secret.Annotations[iamv1beta1.LegacyTokenDeadlineAnnotation]; deadline != "" {
+		if key.LegacyTokenDeadline, err = time.Parse(time.RFC3339, deadline); err != nil {
+			return nil, fmt.Errorf("invalid annotation %s: %v", iamv1beta1.LegacyTokenDeadlineAnnotation, err)
+		}
+	}
+	return key, nil
+}
+
+// KeyringSource hot-reloads the keyring from the Secrets.
+type KeyringSource struct {
+	keyring     *Keyring
+	gracePeriod time.Duration
+
+	mutex sync.Mutex
+	keys  map[string]*Key
+}
+
+func NewKeyringSource(keyring *Keyring, gracePeriod time.Duration) *KeyringSource {
+	return &KeyringSource{keyring: keyring, gracePeriod: gracePeriod, keys: map[string]*Key{}}
+}
+
+func (s *KeyringSource) WatchSecrets(ctx context.Context, cache runtimecache.Cache) error {
+	informer, err := cache.GetInformer(ctx, &corev1.Secret{})
+	if err != nil {
+		return fmt.Errorf("get informer failed: %w", err)
+	}
+
+	_, err = informer.AddEventHandler(toolscache.FilteringResourceEventHandler{
+		FilterFunc: func(obj interface{}) bool {
+			secret, ok := secretFrom(obj)
+			return ok && IsSigningKeySecret(secret)
+		},
+		Handler: &toolscache.ResourceEventHandlerFuncs{
+			AddFunc: func(obj interface{}) {
+				s.OnSecretChange(obj.(*corev1.Secret))
+			},
+			UpdateFunc: func(old, new interface{}) {
+				s.OnSecretChange(new.(*corev1.Secret))
+			},
+			DeleteFunc: func(obj interface{}) {
+				if secret, ok := secretFrom(obj); ok {
+					s.OnSecretDelete(secret)
+				}
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("add event handler failed: %w", err)
+	}
+	return nil
+}
+
+// secretFrom returns the Secret of the event, the deleted Secret is in the tombstone
+// if the deletion is missed by the watch.
+func secretFrom(obj interface{}) (*corev1.Secret, bool) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	secret, ok := obj.(*corev1.Secret)
+	return secret, ok
+}
+
+func (s *KeyringSource) OnSecretChange(secret *corev1.Secret) {
+	key, err := KeyFromSecret(secret, s.gracePeriod)
+	if err != nil {
+		// keep the keys in effect if the new one is invalid
+		klog.Errorf("failed to load signing key from secret %s: %s", secret.Name, err)
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.keys[secret.Name] = key
+	s.update()
+	klog.V(4).Infof("signing key %s loaded from secret %s", key.KeyID, secret.Name)
+}
+
+func (s *KeyringSource) OnSecretDelete(secret *corev1.Secret) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.keys, secret.Name)
+	s.update()
+}
+
+func (s *KeyringSource) update() {
+	keys := make([]*Key, 0, len(s.keys))
+	for _, key := range s.keys {
+		keys = append(keys, key)
+	}
+	s.keyring.Update(keys)
+}
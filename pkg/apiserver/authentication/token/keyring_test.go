@@ -0,0 +1,185 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package token
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	toolscache "k8s.io/client-go/tools/cache"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/oauth"
+	"kubesphere.io/kubesphere/pkg/constants"
+)
+
+func newTestKey(t *testing.T, algorithm string, activatedAt time.Time) *Key {
+	data, err := GenerateSigningKeyData(algorithm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParseSigningKey(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key.ActivatedAt = activatedAt
+	return key
+}
+
+func newTestIssuer(t *testing.T) *issuer {
+	iss, err := NewIssuer(&oauth.IssuerOptions{
+		URL:         "kubesphere",
+		JWTSecret:   "kubesphere",
+		SignKeyData: base64.StdEncoding.EncodeToString([]byte(privateKeyData)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return iss.(*issuer)
+}
+
+func issueIDToken(t *testing.T, iss *issuer) string {
+	token, err := iss.IssueTo(&IssueRequest{
+		User:      &user.DefaultInfo{Name: "user1"},
+		Claims:    Claims{TokenType: IDToken},
+		ExpiresIn: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestParseSigningKey(t *testing.T) {
+	for _, algorithm := range SupportedSigningAlgorithms {
+		t.Run(algorithm, func(t *testing.T) {
+			key := newTestKey(t, algorithm, time.Now())
+			assert.Equal(t, algorithm, key.Algorithm)
+			assert.NotEmpty(t, key.KeyID)
+			assert.NotNil(t, key.PublicKey)
+
+			iss := newTestIssuer(t)
+			iss.keyring.Update([]*Key{key})
+			verified, err := iss.Verify(issueIDToken(t, iss))
+			assert.NoError(t, err)
+			assert.Equal(t, "user1", verified.Username)
+		})
+	}
+
+	_, err := ParseSigningKey([]byte("invalid"))
+	assert.Error(t, err)
+	_, err = GenerateSigningKeyData("HS256")
+	assert.Error(t, err)
+}
+
+func TestKeyringRotation(t *testing.T) {
+	iss := newTestIssuer(t)
+	static := iss.keyring.SigningKey()
+	staticSigned := issueIDToken(t, iss)
+
+	now := time.Now()
+	oldKey := newTestKey(t, "ES256", now.Add(-time.Hour))
+	iss.keyring.Update([]*Key{oldKey})
+	assert.Equal(t, oldKey, iss.keyring.SigningKey())
+	oldSigned := issueIDToken(t, iss)
+
+	// the new key only verifies tokens until it's activated
+	newKey := newTestKey(t, "EdDSA", now.Add(time.Minute))
+	iss.keyring.Update([]*Key{oldKey, newKey})
+	assert.Equal(t, oldKey, iss.keyring.SigningKey())
+	assert.Equal(t, newKey, iss.keyring.VerificationKey(newKey.KeyID))
+	newKey.ActivatedAt = now
+
+	// rotate, the old key keeps verifying the tokens during the grace period
+	retired := *oldKey
+	retired.RetiredAt = now
+	retired.NotAfter = now.Add(time.Hour)
+	iss.keyring.Update([]*Key{&retired, newKey})
+	assert.Equal(t, newKey, iss.keyring.SigningKey())
+
+	_, err := iss.Verify(oldSigned)
+	assert.NoError(t, err)
+	_, err = iss.Verify(staticSigned)
+	assert.NoError(t, err)
+	_, err = iss.Verify(issueIDToken(t, iss))
+	assert.NoError(t, err)
+
+	keys := iss.keyring.PublicKeys()
+	assert.Len(t, keys, 3)
+	assert.Equal(t, newKey.KeyID, keys[0].KeyID)
+	assert.Equal(t, oldKey.KeyID, keys[1].KeyID)
+	assert.Equal(t, static.KeyID, keys[2].KeyID)
+
+	// the old key expires at the end of the grace period
+	retired.NotAfter = now.Add(-time.Second)
+	iss.keyring.Update([]*Key{&retired, newKey})
+	_, err = iss.Verify(oldSigned)
+	assert.Error(t, err)
+	assert.Len(t, iss.keyring.PublicKeys(), 2)
+
+	// fall back to the static key once all keys are removed
+	iss.keyring.Update(nil)
+	assert.Equal(t, static, iss.keyring.SigningKey())
+}
+
+func TestKeyringSource(t *testing.T) {
+	data, err := GenerateSigningKeyData("ES256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now().Truncate(time.Second)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "signing-key-abcde",
+			Namespace:         constants.KubeSphereNamespace,
+			CreationTimestamp: metav1.NewTime(now),
+		},
+		Type: SecretTypeSigningKey,
+		Data: map[string][]byte{SecretDataKeySigningKey: data},
+	}
+	assert.True(t, IsSigningKeySecret(secret))
+
+	keyring := NewKeyring(nil)
+	source := NewKeyringSource(keyring, time.Hour)
+	source.OnSecretChange(secret)
+	active := keyring.SigningKey()
+	assert.NotNil(t, active)
+	assert.Equal(t, "ES256", active.Algorithm)
+
+	// an invalid key doesn't replace the keys in effect
+	invalid := secret.DeepCopy()
+	invalid.Data[SecretDataKeySigningKey] = []byte("invalid")
+	source.OnSecretChange(invalid)
+	assert.Equal(t, active.KeyID, keyring.SigningKey().KeyID)
+
+	// the activation time is taken from the annotation
+	activatedAt := now.Add(time.Minute)
+	pending := secret.DeepCopy()
+	pending.Annotations = map[string]string{iamv1beta1.SigningKeyActivatedAtAnnotation: activatedAt.UTC().Format(time.RFC3339)}
+	key, err := KeyFromSecret(pending, time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, activatedAt.Equal(key.ActivatedAt))
+
+	retired := secret.DeepCopy()
+	retired.Annotations = map[string]string{iamv1beta1.SigningKeyRetiredAtAnnotation: now.UTC().Format(time.RFC3339)}
+	source.OnSecretChange(retired)
+	assert.Nil(t, keyring.SigningKey())
+	key = keyring.VerificationKey(active.KeyID)
+	assert.NotNil(t, key)
+	assert.True(t, now.Add(time.Hour).Equal(key.NotAfter))
+
+	// the deletion missed by the watch is delivered in a tombstone
+	deleted, ok := secretFrom(toolscache.DeletedFinalStateUnknown{Key: "kubesphere-system/signing-key-abcde", Obj: retired})
+	assert.True(t, ok)
+	source.OnSecretDelete(deleted)
+	assert.Nil(t, keyring.VerificationKey(active.KeyID))
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+// Package totp implements the Time-Based One-Time Password algorithm (RFC 6238)
+// with the parameters supported by common authenticator apps: HMAC-SHA1, 6 digits and a 30s period.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Digits = 6
+	Period = 30 * time.Second
+	// Skew is the number of periods before and after the current one in which a code is still accepted,
+	// to tolerate clock drift between the server and the authenticator.
+	Skew = 1
+
+	secretSize = 20
+	// minSecretSize is the minimum length of the secret recommended by RFC 4226, 160 bits
+	minSecretSize = 20
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Key is the shared secret of an authenticator, it is exchanged with the
+// authenticator through a key URI, e.g. by a QR code.
+type Key struct {
+	Issuer      string
+	AccountName string
+	// Secret is the base32 encoded shared secret.
+	Secret string
+}
+
+// GenerateKey generates a key with a random secret.
+func GenerateKey(issuer, accountName string) (*Key, error) {
+	secret := make([]byte, secretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return &Key{Issuer: issuer, AccountName: accountName, Secret: encoding.EncodeToString(secret)}, nil
+}
+
+// ParseKey parses a key URI in the format of
+// otpauth://totp/ISSUER:ACCOUNT?secret=SECRET&issuer=ISSUER
+func ParseKey(uri string) (*Key, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "otpauth" || u.Host != "totp" {
+		return nil, fmt.Errorf("invalid totp key uri")
+	}
+	values := u.Query()
+	if algorithm := values.Get("algorithm"); algorithm != "" && !strings.EqualFold(algorithm, "SHA1") {
+		return nil, fmt.Errorf("unsupported totp algorithm %s", algorithm)
+	}
+	if digits := values.Get("digits"); digits != "" && digits != fmt.Sprint(Digits) {
+		return nil, fmt.Errorf("unsupported totp digits %s", digits)
+	}
+	if period := values.Get("period"); period != "" && period != fmt.Sprint(int(Period.Seconds())) {
+		return nil, fmt.Errorf("unsupported totp period %s", period)
+	}
+
+	key := &Key{Issuer: values.Get("issuer"), Secret: strings.ToUpper(values.Get("secret"))}
+	label := strings.TrimPrefix(u.Path, "/")
+	if issuer, account, found := strings.Cut(label, ":"); found {
+		key.AccountName = account
+		if key.Issuer == "" {
+			key.Issuer = issuer
+		}
+	} else {
+		key.AccountName = label
+	}
+	if _, err = key.secret(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// URI returns the key URI understood by authenticator apps.
+func (k *Key) URI() string {
+	values := url.Values{}
+	values.Set("secret", k.Secret)
+	values.Set("issuer", k.Issuer)
+	values.Set("algorithm", "SHA1")
+	values.Set("digits", fmt.Sprint(Digits))
+	values.Set("period", fmt.Sprint(int(Period.Seconds())))
+	u := url.URL{
+		Scheme:   "otpauth",
+		Host:     "totp",
+		Path:     "/" + k.Issuer + ":" + k.AccountName,
+		RawQuery: values.Encode(),
+	}
+	return u.String()
+}
+
+func (k *Key) secret() ([]byte, error) {
+	secret, err := encoding.DecodeString(strings.TrimRight(k.Secret, "="))
+	if err != nil || len(secret) < minSecretSize {
+		return nil, fmt.Errorf("invalid totp secret")
+	}
+	return secret, nil
+}
+
+// Code returns the code of the key at the given time.
+func (k *Key) Code(t time.Time) (string, error) {
+	secret, err := k.secret()
+	if err != nil {
+		return "", err
+	}
+	return code(secret, counter(t)), nil
+}
+
+// Validate validates the code at the given time, and returns the time step
+// of the code, which can be remembered to reject replays of the code.
+func (k *Key) Validate(passcode string, t time.Time) (int64, bool) {
+	secret, err := k.secret()
+	if err != nil || len(passcode) != Digits {
+		return 0, false
+	}
+	current := counter(t)
+	for step := current - Skew; step <= current+Skew; step++ {
+		if subtle.ConstantTimeCompare([]byte(code(secret, step)), []byte(passcode)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+func counter(t time.Time) int64 {
+	return t.Unix() / int64(Period.Seconds())
+}
+
+// code implements HOTP (RFC 4226) with dynamic truncation.
+func code(secret []byte, counter int64) string {
+	mac := hmac.New(sha1.New, secret)
+	_ = binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", Digits, value%uint32(math.Pow10(Digits)))
+}
@@ -0,0 +1,77 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Key is the SHA1 seed of the test vectors in RFC 6238 Appendix B.
+var rfc6238Key = &Key{Issuer: "KubeSphere", AccountName: "admin", Secret: encoding.EncodeToString([]byte("12345678901234567890"))}
+
+func TestCode(t *testing.T) {
+	// the last 6 digits of the 8 digits SHA1 test vectors
+	tests := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range tests {
+		got, err := rfc6238Key.Code(time.Unix(unix, 0))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got != want {
+			t.Errorf("code at %d = %s, want %s", unix, got, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	code, _ := rfc6238Key.Code(now)
+
+	if step, ok := rfc6238Key.Validate(code, now.Add(Period)); !ok || step != counter(now) {
+		t.Errorf("code of the previous period should be accepted")
+	}
+	if _, ok := rfc6238Key.Validate(code, now.Add(3*Period)); ok {
+		t.Errorf("code out of the skew should be rejected")
+	}
+	if _, ok := rfc6238Key.Validate("12345", now); ok {
+		t.Errorf("code of wrong length should be rejected")
+	}
+}
+
+func TestKeyURI(t *testing.T) {
+	key, err := GenerateKey("KubeSphere", "admin")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := ParseKey(key.URI())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *parsed != *key {
+		t.Errorf("parsed key = %+v, want %+v", parsed, key)
+	}
+
+	for _, uri := range []string{
+		"https://totp/KubeSphere:admin?secret=GEZDGNBV",
+		"otpauth://totp/KubeSphere:admin?secret=GEZDGNBV&algorithm=SHA256",
+		"otpauth://totp/KubeSphere:admin?secret=invalid!",
+		// the secret shorter than 160 bits is guessable
+		"otpauth://totp/KubeSphere:admin?secret=GE",
+	} {
+		if _, err = ParseKey(uri); err == nil {
+			t.Errorf("expected error parsing %s, didn't get any", uri)
+		}
+	}
+}
@@ -8,6 +8,7 @@ package authorization
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/pflag"
 	"k8s.io/klog/v2"
@@ -17,10 +18,14 @@ import (
 
 type Options struct {
 	Mode string `json:"mode" yaml:"mode"`
+	// DecisionCacheTTL is the duration to cache the decisions of the RBAC authorizer for,
+	// the cached decisions are invalidated once the roles or the role bindings are changed.
+	// Zero means the decisions are not cached.
+	DecisionCacheTTL time.Duration `json:"decisionCacheTTL" yaml:"decisionCacheTTL"`
 }
 
 func NewOptions() *Options {
-	return &Options{Mode: RBAC}
+	return &Options{Mode: RBAC, DecisionCacheTTL: 10 * time.Second}
 }
 
 var (
@@ -31,6 +36,7 @@ var (
 
 func (o *Options) AddFlags(fs *pflag.FlagSet, s *Options) {
 	fs.StringVar(&o.Mode, "authorization", s.Mode, "Authorization setting, allowed values: AlwaysDeny, AlwaysAllow, RBAC.")
+	fs.DurationVar(&o.DecisionCacheTTL, "authorization-decision-cache-ttl", s.DecisionCacheTTL, "The duration to cache the decisions of the RBAC authorizer for, zero disables the cache.")
 }
 
 func (o *Options) Validate() []error {
@@ -40,5 +46,8 @@ func (o *Options) Validate() []error {
 		klog.Error(err)
 		errs = append(errs, err)
 	}
+	if o.DecisionCacheTTL < 0 {
+		errs = append(errs, fmt.Errorf("authorization decision cache ttl must not be negative"))
+	}
 	return errs
 }
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package rbac
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	utilcache "k8s.io/apimachinery/pkg/util/cache"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+)
+
+const maxCachedDecisions = 8192
+
+// decisionCache caches the authorization decisions for a short time. All the cached decisions are
+// invalidated at once by bumping the generation when the bindings or the roles are changed.
+type decisionCache struct {
+	cache      *utilcache.LRUExpireCache
+	ttl        time.Duration
+	generation atomic.Uint64
+}
+
+type cachedDecision struct {
+	decision   authorizer.Decision
+	reason     string
+	generation uint64
+}
+
+func newDecisionCache(ttl time.Duration) *decisionCache {
+	return &decisionCache{cache: utilcache.NewLRUExpireCache(maxCachedDecisions), ttl: ttl}
+}
+
+func (c *decisionCache) get(key string) (*cachedDecision, bool) {
+	value, ok := c.cache.Get(key)
+	if !ok {
+		return nil, false
+	}
+	decision := value.(*cachedDecision)
+	if decision.generation != c.generation.Load() {
+		return nil, false
+	}
+	return decision, true
+}
+
+// add caches the decision made in the generation, which must be loaded before making the decision,
+// so that the decision made with the stale bindings or roles is never served.
+func (c *decisionCache) add(key string, generation uint64, decision authorizer.Decision, reason string) {
+	c.cache.Add(key, &cachedDecision{decision: decision, reason: reason, generation: generation}, c.ttl)
+}
+
+func (c *decisionCache) invalidate() {
+	c.generation.Add(1)
+}
+
+// decisionKey identifies the request attributes which the decisions depend on, including the ones
+// that might be used by the rego policies.
+func decisionKey(requestAttributes authorizer.Attributes) string {
+	b := &strings.Builder{}
+	write := func(values ...string) {
+		for _, value := range values {
+			b.WriteString(strconv.Quote(value))
+			b.WriteByte(' ')
+		}
+	}
+
+	u := requestAttributes.GetUser()
+	if u != nil {
+		write(u.GetName(), u.GetUID())
+		groups := append([]string(nil), u.GetGroups()...)
+		sort.Strings(groups)
+		write(strconv.Itoa(len(groups)))
+		write(groups...)
+		extra := u.GetExtra()
+		extraKeys := make([]string, 0, len(extra))
+		for key := range extra {
+			extraKeys = append(extraKeys, key)
+		}
+		sort.Strings(extraKeys)
+		write(strconv.Itoa(len(extraKeys)))
+		for _, key := range extraKeys {
+			write(key, strconv.Itoa(len(extra[key])))
+			write(extra[key]...)
+		}
+	}
+
+	write(requestAttributes.GetVerb(),
+		strconv.FormatBool(requestAttributes.IsResourceRequest()),
+		strconv.FormatBool(requestAttributes.IsKubernetesRequest()),
+		requestAttributes.GetResourceScope(),
+		requestAttributes.GetCluster(),
+		requestAttributes.GetWorkspace(),
+		requestAttributes.GetNamespace(),
+		requestAttributes.GetAPIGroup(),
+		requestAttributes.GetAPIVersion(),
+		requestAttributes.GetResource(),
+		requestAttributes.GetSubresource(),
+		requestAttributes.GetName(),
+		requestAttributes.GetPath())
+	return b.String()
+}
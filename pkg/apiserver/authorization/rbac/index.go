@@ -0,0 +1,297 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package rbac
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	toolscache "k8s.io/client-go/tools/cache"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	runtimecache "sigs.k8s.io/controller-runtime/pkg/cache"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	rbacutils "kubesphere.io/kubesphere/pkg/utils/rbac"
+)
+
+// clusterScope is the scope of the bindings which are not namespaced or workspaced in the index.
+const clusterScope = ""
+
+// bindingIndex indexes the role bindings by the subjects, so that the authorizer visits only the bindings
+// which may apply to the user instead of listing all the bindings for every request.
+type bindingIndex struct {
+	globalRoleBindings    *subjectIndex[*iamv1beta1.GlobalRoleBinding]
+	clusterRoleBindings   *subjectIndex[*iamv1beta1.ClusterRoleBinding]
+	workspaceRoleBindings *subjectIndex[*iamv1beta1.WorkspaceRoleBinding]
+	roleBindings          *subjectIndex[*iamv1beta1.RoleBinding]
+}
+
+// newBindingIndex builds the index from the informers, onChange is called once the bindings, the roles,
+// or the namespaces are changed, which may change the authorization decisions.
+func newBindingIndex(ctx context.Context, informers runtimecache.Informers, onChange func()) (*bindingIndex, error) {
+	index := &bindingIndex{
+		globalRoleBindings: newSubjectIndex(onChange,
+			func(*iamv1beta1.GlobalRoleBinding) string { return clusterScope },
+			func(b *iamv1beta1.GlobalRoleBinding) []rbacv1.Subject { return b.Subjects }),
+		clusterRoleBindings: newSubjectIndex(onChange,
+			func(*iamv1beta1.ClusterRoleBinding) string { return clusterScope },
+			func(b *iamv1beta1.ClusterRoleBinding) []rbacv1.Subject { return b.Subjects }),
+		workspaceRoleBindings: newSubjectIndex(onChange,
+			func(b *iamv1beta1.WorkspaceRoleBinding) string { return b.Labels[tenantv1beta1.WorkspaceLabel] },
+			func(b *iamv1beta1.WorkspaceRoleBinding) []rbacv1.Subject { return b.Subjects }),
+		roleBindings: newSubjectIndex(onChange,
+			func(b *iamv1beta1.RoleBinding) string { return b.Namespace },
+			func(b *iamv1beta1.RoleBinding) []rbacv1.Subject { return b.Subjects }),
+	}
+
+	var err error
+	if index.globalRoleBindings.hasSynced, err = addEventHandler(ctx, informers, &iamv1beta1.GlobalRoleBinding{}, index.globalRoleBindings); err != nil {
+		return nil, err
+	}
+	if index.clusterRoleBindings.hasSynced, err = addEventHandler(ctx, informers, &iamv1beta1.ClusterRoleBinding{}, index.clusterRoleBindings); err != nil {
+		return nil, err
+	}
+	if index.workspaceRoleBindings.hasSynced, err = addEventHandler(ctx, informers, &iamv1beta1.WorkspaceRoleBinding{}, index.workspaceRoleBindings); err != nil {
+		return nil, err
+	}
+	if index.roleBindings.hasSynced, err = addEventHandler(ctx, informers, &iamv1beta1.RoleBinding{}, index.roleBindings); err != nil {
+		return nil, err
+	}
+
+	// the rules of the roles and the workspaces of the namespaces are resolved on demand,
+	// the changes of them only invalidate the cached decisions
+	changeHandler := toolscache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { onChange() },
+		UpdateFunc: func(interface{}, interface{}) { onChange() },
+		DeleteFunc: func(interface{}) { onChange() },
+	}
+	for _, obj := range []runtimeclient.Object{
+		&iamv1beta1.GlobalRole{},
+		&iamv1beta1.ClusterRole{},
+		&iamv1beta1.WorkspaceRole{},
+		&iamv1beta1.Role{},
+		&corev1.Namespace{},
+	} {
+		if _, err = addEventHandler(ctx, informers, obj, changeHandler); err != nil {
+			return nil, err
+		}
+	}
+	return index, nil
+}
+
+func addEventHandler(ctx context.Context, informers runtimecache.Informers, obj runtimeclient.Object, handler toolscache.ResourceEventHandler) (func() bool, error) {
+	informer, err := informers.GetInformer(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	registration, err := informer.AddEventHandler(handler)
+	if err != nil {
+		return nil, err
+	}
+	// the registration isn't implemented by the fake informers
+	if registration == nil {
+		return informer.HasSynced, nil
+	}
+	return registration.HasSynced, nil
+}
+
+// subjectIndex indexes the bindings of a kind by the scope and the subjects.
+type subjectIndex[T runtimeclient.Object] struct {
+	lock         sync.RWMutex
+	scopeFunc    func(T) string
+	subjectsFunc func(T) []rbacv1.Subject
+	onChange     func()
+	hasSynced    func() bool
+	// scope -> subject key -> binding key -> binding
+	bindings map[string]map[string]map[string]T
+	// binding key -> the position of the binding in the index
+	indexed map[string]indexedBinding
+	// binding key -> the timer calling onChange once the time-bound binding becomes active or expires
+	timers map[string]*time.Timer
+}
+
+type indexedBinding struct {
+	scope       string
+	subjectKeys []string
+}
+
+func newSubjectIndex[T runtimeclient.Object](onChange func(), scopeFunc func(T) string, subjectsFunc func(T) []rbacv1.Subject) *subjectIndex[T] {
+	return &subjectIndex[T]{
+		scopeFunc:    scopeFunc,
+		subjectsFunc: subjectsFunc,
+		onChange:     onChange,
+		bindings:     make(map[string]map[string]map[string]T),
+		indexed:      make(map[string]indexedBinding),
+		timers:       make(map[string]*time.Timer),
+	}
+}
+
+// subjectKey returns the key of the binding subject in the index. The service accounts can be referenced in
+// several forms, they share the same key, and are matched by appliesTo like the others.
+func subjectKey(subject rbacv1.Subject) string {
+	switch subject.Kind {
+	case rbacv1.UserKind, rbacv1.GroupKind:
+		return subject.Kind + ":" + subject.Name
+	default:
+		return subject.Kind
+	}
+}
+
+// userSubjectKeys returns the keys of all the subjects which may apply to the user.
+func userSubjectKeys(u user.Info) []string {
+	keys := make([]string, 0, len(u.GetGroups())+2)
+	keys = append(keys, rbacv1.UserKind+":"+u.GetName(), rbacv1.ServiceAccountKind)
+	for _, group := range u.GetGroups() {
+		keys = append(keys, rbacv1.GroupKind+":"+group)
+	}
+	return keys
+}
+
+func (i *subjectIndex[T]) synced() bool {
+	return i.hasSynced != nil && i.hasSynced()
+}
+
+// lookup returns the bindings in the scope whose subjects may apply to the user, sorted by the keys.
+func (i *subjectIndex[T]) lookup(scope string, u user.Info) []T {
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	bySubject := i.bindings[scope]
+	if len(bySubject) == 0 {
+		return nil
+	}
+	matched := make(map[string]T)
+	for _, key := range userSubjectKeys(u) {
+		for bindingKey, binding := range bySubject[key] {
+			matched[bindingKey] = binding
+		}
+	}
+	bindingKeys := make([]string, 0, len(matched))
+	for bindingKey := range matched {
+		bindingKeys = append(bindingKeys, bindingKey)
+	}
+	sort.Strings(bindingKeys)
+	result := make([]T, 0, len(bindingKeys))
+	for _, bindingKey := range bindingKeys {
+		result = append(result, matched[bindingKey])
+	}
+	return result
+}
+
+func (i *subjectIndex[T]) OnAdd(obj interface{}, _ bool) {
+	if binding, ok := obj.(T); ok {
+		i.update(binding)
+	}
+}
+
+func (i *subjectIndex[T]) OnUpdate(_, newObj interface{}) {
+	if binding, ok := newObj.(T); ok {
+		i.update(binding)
+	}
+}
+
+func (i *subjectIndex[T]) OnDelete(obj interface{}) {
+	if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	binding, ok := obj.(T)
+	if !ok {
+		return
+	}
+	bindingKey := runtimeclient.ObjectKeyFromObject(binding).String()
+	i.lock.Lock()
+	i.remove(bindingKey)
+	i.stopTimer(bindingKey)
+	i.lock.Unlock()
+	i.onChange()
+}
+
+func (i *subjectIndex[T]) update(binding T) {
+	bindingKey := runtimeclient.ObjectKeyFromObject(binding).String()
+	scope := i.scopeFunc(binding)
+	entry := indexedBinding{scope: scope}
+
+	i.lock.Lock()
+	i.remove(bindingKey)
+	bySubject, ok := i.bindings[scope]
+	if !ok {
+		bySubject = make(map[string]map[string]T)
+		i.bindings[scope] = bySubject
+	}
+	for _, subject := range i.subjectsFunc(binding) {
+		key := subjectKey(subject)
+		if bySubject[key] == nil {
+			bySubject[key] = make(map[string]T)
+		}
+		bySubject[key][bindingKey] = binding
+		entry.subjectKeys = append(entry.subjectKeys, key)
+	}
+	i.indexed[bindingKey] = entry
+	i.watchValidity(bindingKey, binding)
+	i.lock.Unlock()
+	i.onChange()
+}
+
+// watchValidity calls onChange once the time-bound binding becomes active or expires, since no events
+// are received at that time. The timer of the previous version of the binding is replaced.
+// It must be called with the lock held.
+func (i *subjectIndex[T]) watchValidity(bindingKey string, binding T) {
+	i.stopTimer(bindingKey)
+	validity, err := rbacutils.GetBindingValidity(binding)
+	if err != nil {
+		return
+	}
+	next := validity.NextTransition(time.Now())
+	if next <= 0 {
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(next, func() {
+		i.lock.Lock()
+		// the binding has been updated or deleted since the timer was scheduled
+		if i.timers[bindingKey] != timer {
+			i.lock.Unlock()
+			return
+		}
+		i.watchValidity(bindingKey, binding)
+		i.lock.Unlock()
+		i.onChange()
+	})
+	i.timers[bindingKey] = timer
+}
+
+// stopTimer must be called with the lock held.
+func (i *subjectIndex[T]) stopTimer(bindingKey string) {
+	if timer, ok := i.timers[bindingKey]; ok {
+		timer.Stop()
+		delete(i.timers, bindingKey)
+	}
+}
+
+// remove must be called with the lock held.
+func (i *subjectIndex[T]) remove(bindingKey string) {
+	entry, ok := i.indexed[bindingKey]
+	if !ok {
+		return
+	}
+	delete(i.indexed, bindingKey)
+	bySubject := i.bindings[entry.scope]
+	for _, key := range entry.subjectKeys {
+		delete(bySubject[key], bindingKey)
+		if len(bySubject[key]) == 0 {
+			delete(bySubject, key)
+		}
+	}
+	if len(bySubject) == 0 {
+		delete(i.bindings, entry.scope)
+	}
+}
@@ -0,0 +1,516 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/rego"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+	"kubesphere.io/kubesphere/pkg/scheme"
+	rbacutils "kubesphere.io/kubesphere/pkg/utils/rbac"
+)
+
+// newIndexedTestAuthorizer returns the indexed authorizer with the fake informers synced with the static roles.
+func newIndexedTestAuthorizer(tb testing.TB, staticRoles *StaticRoles, decisionTTL time.Duration) (*Authorizer, *informertest.FakeInformers, runtimeclient.Client) {
+	amOperator, client, err := newMockAccessManager(staticRoles)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	informers := &informertest.FakeInformers{Scheme: scheme.Scheme}
+	authz, err := NewIndexedRBACAuthorizer(context.Background(), amOperator, informers, decisionTTL)
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	var objects []runtimeclient.Object
+	for _, binding := range staticRoles.globalRoleBindings {
+		objects = append(objects, binding)
+	}
+	for _, binding := range staticRoles.clusterRoleBindings {
+		objects = append(objects, binding)
+	}
+	for _, binding := range staticRoles.workspaceRoleBindings {
+		objects = append(objects, binding)
+	}
+	for _, binding := range staticRoles.roleBindings {
+		objects = append(objects, binding)
+	}
+	for _, obj := range []runtimeclient.Object{&iamv1beta1.GlobalRoleBinding{}, &iamv1beta1.ClusterRoleBinding{},
+		&iamv1beta1.WorkspaceRoleBinding{}, &iamv1beta1.RoleBinding{}} {
+		informer, err := informers.FakeInformerFor(context.Background(), obj)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		informer.Synced = true
+	}
+	for _, obj := range objects {
+		informer, err := informers.FakeInformerFor(context.Background(), obj)
+		if err != nil {
+			tb.Fatal(err)
+		}
+		informer.Add(obj.DeepCopyObject().(runtimeclient.Object))
+	}
+	return authz, informers, client
+}
+
+func indexTestRoles() *StaticRoles {
+	return &StaticRoles{
+		roles: []*iamv1beta1.Role{
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "namespace1", Name: "readpods"},
+				Rules:      []rbacv1.PolicyRule{{Verbs: []string{"get", "list"}, APIGroups: []string{""}, Resources: []string{"pods"}}},
+			},
+		},
+		clusterRoles: []*iamv1beta1.ClusterRole{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "node-viewer"},
+				Rules:      []rbacv1.PolicyRule{{Verbs: []string{"get"}, APIGroups: []string{""}, Resources: []string{"nodes"}}},
+			},
+		},
+		workspaceRoles: []*iamv1beta1.WorkspaceRole{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "ws1-admin", Labels: map[string]string{tenantv1beta1.WorkspaceLabel: "ws1"}},
+				Rules:      []rbacv1.PolicyRule{{Verbs: []string{"*"}, APIGroups: []string{"*"}, Resources: []string{"*"}}},
+			},
+		},
+		globalRoles: []*iamv1beta1.GlobalRole{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "users-viewer"},
+				Rules:      []rbacv1.PolicyRule{{Verbs: []string{"get"}, APIGroups: []string{"iam.kubesphere.io"}, Resources: []string{"users"}}},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "rego-users-viewer",
+					Annotations: map[string]string{iamv1beta1.RegoOverrideAnnotation: `package authz
+default allow = false
+allow {
+  input.Verb == "list"
+  input.Resource == "users"
+}`},
+				},
+			},
+		},
+		roleBindings: []*iamv1beta1.RoleBinding{
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "namespace1", Name: "readpods"},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, Name: "group1"}},
+				RoleRef:    rbacv1.RoleRef{APIGroup: iamv1beta1.GroupName, Kind: iamv1beta1.ResourceKindRole, Name: "readpods"},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Namespace: "namespace1", Name: "sa-readpods"},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, APIGroup: rbacv1.GroupName, Name: "robot"}},
+				RoleRef:    rbacv1.RoleRef{APIGroup: iamv1beta1.GroupName, Kind: iamv1beta1.ResourceKindRole, Name: "readpods"},
+			},
+		},
+		clusterRoleBindings: []*iamv1beta1.ClusterRoleBinding{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "bob-node-viewer"},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "bob"}},
+				RoleRef:    rbacv1.RoleRef{APIGroup: iamv1beta1.GroupName, Kind: iamv1beta1.ResourceKindClusterRole, Name: "node-viewer"},
+			},
+		},
+		workspaceRoleBindings: []*iamv1beta1.WorkspaceRoleBinding{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "ws1-admin-alice", Labels: map[string]string{tenantv1beta1.WorkspaceLabel: "ws1"}},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "alice"}},
+				RoleRef:    rbacv1.RoleRef{APIGroup: iamv1beta1.GroupName, Kind: iamv1beta1.ResourceKindWorkspaceRole, Name: "ws1-admin"},
+			},
+		},
+		globalRoleBindings: []*iamv1beta1.GlobalRoleBinding{
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "alice-users-viewer"},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "alice"}},
+				RoleRef:    rbacv1.RoleRef{APIGroup: iamv1beta1.GroupName, Kind: iamv1beta1.ResourceKindGlobalRole, Name: "users-viewer"},
+			},
+			{
+				ObjectMeta: metav1.ObjectMeta{Name: "bob-rego-users-viewer"},
+				Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "bob"}},
+				RoleRef:    rbacv1.RoleRef{APIGroup: iamv1beta1.GroupName, Kind: iamv1beta1.ResourceKindGlobalRole, Name: "rego-users-viewer"},
+			},
+		},
+	}
+}
+
+func TestIndexedRBACAuthorizer(t *testing.T) {
+	staticRoles := indexTestRoles()
+	listAuthorizer, err := newMockRBACAuthorizer(staticRoles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexedAuthorizer, _, _ := newIndexedTestAuthorizer(t, staticRoles, 0)
+	cachedAuthorizer, _, _ := newIndexedTestAuthorizer(t, staticRoles, time.Minute)
+
+	podsOf := func(u user.Info, verb string) authorizer.AttributesRecord {
+		return authorizer.AttributesRecord{User: u, Verb: verb, Namespace: "namespace1", Resource: "pods",
+			ResourceRequest: true, ResourceScope: request.NamespaceScope}
+	}
+	usersOf := func(u user.Info, verb string) authorizer.AttributesRecord {
+		return authorizer.AttributesRecord{User: u, Verb: verb, APIGroup: "iam.kubesphere.io", Resource: "users",
+			ResourceRequest: true, ResourceScope: request.GlobalScope}
+	}
+	tests := []struct {
+		attributes authorizer.AttributesRecord
+		expected   authorizer.Decision
+	}{
+		{attributes: podsOf(&user.DefaultInfo{Name: "carol", Groups: []string{"group1"}}, "list"), expected: authorizer.DecisionAllow},
+		{attributes: podsOf(&user.DefaultInfo{Name: "carol", Groups: []string{"group2"}}, "list"), expected: authorizer.DecisionNoOpinion},
+		{attributes: podsOf(&user.DefaultInfo{Name: "carol", Groups: []string{"group1"}}, "delete"), expected: authorizer.DecisionNoOpinion},
+		{attributes: podsOf(&user.DefaultInfo{Name: "system:serviceaccount:namespace1:robot"}, "get"), expected: authorizer.DecisionAllow},
+		{attributes: podsOf(&user.DefaultInfo{Name: "system:serviceaccount:namespace2:robot"}, "get"), expected: authorizer.DecisionNoOpinion},
+		{attributes: podsOf(&user.DefaultInfo{Name: "bob"}, "get"), expected: authorizer.DecisionNoOpinion},
+		{attributes: usersOf(&user.DefaultInfo{Name: "alice"}, "get"), expected: authorizer.DecisionAllow},
+		{attributes: usersOf(&user.DefaultInfo{Name: "bob"}, "list"), expected: authorizer.DecisionAllow},
+		{attributes: usersOf(&user.DefaultInfo{Name: "bob"}, "get"), expected: authorizer.DecisionNoOpinion},
+		{attributes: usersOf(&user.DefaultInfo{Name: "carol", Groups: []string{"group1"}}, "get"), expected: authorizer.DecisionNoOpinion},
+		{
+			attributes: authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "alice"}, Verb: "delete", Workspace: "ws1",
+				Resource: "devopsprojects", ResourceRequest: true, ResourceScope: request.WorkspaceScope},
+			expected: authorizer.DecisionAllow,
+		},
+		{
+			attributes: authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "alice"}, Verb: "delete", Workspace: "ws2",
+				Resource: "devopsprojects", ResourceRequest: true, ResourceScope: request.WorkspaceScope},
+			expected: authorizer.DecisionNoOpinion,
+		},
+		{
+			attributes: authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "bob"}, Verb: "get", Resource: "nodes",
+				ResourceRequest: true, ResourceScope: request.ClusterScope},
+			expected: authorizer.DecisionAllow,
+		},
+	}
+
+	for i, tt := range tests {
+		for name, authz := range map[string]*Authorizer{"list": listAuthorizer, "indexed": indexedAuthorizer, "cached": cachedAuthorizer} {
+			// twice to hit the decision cache
+			for j := 0; j < 2; j++ {
+				decision, _, err := authz.Authorize(tt.attributes)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if decision != tt.expected {
+					t.Errorf("case %d: %s authorizer expected %v, got %v", i, name, tt.expected, decision)
+				}
+			}
+		}
+	}
+}
+
+func TestIndexedRBACAuthorizerInvalidation(t *testing.T) {
+	staticRoles := indexTestRoles()
+	authz, informers, client := newIndexedTestAuthorizer(t, staticRoles, time.Hour)
+	ctx := context.Background()
+
+	listPods := authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "carol", Groups: []string{"group1"}}, Verb: "list",
+		Namespace: "namespace1", Resource: "pods", ResourceRequest: true, ResourceScope: request.NamespaceScope}
+	expect := func(expected authorizer.Decision) {
+		t.Helper()
+		decision, _, err := authz.Authorize(listPods)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decision != expected {
+			t.Fatalf("expected %v, got %v", expected, decision)
+		}
+	}
+	expect(authorizer.DecisionAllow)
+
+	// the changes of the roles invalidate the cached decisions
+	role := &iamv1beta1.Role{}
+	if err := client.Get(ctx, runtimeclient.ObjectKey{Namespace: "namespace1", Name: "readpods"}, role); err != nil {
+		t.Fatal(err)
+	}
+	oldRole := role.DeepCopy()
+	role.Rules[0].Verbs = []string{"get"}
+	if err := client.Update(ctx, role); err != nil {
+		t.Fatal(err)
+	}
+	roleInformer, err := informers.FakeInformerFor(ctx, role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roleInformer.Update(oldRole, role)
+	expect(authorizer.DecisionNoOpinion)
+
+	role.Rules[0].Verbs = []string{"list"}
+	if err = client.Update(ctx, role); err != nil {
+		t.Fatal(err)
+	}
+	roleInformer.Update(oldRole, role)
+	expect(authorizer.DecisionAllow)
+
+	// the bindings are removed from the index
+	bindingInformer, err := informers.FakeInformerFor(ctx, &iamv1beta1.RoleBinding{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bindingInformer.Delete(staticRoles.roleBindings[0])
+	expect(authorizer.DecisionNoOpinion)
+
+	// and added back
+	bindingInformer.Add(staticRoles.roleBindings[0])
+	expect(authorizer.DecisionAllow)
+
+	// the subjects are re-indexed on update
+	updated := staticRoles.roleBindings[0].DeepCopy()
+	updated.Subjects = []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "dave"}}
+	bindingInformer.Update(staticRoles.roleBindings[0], updated)
+	expect(authorizer.DecisionNoOpinion)
+}
+
+func TestTimeBoundRoleBindings(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	staticRoles := indexTestRoles()
+	// expired
+	rbacutils.SetBindingValidity(staticRoles.globalRoleBindings[0], rbacutils.BindingValidity{NotBefore: &past, ExpiresAt: &past})
+	// not in effect yet
+	rbacutils.SetBindingValidity(staticRoles.clusterRoleBindings[0], rbacutils.BindingValidity{NotBefore: &future})
+	// in effect
+	rbacutils.SetBindingValidity(staticRoles.roleBindings[0], rbacutils.BindingValidity{NotBefore: &past, ExpiresAt: &future})
+	// invalid
+	staticRoles.workspaceRoleBindings[0].Annotations = map[string]string{iamv1beta1.BindingExpiresAtAnnotation: "tomorrow"}
+
+	listAuthorizer, err := newMockRBACAuthorizer(staticRoles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexedAuthorizer, _, _ := newIndexedTestAuthorizer(t, staticRoles, time.Minute)
+
+	tests := []struct {
+		attributes authorizer.AttributesRecord
+		expected   authorizer.Decision
+	}{
+		{
+			attributes: authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "alice"}, Verb: "get", APIGroup: "iam.kubesphere.io",
+				Resource: "users", ResourceRequest: true, ResourceScope: request.GlobalScope},
+			expected: authorizer.DecisionNoOpinion,
+		},
+		{
+			attributes: authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "bob"}, Verb: "get", Resource: "nodes",
+				ResourceRequest: true, ResourceScope: request.ClusterScope},
+			expected: authorizer.DecisionNoOpinion,
+		},
+		{
+			attributes: authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "carol", Groups: []string{"group1"}}, Verb: "list",
+				Namespace: "namespace1", Resource: "pods", ResourceRequest: true, ResourceScope: request.NamespaceScope},
+			expected: authorizer.DecisionAllow,
+		},
+		{
+			attributes: authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "alice"}, Verb: "delete", Workspace: "ws1",
+				Resource: "devopsprojects", ResourceRequest: true, ResourceScope: request.WorkspaceScope},
+			expected: authorizer.DecisionNoOpinion,
+		},
+	}
+	for i, tt := range tests {
+		for name, authz := range map[string]*Authorizer{"list": listAuthorizer, "indexed": indexedAuthorizer} {
+			decision, _, err := authz.Authorize(tt.attributes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if decision != tt.expected {
+				t.Errorf("case %d: %s authorizer expected %v, got %v", i, name, tt.expected, decision)
+			}
+		}
+	}
+
+	grants, err := listAuthorizer.ResourceAccessReview(tests[0].attributes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(grants) != 0 {
+		t.Errorf("expected no grants of the expired binding, got %v", grants)
+	}
+}
+
+func TestTimeBoundRoleBindingExpiration(t *testing.T) {
+	// the validity annotations are in seconds
+	expiresAt := time.Now().Truncate(time.Second).Add(time.Second)
+	staticRoles := indexTestRoles()
+	rbacutils.SetBindingValidity(staticRoles.roleBindings[0], rbacutils.BindingValidity{ExpiresAt: &expiresAt})
+	authz, _, _ := newIndexedTestAuthorizer(t, staticRoles, time.Hour)
+
+	listPods := authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "carol", Groups: []string{"group1"}}, Verb: "list",
+		Namespace: "namespace1", Resource: "pods", ResourceRequest: true, ResourceScope: request.NamespaceScope}
+	if decision, _, _ := authz.Authorize(listPods); decision != authorizer.DecisionAllow {
+		t.Fatalf("expected %v, got %v", authorizer.DecisionAllow, decision)
+	}
+	// the decision cached is invalidated once the binding expires
+	time.Sleep(time.Until(expiresAt) + 100*time.Millisecond)
+	if decision, _, _ := authz.Authorize(listPods); decision != authorizer.DecisionNoOpinion {
+		t.Fatalf("expected the expired binding to be ignored, got %v", decision)
+	}
+}
+
+func TestTimeBoundRoleBindingTimers(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	staticRoles := indexTestRoles()
+	binding := staticRoles.roleBindings[0]
+	rbacutils.SetBindingValidity(binding, rbacutils.BindingValidity{ExpiresAt: &future})
+	authz, informers, _ := newIndexedTestAuthorizer(t, staticRoles, time.Hour)
+	informer, err := informers.FakeInformerFor(context.Background(), &iamv1beta1.RoleBinding{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	timers := func() int {
+		index := authz.index.roleBindings
+		index.lock.RLock()
+		defer index.lock.RUnlock()
+		return len(index.timers)
+	}
+
+	// the timer of the binding is replaced on update instead of piling up
+	for n := 1; n <= 10; n++ {
+		updated := binding.DeepCopy()
+		expiresAt := future.Add(time.Duration(n) * time.Minute)
+		rbacutils.SetBindingValidity(updated, rbacutils.BindingValidity{ExpiresAt: &expiresAt})
+		informer.Update(binding, updated)
+		binding = updated
+	}
+	if n := timers(); n != 1 {
+		t.Errorf("expected 1 timer of the time-bound binding, got %d", n)
+	}
+
+	// the timer is stopped once the binding isn't time-bound anymore
+	updated := binding.DeepCopy()
+	updated.Annotations = nil
+	informer.Update(binding, updated)
+	if n := timers(); n != 0 {
+		t.Errorf("expected no timers of the binding not time-bound, got %d", n)
+	}
+
+	informer.Update(updated, binding)
+	informer.Delete(binding)
+	if n := timers(); n != 0 {
+		t.Errorf("expected no timers of the deleted binding, got %d", n)
+	}
+}
+
+// newBenchmarkRoles returns the roles of a cluster with the users bound to the roles in the global,
+// workspace, and namespace scopes, which is the common layout of KubeSphere.
+func newBenchmarkRoles(users, workspaces, namespaces int) *StaticRoles {
+	staticRoles := &StaticRoles{
+		globalRoles: []*iamv1beta1.GlobalRole{{
+			ObjectMeta: metav1.ObjectMeta{Name: "platform-regular"},
+			Rules:      []rbacv1.PolicyRule{{Verbs: []string{"get"}, APIGroups: []string{"iam.kubesphere.io"}, Resources: []string{"users"}}},
+		}},
+	}
+	for w := 0; w < workspaces; w++ {
+		workspace := fmt.Sprintf("ws%d", w)
+		staticRoles.workspaceRoles = append(staticRoles.workspaceRoles, &iamv1beta1.WorkspaceRole{
+			ObjectMeta: metav1.ObjectMeta{Name: workspace + "-viewer", Labels: map[string]string{tenantv1beta1.WorkspaceLabel: workspace}},
+			Rules:      []rbacv1.PolicyRule{{Verbs: []string{"get", "list"}, APIGroups: []string{"*"}, Resources: []string{"*"}}},
+		})
+	}
+	for n := 0; n < namespaces; n++ {
+		staticRoles.roles = append(staticRoles.roles, &iamv1beta1.Role{
+			ObjectMeta: metav1.ObjectMeta{Namespace: fmt.Sprintf("ns%d", n), Name: "operator"},
+			Rules:      []rbacv1.PolicyRule{{Verbs: []string{"*"}, APIGroups: []string{"*"}, Resources: []string{"*"}}},
+		})
+	}
+	for u := 0; u < users; u++ {
+		username := fmt.Sprintf("user%d", u)
+		workspace := fmt.Sprintf("ws%d", u%workspaces)
+		staticRoles.globalRoleBindings = append(staticRoles.globalRoleBindings, &iamv1beta1.GlobalRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: username + "-platform-regular"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: username}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: iamv1beta1.GroupName, Kind: iamv1beta1.ResourceKindGlobalRole, Name: "platform-regular"},
+		})
+		staticRoles.workspaceRoleBindings = append(staticRoles.workspaceRoleBindings, &iamv1beta1.WorkspaceRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: username + "-" + workspace + "-viewer", Labels: map[string]string{tenantv1beta1.WorkspaceLabel: workspace}},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: username}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: iamv1beta1.GroupName, Kind: iamv1beta1.ResourceKindWorkspaceRole, Name: workspace + "-viewer"},
+		})
+		staticRoles.roleBindings = append(staticRoles.roleBindings, &iamv1beta1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Namespace: fmt.Sprintf("ns%d", u%namespaces), Name: username + "-operator"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: username}},
+			RoleRef:    rbacv1.RoleRef{APIGroup: iamv1beta1.GroupName, Kind: iamv1beta1.ResourceKindRole, Name: "operator"},
+		})
+	}
+	return staticRoles
+}
+
+func BenchmarkRBACAuthorizer(b *testing.B) {
+	const users, workspaces, namespaces = 1000, 20, 100
+	staticRoles := newBenchmarkRoles(users, workspaces, namespaces)
+	listAuthorizer, err := newMockRBACAuthorizer(staticRoles)
+	if err != nil {
+		b.Fatal(err)
+	}
+	indexedAuthorizer, _, _ := newIndexedTestAuthorizer(b, staticRoles, 0)
+	cachedAuthorizer, _, _ := newIndexedTestAuthorizer(b, staticRoles, time.Minute)
+
+	requests := make([]authorizer.AttributesRecord, 0, 100)
+	for u := 0; u < 100; u++ {
+		requests = append(requests, authorizer.AttributesRecord{
+			User:            &user.DefaultInfo{Name: fmt.Sprintf("user%d", u)},
+			Verb:            "delete",
+			Namespace:       fmt.Sprintf("ns%d", u%namespaces),
+			Resource:        "pods",
+			ResourceRequest: true,
+			ResourceScope:   request.NamespaceScope,
+		})
+	}
+
+	for _, bm := range []struct {
+		name  string
+		authz *Authorizer
+	}{
+		{name: "list", authz: listAuthorizer},
+		{name: "indexed", authz: indexedAuthorizer},
+		{name: "indexed-cached", authz: cachedAuthorizer},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				decision, _, _ := bm.authz.Authorize(requests[i%len(requests)])
+				if decision != authorizer.DecisionAllow {
+					b.Fatalf("expected %v, got %v", authorizer.DecisionAllow, decision)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRegoPolicyAllows(b *testing.B) {
+	regoPolicy := indexTestRoles().globalRoles[1].Annotations[iamv1beta1.RegoOverrideAnnotation]
+	attributes := authorizer.AttributesRecord{User: &user.DefaultInfo{Name: "bob"}, Verb: "list", Resource: "users",
+		ResourceRequest: true, ResourceScope: request.GlobalScope}
+
+	b.Run("compile-per-evaluation", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			query, err := rego.New(rego.Query(defaultRegoQuery), rego.Module(defaultRegoFileName, regoPolicy)).PrepareForEval(context.Background())
+			if err != nil {
+				b.Fatal(err)
+			}
+			if _, err = query.Eval(context.Background(), rego.EvalInput(attributes)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("prepared", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if !regoPolicyAllows(attributes, regoPolicy) {
+				b.Fatal("expected the rego policy to allow")
+			}
+		}
+	})
+}
@@ -22,13 +22,14 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"time"
 
-	"github.com/open-policy-agent/opa/rego"
 	rbacv1 "k8s.io/api/rbac/v1"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/apiserver/pkg/authentication/serviceaccount"
 	"k8s.io/apiserver/pkg/authentication/user"
 	"k8s.io/klog/v2"
+	runtimecache "sigs.k8s.io/controller-runtime/pkg/cache"
 
 	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
 	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
@@ -36,6 +37,7 @@ import (
 	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
 	"kubesphere.io/kubesphere/pkg/apiserver/request"
 	"kubesphere.io/kubesphere/pkg/models/iam/am"
+	rbacutils "kubesphere.io/kubesphere/pkg/utils/rbac"
 	ksserviceaccount "kubesphere.io/kubesphere/pkg/utils/serviceaccount"
 	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
 )
@@ -47,6 +49,9 @@ const (
 
 type Authorizer struct {
 	am am.AccessManagementInterface
+	// index and decisions are optional, see NewIndexedRBACAuthorizer
+	index     *bindingIndex
+	decisions *decisionCache
 }
 
 // authorizingVisitor short-circuits once allowed, and collects any resolution errors encountered
@@ -91,11 +96,24 @@ func (r *ruleAccumulator) visit(_ fmt.Stringer, _ string, rule *rbacv1.PolicyRul
 }
 
 func (r *Authorizer) Authorize(requestAttributes authorizer.Attributes) (authorizer.Decision, string, error) {
+	var decisionCacheKey string
+	var generation uint64
+	if r.decisions != nil {
+		decisionCacheKey = decisionKey(requestAttributes)
+		if cached, ok := r.decisions.get(decisionCacheKey); ok {
+			return cached.decision, cached.reason, nil
+		}
+		generation = r.decisions.generation.Load()
+	}
+
 	ruleCheckingVisitor := &authorizingVisitor{requestAttributes: requestAttributes}
 
 	r.visitRulesFor(requestAttributes, ruleCheckingVisitor.visit)
 
 	if ruleCheckingVisitor.allowed {
+		if r.decisions != nil {
+			r.decisions.add(decisionCacheKey, generation, authorizer.DecisionAllow, ruleCheckingVisitor.reason)
+		}
 		return authorizer.DecisionAllow, ruleCheckingVisitor.reason, nil
 	}
 
@@ -145,6 +163,9 @@ func (r *Authorizer) Authorize(requestAttributes authorizer.Attributes) (authori
 	reason := ""
 	if len(ruleCheckingVisitor.errors) > 0 {
 		reason = fmt.Sprintf("RBAC: %v", utilerrors.NewAggregate(ruleCheckingVisitor.errors))
+	} else if r.decisions != nil {
+		// the denials caused by resolution errors are not cached, so that they are retried by the next requests
+		r.decisions.add(decisionCacheKey, generation, authorizer.DecisionNoOpinion, reason)
 	}
 	return authorizer.DecisionNoOpinion, reason, nil
 }
@@ -153,6 +174,28 @@ func NewRBACAuthorizer(am am.AccessManagementInterface) *Authorizer {
 	return &Authorizer{am: am}
 }
 
+// NewIndexedRBACAuthorizer returns an Authorizer which looks up the role bindings of the users in an index
+// maintained by the informers, instead of listing all the role bindings for every request, and caches the
+// decisions for decisionTTL if it is positive. The role bindings are listed until the informers are synced.
+func NewIndexedRBACAuthorizer(ctx context.Context, am am.AccessManagementInterface, informers runtimecache.Informers, decisionTTL time.Duration) (*Authorizer, error) {
+	r := &Authorizer{am: am}
+	if decisionTTL > 0 {
+		r.decisions = newDecisionCache(decisionTTL)
+	}
+	index, err := newBindingIndex(ctx, informers, r.invalidateDecisions)
+	if err != nil {
+		return nil, err
+	}
+	r.index = index
+	return r, nil
+}
+
+func (r *Authorizer) invalidateDecisions() {
+	if r.decisions != nil {
+		r.decisions.invalidate()
+	}
+}
+
 func ruleAllows(requestAttributes authorizer.Attributes, rule *rbacv1.PolicyRule) bool {
 	if requestAttributes.IsResourceRequest() {
 		combinedResource := requestAttributes.GetResource()
@@ -170,31 +213,6 @@ func ruleAllows(requestAttributes authorizer.Attributes, rule *rbacv1.PolicyRule
 		NonResourceURLMatches(rule, requestAttributes.GetPath())
 }
 
-func regoPolicyAllows(requestAttributes authorizer.Attributes, regoPolicy string) bool {
-	// Call the rego.New function to create an object that can be prepared or evaluated
-	//  After constructing a new rego.Rego object you can call PrepareForEval() to obtain an executable query
-	query, err := rego.New(rego.Query(defaultRegoQuery), rego.Module(defaultRegoFileName, regoPolicy)).PrepareForEval(context.Background())
-
-	if err != nil {
-		klog.Warningf("syntax error:%s, content: %s", err, regoPolicy)
-		return false
-	}
-
-	// The policy decision is contained in the results returned by the Eval() call. You can inspect the decision and handle it accordingly.
-	results, err := query.Eval(context.Background(), rego.EvalInput(requestAttributes))
-
-	if err != nil {
-		klog.Warningf("syntax error:%s, content: %s", err, regoPolicy)
-		return false
-	}
-
-	if len(results) > 0 && results[0].Expressions[0].Value == true {
-		return true
-	}
-
-	return false
-}
-
 func (r *Authorizer) rulesFor(requestAttributes authorizer.Attributes) ([]rbacv1.PolicyRule, error) {
 	visitor := &ruleAccumulator{}
 	r.visitRulesFor(requestAttributes, visitor.visit)
@@ -202,14 +220,16 @@ func (r *Authorizer) rulesFor(requestAttributes authorizer.Attributes) ([]rbacv1
 }
 
 func (r *Authorizer) visitRulesFor(requestAttributes authorizer.Attributes, visitor func(source fmt.Stringer, regoPolicy string, rule *rbacv1.PolicyRule, err error) bool) {
-	if globalRoleBindings, err := r.am.ListGlobalRoleBindings("", ""); err != nil {
+	// the time-bound role bindings out of their validity period are ignored
+	now := time.Now()
+	if globalRoleBindings, err := r.listGlobalRoleBindings(requestAttributes.GetUser()); err != nil {
 		visitor(nil, "", nil, err)
 		return
 	} else {
 		sourceDescriber := &globalRoleBindingDescriber{}
 		for _, globalRoleBinding := range globalRoleBindings {
 			subjectIndex, applies := appliesTo(requestAttributes.GetUser(), globalRoleBinding.Subjects, "")
-			if !applies {
+			if !applies || !rbacutils.IsBindingActive(&globalRoleBinding, now) {
 				continue
 			}
 			regoPolicy, rules, err := r.am.GetRoleReferenceRules(globalRoleBinding.RoleRef, "")
@@ -250,14 +270,14 @@ func (r *Authorizer) visitRulesFor(requestAttributes authorizer.Attributes, visi
 
 	// workspace managed resources
 	if targetWorkspace != "" {
-		if workspaceRoleBindings, err := r.am.ListWorkspaceRoleBindings("", "", nil, targetWorkspace); err != nil {
+		if workspaceRoleBindings, err := r.listWorkspaceRoleBindings(requestAttributes.GetUser(), targetWorkspace); err != nil {
 			visitor(nil, "", nil, err)
 			return
 		} else {
 			sourceDescriber := &workspaceRoleBindingDescriber{}
 			for _, workspaceRoleBinding := range workspaceRoleBindings {
 				subjectIndex, applies := appliesTo(requestAttributes.GetUser(), workspaceRoleBinding.Subjects, "")
-				if !applies {
+				if !applies || !rbacutils.IsBindingActive(&workspaceRoleBinding, now) {
 					continue
 				}
 				regoPolicy, rules, err := r.am.GetRoleReferenceRules(workspaceRoleBinding.RoleRef, "")
@@ -285,14 +305,14 @@ func (r *Authorizer) visitRulesFor(requestAttributes authorizer.Attributes, visi
 	}
 
 	if targetNamespace != "" {
-		if roleBindings, err := r.am.ListRoleBindings("", "", nil, targetNamespace); err != nil {
+		if roleBindings, err := r.listRoleBindings(requestAttributes.GetUser(), targetNamespace); err != nil {
 			visitor(nil, "", nil, err)
 			return
 		} else {
 			sourceDescriber := &roleBindingDescriber{}
 			for _, roleBinding := range roleBindings {
 				subjectIndex, applies := appliesTo(requestAttributes.GetUser(), roleBinding.Subjects, targetNamespace)
-				if !applies {
+				if !applies || !rbacutils.IsBindingActive(&roleBinding, now) {
 					continue
 				}
 				regoPolicy, rules, err := r.am.GetRoleReferenceRules(roleBinding.RoleRef, targetNamespace)
@@ -314,14 +334,14 @@ func (r *Authorizer) visitRulesFor(requestAttributes authorizer.Attributes, visi
 		}
 	}
 
-	if clusterRoleBindings, err := r.am.ListClusterRoleBindings("", ""); err != nil {
+	if clusterRoleBindings, err := r.listClusterRoleBindings(requestAttributes.GetUser()); err != nil {
 		visitor(nil, "", nil, err)
 		return
 	} else {
 		sourceDescriber := &clusterRoleBindingDescriber{}
 		for _, clusterRoleBinding := range clusterRoleBindings {
 			subjectIndex, applies := appliesTo(requestAttributes.GetUser(), clusterRoleBinding.Subjects, "")
-			if !applies {
+			if !applies || !rbacutils.IsBindingActive(&clusterRoleBinding, now) {
 				continue
 			}
 			regoPolicy, rules, err := r.am.GetRoleReferenceRules(clusterRoleBinding.RoleRef, "")
@@ -343,6 +363,42 @@ func (r *Authorizer) visitRulesFor(requestAttributes authorizer.Attributes, visi
 	}
 }
 
+func (r *Authorizer) listGlobalRoleBindings(u user.Info) ([]iamv1beta1.GlobalRoleBinding, error) {
+	if r.index != nil && r.index.globalRoleBindings.synced() {
+		return values(r.index.globalRoleBindings.lookup(clusterScope, u)), nil
+	}
+	return r.am.ListGlobalRoleBindings("", "")
+}
+
+func (r *Authorizer) listWorkspaceRoleBindings(u user.Info, workspace string) ([]iamv1beta1.WorkspaceRoleBinding, error) {
+	if r.index != nil && r.index.workspaceRoleBindings.synced() {
+		return values(r.index.workspaceRoleBindings.lookup(workspace, u)), nil
+	}
+	return r.am.ListWorkspaceRoleBindings("", "", nil, workspace)
+}
+
+func (r *Authorizer) listRoleBindings(u user.Info, namespace string) ([]iamv1beta1.RoleBinding, error) {
+	if r.index != nil && r.index.roleBindings.synced() {
+		return values(r.index.roleBindings.lookup(namespace, u)), nil
+	}
+	return r.am.ListRoleBindings("", "", nil, namespace)
+}
+
+func (r *Authorizer) listClusterRoleBindings(u user.Info) ([]iamv1beta1.ClusterRoleBinding, error) {
+	if r.index != nil && r.index.clusterRoleBindings.synced() {
+		return values(r.index.clusterRoleBindings.lookup(clusterScope, u)), nil
+	}
+	return r.am.ListClusterRoleBindings("", "")
+}
+
+func values[T any](pointers []*T) []T {
+	result := make([]T, 0, len(pointers))
+	for _, pointer := range pointers {
+		result = append(result, *pointer)
+	}
+	return result
+}
+
 // appliesTo returns whether any of the bindingSubjects applies to the specified subject,
 // and if true, the index of the first subject that applies
 func appliesTo(user user.Info, bindingSubjects []rbacv1.Subject, namespace string) (int, bool) {
@@ -32,6 +32,7 @@ import (
 	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
 	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
 	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 	runtimefakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
 	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
@@ -867,54 +868,62 @@ func TestRBACAuthorizerMakeDecision(t *testing.T) {
 }
 
 func newMockRBACAuthorizer(staticRoles *StaticRoles) (*Authorizer, error) {
+	amOperator, _, err := newMockAccessManager(staticRoles)
+	if err != nil {
+		return nil, err
+	}
+	return NewRBACAuthorizer(amOperator), nil
+}
+
+func newMockAccessManager(staticRoles *StaticRoles) (am.AccessManagementInterface, runtimeclient.Client, error) {
 	client := runtimefakeclient.NewClientBuilder().
 		WithScheme(scheme.Scheme).Build()
 
 	for _, role := range staticRoles.roles {
 		if err := client.Create(context.Background(), role.DeepCopy()); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	for _, roleBinding := range staticRoles.roleBindings {
 		if err := client.Create(context.Background(), roleBinding.DeepCopy()); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	for _, clusterRole := range staticRoles.clusterRoles {
 		if err := client.Create(context.Background(), clusterRole.DeepCopy()); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	for _, clusterRoleBinding := range staticRoles.clusterRoleBindings {
 		if err := client.Create(context.Background(), clusterRoleBinding.DeepCopy()); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	for _, workspaceRole := range staticRoles.workspaceRoles {
 		if err := client.Create(context.Background(), workspaceRole.DeepCopy()); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	for _, workspaceRoleBinding := range staticRoles.workspaceRoleBindings {
 		if err := client.Create(context.Background(), workspaceRoleBinding.DeepCopy()); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	for _, globalRole := range staticRoles.globalRoles {
 		if err := client.Create(context.Background(), globalRole.DeepCopy()); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
 	for _, globalRoleBinding := range staticRoles.globalRoleBindings {
 		if err := client.Create(context.Background(), globalRoleBinding.DeepCopy()); err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
@@ -922,10 +931,10 @@ func newMockRBACAuthorizer(staticRoles *StaticRoles) (*Authorizer, error) {
 
 	resourceManager, err := v1beta1.New(context.Background(), client, fakeCache)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return NewRBACAuthorizer(am.NewReadOnlyOperator(resourceManager)), nil
+	return am.NewReadOnlyOperator(resourceManager), client, nil
 }
 
 func TestAppliesTo(t *testing.T) {
@@ -0,0 +1,73 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package rbac
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/open-policy-agent/opa/rego"
+	"k8s.io/klog/v2"
+	"k8s.io/utils/lru"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+)
+
+// maxPreparedRegoQueries bounds the number of the prepared queries kept in memory,
+// the rego policies are rarely used, so it is sufficient for most of the clusters.
+const maxPreparedRegoQueries = 1024
+
+// preparedRegoQueries caches the prepared queries by the hash of the rego policies,
+// since compiling a policy is much more expensive than evaluating it.
+var preparedRegoQueries = lru.New(maxPreparedRegoQueries)
+
+type preparedRegoQuery struct {
+	query rego.PreparedEvalQuery
+	// err is the compile error, the invalid policies are not compiled again until evicted
+	err error
+}
+
+func regoPolicyHash(regoPolicy string) string {
+	sum := sha256.Sum256([]byte(regoPolicy))
+	return hex.EncodeToString(sum[:])
+}
+
+func prepareRegoQuery(regoPolicy string) (rego.PreparedEvalQuery, error) {
+	key := regoPolicyHash(regoPolicy)
+	if cached, ok := preparedRegoQueries.Get(key); ok {
+		prepared := cached.(*preparedRegoQuery)
+		return prepared.query, prepared.err
+	}
+	// Call the rego.New function to create an object that can be prepared or evaluated
+	//  After constructing a new rego.Rego object you can call PrepareForEval() to obtain an executable query
+	query, err := rego.New(rego.Query(defaultRegoQuery), rego.Module(defaultRegoFileName, regoPolicy)).PrepareForEval(context.Background())
+	preparedRegoQueries.Add(key, &preparedRegoQuery{query: query, err: err})
+	return query, err
+}
+
+func regoPolicyAllows(requestAttributes authorizer.Attributes, regoPolicy string) bool {
+	query, err := prepareRegoQuery(regoPolicy)
+	if err != nil {
+		klog.Warningf("syntax error:%s, content: %s", err, regoPolicy)
+		return false
+	}
+
+	// The policy decision is contained in the results returned by the Eval() call. You can inspect the decision and handle it accordingly.
+	results, err := query.Eval(context.Background(), rego.EvalInput(requestAttributes))
+
+	if err != nil {
+		klog.Warningf("syntax error:%s, content: %s", err, regoPolicy)
+		return false
+	}
+
+	if len(results) > 0 && results[0].Expressions[0].Value == true {
+		return true
+	}
+
+	return false
+}
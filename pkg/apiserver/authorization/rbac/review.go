@@ -0,0 +1,250 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package rbac
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apiserver/pkg/authentication/serviceaccount"
+	"k8s.io/apiserver/pkg/authentication/user"
+	corev1alpha1 "kubesphere.io/api/core/v1alpha1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+	rbacutils "kubesphere.io/kubesphere/pkg/utils/rbac"
+)
+
+// reviewedBinding is a role binding of any kind in the scopes of the reviewed action.
+type reviewedBinding struct {
+	kind      string
+	name      string
+	namespace string
+	subjects  []rbacv1.Subject
+	roleRef   rbacv1.RoleRef
+}
+
+// ResourceAccessReview returns the grants of the subjects allowed to perform the action described by the
+// request attributes, the user of the attributes is ignored. The role bindings are looked up in the same
+// scopes as Authorize does, the grants resolved are sorted by the subjects and returned along with the errors occurred.
+func (r *Authorizer) ResourceAccessReview(requestAttributes authorizer.AttributesRecord) ([]iamv1beta1.AccessGrant, error) {
+	bindings, errs := r.reviewedBindings(requestAttributes)
+	grants := make([]iamv1beta1.AccessGrant, 0)
+	for _, binding := range bindings {
+		regoPolicy, rules, err := r.am.GetRoleReferenceRules(binding.roleRef, binding.namespace)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		rulesAllow := false
+		for i := range rules {
+			if ruleAllows(requestAttributes, &rules[i]) {
+				rulesAllow = true
+				break
+			}
+		}
+		if !rulesAllow && regoPolicy == "" {
+			continue
+		}
+
+		templates, err := r.aggregatedRoleTemplates(binding.roleRef, binding.namespace)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for _, subject := range binding.subjects {
+			subjectAttributes := requestAttributes
+			subjectAttributes.User = subjectUser(subject, binding.namespace)
+			if !rulesAllow && !regoPolicyAllows(subjectAttributes, regoPolicy) {
+				continue
+			}
+			grant := iamv1beta1.AccessGrant{
+				Subject:          subject,
+				BindingKind:      binding.kind,
+				BindingName:      binding.name,
+				BindingNamespace: binding.namespace,
+				RoleRef:          binding.roleRef,
+				RegoPolicy:       !rulesAllow,
+			}
+			for _, template := range templates {
+				if roleTemplateAllows(subjectAttributes, template) {
+					grant.RoleTemplates = append(grant.RoleTemplates, template.Name)
+				}
+			}
+			grants = append(grants, grant)
+		}
+	}
+	sort.SliceStable(grants, func(i, j int) bool {
+		return grantSortKey(grants[i]) < grantSortKey(grants[j])
+	})
+	return grants, utilerrors.NewAggregate(errs)
+}
+
+func (r *Authorizer) reviewedBindings(requestAttributes authorizer.AttributesRecord) ([]reviewedBinding, []error) {
+	var bindings []reviewedBinding
+	var errs []error
+	now := time.Now()
+
+	if globalRoleBindings, err := r.am.ListGlobalRoleBindings("", ""); err != nil {
+		errs = append(errs, err)
+	} else {
+		for _, binding := range globalRoleBindings {
+			if !rbacutils.IsBindingActive(&binding, now) {
+				continue
+			}
+			bindings = append(bindings, reviewedBinding{kind: iamv1beta1.ResourceKindGlobalRoleBinding,
+				name: binding.Name, subjects: binding.Subjects, roleRef: binding.RoleRef})
+		}
+	}
+	if requestAttributes.GetResourceScope() == request.GlobalScope {
+		return bindings, errs
+	}
+
+	var targetWorkspace string
+	if requestAttributes.GetResourceScope() == request.NamespaceScope {
+		workspace, err := r.am.GetNamespaceControlledWorkspace(requestAttributes.GetNamespace())
+		if err != nil {
+			errs = append(errs, err)
+		}
+		targetWorkspace = workspace
+	}
+	if requestAttributes.GetResourceScope() == request.WorkspaceScope {
+		targetWorkspace = requestAttributes.GetWorkspace()
+	}
+
+	if targetWorkspace != "" {
+		if workspaceRoleBindings, err := r.am.ListWorkspaceRoleBindings("", "", nil, targetWorkspace); err != nil {
+			errs = append(errs, err)
+		} else {
+			for _, binding := range workspaceRoleBindings {
+				if !rbacutils.IsBindingActive(&binding, now) {
+					continue
+				}
+				bindings = append(bindings, reviewedBinding{kind: iamv1beta1.ResourceKindWorkspaceRoleBinding,
+					name: binding.Name, subjects: binding.Subjects, roleRef: binding.RoleRef})
+			}
+		}
+	}
+
+	if requestAttributes.GetResourceScope() == request.NamespaceScope && requestAttributes.GetNamespace() != "" {
+		if roleBindings, err := r.am.ListRoleBindings("", "", nil, requestAttributes.GetNamespace()); err != nil {
+			errs = append(errs, err)
+		} else {
+			for _, binding := range roleBindings {
+				if !rbacutils.IsBindingActive(&binding, now) {
+					continue
+				}
+				bindings = append(bindings, reviewedBinding{kind: iamv1beta1.ResourceKindRoleBinding,
+					name: binding.Name, namespace: binding.Namespace, subjects: binding.Subjects, roleRef: binding.RoleRef})
+			}
+		}
+	}
+
+	if clusterRoleBindings, err := r.am.ListClusterRoleBindings("", ""); err != nil {
+		errs = append(errs, err)
+	} else {
+		for _, binding := range clusterRoleBindings {
+			if !rbacutils.IsBindingActive(&binding, now) {
+				continue
+			}
+			bindings = append(bindings, reviewedBinding{kind: iamv1beta1.ResourceKindClusterRoleBinding,
+				name: binding.Name, subjects: binding.Subjects, roleRef: binding.RoleRef})
+		}
+	}
+	return bindings, errs
+}
+
+// aggregatedRoleTemplates returns the role templates aggregated into the referenced role.
+func (r *Authorizer) aggregatedRoleTemplates(roleRef rbacv1.RoleRef, namespace string) ([]*iamv1beta1.RoleTemplate, error) {
+	var aggregation *iamv1beta1.AggregationRoleTemplates
+	var err error
+	switch roleRef.Kind {
+	case iamv1beta1.ResourceKindRole:
+		var role *iamv1beta1.Role
+		if role, err = r.am.GetNamespaceRole(namespace, roleRef.Name); err == nil {
+			aggregation = role.AggregationRoleTemplates
+		}
+	case iamv1beta1.ResourceKindClusterRole:
+		var role *iamv1beta1.ClusterRole
+		if role, err = r.am.GetClusterRole(roleRef.Name); err == nil {
+			aggregation = role.AggregationRoleTemplates
+		}
+	case iamv1beta1.ResourceKindGlobalRole:
+		var role *iamv1beta1.GlobalRole
+		if role, err = r.am.GetGlobalRole(roleRef.Name); err == nil {
+			aggregation = role.AggregationRoleTemplates
+		}
+	case iamv1beta1.ResourceKindWorkspaceRole:
+		var role *iamv1beta1.WorkspaceRole
+		if role, err = r.am.GetWorkspaceRole("", roleRef.Name); err == nil {
+			aggregation = role.AggregationRoleTemplates
+		}
+	}
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if aggregation == nil {
+		return nil, nil
+	}
+
+	// the selected role templates are recorded in the template names once aggregated
+	templates := make([]*iamv1beta1.RoleTemplate, 0, len(aggregation.TemplateNames))
+	for _, name := range aggregation.TemplateNames {
+		template, err := r.am.GetRoleTemplate(name)
+		if err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		templates = append(templates, template)
+	}
+	return templates, nil
+}
+
+func roleTemplateAllows(requestAttributes authorizer.Attributes, template *iamv1beta1.RoleTemplate) bool {
+	for i := range template.Spec.Rules {
+		if ruleAllows(requestAttributes, &template.Spec.Rules[i]) {
+			return true
+		}
+	}
+	if regoPolicy := template.Annotations[iamv1beta1.RegoOverrideAnnotation]; regoPolicy != "" {
+		return regoPolicyAllows(requestAttributes, regoPolicy)
+	}
+	return false
+}
+
+func grantSortKey(grant iamv1beta1.AccessGrant) string {
+	return strings.Join([]string{grant.Subject.Kind, grant.Subject.Namespace, grant.Subject.Name,
+		grant.BindingKind, grant.BindingNamespace, grant.BindingName}, "/")
+}
+
+// subjectUser returns the user the binding subject applies to, which is the input of the rego policies.
+func subjectUser(subject rbacv1.Subject, bindingNamespace string) user.Info {
+	switch subject.Kind {
+	case rbacv1.GroupKind:
+		return &user.DefaultInfo{Groups: []string{subject.Name}}
+	case rbacv1.ServiceAccountKind:
+		namespace := bindingNamespace
+		if subject.Namespace != "" {
+			namespace = subject.Namespace
+		}
+		if subject.APIGroup == corev1alpha1.GroupName {
+			return &user.DefaultInfo{Name: corev1alpha1.ServiceAccountTokenPrefix + namespace + ":" + subject.Name}
+		}
+		return &user.DefaultInfo{Name: serviceaccount.MakeUsername(namespace, subject.Name)}
+	default:
+		return &user.DefaultInfo{Name: subject.Name}
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package rbac
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+)
+
+func TestResourceAccessReview(t *testing.T) {
+	staticRoles := indexTestRoles()
+	staticRoles.globalRoles[0].AggregationRoleTemplates = &iamv1beta1.AggregationRoleTemplates{
+		TemplateNames: []string{"role-template-view-users", "role-template-view-groups"},
+	}
+	amOperator, client, err := newMockAccessManager(staticRoles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, template := range []*iamv1beta1.RoleTemplate{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "role-template-view-users"},
+			Spec:       iamv1beta1.RoleTemplateSpec{Rules: staticRoles.globalRoles[0].Rules},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "role-template-view-groups"},
+			Spec: iamv1beta1.RoleTemplateSpec{Rules: []rbacv1.PolicyRule{
+				{Verbs: []string{"get"}, APIGroups: []string{"iam.kubesphere.io"}, Resources: []string{"groups"}},
+			}},
+		},
+	} {
+		if err = client.Create(context.Background(), template); err != nil {
+			t.Fatal(err)
+		}
+	}
+	authz := NewRBACAuthorizer(amOperator)
+
+	tests := []struct {
+		name       string
+		attributes authorizer.AttributesRecord
+		expected   []iamv1beta1.AccessGrant
+	}{
+		{
+			name: "rules and aggregated role templates",
+			attributes: authorizer.AttributesRecord{Verb: "get", APIGroup: "iam.kubesphere.io", Resource: "users",
+				ResourceRequest: true, ResourceScope: request.GlobalScope},
+			expected: []iamv1beta1.AccessGrant{{
+				Subject:       rbacv1.Subject{Kind: rbacv1.UserKind, Name: "alice"},
+				BindingKind:   iamv1beta1.ResourceKindGlobalRoleBinding,
+				BindingName:   "alice-users-viewer",
+				RoleRef:       staticRoles.globalRoleBindings[0].RoleRef,
+				RoleTemplates: []string{"role-template-view-users"},
+			}},
+		},
+		{
+			name: "rego policy",
+			attributes: authorizer.AttributesRecord{Verb: "list", APIGroup: "iam.kubesphere.io", Resource: "users",
+				ResourceRequest: true, ResourceScope: request.GlobalScope},
+			expected: []iamv1beta1.AccessGrant{{
+				Subject:     rbacv1.Subject{Kind: rbacv1.UserKind, Name: "bob"},
+				BindingKind: iamv1beta1.ResourceKindGlobalRoleBinding,
+				BindingName: "bob-rego-users-viewer",
+				RoleRef:     staticRoles.globalRoleBindings[1].RoleRef,
+				RegoPolicy:  true,
+			}},
+		},
+		{
+			name: "namespace scope",
+			attributes: authorizer.AttributesRecord{Verb: "get", Namespace: "namespace1", Resource: "pods",
+				ResourceRequest: true, ResourceScope: request.NamespaceScope},
+			expected: []iamv1beta1.AccessGrant{
+				{
+					Subject:          rbacv1.Subject{Kind: rbacv1.GroupKind, Name: "group1"},
+					BindingKind:      iamv1beta1.ResourceKindRoleBinding,
+					BindingName:      "readpods",
+					BindingNamespace: "namespace1",
+					RoleRef:          staticRoles.roleBindings[0].RoleRef,
+				},
+				{
+					Subject:          staticRoles.roleBindings[1].Subjects[0],
+					BindingKind:      iamv1beta1.ResourceKindRoleBinding,
+					BindingName:      "sa-readpods",
+					BindingNamespace: "namespace1",
+					RoleRef:          staticRoles.roleBindings[1].RoleRef,
+				},
+			},
+		},
+		{
+			name: "workspace scope",
+			attributes: authorizer.AttributesRecord{Verb: "delete", Workspace: "ws1", Resource: "devopsprojects",
+				ResourceRequest: true, ResourceScope: request.WorkspaceScope},
+			expected: []iamv1beta1.AccessGrant{{
+				Subject:     rbacv1.Subject{Kind: rbacv1.UserKind, Name: "alice"},
+				BindingKind: iamv1beta1.ResourceKindWorkspaceRoleBinding,
+				BindingName: "ws1-admin-alice",
+				RoleRef:     staticRoles.workspaceRoleBindings[0].RoleRef,
+			}},
+		},
+		{
+			name: "nobody",
+			attributes: authorizer.AttributesRecord{Verb: "delete", Resource: "nodes",
+				ResourceRequest: true, ResourceScope: request.ClusterScope},
+			expected: []iamv1beta1.AccessGrant{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grants, err := authz.ResourceAccessReview(tt.attributes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(tt.expected, grants); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
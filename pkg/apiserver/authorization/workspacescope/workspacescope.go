@@ -0,0 +1,57 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+// Package workspacescope contains an authorizer that confines the requests made with
+// workspace-scoped tokens, such as the tokens issued by the token exchange grant, to the workspace.
+package workspacescope
+
+import (
+	"fmt"
+
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+)
+
+// NamespaceWorkspaceGetter returns the workspace a namespace belongs to.
+type NamespaceWorkspaceGetter interface {
+	GetNamespaceControlledWorkspace(namespace string) (string, error)
+}
+
+// NewAuthorizer returns an authorizer which denies the requests out of the workspace of a workspace-scoped user,
+// and has no opinion on the others, the requests in the workspace are still authorized by the following authorizers.
+func NewAuthorizer(getter NamespaceWorkspaceGetter) authorizer.Authorizer {
+	return authorizer.AuthorizerFunc(func(a authorizer.Attributes) (authorizer.Decision, string, error) {
+		if a.GetUser() == nil {
+			return authorizer.DecisionNoOpinion, "", nil
+		}
+		scopes := a.GetUser().GetExtra()[iamv1beta1.ExtraWorkspaceScope]
+		if len(scopes) == 0 {
+			return authorizer.DecisionNoOpinion, "", nil
+		}
+		if !a.IsResourceRequest() {
+			return authorizer.DecisionNoOpinion, "", nil
+		}
+
+		var workspace string
+		switch a.GetResourceScope() {
+		case request.WorkspaceScope:
+			workspace = a.GetWorkspace()
+		case request.NamespaceScope:
+			namespaceWorkspace, err := getter.GetNamespaceControlledWorkspace(a.GetNamespace())
+			if err != nil {
+				return authorizer.DecisionDeny, "", err
+			}
+			workspace = namespaceWorkspace
+		}
+
+		if workspace != "" && workspace == scopes[0] {
+			return authorizer.DecisionNoOpinion, "", nil
+		}
+		return authorizer.DecisionDeny, fmt.Sprintf("the token is scoped to workspace %q", scopes[0]), nil
+	})
+}
@@ -0,0 +1,82 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package workspacescope
+
+import (
+	"testing"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+)
+
+type fakeGetter map[string]string
+
+func (f fakeGetter) GetNamespaceControlledWorkspace(namespace string) (string, error) {
+	return f[namespace], nil
+}
+
+func TestNewAuthorizer(t *testing.T) {
+	scoped := &user.DefaultInfo{Name: "admin", Extra: map[string][]string{iamv1beta1.ExtraWorkspaceScope: {"ws1"}}}
+	unscoped := &user.DefaultInfo{Name: "admin"}
+	a := NewAuthorizer(fakeGetter{"ns1": "ws1", "ns2": "ws2"})
+
+	tests := []struct {
+		name  string
+		attrs authorizer.AttributesRecord
+		want  authorizer.Decision
+	}{
+		{
+			name:  "unscoped user",
+			attrs: authorizer.AttributesRecord{User: unscoped, ResourceRequest: true, ResourceScope: request.GlobalScope},
+			want:  authorizer.DecisionNoOpinion,
+		},
+		{
+			name:  "non-resource request",
+			attrs: authorizer.AttributesRecord{User: scoped, Path: "/version"},
+			want:  authorizer.DecisionNoOpinion,
+		},
+		{
+			name:  "in workspace",
+			attrs: authorizer.AttributesRecord{User: scoped, ResourceRequest: true, ResourceScope: request.WorkspaceScope, Workspace: "ws1"},
+			want:  authorizer.DecisionNoOpinion,
+		},
+		{
+			name:  "out of workspace",
+			attrs: authorizer.AttributesRecord{User: scoped, ResourceRequest: true, ResourceScope: request.WorkspaceScope, Workspace: "ws2"},
+			want:  authorizer.DecisionDeny,
+		},
+		{
+			name:  "namespace in workspace",
+			attrs: authorizer.AttributesRecord{User: scoped, ResourceRequest: true, ResourceScope: request.NamespaceScope, Namespace: "ns1"},
+			want:  authorizer.DecisionNoOpinion,
+		},
+		{
+			name:  "namespace out of workspace",
+			attrs: authorizer.AttributesRecord{User: scoped, ResourceRequest: true, ResourceScope: request.NamespaceScope, Namespace: "ns2"},
+			want:  authorizer.DecisionDeny,
+		},
+		{
+			name:  "cluster scope",
+			attrs: authorizer.AttributesRecord{User: scoped, ResourceRequest: true, ResourceScope: request.ClusterScope},
+			want:  authorizer.DecisionDeny,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, _, err := a.Authorize(tt.attrs)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if decision != tt.want {
+				t.Errorf("Authorize() = %v, want %v", decision, tt.want)
+			}
+		})
+	}
+}
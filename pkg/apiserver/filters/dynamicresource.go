@@ -17,7 +17,6 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -26,6 +25,7 @@ import (
 	"kubesphere.io/kubesphere/pkg/apiserver/query"
 	"kubesphere.io/kubesphere/pkg/apiserver/request"
 	"kubesphere.io/kubesphere/pkg/models/resources/v1beta1"
+	"kubesphere.io/kubesphere/pkg/models/resources/watcher"
 )
 
 var NotSupportedVerbError = fmt.Errorf("not supported verb")
@@ -104,7 +104,7 @@ func (d *DynamicResourceHandler) HandleServiceError(serviceError restful.Service
 		}
 	}
 
-	var result runtime.Object
+	var result interface{}
 	switch reqInfo.Verb {
 	case request.VerbGet:
 		result, err = d.GetResource(req.Request.Context(), gvr, reqInfo.Namespace, reqInfo.Name)
@@ -117,7 +117,21 @@ func (d *DynamicResourceHandler) HandleServiceError(serviceError restful.Service
 		if reqInfo.Workspace != "" {
 			_ = q.AppendLabelSelector(map[string]string{tenantv1alpha1.WorkspaceLabel: reqInfo.Workspace})
 		}
-		result, err = d.ListResources(req.Request.Context(), gvr, reqInfo.Namespace, q)
+		if aggregation := query.ParseAggregation(req); aggregation != nil {
+			result, err = d.AggregateResources(req.Request.Context(), gvr, reqInfo.Namespace, q, aggregation)
+		} else {
+			result, err = d.ListResources(req.Request.Context(), gvr, reqInfo.Namespace, q)
+		}
+	case request.VerbWatch:
+		q := query.ParseQueryParameter(req)
+		if reqInfo.Workspace != "" {
+			_ = q.AppendLabelSelector(map[string]string{tenantv1alpha1.WorkspaceLabel: reqInfo.Workspace})
+		}
+		var events <-chan watcher.Event
+		if events, err = d.WatchResources(req.Request.Context(), gvr, reqInfo.Namespace, q, req.HeaderParameter(watcher.LastEventIDHeader)); err == nil {
+			watcher.ServeSSE(w, events)
+			return
+		}
 	case request.VerbCreate:
 		obj, ok := object.(metav1.Object)
 		if reqInfo.Workspace != "" && ok && obj.GetLabels()[tenantv1alpha1.WorkspaceLabel] != reqInfo.Workspace {
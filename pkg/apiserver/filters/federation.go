@@ -0,0 +1,280 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package filters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	"k8s.io/klog/v2"
+	"kubesphere.io/api/constants"
+
+	"kubesphere.io/kubesphere/pkg/api"
+	"kubesphere.io/kubesphere/pkg/apiserver/query"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+	"kubesphere.io/kubesphere/pkg/models/resources/v1beta1"
+	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
+)
+
+const (
+	// maxFederatedRequests limits the clusters listed at the same time
+	maxFederatedRequests = 10
+	federatedTimeout     = 30 * time.Second
+)
+
+type federation struct {
+	next http.Handler
+	clusterclient.Interface
+	resolver request.RequestInfoResolver
+}
+
+// WithFederation lists the resources across the clusters selected by the clusters or clusterSelector parameter,
+// the request is dispatched to every cluster through the next handler as /clusters/{cluster}/... is, then the
+// results are merged, sorted and paginated globally.
+func WithFederation(next http.Handler, clusterClient clusterclient.Interface, resolver request.RequestInfoResolver) http.Handler {
+	if clusterClient == nil {
+		klog.V(4).Infof("Federated list is disabled")
+		return next
+	}
+	return &federation{
+		next:      next,
+		Interface: clusterClient,
+		resolver:  resolver,
+	}
+}
+
+func (f *federation) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	values := req.URL.Query()
+	if !values.Has(query.ParameterClusters) && !values.Has(query.ParameterClusterSelector) {
+		f.next.ServeHTTP(w, req)
+		return
+	}
+	info, ok := request.RequestInfoFrom(req.Context())
+	if !ok {
+		responsewriters.InternalError(w, req, errors.NewInternalError(fmt.Errorf("no RequestInfo found in the context")))
+		return
+	}
+	if info.Cluster != "" || info.IsKubernetesRequest || !info.IsResourceRequest || info.Verb != request.VerbList {
+		f.next.ServeHTTP(w, req)
+		return
+	}
+
+	q := query.ParseQueryParameter(restful.NewRequest(req))
+	if q.Pagination.IsContinuing() {
+		responsewriters.WriteRawJSON(http.StatusBadRequest, errors.NewBadRequest("continue is not supported across the clusters"), w)
+		return
+	}
+	// the objects of the clusters can only be sorted by the metadata, since the objects are decoded regardless of their kinds
+	if !query.IsObjectMetaField(q.SortBy) {
+		responsewriters.WriteRawJSON(http.StatusBadRequest, errors.NewBadRequest(fmt.Sprintf("sortBy %s is not supported across the clusters", q.SortBy)), w)
+		return
+	}
+
+	clusters, err := f.selectClusters(req.Context(), values.Get(query.ParameterClusters), values.Get(query.ParameterClusterSelector))
+	if err != nil {
+		if errors.IsBadRequest(err) {
+			responsewriters.WriteRawJSON(http.StatusBadRequest, err, w)
+		} else {
+			responsewriters.InternalError(w, req, err)
+		}
+		return
+	}
+
+	results := make([]api.ClusterResult, len(clusters))
+	items := make([][]runtime.Object, len(clusters))
+	limiter := make(chan struct{}, maxFederatedRequests)
+	wg := sync.WaitGroup{}
+	for i, cluster := range clusters {
+		wg.Add(1)
+		limiter <- struct{}{}
+		go func(i int, cluster string) {
+			defer func() {
+				// the reverse proxy panics with http.ErrAbortHandler if the cluster aborts the response
+				if r := recover(); r != nil {
+					klog.Errorf("panic listing %s in cluster %s: %v", info.Resource, cluster, r)
+					items[i] = nil
+					results[i].Error = fmt.Sprintf("failed to list %s: %v", info.Resource, r)
+				}
+				<-limiter
+				wg.Done()
+			}()
+			results[i].Cluster = cluster
+			clusterItems, total, err := f.list(req, cluster, q)
+			if err != nil {
+				klog.V(4).Infof("failed to list %s in cluster %s: %s", info.Resource, cluster, err)
+				results[i].Error = err.Error()
+				return
+			}
+			results[i].TotalItems = total
+			items[i] = clusterItems
+		}(i, cluster)
+	}
+	wg.Wait()
+
+	result := &api.FederatedListResult{Items: []runtime.Object{}, Clusters: results}
+	var merged []runtime.Object
+	for i := range clusters {
+		merged = append(merged, items[i]...)
+		result.TotalItems += results[i].TotalItems
+	}
+	less := q.Order(compareObjectMeta)
+	sort.SliceStable(merged, func(i, j int) bool {
+		return less(merged[i], merged[j])
+	})
+	// only the top objects of every cluster are listed, which are enough to locate the page among all the objects
+	start, end := q.Pagination.GetValidPagination(len(merged))
+	result.Items = append(result.Items, merged[start:end]...)
+	responsewriters.WriteRawJSON(http.StatusOK, result, w)
+}
+
+// selectClusters returns the clusters named, or the clusters matching the label selector if no cluster is named.
+func (f *federation) selectClusters(ctx context.Context, names, clusterSelector string) ([]string, error) {
+	var clusters []string
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			clusters = append(clusters, name)
+		}
+	}
+	if len(clusters) > 0 {
+		return clusters, nil
+	}
+
+	selector, err := labels.Parse(clusterSelector)
+	if err != nil {
+		return nil, errors.NewBadRequest(fmt.Sprintf("invalid clusterSelector: %s", err))
+	}
+	list, err := f.ListClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, cluster := range list {
+		if selector.Matches(labels.Set(cluster.Labels)) {
+			clusters = append(clusters, cluster.Name)
+		}
+	}
+	sort.Strings(clusters)
+	return clusters, nil
+}
+
+type clusterListResult struct {
+	Items      []json.RawMessage `json:"items"`
+	TotalItems *int              `json:"totalItems,omitempty"`
+	Metadata   struct {
+		RemainingItemCount *int64 `json:"remainingItemCount,omitempty"`
+	} `json:"metadata"`
+}
+
+// list lists the top objects of the cluster enough to fill the requested page, the objects are labeled with the cluster.
+func (f *federation) list(req *http.Request, cluster string, q *query.Query) ([]runtime.Object, int, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), federatedTimeout)
+	defer cancel()
+
+	clusterReq := req.Clone(ctx)
+	clusterReq.URL.Path = fmt.Sprintf("/clusters/%s%s", cluster, req.URL.Path)
+	clusterReq.URL.RawPath = ""
+	values := clusterReq.URL.Query()
+	values.Del(query.ParameterClusters)
+	values.Del(query.ParameterClusterSelector)
+	values.Del(query.ParameterPage)
+	values.Del(query.ParameterLimit)
+	if q.Pagination.Limit > 0 {
+		values.Set(query.ParameterLimit, strconv.Itoa(q.Pagination.Offset+q.Pagination.Limit))
+	}
+	clusterReq.URL.RawQuery = values.Encode()
+	clusterReq.RequestURI = clusterReq.URL.RequestURI()
+
+	info, err := f.resolver.NewRequestInfo(clusterReq)
+	if err != nil {
+		return nil, 0, err
+	}
+	clusterReq = clusterReq.WithContext(request.WithRequestInfo(ctx, info))
+
+	resp := &responseBuffer{header: http.Header{}, statusCode: http.StatusOK}
+	f.next.ServeHTTP(resp, clusterReq)
+	if resp.statusCode != http.StatusOK {
+		status := &metav1.Status{}
+		if err = json.Unmarshal(resp.body.Bytes(), status); err == nil && status.Message != "" {
+			return nil, 0, fmt.Errorf("%s", status.Message)
+		}
+		return nil, 0, fmt.Errorf("%d %s: %s", resp.statusCode, http.StatusText(resp.statusCode), strings.TrimSpace(resp.body.String()))
+	}
+
+	list := &clusterListResult{}
+	if err = json.Unmarshal(resp.body.Bytes(), list); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode the objects: %s", err)
+	}
+	items := make([]runtime.Object, 0, len(list.Items))
+	for _, item := range list.Items {
+		object := &unstructured.Unstructured{}
+		if err = object.UnmarshalJSON(item); err != nil {
+			return nil, 0, fmt.Errorf("failed to decode the object: %s", err)
+		}
+		objectLabels := object.GetLabels()
+		if objectLabels == nil {
+			objectLabels = map[string]string{}
+		}
+		objectLabels[constants.ClusterNameLabelKey] = cluster
+		object.SetLabels(objectLabels)
+		items = append(items, object)
+	}
+
+	total := len(items)
+	if list.TotalItems != nil {
+		total = *list.TotalItems
+	} else if list.Metadata.RemainingItemCount != nil {
+		total += int(*list.Metadata.RemainingItemCount)
+	}
+	return items, total, nil
+}
+
+func compareObjectMeta(left, right runtime.Object, field query.Field) bool {
+	leftMeta, ok := left.(metav1.Object)
+	if !ok {
+		return false
+	}
+	rightMeta, ok := right.(metav1.Object)
+	if !ok {
+		return false
+	}
+	return v1beta1.DefaultObjectMetaCompare(leftMeta, rightMeta, field)
+}
+
+// responseBuffer buffers the response of a cluster to be merged with the others.
+type responseBuffer struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (r *responseBuffer) Header() http.Header {
+	return r.header
+}
+
+func (r *responseBuffer) Write(data []byte) (int, error) {
+	return r.body.Write(data)
+}
+
+func (r *responseBuffer) WriteHeader(statusCode int) {
+	r.statusCode = statusCode
+}
+
+func (r *responseBuffer) Flush() {}
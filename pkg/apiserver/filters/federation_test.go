@@ -0,0 +1,171 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package filters
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+	"kubesphere.io/api/constants"
+
+	"kubesphere.io/kubesphere/pkg/api"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
+)
+
+type fakeClusterClient struct {
+	clusterclient.Interface
+	clusters []clusterv1alpha1.Cluster
+}
+
+func (f *fakeClusterClient) ListClusters(context.Context) ([]clusterv1alpha1.Cluster, error) {
+	return f.clusters, nil
+}
+
+// fakeClusterHandler lists the workspaces named in every cluster sorted by name, and panics as the reverse
+// proxy does for the cluster named broken.
+func fakeClusterHandler(workspaces map[string][]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		info, _ := request.RequestInfoFrom(req.Context())
+		if info.Cluster == "broken" {
+			panic(http.ErrAbortHandler)
+		}
+		names := append([]string{}, workspaces[info.Cluster]...)
+		sort.Strings(names)
+		total := len(names)
+		if limit, err := strconv.Atoi(req.URL.Query().Get("limit")); err == nil && limit < len(names) {
+			names = names[:limit]
+		}
+		items := make([]interface{}, 0, len(names))
+		for _, name := range names {
+			items = append(items, map[string]interface{}{
+				"apiVersion": "tenant.kubesphere.io/v1beta1",
+				"kind":       "Workspace",
+				"metadata":   map[string]interface{}{"name": name},
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"items": items, "totalItems": total})
+	})
+}
+
+func TestFederation(t *testing.T) {
+	resolver := &request.RequestInfoFactory{
+		APIPrefixes:          sets.New("api", "apis", "kapis", "kapi"),
+		GrouplessAPIPrefixes: sets.New("api", "kapi"),
+	}
+	clusterClient := &fakeClusterClient{clusters: []clusterv1alpha1.Cluster{
+		{ObjectMeta: metav1.ObjectMeta{Name: "host", Labels: map[string]string{"env": "prod"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "member", Labels: map[string]string{"env": "prod"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "test", Labels: map[string]string{"env": "test"}}},
+	}}
+	handler := WithFederation(fakeClusterHandler(map[string][]string{
+		"host":   {"a", "d", "e"},
+		"member": {"b", "c", "f"},
+		"test":   {"g"},
+	}), clusterClient, resolver)
+
+	type item struct {
+		Cluster string
+		Name    string
+	}
+	tests := []struct {
+		name             string
+		query            string
+		expectedItems    []item
+		expectedTotal    int
+		expectedClusters []api.ClusterResult
+	}{
+		{
+			name:  "sorted and paginated globally",
+			query: "clusters=host,member&sortBy=name&ascending=true&page=2&limit=2",
+			expectedItems: []item{
+				{Cluster: "member", Name: "c"},
+				{Cluster: "host", Name: "d"},
+			},
+			expectedTotal: 6,
+			expectedClusters: []api.ClusterResult{
+				{Cluster: "host", TotalItems: 3},
+				{Cluster: "member", TotalItems: 3},
+			},
+		},
+		{
+			name:  "clusters selected by the label selector",
+			query: "clusterSelector=env%3Dprod&sortBy=name&limit=3",
+			expectedItems: []item{
+				{Cluster: "member", Name: "f"},
+				{Cluster: "host", Name: "e"},
+				{Cluster: "host", Name: "d"},
+			},
+			expectedTotal: 6,
+			expectedClusters: []api.ClusterResult{
+				{Cluster: "host", TotalItems: 3},
+				{Cluster: "member", TotalItems: 3},
+			},
+		},
+		{
+			name:  "the failed cluster excluded",
+			query: "clusters=broken,test&sortBy=name",
+			expectedItems: []item{
+				{Cluster: "test", Name: "g"},
+			},
+			expectedTotal: 1,
+			expectedClusters: []api.ClusterResult{
+				{Cluster: "broken", Error: "failed to list workspaces: " + http.ErrAbortHandler.Error()},
+				{Cluster: "test", TotalItems: 1},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/kapis/tenant.kubesphere.io/v1beta1/workspaces?"+test.query, nil)
+			info, err := resolver.NewRequestInfo(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req = req.WithContext(request.WithRequestInfo(req.Context(), info))
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", recorder.Code, strings.TrimSpace(recorder.Body.String()))
+			}
+
+			result := struct {
+				Items []struct {
+					Metadata metav1.ObjectMeta `json:"metadata"`
+				} `json:"items"`
+				TotalItems int                 `json:"totalItems"`
+				Clusters   []api.ClusterResult `json:"clusters"`
+			}{}
+			if err = json.Unmarshal(recorder.Body.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+			items := []item{}
+			for _, object := range result.Items {
+				items = append(items, item{Cluster: object.Metadata.Labels[constants.ClusterNameLabelKey], Name: object.Metadata.Name})
+			}
+			if diff := cmp.Diff(test.expectedItems, items); diff != "" {
+				t.Errorf("items: %s", diff)
+			}
+			if result.TotalItems != test.expectedTotal {
+				t.Errorf("expected %d items in total, got %d", test.expectedTotal, result.TotalItems)
+			}
+			if diff := cmp.Diff(test.expectedClusters, result.Clusters); diff != "" {
+				t.Errorf("clusters: %s", diff)
+			}
+		})
+	}
+}
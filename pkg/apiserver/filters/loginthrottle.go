@@ -0,0 +1,120 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package filters
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apiserver/pkg/endpoints/handlers/responsewriters"
+	"k8s.io/apiserver/pkg/endpoints/responsewriter"
+	"k8s.io/klog/v2"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+	"kubesphere.io/kubesphere/pkg/models/auth"
+	"kubesphere.io/kubesphere/pkg/utils/iputil"
+)
+
+// loginPaths are the endpoints accepting credentials, requests with basic authentication are login attempts as well.
+var loginPaths = map[string]bool{
+	"/oauth/token":        true,
+	"/oauth/authenticate": true,
+}
+
+type loginThrottleFilter struct {
+	next           http.Handler
+	throttle       auth.LoginThrottle
+	trustedProxies []*net.IPNet
+	// auditing is optional, the blocked source IPs are recorded as auditing events if it is not nil
+	auditing   auditing.Auditing
+	serializer runtime.NegotiatedSerializer
+}
+
+// WithLoginThrottle rejects the login attempts from the blocked source IPs with 429 Too Many Requests,
+// and records the login attempts flagged by the handlers for invalid credentials or successful logins
+// to the throttle, see auth.FlagLoginFailure and auth.FlagLoginSuccess.
+// It must be installed before WithAuthentication to throttle the basic authentication.
+func WithLoginThrottle(next http.Handler, throttle auth.LoginThrottle, trustedProxies []*net.IPNet, auditing auditing.Auditing) http.Handler {
+	return &loginThrottleFilter{
+		next:           next,
+		throttle:       throttle,
+		trustedProxies: trustedProxies,
+		auditing:       auditing,
+		serializer:     serializer.NewCodecFactory(runtime.NewScheme()).WithoutConversion(),
+	}
+}
+
+func (l *loginThrottleFilter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	_, _, usingBasicAuth := req.BasicAuth()
+	loginPath := loginPaths[req.URL.Path]
+	if !usingBasicAuth && !loginPath {
+		l.next.ServeHTTP(w, req)
+		return
+	}
+
+	ip := iputil.ClientIP(req, l.trustedProxies)
+	block, err := l.throttle.Blocked(ip)
+	if err != nil {
+		// fail open, the users are still protected by AuthenticateRateLimiter
+		klog.Errorf("failed to check the login throttle of %s: %s", ip, err)
+	}
+	if block != nil {
+		err = apierrors.NewTooManyRequests(fmt.Sprintf("Too many failed login attempts from %s, please try again later.", block.Subject),
+			int(block.RetryAfter.Seconds())+1)
+		responsewriters.ErrorNegotiated(err, l.serializer, schema.GroupVersion{}, w, req)
+		return
+	}
+
+	ctx, attempt := auth.WithLoginAttempt(req.Context())
+	req = req.WithContext(ctx)
+	resp := auditing.NewResponseCapture(w)
+	l.next.ServeHTTP(responsewriter.WrapForHTTP1Or2(resp), req)
+	if !attempt.Failed() {
+		if attempt.Succeeded() {
+			if err = l.throttle.RecordSuccess(ip); err != nil {
+				klog.Errorf("failed to record the successful login from %s: %s", ip, err)
+			}
+		}
+		return
+	}
+
+	block, err = l.throttle.RecordFailure(ip)
+	if err != nil {
+		klog.Errorf("failed to record the failed login attempt from %s: %s", ip, err)
+		return
+	}
+	if block != nil {
+		klog.Warningf("source %s %s is blocked for %s due to too many failed login attempts", block.Scope, block.Subject, block.RetryAfter)
+		l.audit(req, resp, block)
+	}
+}
+
+func (l *loginThrottleFilter) audit(req *http.Request, resp *auditing.ResponseCapture, block *auth.LoginBlock) {
+	if l.auditing == nil || !l.auditing.Enabled() {
+		return
+	}
+	info, ok := request.RequestInfoFrom(req.Context())
+	if !ok {
+		return
+	}
+	// the credentials in the request body must not be recorded
+	withoutBody := req.Clone(req.Context())
+	withoutBody.Body = http.NoBody
+	withoutBody.ContentLength = 0
+	if event := l.auditing.LogRequestObject(withoutBody, info); event != nil {
+		event.Verb = "block"
+		event.Message = fmt.Sprintf("Source %s %s is blocked for %s due to too many failed login attempts",
+			block.Scope, block.Subject, block.RetryAfter)
+		l.auditing.LogResponseObject(event, resp)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package filters
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication"
+	"kubesphere.io/kubesphere/pkg/models/auth"
+	"kubesphere.io/kubesphere/pkg/simple/client/cache"
+)
+
+// fakeLoginHandler logs in the user if the password is correct, and flags the login attempt as the handlers do.
+func fakeLoginHandler(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Query().Get("password") != "P@88w0rd" {
+		auth.FlagLoginFailure(req.Context())
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	auth.FlagLoginSuccess(req.Context())
+	w.WriteHeader(http.StatusOK)
+}
+
+func TestWithLoginThrottle(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	inMemoryCache, err := cache.NewInMemoryCache(nil, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := authentication.NewOptions().LoginThrottle
+	options.MaxFailuresPerIP = 3
+	options.MaxFailuresPerCIDR = 0
+	handler := WithLoginThrottle(http.HandlerFunc(fakeLoginHandler), auth.NewLoginThrottle(inMemoryCache, &options), nil, nil)
+
+	login := func(password string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodPost, "/oauth/token?password="+password, nil)
+		request.RemoteAddr = "10.0.0.1:52000"
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	for i := 0; i < options.MaxFailuresPerIP-1; i++ {
+		if recorder := login("wrong"); recorder.Code != http.StatusUnauthorized {
+			t.Fatalf("status code = %d, want %d", recorder.Code, http.StatusUnauthorized)
+		}
+	}
+	// the failures are forgotten once the user logs in
+	if recorder := login("P@88w0rd"); recorder.Code != http.StatusOK {
+		t.Fatalf("status code = %d, want %d", recorder.Code, http.StatusOK)
+	}
+	for i := 0; i < options.MaxFailuresPerIP-1; i++ {
+		if recorder := login("wrong"); recorder.Code != http.StatusUnauthorized {
+			t.Fatalf("status code = %d, want %d", recorder.Code, http.StatusUnauthorized)
+		}
+	}
+	if recorder := login("wrong"); recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("status code = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+
+	// the blocked source IP is rejected even with the correct password
+	recorder := login("P@88w0rd")
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("status code = %d, want %d: %s", recorder.Code, http.StatusTooManyRequests, recorder.Body.String())
+	}
+	retryAfter, err := strconv.Atoi(recorder.Header().Get("Retry-After"))
+	if err != nil {
+		t.Fatalf("invalid Retry-After header %q: %v", recorder.Header().Get("Retry-After"), err)
+	}
+	if retryAfter <= 0 || retryAfter > int(options.BlockDuration.Seconds())+1 {
+		t.Errorf("Retry-After = %d, want at most %d", retryAfter, int(options.BlockDuration.Seconds())+1)
+	}
+
+	// the other requests are not throttled
+	recorder = httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/kapis/iam.kubesphere.io/v1beta1/users?password=P@88w0rd", nil)
+	request.RemoteAddr = "10.0.0.1:52000"
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusOK {
+		t.Errorf("status code = %d, want %d", recorder.Code, http.StatusOK)
+	}
+}
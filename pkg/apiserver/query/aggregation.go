@@ -0,0 +1,100 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package query
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/emicklei/go-restful/v3"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/sets"
+
+	"kubesphere.io/kubesphere/pkg/api"
+	"kubesphere.io/kubesphere/pkg/utils/resourceutil"
+)
+
+// Aggregation groups the objects listed by the values of a field or a label.
+type Aggregation struct {
+	// Field is the field the objects are grouped by
+	Field Field
+
+	// LabelKey is the key of the label the objects are grouped by, which takes precedence over the field
+	LabelKey string
+
+	// SumRequests sums up the resource requests of the containers of the objects in each group
+	SumRequests bool
+}
+
+// ParseAggregation returns the aggregation requested, or nil if the objects are listed instead.
+func ParseAggregation(request *restful.Request) *Aggregation {
+	aggregateBy := request.QueryParameter(ParameterAggregateBy)
+	if aggregateBy == "" {
+		return nil
+	}
+	aggregation := &Aggregation{}
+	if strings.HasPrefix(aggregateBy, AggregateByLabelPrefix) {
+		aggregation.LabelKey = strings.TrimPrefix(aggregateBy, AggregateByLabelPrefix)
+	} else {
+		aggregation.Field = Field(aggregateBy)
+	}
+	aggregation.SumRequests, _ = strconv.ParseBool(request.QueryParameter(ParameterSumRequests))
+	return aggregation
+}
+
+// Aggregate groups the objects by the values returned by the fieldValues func, or by the values of the label
+// if the label key is set. The buckets are sorted by the counts in descending order, then by the values.
+func (a *Aggregation) Aggregate(objects []runtime.Object, fieldValues func(runtime.Object, Field) []string) *api.AggregationResult {
+	buckets := make(map[string]*api.AggregationBucket)
+	for _, object := range objects {
+		var values []string
+		if a.LabelKey != "" {
+			if accessor, err := meta.Accessor(object); err == nil {
+				values = []string{accessor.GetLabels()[a.LabelKey]}
+			}
+		} else {
+			values = fieldValues(object, a.Field)
+		}
+		if len(values) == 0 {
+			values = []string{""}
+		}
+
+		var requests corev1.ResourceList
+		if a.SumRequests {
+			requests = resourceutil.ObjectRequests(object)
+		}
+		for _, value := range sets.List(sets.New(values...)) {
+			bucket, ok := buckets[value]
+			if !ok {
+				bucket = &api.AggregationBucket{Value: value}
+				buckets[value] = bucket
+			}
+			bucket.Count++
+			if len(requests) > 0 {
+				if bucket.Requests == nil {
+					bucket.Requests = corev1.ResourceList{}
+				}
+				resourceutil.AddResourceList(bucket.Requests, requests)
+			}
+		}
+	}
+
+	result := &api.AggregationResult{Buckets: make([]api.AggregationBucket, 0, len(buckets)), TotalItems: len(objects)}
+	for _, bucket := range buckets {
+		result.Buckets = append(result.Buckets, *bucket)
+	}
+	sort.Slice(result.Buckets, func(i, j int) bool {
+		if result.Buckets[i].Count != result.Buckets[j].Count {
+			return result.Buckets[i].Count > result.Buckets[j].Count
+		}
+		return result.Buckets[i].Value < result.Buckets[j].Value
+	})
+	return result
+}
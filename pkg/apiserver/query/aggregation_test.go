@@ -0,0 +1,127 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package query
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/ptr"
+
+	"kubesphere.io/kubesphere/pkg/api"
+)
+
+func TestParseAggregation(t *testing.T) {
+	tests := []struct {
+		queryString string
+		expected    *Aggregation
+	}{
+		{queryString: "status=Running", expected: nil},
+		{queryString: "aggregateBy=status", expected: &Aggregation{Field: FieldStatus}},
+		{queryString: "aggregateBy=label:app.kubernetes.io/name&sumRequests=true", expected: &Aggregation{LabelKey: "app.kubernetes.io/name", SumRequests: true}},
+	}
+	for _, test := range tests {
+		t.Run(test.queryString, func(t *testing.T) {
+			req, err := http.NewRequest("GET", fmt.Sprintf("http://localhost?%s", test.queryString), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			request := restful.NewRequest(req)
+			if diff := cmp.Diff(test.expected, ParseAggregation(request)); diff != "" {
+				t.Error(diff)
+			}
+			if _, ok := ParseQueryParameter(request).Filters[ParameterAggregateBy]; ok {
+				t.Errorf("unexpected filter %s", ParameterAggregateBy)
+			}
+		})
+	}
+}
+
+func newPod(name, app string, owners []metav1.OwnerReference, cpu string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"app": app}, OwnerReferences: owners},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}},
+		}}},
+	}
+}
+
+func ownerKinds(object runtime.Object, _ Field) []string {
+	var kinds []string
+	for _, owner := range object.(metav1.Object).GetOwnerReferences() {
+		kinds = append(kinds, owner.Kind)
+	}
+	return kinds
+}
+
+func TestAggregate(t *testing.T) {
+	replicaSet := metav1.OwnerReference{Kind: "ReplicaSet", Name: "rs"}
+	node := metav1.OwnerReference{Kind: "Node", Name: "node"}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "deployment", Labels: map[string]string{"app": "foo"}},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: ptr.To[int32](3),
+			Template: corev1.PodTemplateSpec{Spec: newPod("", "", nil, "100m").Spec},
+		},
+	}
+	objects := []runtime.Object{
+		newPod("pod-1", "foo", []metav1.OwnerReference{replicaSet}, "100m"),
+		newPod("pod-2", "foo", []metav1.OwnerReference{replicaSet, replicaSet}, "200m"),
+		newPod("pod-3", "bar", []metav1.OwnerReference{node}, "500m"),
+		newPod("pod-4", "", nil, "1"),
+		deployment,
+	}
+
+	tests := []struct {
+		name        string
+		aggregation *Aggregation
+		expected    *api.AggregationResult
+	}{
+		{
+			name:        "label",
+			aggregation: &Aggregation{LabelKey: "app", SumRequests: true},
+			expected: &api.AggregationResult{
+				Buckets: []api.AggregationBucket{
+					{Value: "foo", Count: 3, Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("600m")}},
+					{Value: "", Count: 1, Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}},
+					{Value: "bar", Count: 1, Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}},
+				},
+				TotalItems: 5,
+			},
+		},
+		{
+			name:        "field",
+			aggregation: &Aggregation{Field: FieldOwnerKind},
+			expected: &api.AggregationResult{
+				Buckets: []api.AggregationBucket{
+					{Value: "", Count: 2},
+					{Value: "ReplicaSet", Count: 2},
+					{Value: "Node", Count: 1},
+				},
+				TotalItems: 5,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := test.aggregation.Aggregate(objects, ownerKinds)
+			if diff := cmp.Diff(test.expected, result, cmp.Comparer(func(x, y resource.Quantity) bool {
+				return x.Cmp(y) == 0
+			})); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/v2"
+)
+
+// continueToken identifies the last object of the page returned. The next page starts right after the position
+// the object is sorted into, so the pages are not shifted by the objects created or deleted concurrently.
+type continueToken struct {
+	SortBy            Field       `json:"sortBy,omitempty"`
+	Ascending         bool        `json:"ascending,omitempty"`
+	Namespace         string      `json:"namespace,omitempty"`
+	Name              string      `json:"name"`
+	UID               types.UID   `json:"uid,omitempty"`
+	CreationTimestamp metav1.Time `json:"creationTimestamp"`
+	// Offset is the position of the next page, which is only used if the last object is no longer listed
+	// and the position can not be located by the sort field
+	Offset int `json:"offset"`
+}
+
+func newContinueToken(q *Query, last runtime.Object, offset int) (string, error) {
+	accessor, err := meta.Accessor(last)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(continueToken{
+		SortBy:            q.SortBy,
+		Ascending:         q.Ascending,
+		Namespace:         accessor.GetNamespace(),
+		Name:              accessor.GetName(),
+		UID:               accessor.GetUID(),
+		CreationTimestamp: accessor.GetCreationTimestamp(),
+		Offset:            offset,
+	})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeContinueToken(value string) (*continueToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	token := &continueToken{}
+	if err = json.Unmarshal(data, token); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// ValidateContinue returns a bad request error if the continue token can't be decoded or is issued for another order,
+// which must be rejected before listing instead of returning an empty page, since the clients take it as the end
+// of the list.
+func (q *Query) ValidateContinue() error {
+	if q.Pagination == nil || q.Pagination.Continue == "" {
+		return nil
+	}
+	token, err := decodeContinueToken(q.Pagination.Continue)
+	if err != nil {
+		return errors.NewBadRequest("invalid continue token")
+	}
+	if token.SortBy != q.SortBy || token.Ascending != q.Ascending {
+		return errors.NewBadRequest("the continue token is issued for another order, the sortBy and ascending parameters must not be changed")
+	}
+	return nil
+}
+
+// position returns the index of the first sorted object after the last object of the token.
+func (t *continueToken) position(sorted []runtime.Object, less func(left, right runtime.Object) bool) int {
+	// the objects sorted by the immutable metadata fields are located by a copy carrying the metadata
+	// of the last object, which works even though the last object has been deleted
+	if IsObjectMetaField(t.SortBy) && len(sorted) > 0 {
+		last := sorted[0].DeepCopyObject()
+		if accessor, err := meta.Accessor(last); err == nil {
+			accessor.SetNamespace(t.Namespace)
+			accessor.SetName(t.Name)
+			accessor.SetUID(t.UID)
+			accessor.SetCreationTimestamp(t.CreationTimestamp)
+			return sort.Search(len(sorted), func(i int) bool {
+				return less(last, sorted[i])
+			})
+		}
+	}
+	for i, object := range sorted {
+		if accessor, err := meta.Accessor(object); err == nil && accessor.GetUID() == t.UID &&
+			accessor.GetNamespace() == t.Namespace && accessor.GetName() == t.Name {
+			return i + 1
+		}
+	}
+	if t.Offset > len(sorted) {
+		return len(sorted)
+	}
+	return t.Offset
+}
+
+// IsObjectMetaField returns true if the objects are sorted by the metadata field the same way regardless of their kinds.
+func IsObjectMetaField(field Field) bool {
+	switch field {
+	case "", FieldName, FieldCreateTime, FieldCreationTimeStamp:
+		return true
+	default:
+		return false
+	}
+}
+
+// Order returns the function sorting the objects as requested by the query, the compare func reports whether
+// the left object is greater than the right one. The objects compared equal are ordered by their namespaces,
+// names and UIDs, so that every object has a fixed position in the pages.
+func (q *Query) Order(compare func(left, right runtime.Object, field Field) bool) func(left, right runtime.Object) bool {
+	return func(left, right runtime.Object) bool {
+		greater, lesser := left, right
+		if q.Ascending {
+			greater, lesser = right, left
+		}
+		if compare(greater, lesser, q.SortBy) {
+			return true
+		}
+		if compare(lesser, greater, q.SortBy) {
+			return false
+		}
+		return objectKey(left) < objectKey(right)
+	}
+}
+
+func objectKey(object runtime.Object) string {
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return ""
+	}
+	return strings.Join([]string{accessor.GetNamespace(), accessor.GetName(), string(accessor.GetUID())}, "/")
+}
+
+// Page returns the range of the sorted objects in the page requested by the query, along with the continue
+// token of the next page, which is empty if it's the last page. The objects must be sorted by the less func
+// returned by Order. An invalid continue token or one issued for another order results in an empty page,
+// it should be rejected by ValidateContinue in advance.
+func (q *Query) Page(sorted []runtime.Object, less func(left, right runtime.Object) bool) (start, end int, next string) {
+	pagination := q.Pagination
+	if pagination == nil {
+		pagination = NoPagination
+	}
+	total := len(sorted)
+
+	if !pagination.IsContinuing() {
+		start, end = pagination.GetValidPagination(total)
+	} else {
+		if pagination.Limit < 0 && pagination.Limit != NoPagination.Limit {
+			return 0, 0, ""
+		}
+		if pagination.Continue != "" {
+			token, err := decodeContinueToken(pagination.Continue)
+			if err != nil {
+				klog.V(4).Infof("invalid continue token: %s", err)
+				return 0, 0, ""
+			}
+			if token.SortBy != q.SortBy || token.Ascending != q.Ascending {
+				klog.V(4).Infof("continue token issued for another order: sortBy=%s, ascending=%t", token.SortBy, token.Ascending)
+				return 0, 0, ""
+			}
+			start = token.position(sorted, less)
+		}
+		end = total
+		if pagination.Limit != NoPagination.Limit && start+pagination.Limit < total {
+			end = start + pagination.Limit
+		}
+	}
+
+	if start < end && end < total {
+		token, err := newContinueToken(q, sorted[end-1], end)
+		if err != nil {
+			klog.Warningf("failed to issue continue token: %s", err)
+			return start, end, ""
+		}
+		next = token
+	}
+	return start, end, next
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package query
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func compareName(left, right runtime.Object, _ Field) bool {
+	return left.(*corev1.ConfigMap).Name > right.(*corev1.ConfigMap).Name
+}
+
+func compareCreationTimestamp(left, right runtime.Object, _ Field) bool {
+	return left.(*corev1.ConfigMap).CreationTimestamp.After(right.(*corev1.ConfigMap).CreationTimestamp.Time)
+}
+
+func newConfigMap(name string, created time.Time) *corev1.ConfigMap {
+	return &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+		Name:              name,
+		Namespace:         "default",
+		UID:               types.UID("uid-" + name),
+		CreationTimestamp: metav1.NewTime(created),
+	}}
+}
+
+func page(q *Query, objects []runtime.Object, compare func(left, right runtime.Object, field Field) bool) ([]string, string) {
+	less := q.Order(compare)
+	sorted := append([]runtime.Object{}, objects...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return less(sorted[i], sorted[j])
+	})
+	start, end, next := q.Page(sorted, less)
+	names := make([]string, 0)
+	for _, object := range sorted[start:end] {
+		names = append(names, object.(*corev1.ConfigMap).Name)
+	}
+	return names, next
+}
+
+func TestContinueToken(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var objects []runtime.Object
+	for i := 0; i < 7; i++ {
+		objects = append(objects, newConfigMap(fmt.Sprintf("cm-%d", i), created))
+	}
+
+	q := &Query{
+		Pagination: &Pagination{Limit: 3, Continuing: true},
+		SortBy:     FieldName,
+		Ascending:  true,
+		Filters:    map[Field]Value{},
+	}
+	names, next := page(q, objects, compareName)
+	if diff := cmp.Diff([]string{"cm-0", "cm-1", "cm-2"}, names); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// the last object of the page is deleted and objects are created before the next page concurrently
+	objects = append(objects[3:], newConfigMap("cm-1a", created), newConfigMap("cm-3a", created))
+	q.Pagination.Continue = next
+	names, next = page(q, objects, compareName)
+	if diff := cmp.Diff([]string{"cm-3", "cm-3a", "cm-4"}, names); diff != "" {
+		t.Fatal(diff)
+	}
+
+	q.Pagination.Continue = next
+	names, next = page(q, objects, compareName)
+	if diff := cmp.Diff([]string{"cm-5", "cm-6"}, names); diff != "" {
+		t.Fatal(diff)
+	}
+	if next != "" {
+		t.Errorf("expected no more pages, got continue token %s", next)
+	}
+}
+
+func TestContinueTokenByOtherFields(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	objects := []runtime.Object{
+		newConfigMap("a", created),
+		newConfigMap("b", created.Add(time.Minute)),
+		newConfigMap("c", created.Add(2*time.Minute)),
+		newConfigMap("d", created.Add(3*time.Minute)),
+	}
+	q := &Query{
+		Pagination: &Pagination{Limit: 2, Offset: 0},
+		SortBy:     FieldStatus,
+		Filters:    map[Field]Value{},
+	}
+	names, next := page(q, objects, compareCreationTimestamp)
+	if diff := cmp.Diff([]string{"d", "c"}, names); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// the last object of the page is located by its UID if it's not sorted by the metadata
+	q.Pagination = &Pagination{Limit: 2, Continue: next}
+	names, _ = page(q, append([]runtime.Object{newConfigMap("e", created.Add(time.Hour))}, objects...), compareCreationTimestamp)
+	if diff := cmp.Diff([]string{"b", "a"}, names); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// the offset is used once the last object of the page is deleted
+	names, _ = page(q, objects[:2], compareCreationTimestamp)
+	if diff := cmp.Diff([]string{}, names); diff != "" {
+		t.Fatal(diff)
+	}
+
+	if err := q.ValidateContinue(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// continue token issued for another order
+	q.Ascending = true
+	if err := q.ValidateContinue(); !errors.IsBadRequest(err) {
+		t.Errorf("expected bad request error, got %v", err)
+	}
+	names, _ = page(q, objects, compareCreationTimestamp)
+	if diff := cmp.Diff([]string{}, names); diff != "" {
+		t.Fatal(diff)
+	}
+
+	// invalid continue token
+	q.Pagination.Continue = "invalid"
+	if err := q.ValidateContinue(); !errors.IsBadRequest(err) {
+		t.Errorf("expected bad request error, got %v", err)
+	}
+	names, _ = page(q, objects, compareCreationTimestamp)
+	if diff := cmp.Diff([]string{}, names); diff != "" {
+		t.Fatal(diff)
+	}
+}
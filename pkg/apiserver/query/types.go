@@ -8,6 +8,7 @@ package query
 
 import (
 	"strconv"
+	"strings"
 
 	"github.com/emicklei/go-restful/v3"
 	"k8s.io/apimachinery/pkg/labels"
@@ -16,13 +17,22 @@ import (
 )
 
 const (
-	ParameterName          = "name"
-	ParameterLabelSelector = "labelSelector"
-	ParameterFieldSelector = "fieldSelector"
-	ParameterPage          = "page"
-	ParameterLimit         = "limit"
-	ParameterOrderBy       = "sortBy"
-	ParameterAscending     = "ascending"
+	ParameterName            = "name"
+	ParameterLabelSelector   = "labelSelector"
+	ParameterFieldSelector   = "fieldSelector"
+	ParameterPage            = "page"
+	ParameterLimit           = "limit"
+	ParameterOrderBy         = "sortBy"
+	ParameterAscending       = "ascending"
+	ParameterContinue        = "continue"
+	ParameterAggregateBy     = "aggregateBy"
+	ParameterSumRequests     = "sumRequests"
+	ParameterWatch           = "watch"
+	ParameterClusters        = "clusters"
+	ParameterClusterSelector = "clusterSelector"
+
+	// AggregateByLabelPrefix is the prefix of the label key the objects are aggregated by, e.g. aggregateBy=label:app
+	AggregateByLabelPrefix = "label:"
 )
 
 // Query represents api search terms
@@ -47,6 +57,14 @@ type Pagination struct {
 
 	// offset
 	Offset int
+
+	// Continue is the token returned along with the previous page, the page right after
+	// the last object of the previous page is returned regardless of the offset if set
+	Continue string
+
+	// Continuing is true if the continue parameter is present even empty,
+	// which starts the pagination by the continue tokens from the first page
+	Continuing bool
 }
 
 var NoPagination = newPagination(-1, 0)
@@ -76,6 +94,11 @@ func (q *Query) AppendLabelSelector(ls map[string]string) error {
 	return nil
 }
 
+// IsContinuing returns true if the pages are located by the continue tokens instead of the offset.
+func (p *Pagination) IsContinuing() bool {
+	return p.Continuing || p.Continue != ""
+}
+
 func (p *Pagination) GetValidPagination(total int) (startIndex, endIndex int) {
 	// no pagination
 	if p.Limit == NoPagination.Limit {
@@ -126,6 +149,12 @@ func ParseQueryParameter(request *restful.Request) *Query {
 	}
 
 	query.Pagination = newPagination(limit, (page-1)*limit)
+	if values, ok := request.Request.URL.Query()[ParameterContinue]; ok {
+		query.Pagination.Continuing = true
+		if len(values) > 0 {
+			query.Pagination.Continue = values[0]
+		}
+	}
 
 	query.SortBy = Field(defaultString(request.QueryParameter(ParameterOrderBy), FieldCreationTimeStamp))
 
@@ -139,7 +168,7 @@ func ParseQueryParameter(request *restful.Request) *Query {
 	query.LabelSelector = request.QueryParameter(ParameterLabelSelector)
 
 	for key, values := range request.Request.URL.Query() {
-		if !sliceutil.HasString([]string{ParameterPage, ParameterLimit, ParameterOrderBy, ParameterAscending, ParameterLabelSelector}, key) {
+		if !sliceutil.HasString([]string{ParameterPage, ParameterLimit, ParameterOrderBy, ParameterAscending, ParameterLabelSelector, ParameterContinue, ParameterAggregateBy, ParameterSumRequests, ParameterWatch, ParameterClusters, ParameterClusterSelector}, key) {
 			value := ""
 			if len(values) > 0 {
 				value = values[0]
@@ -151,6 +180,20 @@ func ParseQueryParameter(request *restful.Request) *Query {
 	return query
 }
 
+// IsWatch returns true if the objects are watched instead of listed, the same as the watch verb of the request is resolved.
+func IsWatch(request *restful.Request) bool {
+	values, ok := request.Request.URL.Query()[ParameterWatch]
+	if !ok || len(values) == 0 {
+		return false
+	}
+	switch strings.ToLower(values[0]) {
+	case "false", "0":
+		return false
+	default:
+		return true
+	}
+}
+
 func defaultString(value, defaultValue string) string {
 	if len(value) == 0 {
 		return defaultValue
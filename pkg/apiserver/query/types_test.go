@@ -69,3 +69,27 @@ func TestParseQueryParameter(t *testing.T) {
 		})
 	}
 }
+
+func TestIsWatch(t *testing.T) {
+	tests := map[string]bool{
+		"status=Running":       false,
+		"watch=false":          false,
+		"watch=0":              false,
+		"watch=true":           true,
+		"watch=1":              true,
+		"watch=&status=Failed": true,
+	}
+	for queryString, expected := range tests {
+		req, err := http.NewRequest("GET", fmt.Sprintf("http://localhost?%s", queryString), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		request := restful.NewRequest(req)
+		if got := IsWatch(request); got != expected {
+			t.Errorf("%s: expected %t, got %t", queryString, expected, got)
+		}
+		if _, ok := ParseQueryParameter(request).Filters[ParameterWatch]; ok {
+			t.Errorf("%s: unexpected filter %s", queryString, ParameterWatch)
+		}
+	}
+}
@@ -25,10 +25,12 @@ type ContainerBuilder []func(c *restful.Container) error
 const MimeMergePatchJson = "application/merge-patch+json"
 const MimeJsonPatchJson = "application/json-patch+json"
 const MimeMultipartFormData = "multipart/form-data"
+const MimeSCIMJson = "application/scim+json"
 
 func init() {
 	restful.RegisterEntityAccessor(MimeMergePatchJson, restful.NewEntityAccessorJSON(restful.MIME_JSON))
 	restful.RegisterEntityAccessor(MimeJsonPatchJson, restful.NewEntityAccessorJSON(restful.MIME_JSON))
+	restful.RegisterEntityAccessor(MimeSCIMJson, restful.NewEntityAccessorJSON(MimeSCIMJson))
 }
 
 func NewWebService(gv schema.GroupVersion) *restful.WebService {
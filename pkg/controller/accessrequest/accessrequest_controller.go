@@ -0,0 +1,175 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package accessrequest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"kubesphere.io/kubesphere/pkg/constants"
+	kscontroller "kubesphere.io/kubesphere/pkg/controller"
+	"kubesphere.io/kubesphere/pkg/models/kubeconfig"
+	rbacutils "kubesphere.io/kubesphere/pkg/utils/rbac"
+)
+
+const (
+	controllerName = "accessrequest"
+	// AccessGranted is the reason of the event recorded when the time-bound role binding is created
+	AccessGranted = "AccessGranted"
+	// AccessExpired is the reason of the event recorded when the access granted expires
+	AccessExpired = "AccessExpired"
+)
+
+var _ kscontroller.Controller = &Reconciler{}
+var _ reconcile.Reconciler = &Reconciler{}
+
+// Reconciler creates the time-bound role bindings for the approved access requests,
+// the role bindings are garbage collected by their controllers once expired.
+type Reconciler struct {
+	client.Client
+	logger   logr.Logger
+	recorder record.EventRecorder
+}
+
+func (r *Reconciler) Name() string {
+	return controllerName
+}
+
+func (r *Reconciler) SetupWithManager(mgr *kscontroller.Manager) error {
+	r.Client = mgr.GetClient()
+	r.logger = ctrl.Log.WithName("controllers").WithName(controllerName)
+	r.recorder = mgr.GetEventRecorderFor(controllerName)
+	return builder.
+		ControllerManagedBy(mgr).
+		For(&iamv1beta1.AccessRequest{}).
+		Named(controllerName).
+		Complete(r)
+}
+
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := r.logger.WithValues("AccessRequest", req.String())
+	ctx = logr.NewContext(ctx, log)
+	accessRequest := &iamv1beta1.AccessRequest{}
+	if err := r.Get(ctx, req.NamespacedName, accessRequest); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if !accessRequest.DeletionTimestamp.IsZero() ||
+		accessRequest.Status.State != iamv1beta1.AccessRequestApproved {
+		return ctrl.Result{}, nil
+	}
+
+	if accessRequest.Status.NotBefore == nil || accessRequest.Status.ExpiresAt == nil {
+		r.recorder.Event(accessRequest, corev1.EventTypeWarning, kscontroller.SyncFailed, "the validity period of the approved request is missing")
+		return ctrl.Result{}, nil
+	}
+
+	now := time.Now()
+	if !now.Before(accessRequest.Status.ExpiresAt.Time) {
+		expired := accessRequest.DeepCopy()
+		expired.Status.State = iamv1beta1.AccessRequestExpired
+		if err := r.Update(ctx, expired); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.recorder.Event(accessRequest, corev1.EventTypeNormal, AccessExpired,
+			fmt.Sprintf("access of %s to %s %s expired", accessRequest.Spec.Username, accessRequest.Spec.RoleRef.Kind, accessRequest.Spec.RoleRef.Name))
+		return ctrl.Result{}, nil
+	}
+
+	binding, err := newRoleBinding(accessRequest)
+	if err != nil {
+		r.recorder.Event(accessRequest, corev1.EventTypeWarning, kscontroller.SyncFailed, err.Error())
+		return ctrl.Result{}, nil
+	}
+	if err = r.Create(ctx, binding); err != nil && !errors.IsAlreadyExists(err) {
+		log.Error(err, "failed to create role binding")
+		return ctrl.Result{}, err
+	}
+
+	if accessRequest.Status.BindingName != binding.GetName() {
+		granted := accessRequest.DeepCopy()
+		granted.Status.BindingName = binding.GetName()
+		if err = r.Update(ctx, granted); err != nil {
+			return ctrl.Result{}, err
+		}
+		r.recorder.Event(accessRequest, corev1.EventTypeNormal, AccessGranted,
+			fmt.Sprintf("%sBinding %s of %s to %s %s approved by %s is valid until %s", accessRequest.Spec.RoleRef.Kind,
+				binding.GetName(), accessRequest.Spec.Username, accessRequest.Spec.RoleRef.Kind, accessRequest.Spec.RoleRef.Name,
+				accessRequest.Status.Reviewer, accessRequest.Status.ExpiresAt.UTC().Format(time.RFC3339)))
+	}
+
+	return ctrl.Result{RequeueAfter: accessRequest.Status.ExpiresAt.Sub(now)}, nil
+}
+
+// newRoleBinding returns the role binding of the kind corresponding to the requested role,
+// which is named after the access request and in effect during the validity period approved.
+func newRoleBinding(accessRequest *iamv1beta1.AccessRequest) (client.Object, error) {
+	username := accessRequest.Spec.Username
+	roleName := accessRequest.Spec.RoleRef.Name
+	objectMeta := metav1.ObjectMeta{
+		Name: accessRequest.Name,
+		Labels: map[string]string{
+			iamv1beta1.UserReferenceLabel:          username,
+			iamv1beta1.RoleReferenceLabel:          roleName,
+			iamv1beta1.AccessRequestReferenceLabel: accessRequest.Name,
+		},
+	}
+	rbacutils.SetBindingValidity(&objectMeta, rbacutils.BindingValidity{
+		NotBefore: &accessRequest.Status.NotBefore.Time,
+		ExpiresAt: &accessRequest.Status.ExpiresAt.Time,
+	})
+	roleRef := rbacv1.RoleRef{
+		APIGroup: iamv1beta1.SchemeGroupVersion.Group,
+		Kind:     accessRequest.Spec.RoleRef.Kind,
+		Name:     roleName,
+	}
+	userSubject := rbacv1.Subject{
+		Kind:     iamv1beta1.ResourceKindUser,
+		APIGroup: iamv1beta1.SchemeGroupVersion.Group,
+		Name:     username,
+	}
+	kubeconfigSubject := rbacv1.Subject{
+		Kind:      rbacv1.ServiceAccountKind,
+		Name:      fmt.Sprintf(kubeconfig.UserKubeConfigServiceAccountNameFormat, username),
+		Namespace: constants.KubeSphereNamespace,
+	}
+
+	switch roleRef.Kind {
+	case iamv1beta1.ResourceKindGlobalRole:
+		return &iamv1beta1.GlobalRoleBinding{ObjectMeta: objectMeta, Subjects: []rbacv1.Subject{userSubject}, RoleRef: roleRef}, nil
+	case iamv1beta1.ResourceKindWorkspaceRole:
+		if accessRequest.Spec.Workspace == "" {
+			return nil, fmt.Errorf("workspace of the workspace role %s is required", roleName)
+		}
+		objectMeta.Labels[tenantv1beta1.WorkspaceLabel] = accessRequest.Spec.Workspace
+		return &iamv1beta1.WorkspaceRoleBinding{ObjectMeta: objectMeta, Subjects: []rbacv1.Subject{userSubject}, RoleRef: roleRef}, nil
+	case iamv1beta1.ResourceKindClusterRole:
+		return &iamv1beta1.ClusterRoleBinding{ObjectMeta: objectMeta, Subjects: []rbacv1.Subject{userSubject, kubeconfigSubject}, RoleRef: roleRef}, nil
+	case iamv1beta1.ResourceKindRole:
+		if accessRequest.Spec.Namespace == "" {
+			return nil, fmt.Errorf("namespace of the role %s is required", roleName)
+		}
+		objectMeta.Namespace = accessRequest.Spec.Namespace
+		return &iamv1beta1.RoleBinding{ObjectMeta: objectMeta, Subjects: []rbacv1.Subject{userSubject, kubeconfigSubject}, RoleRef: roleRef}, nil
+	default:
+		return nil, fmt.Errorf("unsupported role kind %s", roleRef.Kind)
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package accessrequest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"kubesphere.io/kubesphere/pkg/scheme"
+	rbacutils "kubesphere.io/kubesphere/pkg/utils/rbac"
+)
+
+func newAccessRequest(name string, state iamv1beta1.AccessRequestState, notBefore, expiresAt time.Time) *iamv1beta1.AccessRequest {
+	return &iamv1beta1.AccessRequest{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: iamv1beta1.AccessRequestSpec{
+			Username:      "alice",
+			RoleRef:       rbacv1.RoleRef{APIGroup: iamv1beta1.GroupName, Kind: iamv1beta1.ResourceKindWorkspaceRole, Name: "ws1-admin"},
+			Workspace:     "ws1",
+			Duration:      metav1.Duration{Duration: expiresAt.Sub(notBefore)},
+			Justification: "incident",
+		},
+		Status: iamv1beta1.AccessRequestStatus{
+			State:     state,
+			Reviewer:  "bob",
+			NotBefore: &metav1.Time{Time: notBefore},
+			ExpiresAt: &metav1.Time{Time: expiresAt},
+		},
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	approved := newAccessRequest("alice-approved", iamv1beta1.AccessRequestApproved, now, now.Add(time.Hour))
+	expired := newAccessRequest("alice-expired", iamv1beta1.AccessRequestApproved, now.Add(-2*time.Hour), now.Add(-time.Hour))
+	pending := newAccessRequest("alice-pending", iamv1beta1.AccessRequestPending, now, now.Add(time.Hour))
+	fakeClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(approved, expired, pending).Build()
+	reconciler := &Reconciler{Client: fakeClient, logger: log.Log, recorder: record.NewFakeRecorder(10)}
+	ctx := context.Background()
+
+	result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: approved.Name}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.RequeueAfter <= 0 || result.RequeueAfter > time.Hour {
+		t.Errorf("expected to requeue once expired, got %v", result.RequeueAfter)
+	}
+	binding := &iamv1beta1.WorkspaceRoleBinding{}
+	if err = fakeClient.Get(ctx, types.NamespacedName{Name: approved.Name}, binding); err != nil {
+		t.Fatal(err)
+	}
+	if binding.Labels[tenantv1beta1.WorkspaceLabel] != "ws1" || binding.Labels[iamv1beta1.UserReferenceLabel] != "alice" ||
+		binding.Labels[iamv1beta1.AccessRequestReferenceLabel] != approved.Name {
+		t.Errorf("unexpected labels %v", binding.Labels)
+	}
+	if len(binding.Subjects) != 1 || binding.Subjects[0].Name != "alice" || binding.RoleRef.Name != "ws1-admin" {
+		t.Errorf("unexpected binding %v %v", binding.Subjects, binding.RoleRef)
+	}
+	validity, err := rbacutils.GetBindingValidity(binding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !validity.NotBefore.Equal(now) || !validity.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("unexpected validity period %v - %v", validity.NotBefore, validity.ExpiresAt)
+	}
+	if err = fakeClient.Get(ctx, types.NamespacedName{Name: approved.Name}, approved); err != nil {
+		t.Fatal(err)
+	}
+	if approved.Status.BindingName != approved.Name {
+		t.Errorf("expected the binding name recorded, got %q", approved.Status.BindingName)
+	}
+
+	if _, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: expired.Name}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = fakeClient.Get(ctx, types.NamespacedName{Name: expired.Name}, expired); err != nil {
+		t.Fatal(err)
+	}
+	if expired.Status.State != iamv1beta1.AccessRequestExpired {
+		t.Errorf("expected the request expired, got %s", expired.Status.State)
+	}
+
+	if _, err = reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Name: pending.Name}}); err != nil {
+		t.Fatal(err)
+	}
+	bindings := &iamv1beta1.WorkspaceRoleBindingList{}
+	if err = fakeClient.List(ctx, bindings); err != nil {
+		t.Fatal(err)
+	}
+	if len(bindings.Items) != 1 {
+		t.Errorf("expected only the binding of the approved request, got %d bindings", len(bindings.Items))
+	}
+}
@@ -9,8 +9,10 @@ package clusterrolebinding
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/tools/record"
@@ -64,11 +66,37 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	now := time.Now()
+	validity, err := rbacutils.GetBindingValidity(clusterRole)
+	if err != nil {
+		r.recorder.Event(clusterRole, corev1.EventTypeWarning, kscontroller.SyncFailed, err.Error())
+	}
+	if validity.Expired(now) {
+		// the kubernetes cluster role binding is deleted along with the expired cluster role binding it's owned by
+		log.V(4).Info("delete expired cluster role binding")
+		return ctrl.Result{}, client.IgnoreNotFound(r.Delete(ctx, clusterRole))
+	}
+	if err != nil || !validity.Active(now) {
+		if err := r.removeFromKubernetes(ctx, clusterRole); err != nil {
+			log.Error(err, "remove inactive cluster role binding failed")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: validity.NextTransition(now)}, nil
+	}
+
 	if err := r.syncToKubernetes(ctx, clusterRole); err != nil {
 		log.Error(err, "sync cluster role binding failed")
 		return ctrl.Result{}, err
 	}
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: validity.NextTransition(now)}, nil
+}
+
+// removeFromKubernetes removes the kubernetes cluster role binding of the cluster role binding which isn't in effect yet.
+func (r *Reconciler) removeFromKubernetes(ctx context.Context, clusterRoleBinding *iamv1beta1.ClusterRoleBinding) error {
+	k8sClusterRolBinding := &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Name: rbacutils.RelatedK8sResourceName(clusterRoleBinding.Name)},
+	}
+	return client.IgnoreNotFound(r.Delete(ctx, k8sClusterRolBinding))
 }
 
 func (r *Reconciler) syncToKubernetes(ctx context.Context, clusterRoleBinding *iamv1beta1.ClusterRoleBinding) error {
@@ -10,6 +10,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
@@ -35,6 +36,7 @@ import (
 	clusterutils "kubesphere.io/kubesphere/pkg/controller/cluster/utils"
 	"kubesphere.io/kubesphere/pkg/models/kubeconfig"
 	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
+	rbacutils "kubesphere.io/kubesphere/pkg/utils/rbac"
 )
 
 const (
@@ -124,12 +126,28 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, nil
 	}
 
+	now := time.Now()
+	validity, err := rbacutils.GetBindingValidity(globalRoleBinding)
+	if err != nil {
+		r.recorder.Event(globalRoleBinding, corev1.EventTypeWarning, kscontroller.SyncFailed, err.Error())
+		return ctrl.Result{}, nil
+	}
+	if validity.Expired(now) {
+		// the copies in the member clusters are deleted by the finalizer
+		klog.FromContext(ctx).V(4).Info("delete expired global role binding", "name", globalRoleBinding.Name)
+		return ctrl.Result{}, client.IgnoreNotFound(r.Delete(ctx, globalRoleBinding))
+	}
+	// the global role binding is synced to the member clusters once it becomes active
+	if !validity.Active(now) {
+		return ctrl.Result{RequeueAfter: validity.NextTransition(now)}, nil
+	}
+
 	if err := r.multiClusterSync(ctx, globalRoleBinding); err != nil {
 		return ctrl.Result{}, err
 	}
 
 	r.recorder.Event(globalRoleBinding, corev1.EventTypeNormal, kscontroller.Synced, kscontroller.MessageResourceSynced)
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: validity.NextTransition(now)}, nil
 }
 
 func (r *Reconciler) deleteRelatedResources(ctx context.Context, globalRoleBinding *iamv1beta1.GlobalRoleBinding) error {
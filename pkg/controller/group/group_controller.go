@@ -8,12 +8,14 @@ package group
 
 import (
 	"context"
+	"strings"
 
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/validation"
 	"k8s.io/client-go/tools/record"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
 	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
@@ -29,13 +31,24 @@ const (
 	finalizer      = "finalizers.kubesphere.io/groups"
 )
 
+var _ controller.Controller = &Reconciler{}
+var _ controller.ClusterSelector = &Reconciler{}
+
 type Reconciler struct {
 	client.Client
 
 	recorder record.EventRecorder
 }
 
-func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+func (r *Reconciler) Name() string {
+	return controllerName
+}
+
+func (r *Reconciler) Enabled(clusterRole string) bool {
+	return strings.EqualFold(clusterRole, string(clusterv1alpha1.ClusterRoleHost))
+}
+
+func (r *Reconciler) SetupWithManager(mgr *controller.Manager) error {
 	r.recorder = mgr.GetEventRecorderFor(controllerName)
 	r.Client = mgr.GetClient()
 	return builder.
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package group
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/identityprovider"
+	"kubesphere.io/kubesphere/pkg/controller"
+	"kubesphere.io/kubesphere/pkg/models/iam/group"
+)
+
+const identityProviderGroupController = "identityprovider-group"
+
+var _ controller.Controller = &IdentityProviderGroupReconciler{}
+var _ controller.ClusterSelector = &IdentityProviderGroupReconciler{}
+
+// IdentityProviderGroupReconciler synchronizes the groups of the users mapped to the identity providers which
+// look up the groups without the user credentials, such as LDAP, so that the group bindings follow the directory
+// even if the users don't log in again.
+type IdentityProviderGroupReconciler struct {
+	client.Client
+	resyncPeriod time.Duration
+	// the last time the identity providers are synchronized, keyed by the name
+	lastSynced map[string]time.Time
+}
+
+func (r *IdentityProviderGroupReconciler) Name() string {
+	return identityProviderGroupController
+}
+
+func (r *IdentityProviderGroupReconciler) Enabled(clusterRole string) bool {
+	return strings.EqualFold(clusterRole, string(clusterv1alpha1.ClusterRoleHost))
+}
+
+func (r *IdentityProviderGroupReconciler) SetupWithManager(mgr *controller.Manager) error {
+	r.Client = mgr.GetClient()
+	r.resyncPeriod = time.Minute
+	r.lastSynced = make(map[string]time.Time)
+	return mgr.Add(r)
+}
+
+func (r *IdentityProviderGroupReconciler) Start(ctx context.Context) error {
+	go wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.reconcile(ctx, time.Now()); err != nil {
+			klog.Errorf("%s controller reconcile error: %s", identityProviderGroupController, err)
+		}
+	}, r.resyncPeriod)
+	return nil
+}
+
+func (r *IdentityProviderGroupReconciler) reconcile(ctx context.Context, now time.Time) error {
+	configurations, err := identityprovider.NewConfigurationGetter(r.Client).ListConfigurations(ctx)
+	if err != nil {
+		return err
+	}
+	for _, configuration := range configurations {
+		if configuration.Disabled || configuration.GroupMapping == nil || configuration.GroupMapping.SyncPeriod <= 0 {
+			continue
+		}
+		if now.Sub(r.lastSynced[configuration.Name]) < configuration.GroupMapping.SyncPeriod {
+			continue
+		}
+		if err = r.syncIdentityProvider(ctx, configuration); err != nil {
+			klog.Errorf("failed to sync groups of identity provider %s: %s", configuration.Name, err)
+			continue
+		}
+		r.lastSynced[configuration.Name] = now
+	}
+	return nil
+}
+
+func (r *IdentityProviderGroupReconciler) syncIdentityProvider(ctx context.Context, configuration *identityprovider.Configuration) error {
+	provider, err := identityprovider.NewGenericProvider(configuration)
+	if err != nil {
+		return err
+	}
+	lookup, ok := provider.(identityprovider.GroupsLookupProvider)
+	if !ok {
+		return fmt.Errorf("identity provider with type %s doesn't support looking up the groups", configuration.Type)
+	}
+
+	users := &iamv1beta1.UserList{}
+	if err = r.List(ctx, users); err != nil {
+		return err
+	}
+	annotation := fmt.Sprintf("%s.%s", iamv1beta1.IdentityProviderAnnotation, configuration.Name)
+	for _, user := range users.Items {
+		userID := user.Annotations[annotation]
+		if userID == "" || !user.DeletionTimestamp.IsZero() {
+			continue
+		}
+		externalGroups, err := lookup.LookupGroups(userID)
+		if err != nil {
+			// the user no longer exists in the directory, the groups are kept if the lookup failed for other reasons
+			if !errors.Is(err, identityprovider.ErrorUserNotFound) {
+				klog.Errorf("failed to look up groups of user %s: %s", user.Name, err)
+				continue
+			}
+			externalGroups = nil
+		}
+		groups := configuration.GroupMapping.Map(externalGroups)
+		if err = group.SyncIdentityProviderGroups(ctx, r.Client, configuration.Name, user.Name, groups); err != nil {
+			klog.Errorf("failed to sync groups of user %s: %s", user.Name, err)
+		}
+	}
+	return nil
+}
@@ -9,11 +9,13 @@ package rolebinding
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 
 	rbacutils "kubesphere.io/kubesphere/pkg/utils/rbac"
 
+	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -61,11 +63,37 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
+	now := time.Now()
+	validity, err := rbacutils.GetBindingValidity(roleBinding)
+	if err != nil {
+		r.recorder.Event(roleBinding, corev1.EventTypeWarning, kscontroller.SyncFailed, err.Error())
+	}
+	if validity.Expired(now) {
+		// the kubernetes role binding is deleted along with the expired role binding it's owned by
+		log.V(4).Info("delete expired role binding")
+		return ctrl.Result{}, client.IgnoreNotFound(r.Delete(ctx, roleBinding))
+	}
+	if err != nil || !validity.Active(now) {
+		if err := r.removeFromKubernetes(ctx, roleBinding); err != nil {
+			log.Error(err, "remove inactive role binding failed")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{RequeueAfter: validity.NextTransition(now)}, nil
+	}
+
 	if err := r.syncToKubernetes(ctx, roleBinding); err != nil {
 		log.Error(err, "sync role binding failed")
 		return ctrl.Result{}, err
 	}
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: validity.NextTransition(now)}, nil
+}
+
+// removeFromKubernetes removes the kubernetes role binding of the role binding which isn't in effect yet.
+func (r *Reconciler) removeFromKubernetes(ctx context.Context, roleBinding *iamv1beta1.RoleBinding) error {
+	k8sRolBinding := &rbacv1.RoleBinding{
+		ObjectMeta: metav1.ObjectMeta{Namespace: roleBinding.Namespace, Name: rbacutils.RelatedK8sResourceName(roleBinding.Name)},
+	}
+	return client.IgnoreNotFound(r.Delete(ctx, k8sRolBinding))
 }
 
 func (r *Reconciler) syncToKubernetes(ctx context.Context, roleBinding *iamv1beta1.RoleBinding) error {
@@ -0,0 +1,304 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package secret
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/klog/v2"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/oauth"
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/token"
+	"kubesphere.io/kubesphere/pkg/constants"
+	kscontroller "kubesphere.io/kubesphere/pkg/controller"
+	clusterutils "kubesphere.io/kubesphere/pkg/controller/cluster/utils"
+	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
+)
+
+const (
+	signingKeyController = "signing-key"
+	signingKeyNamePrefix = "signing-key-"
+)
+
+var _ kscontroller.Controller = &SigningKeyReconciler{}
+var _ kscontroller.ClusterSelector = &SigningKeyReconciler{}
+
+// SigningKeyReconciler rotates the keys signing the tokens. It generates a new key at the end of the
+// rotation period, retires the previous keys once the new key is activated, and deletes the retired keys
+// at the end of the grace period. A key is always generated if there is neither an active key nor a pending
+// one, so that the replicas of ks-apiserver sign the tokens with the same key. The keys are synchronized to
+// the member clusters to verify the tokens forwarded. A new key is activated one resync period after it's
+// created, so that it's published to the replicas and the member clusters before any token is signed by it,
+// except the key created when there is no active key, which is activated immediately, otherwise no token
+// could be signed in the meantime. The deadline of the tokens signed by the JWT secret before the upgrade is
+// recorded once on the first key, and carried over by the keys created afterwards.
+type SigningKeyReconciler struct {
+	client.Client
+	clusterClient     clusterclient.Interface
+	options           oauth.KeyringOptions
+	legacyTokenWindow time.Duration
+	resyncPeriod      time.Duration
+}
+
+func (r *SigningKeyReconciler) Name() string {
+	return signingKeyController
+}
+
+func (r *SigningKeyReconciler) Enabled(clusterRole string) bool {
+	return strings.EqualFold(clusterRole, string(clusterv1alpha1.ClusterRoleHost))
+}
+
+func (r *SigningKeyReconciler) SetupWithManager(mgr *kscontroller.Manager) error {
+	r.Client = mgr.GetClient()
+	r.clusterClient = mgr.ClusterClient
+	r.options = mgr.AuthenticationOptions.Issuer.Keyring
+	r.legacyTokenWindow = mgr.AuthenticationOptions.Issuer.LegacyTokenWindow()
+	r.resyncPeriod = time.Minute
+	return mgr.Add(r)
+}
+
+func (r *SigningKeyReconciler) Start(ctx context.Context) error {
+	go wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := r.reconcile(ctx, time.Now()); err != nil {
+			klog.Errorf("%s controller reconcile error: %s", signingKeyController, err)
+		}
+	}, r.resyncPeriod)
+	return nil
+}
+
+func (r *SigningKeyReconciler) reconcile(ctx context.Context, now time.Time) error {
+	secrets := &corev1.SecretList{}
+	if err := r.List(ctx, secrets, client.InNamespace(constants.KubeSphereNamespace)); err != nil {
+		return err
+	}
+	keys := make([]*corev1.Secret, 0)
+	for i := range secrets.Items {
+		if token.IsSigningKeySecret(&secrets.Items[i]) {
+			keys = append(keys, &secrets.Items[i])
+		}
+	}
+	// the newest key goes first, which is the active signing key if it's activated and not retired
+	sort.SliceStable(keys, func(i, j int) bool {
+		return activatedAt(keys[i]).After(activatedAt(keys[j]))
+	})
+
+	var active, pending *corev1.Secret
+	for _, secret := range keys {
+		if secret.Annotations[iamv1beta1.SigningKeyRetiredAtAnnotation] != "" {
+			continue
+		}
+		if activatedAt(secret).After(now) {
+			if pending == nil {
+				pending = secret
+			}
+			continue
+		}
+		active = secret
+		break
+	}
+
+	var legacyTokenDeadline string
+	if deadline := earliestLegacyTokenDeadline(keys); !deadline.IsZero() {
+		legacyTokenDeadline = deadline.UTC().Format(time.RFC3339)
+	} else {
+		legacyTokenDeadline = now.Add(r.legacyTokenWindow).UTC().Format(time.RFC3339)
+		// the keys created by the previous versions don't record the deadline
+		if active != nil {
+			if err := r.annotate(ctx, active, iamv1beta1.LegacyTokenDeadlineAnnotation, legacyTokenDeadline); err != nil {
+				return err
+			}
+		}
+	}
+
+	if pending == nil && (active == nil || (r.options.RotationPeriod > 0 && now.Sub(activatedAt(active)) >= r.options.RotationPeriod)) {
+		activation := now.Add(r.resyncPeriod)
+		if active == nil {
+			activation = now
+		}
+		created, err := r.createSigningKey(ctx, activation, legacyTokenDeadline)
+		if err != nil {
+			return err
+		}
+		klog.Infof("signing key %s is created, which is activated at %s", created.Name, activatedAt(created).Format(time.RFC3339))
+		pending = created
+	}
+
+	gracePeriod := r.options.KeyGracePeriod()
+	for _, secret := range keys {
+		if secret == active || secret == pending {
+			continue
+		}
+		retiredAt := secret.Annotations[iamv1beta1.SigningKeyRetiredAtAnnotation]
+		if retiredAt == "" {
+			if err := r.annotate(ctx, secret, iamv1beta1.SigningKeyRetiredAtAnnotation, now.UTC().Format(time.RFC3339)); err != nil {
+				return err
+			}
+			klog.Infof("signing key %s is retired", secret.Name)
+			continue
+		}
+		retiredTime, err := time.Parse(time.RFC3339, retiredAt)
+		if err != nil {
+			klog.Warningf("invalid annotation %s of signing key %s: %s", iamv1beta1.SigningKeyRetiredAtAnnotation, secret.Name, err)
+			continue
+		}
+		if now.After(retiredTime.Add(gracePeriod)) {
+			if err = r.Delete(ctx, secret); client.IgnoreNotFound(err) != nil {
+				return err
+			}
+			klog.Infof("signing key %s is deleted at the end of the grace period", secret.Name)
+		}
+	}
+	return r.syncMemberClusters(ctx)
+}
+
+// activatedAt returns the time the key starts signing tokens, the creation timestamp is
+// taken if the key isn't annotated.
+func activatedAt(secret *corev1.Secret) time.Time {
+	if value := secret.Annotations[iamv1beta1.SigningKeyActivatedAtAnnotation]; value != "" {
+		if activatedAt, err := time.Parse(time.RFC3339, value); err == nil {
+			return activatedAt
+		}
+		klog.Warningf("invalid annotation %s of signing key %s", iamv1beta1.SigningKeyActivatedAtAnnotation, secret.Name)
+	}
+	return secret.CreationTimestamp.Time
+}
+
+// earliestLegacyTokenDeadline returns the earliest deadline of the tokens signed by the JWT secret recorded by the keys,
+// zero if there is none.
+func earliestLegacyTokenDeadline(keys []*corev1.Secret) time.Time {
+	var earliest time.Time
+	for _, secret := range keys {
+		value := secret.Annotations[iamv1beta1.LegacyTokenDeadlineAnnotation]
+		if value == "" {
+			continue
+		}
+		deadline, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			klog.Warningf("invalid annotation %s of signing key %s", iamv1beta1.LegacyTokenDeadlineAnnotation, secret.Name)
+			continue
+		}
+		if earliest.IsZero() || deadline.Before(earliest) {
+			earliest = deadline
+		}
+	}
+	return earliest
+}
+
+// syncMemberClusters copies the keys to the member clusters, the keys deleted are deleted from the member clusters.
+// A member cluster failed doesn't stop the keys from being synchronized to the others.
+func (r *SigningKeyReconciler) syncMemberClusters(ctx context.Context) error {
+	secrets := &corev1.SecretList{}
+	if err := r.List(ctx, secrets, client.InNamespace(constants.KubeSphereNamespace)); err != nil {
+		return err
+	}
+	keys := make(map[string]*corev1.Secret)
+	for i := range secrets.Items {
+		if token.IsSigningKeySecret(&secrets.Items[i]) {
+			keys[secrets.Items[i].Name] = &secrets.Items[i]
+		}
+	}
+
+	clusters, err := r.clusterClient.ListClusters(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list clusters: %s", err)
+	}
+	var notReadyClusters []string
+	var errs []error
+	for _, cluster := range clusters {
+		if clusterutils.IsHostCluster(&cluster) {
+			continue
+		}
+		if !clusterutils.IsClusterReady(&cluster) {
+			notReadyClusters = append(notReadyClusters, cluster.Name)
+			continue
+		}
+		if err = r.syncMemberCluster(ctx, cluster.Name, keys); err != nil {
+			errs = append(errs, fmt.Errorf("failed to sync signing keys to cluster %s: %s", cluster.Name, err))
+		}
+	}
+	if len(notReadyClusters) > 0 {
+		klog.V(4).Infof("signing keys are not synced to the clusters not ready: %s", strings.Join(notReadyClusters, ","))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (r *SigningKeyReconciler) syncMemberCluster(ctx context.Context, cluster string, keys map[string]*corev1.Secret) error {
+	clusterClient, err := r.clusterClient.GetRuntimeClient(cluster)
+	if err != nil {
+		return fmt.Errorf("failed to get cluster client: %s", err)
+	}
+	secrets := &corev1.SecretList{}
+	if err = clusterClient.List(ctx, secrets, client.InNamespace(constants.KubeSphereNamespace)); err != nil {
+		return err
+	}
+	for i := range secrets.Items {
+		secret := &secrets.Items[i]
+		if _, ok := keys[secret.Name]; ok || !token.IsSigningKeySecret(secret) {
+			continue
+		}
+		if err = clusterClient.Delete(ctx, secret); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	for _, key := range keys {
+		target := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+		if _, err = controllerutil.CreateOrUpdate(ctx, clusterClient, target, func() error {
+			target.Annotations = key.Annotations
+			target.Type = key.Type
+			target.Data = key.Data
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createSigningKey creates a key which only verifies tokens until the activation time.
+func (r *SigningKeyReconciler) createSigningKey(ctx context.Context, activatedAt time.Time, legacyTokenDeadline string) (*corev1.Secret, error) {
+	data, err := token.GenerateSigningKeyData(r.options.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: signingKeyNamePrefix,
+			Namespace:    constants.KubeSphereNamespace,
+			Annotations: map[string]string{
+				iamv1beta1.SigningKeyActivatedAtAnnotation: activatedAt.UTC().Format(time.RFC3339),
+				iamv1beta1.LegacyTokenDeadlineAnnotation:   legacyTokenDeadline,
+			},
+		},
+		Type: token.SecretTypeSigningKey,
+		Data: map[string][]byte{token.SecretDataKeySigningKey: data},
+	}
+	if err = r.Create(ctx, secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+func (r *SigningKeyReconciler) annotate(ctx context.Context, secret *corev1.Secret, key, value string) error {
+	secret = secret.DeepCopy()
+	if secret.Annotations == nil {
+		secret.Annotations = make(map[string]string)
+	}
+	secret.Annotations[key] = value
+	return r.Update(ctx, secret)
+}
@@ -0,0 +1,141 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package secret
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	runtimefakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/oauth"
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/token"
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/scheme"
+	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
+)
+
+type fakeClusterClient struct {
+	clusterclient.Interface
+	clusters []clusterv1alpha1.Cluster
+	clients  map[string]client.Client
+}
+
+func (f *fakeClusterClient) ListClusters(context.Context) ([]clusterv1alpha1.Cluster, error) {
+	return f.clusters, nil
+}
+
+func (f *fakeClusterClient) GetRuntimeClient(name string) (client.Client, error) {
+	if c, ok := f.clients[name]; ok {
+		return c, nil
+	}
+	return nil, errors.New("cluster unreachable")
+}
+
+func newCluster(name string, host bool) clusterv1alpha1.Cluster {
+	cluster := clusterv1alpha1.Cluster{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Status: clusterv1alpha1.ClusterStatus{Conditions: []clusterv1alpha1.ClusterCondition{
+			{Type: clusterv1alpha1.ClusterReady, Status: corev1.ConditionTrue},
+		}},
+	}
+	if host {
+		cluster.Labels = map[string]string{clusterv1alpha1.HostCluster: ""}
+	}
+	return cluster
+}
+
+func listSigningKeys(t *testing.T, c client.Client) []corev1.Secret {
+	secrets := &corev1.SecretList{}
+	if err := c.List(context.Background(), secrets, client.InNamespace(constants.KubeSphereNamespace)); err != nil {
+		t.Fatal(err)
+	}
+	keys := make([]corev1.Secret, 0)
+	for _, secret := range secrets.Items {
+		if token.IsSigningKeySecret(&secret) {
+			keys = append(keys, secret)
+		}
+	}
+	return keys
+}
+
+func TestSigningKeyRotation(t *testing.T) {
+	hostClient := runtimefakeclient.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	memberClient := runtimefakeclient.NewClientBuilder().WithScheme(scheme.Scheme).Build()
+	r := &SigningKeyReconciler{
+		Client: hostClient,
+		clusterClient: &fakeClusterClient{
+			clusters: []clusterv1alpha1.Cluster{newCluster("host", true), newCluster("broken", false), newCluster("member", false)},
+			clients:  map[string]client.Client{"member": memberClient},
+		},
+		options:           oauth.KeyringOptions{RotationPeriod: time.Hour},
+		legacyTokenWindow: 24 * time.Hour,
+		resyncPeriod:      time.Minute,
+	}
+	ctx := context.Background()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	// the first key is activated immediately and published to the member clusters, even if one of them fails
+	if err := r.reconcile(ctx, now); err == nil {
+		t.Error("expected the error of the broken cluster")
+	}
+	keys := listSigningKeys(t, hostClient)
+	if len(keys) != 1 {
+		t.Fatalf("expected 1 signing key, got %d", len(keys))
+	}
+	first := keys[0].Name
+	if activatedAt := keys[0].Annotations[iamv1beta1.SigningKeyActivatedAtAnnotation]; activatedAt != now.Format(time.RFC3339) {
+		t.Errorf("expected the first key to be activated immediately, got %s", activatedAt)
+	}
+	legacyTokenDeadline := now.Add(24 * time.Hour).Format(time.RFC3339)
+	if deadline := keys[0].Annotations[iamv1beta1.LegacyTokenDeadlineAnnotation]; deadline != legacyTokenDeadline {
+		t.Errorf("expected the legacy token deadline to be recorded on the first key, got %s", deadline)
+	}
+	if members := listSigningKeys(t, memberClient); len(members) != 1 || members[0].Name != first {
+		t.Errorf("expected the key %s to be synced to the member cluster, got %v", first, members)
+	}
+
+	// the active key is retired only once the new key is activated
+	rotatedAt := now.Add(time.Hour)
+	_ = r.reconcile(ctx, rotatedAt)
+	keys = listSigningKeys(t, hostClient)
+	if len(keys) != 2 {
+		t.Fatalf("expected 2 signing keys, got %d", len(keys))
+	}
+	for _, key := range keys {
+		if key.Name != first && key.Annotations[iamv1beta1.SigningKeyActivatedAtAnnotation] != rotatedAt.Add(time.Minute).Format(time.RFC3339) {
+			t.Errorf("expected the new key to be activated after a resync period")
+		}
+		if deadline := key.Annotations[iamv1beta1.LegacyTokenDeadlineAnnotation]; deadline != legacyTokenDeadline {
+			t.Errorf("expected the legacy token deadline to be carried over to the key %s, got %s", key.Name, deadline)
+		}
+	}
+
+	// no key is created while the pending one is not activated
+	_ = r.reconcile(ctx, rotatedAt.Add(30*time.Second))
+	if keys = listSigningKeys(t, hostClient); len(keys) != 2 {
+		t.Fatalf("expected 2 signing keys, got %d", len(keys))
+	}
+	for _, key := range keys {
+		if key.Annotations[iamv1beta1.SigningKeyRetiredAtAnnotation] != "" {
+			t.Errorf("expected the key %s not to be retired before the new key is activated", key.Name)
+		}
+	}
+	_ = r.reconcile(ctx, rotatedAt.Add(time.Minute))
+	for _, key := range listSigningKeys(t, hostClient) {
+		if retired := key.Annotations[iamv1beta1.SigningKeyRetiredAtAnnotation] != ""; retired != (key.Name == first) {
+			t.Errorf("expected only the key %s to be retired, got %s retired: %v", first, key.Name, retired)
+		}
+	}
+}
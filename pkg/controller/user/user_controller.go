@@ -17,6 +17,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 
 	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/models/auth"
 	"kubesphere.io/kubesphere/pkg/models/kubeconfig"
 	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
 
@@ -62,6 +63,7 @@ type Reconciler struct {
 	logger                logr.Logger
 	recorder              record.EventRecorder
 	clusterClient         clusterclient.Interface
+	passwordPolicy        auth.PasswordPolicy
 }
 
 func (r *Reconciler) Enabled(clusterRole string) bool {
@@ -78,6 +80,7 @@ func (r *Reconciler) SetupWithManager(mgr *kscontroller.Manager) error {
 	r.logger = mgr.GetLogger().WithName(controllerName)
 	r.recorder = mgr.GetEventRecorderFor(controllerName)
 	r.clusterClient = mgr.ClusterClient
+	r.passwordPolicy = auth.NewPasswordPolicy(r.Client, &r.authenticationOptions.PasswordPolicy)
 	return ctrl.NewControllerManagedBy(mgr).
 		Named(controllerName).
 		WithOptions(controller.Options{MaxConcurrentReconciles: 2}).
@@ -167,6 +170,10 @@ func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reco
 	if err := r.reconcileUserStatus(ctx, user); err != nil {
 		return ctrl.Result{}, err
 	}
+	passwordExpiresIn, err := r.reconcilePasswordReset(ctx, user)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 	if err := r.multiClusterSync(ctx, user); err != nil {
 		return ctrl.Result{}, err
 	}
@@ -176,6 +183,10 @@ func (r *Reconciler) Reconcile(ctx context.Context, req reconcile.Request) (reco
 	if user.Status.State == iamv1beta1.UserAuthLimitExceeded {
 		return ctrl.Result{Requeue: true, RequeueAfter: r.authenticationOptions.AuthenticateRateLimiterDuration}, nil
 	}
+	// put it back to the queue to require the password reset once the password expires
+	if passwordExpiresIn > 0 {
+		return ctrl.Result{RequeueAfter: passwordExpiresIn}, nil
+	}
 
 	return ctrl.Result{}, nil
 }
@@ -198,10 +209,54 @@ func (r *Reconciler) encryptPassword(ctx context.Context, user *iamv1beta1.User)
 		if err = r.Update(ctx, user, &client.UpdateOptions{}); err != nil {
 			return err
 		}
+		if err = r.passwordPolicy.RecordPassword(ctx, user); err != nil {
+			return fmt.Errorf("failed to record password history: %s", err)
+		}
 	}
 	return nil
 }
 
+// reconcilePasswordReset requires the user to reset the password if an administrator requires so
+// or the password is expired, and returns the duration until the password expires.
+func (r *Reconciler) reconcilePasswordReset(ctx context.Context, user *iamv1beta1.User) (time.Duration, error) {
+	// only the active users with a local password are concerned
+	if user.Status.State != iamv1beta1.UserActive && user.Status.State != iamv1beta1.UserPasswordResetRequired ||
+		user.Spec.EncryptedPassword == "" || !isEncrypted(user.Spec.EncryptedPassword) {
+		return 0, nil
+	}
+
+	var reason string
+	var expiresIn time.Duration
+	if user.Annotations[iamv1beta1.PasswordResetRequiredAnnotation] == "true" {
+		reason = "Password reset is required by the administrator"
+	} else if maxAge := r.authenticationOptions.PasswordPolicy.MaxAge; maxAge > 0 {
+		lastPasswordChangeTime := user.CreationTimestamp.Time
+		if value := user.Annotations[iamv1beta1.LastPasswordChangeTimeAnnotation]; value != "" {
+			if t, err := time.Parse(time.RFC3339, value); err == nil {
+				lastPasswordChangeTime = t
+			}
+		}
+		if expiresIn = time.Until(lastPasswordChangeTime.Add(maxAge)); expiresIn <= 0 {
+			reason = fmt.Sprintf("Password expired, it has not been changed in %s", maxAge)
+			expiresIn = 0
+		}
+	}
+
+	state := iamv1beta1.UserActive
+	if reason != "" {
+		state = iamv1beta1.UserPasswordResetRequired
+	}
+	if user.Status.State == state {
+		return expiresIn, nil
+	}
+	user.Status = iamv1beta1.UserStatus{
+		State:              state,
+		Reason:             reason,
+		LastTransitionTime: &metav1.Time{Time: time.Now()},
+	}
+	return expiresIn, r.Update(ctx, user, &client.UpdateOptions{})
+}
+
 func (r *Reconciler) deleteRelatedResources(ctx context.Context, user *iamv1beta1.User) error {
 	if err := r.DeleteAllOf(ctx, &iamv1beta1.LoginRecord{}, client.MatchingLabels{iamv1beta1.UserReferenceLabel: user.Name}); err != nil {
 		return err
@@ -26,6 +26,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication"
+	"kubesphere.io/kubesphere/pkg/models/auth"
 	"kubesphere.io/kubesphere/pkg/scheme"
 	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
 )
@@ -78,6 +79,7 @@ func TestDoNothing(t *testing.T) {
 		Client:                client,
 		authenticationOptions: authenticateOptions,
 		clusterClient:         clusterClientSet,
+		passwordPolicy:        auth.NewPasswordPolicy(client, &authenticateOptions.PasswordPolicy),
 	}
 
 	users := &iamv1beta1.UserList{}
@@ -139,3 +141,94 @@ func TestDoNothing(t *testing.T) {
 	user = updateEvent.Object.(*iamv1beta1.User)
 	assert.Equal(t, iamv1beta1.UserActive, user.Status.State)
 }
+
+func TestPasswordResetRequiredOnCreation(t *testing.T) {
+	authenticateOptions := authentication.NewOptions()
+	user := newUser("test")
+	user.Annotations = map[string]string{iamv1beta1.PasswordResetRequiredAnnotation: "true"}
+
+	client := runtimefakeclient.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(user).Build()
+	clusterClientSet, err := clusterclient.NewClusterClientSet(&informertest.FakeInformers{Scheme: scheme.Scheme})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Reconciler{
+		recorder:              &record.FakeRecorder{},
+		logger:                ctrl.Log.WithName("controllers").WithName(controllerName),
+		Client:                client,
+		authenticationOptions: authenticateOptions,
+		clusterClient:         clusterClientSet,
+		passwordPolicy:        auth.NewPasswordPolicy(client, &authenticateOptions.PasswordPolicy),
+	}
+
+	// the first reconciliation appends the finalizer, the second one encrypts the password
+	for i := 0; i < 2; i++ {
+		if _, err = c.Reconcile(context.Background(), reconcile.Request{
+			NamespacedName: types.NamespacedName{Name: user.Name},
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = client.Get(context.Background(), types.NamespacedName{Name: user.Name}, user); err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, isEncrypted(user.Spec.EncryptedPassword))
+	assert.Equal(t, "true", user.Annotations[iamv1beta1.PasswordResetRequiredAnnotation])
+	assert.Equal(t, iamv1beta1.UserPasswordResetRequired, user.Status.State)
+}
+
+func TestReconcilePasswordReset(t *testing.T) {
+	authenticateOptions := authentication.NewOptions()
+	authenticateOptions.PasswordPolicy.MaxAge = time.Hour
+	encryptedPassword, err := encrypt("P@88w0rd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := newUser("test")
+	user.Spec.EncryptedPassword = encryptedPassword
+	user.Status.State = iamv1beta1.UserActive
+	user.Annotations = map[string]string{
+		iamv1beta1.LastPasswordChangeTimeAnnotation: time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339),
+	}
+
+	client := runtimefakeclient.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(user).Build()
+	c := &Reconciler{
+		Client:                client,
+		authenticationOptions: authenticateOptions,
+	}
+	if err = client.Get(context.Background(), types.NamespacedName{Name: user.Name}, user); err != nil {
+		t.Fatal(err)
+	}
+
+	// the password is expired
+	expiresIn, err := c.reconcilePasswordReset(context.Background(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, iamv1beta1.UserPasswordResetRequired, user.Status.State)
+	assert.Zero(t, expiresIn)
+
+	// the password has been changed
+	user.Annotations[iamv1beta1.LastPasswordChangeTimeAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	expiresIn, err = c.reconcilePasswordReset(context.Background(), user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, iamv1beta1.UserActive, user.Status.State)
+	assert.True(t, expiresIn > 59*time.Minute && expiresIn <= time.Hour)
+
+	// required by the administrator
+	user.Annotations[iamv1beta1.PasswordResetRequiredAnnotation] = "true"
+	if _, err = c.reconcilePasswordReset(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, iamv1beta1.UserPasswordResetRequired, user.Status.State)
+
+	// the disabled users are skipped
+	user.Status.State = iamv1beta1.UserDisabled
+	if _, err = c.reconcilePasswordReset(context.Background(), user); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, iamv1beta1.UserDisabled, user.Status.State)
+}
@@ -18,6 +18,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	kscontroller "kubesphere.io/kubesphere/pkg/controller"
+	"kubesphere.io/kubesphere/pkg/models/auth"
 )
 
 const webhookName = "user-webhook"
@@ -31,10 +32,12 @@ var _ admission.CustomValidator = &Webhook{}
 
 type Webhook struct {
 	client.Client
+	passwordPolicy auth.PasswordPolicy
 }
 
 func (v *Webhook) SetupWithManager(mgr *kscontroller.Manager) error {
 	v.Client = mgr.GetClient()
+	v.passwordPolicy = auth.NewPasswordPolicy(v.Client, &mgr.AuthenticationOptions.PasswordPolicy)
 	return builder.WebhookManagedBy(mgr).
 		For(&iamv1beta1.User{}).
 		WithValidator(v).
@@ -69,12 +72,33 @@ func (v *Webhook) validate(ctx context.Context, obj runtime.Object) (admission.W
 	return nil, nil
 }
 
+// validatePassword checks the plain text password against the password policy,
+// the password is encrypted by the user controller once admitted.
+func (v *Webhook) validatePassword(ctx context.Context, user *iamv1beta1.User) error {
+	if user.Spec.EncryptedPassword == "" || isEncrypted(user.Spec.EncryptedPassword) {
+		return nil
+	}
+	return v.passwordPolicy.Validate(ctx, user.Name, user.Spec.EncryptedPassword)
+}
+
 func (v *Webhook) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
-	return v.validate(ctx, obj)
+	warnings, err := v.validate(ctx, obj)
+	if err != nil {
+		return warnings, err
+	}
+	return warnings, v.validatePassword(ctx, obj.(*iamv1beta1.User))
 }
 
-func (v *Webhook) ValidateUpdate(ctx context.Context, _, newObj runtime.Object) (admission.Warnings, error) {
-	return v.validate(ctx, newObj)
+func (v *Webhook) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	warnings, err := v.validate(ctx, newObj)
+	if err != nil {
+		return warnings, err
+	}
+	oldUser, newUser := oldObj.(*iamv1beta1.User), newObj.(*iamv1beta1.User)
+	if oldUser.Spec.EncryptedPassword == newUser.Spec.EncryptedPassword {
+		return warnings, nil
+	}
+	return warnings, v.validatePassword(ctx, newUser)
 }
 
 func (v *Webhook) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
@@ -12,6 +12,7 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/go-logr/logr"
 	corev1 "k8s.io/api/core/v1"
@@ -44,6 +45,7 @@ import (
 	"kubesphere.io/kubesphere/pkg/controller/workspacetemplate/utils"
 	"kubesphere.io/kubesphere/pkg/utils/clusterclient"
 	"kubesphere.io/kubesphere/pkg/utils/k8sutil"
+	rbacutils "kubesphere.io/kubesphere/pkg/utils/rbac"
 )
 
 const (
@@ -199,6 +201,22 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 		return ctrl.Result{}, nil
 	}
 
+	now := time.Now()
+	validity, err := rbacutils.GetBindingValidity(workspaceRoleBinding)
+	if err != nil {
+		r.recorder.Event(workspaceRoleBinding, corev1.EventTypeWarning, kscontroller.SyncFailed, err.Error())
+		return ctrl.Result{}, nil
+	}
+	if validity.Expired(now) {
+		// the copies in the member clusters are deleted by the finalizer
+		klog.FromContext(ctx).V(4).Info("delete expired workspace role binding", "name", workspaceRoleBinding.Name)
+		return ctrl.Result{}, client.IgnoreNotFound(r.Delete(ctx, workspaceRoleBinding))
+	}
+	// the workspace role binding is synced to the member clusters once it becomes active
+	if !validity.Active(now) {
+		return ctrl.Result{RequeueAfter: validity.NextTransition(now)}, nil
+	}
+
 	if err := r.bindWorkspace(ctx, workspaceRoleBinding); err != nil {
 		return ctrl.Result{}, err
 	}
@@ -208,7 +226,7 @@ func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Resu
 	}
 
 	r.recorder.Event(workspaceRoleBinding, corev1.EventTypeNormal, kscontroller.Synced, kscontroller.MessageResourceSynced)
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: validity.NextTransition(now)}, nil
 }
 
 func (r *Reconciler) deleteRelatedResources(ctx context.Context, workspaceRoleBinding *iamv1beta1.WorkspaceRoleBinding) error {
@@ -0,0 +1,112 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package v1alpha1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+
+	"kubesphere.io/kubesphere/pkg/api"
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing/store"
+	"kubesphere.io/kubesphere/pkg/apiserver/query"
+)
+
+const (
+	ParameterUser         = "user"
+	ParameterVerb         = "verb"
+	ParameterResource     = "resource"
+	ParameterWorkspace    = "workspace"
+	ParameterCluster      = "cluster"
+	ParameterResponseCode = "code"
+	ParameterStartTime    = "start_time"
+	ParameterEndTime      = "end_time"
+)
+
+type handler struct {
+	store store.Interface
+}
+
+// listEvents serves both the global and the workspace routes. The authorization filter
+// has checked the caller's permission in the scope of the route, so the workspace route
+// is restricted to the events of its workspace regardless of the query parameters.
+func (h *handler) listEvents(req *restful.Request, resp *restful.Response) {
+	q, err := parseQuery(req)
+	if err != nil {
+		api.HandleBadRequest(resp, req, err)
+		return
+	}
+
+	if workspace := req.PathParameter("workspace"); workspace != "" {
+		q.Workspaces = []string{workspace}
+	}
+
+	result, err := h.store.Query(q)
+	if err != nil {
+		api.HandleInternalError(resp, req, err)
+		return
+	}
+	_ = resp.WriteEntity(result)
+}
+
+func parseQuery(req *restful.Request) (*store.Query, error) {
+	pagination := query.ParseQueryParameter(req).Pagination
+	q := &store.Query{
+		Users:      splitParameter(req, ParameterUser),
+		Verbs:      splitParameter(req, ParameterVerb),
+		Resources:  splitParameter(req, ParameterResource),
+		Workspaces: splitParameter(req, ParameterWorkspace),
+		Clusters:   splitParameter(req, ParameterCluster),
+		Offset:     pagination.Offset,
+		Limit:      pagination.Limit,
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+
+	for _, code := range splitParameter(req, ParameterResponseCode) {
+		c, err := strconv.ParseInt(code, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid response code %q", code)
+		}
+		q.ResponseCodes = append(q.ResponseCodes, int32(c))
+	}
+
+	var err error
+	if q.StartTime, err = parseTime(req, ParameterStartTime); err != nil {
+		return nil, err
+	}
+	if q.EndTime, err = parseTime(req, ParameterEndTime); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func splitParameter(req *restful.Request, name string) []string {
+	var values []string
+	for _, value := range strings.Split(req.QueryParameter(name), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func parseTime(req *restful.Request, name string) (time.Time, error) {
+	value := req.QueryParameter(name)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q, unix seconds expected", name, value)
+	}
+	return time.Unix(seconds, 0), nil
+}
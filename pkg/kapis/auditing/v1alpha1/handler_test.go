@@ -0,0 +1,75 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing/store"
+)
+
+func TestListEvents(t *testing.T) {
+	eventStore, err := store.Open(&store.Options{Path: t.TempDir()})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Now().Unix()
+	var events [][]byte
+	for i, workspace := range []string{"ws1", "ws2", "ws1"} {
+		events = append(events, []byte(fmt.Sprintf(
+			`{"AuditID":"%d","Verb":"create","Workspace":%q,"User":{"username":"admin"},"ResponseStatus":{"code":%d},"StageTimestamp":%q}`,
+			i, workspace, 200+i, time.Unix(now-int64(10-i), 0).UTC().Format(time.RFC3339))))
+	}
+	if err = eventStore.ProcessEvents(events...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	container := restful.NewContainer()
+	if err = NewHandler(eventStore).AddToContainer(container); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		url        string
+		statusCode int
+		total      int
+	}{
+		{url: "/kapis/auditing.kubesphere.io/v1alpha1/events", statusCode: http.StatusOK, total: 3},
+		{url: "/kapis/auditing.kubesphere.io/v1alpha1/events?workspace=ws2,ws1&code=200,201", statusCode: http.StatusOK, total: 2},
+		{url: fmt.Sprintf("/kapis/auditing.kubesphere.io/v1alpha1/events?start_time=%d&end_time=%d", now-9, now), statusCode: http.StatusOK, total: 2},
+		// the workspace route ignores the workspace query parameter
+		{url: "/kapis/auditing.kubesphere.io/v1alpha1/workspaces/ws1/events?workspace=ws2", statusCode: http.StatusOK, total: 2},
+		{url: "/kapis/auditing.kubesphere.io/v1alpha1/events?code=ok", statusCode: http.StatusBadRequest},
+		{url: "/kapis/auditing.kubesphere.io/v1alpha1/events?start_time=yesterday", statusCode: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			container.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if recorder.Code != tt.statusCode {
+				t.Fatalf("status code = %d, want %d: %s", recorder.Code, tt.statusCode, recorder.Body.String())
+			}
+			if tt.statusCode != http.StatusOK {
+				return
+			}
+			result := &store.Result{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), result); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.TotalItems != tt.total || len(result.Items) != tt.total {
+				t.Errorf("got %d of %d items, want %d", len(result.Items), result.TotalItems, tt.total)
+			}
+		})
+	}
+}
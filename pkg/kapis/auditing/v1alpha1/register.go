@@ -0,0 +1,81 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package v1alpha1
+
+import (
+	"net/http"
+
+	restfulspec "github.com/emicklei/go-restful-openapi/v2"
+	"github.com/emicklei/go-restful/v3"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"kubesphere.io/kubesphere/pkg/api"
+	"kubesphere.io/kubesphere/pkg/apiserver/auditing/store"
+	"kubesphere.io/kubesphere/pkg/apiserver/query"
+	"kubesphere.io/kubesphere/pkg/apiserver/rest"
+	"kubesphere.io/kubesphere/pkg/apiserver/runtime"
+)
+
+const (
+	GroupName = "auditing.kubesphere.io"
+	Version   = "v1alpha1"
+
+	ResourcesPluralEvent = "events"
+)
+
+var GroupVersion = schema.GroupVersion{Group: GroupName, Version: Version}
+
+func Resource(resource string) schema.GroupResource {
+	return GroupVersion.WithResource(resource).GroupResource()
+}
+
+func NewHandler(eventStore store.Interface) rest.Handler {
+	return &handler{store: eventStore}
+}
+
+func (h *handler) AddToContainer(c *restful.Container) error {
+	ws := runtime.NewWebService(GroupVersion)
+
+	ws.Route(withQueryParameters(ws, ws.GET("/events")).
+		To(h.listEvents).
+		Doc("List auditing events").
+		Notes("List the auditing events of all workspaces and clusters, the newest first.").
+		Operation("list-auditing-events").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagAuditing}).
+		Param(ws.QueryParameter(ParameterWorkspace, "workspaces of events, separated by commas").Required(false)).
+		Returns(http.StatusOK, api.StatusOK, store.Result{}))
+
+	ws.Route(withQueryParameters(ws, ws.GET("/workspaces/{workspace}/events")).
+		To(h.listEvents).
+		Doc("List auditing events of a workspace").
+		Notes("List the auditing events of the workspace, the newest first.").
+		Operation("list-workspace-auditing-events").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagAuditing}).
+		Param(ws.PathParameter("workspace", "workspace name")).
+		Returns(http.StatusOK, api.StatusOK, store.Result{}))
+
+	c.Add(ws)
+	return nil
+}
+
+func withQueryParameters(ws *restful.WebService, builder *restful.RouteBuilder) *restful.RouteBuilder {
+	parameters := []*restful.Parameter{
+		ws.QueryParameter(ParameterUser, "usernames of events, separated by commas").Required(false),
+		ws.QueryParameter(ParameterVerb, "verbs of events, separated by commas, e.g. create,delete").Required(false),
+		ws.QueryParameter(ParameterResource, "resources of events, separated by commas, e.g. users,deployments").Required(false),
+		ws.QueryParameter(ParameterCluster, "clusters of events, separated by commas").Required(false),
+		ws.QueryParameter(ParameterResponseCode, "response codes of events, separated by commas, e.g. 403,500").Required(false),
+		ws.QueryParameter(ParameterStartTime, "start of the time range in unix seconds, inclusive").Required(false),
+		ws.QueryParameter(ParameterEndTime, "end of the time range in unix seconds, inclusive").Required(false),
+		ws.QueryParameter(query.ParameterPage, "page").Required(false).DataFormat("page=%d").DefaultValue("page=1"),
+		ws.QueryParameter(query.ParameterLimit, "limit").Required(false),
+	}
+	for _, parameter := range parameters {
+		builder.Param(parameter)
+	}
+	return builder
+}
@@ -8,7 +8,10 @@ package v1beta1
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	"kubesphere.io/kubesphere/pkg/apiserver/authorization/rbac"
 	"kubesphere.io/kubesphere/pkg/apiserver/rest"
@@ -20,7 +23,9 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/sets"
 	authuser "k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/klog/v2"
 	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
 
 	"kubesphere.io/kubesphere/pkg/api"
@@ -50,22 +55,47 @@ type PasswordReset struct {
 }
 
 type TOTOAuthKeyBind struct {
-	AuthKey string `json:"authKey"`
-	OTP     string `json:"otp"`
+	OTP        string `json:"otp" description:"one-time password generated by the generated auth key"`
+	CurrentOTP string `json:"currentOTP,omitempty" description:"one-time password or recovery code of the bound auth key, required if TOTP is enabled"`
 }
 
 type TOTPAuthKey struct {
 	AuthKey string `json:"authKey"`
 }
 
+type TOTPVerify struct {
+	OTP string `json:"otp" description:"one-time password or recovery code"`
+}
+
+type TOTPRecoveryCodes struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+type AccessRequestReview struct {
+	Comment string `json:"comment,omitempty" description:"comment of the reviewer"`
+}
+
+type SessionList struct {
+	Items      []*auth.Session `json:"items"`
+	TotalItems int             `json:"totalItems"`
+}
+
+// maxAccessRequestDuration is the longest duration of the access requested.
+const maxAccessRequestDuration = 7 * 24 * time.Hour
+
 type handler struct {
-	im         im.IdentityManagementInterface
-	am         am.AccessManagementInterface
-	authorizer authorizer.Authorizer
+	im            im.IdentityManagementInterface
+	am            am.AccessManagementInterface
+	totpOperator  auth.TOTPOperator
+	tokenOperator auth.TokenManagementInterface
+	loginRecorder auth.LoginRecorder
+	authorizer    *rbac.Authorizer
 }
 
-func NewHandler(im im.IdentityManagementInterface, am am.AccessManagementInterface) rest.Handler {
-	return &handler{im: im, am: am, authorizer: rbac.NewRBACAuthorizer(am)}
+func NewHandler(im im.IdentityManagementInterface, am am.AccessManagementInterface, totpOperator auth.TOTPOperator,
+	tokenOperator auth.TokenManagementInterface, loginRecorder auth.LoginRecorder) rest.Handler {
+	return &handler{im: im, am: am, totpOperator: totpOperator, tokenOperator: tokenOperator,
+		loginRecorder: loginRecorder, authorizer: rbac.NewRBACAuthorizer(am)}
 }
 
 func NewFakeHandler() rest.Handler {
@@ -250,6 +280,10 @@ func (h *handler) ModifyPassword(request *restful.Request, response *restful.Res
 		api.HandleBadRequest(response, request, err)
 		return
 	}
+	if auth.IsEncryptedPassword(passwordReset.Password) {
+		api.HandleBadRequest(response, request, fmt.Errorf("the password must not be encrypted"))
+		return
+	}
 
 	operator, ok := apirequest.UserFrom(request.Request.Context())
 
@@ -285,7 +319,11 @@ func (h *handler) ModifyPassword(request *restful.Request, response *restful.Res
 		}
 	}
 
-	err = h.im.ModifyPassword(username, passwordReset.Password)
+	if operator.GetName() == username {
+		err = h.im.ChangeOwnPassword(username, passwordReset.Password)
+	} else {
+		err = h.im.ModifyPassword(username, passwordReset.Password)
+	}
 	if err != nil {
 		api.HandleError(response, request, err)
 		return
@@ -294,6 +332,250 @@ func (h *handler) ModifyPassword(request *restful.Request, response *restful.Res
 	response.WriteEntity(servererr.None)
 }
 
+// RequirePasswordReset forces the user to change the password at next login.
+func (h *handler) RequirePasswordReset(request *restful.Request, response *restful.Response) {
+	h.requirePasswordReset(request, response, true)
+}
+
+// CancelPasswordReset cancels the password reset required by the user manager,
+// the expired password still needs to be changed.
+func (h *handler) CancelPasswordReset(request *restful.Request, response *restful.Response) {
+	h.requirePasswordReset(request, response, false)
+}
+
+func (h *handler) requirePasswordReset(request *restful.Request, response *restful.Response, required bool) {
+	username := request.PathParameter("user")
+	operator, ok := apirequest.UserFrom(request.Request.Context())
+	if !ok {
+		err := errors.NewInternalError(fmt.Errorf("cannot obtain user info"))
+		api.HandleInternalError(response, request, err)
+		return
+	}
+
+	userManagement := authorizer.AttributesRecord{
+		Resource:        "users/password",
+		Verb:            "update",
+		ResourceScope:   apirequest.GlobalScope,
+		ResourceRequest: true,
+		User:            operator,
+	}
+	decision, _, err := h.authorizer.Authorize(userManagement)
+	if err != nil {
+		api.HandleInternalError(response, request, err)
+		return
+	}
+	// users can't bypass the password reset of their own
+	if decision != authorizer.DecisionAllow {
+		api.HandleForbidden(response, request, errors.NewForbidden(iamv1beta1.Resource(iamv1beta1.ResourcesPluralUser),
+			username, fmt.Errorf("only the user manager can require the password reset")))
+		return
+	}
+
+	if err = h.im.RequirePasswordReset(username, required); err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	response.WriteEntity(servererr.None)
+}
+
+func (h *handler) DescribeTOTPStatus(request *restful.Request, response *restful.Response) {
+	username := request.PathParameter("user")
+	status, err := h.totpOperator.Status(request.Request.Context(), username)
+	if err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	response.WriteEntity(status)
+}
+
+func (h *handler) GenerateTOTPAuthKey(request *restful.Request, response *restful.Response) {
+	username := request.PathParameter("user")
+	// the auth key is generated for the user to scan, nobody else should see it
+	if err := h.requireSelf(request, username); err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	authKey, err := h.totpOperator.GenerateAuthKey(username)
+	if err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	response.WriteEntity(TOTPAuthKey{AuthKey: authKey})
+}
+
+func (h *handler) BindTOTPAuthKey(request *restful.Request, response *restful.Response) {
+	username := request.PathParameter("user")
+	var bind TOTOAuthKeyBind
+	if err := request.ReadEntity(&bind); err != nil {
+		api.HandleBadRequest(response, request, err)
+		return
+	}
+	if err := h.requireSelf(request, username); err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	codes, err := h.totpOperator.Bind(request.Request.Context(), username, bind.OTP, bind.CurrentOTP)
+	switch err {
+	case nil:
+	case auth.IncorrectOTPError, auth.AuthKeyNotGeneratedError:
+		api.HandleError(response, request, errors.NewBadRequest(err.Error()))
+		return
+	case auth.IncorrectCurrentOTPError:
+		api.HandleError(response, request, h.otpError(request, username, auth.IncorrectOTPError))
+		return
+	default:
+		api.HandleError(response, request, h.otpError(request, username, err))
+		return
+	}
+	response.WriteEntity(TOTPRecoveryCodes{RecoveryCodes: codes})
+}
+
+func (h *handler) UnbindTOTPAuthKey(request *restful.Request, response *restful.Response) {
+	username := request.PathParameter("user")
+	if err := h.verifyOTP(request, username); err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	if err := h.totpOperator.Unbind(request.Request.Context(), username); err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	response.WriteEntity(servererr.None)
+}
+
+// ResetTOTPAuthKey disables TOTP of a user who has lost the authenticator and the recovery codes.
+func (h *handler) ResetTOTPAuthKey(request *restful.Request, response *restful.Response) {
+	username := request.PathParameter("user")
+	operator, ok := apirequest.UserFrom(request.Request.Context())
+	if !ok {
+		err := errors.NewInternalError(fmt.Errorf("cannot obtain user info"))
+		api.HandleInternalError(response, request, err)
+		return
+	}
+
+	userManagement := authorizer.AttributesRecord{
+		Resource:        "users/totp",
+		Verb:            "update",
+		ResourceScope:   apirequest.GlobalScope,
+		ResourceRequest: true,
+		User:            operator,
+	}
+	decision, _, err := h.authorizer.Authorize(userManagement)
+	if err != nil {
+		api.HandleInternalError(response, request, err)
+		return
+	}
+	// only the user manager can reset the TOTP, users can't bypass the second factor of their own
+	if decision != authorizer.DecisionAllow {
+		api.HandleForbidden(response, request, errors.NewForbidden(iamv1beta1.Resource(iamv1beta1.ResourcesPluralUser),
+			username, fmt.Errorf("only the user manager can reset the totp")))
+		return
+	}
+
+	if err = h.totpOperator.Unbind(request.Request.Context(), username); err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	response.WriteEntity(servererr.None)
+}
+
+func (h *handler) RegenerateTOTPRecoveryCodes(request *restful.Request, response *restful.Response) {
+	username := request.PathParameter("user")
+	if err := h.verifyOTP(request, username); err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	codes, err := h.totpOperator.RegenerateRecoveryCodes(request.Request.Context(), username)
+	if err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	response.WriteEntity(TOTPRecoveryCodes{RecoveryCodes: codes})
+}
+
+// verifyOTP verifies that the request is made by the user with a valid one-time password.
+func (h *handler) verifyOTP(request *restful.Request, username string) error {
+	var verify TOTPVerify
+	if err := request.ReadEntity(&verify); err != nil {
+		return errors.NewBadRequest(err.Error())
+	}
+	if err := h.requireSelf(request, username); err != nil {
+		return err
+	}
+	return h.otpError(request, username, h.totpOperator.Verify(request.Request.Context(), username, verify.OTP))
+}
+
+// otpError converts the error of verifying a one-time password to the API error.
+func (h *handler) otpError(request *restful.Request, username string, err error) error {
+	switch err {
+	case nil:
+		return nil
+	case auth.IncorrectOTPError:
+		// the failed attempts are limited in the same way as the ones on login
+		var sourceIP, userAgent string
+		if requestInfo, ok := apirequest.RequestInfoFrom(request.Request.Context()); ok {
+			sourceIP = requestInfo.SourceIP
+			userAgent = requestInfo.UserAgent
+		}
+		if err := h.loginRecorder.RecordLogin(request.Request.Context(), username, iamv1beta1.Token, "", sourceIP, userAgent, err); err != nil {
+			klog.Errorf("Failed to record unsuccessful login attempt for user %s, error: %v", username, err)
+		}
+		return errors.NewBadRequest("incorrect one-time password")
+	case auth.TOTPNotEnabledError:
+		return errors.NewBadRequest("totp is not enabled")
+	case auth.RateLimitExceededError:
+		return errors.NewTooManyRequests("rate limit exceeded", 0)
+	default:
+		return err
+	}
+}
+
+func (h *handler) ListUserSessions(request *restful.Request, response *restful.Response) {
+	username := request.PathParameter("user")
+	sessions, err := h.tokenOperator.ListSessions(username)
+	if err != nil {
+		api.HandleInternalError(response, request, err)
+		return
+	}
+	response.WriteEntity(SessionList{Items: sessions, TotalItems: len(sessions)})
+}
+
+func (h *handler) RevokeUserSession(request *restful.Request, response *restful.Response) {
+	username := request.PathParameter("user")
+	session := request.PathParameter("session")
+	if err := h.tokenOperator.RevokeSession(username, session); err != nil {
+		if err == auth.SessionNotFoundError {
+			api.HandleNotFound(response, request, err)
+			return
+		}
+		api.HandleInternalError(response, request, err)
+		return
+	}
+	response.WriteEntity(servererr.None)
+}
+
+// RevokeUserSessions forces the user to log out, the tokens issued before sessions are tracked are revoked as well.
+func (h *handler) RevokeUserSessions(request *restful.Request, response *restful.Response) {
+	username := request.PathParameter("user")
+	if err := h.tokenOperator.RevokeAllUserTokens(username); err != nil {
+		api.HandleInternalError(response, request, err)
+		return
+	}
+	response.WriteEntity(servererr.None)
+}
+
+func (h *handler) requireSelf(request *restful.Request, username string) error {
+	operator, ok := apirequest.UserFrom(request.Request.Context())
+	if !ok {
+		return errors.NewInternalError(fmt.Errorf("cannot obtain user info"))
+	}
+	if operator.GetName() != username {
+		return errors.NewForbidden(iamv1beta1.Resource(iamv1beta1.ResourcesPluralUser), username,
+			fmt.Errorf("the operation can only be performed by the user"))
+	}
+	return nil
+}
+
 func (h *handler) DeleteUser(request *restful.Request, response *restful.Response) {
 	username := request.PathParameter("user")
 
@@ -702,6 +984,278 @@ func prepareAttribute(subjectAccessReview iamv1beta1.SubjectAccessReview) author
 	return attr
 }
 
+func (h *handler) CreateResourceAccessReview(request *restful.Request, response *restful.Response) {
+	review := &iamv1beta1.ResourceAccessReview{}
+	if err := request.ReadEntity(review); err != nil {
+		api.HandleBadRequest(response, request, err)
+		return
+	}
+	if (review.Spec.ResourceAttributes == nil) == (review.Spec.NonResourceAttributes == nil) {
+		api.HandleBadRequest(response, request, fmt.Errorf("exactly one of resourceAttributes and nonResourceAttributes must be set"))
+		return
+	}
+
+	attributes := prepareAttribute(iamv1beta1.SubjectAccessReview{Spec: iamv1beta1.SubjectAccessReviewSpec{
+		ResourceAttributes:    review.Spec.ResourceAttributes,
+		NonResourceAttributes: review.Spec.NonResourceAttributes,
+	}})
+	grants, err := h.authorizer.ResourceAccessReview(attributes)
+	review.Status = resourceAccessReviewStatus(grants)
+	if err != nil {
+		review.Status.EvaluationError = err.Error()
+	}
+	_ = response.WriteEntity(review)
+}
+
+func resourceAccessReviewStatus(grants []iamv1beta1.AccessGrant) iamv1beta1.ResourceAccessReviewStatus {
+	users, groups, serviceAccounts := sets.New[string](), sets.New[string](), sets.New[string]()
+	for _, grant := range grants {
+		switch grant.Subject.Kind {
+		case rbacv1.UserKind:
+			users.Insert(grant.Subject.Name)
+		case rbacv1.GroupKind:
+			groups.Insert(grant.Subject.Name)
+		case rbacv1.ServiceAccountKind:
+			namespace := grant.Subject.Namespace
+			if namespace == "" {
+				namespace = grant.BindingNamespace
+			}
+			serviceAccounts.Insert(namespace + "/" + grant.Subject.Name)
+		}
+	}
+	return iamv1beta1.ResourceAccessReviewStatus{
+		Users:           sets.List(users),
+		Groups:          sets.List(groups),
+		ServiceAccounts: sets.List(serviceAccounts),
+		Grants:          grants,
+	}
+}
+
+// CreateAccessRequest creates an access request of the current user, which is pending until reviewed.
+func (h *handler) CreateAccessRequest(request *restful.Request, response *restful.Response) {
+	requester, ok := apirequest.UserFrom(request.Request.Context())
+	if !ok {
+		api.HandleInternalError(response, request, errors.NewInternalError(fmt.Errorf("cannot obtain user info")))
+		return
+	}
+	accessRequest := &iamv1beta1.AccessRequest{}
+	if err := request.ReadEntity(accessRequest); err != nil {
+		api.HandleBadRequest(response, request, err)
+		return
+	}
+	if err := h.validateAccessRequest(accessRequest); err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+
+	accessRequest.ObjectMeta = metav1.ObjectMeta{GenerateName: requester.GetName() + "-"}
+	accessRequest.Spec.Username = requester.GetName()
+	accessRequest.Status = iamv1beta1.AccessRequestStatus{State: iamv1beta1.AccessRequestPending}
+	if err := h.am.CreateAccessRequest(accessRequest); err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	_ = response.WriteEntity(accessRequest)
+}
+
+func (h *handler) validateAccessRequest(accessRequest *iamv1beta1.AccessRequest) error {
+	spec := accessRequest.Spec
+	if spec.Duration.Duration <= 0 || spec.Duration.Duration > maxAccessRequestDuration {
+		return errors.NewBadRequest(fmt.Sprintf("the duration must be positive and no more than %s", maxAccessRequestDuration))
+	}
+	if spec.Justification == "" {
+		return errors.NewBadRequest("the justification is required")
+	}
+	var err error
+	switch spec.RoleRef.Kind {
+	case iamv1beta1.ResourceKindGlobalRole:
+		_, err = h.am.GetGlobalRole(spec.RoleRef.Name)
+	case iamv1beta1.ResourceKindWorkspaceRole:
+		if spec.Workspace == "" {
+			return errors.NewBadRequest("the workspace of the workspace role is required")
+		}
+		_, err = h.am.GetWorkspaceRole(spec.Workspace, spec.RoleRef.Name)
+	case iamv1beta1.ResourceKindClusterRole:
+		_, err = h.am.GetClusterRole(spec.RoleRef.Name)
+	case iamv1beta1.ResourceKindRole:
+		if spec.Namespace == "" {
+			return errors.NewBadRequest("the namespace of the role is required")
+		}
+		_, err = h.am.GetNamespaceRole(spec.Namespace, spec.RoleRef.Name)
+	default:
+		return errors.NewBadRequest(fmt.Sprintf("unsupported role kind %q", spec.RoleRef.Kind))
+	}
+	if errors.IsNotFound(err) {
+		return errors.NewBadRequest(err.Error())
+	}
+	return err
+}
+
+// ListAccessRequests lists the access requests of the current user and the ones the user can review.
+func (h *handler) ListAccessRequests(request *restful.Request, response *restful.Response) {
+	operator, ok := apirequest.UserFrom(request.Request.Context())
+	if !ok {
+		api.HandleInternalError(response, request, errors.NewInternalError(fmt.Errorf("cannot obtain user info")))
+		return
+	}
+	state := iamv1beta1.AccessRequestState(request.QueryParameter("state"))
+	accessRequests, err := h.am.ListAccessRequests("")
+	if err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	sort.Slice(accessRequests, func(i, j int) bool {
+		return accessRequests[j].CreationTimestamp.Before(&accessRequests[i].CreationTimestamp)
+	})
+	result := &api.ListResult{Items: []runtime.Object{}}
+	for i := range accessRequests {
+		if state != "" && accessRequests[i].Status.State != state {
+			continue
+		}
+		visible, err := h.accessRequestVisible(operator, &accessRequests[i])
+		if err != nil {
+			api.HandleError(response, request, err)
+			return
+		}
+		if visible {
+			result.Items = append(result.Items, &accessRequests[i])
+		}
+	}
+	result.TotalItems = len(result.Items)
+	_ = response.WriteEntity(result)
+}
+
+func (h *handler) DescribeAccessRequest(request *restful.Request, response *restful.Response) {
+	operator, ok := apirequest.UserFrom(request.Request.Context())
+	if !ok {
+		api.HandleInternalError(response, request, errors.NewInternalError(fmt.Errorf("cannot obtain user info")))
+		return
+	}
+	accessRequest, err := h.am.GetAccessRequest(request.PathParameter("accessrequest"))
+	if err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	visible, err := h.accessRequestVisible(operator, accessRequest)
+	if err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	if !visible {
+		api.HandleForbidden(response, request, errors.NewForbidden(iamv1beta1.Resource(iamv1beta1.ResourcesPluralAccessRequest),
+			accessRequest.Name, fmt.Errorf("the access request can only be viewed by the requester and the reviewers")))
+		return
+	}
+	_ = response.WriteEntity(accessRequest)
+}
+
+func (h *handler) ApproveAccessRequest(request *restful.Request, response *restful.Response) {
+	h.reviewAccessRequest(request, response, iamv1beta1.AccessRequestApproved)
+}
+
+func (h *handler) DenyAccessRequest(request *restful.Request, response *restful.Response) {
+	h.reviewAccessRequest(request, response, iamv1beta1.AccessRequestDenied)
+}
+
+// reviewAccessRequest records the decision of the reviewer, who must be able to grant the requested role,
+// the role binding of the approved request is created by the controller.
+func (h *handler) reviewAccessRequest(request *restful.Request, response *restful.Response, state iamv1beta1.AccessRequestState) {
+	reviewer, ok := apirequest.UserFrom(request.Request.Context())
+	if !ok {
+		api.HandleInternalError(response, request, errors.NewInternalError(fmt.Errorf("cannot obtain user info")))
+		return
+	}
+	review := &AccessRequestReview{}
+	if request.Request.ContentLength > 0 {
+		if err := request.ReadEntity(review); err != nil {
+			api.HandleBadRequest(response, request, err)
+			return
+		}
+	}
+	accessRequest, err := h.am.GetAccessRequest(request.PathParameter("accessrequest"))
+	if err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	if accessRequest.Status.State != iamv1beta1.AccessRequestPending {
+		api.HandleConflict(response, request, fmt.Errorf("the access request has been %s", strings.ToLower(string(accessRequest.Status.State))))
+		return
+	}
+	if accessRequest.Spec.Username == reviewer.GetName() {
+		api.HandleForbidden(response, request, errors.NewForbidden(iamv1beta1.Resource(iamv1beta1.ResourcesPluralAccessRequest),
+			accessRequest.Name, fmt.Errorf("the access request can't be reviewed by the requester")))
+		return
+	}
+	allowed, err := h.canReviewAccessRequest(reviewer, accessRequest)
+	if err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	if !allowed {
+		api.HandleForbidden(response, request, errors.NewForbidden(iamv1beta1.Resource(iamv1beta1.ResourcesPluralAccessRequest),
+			accessRequest.Name, fmt.Errorf("the reviewer isn't allowed to grant the %s %s", accessRequest.Spec.RoleRef.Kind, accessRequest.Spec.RoleRef.Name)))
+		return
+	}
+
+	now := metav1.Now()
+	accessRequest.Status.State = state
+	accessRequest.Status.Reviewer = reviewer.GetName()
+	accessRequest.Status.ReviewTime = &now
+	accessRequest.Status.Comment = review.Comment
+	if state == iamv1beta1.AccessRequestApproved {
+		expiresAt := metav1.NewTime(now.Add(accessRequest.Spec.Duration.Duration))
+		accessRequest.Status.NotBefore = &now
+		accessRequest.Status.ExpiresAt = &expiresAt
+	}
+	if err = h.am.UpdateAccessRequest(accessRequest); err != nil {
+		api.HandleError(response, request, err)
+		return
+	}
+	_ = response.WriteEntity(accessRequest)
+}
+
+func (h *handler) accessRequestVisible(operator authuser.Info, accessRequest *iamv1beta1.AccessRequest) (bool, error) {
+	if accessRequest.Spec.Username == operator.GetName() {
+		return true, nil
+	}
+	return h.canReviewAccessRequest(operator, accessRequest)
+}
+
+// canReviewAccessRequest returns true if the reviewer is allowed to grant the requested role to the members,
+// which is the same permission required to assign the role.
+func (h *handler) canReviewAccessRequest(reviewer authuser.Info, accessRequest *iamv1beta1.AccessRequest) (bool, error) {
+	memberManagement := authorizer.AttributesRecord{
+		User:            reviewer,
+		Verb:            "create",
+		APIGroup:        iamv1beta1.GroupName,
+		ResourceRequest: true,
+	}
+	switch accessRequest.Spec.RoleRef.Kind {
+	case iamv1beta1.ResourceKindGlobalRole:
+		memberManagement.Verb = "update"
+		memberManagement.Resource = iamv1beta1.ResourcesPluralUser
+		memberManagement.ResourceScope = apirequest.GlobalScope
+	case iamv1beta1.ResourceKindWorkspaceRole:
+		memberManagement.Resource = "workspacemembers"
+		memberManagement.Workspace = accessRequest.Spec.Workspace
+		memberManagement.ResourceScope = apirequest.WorkspaceScope
+	case iamv1beta1.ResourceKindClusterRole:
+		memberManagement.Resource = "clustermembers"
+		memberManagement.ResourceScope = apirequest.ClusterScope
+	case iamv1beta1.ResourceKindRole:
+		memberManagement.Resource = "namespacemembers"
+		memberManagement.Namespace = accessRequest.Spec.Namespace
+		memberManagement.ResourceScope = apirequest.NamespaceScope
+	default:
+		return false, nil
+	}
+	decision, _, err := h.authorizer.Authorize(memberManagement)
+	if err != nil {
+		return false, err
+	}
+	return decision == authorizer.DecisionAllow, nil
+}
+
 func (h *handler) CreateWorkspaceMembers(request *restful.Request, response *restful.Response) {
 	workspace := request.PathParameter("workspace")
 
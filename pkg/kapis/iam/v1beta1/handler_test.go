@@ -0,0 +1,61 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package v1beta1
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+	"golang.org/x/crypto/bcrypt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	runtimefakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/models/iam/im"
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func TestModifyPasswordRejectsEncryptedPassword(t *testing.T) {
+	client := runtimefakeclient.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(&iamv1beta1.User{
+			ObjectMeta: metav1.ObjectMeta{Name: "tester"},
+			Spec:       iamv1beta1.UserSpec{EncryptedPassword: "encrypted"},
+		}).
+		Build()
+	container := restful.NewContainer()
+	h := &handler{im: im.NewOperator(client, nil, nil)}
+	if err := h.AddToContainer(container); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	request := httptest.NewRequest(http.MethodPut, "/kapis/iam.kubesphere.io/v1beta1/users/tester/password",
+		strings.NewReader(`{"currentPassword":"P@88w0rd","password":"`+string(hash)+`"}`))
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	container.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusBadRequest {
+		t.Fatalf("status code = %d, want %d: %s", recorder.Code, http.StatusBadRequest, recorder.Body.String())
+	}
+
+	user := &iamv1beta1.User{}
+	if err = client.Get(context.Background(), types.NamespacedName{Name: "tester"}, user); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.Spec.EncryptedPassword != "encrypted" {
+		t.Errorf("expected the password to be kept, got %s", user.Spec.EncryptedPassword)
+	}
+}
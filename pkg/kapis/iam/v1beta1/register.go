@@ -17,6 +17,7 @@ import (
 
 	"kubesphere.io/kubesphere/pkg/api"
 	apiserverruntime "kubesphere.io/kubesphere/pkg/apiserver/runtime"
+	"kubesphere.io/kubesphere/pkg/models/auth"
 	"kubesphere.io/kubesphere/pkg/server/errors"
 )
 
@@ -51,6 +52,20 @@ func (h *handler) AddToContainer(container *restful.Container) error {
 		Reads(PasswordReset{}).
 		Param(ws.PathParameter("user", "username")).
 		Returns(http.StatusOK, api.StatusOK, errors.None))
+	ws.Route(ws.POST("/users/{user}/password/reset").
+		To(h.RequirePasswordReset).
+		Doc("Require password reset").
+		Notes("Force the user to change the password at next login, only the user manager is allowed.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Param(ws.PathParameter("user", "username")).
+		Returns(http.StatusOK, api.StatusOK, errors.None))
+	ws.Route(ws.DELETE("/users/{user}/password/reset").
+		To(h.CancelPasswordReset).
+		Doc("Cancel password reset").
+		Notes("Cancel the password reset required by the user manager, only the user manager is allowed.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Param(ws.PathParameter("user", "username")).
+		Returns(http.StatusOK, api.StatusOK, errors.None))
 	ws.Route(ws.GET("/users/{user}").
 		To(h.DescribeUser).
 		Doc("Get user").
@@ -70,6 +85,72 @@ func (h *handler) AddToContainer(container *restful.Container) error {
 		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
 		Param(ws.PathParameter("user", "username of the user")).
 		Returns(http.StatusOK, api.StatusOK, api.ListResult{Items: []runtime.Object{&iamv1beta1.LoginRecord{}}}))
+	ws.Route(ws.GET("/users/{user}/totp").
+		To(h.DescribeTOTPStatus).
+		Doc("Get TOTP status").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Param(ws.PathParameter("user", "username")).
+		Returns(http.StatusOK, api.StatusOK, auth.TOTPStatus{}))
+	ws.Route(ws.GET("/users/{user}/totp/authkey").
+		To(h.GenerateTOTPAuthKey).
+		Doc("Generate TOTP auth key").
+		Notes("Generate an auth key URI for the user to enroll an authenticator app, it takes effect after being bound.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Param(ws.PathParameter("user", "username")).
+		Returns(http.StatusOK, api.StatusOK, TOTPAuthKey{}))
+	ws.Route(ws.POST("/users/{user}/totp/bind").
+		To(h.BindTOTPAuthKey).
+		Doc("Enable TOTP").
+		Notes("Bind the generated auth key with a one-time password generated by it, the recovery codes are only returned once. "+
+			"If TOTP is already enabled, a one-time password of the current auth key is required.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Reads(TOTOAuthKeyBind{}).
+		Param(ws.PathParameter("user", "username")).
+		Returns(http.StatusOK, api.StatusOK, TOTPRecoveryCodes{}))
+	ws.Route(ws.POST("/users/{user}/totp/unbind").
+		To(h.UnbindTOTPAuthKey).
+		Doc("Disable TOTP").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Reads(TOTPVerify{}).
+		Param(ws.PathParameter("user", "username")).
+		Returns(http.StatusOK, api.StatusOK, errors.None))
+	ws.Route(ws.POST("/users/{user}/totp/reset").
+		To(h.ResetTOTPAuthKey).
+		Doc("Reset TOTP").
+		Notes("Disable TOTP of the user without a one-time password, only the user manager is allowed.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Param(ws.PathParameter("user", "username")).
+		Returns(http.StatusOK, api.StatusOK, errors.None))
+	ws.Route(ws.POST("/users/{user}/totp/recoverycodes").
+		To(h.RegenerateTOTPRecoveryCodes).
+		Doc("Regenerate TOTP recovery codes").
+		Notes("Replace the recovery codes of the user, the previous ones are no longer valid.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Reads(TOTPVerify{}).
+		Param(ws.PathParameter("user", "username")).
+		Returns(http.StatusOK, api.StatusOK, TOTPRecoveryCodes{}))
+	ws.Route(ws.GET("/users/{user}/sessions").
+		To(h.ListUserSessions).
+		Doc("List sessions").
+		Notes("List the active login sessions of the user.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Param(ws.PathParameter("user", "username")).
+		Returns(http.StatusOK, api.StatusOK, SessionList{}))
+	ws.Route(ws.DELETE("/users/{user}/sessions").
+		To(h.RevokeUserSessions).
+		Doc("Revoke all sessions").
+		Notes("Force the user to log out of all the sessions.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Param(ws.PathParameter("user", "username")).
+		Returns(http.StatusOK, api.StatusOK, errors.None))
+	ws.Route(ws.DELETE("/users/{user}/sessions/{session}").
+		To(h.RevokeUserSession).
+		Doc("Revoke session").
+		Notes("Log out the session, the tokens issued in the session are no longer valid.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Param(ws.PathParameter("user", "username")).
+		Param(ws.PathParameter("session", "session ID")).
+		Returns(http.StatusOK, api.StatusOK, errors.None))
 
 	// members
 	ws.Route(ws.GET("/clustermembers").
@@ -181,6 +262,51 @@ func (h *handler) AddToContainer(container *restful.Container) error {
 		Reads(iamv1beta1.SubjectAccessReview{}).
 		Returns(http.StatusOK, api.StatusOK, iamv1beta1.SubjectAccessReview{}))
 
+	ws.Route(ws.POST("/resourceaccessreviews").
+		To(h.CreateResourceAccessReview).
+		Doc("Create resource access review").
+		Notes("Lists the users, groups and service accounts that can perform the action, and the role bindings and roles granting it.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagAccessManagement}).
+		Reads(iamv1beta1.ResourceAccessReview{}).
+		Returns(http.StatusOK, api.StatusOK, iamv1beta1.ResourceAccessReview{}))
+
+	ws.Route(ws.POST("/accessrequests").
+		To(h.CreateAccessRequest).
+		Doc("Create access request").
+		Notes("Requests a role for a limited duration with a justification, the access is granted once approved.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagAccessManagement}).
+		Reads(iamv1beta1.AccessRequest{}).
+		Returns(http.StatusOK, api.StatusOK, iamv1beta1.AccessRequest{}))
+	ws.Route(ws.GET("/accessrequests").
+		To(h.ListAccessRequests).
+		Doc("List access requests").
+		Notes("Lists the access requests of the current user and the ones the user can review.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagAccessManagement}).
+		Param(ws.QueryParameter("state", "state of the access requests, one of Pending, Approved, Denied and Expired").Required(false)).
+		Returns(http.StatusOK, api.StatusOK, api.ListResult{Items: []runtime.Object{&iamv1beta1.AccessRequest{}}}))
+	ws.Route(ws.GET("/accessrequests/{accessrequest}").
+		To(h.DescribeAccessRequest).
+		Doc("Get access request").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagAccessManagement}).
+		Param(ws.PathParameter("accessrequest", "access request name")).
+		Returns(http.StatusOK, api.StatusOK, iamv1beta1.AccessRequest{}))
+	ws.Route(ws.POST("/accessrequests/{accessrequest}/approve").
+		To(h.ApproveAccessRequest).
+		Doc("Approve access request").
+		Notes("The reviewer must be allowed to assign the requested role, and can't be the requester.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagAccessManagement}).
+		Param(ws.PathParameter("accessrequest", "access request name")).
+		Reads(AccessRequestReview{}).
+		Returns(http.StatusOK, api.StatusOK, iamv1beta1.AccessRequest{}))
+	ws.Route(ws.POST("/accessrequests/{accessrequest}/deny").
+		To(h.DenyAccessRequest).
+		Doc("Deny access request").
+		Notes("The reviewer must be allowed to assign the requested role, and can't be the requester.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagAccessManagement}).
+		Param(ws.PathParameter("accessrequest", "access request name")).
+		Reads(AccessRequestReview{}).
+		Returns(http.StatusOK, api.StatusOK, iamv1beta1.AccessRequest{}))
+
 	container.Add(ws)
 	return nil
 }
@@ -0,0 +1,298 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package oauth
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/emicklei/go-restful/v3"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/klog/v2"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/oauth"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+	"kubesphere.io/kubesphere/pkg/models/auth"
+)
+
+const (
+	// loginPath is the login page of ks-console, which redirects the End-User
+	// back to the referer once the End-User is logged in.
+	loginPath              = "/login"
+	deviceVerificationPath = "/device"
+	deviceActionApprove    = "approve"
+	deviceActionDeny       = "deny"
+)
+
+var deviceVerificationTemplate = template.Must(template.New("device").Parse(`<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>KubeSphere Device Login</title>
+</head>
+<body>
+  <h2>Device Login</h2>
+  {{- if .Message }}
+  <p>{{ .Message }}</p>
+  {{- end }}
+  {{- if .Authorization }}
+  <p><b>{{ .Authorization.ClientID }}</b> is requesting access to your account <b>{{ .Username }}</b>{{ with .Authorization.Scopes }} with scopes {{ range . }}<code>{{ . }}</code> {{ end }}{{ end }}.</p>
+  <p>Only approve the request if the code <b>{{ .UserCode }}</b> is displayed on your device.</p>
+  <form method="post">
+    <input type="hidden" name="user_code" value="{{ .UserCode }}">
+    <input type="hidden" name="verification_token" value="{{ .VerificationToken }}">
+    <button type="submit" name="action" value="approve">Approve</button>
+    <button type="submit" name="action" value="deny">Deny</button>
+  </form>
+  {{- else if not .Completed }}
+  <form method="get">
+    <label for="user_code">Enter the code displayed on your device:</label>
+    <input id="user_code" name="user_code" value="{{ .UserCode }}" autocomplete="off" autofocus>
+    <button type="submit">Continue</button>
+  </form>
+  {{- end }}
+</body>
+</html>
+`))
+
+type deviceVerificationPage struct {
+	Username      string
+	UserCode      string
+	Authorization *auth.DeviceAuthorization
+	// VerificationToken is submitted along with the approval or the denial of the request
+	VerificationToken string
+	Message           string
+	Completed         bool
+}
+
+// deviceAuthorization starts the device authorization grant for the devices that lack a browser,
+// such as the CLI on a headless host.
+// For more details, refer to: https://datatracker.ietf.org/doc/html/rfc8628#section-3.1
+func (h *handler) deviceAuthorization(req *restful.Request, response *restful.Response) {
+	scope, _ := req.BodyParameter("scope")
+
+	response.Header().Set("Cache-Control", "no-store")
+	response.Header().Set("Pragma", "no-cache")
+
+	client, _, ok := h.authenticateClient(req, response)
+	if !ok {
+		return
+	}
+	if client.GrantMethod == oauth.GrantMethodDeny {
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewError(oauth.AccessDenied, "The resource owner or authorization server denied the request."))
+		return
+	}
+
+	var scopes []string
+	if scope != "" {
+		if !client.IsValidScope(scope) {
+			_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidScope("The requested scope is invalid or not supported."))
+			return
+		}
+		scopes = strings.Split(scope, " ")
+	}
+
+	deviceCode, authorization, err := h.deviceOperator.Create(client.Name, scopes)
+	if err != nil {
+		klog.Errorf("failed to create device authorization: %s", err)
+		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
+		return
+	}
+
+	userCode := auth.FormatUserCode(authorization.UserCode)
+	verificationURI := h.options.Issuer.URL + root + deviceVerificationPath
+	_ = response.WriteEntity(oauth.DeviceAuthorization{
+		DeviceCode:              deviceCode,
+		UserCode:                userCode,
+		VerificationURI:         verificationURI,
+		VerificationURIComplete: verificationURI + "?" + url.Values{"user_code": {userCode}}.Encode(),
+		ExpiresIn:               int(auth.DeviceCodeMaxAge.Seconds()),
+		Interval:                int(authorization.Interval.Seconds()),
+	})
+}
+
+// deviceVerification renders the page for the End-User to review the device authorization request
+// of the user code, and approves or denies it on submit.
+// For more details, refer to: https://datatracker.ietf.org/doc/html/rfc8628#section-3.3
+func (h *handler) deviceVerification(req *restful.Request, response *restful.Response) {
+	userCode := req.QueryParameter("user_code")
+	action, verificationToken := "", ""
+	if req.Request.Method == http.MethodPost {
+		userCode, _ = req.BodyParameter("user_code")
+		action, _ = req.BodyParameter("action")
+		verificationToken, _ = req.BodyParameter("verification_token")
+	}
+
+	authenticated, _ := request.UserFrom(req.Request.Context())
+	if authenticated == nil || authenticated.GetName() == user.Anonymous ||
+		authenticated.GetName() == iamv1beta1.PreRegistrationUser {
+		http.Redirect(response.ResponseWriter, req.Request, h.loginURL(userCode), http.StatusFound)
+		return
+	}
+
+	page := &deviceVerificationPage{Username: authenticated.GetName(), UserCode: userCode}
+	// The approval posted from another site is rejected, the verification token is checked as well below.
+	if req.Request.Method == http.MethodPost && !h.isSameOrigin(req.Request) {
+		page.Message = "The request must be submitted from the device login page."
+		h.renderDeviceVerification(response, http.StatusForbidden, page)
+		return
+	}
+	if userCode == "" {
+		h.renderDeviceVerification(response, http.StatusOK, page)
+		return
+	}
+
+	sessionID := h.sessionIDFrom(req.Request)
+	var err error
+	switch action {
+	case "":
+		if page.Authorization, err = h.deviceOperator.Get(userCode); err == nil {
+			page.VerificationToken, err = h.deviceOperator.IssueVerificationToken(userCode, authenticated, sessionID)
+		}
+		page.UserCode = auth.FormatUserCode(auth.NormalizeUserCode(userCode))
+	case deviceActionApprove:
+		err = h.deviceOperator.Approve(userCode, verificationToken, authenticated, sessionID)
+		page.Message = "The device has been authorized, you can return to your device now."
+		page.Completed = true
+	case deviceActionDeny:
+		err = h.deviceOperator.Deny(userCode, verificationToken, authenticated, sessionID)
+		page.Message = "The device authorization request has been denied."
+		page.Completed = true
+	default:
+		page.Message = "Unsupported action."
+		h.renderDeviceVerification(response, http.StatusBadRequest, page)
+		return
+	}
+
+	if err != nil {
+		page.Authorization = nil
+		page.Completed = false
+		if errors.Is(err, auth.UserCodeExpiredError) {
+			page.Message = "The code is invalid or expired, please check the code displayed on your device."
+			h.renderDeviceVerification(response, http.StatusBadRequest, page)
+			return
+		}
+		if errors.Is(err, auth.VerificationTokenInvalidError) {
+			page.Message = "The page has expired, please enter the code displayed on your device again."
+			h.renderDeviceVerification(response, http.StatusForbidden, page)
+			return
+		}
+		klog.Errorf("failed to verify user code: %s", err)
+		page.Message = internalServerErrorMessage
+		h.renderDeviceVerification(response, http.StatusInternalServerError, page)
+		return
+	}
+	h.renderDeviceVerification(response, http.StatusOK, page)
+}
+
+// loginURL returns the login page which leads the End-User back to the verification page of the user code.
+func (h *handler) loginURL(userCode string) string {
+	referer := root + deviceVerificationPath
+	if userCode != "" {
+		referer += "?" + url.Values{"user_code": {userCode}}.Encode()
+	}
+	return h.options.Issuer.URL + loginPath + "?" + url.Values{"referer": {referer}}.Encode()
+}
+
+// isSameOrigin checks the form is posted from the issuer, the browsers send the Origin or at least
+// the Referer with the form posted.
+func (h *handler) isSameOrigin(req *http.Request) bool {
+	source := req.Header.Get("Origin")
+	if source == "" || source == "null" {
+		source = req.Referer()
+	}
+	if source == "" {
+		return false
+	}
+	sourceURL, err := url.Parse(source)
+	if err != nil {
+		return false
+	}
+	issuerURL, err := url.Parse(h.options.Issuer.URL)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(sourceURL.Scheme, issuerURL.Scheme) && strings.EqualFold(sourceURL.Host, issuerURL.Host)
+}
+
+// sessionIDFrom returns the login session of the bearer token the End-User is authenticated with.
+func (h *handler) sessionIDFrom(req *http.Request) string {
+	parts := strings.Fields(req.Header.Get("Authorization"))
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
+		return ""
+	}
+	verified, err := h.tokenOperator.Verify(parts[1])
+	if err != nil {
+		return ""
+	}
+	return verified.SessionID
+}
+
+func (h *handler) renderDeviceVerification(response *restful.Response, status int, page *deviceVerificationPage) {
+	response.Header().Set("Content-Type", "text/html; charset=utf-8")
+	response.Header().Set("Cache-Control", "no-store")
+	// The page must not be framed by other sites to trick the End-User into approving the request.
+	response.Header().Set("X-Frame-Options", "DENY")
+	response.WriteHeader(status)
+	if err := deviceVerificationTemplate.Execute(response, page); err != nil {
+		klog.Errorf("failed to render device verification page: %s", err)
+	}
+}
+
+// deviceCodeGrant issues tokens to the device once the End-User has approved the device authorization request,
+// the device polls it with the device code until then.
+// For more details, refer to: https://datatracker.ietf.org/doc/html/rfc8628#section-3.4
+func (h *handler) deviceCodeGrant(req *restful.Request, response *restful.Response, client *oauth.Client) {
+	deviceCode, _ := req.BodyParameter("device_code")
+	if deviceCode == "" {
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidRequest("The device code is empty or missing."))
+		return
+	}
+
+	authorization, err := h.deviceOperator.Poll(client.Name, deviceCode)
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.DeviceAuthorizationPendingError):
+			_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewError(oauth.AuthorizationPending, "The authorization request is still pending."))
+		case errors.Is(err, auth.DeviceAuthorizationSlowDownError):
+			_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewError(oauth.SlowDown, "The polling interval must be increased by 5 seconds."))
+		case errors.Is(err, auth.DeviceAuthorizationDeniedError):
+			_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewError(oauth.AccessDenied, "The authorization request was denied."))
+		case errors.Is(err, auth.DeviceCodeExpiredError):
+			_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewError(oauth.ExpiredToken, "The device code is invalid or expired."))
+		default:
+			klog.Errorf("failed to poll device authorization: %s", err)
+			_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
+		}
+		return
+	}
+
+	// The End-User approved the request with an authenticated session, which has passed the MFA policy already.
+	var authenticated user.Info = &user.DefaultInfo{Name: authorization.Username, Extra: authorization.Extra}
+	if authenticated, err = h.withMFAEnrollment(authenticated); err != nil {
+		klog.Errorf("failed to get mfa policy: %s", err)
+		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
+		return
+	}
+
+	result, err := h.issueTokensTo(authenticated, client, authorization.Scopes, "", newSession(req, ""))
+	if err != nil {
+		klog.Errorf("failed to issue token: %s", err)
+		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
+		return
+	}
+
+	requestInfo, _ := request.RequestInfoFrom(req.Request.Context())
+	if err = h.loginRecorder.RecordLogin(req.Request.Context(), authenticated.GetName(), iamv1beta1.Token, "", requestInfo.SourceIP, requestInfo.UserAgent, nil); err != nil {
+		klog.Errorf("Failed to record successful login for user %s, error: %v", authenticated.GetName(), err)
+	}
+	_ = response.WriteEntity(result)
+}
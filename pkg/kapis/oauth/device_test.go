@@ -0,0 +1,129 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package oauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication"
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/oauth"
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/token"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+	"kubesphere.io/kubesphere/pkg/models/auth"
+	"kubesphere.io/kubesphere/pkg/simple/client/cache"
+)
+
+const issuerURL = "https://ks-console.kubesphere.io"
+
+var verificationTokenPattern = regexp.MustCompile(`name="verification_token" value="([^"]+)"`)
+
+func newDeviceVerificationRequest(method, accessToken, origin string, form url.Values) *http.Request {
+	var req *http.Request
+	if method == http.MethodPost {
+		req = httptest.NewRequest(method, root+deviceVerificationPath, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		req = httptest.NewRequest(method, root+deviceVerificationPath+"?"+form.Encode(), nil)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	return req.WithContext(request.WithUser(req.Context(), &user.DefaultInfo{Name: "admin"}))
+}
+
+func TestDeviceVerification(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	inMemoryCache, err := cache.NewInMemoryCache(nil, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deviceOperator := auth.NewDeviceAuthorizationOperator(inMemoryCache)
+	h := &handler{
+		options: &authentication.Options{Issuer: &oauth.IssuerOptions{URL: issuerURL}},
+		tokenOperator: &fakeTokenOperator{verified: map[string]*token.VerifiedResponse{
+			"session": accessToken("admin", "", "session"),
+			"another": accessToken("admin", "", "another"),
+		}},
+		deviceOperator: deviceOperator,
+	}
+	deviceCode, authorization, err := deviceOperator.Create("kubectl", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		h.deviceVerification(restful.NewRequest(req), restful.NewResponse(recorder))
+		return recorder
+	}
+
+	// the End-User not logged in is led back to the verification page after login
+	unauthenticated := newDeviceVerificationRequest(http.MethodGet, "", "", url.Values{"user_code": {authorization.UserCode}})
+	unauthenticated = unauthenticated.WithContext(request.WithUser(unauthenticated.Context(), &user.DefaultInfo{Name: user.Anonymous}))
+	recorder := serve(unauthenticated)
+	if recorder.Code != http.StatusFound {
+		t.Fatalf("expected status 302, got %d", recorder.Code)
+	}
+	expectedLocation := issuerURL + "/login?" + url.Values{"referer": {"/oauth/device?user_code=" + authorization.UserCode}}.Encode()
+	if location := recorder.Header().Get("Location"); location != expectedLocation {
+		t.Errorf("expected redirect to %s, got %s", expectedLocation, location)
+	}
+
+	recorder = serve(newDeviceVerificationRequest(http.MethodGet, "session", "", url.Values{"user_code": {authorization.UserCode}}))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", recorder.Code)
+	}
+	matches := verificationTokenPattern.FindStringSubmatch(recorder.Body.String())
+	if len(matches) != 2 {
+		t.Fatalf("expected the verification token in the page: %s", recorder.Body.String())
+	}
+	form := url.Values{"user_code": {authorization.UserCode}, "action": {deviceActionApprove}, "verification_token": {matches[1]}}
+
+	tests := []struct {
+		name        string
+		accessToken string
+		origin      string
+		form        url.Values
+	}{
+		{name: "posted from another site", accessToken: "session", origin: "https://attacker.example.com", form: form},
+		{name: "posted without origin", accessToken: "session", form: form},
+		{name: "posted in another session", accessToken: "another", origin: issuerURL, form: form},
+		{
+			name:        "posted without the verification token",
+			accessToken: "session",
+			origin:      issuerURL,
+			form:        url.Values{"user_code": {authorization.UserCode}, "action": {deviceActionApprove}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if recorder := serve(newDeviceVerificationRequest(http.MethodPost, test.accessToken, test.origin, test.form)); recorder.Code != http.StatusForbidden {
+				t.Errorf("expected status 403, got %d", recorder.Code)
+			}
+			if _, err := deviceOperator.Poll("kubectl", deviceCode); err == nil || err == auth.DeviceAuthorizationDeniedError {
+				t.Errorf("expected the device authorization to be pending, got %v", err)
+			}
+		})
+	}
+
+	if recorder = serve(newDeviceVerificationRequest(http.MethodPost, "session", issuerURL, form)); recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", recorder.Code)
+	}
+	if approved, err := deviceOperator.Poll("kubectl", deviceCode); err != nil || approved.Username != "admin" {
+		t.Errorf("expected the device authorization to be approved by admin, got %v", err)
+	}
+}
@@ -0,0 +1,191 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package oauth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/golang-jwt/jwt/v4"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/klog/v2"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/oauth"
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/token"
+	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
+)
+
+// clientCredentialsGrant issues an access token to the identity of the client, for non-human access such as CI systems.
+// For more details, refer to: https://datatracker.ietf.org/doc/html/rfc6749#section-4.4
+func (h *handler) clientCredentialsGrant(req *restful.Request, response *restful.Response, client *oauth.Client) {
+	if client.ClientCredentials == nil {
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewError(oauth.UnauthorizedClient, "The client is not authorized to use the client_credentials grant."))
+		return
+	}
+
+	scope, _ := req.BodyParameter("scope")
+	var scopes []string
+	if scope != "" {
+		if !client.IsValidScope(scope) {
+			_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidScope("The requested scope is invalid or not supported."))
+			return
+		}
+		scopes = strings.Split(scope, " ")
+	}
+
+	expiresIn := h.accessTokenMaxAge(client)
+	// A refresh token SHOULD NOT be included, the client can always request a new token with its credentials.
+	accessToken, err := h.tokenOperator.IssueTo(&token.IssueRequest{
+		User: &user.DefaultInfo{
+			Name:   client.ClientIdentity(),
+			Groups: []string{user.AllAuthenticated},
+		},
+		Claims: token.Claims{
+			TokenType: token.AccessToken,
+			ClientID:  client.Name,
+			Scopes:    scopes,
+		},
+		ExpiresIn: expiresIn,
+	})
+	if err != nil {
+		klog.Errorf("Failed to issue token: %s", err)
+		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
+		return
+	}
+
+	_ = response.WriteEntity(oauth.Token{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(expiresIn.Seconds()),
+		Scope:       scope,
+	})
+}
+
+// tokenExchangeGrant exchanges an access token for a token with a narrower audience, scope or workspace,
+// which can be handed over to a less trusted service. Delegation with an actor token is not supported.
+// For more details, refer to: https://datatracker.ietf.org/doc/html/rfc8693
+func (h *handler) tokenExchangeGrant(req *restful.Request, response *restful.Response, client *oauth.Client) {
+	subjectToken, _ := req.BodyParameter("subject_token")
+	subjectTokenType, _ := req.BodyParameter("subject_token_type")
+	requestedTokenType, _ := req.BodyParameter("requested_token_type")
+	actorToken, _ := req.BodyParameter("actor_token")
+	scope, _ := req.BodyParameter("scope")
+	workspace, _ := req.BodyParameter("workspace")
+	audiences := req.Request.PostForm["audience"]
+
+	if subjectToken == "" {
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidRequest("The subject token is empty or missing."))
+		return
+	}
+	if subjectTokenType != oauth.TokenTypeAccessToken && subjectTokenType != oauth.TokenTypeJWT {
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidRequest(fmt.Sprintf("The subject token type %s is not supported.", subjectTokenType)))
+		return
+	}
+	if requestedTokenType != "" && requestedTokenType != oauth.TokenTypeAccessToken {
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidRequest(fmt.Sprintf("The requested token type %s is not supported.", requestedTokenType)))
+		return
+	}
+	if actorToken != "" {
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidRequest("The actor token is not supported."))
+		return
+	}
+
+	verified, err := h.tokenOperator.Verify(subjectToken)
+	if err != nil || (verified.TokenType != token.AccessToken && verified.TokenType != token.StaticToken) ||
+		verified.User.GetName() == iamv1beta1.PreRegistrationUser {
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidGrant("The subject token is invalid or expired."))
+		return
+	}
+	if err = h.verifyAuthenticationMethods(req.Request.Context(), verified.User); err != nil {
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidGrant("The subject token is invalid or expired."))
+		return
+	}
+
+	// The exchanged token can only be narrower than the subject token.
+	scopes := verified.Scopes
+	if scope != "" {
+		scopes = strings.Split(scope, " ")
+		for _, s := range scopes {
+			if len(verified.Scopes) > 0 && !sliceutil.HasString(verified.Scopes, s) ||
+				len(verified.Scopes) == 0 && !client.IsValidScope(s) {
+				_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidScope("The requested scope is invalid or exceeds the subject token."))
+				return
+			}
+		}
+	}
+	if len(audiences) == 0 {
+		audiences = verified.Audience
+	}
+	for _, audience := range audiences {
+		if len(verified.Audience) > 0 && !sliceutil.HasString(verified.Audience, audience) {
+			_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewError(oauth.InvalidTarget, "The requested audience exceeds the subject token."))
+			return
+		}
+	}
+
+	extra := map[string][]string{}
+	for k, v := range verified.User.GetExtra() {
+		extra[k] = v
+	}
+	if workspaceScope := extra[iamv1beta1.ExtraWorkspaceScope]; len(workspaceScope) > 0 {
+		if workspace != "" && workspace != workspaceScope[0] {
+			_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewError(oauth.InvalidTarget, "The requested workspace exceeds the subject token."))
+			return
+		}
+	} else if workspace != "" {
+		extra[iamv1beta1.ExtraWorkspaceScope] = []string{workspace}
+	}
+
+	expiresIn := h.accessTokenMaxAge(client)
+	if verified.ExpiresAt != nil {
+		if remaining := time.Until(verified.ExpiresAt.Time); expiresIn == 0 || remaining < expiresIn {
+			expiresIn = remaining
+		}
+	}
+
+	accessToken, err := h.tokenOperator.IssueTo(&token.IssueRequest{
+		User: &user.DefaultInfo{
+			Name:   verified.User.GetName(),
+			UID:    verified.User.GetUID(),
+			Groups: verified.User.GetGroups(),
+			Extra:  extra,
+		},
+		Claims: token.Claims{
+			RegisteredClaims: jwt.RegisteredClaims{Audience: audiences},
+			TokenType:        token.AccessToken,
+			ClientID:         client.Name,
+			Scopes:           scopes,
+			// the exchanged token is invalidated as well once the session of the subject token is revoked
+			SessionID: verified.SessionID,
+		},
+		ExpiresIn: expiresIn,
+	})
+	if err != nil {
+		klog.Errorf("Failed to issue token: %s", err)
+		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
+		return
+	}
+
+	_ = response.WriteEntity(oauth.Token{
+		AccessToken:     accessToken,
+		IssuedTokenType: oauth.TokenTypeAccessToken,
+		TokenType:       "Bearer",
+		ExpiresIn:       int(expiresIn.Seconds()),
+		Scope:           strings.Join(scopes, " "),
+	})
+}
+
+func (h *handler) accessTokenMaxAge(client *oauth.Client) time.Duration {
+	if client != nil && client.AccessTokenMaxAgeSeconds > 0 {
+		return time.Duration(client.AccessTokenMaxAgeSeconds) * time.Second
+	}
+	return h.options.Issuer.AccessTokenMaxAge
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package oauth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication"
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/oauth"
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/token"
+	"kubesphere.io/kubesphere/pkg/models/auth"
+	"kubesphere.io/kubesphere/pkg/simple/client/cache"
+)
+
+func newTokenOperator(t *testing.T, stopCh <-chan struct{}) auth.TokenManagementInterface {
+	inMemoryCache, err := cache.NewInMemoryCache(nil, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := authentication.NewOptions()
+	options.Issuer.JWTSecret = "kubesphere"
+	tokenOperator, err := auth.NewTokenOperator(inMemoryCache, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := token.GenerateSigningKeyData("ES256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := token.ParseSigningKey(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tokenOperator.Keyring().Update([]*token.Key{key})
+	return tokenOperator
+}
+
+func TestTokenExchangeGrantRevokedSession(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	tokenOperator := newTokenOperator(t, stopCh)
+	h := &handler{
+		options:       authentication.NewOptions(),
+		tokenOperator: tokenOperator,
+	}
+	client := &oauth.Client{Name: "kubectl", Secret: "kubectl-secret"}
+
+	session := &auth.Session{Username: "admin", ClientID: client.Name}
+	if err := tokenOperator.SaveSession(session); err != nil {
+		t.Fatal(err)
+	}
+	subjectToken, err := tokenOperator.IssueTo(&token.IssueRequest{
+		User:      &user.DefaultInfo{Name: "admin"},
+		Claims:    token.Claims{TokenType: token.AccessToken, ClientID: client.Name, SessionID: session.ID},
+		ExpiresIn: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := serveForm(func(req *restful.Request, response *restful.Response) {
+		h.tokenExchangeGrant(req, response, client)
+	}, url.Values{
+		"grant_type":         {oauth.GrantTypeTokenExchange},
+		"subject_token":      {subjectToken},
+		"subject_token_type": {oauth.TokenTypeAccessToken},
+	})
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	exchanged := &oauth.Token{}
+	if err = json.Unmarshal(recorder.Body.Bytes(), exchanged); err != nil {
+		t.Fatal(err)
+	}
+	verified, err := tokenOperator.Verify(exchanged.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.SessionID != session.ID {
+		t.Errorf("expected the exchanged token bound to session %s, got %q", session.ID, verified.SessionID)
+	}
+
+	if err = tokenOperator.RevokeSession("admin", session.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = tokenOperator.Verify(exchanged.AccessToken); !errors.Is(err, auth.SessionNotFoundError) {
+		t.Errorf("expected %v after the session is revoked, got %v", auth.SessionNotFoundError, err)
+	}
+}
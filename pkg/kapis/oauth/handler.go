@@ -24,6 +24,7 @@ import (
 
 	"kubesphere.io/kubesphere/pkg/api"
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication"
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/identityprovider"
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication/oauth"
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication/token"
 	"kubesphere.io/kubesphere/pkg/apiserver/query"
@@ -42,11 +43,15 @@ const (
 
 type Spec struct {
 	Token string `json:"token" description:"access token"`
+	// Audiences are the identifiers of the resource server, the token with an audience is
+	// only authenticated if any of them matches. It defaults to the audience of ks-apiserver.
+	Audiences []string `json:"audiences,omitempty" description:"identifiers of the resource server"`
 }
 
 type Status struct {
 	Authenticated bool                   `json:"authenticated" description:"is authenticated"`
 	User          map[string]interface{} `json:"user,omitempty" description:"user info"`
+	Audiences     []string               `json:"audiences,omitempty" description:"audiences of the token matching the spec"`
 }
 
 type TokenReview struct {
@@ -81,6 +86,16 @@ type ProviderMetadata struct {
 	UserInfo string `json:"userinfo_endpoint"`
 	// URL of the OP's JSON Web Key Set [JWK] document.
 	Keys string `json:"jwks_uri"`
+	// URL of the authorization server's device authorization endpoint.
+	DeviceAuthorization string `json:"device_authorization_endpoint"`
+	// URL of the authorization server's OAuth 2.0 introspection endpoint.
+	Introspection string `json:"introspection_endpoint"`
+	// URL of the authorization server's OAuth 2.0 revocation endpoint.
+	Revocation string `json:"revocation_endpoint"`
+	// JSON array containing a list of client authentication methods supported by the introspection endpoint.
+	IntrospectionAuthMethods []string `json:"introspection_endpoint_auth_methods_supported"`
+	// JSON array containing a list of client authentication methods supported by the revocation endpoint.
+	RevocationAuthMethods []string `json:"revocation_endpoint_auth_methods_supported"`
 	// JSON array containing a list of the OAuth 2.0 Grant Type values that this OP supports.
 	GrantTypes []string `json:"grant_types_supported"`
 	// JSON array containing a list of the OAuth 2.0 response_type values that this OP supports.
@@ -110,6 +125,8 @@ type handler struct {
 	oauthAuthenticator    auth.OAuthAuthenticator
 	loginRecorder         auth.LoginRecorder
 	clientGetter          oauth.ClientGetter
+	totpOperator          auth.TOTPOperator
+	deviceOperator        auth.DeviceAuthorizationOperator
 }
 
 func NewHandler(im im.IdentityManagementInterface,
@@ -118,14 +135,18 @@ func NewHandler(im im.IdentityManagementInterface,
 	oauth2Authenticator auth.OAuthAuthenticator,
 	loginRecorder auth.LoginRecorder,
 	options *authentication.Options,
-	oauthOperator oauth.ClientGetter) rest.Handler {
+	oauthOperator oauth.ClientGetter,
+	totpOperator auth.TOTPOperator,
+	deviceOperator auth.DeviceAuthorizationOperator) rest.Handler {
 	handler := &handler{im: im,
 		tokenOperator:         tokenOperator,
 		passwordAuthenticator: passwordAuthenticator,
 		oauthAuthenticator:    oauth2Authenticator,
 		loginRecorder:         loginRecorder,
 		options:               options,
-		clientGetter:          oauthOperator}
+		clientGetter:          oauthOperator,
+		totpOperator:          totpOperator,
+		deviceOperator:        deviceOperator}
 	return handler
 }
 
@@ -155,6 +176,31 @@ func (h *handler) tokenReview(req *restful.Request, resp *restful.Response) {
 		api.HandleBadRequest(resp, req, err)
 		return
 	}
+	if verified.TokenType == token.MFAToken {
+		api.HandleBadRequest(resp, req, fmt.Errorf("invalid token type %s", verified.TokenType))
+		return
+	}
+	if err = h.verifyAuthenticationMethods(req.Request.Context(), verified.User); err != nil {
+		api.HandleBadRequest(resp, req, err)
+		return
+	}
+
+	var audiences []string
+	if len(verified.Audience) > 0 {
+		expected := tokenReview.Spec.Audiences
+		if len(expected) == 0 {
+			expected = []string{token.DefaultAudience}
+		}
+		for _, audience := range expected {
+			if sliceutil.HasString(verified.Audience, audience) {
+				audiences = append(audiences, audience)
+			}
+		}
+		if len(audiences) == 0 {
+			api.HandleBadRequest(resp, req, fmt.Errorf("token audiences %v don't match %v", verified.Audience, expected))
+			return
+		}
+	}
 
 	authenticated := verified.User
 	success := TokenReview{APIVersion: tokenReview.APIVersion,
@@ -162,6 +208,7 @@ func (h *handler) tokenReview(req *restful.Request, resp *restful.Response) {
 		Status: &Status{
 			Authenticated: true,
 			User:          map[string]interface{}{"username": authenticated.GetName(), "uid": authenticated.GetUID()},
+			Audiences:     audiences,
 		},
 	}
 
@@ -170,17 +217,26 @@ func (h *handler) tokenReview(req *restful.Request, resp *restful.Response) {
 
 func (h *handler) discovery(_ *restful.Request, response *restful.Response) {
 	result := ProviderMetadata{
-		Issuer:            h.options.Issuer.URL,
-		Auth:              h.options.Issuer.URL + root + "/authorize",
-		Token:             h.options.Issuer.URL + root + "/token",
-		Keys:              h.options.Issuer.URL + root + "/keys",
-		UserInfo:          h.options.Issuer.URL + root + "/userinfo",
-		Subjects:          []string{"public"},
-		GrantTypes:        []string{oauth.GrantTypeAuthorizationCode, oauth.GrantTypeRefreshToken},
-		IDTokenAlgs:       []string{string(jose.RS256)},
-		CodeChallengeAlgs: []string{"plain", "S256"},
-		Scopes:            []string{oauth.ScopeOpenID, oauth.ScopeEmail, oauth.ScopeProfile},
-		AuthMethods:       []string{"client_secret_post"},
+		Issuer:              h.options.Issuer.URL,
+		Auth:                h.options.Issuer.URL + root + "/authorize",
+		Token:               h.options.Issuer.URL + root + "/token",
+		Keys:                h.options.Issuer.URL + root + "/keys",
+		UserInfo:            h.options.Issuer.URL + root + "/userinfo",
+		DeviceAuthorization: h.options.Issuer.URL + root + "/device_authorization",
+		Introspection:       h.options.Issuer.URL + root + "/introspect",
+		Revocation:          h.options.Issuer.URL + root + "/revoke",
+		Subjects:            []string{"public"},
+		IDTokenAlgs:         token.SupportedSigningAlgorithms,
+		CodeChallengeAlgs:   oauth.ValidCodeChallengeMethods,
+		Scopes:              []string{oauth.ScopeOpenID, oauth.ScopeEmail, oauth.ScopeProfile},
+		AuthMethods:         []string{"client_secret_post", "none"},
+		// Only confidential clients can introspect tokens.
+		IntrospectionAuthMethods: []string{"client_secret_post"},
+		RevocationAuthMethods:    []string{"client_secret_post", "none"},
+		GrantTypes: []string{
+			oauth.GrantTypeAuthorizationCode, oauth.GrantTypeRefreshToken, oauth.GrantTypeClientCredentials,
+			oauth.GrantTypeTokenExchange, oauth.GrantTypeDeviceCode,
+		},
 		Claims: []string{
 			"iss", "sub", "aud", "iat", "exp", "email", "locale", "preferred_username",
 		},
@@ -195,14 +251,14 @@ func (h *handler) discovery(_ *restful.Request, response *restful.Response) {
 
 func (h *handler) keys(_ *restful.Request, response *restful.Response) {
 	jwks := jose.JSONWebKeySet{
-		Keys: []jose.JSONWebKey{*h.tokenOperator.Keys().SigningKeyPub},
+		Keys: h.tokenOperator.Keys().VerificationKeys,
 	}
 	_ = response.WriteEntity(jwks)
 }
 
 // The Authorization Endpoint performs Authentication of the End-User.
 func (h *handler) authorize(req *restful.Request, response *restful.Response) {
-	var scope, responseType, clientID, redirectURI, state, nonce, prompt string
+	var scope, responseType, clientID, redirectURI, state, nonce, prompt, codeChallenge, codeChallengeMethod string
 	scope = req.QueryParameter("scope")
 	clientID = req.QueryParameter("client_id")
 	redirectURI = req.QueryParameter("redirect_uri")
@@ -210,6 +266,8 @@ func (h *handler) authorize(req *restful.Request, response *restful.Response) {
 	state = req.QueryParameter("state")
 	nonce = req.QueryParameter("nonce")
 	prompt = req.QueryParameter("prompt")
+	codeChallenge = req.QueryParameter("code_challenge")
+	codeChallengeMethod = req.QueryParameter("code_challenge_method")
 	// Authorization Servers MUST support the use of the HTTP GET and POST methods
 	// defined in RFC 2616 [RFC2616] at the Authorization Endpoint.
 	if req.Request.Method == http.MethodPost {
@@ -220,6 +278,8 @@ func (h *handler) authorize(req *restful.Request, response *restful.Response) {
 		state, _ = req.BodyParameter("state")
 		nonce, _ = req.BodyParameter("nonce")
 		prompt, _ = req.BodyParameter("prompt")
+		codeChallenge, _ = req.BodyParameter("code_challenge")
+		codeChallengeMethod, _ = req.BodyParameter("code_challenge_method")
 	}
 
 	client, err := h.clientGetter.GetOAuthClient(req.Request.Context(), clientID)
@@ -280,6 +340,21 @@ func (h *handler) authorize(req *restful.Request, response *restful.Response) {
 		return
 	}
 
+	// Public clients can't authenticate at the Token Endpoint, the authorization code is
+	// bound to the client with PKCE, so that an intercepted code can't be exchanged for tokens.
+	if responseType == oauth.ResponseTypeCode {
+		if codeChallenge == "" && client.Public {
+			informsError(oauth.NewInvalidRequest("Code challenge required."))
+			return
+		}
+		if codeChallenge != "" {
+			if err = oauth.ValidateCodeChallenge(codeChallenge, codeChallengeMethod); err != nil {
+				informsError(oauth.NewInvalidRequest(fmt.Sprintf("Invalid code challenge: %s.", err)))
+				return
+			}
+		}
+	}
+
 	if client.GrantMethod == oauth.GrantMethodDeny {
 		informsError(oauth.NewInvalidGrant("The resource owner or authorization server denied the request."))
 		return
@@ -314,6 +389,9 @@ func (h *handler) authorize(req *restful.Request, response *restful.Response) {
 			scopes:        scopes,
 			redirectURL:   redirectURL,
 			state:         state,
+
+			codeChallenge:       codeChallenge,
+			codeChallengeMethod: codeChallengeMethod,
 		})
 	case oauth.ResponseTypeIDToken:
 		h.handleAuthIDTokenRequest(req, response, &authIDTokenRequest{
@@ -333,14 +411,39 @@ func (h *handler) authorize(req *restful.Request, response *restful.Response) {
 
 func (h *handler) oauthCallback(req *restful.Request, response *restful.Response) {
 	provider := req.PathParameter("callback")
+	// The providers such as SAML start the login by redirecting the user agent to the identity provider.
+	if oauthProvider, ok := identityprovider.SharedIdentityProviderController.GetOAuthProvider(provider); ok {
+		if redirector, ok := oauthProvider.(identityprovider.LoginRedirector); ok {
+			redirectURL, redirect, err := redirector.LoginRedirect(response, req.Request)
+			if err != nil {
+				klog.Errorf("failed to start login with identity provider %s: %s", provider, err)
+				_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
+				return
+			}
+			if redirect {
+				http.Redirect(response, req.Request, redirectURL, http.StatusFound)
+				return
+			}
+		}
+	}
+
 	authenticated, err := h.oauthAuthenticator.Authenticate(req.Request.Context(), provider, req.Request)
 	if err != nil {
 		api.HandleUnauthorized(response, req, apierrors.NewUnauthorized(fmt.Sprintf("Unauthorized: %s", err)))
 		return
 	}
 
+	if h.challengeMFA(response, &mfaChallengeRequest{authenticated: authenticated, provider: provider}) {
+		return
+	}
+	if authenticated, err = h.withMFAEnrollment(authenticated); err != nil {
+		klog.Errorf("failed to get mfa policy: %s", err)
+		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
+		return
+	}
+
 	// TODO(@hongming) using the really client configuration
-	result, err := h.issueTokenTo(authenticated, nil)
+	result, err := h.issueTokenTo(authenticated, nil, newSession(req, provider))
 	if err != nil {
 		klog.Errorf("failed to issue token: %s", err)
 		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
@@ -355,20 +458,29 @@ func (h *handler) oauthCallback(req *restful.Request, response *restful.Response
 	_ = response.WriteEntity(result)
 }
 
-// token handles the Token Request to obtain an Access Token, an ID Token, and optionally a Refresh Token.
-// This is used in the Authorization Code Flow, where the RP (Client) sends a Token Request to the Token Endpoint
-// (described in Section 3.2 of OAuth 2.0 [RFC6749]) to obtain a Token Response.
-// Communication with the Token Endpoint is required to utilize TLS for security.
-func (h *handler) token(req *restful.Request, response *restful.Response) {
+func (h *handler) oauthCallbackMetadata(req *restful.Request, response *restful.Response) {
+	provider := req.PathParameter("callback")
+	oauthProvider, _ := identityprovider.SharedIdentityProviderController.GetOAuthProvider(provider)
+	metadataProvider, ok := oauthProvider.(identityprovider.MetadataProvider)
+	if !ok {
+		api.HandleNotFound(response, req, fmt.Errorf("the metadata of identity provider %s is not found", provider))
+		return
+	}
+	data, err := metadataProvider.Metadata()
+	if err != nil {
+		api.HandleInternalError(response, req, err)
+		return
+	}
+	response.Header().Set("Content-Type", "application/samlmetadata+xml")
+	_, _ = response.Write(data)
+}
+
+// authenticateClient retrieves the OAuth client of the request and checks its client_secret,
+// it returns false if the error response has been written.
+// Public clients are identified by the client_id only, they can't use the endpoints relying on client authentication.
+func (h *handler) authenticateClient(req *restful.Request, response *restful.Response) (client *oauth.Client, public bool, ok bool) {
 	clientID, _ := req.BodyParameter("client_id")
 	clientSecret, _ := req.BodyParameter("client_secret")
-	grantType, _ := req.BodyParameter("grant_type")
-
-	// All Token Responses containing sensitive information MUST include the following HTTP response header fields and values:
-	// Cache-Control: no-store
-	// Pragma: no-cache
-	response.Header().Set("Cache-Control", "no-store")
-	response.Header().Set("Pragma", "no-cache")
 
 	// Retrieve the OAuth client associated with the provided client_id.
 	client, err := h.clientGetter.GetOAuthClient(req.Request.Context(), clientID)
@@ -376,17 +488,40 @@ func (h *handler) token(req *restful.Request, response *restful.Response) {
 		if errors.Is(err, oauth.ErrorClientNotFound) {
 			klog.Warningf("The provided client_id %s is invalid or does not exist.", clientID)
 			_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidClient("The provided client_id is invalid or does not exist."))
-			return
+			return nil, false, false
 		}
 		klog.Errorf("failed to get oauth client: %v", err)
 		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
-		return
+		return nil, false, false
 	}
 
+	public = client.Public && clientSecret == ""
+
 	// Check if the client_secret matches the one associated with the retrieved client.
-	if client.Secret != clientSecret {
+	if !public && client.Secret != clientSecret {
 		klog.Warningf("Invalid client credential for client_id %s", clientID)
+		auth.FlagLoginFailure(req.Request.Context())
 		_ = response.WriteHeaderAndEntity(http.StatusUnauthorized, oauth.NewError(oauth.UnauthorizedClient, "Invalid client credential."))
+		return nil, false, false
+	}
+	return client, public, true
+}
+
+// token handles the Token Request to obtain an Access Token, an ID Token, and optionally a Refresh Token.
+// This is used in the Authorization Code Flow, where the RP (Client) sends a Token Request to the Token Endpoint
+// (described in Section 3.2 of OAuth 2.0 [RFC6749]) to obtain a Token Response.
+// Communication with the Token Endpoint is required to utilize TLS for security.
+func (h *handler) token(req *restful.Request, response *restful.Response) {
+	grantType, _ := req.BodyParameter("grant_type")
+
+	// All Token Responses containing sensitive information MUST include the following HTTP response header fields and values:
+	// Cache-Control: no-store
+	// Pragma: no-cache
+	response.Header().Set("Cache-Control", "no-store")
+	response.Header().Set("Pragma", "no-cache")
+
+	client, public, ok := h.authenticateClient(req, response)
+	if !ok {
 		return
 	}
 
@@ -394,7 +529,7 @@ func (h *handler) token(req *restful.Request, response *restful.Response) {
 
 	switch grantType {
 	case oauth.GrantTypePassword:
-		if client.Trusted {
+		if client.Trusted && !public {
 			h.passwordGrant(req, response, client)
 			return
 		}
@@ -404,6 +539,21 @@ func (h *handler) token(req *restful.Request, response *restful.Response) {
 		h.refreshTokenGrant(req, response, client)
 	case oauth.GrantTypeCode, oauth.GrantTypeAuthorizationCode:
 		h.codeGrant(req, response, client)
+	case oauth.GrantTypeOTP:
+		h.otpGrant(req, response, client)
+	case oauth.GrantTypeDeviceCode:
+		h.deviceCodeGrant(req, response, client)
+	case oauth.GrantTypeClientCredentials, oauth.GrantTypeTokenExchange:
+		// Machine grants are only available to the clients that can keep their credentials confidential.
+		if public {
+			_ = response.WriteHeaderAndEntity(http.StatusUnauthorized, oauth.NewError(oauth.UnauthorizedClient, "Invalid client credential."))
+			return
+		}
+		if grantType == oauth.GrantTypeClientCredentials {
+			h.clientCredentialsGrant(req, response, client)
+		} else {
+			h.tokenExchangeGrant(req, response, client)
+		}
 	default:
 		klog.Warningf("The provided grant_type %s is not supported.", grantType)
 		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, unsupportedGrantType)
@@ -453,8 +603,18 @@ func (h *handler) passwordGrant(req *restful.Request, response *restful.Response
 		}
 	}
 
+	// Challenge the second factor if the user has enabled TOTP.
+	if h.challengeMFA(response, &mfaChallengeRequest{authenticated: authenticated, client: client, provider: provider}) {
+		return
+	}
+	if authenticated, err = h.withMFAEnrollment(authenticated); err != nil {
+		klog.Errorf("Failed to get MFA policy: %s", err)
+		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
+		return
+	}
+
 	// Issue token to the authenticated user.
-	result, err := h.issueTokenTo(authenticated, client)
+	result, err := h.issueTokenTo(authenticated, client, newSession(req, provider))
 	if err != nil {
 		// Failed to issue token.
 		klog.Errorf("Failed to issue token: %s", err)
@@ -472,7 +632,20 @@ func (h *handler) passwordGrant(req *restful.Request, response *restful.Response
 	_ = response.WriteEntity(result)
 }
 
-func (h *handler) issueTokenTo(user user.Info, client *oauth.Client) (*oauth.Token, error) {
+// newSession returns a new login session of the request, the source IP and the user agent
+// are the same as the ones in the LoginRecord of the login.
+func newSession(req *restful.Request, provider string) *auth.Session {
+	requestInfo, _ := request.RequestInfoFrom(req.Request.Context())
+	session := &auth.Session{Provider: provider}
+	if requestInfo != nil {
+		session.SourceIP = requestInfo.SourceIP
+		session.UserAgent = requestInfo.UserAgent
+	}
+	return session
+}
+
+// issueTokenTo issues the access token and the refresh token of the session to the user.
+func (h *handler) issueTokenTo(user user.Info, client *oauth.Client, session *auth.Session) (*oauth.Token, error) {
 	accessTokenMaxAge := h.options.Issuer.AccessTokenMaxAge
 	accessTokenInactivityTimeout := h.options.Issuer.AccessTokenInactivityTimeout
 	if client != nil && client.AccessTokenMaxAgeSeconds > 0 && client.AccessTokenInactivityTimeoutSeconds > 0 {
@@ -485,9 +658,27 @@ func (h *handler) issueTokenTo(user user.Info, client *oauth.Client) (*oauth.Tok
 			return nil, err
 		}
 	}
+	// The tokens are bound to the client, so that only the client can revoke them.
+	var clientID string
+	if client != nil {
+		clientID = client.Name
+	}
+	// The sessions are tracked along with the tokens in the cache, the tokens never expire
+	// and can't be revoked if the max age is 0.
+	var sessionID string
+	if accessTokenMaxAge > 0 && session != nil {
+		expiresAt := time.Now().Add(accessTokenMaxAge + accessTokenInactivityTimeout)
+		session.Username = user.GetName()
+		session.ClientID = clientID
+		session.ExpiresAt = &expiresAt
+		if err := h.tokenOperator.SaveSession(session); err != nil {
+			return nil, err
+		}
+		sessionID = session.ID
+	}
 	accessToken, err := h.tokenOperator.IssueTo(&token.IssueRequest{
 		User:      user,
-		Claims:    token.Claims{TokenType: token.AccessToken},
+		Claims:    token.Claims{TokenType: token.AccessToken, ClientID: clientID, SessionID: sessionID},
 		ExpiresIn: accessTokenMaxAge,
 	})
 	if err != nil {
@@ -495,7 +686,7 @@ func (h *handler) issueTokenTo(user user.Info, client *oauth.Client) (*oauth.Tok
 	}
 	refreshToken, err := h.tokenOperator.IssueTo(&token.IssueRequest{
 		User:      user,
-		Claims:    token.Claims{TokenType: token.RefreshToken},
+		Claims:    token.Claims{TokenType: token.RefreshToken, ClientID: clientID, SessionID: sessionID},
 		ExpiresIn: accessTokenMaxAge + accessTokenInactivityTimeout,
 	})
 	if err != nil {
@@ -515,7 +706,8 @@ func (h *handler) issueTokenTo(user user.Info, client *oauth.Client) (*oauth.Tok
 func (h *handler) refreshTokenGrant(req *restful.Request, response *restful.Response, client *oauth.Client) {
 	refreshToken, _ := req.BodyParameter("refresh_token")
 	verified, err := h.tokenOperator.Verify(refreshToken)
-	if err != nil || verified.TokenType != token.RefreshToken {
+	if err != nil || verified.TokenType != token.RefreshToken ||
+		(verified.ClientID != "" && verified.ClientID != client.Name) {
 		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidGrant("The refresh token is invalid or expired."))
 		return
 	}
@@ -548,7 +740,21 @@ func (h *handler) refreshTokenGrant(req *restful.Request, response *restful.Resp
 		authenticated = &user.DefaultInfo{Name: users.Items[0].(*iamv1beta1.User).Name}
 	}
 
-	result, err := h.issueTokenTo(authenticated, client)
+	// The refreshed tokens belong to the same session, unless the pre-registered user has registered.
+	session := newSession(req, "")
+	if verified.SessionID != "" && authenticated.GetName() == verified.User.GetName() {
+		if session, err = h.tokenOperator.DescribeSession(authenticated.GetName(), verified.SessionID); err != nil {
+			if errors.Is(err, auth.SessionNotFoundError) {
+				_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidGrant("The refresh token is invalid or expired."))
+				return
+			}
+			klog.Errorf("failed to get session: %s", err)
+			_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
+			return
+		}
+	}
+
+	result, err := h.issueTokenTo(authenticated, client, session)
 	if err != nil {
 		klog.Errorf("failed to issue token: %s", err)
 		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
@@ -571,11 +777,24 @@ func (h *handler) codeGrant(req *restful.Request, response *restful.Response, cl
 	}
 
 	authorizeContext, err := h.tokenOperator.Verify(code)
-	if err != nil || authorizeContext.TokenType != token.AuthorizationCode {
+	if err != nil || authorizeContext.TokenType != token.AuthorizationCode ||
+		!sliceutil.HasString(authorizeContext.Audience, client.Name) {
 		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidGrant("The authorization code is invalid or expired."))
 		return
 	}
 
+	// https://datatracker.ietf.org/doc/html/rfc7636#section-4.6
+	codeVerifier, _ := req.BodyParameter("code_verifier")
+	if authorizeContext.CodeChallenge != "" &&
+		!oauth.VerifyCodeVerifier(codeVerifier, authorizeContext.CodeChallenge, authorizeContext.CodeChallengeMethod) {
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidGrant("The code verifier is invalid."))
+		return
+	}
+	if authorizeContext.CodeChallenge == "" && client.Public {
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidGrant("Code verifier required."))
+		return
+	}
+
 	defer func() {
 		// The client MUST NOT use the authorization code more than once.
 		if err = h.tokenOperator.Revoke(code); err != nil {
@@ -583,43 +802,56 @@ func (h *handler) codeGrant(req *restful.Request, response *restful.Response, cl
 		}
 	}()
 
-	result, err := h.issueTokenTo(authorizeContext.User, client)
+	// The user authorized by a token issued before enabling TOTP steps up to the second factor.
+	if h.challengeMFA(response, &mfaChallengeRequest{
+		authenticated: authorizeContext.User,
+		client:        client,
+		scopes:        authorizeContext.Scopes,
+		nonce:         authorizeContext.Nonce,
+	}) {
+		return
+	}
+
+	result, err := h.issueTokensTo(authorizeContext.User, client, authorizeContext.Scopes, authorizeContext.Nonce, newSession(req, ""))
 	if err != nil {
 		klog.Errorf("failed to issue token: %s", err)
 		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
 		return
 	}
 
+	_ = response.WriteEntity(result)
+}
+
+// issueTokensTo issues tokens to the user, and an ID token as well if the openid scope is requested.
+func (h *handler) issueTokensTo(authenticated user.Info, client *oauth.Client, scopes []string, nonce string, session *auth.Session) (*oauth.Token, error) {
+	result, err := h.issueTokenTo(authenticated, client, session)
+	if err != nil {
+		return nil, err
+	}
+
 	// If no openid scope value is present, the request may still be a valid OAuth 2.0 request,
 	// but is not an OpenID Connect request.
-	if !sliceutil.HasString(authorizeContext.Scopes, oauth.ScopeOpenID) {
-		_ = response.WriteEntity(result)
-		return
+	if !sliceutil.HasString(scopes, oauth.ScopeOpenID) {
+		return result, nil
 	}
 
 	idTokenRequest, err := h.buildIDTokenIssueRequest(&idTokenRequest{
-		authenticated: authorizeContext.User,
+		authenticated: authenticated,
 		client:        client,
-		scopes:        authorizeContext.Scopes,
-		nonce:         authorizeContext.Nonce,
+		scopes:        scopes,
+		nonce:         nonce,
 	})
-
 	if err != nil {
-		klog.Errorf("failed to build id token request: %s", err)
-		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
-		return
+		return nil, fmt.Errorf("failed to build id token request: %s", err)
 	}
 
 	idToken, err := h.tokenOperator.IssueTo(idTokenRequest)
 	if err != nil {
-		klog.Errorf("failed to issue id token: %s", err)
-		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
-		return
+		return nil, fmt.Errorf("failed to issue id token: %s", err)
 	}
 
 	result.IDToken = idToken
-
-	_ = response.WriteEntity(result)
+	return result, nil
 }
 
 func (h *handler) buildIDTokenIssueRequest(request *idTokenRequest) (*token.IssueRequest, error) {
@@ -672,6 +904,15 @@ func (h *handler) logout(req *restful.Request, resp *restful.Response) {
 	}
 
 	accessToken := parts[1]
+	// log out the session of the access token, so that the refresh token is invalidated as well
+	if verified, err := h.tokenOperator.Verify(accessToken); err == nil && verified.SessionID != "" {
+		if err = h.tokenOperator.RevokeSession(verified.User.GetName(), verified.SessionID); err != nil &&
+			!errors.Is(err, auth.SessionNotFoundError) {
+			klog.Errorf("failed to revoke session: %s", err)
+			api.HandleInternalError(resp, req, fmt.Errorf("failed to revoke session"))
+			return
+		}
+	}
 	if err := h.tokenOperator.Revoke(accessToken); err != nil {
 		reason := fmt.Errorf("failed to revoke access token")
 		klog.Errorf("%s: %s", reason, err)
@@ -735,6 +976,9 @@ type authCodeRequest struct {
 	scopes        []string
 	redirectURL   *url.URL
 	state         string
+
+	codeChallenge       string
+	codeChallengeMethod string
 }
 
 func (h *handler) handleAuthorizationCodeRequest(req *restful.Request, response *restful.Response, authCodeRequest authCodeRequest) {
@@ -744,9 +988,11 @@ func (h *handler) handleAuthorizationCodeRequest(req *restful.Request, response
 			RegisteredClaims: jwt.RegisteredClaims{
 				Audience: []string{authCodeRequest.clientID},
 			},
-			TokenType: token.AuthorizationCode,
-			Nonce:     authCodeRequest.nonce,
-			Scopes:    authCodeRequest.scopes,
+			TokenType:           token.AuthorizationCode,
+			Nonce:               authCodeRequest.nonce,
+			Scopes:              authCodeRequest.scopes,
+			CodeChallenge:       authCodeRequest.codeChallenge,
+			CodeChallengeMethod: authCodeRequest.codeChallengeMethod,
 		},
 		// A maximum authorization code lifetime of 10 minutes is
 		ExpiresIn: 10 * time.Minute,
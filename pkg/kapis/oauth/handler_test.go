@@ -0,0 +1,67 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package oauth
+
+import (
+	"context"
+	"errors"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/oauth"
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/token"
+	"kubesphere.io/kubesphere/pkg/models/auth"
+)
+
+// fakeTokenOperator verifies the tokens issued in advance, and records the tokens revoked.
+type fakeTokenOperator struct {
+	auth.TokenManagementInterface
+	verified map[string]*token.VerifiedResponse
+	revoked  []string
+	// revokedSessions are the revoked sessions in the format of username/session
+	revokedSessions []string
+}
+
+func (f *fakeTokenOperator) Verify(tokenStr string) (*token.VerifiedResponse, error) {
+	verified, ok := f.verified[tokenStr]
+	if !ok {
+		return nil, errors.New("token not found")
+	}
+	return verified, nil
+}
+
+func (f *fakeTokenOperator) Revoke(tokenStr string) error {
+	f.revoked = append(f.revoked, tokenStr)
+	delete(f.verified, tokenStr)
+	return nil
+}
+
+func (f *fakeTokenOperator) RevokeSession(username, id string) error {
+	f.revokedSessions = append(f.revokedSessions, username+"/"+id)
+	return nil
+}
+
+type fakeClientGetter struct {
+	oauth.ClientGetter
+	clients []*oauth.Client
+}
+
+func (f *fakeClientGetter) GetOAuthClient(_ context.Context, name string) (*oauth.Client, error) {
+	for _, client := range f.clients {
+		if client.Name == name {
+			return client, nil
+		}
+	}
+	return nil, oauth.ErrorClientNotFound
+}
+
+func accessToken(username, clientID, sessionID string) *token.VerifiedResponse {
+	return &token.VerifiedResponse{
+		User:   &user.DefaultInfo{Name: username},
+		Claims: token.Claims{TokenType: token.AccessToken, ClientID: clientID, SessionID: sessionID},
+	}
+}
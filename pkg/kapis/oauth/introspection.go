@@ -0,0 +1,181 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/emicklei/go-restful/v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/oauth"
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/token"
+	"kubesphere.io/kubesphere/pkg/models/auth"
+	"kubesphere.io/kubesphere/pkg/utils/serviceaccount"
+)
+
+// introspect allows the protected resources, such as the services behind the reverse proxy of ks-apiserver,
+// to query the active state and the meta-information of a token. Only confidential clients can introspect tokens.
+// For more details, refer to: https://datatracker.ietf.org/doc/html/rfc7662
+func (h *handler) introspect(req *restful.Request, response *restful.Response) {
+	response.Header().Set("Cache-Control", "no-store")
+	response.Header().Set("Pragma", "no-cache")
+
+	client, public, ok := h.authenticateClient(req, response)
+	if !ok {
+		return
+	}
+	if public {
+		_ = response.WriteHeaderAndEntity(http.StatusUnauthorized, oauth.NewError(oauth.UnauthorizedClient, "Invalid client credential."))
+		return
+	}
+
+	tokenStr, _ := req.BodyParameter("token")
+	if tokenStr == "" {
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidRequest("The token is empty or missing."))
+		return
+	}
+
+	inactive := oauth.Introspection{Active: false}
+	verified, err := h.tokenOperator.Verify(tokenStr)
+	if err != nil {
+		_ = response.WriteEntity(inactive)
+		return
+	}
+	switch verified.TokenType {
+	case token.AccessToken, token.StaticToken:
+	case token.RefreshToken:
+		// The refresh token is only meaningful to the client it's issued to.
+		if verified.ClientID != "" && verified.ClientID != client.Name {
+			_ = response.WriteEntity(inactive)
+			return
+		}
+	default:
+		_ = response.WriteEntity(inactive)
+		return
+	}
+	// The token of a user who hasn't passed the MFA policy isn't accepted by ks-apiserver either.
+	if err = h.verifyAuthenticationMethods(req.Request.Context(), verified.User); err != nil {
+		_ = response.WriteEntity(inactive)
+		return
+	}
+	active, err := h.identityActive(req.Request.Context(), verified)
+	if err != nil {
+		klog.Errorf("failed to check the identity of the token: %s", err)
+		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
+		return
+	}
+	if !active {
+		_ = response.WriteEntity(inactive)
+		return
+	}
+
+	result := oauth.Introspection{
+		Active:   true,
+		Scope:    strings.Join(verified.Scopes, " "),
+		ClientID: verified.ClientID,
+		Username: verified.User.GetName(),
+		Subject:  verified.Subject,
+		Audience: verified.Audience,
+		Issuer:   verified.Issuer,
+	}
+	if verified.TokenType != token.RefreshToken {
+		result.TokenType = "Bearer"
+	}
+	if verified.ExpiresAt != nil {
+		result.Expiry = verified.ExpiresAt.Unix()
+	}
+	if verified.IssuedAt != nil {
+		result.IssuedAt = verified.IssuedAt.Unix()
+	}
+	if verified.NotBefore != nil {
+		result.NotBefore = verified.NotBefore.Unix()
+	}
+	_ = response.WriteEntity(result)
+}
+
+// identityActive checks the identity of the token as the token authenticator of ks-apiserver does,
+// the tokens of the disabled or removed users and of the removed clients are no longer active.
+func (h *handler) identityActive(ctx context.Context, verified *token.VerifiedResponse) (bool, error) {
+	username := verified.User.GetName()
+	if serviceaccount.IsServiceAccountToken(verified.Subject) || username == iamv1beta1.PreRegistrationUser {
+		return true, nil
+	}
+	if oauth.IsClientIdentity(username) {
+		if _, err := h.clientGetter.GetOAuthClient(ctx, strings.TrimPrefix(username, oauth.ClientIdentityPrefix)); err != nil {
+			if errors.Is(err, oauth.ErrorClientNotFound) {
+				return false, nil
+			}
+			return false, err
+		}
+		return true, nil
+	}
+	user, err := h.im.DescribeUser(username)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	// the user can only change the password until it's reset, which isn't a use of the resource servers
+	return user.Status.State != iamv1beta1.UserDisabled && user.Status.State != iamv1beta1.UserPasswordResetRequired, nil
+}
+
+// revoke allows the clients to notify the authorization server that an access token or a refresh token
+// is no longer needed, the token can only be revoked by the client it's issued to.
+// For more details, refer to: https://datatracker.ietf.org/doc/html/rfc7009
+func (h *handler) revoke(req *restful.Request, response *restful.Response) {
+	client, _, ok := h.authenticateClient(req, response)
+	if !ok {
+		return
+	}
+
+	tokenStr, _ := req.BodyParameter("token")
+	if tokenStr == "" {
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidRequest("The token is empty or missing."))
+		return
+	}
+
+	// Invalid tokens do not cause an error response since the client cannot handle such an error in a reasonable way,
+	// the purpose of the revocation request, invalidating the particular token, is already achieved.
+	verified, err := h.tokenOperator.Verify(tokenStr)
+	if err != nil {
+		response.WriteHeader(http.StatusOK)
+		return
+	}
+	if verified.TokenType != token.AccessToken && verified.TokenType != token.RefreshToken {
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewError(oauth.UnsupportedTokenType, "The revocation of the presented token type is not supported."))
+		return
+	}
+	// The tokens not issued to any client, such as the tokens of the console session, can't be revoked by the clients.
+	if verified.ClientID != client.Name {
+		klog.Warningf("The client %s is not allowed to revoke the token issued to %q.", client.Name, verified.ClientID)
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewError(oauth.UnauthorizedClient, "The token was issued to another client."))
+		return
+	}
+
+	if err = h.tokenOperator.Revoke(tokenStr); err != nil {
+		klog.Errorf("failed to revoke token: %s", err)
+		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
+		return
+	}
+	// The access tokens issued with the refresh token are revoked as well by revoking the session of them,
+	// see https://datatracker.ietf.org/doc/html/rfc7009#section-2.1
+	if verified.TokenType == token.RefreshToken && verified.SessionID != "" {
+		if err = h.tokenOperator.RevokeSession(verified.User.GetName(), verified.SessionID); err != nil && !errors.Is(err, auth.SessionNotFoundError) {
+			klog.Errorf("failed to revoke session: %s", err)
+			_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
+			return
+		}
+	}
+	response.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,233 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package oauth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/google/go-cmp/cmp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apiserver/pkg/authentication/user"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	runtimefakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/oauth"
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/token"
+	"kubesphere.io/kubesphere/pkg/models/iam/im"
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func newIntrospectionHandler() (*handler, *fakeTokenOperator) {
+	tokenOperator := &fakeTokenOperator{verified: map[string]*token.VerifiedResponse{
+		"kubectl-access-token": accessToken("admin", "kubectl", "session"),
+		"kubectl-refresh-token": {
+			User:   &user.DefaultInfo{Name: "admin"},
+			Claims: token.Claims{TokenType: token.RefreshToken, ClientID: "kubectl", SessionID: "session"},
+		},
+		"console-access-token":        accessToken("admin", "", "session"),
+		"cli-access-token":            accessToken("admin", "cli", "session"),
+		"disabled-user-access-token":  accessToken("tester", "kubectl", "session"),
+		"reset-user-access-token":     accessToken("reset", "kubectl", "session"),
+		"deleted-user-access-token":   accessToken("deleted", "kubectl", "session"),
+		"client-access-token":         accessToken(oauth.ClientIdentityPrefix+"grafana", "grafana", ""),
+		"deleted-client-access-token": accessToken(oauth.ClientIdentityPrefix+"deleted", "deleted", ""),
+	}}
+	client := runtimefakeclient.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(
+			&iamv1beta1.User{ObjectMeta: metav1.ObjectMeta{Name: "admin"}, Status: iamv1beta1.UserStatus{State: iamv1beta1.UserActive}},
+			&iamv1beta1.User{ObjectMeta: metav1.ObjectMeta{Name: "tester"}, Status: iamv1beta1.UserStatus{State: iamv1beta1.UserDisabled}},
+			&iamv1beta1.User{ObjectMeta: metav1.ObjectMeta{Name: "reset"}, Status: iamv1beta1.UserStatus{State: iamv1beta1.UserPasswordResetRequired}},
+		).
+		Build()
+	return &handler{
+		im:            im.NewOperator(client, nil, nil),
+		tokenOperator: tokenOperator,
+		clientGetter: &fakeClientGetter{clients: []*oauth.Client{
+			{Name: "kubectl", Secret: "kubectl-secret"},
+			{Name: "grafana", Secret: "grafana-secret"},
+			{Name: "cli", Public: true},
+		}},
+	}, tokenOperator
+}
+
+func serveForm(handle restful.RouteFunction, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/oauth/introspect", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	response := restful.NewResponse(recorder)
+	response.SetRequestAccepts(restful.MIME_JSON)
+	handle(restful.NewRequest(req), response)
+	return recorder
+}
+
+func TestIntrospect(t *testing.T) {
+	h, _ := newIntrospectionHandler()
+	tests := []struct {
+		name           string
+		form           url.Values
+		expectedStatus int
+		expected       *oauth.Introspection
+	}{
+		{
+			name:           "active access token",
+			form:           url.Values{"client_id": {"grafana"}, "client_secret": {"grafana-secret"}, "token": {"kubectl-access-token"}},
+			expectedStatus: http.StatusOK,
+			expected:       &oauth.Introspection{Active: true, ClientID: "kubectl", Username: "admin", TokenType: "Bearer"},
+		},
+		{
+			name:           "active refresh token of the client",
+			form:           url.Values{"client_id": {"kubectl"}, "client_secret": {"kubectl-secret"}, "token": {"kubectl-refresh-token"}},
+			expectedStatus: http.StatusOK,
+			expected:       &oauth.Introspection{Active: true, ClientID: "kubectl", Username: "admin"},
+		},
+		{
+			name:           "refresh token of another client",
+			form:           url.Values{"client_id": {"grafana"}, "client_secret": {"grafana-secret"}, "token": {"kubectl-refresh-token"}},
+			expectedStatus: http.StatusOK,
+			expected:       &oauth.Introspection{Active: false},
+		},
+		{
+			name:           "inactive token",
+			form:           url.Values{"client_id": {"grafana"}, "client_secret": {"grafana-secret"}, "token": {"expired"}},
+			expectedStatus: http.StatusOK,
+			expected:       &oauth.Introspection{Active: false},
+		},
+		{
+			name:           "token of a disabled user",
+			form:           url.Values{"client_id": {"grafana"}, "client_secret": {"grafana-secret"}, "token": {"disabled-user-access-token"}},
+			expectedStatus: http.StatusOK,
+			expected:       &oauth.Introspection{Active: false},
+		},
+		{
+			name:           "token of a user required to reset the password",
+			form:           url.Values{"client_id": {"grafana"}, "client_secret": {"grafana-secret"}, "token": {"reset-user-access-token"}},
+			expectedStatus: http.StatusOK,
+			expected:       &oauth.Introspection{Active: false},
+		},
+		{
+			name:           "token of a deleted user",
+			form:           url.Values{"client_id": {"grafana"}, "client_secret": {"grafana-secret"}, "token": {"deleted-user-access-token"}},
+			expectedStatus: http.StatusOK,
+			expected:       &oauth.Introspection{Active: false},
+		},
+		{
+			name:           "token of a client",
+			form:           url.Values{"client_id": {"kubectl"}, "client_secret": {"kubectl-secret"}, "token": {"client-access-token"}},
+			expectedStatus: http.StatusOK,
+			expected:       &oauth.Introspection{Active: true, ClientID: "grafana", Username: oauth.ClientIdentityPrefix + "grafana", TokenType: "Bearer"},
+		},
+		{
+			name:           "token of a deleted client",
+			form:           url.Values{"client_id": {"kubectl"}, "client_secret": {"kubectl-secret"}, "token": {"deleted-client-access-token"}},
+			expectedStatus: http.StatusOK,
+			expected:       &oauth.Introspection{Active: false},
+		},
+		{
+			name:           "public client",
+			form:           url.Values{"client_id": {"cli"}, "token": {"kubectl-access-token"}},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "invalid client credential",
+			form:           url.Values{"client_id": {"grafana"}, "client_secret": {"invalid"}, "token": {"kubectl-access-token"}},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			recorder := serveForm(h.introspect, test.form)
+			if recorder.Code != test.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", test.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+			if test.expected == nil {
+				return
+			}
+			result := &oauth.Introspection{}
+			if err := json.Unmarshal(recorder.Body.Bytes(), result); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.expected, result); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	tests := []struct {
+		name           string
+		form           url.Values
+		expectedStatus int
+		revoked        bool
+		sessionRevoked bool
+	}{
+		{
+			name:           "token of the client",
+			form:           url.Values{"client_id": {"kubectl"}, "client_secret": {"kubectl-secret"}, "token": {"kubectl-refresh-token"}},
+			expectedStatus: http.StatusOK,
+			revoked:        true,
+			sessionRevoked: true,
+		},
+		{
+			name:           "token of the public client",
+			form:           url.Values{"client_id": {"cli"}, "token": {"cli-access-token"}},
+			expectedStatus: http.StatusOK,
+			revoked:        true,
+		},
+		{
+			name:           "token of another client",
+			form:           url.Values{"client_id": {"grafana"}, "client_secret": {"grafana-secret"}, "token": {"kubectl-access-token"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "token of another client by the public client",
+			form:           url.Values{"client_id": {"cli"}, "token": {"kubectl-access-token"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "token not issued to any client",
+			form:           url.Values{"client_id": {"kubectl"}, "client_secret": {"kubectl-secret"}, "token": {"console-access-token"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "inactive token",
+			form:           url.Values{"client_id": {"kubectl"}, "client_secret": {"kubectl-secret"}, "token": {"expired"}},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid client credential",
+			form:           url.Values{"client_id": {"kubectl"}, "client_secret": {"invalid"}, "token": {"kubectl-access-token"}},
+			expectedStatus: http.StatusUnauthorized,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			h, tokenOperator := newIntrospectionHandler()
+			recorder := serveForm(h.revoke, test.form)
+			if recorder.Code != test.expectedStatus {
+				t.Fatalf("expected status %d, got %d: %s", test.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+			if revoked := len(tokenOperator.revoked) > 0; revoked != test.revoked {
+				t.Errorf("expected the token revoked %v, got %v", test.revoked, revoked)
+			}
+			// the access tokens are revoked along with the refresh token by revoking the session
+			if sessionRevoked := len(tokenOperator.revokedSessions) > 0; sessionRevoked != test.sessionRevoked {
+				t.Errorf("expected the session revoked %v, got %v", test.sessionRevoked, sessionRevoked)
+			}
+			if test.sessionRevoked && tokenOperator.revokedSessions[0] != "admin/session" {
+				t.Errorf("expected the session admin/session to be revoked, got %v", tokenOperator.revokedSessions)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package oauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/emicklei/go-restful/v3"
+	"github.com/golang-jwt/jwt/v4"
+	"k8s.io/apiserver/pkg/authentication/user"
+	"k8s.io/klog/v2"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/oauth"
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/token"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+	"kubesphere.io/kubesphere/pkg/models/auth"
+	"kubesphere.io/kubesphere/pkg/utils/sliceutil"
+)
+
+// mfaTokenMaxAge is the time the End-User has to enter a one-time password after the first factor.
+const mfaTokenMaxAge = 5 * time.Minute
+
+// MFAChallenge is responded instead of tokens if the End-User has enabled TOTP,
+// the client exchanges the MFA token and a one-time password for tokens with the otp grant.
+type MFAChallenge struct {
+	oauth.Error
+	MFAToken  string `json:"mfa_token"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+type mfaChallengeRequest struct {
+	authenticated user.Info
+	client        *oauth.Client
+	provider      string
+	scopes        []string
+	nonce         string
+}
+
+// challengeMFA responds with an MFA challenge if the authenticated user has enabled TOTP
+// but hasn't passed it, it returns false if the response hasn't been written.
+func (h *handler) challengeMFA(response *restful.Response, challenge *mfaChallengeRequest) bool {
+	if h.totpOperator == nil || authenticatedByOTP(challenge.authenticated) {
+		return false
+	}
+	enabled, err := h.totpOperator.Enabled(context.Background(), challenge.authenticated.GetName())
+	if err != nil {
+		klog.Errorf("failed to get totp status of user %s: %s", challenge.authenticated.GetName(), err)
+		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
+		return true
+	}
+	if !enabled {
+		return false
+	}
+
+	extra := map[string][]string{}
+	for k, v := range challenge.authenticated.GetExtra() {
+		extra[k] = v
+	}
+	if challenge.provider != "" {
+		extra[iamv1beta1.ExtraIdentityProvider] = []string{challenge.provider}
+	}
+	claims := token.Claims{
+		TokenType: token.MFAToken,
+		Scopes:    challenge.scopes,
+		Nonce:     challenge.nonce,
+	}
+	if challenge.client != nil {
+		claims.Audience = jwt.ClaimStrings{challenge.client.Name}
+	}
+	mfaToken, err := h.tokenOperator.IssueTo(&token.IssueRequest{
+		User: &user.DefaultInfo{
+			Name:   challenge.authenticated.GetName(),
+			Groups: challenge.authenticated.GetGroups(),
+			Extra:  extra,
+		},
+		Claims:    claims,
+		ExpiresIn: mfaTokenMaxAge,
+	})
+	if err != nil {
+		klog.Errorf("failed to issue mfa token: %s", err)
+		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
+		return true
+	}
+	_ = response.WriteHeaderAndEntity(http.StatusUnauthorized, MFAChallenge{
+		Error:     *oauth.NewError(oauth.MFARequired, "Multi-factor authentication is required."),
+		MFAToken:  mfaToken,
+		ExpiresIn: int(mfaTokenMaxAge.Seconds()),
+	})
+	return true
+}
+
+// otpGrant completes the authentication challenged by challengeMFA with a one-time password
+// or a recovery code of the End-User.
+func (h *handler) otpGrant(req *restful.Request, response *restful.Response, client *oauth.Client) {
+	mfaToken, _ := req.BodyParameter("mfa_token")
+	otp, _ := req.BodyParameter("otp")
+	if h.totpOperator == nil {
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewError(oauth.UnsupportedGrantType, "The provided grant_type is not supported."))
+		return
+	}
+	if otp == "" {
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidRequest("The one-time password is empty or missing."))
+		return
+	}
+
+	verified, err := h.tokenOperator.Verify(mfaToken)
+	if err != nil || verified.TokenType != token.MFAToken ||
+		(len(verified.Audience) > 0 && !sliceutil.HasString(verified.Audience, client.Name)) {
+		_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidGrant("The MFA token is invalid or expired."))
+		return
+	}
+
+	username := verified.User.GetName()
+	var provider string
+	extra := map[string][]string{}
+	for k, v := range verified.User.GetExtra() {
+		if k == iamv1beta1.ExtraIdentityProvider {
+			provider = v[0]
+			continue
+		}
+		extra[k] = v
+	}
+	requestInfo, _ := request.RequestInfoFrom(req.Request.Context())
+
+	if err = h.totpOperator.Verify(req.Request.Context(), username, otp); err != nil {
+		switch {
+		case errors.Is(err, auth.IncorrectOTPError):
+			if err := h.loginRecorder.RecordLogin(req.Request.Context(), username, iamv1beta1.Token, provider, requestInfo.SourceIP, requestInfo.UserAgent, err); err != nil {
+				klog.Errorf("Failed to record unsuccessful login attempt for user %s, error: %v", username, err)
+			}
+			_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidGrant("Invalid one-time password."))
+		case errors.Is(err, auth.RateLimitExceededError):
+			_ = response.WriteHeaderAndEntity(http.StatusTooManyRequests, oauth.NewInvalidGrant("Rate limit exceeded."))
+		case errors.Is(err, auth.AccountIsNotActiveError):
+			_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidGrant("Account suspended."))
+		case errors.Is(err, auth.TOTPNotEnabledError):
+			_ = response.WriteHeaderAndEntity(http.StatusBadRequest, oauth.NewInvalidGrant("The MFA token is invalid or expired."))
+		default:
+			klog.Errorf("Failed to verify one-time password: %s", err)
+			_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
+		}
+		return
+	}
+
+	// The MFA token MUST NOT be used more than once.
+	if err = h.tokenOperator.Revoke(mfaToken); err != nil {
+		klog.Warningf("grant: failed to revoke mfa token: %v", err)
+	}
+
+	extra[iamv1beta1.ExtraAuthenticationMethods] = []string{iamv1beta1.AuthenticationMethodOTP}
+	authenticated := &user.DefaultInfo{Name: username, Groups: verified.User.GetGroups(), Extra: extra}
+	result, err := h.issueTokensTo(authenticated, client, verified.Scopes, verified.Nonce, newSession(req, provider))
+	if err != nil {
+		klog.Errorf("Failed to issue token: %s", err)
+		_ = response.WriteHeaderAndEntity(http.StatusInternalServerError, oauth.NewServerError(internalServerErrorMessage))
+		return
+	}
+
+	if err = h.loginRecorder.RecordLogin(req.Request.Context(), username, iamv1beta1.Token, provider, requestInfo.SourceIP, requestInfo.UserAgent, nil); err != nil {
+		klog.Errorf("Failed to record successful login for user %s, error: %v", username, err)
+	}
+	_ = response.WriteEntity(result)
+}
+
+// withMFAEnrollment marks the authenticated user if the MFA policy requires
+// the user to enable TOTP, so that the console can guide the user to enroll.
+func (h *handler) withMFAEnrollment(authenticated user.Info) (user.Info, error) {
+	if h.totpOperator == nil || authenticatedByOTP(authenticated) {
+		return authenticated, nil
+	}
+	required, err := h.totpOperator.Required(context.Background(), authenticated.GetName())
+	if err != nil || !required {
+		return authenticated, err
+	}
+	extra := map[string][]string{iamv1beta1.ExtraMFAEnrollmentRequired: {"true"}}
+	for k, v := range authenticated.GetExtra() {
+		extra[k] = v
+	}
+	return &user.DefaultInfo{
+		Name:   authenticated.GetName(),
+		UID:    authenticated.GetUID(),
+		Groups: authenticated.GetGroups(),
+		Extra:  extra,
+	}, nil
+}
+
+// verifyAuthenticationMethods checks whether the user of the token has passed the MFA policy.
+func (h *handler) verifyAuthenticationMethods(ctx context.Context, authenticated user.Info) error {
+	if h.totpOperator == nil || authenticatedByOTP(authenticated) {
+		return nil
+	}
+	if len(authenticated.GetExtra()[iamv1beta1.ExtraMFAEnrollmentRequired]) > 0 {
+		return auth.MFARequiredError
+	}
+	enabled, err := h.totpOperator.Enabled(ctx, authenticated.GetName())
+	if err != nil {
+		return err
+	}
+	if enabled {
+		return auth.MFARequiredError
+	}
+	return nil
+}
+
+func authenticatedByOTP(authenticated user.Info) bool {
+	return sliceutil.HasString(authenticated.GetExtra()[iamv1beta1.ExtraAuthenticationMethods], iamv1beta1.AuthenticationMethodOTP)
+}
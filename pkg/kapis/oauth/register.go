@@ -87,7 +87,9 @@ func (h *handler) AddToContainer(c *restful.Container) error {
 			"This URI MUST exactly match one of the Redirection URI values for the Client pre-registered at the OpenID Provider.").Required(true)).
 		Param(ws.QueryParameter("scope", "OpenID Connect requests MUST contain the openid scope value. "+
 			"If the openid scope value is not present, the behavior is entirely unspecified.").Required(false)).
-		Param(ws.QueryParameter("state", "Opaque value used to maintain state between the request and the callback.").Required(false)))
+		Param(ws.QueryParameter("state", "Opaque value used to maintain state between the request and the callback.").Required(false)).
+		Param(ws.QueryParameter("code_challenge", "PKCE code challenge derived from the code verifier, required for public clients.").Required(false)).
+		Param(ws.QueryParameter("code_challenge_method", "PKCE code challenge method, only S256 is supported.").Required(false)))
 
 	// Authorization Servers MUST support the use of the HTTP GET and POST methods
 	// defined in RFC 2616 [RFC2616] at the Authorization Endpoint.
@@ -106,7 +108,9 @@ func (h *handler) AddToContainer(c *restful.Container) error {
 			"This URI MUST exactly match one of the Redirection URI values for the Client pre-registered at the OpenID Provider.").Required(true)).
 		Param(ws.FormParameter("scope", "OpenID Connect requests MUST contain the openid scope value. "+
 			"If the openid scope value is not present, the behavior is entirely unspecified.").Required(false)).
-		Param(ws.FormParameter("state", "Opaque value used to maintain state between the request and the callback.").Required(false)))
+		Param(ws.FormParameter("state", "Opaque value used to maintain state between the request and the callback.").Required(false)).
+		Param(ws.FormParameter("code_challenge", "PKCE code challenge derived from the code verifier, required for public clients.").Required(false)).
+		Param(ws.FormParameter("code_challenge_method", "PKCE code challenge method, only S256 is supported.").Required(false)))
 
 	// https://datatracker.ietf.org/doc/html/rfc6749#section-3.2
 	ws.Route(ws.POST("/token").
@@ -122,12 +126,82 @@ func (h *handler) AddToContainer(c *restful.Container) error {
 			"authorization code, implicit, resource owner password credentials, and client credentials.").
 			Required(true)).
 		Param(ws.FormParameter("client_id", "Valid client credential.").Required(true)).
-		Param(ws.FormParameter("client_secret", "Valid client credential.").Required(true)).
+		Param(ws.FormParameter("client_secret", "Valid client credential, public clients omit it.").Required(false)).
 		Param(ws.FormParameter("username", "The resource owner username.").Required(false)).
 		Param(ws.FormParameter("password", "The resource owner password.").Required(false)).
 		Param(ws.FormParameter("code", "Valid authorization code.").Required(false)).
+		Param(ws.FormParameter("code_verifier", "PKCE code verifier of the authorization code.").Required(false)).
+		Param(ws.FormParameter("device_code", "The device verification code of the device authorization grant.").Required(false)).
 		Returns(http.StatusOK, api.StatusOK, &oauth.Token{}))
 
+	// https://datatracker.ietf.org/doc/html/rfc7662#section-2
+	ws.Route(ws.POST("/introspect").
+		Consumes(contentTypeFormData).
+		To(h.introspect).
+		Doc("Token introspection endpoint").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagAuthentication}).
+		Notes("The introspection endpoint is used by the protected resources to query the active state "+
+			"and the meta-information of a token, only confidential clients are allowed.").
+		Operation("token-introspection").
+		Param(ws.FormParameter("client_id", "OAuth 2.0 Client Identifier valid at the Authorization Server.").Required(true)).
+		Param(ws.FormParameter("client_secret", "Valid client credential.").Required(true)).
+		Param(ws.FormParameter("token", "The string value of the token.").Required(true)).
+		Param(ws.FormParameter("token_type_hint", "A hint about the type of the token, access_token or refresh_token.").Required(false)).
+		Returns(http.StatusOK, api.StatusOK, oauth.Introspection{}))
+
+	// https://datatracker.ietf.org/doc/html/rfc7009#section-2
+	ws.Route(ws.POST("/revoke").
+		Consumes(contentTypeFormData).
+		To(h.revoke).
+		Doc("Token revocation endpoint").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagAuthentication}).
+		Notes("The revocation endpoint is used by the clients to revoke an access token or a refresh token issued to them.").
+		Operation("token-revocation").
+		Param(ws.FormParameter("client_id", "OAuth 2.0 Client Identifier valid at the Authorization Server.").Required(true)).
+		Param(ws.FormParameter("client_secret", "Valid client credential, public clients omit it.").Required(false)).
+		Param(ws.FormParameter("token", "The token that the client wants to get revoked.").Required(true)).
+		Param(ws.FormParameter("token_type_hint", "A hint about the type of the token, access_token or refresh_token.").Required(false)).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), nil))
+
+	// https://datatracker.ietf.org/doc/html/rfc8628#section-3.1
+	ws.Route(ws.POST("/device_authorization").
+		Consumes(contentTypeFormData).
+		To(h.deviceAuthorization).
+		Doc("Device authorization endpoint").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagAuthentication}).
+		Notes("The device authorization endpoint is used by the devices that lack a browser, such as the CLI, "+
+			"to obtain a device code and a user code. The End-User enters the user code on the verification page, "+
+			"while the device polls the token endpoint with the device code.").
+		Operation("device-authorization").
+		Param(ws.FormParameter("client_id", "OAuth 2.0 Client Identifier valid at the Authorization Server.").Required(true)).
+		Param(ws.FormParameter("client_secret", "Valid client credential, public clients omit it.").Required(false)).
+		Param(ws.FormParameter("scope", "The scope of the access request.").Required(false)).
+		Returns(http.StatusOK, api.StatusOK, oauth.DeviceAuthorization{}))
+
+	// https://datatracker.ietf.org/doc/html/rfc8628#section-3.3
+	ws.Route(ws.GET(deviceVerificationPath).
+		To(h.deviceVerification).
+		Produces("text/html").
+		Doc("Device verification page").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagAuthentication}).
+		Notes("The page for the authenticated End-User to review the device authorization request of the user code.").
+		Operation("device-verification-get").
+		Param(ws.QueryParameter("user_code", "The user code displayed on the device.").Required(false)).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), ""))
+	ws.Route(ws.POST(deviceVerificationPath).
+		Consumes(contentTypeFormData).
+		Produces("text/html").
+		To(h.deviceVerification).
+		Doc("Device verification").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagAuthentication}).
+		Notes("Approve or deny the device authorization request of the user code, "+
+			"the request must be posted from the verification page.").
+		Operation("device-verification-post").
+		Param(ws.FormParameter("user_code", "The user code displayed on the device.").Required(true)).
+		Param(ws.FormParameter("action", "approve or deny.").Required(true)).
+		Param(ws.FormParameter("verification_token", "The token issued with the verification page.").Required(true)).
+		Returns(http.StatusOK, http.StatusText(http.StatusOK), ""))
+
 	// Authorization callback URL, where the end of the URL contains the identity provider name.
 	// The provider name is also used to build the callback URL.
 	ws.Route(ws.GET("/callback/{callback}").
@@ -151,6 +225,29 @@ func (h *handler) AddToContainer(c *restful.Container) error {
 			"The exact value received from the client.").Required(true)).
 		Returns(http.StatusOK, api.StatusOK, oauth.Token{}))
 
+	// The SAML identity providers post the response to the Assertion Consumer Service, which is the callback URL.
+	ws.Route(ws.POST("/callback/{callback}").
+		Consumes(contentTypeFormData).
+		To(h.oauthCallback).
+		Doc("SAML assertion consumer service").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagAuthentication}).
+		Notes("The SAML identity provider posts the SAML response to this endpoint via the HTTP-POST binding.").
+		Operation("oauth-callback-post").
+		Param(ws.PathParameter("callback", "The identity provider name.")).
+		Param(ws.FormParameter("SAMLResponse", "The base64 encoded SAML response.").Required(true)).
+		Param(ws.FormParameter("RelayState", "The RelayState of the authentication request.").Required(false)).
+		Returns(http.StatusOK, api.StatusOK, oauth.Token{}))
+
+	ws.Route(ws.GET("/callback/{callback}/metadata").
+		To(h.oauthCallbackMetadata).
+		Produces("application/samlmetadata+xml", restful.MIME_XML).
+		Doc("Service provider metadata").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagAuthentication}).
+		Notes("The metadata of KubeSphere as a service provider, which is registered at the identity provider, such as SAML.").
+		Operation("oauth-callback-metadata").
+		Param(ws.PathParameter("callback", "The identity provider name.")).
+		Returns(http.StatusOK, api.StatusOK, ""))
+
 	// https://openid.net/specs/openid-connect-rpinitiated-1_0.html
 	ws.Route(ws.GET("/logout").
 		To(h.logout).
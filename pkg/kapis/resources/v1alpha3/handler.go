@@ -26,6 +26,7 @@ import (
 	"kubesphere.io/kubesphere/pkg/models/registries/imagesearch/harbor"
 	v2 "kubesphere.io/kubesphere/pkg/models/registries/v2"
 	resourcev1alpha3 "kubesphere.io/kubesphere/pkg/models/resources/v1alpha3/resource"
+	"kubesphere.io/kubesphere/pkg/models/resources/watcher"
 	"kubesphere.io/kubesphere/pkg/simple/client/overview"
 )
 
@@ -74,13 +75,25 @@ func (h *handler) GetResources(request *restful.Request, response *restful.Respo
 	response.WriteEntity(result)
 }
 
-// ListResources retrieves resources
+// ListResources retrieves resources, or the counts of them grouped by a field or a label if aggregated,
+// or streams the changes of them if watched
 func (h *handler) ListResources(request *restful.Request, response *restful.Response) {
-	query := query.ParseQueryParameter(request)
+	q := query.ParseQueryParameter(request)
 	resourceType := request.PathParameter("resources")
 	namespace := request.PathParameter("namespace")
 
-	result, err := h.resourceGetterV1alpha3.List(resourceType, namespace, query)
+	if query.IsWatch(request) {
+		h.watchResources(request, response, resourceType, namespace, q)
+		return
+	}
+
+	var result interface{}
+	var err error
+	if aggregation := query.ParseAggregation(request); aggregation != nil {
+		result, err = h.resourceGetterV1alpha3.Aggregate(resourceType, namespace, q, aggregation)
+	} else {
+		result, err = h.resourceGetterV1alpha3.List(resourceType, namespace, q)
+	}
 	if err != nil {
 		if err == resourcev1alpha3.ErrResourceNotSupported {
 			api.HandleNotFound(response, request, err)
@@ -94,6 +107,25 @@ func (h *handler) ListResources(request *restful.Request, response *restful.Resp
 	response.WriteEntity(result)
 }
 
+// watchResources streams the events of the resources matching the query as the server-sent events,
+// the clients reconnecting with the Last-Event-ID header resume the watch following the event.
+func (h *handler) watchResources(request *restful.Request, response *restful.Response, resourceType, namespace string, q *query.Query) {
+	events, err := h.resourceGetterV1alpha3.Watch(request.Request.Context(), resourceType, namespace, q,
+		request.HeaderParameter(watcher.LastEventIDHeader))
+	if err != nil {
+		switch err {
+		case resourcev1alpha3.ErrResourceNotSupported:
+			api.HandleNotFound(response, request, err)
+		case resourcev1alpha3.ErrWatchNotSupported:
+			api.HandleBadRequest(response, request, err)
+		default:
+			api.HandleError(response, request, err)
+		}
+		return
+	}
+	watcher.ServeSSE(response, events)
+}
+
 func (h *handler) GetComponentStatus(request *restful.Request, response *restful.Response) {
 	component := request.PathParameter("component")
 	result, err := h.componentsGetter.GetComponentStatus(component)
@@ -24,6 +24,7 @@ import (
 	"kubesphere.io/kubesphere/pkg/models/components"
 	v2 "kubesphere.io/kubesphere/pkg/models/registries/v2"
 	resourcev1alpha3 "kubesphere.io/kubesphere/pkg/models/resources/v1alpha3/resource"
+	"kubesphere.io/kubesphere/pkg/models/resources/watcher"
 	"kubesphere.io/kubesphere/pkg/simple/client/overview"
 
 	"kubesphere.io/kubesphere/pkg/models/registries/imagesearch"
@@ -68,8 +69,13 @@ func (h *handler) AddToContainer(c *restful.Container) error {
 		Param(ws.QueryParameter(query.ParameterName, "name used to do filtering").Required(false)).
 		Param(ws.QueryParameter(query.ParameterPage, "page").Required(false).DataFormat("page=%d").DefaultValue("page=1")).
 		Param(ws.QueryParameter(query.ParameterLimit, "limit").Required(false)).
+		Param(ws.QueryParameter(query.ParameterContinue, "the continue token returned along with the previous page, the page following it is returned regardless of the page parameter").Required(false)).
+		Param(ws.QueryParameter(query.ParameterAggregateBy, "the field or the label prefixed by label: to group the resources by, the counts of the resources grouped are returned instead if set, e.g. aggregateBy=status, aggregateBy=label:app").Required(false)).
+		Param(ws.QueryParameter(query.ParameterSumRequests, "sum up the resource requests of the containers of the resources grouped").Required(false).DefaultValue("sumRequests=false")).
+		Param(ws.QueryParameter(query.ParameterWatch, "stream the added, modified and deleted events of the resources matching the filters as the server-sent events instead, the pagination and the sorting are ignored").Required(false).DefaultValue("watch=false")).
 		Param(ws.QueryParameter(query.ParameterAscending, "sort parameters, e.g. reverse=true").Required(false).DefaultValue("ascending=false")).
 		Param(ws.QueryParameter(query.ParameterOrderBy, "sort parameters, e.g. orderBy=createTime")).
+		Produces(restful.MIME_JSON, watcher.MIMEEventStream).
 		Returns(http.StatusOK, api.StatusOK, api.ListResult{}))
 
 	ws.Route(ws.GET("/{resources}/{name}").
@@ -93,9 +99,14 @@ func (h *handler) AddToContainer(c *restful.Container) error {
 		Param(ws.QueryParameter(query.ParameterName, "name used to do filtering").Required(false)).
 		Param(ws.QueryParameter(query.ParameterPage, "page").Required(false).DataFormat("page=%d").DefaultValue("page=1")).
 		Param(ws.QueryParameter(query.ParameterLimit, "limit").Required(false)).
+		Param(ws.QueryParameter(query.ParameterContinue, "the continue token returned along with the previous page, the page following it is returned regardless of the page parameter").Required(false)).
+		Param(ws.QueryParameter(query.ParameterAggregateBy, "the field or the label prefixed by label: to group the resources by, the counts of the resources grouped are returned instead if set, e.g. aggregateBy=status, aggregateBy=label:app").Required(false)).
+		Param(ws.QueryParameter(query.ParameterSumRequests, "sum up the resource requests of the containers of the resources grouped").Required(false).DefaultValue("sumRequests=false")).
+		Param(ws.QueryParameter(query.ParameterWatch, "stream the added, modified and deleted events of the resources matching the filters as the server-sent events instead, the pagination and the sorting are ignored").Required(false).DefaultValue("watch=false")).
 		Param(ws.QueryParameter(query.ParameterAscending, "sort parameters, e.g. reverse=true").Required(false).DefaultValue("ascending=false")).
 		Param(ws.QueryParameter(query.ParameterOrderBy, "sort parameters, e.g. orderBy=createTime")).
 		Param(ws.QueryParameter(query.ParameterFieldSelector, "field selector used for filtering, you can use the = , == and != operators with field selectors( = and == mean the same thing), e.g. fieldSelector=type=kubernetes.io/dockerconfigjson, multiple separated by comma").Required(false)).
+		Produces(restful.MIME_JSON, watcher.MIMEEventStream).
 		Returns(http.StatusOK, api.StatusOK, api.ListResult{}))
 
 	ws.Route(ws.GET("/namespaces/{namespace}/{resources}/{name}").
@@ -0,0 +1,263 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package v2
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/emicklei/go-restful/v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog/v2"
+
+	scimauth "kubesphere.io/kubesphere/pkg/apiserver/authentication/authenticators/scim"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+	"kubesphere.io/kubesphere/pkg/apiserver/rest"
+	"kubesphere.io/kubesphere/pkg/apiserver/runtime"
+	"kubesphere.io/kubesphere/pkg/models/iam/scim"
+)
+
+const (
+	// maxResults is the maximum number of the resources returned in a page.
+	maxResults                 = 1000
+	internalServerErrorMessage = "The server encountered an unexpected condition that prevented it from fulfilling the request."
+)
+
+type handler struct {
+	scim scim.Interface
+}
+
+func NewHandler(scim scim.Interface) rest.Handler {
+	return &handler{scim: scim}
+}
+
+// authenticate only allows the SCIM client authenticated by the dedicated bearer token.
+func (h *handler) authenticate(req *restful.Request, resp *restful.Response, chain *restful.FilterChain) {
+	if user, ok := request.UserFrom(req.Request.Context()); !ok || user.GetName() != scimauth.User {
+		writeError(resp, scim.NewError(http.StatusUnauthorized, "", "The request is not authenticated by the SCIM bearer token."))
+		return
+	}
+	chain.ProcessFilter(req, resp)
+}
+
+func (h *handler) serviceProviderConfig(_ *restful.Request, resp *restful.Response) {
+	writeResource(resp, http.StatusOK, &scim.ServiceProviderConfig{
+		Schemas:        []string{scim.SchemaServiceProviderConfig},
+		Patch:          scim.Supported{Supported: true},
+		Bulk:           scim.BulkSupported{Supported: false},
+		Filter:         scim.FilterSupported{Supported: true, MaxResults: maxResults},
+		ChangePassword: scim.Supported{Supported: true},
+		Sort:           scim.Supported{Supported: false},
+		ETag:           scim.Supported{Supported: false},
+		AuthenticationSchemes: []scim.AuthenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "OAuth Bearer Token",
+			Description: "Authentication scheme using the dedicated bearer token of the SCIM client.",
+			Primary:     true,
+		}},
+		Meta: &scim.Meta{ResourceType: "ServiceProviderConfig", Location: scim.BasePath + "/ServiceProviderConfig"},
+	})
+}
+
+func (h *handler) listUsers(req *restful.Request, resp *restful.Response) {
+	startIndex, count, err := pagination(req)
+	if err != nil {
+		writeError(resp, err)
+		return
+	}
+	result, err := h.scim.ListUsers(req.Request.Context(), req.QueryParameter("filter"), startIndex, count)
+	if err != nil {
+		writeError(resp, err)
+		return
+	}
+	writeResource(resp, http.StatusOK, result)
+}
+
+func (h *handler) getUser(req *restful.Request, resp *restful.Response) {
+	user, err := h.scim.GetUser(req.Request.Context(), req.PathParameter("id"))
+	if err != nil {
+		writeError(resp, err)
+		return
+	}
+	writeResource(resp, http.StatusOK, user)
+}
+
+func (h *handler) createUser(req *restful.Request, resp *restful.Response) {
+	user := &scim.User{}
+	if err := req.ReadEntity(user); err != nil {
+		writeError(resp, scim.NewBadRequest(scim.ErrorInvalidSyntax, "%s", err))
+		return
+	}
+	created, err := h.scim.CreateUser(req.Request.Context(), user)
+	if err != nil {
+		writeError(resp, err)
+		return
+	}
+	resp.Header().Set("Location", created.Meta.Location)
+	writeResource(resp, http.StatusCreated, created)
+}
+
+func (h *handler) replaceUser(req *restful.Request, resp *restful.Response) {
+	user := &scim.User{}
+	if err := req.ReadEntity(user); err != nil {
+		writeError(resp, scim.NewBadRequest(scim.ErrorInvalidSyntax, "%s", err))
+		return
+	}
+	updated, err := h.scim.ReplaceUser(req.Request.Context(), req.PathParameter("id"), user)
+	if err != nil {
+		writeError(resp, err)
+		return
+	}
+	writeResource(resp, http.StatusOK, updated)
+}
+
+func (h *handler) patchUser(req *restful.Request, resp *restful.Response) {
+	patch := &scim.PatchOp{}
+	if err := req.ReadEntity(patch); err != nil {
+		writeError(resp, scim.NewBadRequest(scim.ErrorInvalidSyntax, "%s", err))
+		return
+	}
+	updated, err := h.scim.PatchUser(req.Request.Context(), req.PathParameter("id"), patch)
+	if err != nil {
+		writeError(resp, err)
+		return
+	}
+	writeResource(resp, http.StatusOK, updated)
+}
+
+func (h *handler) deleteUser(req *restful.Request, resp *restful.Response) {
+	if err := h.scim.DeleteUser(req.Request.Context(), req.PathParameter("id")); err != nil {
+		writeError(resp, err)
+		return
+	}
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+func (h *handler) listGroups(req *restful.Request, resp *restful.Response) {
+	startIndex, count, err := pagination(req)
+	if err != nil {
+		writeError(resp, err)
+		return
+	}
+	result, err := h.scim.ListGroups(req.Request.Context(), req.QueryParameter("filter"), startIndex, count)
+	if err != nil {
+		writeError(resp, err)
+		return
+	}
+	writeResource(resp, http.StatusOK, result)
+}
+
+func (h *handler) getGroup(req *restful.Request, resp *restful.Response) {
+	group, err := h.scim.GetGroup(req.Request.Context(), req.PathParameter("id"))
+	if err != nil {
+		writeError(resp, err)
+		return
+	}
+	writeResource(resp, http.StatusOK, group)
+}
+
+func (h *handler) createGroup(req *restful.Request, resp *restful.Response) {
+	group := &scim.Group{}
+	if err := req.ReadEntity(group); err != nil {
+		writeError(resp, scim.NewBadRequest(scim.ErrorInvalidSyntax, "%s", err))
+		return
+	}
+	created, err := h.scim.CreateGroup(req.Request.Context(), group)
+	if err != nil {
+		writeError(resp, err)
+		return
+	}
+	resp.Header().Set("Location", created.Meta.Location)
+	writeResource(resp, http.StatusCreated, created)
+}
+
+func (h *handler) replaceGroup(req *restful.Request, resp *restful.Response) {
+	group := &scim.Group{}
+	if err := req.ReadEntity(group); err != nil {
+		writeError(resp, scim.NewBadRequest(scim.ErrorInvalidSyntax, "%s", err))
+		return
+	}
+	updated, err := h.scim.ReplaceGroup(req.Request.Context(), req.PathParameter("id"), group)
+	if err != nil {
+		writeError(resp, err)
+		return
+	}
+	writeResource(resp, http.StatusOK, updated)
+}
+
+func (h *handler) patchGroup(req *restful.Request, resp *restful.Response) {
+	patch := &scim.PatchOp{}
+	if err := req.ReadEntity(patch); err != nil {
+		writeError(resp, scim.NewBadRequest(scim.ErrorInvalidSyntax, "%s", err))
+		return
+	}
+	updated, err := h.scim.PatchGroup(req.Request.Context(), req.PathParameter("id"), patch)
+	if err != nil {
+		writeError(resp, err)
+		return
+	}
+	writeResource(resp, http.StatusOK, updated)
+}
+
+func (h *handler) deleteGroup(req *restful.Request, resp *restful.Response) {
+	if err := h.scim.DeleteGroup(req.Request.Context(), req.PathParameter("id")); err != nil {
+		writeError(resp, err)
+		return
+	}
+	resp.WriteHeader(http.StatusNoContent)
+}
+
+// pagination returns the 1-based start index and the count of the resources per page,
+// https://datatracker.ietf.org/doc/html/rfc7644#section-3.4.2.4
+func pagination(req *restful.Request) (int, int, error) {
+	startIndex, count := 1, maxResults
+	var err error
+	if value := req.QueryParameter("startIndex"); value != "" {
+		if startIndex, err = strconv.Atoi(value); err != nil {
+			return 0, 0, scim.NewBadRequest(scim.ErrorInvalidValue, "invalid startIndex %q", value)
+		}
+	}
+	if value := req.QueryParameter("count"); value != "" {
+		if count, err = strconv.Atoi(value); err != nil {
+			return 0, 0, scim.NewBadRequest(scim.ErrorInvalidValue, "invalid count %q", value)
+		}
+		if count < 0 {
+			count = 0
+		}
+		if count > maxResults {
+			count = maxResults
+		}
+	}
+	return startIndex, count, nil
+}
+
+func writeResource(resp *restful.Response, status int, resource interface{}) {
+	if err := resp.WriteHeaderAndJson(status, resource, runtime.MimeSCIMJson); err != nil {
+		klog.Errorf("failed to write SCIM response: %s", err)
+	}
+}
+
+func writeError(resp *restful.Response, err error) {
+	var scimError *scim.Error
+	var status apierrors.APIStatus
+	switch {
+	case errors.As(err, &scimError):
+	case apierrors.IsAlreadyExists(err) || apierrors.IsConflict(err):
+		scimError = scim.NewError(http.StatusConflict, scim.ErrorUniqueness, err.Error())
+	case apierrors.IsNotFound(err):
+		scimError = scim.NewError(http.StatusNotFound, "", err.Error())
+	case apierrors.IsInvalid(err) || apierrors.IsBadRequest(err):
+		scimError = scim.NewBadRequest(scim.ErrorInvalidValue, "%s", err)
+	case errors.As(err, &status) && status.Status().Code < http.StatusInternalServerError:
+		scimError = scim.NewError(int(status.Status().Code), "", err.Error())
+	default:
+		klog.Errorf("SCIM request failed: %s", err)
+		scimError = scim.NewError(http.StatusInternalServerError, "", internalServerErrorMessage)
+	}
+	writeResource(resp, scimError.StatusCode(), scimError)
+}
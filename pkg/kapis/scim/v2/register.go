@@ -0,0 +1,121 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package v2
+
+import (
+	"net/http"
+
+	restfulspec "github.com/emicklei/go-restful-openapi/v2"
+	"github.com/emicklei/go-restful/v3"
+
+	"kubesphere.io/kubesphere/pkg/api"
+	"kubesphere.io/kubesphere/pkg/apiserver/runtime"
+	"kubesphere.io/kubesphere/pkg/models/iam/scim"
+)
+
+// AddToContainer installs the SCIM 2.0 protocol endpoints, https://datatracker.ietf.org/doc/html/rfc7644
+func (h *handler) AddToContainer(container *restful.Container) error {
+	ws := &restful.WebService{}
+	ws.Path(scim.BasePath).
+		Consumes(runtime.MimeSCIMJson, restful.MIME_JSON).
+		Produces(runtime.MimeSCIMJson, restful.MIME_JSON).
+		Filter(h.authenticate)
+
+	ws.Route(ws.GET("/ServiceProviderConfig").
+		To(h.serviceProviderConfig).
+		Doc("Get service provider configuration").
+		Notes("Retrieve the SCIM features supported by the service provider.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Returns(http.StatusOK, api.StatusOK, scim.ServiceProviderConfig{}))
+
+	ws.Route(ws.GET("/Users").
+		To(h.listUsers).
+		Doc("List users").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Param(ws.QueryParameter("filter", "filter expression, e.g. userName eq \"admin\"").Required(false)).
+		Param(ws.QueryParameter("startIndex", "1-based index of the first result").DataType("integer").DefaultValue("1").Required(false)).
+		Param(ws.QueryParameter("count", "maximum number of the results per page").DataType("integer").Required(false)).
+		Returns(http.StatusOK, api.StatusOK, scim.ListResponse{}))
+	ws.Route(ws.POST("/Users").
+		To(h.createUser).
+		Doc("Create user").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Reads(scim.User{}).
+		Returns(http.StatusCreated, http.StatusText(http.StatusCreated), scim.User{}))
+	ws.Route(ws.GET("/Users/{id}").
+		To(h.getUser).
+		Doc("Get user").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Param(ws.PathParameter("id", "username")).
+		Returns(http.StatusOK, api.StatusOK, scim.User{}))
+	ws.Route(ws.PUT("/Users/{id}").
+		To(h.replaceUser).
+		Doc("Replace user").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Param(ws.PathParameter("id", "username")).
+		Reads(scim.User{}).
+		Returns(http.StatusOK, api.StatusOK, scim.User{}))
+	ws.Route(ws.PATCH("/Users/{id}").
+		To(h.patchUser).
+		Doc("Patch user").
+		Notes("Modify the attributes of the user, e.g. disable the user by replacing active with false.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Param(ws.PathParameter("id", "username")).
+		Reads(scim.PatchOp{}).
+		Returns(http.StatusOK, api.StatusOK, scim.User{}))
+	ws.Route(ws.DELETE("/Users/{id}").
+		To(h.deleteUser).
+		Doc("Delete user").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Param(ws.PathParameter("id", "username")).
+		Returns(http.StatusNoContent, http.StatusText(http.StatusNoContent), nil))
+
+	ws.Route(ws.GET("/Groups").
+		To(h.listGroups).
+		Doc("List groups").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Param(ws.QueryParameter("filter", "filter expression, e.g. displayName eq \"developers\"").Required(false)).
+		Param(ws.QueryParameter("startIndex", "1-based index of the first result").DataType("integer").DefaultValue("1").Required(false)).
+		Param(ws.QueryParameter("count", "maximum number of the results per page").DataType("integer").Required(false)).
+		Returns(http.StatusOK, api.StatusOK, scim.ListResponse{}))
+	ws.Route(ws.POST("/Groups").
+		To(h.createGroup).
+		Doc("Create group").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Reads(scim.Group{}).
+		Returns(http.StatusCreated, http.StatusText(http.StatusCreated), scim.Group{}))
+	ws.Route(ws.GET("/Groups/{id}").
+		To(h.getGroup).
+		Doc("Get group").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Param(ws.PathParameter("id", "group name")).
+		Returns(http.StatusOK, api.StatusOK, scim.Group{}))
+	ws.Route(ws.PUT("/Groups/{id}").
+		To(h.replaceGroup).
+		Doc("Replace group").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Param(ws.PathParameter("id", "group name")).
+		Reads(scim.Group{}).
+		Returns(http.StatusOK, api.StatusOK, scim.Group{}))
+	ws.Route(ws.PATCH("/Groups/{id}").
+		To(h.patchGroup).
+		Doc("Patch group").
+		Notes("Modify the attributes of the group, e.g. add or remove members.").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Param(ws.PathParameter("id", "group name")).
+		Reads(scim.PatchOp{}).
+		Returns(http.StatusOK, api.StatusOK, scim.Group{}))
+	ws.Route(ws.DELETE("/Groups/{id}").
+		To(h.deleteGroup).
+		Doc("Delete group").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagIdentityManagement}).
+		Param(ws.PathParameter("id", "group name")).
+		Returns(http.StatusNoContent, http.StatusText(http.StatusNoContent), nil))
+
+	container.Add(ws)
+	return nil
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/emicklei/go-restful/v3"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+
+	"kubesphere.io/kubesphere/pkg/api"
+	"kubesphere.io/kubesphere/pkg/apiserver/query"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+	"kubesphere.io/kubesphere/pkg/models/search"
+)
+
+type handler struct {
+	searcher search.Interface
+}
+
+func (h *handler) search(req *restful.Request, resp *restful.Response) {
+	terms := strings.Fields(req.QueryParameter(parameterTerms))
+	if len(terms) == 0 {
+		api.HandleBadRequest(resp, req, fmt.Errorf("the search terms are required"))
+		return
+	}
+	user, ok := request.UserFrom(req.Request.Context())
+	if !ok {
+		err := fmt.Errorf("cannot obtain user info")
+		klog.Errorln(err)
+		api.HandleForbidden(resp, req, err)
+		return
+	}
+
+	options := search.Options{
+		Terms:     terms,
+		Resources: sets.New[string](),
+		Workspace: req.QueryParameter(parameterWorkspace),
+		Namespace: req.QueryParameter(parameterNamespace),
+	}
+	for _, resource := range strings.Split(req.QueryParameter(parameterResources), ",") {
+		if resource = strings.TrimSpace(resource); resource != "" {
+			options.Resources.Insert(resource)
+		}
+	}
+	pagination := query.ParseQueryParameter(req).Pagination
+	if pagination.Limit == query.NoPagination.Limit {
+		page, err := strconv.Atoi(req.QueryParameter(query.ParameterPage))
+		if err != nil || page < 1 {
+			page = 1
+		}
+		pagination = &query.Pagination{Limit: defaultLimit, Offset: (page - 1) * defaultLimit}
+	}
+
+	result, err := h.searcher.Search(user, options, pagination)
+	if err != nil {
+		api.HandleInternalError(resp, req, err)
+		return
+	}
+	_ = resp.WriteEntity(result)
+}
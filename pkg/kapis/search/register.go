@@ -0,0 +1,61 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package search
+
+import (
+	"net/http"
+
+	restfulspec "github.com/emicklei/go-restful-openapi/v2"
+	"github.com/emicklei/go-restful/v3"
+	runtimecache "sigs.k8s.io/controller-runtime/pkg/cache"
+
+	"kubesphere.io/kubesphere/pkg/api"
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+	"kubesphere.io/kubesphere/pkg/apiserver/query"
+	"kubesphere.io/kubesphere/pkg/apiserver/rest"
+	"kubesphere.io/kubesphere/pkg/apiserver/runtime"
+	"kubesphere.io/kubesphere/pkg/models/search"
+)
+
+const (
+	parameterTerms     = "q"
+	parameterResources = "resources"
+	parameterWorkspace = "workspace"
+	parameterNamespace = "namespace"
+	// defaultLimit limits the objects returned if no limit is specified
+	defaultLimit = 20
+)
+
+func NewHandler(informers runtimecache.Informers, authorizer authorizer.Authorizer) rest.Handler {
+	return &handler{searcher: search.NewSearcher(informers, authorizer)}
+}
+
+func NewFakeHandler() rest.Handler {
+	return &handler{}
+}
+
+func (h *handler) AddToContainer(c *restful.Container) error {
+	ws := &restful.WebService{}
+	ws.Path(runtime.ApiRootPath + "/search").Produces(restful.MIME_JSON)
+
+	ws.Route(ws.GET("").
+		To(h.search).
+		Doc("Search the resources by their names, aliases, labels, annotations and images").
+		Notes("The resources matching all the search terms the user is authorized to list are returned, ranked by the relevance.").
+		Operation("search-resources").
+		Metadata(restfulspec.KeyOpenAPITags, []string{api.TagNonResourceAPI}).
+		Param(ws.QueryParameter(parameterTerms, "the search terms separated by spaces, the terms match the words of the fields exactly or by prefix, e.g. q=payment").Required(true)).
+		Param(ws.QueryParameter(parameterResources, "the resources to search separated by commas, all the resources are searched by default, e.g. resources=pods,deployments").Required(false)).
+		Param(ws.QueryParameter(parameterWorkspace, "the workspace to search").Required(false)).
+		Param(ws.QueryParameter(parameterNamespace, "the namespace to search").Required(false)).
+		Param(ws.QueryParameter(query.ParameterPage, "page").Required(false).DataFormat("page=%d").DefaultValue("page=1")).
+		Param(ws.QueryParameter(query.ParameterLimit, "limit").Required(false).DefaultValue("limit=20")).
+		Returns(http.StatusOK, api.StatusOK, api.SearchResult{}))
+
+	c.Add(ws)
+	return nil
+}
@@ -20,6 +20,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication/identityprovider"
+	"kubesphere.io/kubesphere/pkg/models/iam/group"
 )
 
 var (
@@ -86,7 +87,10 @@ func authByIdentityProvider(ctx context.Context, client client.Client, mapper Us
 					mappedUser.Annotations = make(map[string]string)
 				}
 				mappedUser.Annotations[fmt.Sprintf("%s.%s", iamv1beta1.IdentityProviderAnnotation, providerConfig.Name)] = identity.GetUserID()
-				mappedUser.Status.State = iamv1beta1.UserActive
+				// the state of the existing user, e.g. PasswordResetRequired or AuthLimitExceeded, is kept
+				if mappedUser.ResourceVersion == "" {
+					mappedUser.Status.State = iamv1beta1.UserActive
+				}
 				if identity.GetEmail() != "" {
 					mappedUser.Spec.Email = identity.GetEmail()
 				}
@@ -99,6 +103,10 @@ func authByIdentityProvider(ctx context.Context, client client.Client, mapper Us
 
 			klog.V(4).Infof("user %s has been updated successfully, operation: %s", mappedUser.Name, op)
 
+			if err = syncIdentityProviderGroups(ctx, client, providerConfig, mappedUser.Name, identity); err != nil {
+				return nil, fmt.Errorf("failed to sync groups of user %s, error: %v", mappedUser.Name, err)
+			}
+
 			return &authuser.DefaultInfo{Name: mappedUser.GetName()}, nil
 		}
 
@@ -109,5 +117,20 @@ func authByIdentityProvider(ctx context.Context, client client.Client, mapper Us
 		return nil, AccountIsNotActiveError
 	}
 
+	if err = syncIdentityProviderGroups(ctx, client, providerConfig, mappedUser.Name, identity); err != nil {
+		return nil, fmt.Errorf("failed to sync groups of user %s, error: %v", mappedUser.Name, err)
+	}
+
 	return &authuser.DefaultInfo{Name: mappedUser.GetName()}, nil
 }
+
+// syncIdentityProviderGroups binds the user to the groups mapped from the groups of the identity,
+// if the identity provider carries the groups and the group mapping is configured.
+func syncIdentityProviderGroups(ctx context.Context, client client.Client, providerConfig *identityprovider.Configuration, username string, identity identityprovider.Identity) error {
+	groupsIdentity, ok := identity.(identityprovider.GroupsIdentity)
+	if !ok || providerConfig.GroupMapping == nil {
+		return nil
+	}
+	groups := providerConfig.GroupMapping.Map(groupsIdentity.GetGroups())
+	return group.SyncIdentityProviderGroups(ctx, client, providerConfig.Name, username, groups)
+}
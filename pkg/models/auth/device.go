@@ -0,0 +1,401 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	"kubesphere.io/kubesphere/pkg/simple/client/cache"
+)
+
+var (
+	DeviceAuthorizationPendingError  = fmt.Errorf("device authorization is pending")
+	DeviceAuthorizationSlowDownError = fmt.Errorf("device authorization is polled too frequently")
+	DeviceAuthorizationDeniedError   = fmt.Errorf("device authorization is denied")
+	DeviceCodeExpiredError           = fmt.Errorf("device code is invalid or expired")
+	UserCodeExpiredError             = fmt.Errorf("user code is invalid or expired")
+	VerificationTokenInvalidError    = fmt.Errorf("verification token is invalid or expired")
+)
+
+type DeviceAuthorizationStatus string
+
+const (
+	DeviceAuthorizationPending  DeviceAuthorizationStatus = "Pending"
+	DeviceAuthorizationApproved DeviceAuthorizationStatus = "Approved"
+	DeviceAuthorizationDenied   DeviceAuthorizationStatus = "Denied"
+
+	// DeviceCodeMaxAge is the lifetime of the device code and the user code.
+	DeviceCodeMaxAge = 10 * time.Minute
+	// DevicePollingInterval is the minimum interval between polling requests, it's
+	// increased by 5 seconds every time the client polls too frequently.
+	DevicePollingInterval = 5 * time.Second
+
+	deviceCodeCacheKeyFormat = "kubesphere:oauth:devicecode:%s"
+	// the marker is set once the approved device code is redeemed for the tokens
+	deviceCodeRedeemedCacheKeyFormat = "kubesphere:oauth:devicecode:%s:redeemed"
+	// the polling state is stored apart from the request, so that the polls never overwrite the approval
+	deviceCodePollingCacheKeyFormat = "kubesphere:oauth:devicecode:%s:polling"
+	userCodeCacheKeyFormat          = "kubesphere:oauth:usercode:%s"
+	// the verification token is bound to the user code, the End-User and the login session
+	verificationTokenCacheKeyFormat = "kubesphere:oauth:usercode:%s:user:%s:session:%s"
+	deviceCodeSize                  = 32
+	// The user code is drawn from 20 consonants which are unlikely to be misread or form words,
+	// https://datatracker.ietf.org/doc/html/rfc8628#section-6.1
+	userCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength  = 8
+)
+
+// DeviceAuthorization is a device authorization request pending on the approval of the End-User.
+type DeviceAuthorization struct {
+	ClientID  string                    `json:"clientID"`
+	Scopes    []string                  `json:"scopes,omitempty"`
+	UserCode  string                    `json:"userCode"`
+	Status    DeviceAuthorizationStatus `json:"status"`
+	Interval  time.Duration             `json:"interval"`
+	ExpiresAt time.Time                 `json:"expiresAt"`
+	// Username and Extra are the End-User who approved the request.
+	Username string              `json:"username,omitempty"`
+	Extra    map[string][]string `json:"extra,omitempty"`
+}
+
+// devicePolling is the polling state of the device, the interval is increased when the device polls too frequently.
+type devicePolling struct {
+	Interval     time.Duration `json:"interval"`
+	LastPolledAt time.Time     `json:"lastPolledAt"`
+}
+
+// DeviceAuthorizationOperator manages the pending device authorization requests of the device authorization grant,
+// https://datatracker.ietf.org/doc/html/rfc8628. The requests are stored in the cache, so that the device
+// can poll any replica of ks-apiserver.
+type DeviceAuthorizationOperator interface {
+	// Create starts a device authorization request of the client, and returns the device code.
+	Create(clientID string, scopes []string) (string, *DeviceAuthorization, error)
+	// Get returns the pending device authorization request of the user code.
+	Get(userCode string) (*DeviceAuthorization, error)
+	// IssueVerificationToken returns the token to be submitted along with the approval or the denial of
+	// the device authorization request of the user code, so that the End-User can't be tricked into
+	// submitting the request from another site. The token is bound to the login session of the End-User.
+	IssueVerificationToken(userCode string, authenticated user.Info, sessionID string) (string, error)
+	// Approve approves the device authorization request of the user code on behalf of the End-User,
+	// the verification token issued to the login session is required.
+	Approve(userCode, verificationToken string, authenticated user.Info, sessionID string) error
+	// Deny denies the device authorization request of the user code,
+	// the verification token issued to the login session is required.
+	Deny(userCode, verificationToken string, authenticated user.Info, sessionID string) error
+	// Poll returns the approved device authorization request of the device code, which can only be returned once.
+	Poll(clientID, deviceCode string) (*DeviceAuthorization, error)
+}
+
+type deviceAuthorizationOperator struct {
+	cache cache.Interface
+}
+
+func NewDeviceAuthorizationOperator(cache cache.Interface) DeviceAuthorizationOperator {
+	return &deviceAuthorizationOperator{cache: cache}
+}
+
+func (d *deviceAuthorizationOperator) Create(clientID string, scopes []string) (string, *DeviceAuthorization, error) {
+	deviceCode, err := generateDeviceCode()
+	if err != nil {
+		return "", nil, err
+	}
+	userCode, err := generateUserCode()
+	if err != nil {
+		return "", nil, err
+	}
+	exists, err := d.cache.Exists(fmt.Sprintf(userCodeCacheKeyFormat, userCode))
+	if err != nil {
+		return "", nil, err
+	}
+	if exists {
+		return "", nil, fmt.Errorf("user code collision, please retry")
+	}
+
+	authorization := &DeviceAuthorization{
+		ClientID:  clientID,
+		Scopes:    scopes,
+		UserCode:  userCode,
+		Status:    DeviceAuthorizationPending,
+		Interval:  DevicePollingInterval,
+		ExpiresAt: time.Now().Add(DeviceCodeMaxAge),
+	}
+	if err = d.save(deviceCode, authorization); err != nil {
+		return "", nil, err
+	}
+	if err = d.cache.Set(fmt.Sprintf(userCodeCacheKeyFormat, userCode), deviceCode, DeviceCodeMaxAge); err != nil {
+		return "", nil, err
+	}
+	return deviceCode, authorization, nil
+}
+
+func (d *deviceAuthorizationOperator) Get(userCode string) (*DeviceAuthorization, error) {
+	_, authorization, err := d.getByUserCode(userCode)
+	return authorization, err
+}
+
+func (d *deviceAuthorizationOperator) IssueVerificationToken(userCode string, authenticated user.Info, sessionID string) (string, error) {
+	_, authorization, err := d.getByUserCode(userCode)
+	if err != nil {
+		return "", err
+	}
+	expiresIn := time.Until(authorization.ExpiresAt)
+	if expiresIn <= 0 {
+		return "", UserCodeExpiredError
+	}
+	verificationToken, err := generateDeviceCode()
+	if err != nil {
+		return "", err
+	}
+	key := fmt.Sprintf(verificationTokenCacheKeyFormat, authorization.UserCode, authenticated.GetName(), sessionID)
+	if err = d.cache.Set(key, verificationToken, expiresIn); err != nil {
+		return "", err
+	}
+	return verificationToken, nil
+}
+
+func (d *deviceAuthorizationOperator) Approve(userCode, verificationToken string, authenticated user.Info, sessionID string) error {
+	deviceCode, authorization, err := d.getByUserCode(userCode)
+	if err != nil {
+		return err
+	}
+	if err = d.verify(authorization, verificationToken, authenticated, sessionID); err != nil {
+		return err
+	}
+	authorization.Status = DeviceAuthorizationApproved
+	authorization.Username = authenticated.GetName()
+	authorization.Extra = authenticated.GetExtra()
+	if err = d.save(deviceCode, authorization); err != nil {
+		return err
+	}
+	// The user code MUST NOT be used more than once.
+	return d.cache.Del(fmt.Sprintf(userCodeCacheKeyFormat, authorization.UserCode))
+}
+
+func (d *deviceAuthorizationOperator) Deny(userCode, verificationToken string, authenticated user.Info, sessionID string) error {
+	deviceCode, authorization, err := d.getByUserCode(userCode)
+	if err != nil {
+		return err
+	}
+	if err = d.verify(authorization, verificationToken, authenticated, sessionID); err != nil {
+		return err
+	}
+	authorization.Status = DeviceAuthorizationDenied
+	if err = d.save(deviceCode, authorization); err != nil {
+		return err
+	}
+	return d.cache.Del(fmt.Sprintf(userCodeCacheKeyFormat, authorization.UserCode))
+}
+
+func (d *deviceAuthorizationOperator) Poll(clientID, deviceCode string) (*DeviceAuthorization, error) {
+	authorization, err := d.get(deviceCode)
+	if err != nil {
+		return nil, err
+	}
+	// The device code is bound to the client it's issued to.
+	if authorization.ClientID != clientID {
+		return nil, DeviceCodeExpiredError
+	}
+
+	switch authorization.Status {
+	case DeviceAuthorizationApproved:
+		// The concurrent polls may all read the approval, only the first one redeems it.
+		redeemed, err := d.cache.SetNX(fmt.Sprintf(deviceCodeRedeemedCacheKeyFormat, deviceCode), clientID, DeviceCodeMaxAge)
+		if err != nil {
+			return nil, err
+		}
+		if !redeemed {
+			return nil, DeviceCodeExpiredError
+		}
+		if err = d.cache.Del(fmt.Sprintf(deviceCodeCacheKeyFormat, deviceCode),
+			fmt.Sprintf(deviceCodePollingCacheKeyFormat, deviceCode)); err != nil {
+			return nil, err
+		}
+		return authorization, nil
+	case DeviceAuthorizationDenied:
+		if err = d.cache.Del(fmt.Sprintf(deviceCodeCacheKeyFormat, deviceCode),
+			fmt.Sprintf(deviceCodePollingCacheKeyFormat, deviceCode)); err != nil {
+			return nil, err
+		}
+		return nil, DeviceAuthorizationDeniedError
+	}
+
+	polling, err := d.getPolling(deviceCode, authorization)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	pollingErr := DeviceAuthorizationPendingError
+	if !polling.LastPolledAt.IsZero() && now.Sub(polling.LastPolledAt) < polling.Interval {
+		polling.Interval += DevicePollingInterval
+		pollingErr = DeviceAuthorizationSlowDownError
+	}
+	polling.LastPolledAt = now
+	if err = d.savePolling(deviceCode, authorization, polling); err != nil {
+		return nil, err
+	}
+	return nil, pollingErr
+}
+
+// getPolling returns the polling state of the device, the interval of the request is used before the first poll.
+func (d *deviceAuthorizationOperator) getPolling(deviceCode string, authorization *DeviceAuthorization) (*devicePolling, error) {
+	data, err := d.cache.Get(fmt.Sprintf(deviceCodePollingCacheKeyFormat, deviceCode))
+	if err != nil {
+		if err == cache.ErrNoSuchKey {
+			return &devicePolling{Interval: authorization.Interval}, nil
+		}
+		return nil, err
+	}
+	polling := &devicePolling{}
+	if err = json.Unmarshal([]byte(data), polling); err != nil {
+		return nil, err
+	}
+	return polling, nil
+}
+
+// savePolling stores the polling state of the device until the request expires.
+func (d *deviceAuthorizationOperator) savePolling(deviceCode string, authorization *DeviceAuthorization, polling *devicePolling) error {
+	expiresIn := time.Until(authorization.ExpiresAt)
+	if expiresIn <= 0 {
+		return DeviceCodeExpiredError
+	}
+	data, err := json.Marshal(polling)
+	if err != nil {
+		return err
+	}
+	return d.cache.Set(fmt.Sprintf(deviceCodePollingCacheKeyFormat, deviceCode), string(data), expiresIn)
+}
+
+func (d *deviceAuthorizationOperator) getByUserCode(userCode string) (string, *DeviceAuthorization, error) {
+	userCode = NormalizeUserCode(userCode)
+	if userCode == "" {
+		return "", nil, UserCodeExpiredError
+	}
+	deviceCode, err := d.cache.Get(fmt.Sprintf(userCodeCacheKeyFormat, userCode))
+	if err != nil {
+		if err == cache.ErrNoSuchKey {
+			return "", nil, UserCodeExpiredError
+		}
+		return "", nil, err
+	}
+	authorization, err := d.get(deviceCode)
+	if err != nil {
+		if err == DeviceCodeExpiredError {
+			return "", nil, UserCodeExpiredError
+		}
+		return "", nil, err
+	}
+	if authorization.Status != DeviceAuthorizationPending {
+		return "", nil, UserCodeExpiredError
+	}
+	return deviceCode, authorization, nil
+}
+
+// verify checks the verification token issued to the login session of the End-User, the token can only be used once.
+func (d *deviceAuthorizationOperator) verify(authorization *DeviceAuthorization, verificationToken string, authenticated user.Info, sessionID string) error {
+	if verificationToken == "" {
+		return VerificationTokenInvalidError
+	}
+	key := fmt.Sprintf(verificationTokenCacheKeyFormat, authorization.UserCode, authenticated.GetName(), sessionID)
+	issued, err := d.cache.Get(key)
+	if err != nil {
+		if err == cache.ErrNoSuchKey {
+			return VerificationTokenInvalidError
+		}
+		return err
+	}
+	if subtle.ConstantTimeCompare([]byte(issued), []byte(verificationToken)) != 1 {
+		return VerificationTokenInvalidError
+	}
+	return d.cache.Del(key)
+}
+
+func (d *deviceAuthorizationOperator) get(deviceCode string) (*DeviceAuthorization, error) {
+	if deviceCode == "" {
+		return nil, DeviceCodeExpiredError
+	}
+	data, err := d.cache.Get(fmt.Sprintf(deviceCodeCacheKeyFormat, deviceCode))
+	if err != nil {
+		if err == cache.ErrNoSuchKey {
+			return nil, DeviceCodeExpiredError
+		}
+		return nil, err
+	}
+	authorization := &DeviceAuthorization{}
+	if err = json.Unmarshal([]byte(data), authorization); err != nil {
+		return nil, err
+	}
+	if time.Now().After(authorization.ExpiresAt) {
+		return nil, DeviceCodeExpiredError
+	}
+	return authorization, nil
+}
+
+// save stores the device authorization request until it expires.
+func (d *deviceAuthorizationOperator) save(deviceCode string, authorization *DeviceAuthorization) error {
+	expiresIn := time.Until(authorization.ExpiresAt)
+	if expiresIn <= 0 {
+		return DeviceCodeExpiredError
+	}
+	data, err := json.Marshal(authorization)
+	if err != nil {
+		return err
+	}
+	return d.cache.Set(fmt.Sprintf(deviceCodeCacheKeyFormat, deviceCode), string(data), expiresIn)
+}
+
+// NormalizeUserCode removes the separators and converts the user code to upper case,
+// so that the End-User can enter it in a case-insensitive way.
+func NormalizeUserCode(userCode string) string {
+	var builder strings.Builder
+	for _, c := range strings.ToUpper(userCode) {
+		if strings.ContainsRune(userCodeCharset, c) {
+			builder.WriteRune(c)
+		}
+	}
+	if builder.Len() != userCodeLength {
+		return ""
+	}
+	return builder.String()
+}
+
+// FormatUserCode formats the user code as XXXX-XXXX for display.
+func FormatUserCode(userCode string) string {
+	if len(userCode) != userCodeLength {
+		return userCode
+	}
+	return userCode[:userCodeLength/2] + "-" + userCode[userCodeLength/2:]
+}
+
+func generateDeviceCode() (string, error) {
+	b := make([]byte, deviceCodeSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func generateUserCode() (string, error) {
+	b := make([]byte, userCodeLength)
+	max := big.NewInt(int64(len(userCodeCharset)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = userCodeCharset[n.Int64()]
+	}
+	return string(b), nil
+}
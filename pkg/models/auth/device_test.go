@@ -0,0 +1,204 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	"kubesphere.io/kubesphere/pkg/simple/client/cache"
+)
+
+func TestDeviceAuthorizationOperator(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	inMemoryCache, err := cache.NewInMemoryCache(nil, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	operator := NewDeviceAuthorizationOperator(inMemoryCache)
+
+	deviceCode, authorization, err := operator.Create("kubectl", []string{"openid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = operator.Poll("kubectl", deviceCode); err != DeviceAuthorizationPendingError {
+		t.Errorf("expected %v, got %v", DeviceAuthorizationPendingError, err)
+	}
+	if _, err = operator.Poll("kubectl", deviceCode); err != DeviceAuthorizationSlowDownError {
+		t.Errorf("expected %v, got %v", DeviceAuthorizationSlowDownError, err)
+	}
+	if _, err = operator.Poll("ks-console", deviceCode); err != DeviceCodeExpiredError {
+		t.Errorf("expected %v for another client, got %v", DeviceCodeExpiredError, err)
+	}
+
+	// the End-User enters the formatted user code in lower case
+	userCode := strings.ToLower(FormatUserCode(authorization.UserCode))
+	pending, err := operator.Get(userCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending.ClientID != "kubectl" {
+		t.Errorf("expected client kubectl, got %s", pending.ClientID)
+	}
+	admin := &user.DefaultInfo{Name: "admin"}
+	verificationToken, err := operator.IssueVerificationToken(userCode, admin, "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = operator.Approve(userCode, "", admin, "session"); err != VerificationTokenInvalidError {
+		t.Errorf("expected %v without the verification token, got %v", VerificationTokenInvalidError, err)
+	}
+	if err = operator.Approve(userCode, verificationToken, admin, "another"); err != VerificationTokenInvalidError {
+		t.Errorf("expected %v in another session, got %v", VerificationTokenInvalidError, err)
+	}
+	if err = operator.Approve(userCode, verificationToken, &user.DefaultInfo{Name: "tester"}, "session"); err != VerificationTokenInvalidError {
+		t.Errorf("expected %v for another user, got %v", VerificationTokenInvalidError, err)
+	}
+	if err = operator.Approve(userCode, verificationToken, admin, "session"); err != nil {
+		t.Fatal(err)
+	}
+	if err = operator.Approve(userCode, verificationToken, admin, "session"); err != UserCodeExpiredError {
+		t.Errorf("expected %v on reuse of the user code, got %v", UserCodeExpiredError, err)
+	}
+
+	approved, err := operator.Poll("kubectl", deviceCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if approved.Username != "admin" || len(approved.Scopes) != 1 {
+		t.Errorf("unexpected device authorization: %+v", approved)
+	}
+	if _, err = operator.Poll("kubectl", deviceCode); err != DeviceCodeExpiredError {
+		t.Errorf("expected %v on reuse of the device code, got %v", DeviceCodeExpiredError, err)
+	}
+
+	deviceCode, authorization, err = operator.Create("kubectl", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verificationToken, err = operator.IssueVerificationToken(authorization.UserCode, admin, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err = operator.Deny(authorization.UserCode, verificationToken, admin, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = operator.Poll("kubectl", deviceCode); err != DeviceAuthorizationDeniedError {
+		t.Errorf("expected %v, got %v", DeviceAuthorizationDeniedError, err)
+	}
+}
+
+func TestDeviceCodeRedeemedOnce(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	inMemoryCache, err := cache.NewInMemoryCache(nil, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	operator := NewDeviceAuthorizationOperator(inMemoryCache)
+	deviceCode, authorization, err := operator.Create("kubectl", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := &user.DefaultInfo{Name: "admin"}
+	verificationToken, err := operator.IssueVerificationToken(authorization.UserCode, admin, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = operator.Approve(authorization.UserCode, verificationToken, admin, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var redeemed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := operator.Poll("kubectl", deviceCode); err == nil {
+				redeemed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if redeemed.Load() != 1 {
+		t.Errorf("expected the approved device code to be redeemed once, got %d", redeemed.Load())
+	}
+}
+
+// approvingCache approves the device authorization request once the device code is read by a poll,
+// so that the approval lands between the read and the write of the poll.
+type approvingCache struct {
+	cache.Interface
+	deviceCode string
+	approve    func()
+}
+
+func (c *approvingCache) Get(key string) (string, error) {
+	value, err := c.Interface.Get(key)
+	if approve := c.approve; approve != nil && key == fmt.Sprintf(deviceCodeCacheKeyFormat, c.deviceCode) {
+		c.approve = nil
+		approve()
+	}
+	return value, err
+}
+
+func TestDevicePollDoesNotOverwriteApproval(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	inMemoryCache, err := cache.NewInMemoryCache(nil, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	approvingCache := &approvingCache{Interface: inMemoryCache}
+	operator := NewDeviceAuthorizationOperator(approvingCache)
+	deviceCode, authorization, err := operator.Create("kubectl", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := &user.DefaultInfo{Name: "admin"}
+	verificationToken, err := operator.IssueVerificationToken(authorization.UserCode, admin, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	approvingCache.deviceCode = deviceCode
+	approvingCache.approve = func() {
+		if err := operator.Approve(authorization.UserCode, verificationToken, admin, ""); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err = operator.Poll("kubectl", deviceCode); err != DeviceAuthorizationPendingError {
+		t.Errorf("expected %v, got %v", DeviceAuthorizationPendingError, err)
+	}
+	approved, err := operator.Poll("kubectl", deviceCode)
+	if err != nil {
+		t.Fatalf("expected the approval to be kept, got %v", err)
+	}
+	if approved.Username != "admin" {
+		t.Errorf("unexpected device authorization: %+v", approved)
+	}
+}
+
+func TestNormalizeUserCode(t *testing.T) {
+	tests := map[string]string{
+		"BCDF-GHJK": "BCDFGHJK",
+		"bcdf ghjk": "BCDFGHJK",
+		"BCDF-GHJ":  "",
+		"AEIO-UBCD": "",
+	}
+	for userCode, want := range tests {
+		if got := NormalizeUserCode(userCode); got != want {
+			t.Errorf("NormalizeUserCode(%q) = %q, want %q", userCode, got, want)
+		}
+	}
+}
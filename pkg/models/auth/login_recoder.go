@@ -17,6 +17,8 @@ import (
 )
 
 type LoginRecorder interface {
+	// RecordLogin records the login attempt of the user, the attempt carried by the context is flagged
+	// as failed if authErr is not nil, or as succeeded otherwise, see FlagLoginFailure and FlagLoginSuccess.
 	RecordLogin(ctx context.Context, username string, loginType iamv1beta1.LoginType, provider string, sourceIP string, userAgent string, authErr error) error
 }
 
@@ -34,6 +36,11 @@ func NewLoginRecorder(cacheClient runtimeclient.Client) LoginRecorder {
 
 // RecordLogin Create v1alpha2.LoginRecord for existing accounts
 func (l *loginRecorder) RecordLogin(ctx context.Context, username string, loginType iamv1beta1.LoginType, provider, sourceIP, userAgent string, authErr error) error {
+	if authErr != nil {
+		FlagLoginFailure(ctx)
+	} else {
+		FlagLoginSuccess(ctx)
+	}
 	// only for existing accounts, solve the problem of huge entries
 	user, err := l.userMapper.Find(ctx, username)
 	if err != nil {
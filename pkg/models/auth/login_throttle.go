@@ -0,0 +1,252 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	compbasemetrics "k8s.io/component-base/metrics"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication"
+	"kubesphere.io/kubesphere/pkg/apiserver/metrics"
+	"kubesphere.io/kubesphere/pkg/simple/client/cache"
+)
+
+const (
+	ThrottleScopeIP   = "ip"
+	ThrottleScopeCIDR = "cidr"
+
+	// the failures are counted in buckets of a fraction of the window, keyed by the index of the bucket
+	loginFailuresCacheKeyFormat = "kubesphere:login-throttle:failures:%s:%s:%d"
+	loginBlockedCacheKeyFormat  = "kubesphere:login-throttle:blocked:%s:%s"
+	// loginFailureBuckets is the number of buckets the window is divided into
+	loginFailureBuckets = 10
+)
+
+var (
+	registerThrottleMetricsOnce sync.Once
+
+	loginBlocked = compbasemetrics.NewCounterVec(
+		&compbasemetrics.CounterOpts{
+			Name:           "ks_login_throttle_blocked_total",
+			Help:           "Counter of source IPs or networks blocked for too many failed login attempts, broken out for each scope.",
+			StabilityLevel: compbasemetrics.ALPHA,
+		},
+		[]string{"scope"},
+	)
+
+	loginThrottled = compbasemetrics.NewCounterVec(
+		&compbasemetrics.CounterOpts{
+			Name:           "ks_login_throttle_rejected_requests_total",
+			Help:           "Counter of login requests rejected because the source IP or network is blocked, broken out for each scope.",
+			StabilityLevel: compbasemetrics.ALPHA,
+		},
+		[]string{"scope"},
+	)
+)
+
+// LoginBlock describes a source IP or network blocked by the login throttle.
+type LoginBlock struct {
+	// Scope is either ThrottleScopeIP or ThrottleScopeCIDR.
+	Scope string
+	// Subject is the blocked IP or CIDR.
+	Subject    string
+	RetryAfter time.Duration
+}
+
+type loginAttemptKey struct{}
+
+// LoginAttempt is carried by the context of a login request, the handlers flag it once the credentials
+// are found invalid, so that the other failures, e.g. the MFA challenges or the bad requests, aren't counted.
+type LoginAttempt struct {
+	failed    atomic.Bool
+	succeeded atomic.Bool
+}
+
+// Failed returns true if the credentials of the login attempt are invalid.
+func (a *LoginAttempt) Failed() bool {
+	return a.failed.Load()
+}
+
+// Succeeded returns true if the user of the login attempt is logged in.
+func (a *LoginAttempt) Succeeded() bool {
+	return a.succeeded.Load()
+}
+
+// WithLoginAttempt returns a copy of the context carrying a new login attempt.
+func WithLoginAttempt(ctx context.Context) (context.Context, *LoginAttempt) {
+	attempt := &LoginAttempt{}
+	return context.WithValue(ctx, loginAttemptKey{}, attempt), attempt
+}
+
+// FlagLoginFailure marks the login attempt carried by the context as failed, it's a no-op if there is none.
+func FlagLoginFailure(ctx context.Context) {
+	if attempt, ok := ctx.Value(loginAttemptKey{}).(*LoginAttempt); ok {
+		attempt.failed.Store(true)
+	}
+}
+
+// FlagLoginSuccess marks the login attempt carried by the context as succeeded, it's a no-op if there is none.
+func FlagLoginSuccess(ctx context.Context) {
+	if attempt, ok := ctx.Value(loginAttemptKey{}).(*LoginAttempt); ok {
+		attempt.succeeded.Store(true)
+	}
+}
+
+// LoginThrottle counts the failed login attempts for each source IP and the network it belongs to, so that
+// credential stuffing across many usernames is slowed down as well. The failures are counted in a window
+// sliding by a tenth of its length, a failure is forgotten between one window and one window and a tenth
+// after it. The counters are kept in the cache, which is shared by the replicas of ks-apiserver if Redis is used.
+type LoginThrottle interface {
+	// Blocked returns the block of the source IP, nil if the source IP is allowed to log in.
+	Blocked(ip string) (*LoginBlock, error)
+	// RecordFailure records a failed login attempt from the source IP, and returns the block
+	// if the failed attempts reach the maximum.
+	RecordFailure(ip string) (*LoginBlock, error)
+	// RecordSuccess forgets the failed login attempts from the source IP once a user logs in from it.
+	// The failures of the network are kept, so that an account of the attacker can't lift the throttle of the network.
+	RecordSuccess(ip string) error
+}
+
+type loginThrottle struct {
+	cache   cache.Interface
+	options *authentication.LoginThrottleOptions
+}
+
+func NewLoginThrottle(cache cache.Interface, options *authentication.LoginThrottleOptions) LoginThrottle {
+	registerThrottleMetricsOnce.Do(func() {
+		metrics.Registry.MustRegister(loginBlocked, loginThrottled)
+	})
+	return &loginThrottle{cache: cache, options: options}
+}
+
+type throttleSubject struct {
+	scope       string
+	subject     string
+	maxFailures int
+}
+
+func (l *loginThrottle) subjects(ip string) []throttleSubject {
+	subjects := []throttleSubject{{scope: ThrottleScopeIP, subject: ip, maxFailures: l.options.MaxFailuresPerIP}}
+	if l.options.MaxFailuresPerCIDR <= 0 {
+		return subjects
+	}
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return subjects
+	}
+	var network *net.IPNet
+	if ipv4 := parsed.To4(); ipv4 != nil {
+		mask := net.CIDRMask(l.options.CIDRPrefixIPv4, net.IPv4len*8)
+		network = &net.IPNet{IP: ipv4.Mask(mask), Mask: mask}
+	} else {
+		mask := net.CIDRMask(l.options.CIDRPrefixIPv6, net.IPv6len*8)
+		network = &net.IPNet{IP: parsed.Mask(mask), Mask: mask}
+	}
+	return append(subjects, throttleSubject{scope: ThrottleScopeCIDR, subject: network.String(), maxFailures: l.options.MaxFailuresPerCIDR})
+}
+
+func (l *loginThrottle) Blocked(ip string) (*LoginBlock, error) {
+	now := time.Now()
+	for _, s := range l.subjects(ip) {
+		value, err := l.cache.Get(fmt.Sprintf(loginBlockedCacheKeyFormat, s.scope, s.subject))
+		if err != nil {
+			if errors.Is(err, cache.ErrNoSuchKey) {
+				continue
+			}
+			return nil, err
+		}
+		blockedUntil, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if retryAfter := time.Unix(blockedUntil, 0).Sub(now); retryAfter > 0 {
+			loginThrottled.WithLabelValues(s.scope).Inc()
+			return &LoginBlock{Scope: s.scope, Subject: s.subject, RetryAfter: retryAfter}, nil
+		}
+	}
+	return nil, nil
+}
+
+func (l *loginThrottle) RecordFailure(ip string) (*LoginBlock, error) {
+	now := time.Now()
+	var block *LoginBlock
+	for _, s := range l.subjects(ip) {
+		keys := l.failureKeys(s, now)
+		// the counter is incremented atomically, so that the failures recorded by the replicas aren't lost,
+		// and expires once the bucket slides out of the window
+		failures, err := l.cache.Incr(keys[0], l.options.Window+l.bucketSize())
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys[1:] {
+			value, err := l.cache.Get(key)
+			if err != nil {
+				if errors.Is(err, cache.ErrNoSuchKey) {
+					continue
+				}
+				return nil, err
+			}
+			count, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			failures += count
+		}
+		if failures < int64(s.maxFailures) {
+			continue
+		}
+
+		// start over once the block expires
+		if err = l.cache.Del(keys...); err != nil {
+			return nil, err
+		}
+		blockedUntil := now.Add(l.options.BlockDuration)
+		if err = l.cache.Set(fmt.Sprintf(loginBlockedCacheKeyFormat, s.scope, s.subject),
+			strconv.FormatInt(blockedUntil.Unix(), 10), l.options.BlockDuration); err != nil {
+			return nil, err
+		}
+		loginBlocked.WithLabelValues(s.scope).Inc()
+		if block == nil {
+			block = &LoginBlock{Scope: s.scope, Subject: s.subject, RetryAfter: l.options.BlockDuration}
+		}
+	}
+	return block, nil
+}
+
+func (l *loginThrottle) RecordSuccess(ip string) error {
+	s := l.subjects(ip)[0]
+	return l.cache.Del(l.failureKeys(s, time.Now())...)
+}
+
+// bucketSize returns the length of the buckets the window is divided into.
+func (l *loginThrottle) bucketSize() time.Duration {
+	if size := l.options.Window / loginFailureBuckets; size > 0 {
+		return size
+	}
+	return l.options.Window
+}
+
+// failureKeys returns the keys of the buckets counting the failures of the subject in the window ending at now,
+// the first one is the bucket of now. The bucket the window starts in is counted in whole.
+func (l *loginThrottle) failureKeys(s throttleSubject, now time.Time) []string {
+	size := l.bucketSize()
+	current := now.UnixNano() / int64(size)
+	buckets := int64(l.options.Window / size)
+	keys := make([]string, 0, buckets+1)
+	for i := int64(0); i <= buckets; i++ {
+		keys = append(keys, fmt.Sprintf(loginFailuresCacheKeyFormat, s.scope, s.subject, current-i))
+	}
+	return keys
+}
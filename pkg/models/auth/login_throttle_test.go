@@ -0,0 +1,187 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package auth
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication"
+	"kubesphere.io/kubesphere/pkg/simple/client/cache"
+)
+
+func TestLoginThrottle(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	inMemoryCache, err := cache.NewInMemoryCache(nil, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := authentication.NewOptions().LoginThrottle
+	options.MaxFailuresPerIP = 3
+	options.MaxFailuresPerCIDR = 5
+	throttle := NewLoginThrottle(inMemoryCache, &options)
+
+	for i := 0; i < 2; i++ {
+		if block, err := throttle.RecordFailure("10.0.0.1"); err != nil || block != nil {
+			t.Fatalf("expected no block, got %v, %v", block, err)
+		}
+	}
+	block, err := throttle.RecordFailure("10.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block == nil || block.Scope != ThrottleScopeIP || block.Subject != "10.0.0.1" || block.RetryAfter != options.BlockDuration {
+		t.Fatalf("expected the IP to be blocked, got %+v", block)
+	}
+	if block, err = throttle.Blocked("10.0.0.1"); err != nil || block == nil {
+		t.Fatalf("expected the IP to be blocked, got %v, %v", block, err)
+	}
+	if block, err = throttle.Blocked("10.0.0.2"); err != nil || block != nil {
+		t.Fatalf("expected the other IP not to be blocked, got %v, %v", block, err)
+	}
+
+	// the failures of the network are counted across the IPs
+	if block, err = throttle.RecordFailure("10.0.0.2"); err != nil || block != nil {
+		t.Fatalf("expected no block, got %v, %v", block, err)
+	}
+	if block, err = throttle.RecordFailure("10.0.0.3"); err != nil {
+		t.Fatal(err)
+	}
+	if block == nil || block.Scope != ThrottleScopeCIDR || block.Subject != "10.0.0.0/24" {
+		t.Fatalf("expected the network to be blocked, got %+v", block)
+	}
+	if block, err = throttle.Blocked("10.0.0.5"); err != nil || block == nil {
+		t.Fatalf("expected the IP in the blocked network to be blocked, got %v, %v", block, err)
+	}
+	if block, err = throttle.Blocked("10.0.1.1"); err != nil || block != nil {
+		t.Fatalf("expected the IP in the other network not to be blocked, got %v, %v", block, err)
+	}
+}
+
+func TestLoginThrottleWindow(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	inMemoryCache, err := cache.NewInMemoryCache(nil, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := authentication.NewOptions().LoginThrottle
+	options.MaxFailuresPerIP = 3
+	options.MaxFailuresPerCIDR = 0
+	options.Window = 200 * time.Millisecond
+	throttle := NewLoginThrottle(inMemoryCache, &options)
+
+	if block, err := throttle.RecordFailure("2001:db8::1"); err != nil || block != nil {
+		t.Fatalf("expected no block, got %v, %v", block, err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if block, err := throttle.RecordFailure("2001:db8::1"); err != nil || block != nil {
+		t.Fatalf("expected no block, got %v, %v", block, err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	// the first failure slides out of the window, the second one is still counted
+	if block, err := throttle.RecordFailure("2001:db8::1"); err != nil || block != nil {
+		t.Fatalf("expected no block, got %v, %v", block, err)
+	}
+	if block, err := throttle.RecordFailure("2001:db8::1"); err != nil || block == nil {
+		t.Fatalf("expected the IP to be blocked, got %v, %v", block, err)
+	}
+
+	if block, err := throttle.RecordFailure("2001:db8::2"); err != nil || block != nil {
+		t.Fatalf("expected no block, got %v, %v", block, err)
+	}
+	time.Sleep(2 * options.Window)
+	for i := 0; i < options.MaxFailuresPerIP-1; i++ {
+		if block, err := throttle.RecordFailure("2001:db8::2"); err != nil || block != nil {
+			t.Fatalf("expected the failure out of the window to be forgotten, got %v, %v", block, err)
+		}
+	}
+}
+
+func TestLoginThrottleRecordSuccess(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	inMemoryCache, err := cache.NewInMemoryCache(nil, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := authentication.NewOptions().LoginThrottle
+	options.MaxFailuresPerIP = 2
+	options.MaxFailuresPerCIDR = 3
+	throttle := NewLoginThrottle(inMemoryCache, &options)
+
+	if block, err := throttle.RecordFailure("10.0.0.1"); err != nil || block != nil {
+		t.Fatalf("expected no block, got %v, %v", block, err)
+	}
+	if err = throttle.RecordSuccess("10.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if block, err := throttle.RecordFailure("10.0.0.1"); err != nil || block != nil {
+		t.Fatalf("expected the failures of the IP to be forgotten, got %v, %v", block, err)
+	}
+	// the failures of the network are kept
+	block, err := throttle.RecordFailure("10.0.0.2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if block == nil || block.Scope != ThrottleScopeCIDR {
+		t.Fatalf("expected the network to be blocked, got %+v", block)
+	}
+}
+
+func TestLoginThrottleConcurrentFailures(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	inMemoryCache, err := cache.NewInMemoryCache(nil, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := authentication.NewOptions().LoginThrottle
+	options.MaxFailuresPerIP = 20
+	options.MaxFailuresPerCIDR = 0
+	throttle := NewLoginThrottle(inMemoryCache, &options)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < options.MaxFailuresPerIP-1; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := throttle.RecordFailure("10.0.0.1"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	// none of the failures recorded concurrently is lost
+	if block, err := throttle.RecordFailure("10.0.0.1"); err != nil || block == nil {
+		t.Fatalf("expected the IP to be blocked, got %v, %v", block, err)
+	}
+}
+
+func TestFlagLoginFailure(t *testing.T) {
+	// no-op without a login attempt
+	FlagLoginFailure(context.Background())
+
+	ctx, attempt := WithLoginAttempt(context.Background())
+	if attempt.Failed() {
+		t.Fatal("expected the login attempt not to be failed")
+	}
+	FlagLoginFailure(ctx)
+	if !attempt.Failed() {
+		t.Fatal("expected the login attempt to be failed")
+	}
+
+	FlagLoginSuccess(context.Background())
+	ctx, attempt = WithLoginAttempt(context.Background())
+	FlagLoginSuccess(ctx)
+	if !attempt.Succeeded() || attempt.Failed() {
+		t.Fatal("expected the login attempt to be succeeded")
+	}
+}
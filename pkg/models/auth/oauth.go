@@ -10,24 +10,31 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"time"
 
 	authuser "k8s.io/apiserver/pkg/authentication/user"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication/identityprovider"
+	"kubesphere.io/kubesphere/pkg/simple/client/cache"
 )
 
+const assertionCacheKeyFormat = "kubesphere:oauth:assertion:%s:%s"
+
 type oauthAuthenticator struct {
 	client                 runtimeclient.Client
 	userMapper             UserMapper
 	idpConfigurationGetter identityprovider.ConfigurationGetter
+	// cache holds the assertions accepted until they expire
+	cache cache.Interface
 }
 
-func NewOAuthAuthenticator(client runtimeclient.Client) OAuthAuthenticator {
+func NewOAuthAuthenticator(client runtimeclient.Client, cache cache.Interface) OAuthAuthenticator {
 	authenticator := &oauthAuthenticator{
 		client:                 client,
 		userMapper:             &userMapper{cache: client},
 		idpConfigurationGetter: identityprovider.NewConfigurationGetter(client),
+		cache:                  cache,
 	}
 	return authenticator
 }
@@ -49,5 +56,29 @@ func (o *oauthAuthenticator) Authenticate(ctx context.Context, provider string,
 		return nil, fmt.Errorf("failed to exchange identity for %s, error: %v", provider, err)
 	}
 
+	if asserted, ok := identity.(identityprovider.AssertedIdentity); ok {
+		if err = o.acceptAssertion(provider, asserted); err != nil {
+			return nil, err
+		}
+	}
+
 	return authByIdentityProvider(ctx, o.client, o.userMapper, providerConfig, identity)
 }
+
+// acceptAssertion records the assertion in the cache until it expires, the assertion already accepted is replayed.
+// The assertion is recorded only if absent, so that it's accepted once across the replicas sharing the cache.
+func (o *oauthAuthenticator) acceptAssertion(provider string, identity identityprovider.AssertedIdentity) error {
+	ttl := time.Until(identity.GetAssertionExpiry())
+	if identity.GetAssertionID() == "" || ttl <= 0 {
+		return fmt.Errorf("invalid assertion for %s", provider)
+	}
+	key := fmt.Sprintf(assertionCacheKeyFormat, provider, identity.GetAssertionID())
+	accepted, err := o.cache.SetNX(key, provider, ttl)
+	if err != nil {
+		return err
+	}
+	if !accepted {
+		return fmt.Errorf("the assertion %s for %s has already been used", identity.GetAssertionID(), provider)
+	}
+	return nil
+}
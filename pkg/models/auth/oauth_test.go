@@ -11,7 +11,10 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
 
@@ -21,6 +24,7 @@ import (
 	"gopkg.in/yaml.v3"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apiserver/pkg/authentication/user"
 	runtimefakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
 
@@ -29,6 +33,7 @@ import (
 	"kubesphere.io/kubesphere/pkg/apiserver/authentication/identityprovider"
 	"kubesphere.io/kubesphere/pkg/scheme"
 	"kubesphere.io/kubesphere/pkg/server/options"
+	"kubesphere.io/kubesphere/pkg/simple/client/cache"
 )
 
 func Test_oauthAuthenticator_Authenticate(t *testing.T) {
@@ -95,6 +100,13 @@ func Test_oauthAuthenticator_Authenticate(t *testing.T) {
 		WithRuntimeObjects(newUser("user1", "100001", "fake"), secret, blockedUser).
 		Build()
 
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	inMemoryCache, err := cache.NewInMemoryCache(nil, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	type args struct {
 		ctx      context.Context
 		provider string
@@ -110,7 +122,7 @@ func Test_oauthAuthenticator_Authenticate(t *testing.T) {
 	}{
 		{
 			name:               "Should successfully",
-			oauthAuthenticator: NewOAuthAuthenticator(client),
+			oauthAuthenticator: NewOAuthAuthenticator(client, inMemoryCache),
 			args: args{
 				ctx:      context.Background(),
 				provider: "fake",
@@ -124,7 +136,7 @@ func Test_oauthAuthenticator_Authenticate(t *testing.T) {
 		},
 		{
 			name:               "Blocked user test",
-			oauthAuthenticator: NewOAuthAuthenticator(client),
+			oauthAuthenticator: NewOAuthAuthenticator(client, inMemoryCache),
 			args: args{
 				ctx:      context.Background(),
 				provider: "fake",
@@ -136,7 +148,7 @@ func Test_oauthAuthenticator_Authenticate(t *testing.T) {
 		},
 		{
 			name:               "Should successfully",
-			oauthAuthenticator: NewOAuthAuthenticator(client),
+			oauthAuthenticator: NewOAuthAuthenticator(client, inMemoryCache),
 			args: args{
 				ctx:      context.Background(),
 				provider: "fake1",
@@ -160,6 +172,96 @@ func Test_oauthAuthenticator_Authenticate(t *testing.T) {
 	}
 }
 
+type fakeAssertedIdentity struct {
+	fakeIdentity
+	assertionID     string
+	assertionExpiry time.Time
+}
+
+func (f fakeAssertedIdentity) GetAssertionID() string {
+	return f.assertionID
+}
+
+func (f fakeAssertedIdentity) GetAssertionExpiry() time.Time {
+	return f.assertionExpiry
+}
+
+func Test_authByIdentityProvider_autoMapping(t *testing.T) {
+	existing := &iamv1beta1.User{
+		ObjectMeta: metav1.ObjectMeta{Name: "user3"},
+		Status:     iamv1beta1.UserStatus{State: iamv1beta1.UserPasswordResetRequired},
+	}
+	client := runtimefakeclient.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithRuntimeObjects(existing).
+		Build()
+	providerConfig := &identityprovider.Configuration{Name: "fake", MappingMethod: identityprovider.MappingMethodAuto}
+	mapper := &userMapper{cache: client}
+	ctx := context.Background()
+
+	for _, identity := range []fakeIdentity{{UID: "100003", Username: "user3"}, {UID: "100004", Username: "user4"}} {
+		if _, err := authByIdentityProvider(ctx, client, mapper, providerConfig, identity); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the state of the existing user is kept, the new user is active
+	expected := map[string]iamv1beta1.UserState{"user3": iamv1beta1.UserPasswordResetRequired, "user4": iamv1beta1.UserActive}
+	for username, state := range expected {
+		mapped := &iamv1beta1.User{}
+		if err := client.Get(ctx, types.NamespacedName{Name: username}, mapped); err != nil {
+			t.Fatal(err)
+		}
+		if mapped.Status.State != state {
+			t.Errorf("expected the state of %s to be %s, got %s", username, state, mapped.Status.State)
+		}
+	}
+}
+
+func Test_oauthAuthenticator_acceptAssertion(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	inMemoryCache, err := cache.NewInMemoryCache(nil, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := NewOAuthAuthenticator(nil, inMemoryCache).(*oauthAuthenticator)
+
+	identity := fakeAssertedIdentity{assertionID: "assertion1", assertionExpiry: time.Now().Add(time.Minute)}
+	if err = authenticator.acceptAssertion("saml", identity); err != nil {
+		t.Fatal(err)
+	}
+	if err = authenticator.acceptAssertion("saml", identity); err == nil {
+		t.Error("expected the replayed assertion to be rejected")
+	}
+	// the assertions are identified in the scope of the identity provider
+	if err = authenticator.acceptAssertion("saml2", identity); err != nil {
+		t.Error(err)
+	}
+	expired := fakeAssertedIdentity{assertionID: "assertion2", assertionExpiry: time.Now().Add(-time.Second)}
+	if err = authenticator.acceptAssertion("saml", expired); err == nil {
+		t.Error("expected the expired assertion to be rejected")
+	}
+
+	// the assertion replayed concurrently is accepted once
+	replayed := fakeAssertedIdentity{assertionID: "assertion3", assertionExpiry: time.Now().Add(time.Minute)}
+	var accepted atomic.Int32
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if authenticator.acceptAssertion("saml", replayed) == nil {
+				accepted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if accepted.Load() != 1 {
+		t.Errorf("expected the assertion to be accepted once, got %d", accepted.Load())
+	}
+}
+
 func must(r *http.Request, err error) *http.Request {
 	if err != nil {
 		panic(err)
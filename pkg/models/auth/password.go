@@ -62,7 +62,8 @@ func (p *passwordAuthenticator) authByKubeSphere(ctx context.Context, username,
 	switch user.Status.State {
 	case iamv1beta1.UserAuthLimitExceeded:
 		return nil, RateLimitExceededError
-	case iamv1beta1.UserActive:
+	// the user must reset the password after logging in
+	case iamv1beta1.UserActive, iamv1beta1.UserPasswordResetRequired:
 		break
 	default:
 		return nil, AccountIsNotActiveError
@@ -83,6 +84,13 @@ func (p *passwordAuthenticator) authByKubeSphere(ctx context.Context, username,
 			iamv1beta1.ExtraUninitialized: {uninitialized},
 		}
 	}
+	// the console guides the user to reset the password
+	if user.Status.State == iamv1beta1.UserPasswordResetRequired {
+		if info.Extra == nil {
+			info.Extra = map[string][]string{}
+		}
+		info.Extra[iamv1beta1.ExtraPasswordResetRequired] = []string{"true"}
+	}
 
 	return info, nil
 }
@@ -110,6 +118,13 @@ func (p *passwordAuthenticator) authByProvider(ctx context.Context, provider, us
 	return authByIdentityProvider(ctx, p.client, p.userMapper, providerConfig, identity)
 }
 
+// IsEncryptedPassword returns whether the given password is already a bcrypt hash,
+// which must not be accepted as a new password since it bypasses the password policy.
+func IsEncryptedPassword(password string) bool {
+	cost, _ := bcrypt.Cost([]byte(password))
+	return cost > 0
+}
+
 func PasswordVerify(encryptedPassword, password string) error {
 	if err := bcrypt.CompareHashAndPassword([]byte(encryptedPassword), []byte(password)); err != nil {
 		return IncorrectPasswordError
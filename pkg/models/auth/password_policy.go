@@ -0,0 +1,233 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+	"kubesphere.io/kubesphere/pkg/constants"
+)
+
+var PasswordResetRequiredError = fmt.Errorf("password reset is required")
+
+const (
+	SecretTypePasswordHistory corev1.SecretType = "iam.kubesphere.io/password-history"
+
+	passwordHistoryNameFormat = "password-history-%s"
+	secretKeyPasswordHistory  = "history"
+	// usernameCheckMinLength avoids rejecting the passwords containing a very short username by accident.
+	usernameCheckMinLength = 3
+)
+
+// commonPasswords are rejected by the dictionary check regardless of the configured dictionary.
+var commonPasswords = []string{
+	"123456", "12345678", "123456789", "1234567890", "111111", "000000", "123123",
+	"password", "password1", "password123", "p@ssw0rd", "p@88w0rd", "passw0rd",
+	"qwerty", "qwerty123", "qwertyuiop", "1q2w3e4r", "1qaz2wsx", "abc123", "abcd1234",
+	"iloveyou", "welcome", "welcome1", "admin", "admin123", "administrator",
+	"letmein", "monkey", "dragon", "sunshine", "princess", "football", "changeme",
+	"kubesphere", "kubernetes",
+}
+
+// PasswordPolicyError lists the rules of the password policy that a password violates.
+type PasswordPolicyError struct {
+	Reasons []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return fmt.Sprintf("the password does not satisfy the password policy: %s", strings.Join(e.Reasons, "; "))
+}
+
+// PasswordPolicy enforces the complexity and the history rules of the passwords of local accounts.
+// The hashes of the recent passwords of a user are stored in a Secret in the kubesphere-system namespace,
+// which is garbage collected with the user.
+type PasswordPolicy interface {
+	// Validate checks the plain text password of the user against the policy.
+	Validate(ctx context.Context, username, password string) error
+	// RecordPassword appends the encrypted password of the user to the password history.
+	RecordPassword(ctx context.Context, user *iamv1beta1.User) error
+}
+
+type passwordPolicy struct {
+	client  runtimeclient.Client
+	options *authentication.PasswordPolicyOptions
+}
+
+func NewPasswordPolicy(client runtimeclient.Client, options *authentication.PasswordPolicyOptions) PasswordPolicy {
+	return &passwordPolicy{client: client, options: options}
+}
+
+func (p *passwordPolicy) Validate(ctx context.Context, username, password string) error {
+	var reasons []string
+	if utf8.RuneCountInString(password) < p.options.MinLength {
+		reasons = append(reasons, fmt.Sprintf("must be at least %d characters long", p.options.MinLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+	if p.options.RequireUppercase && !hasUpper {
+		reasons = append(reasons, "must contain an uppercase letter")
+	}
+	if p.options.RequireLowercase && !hasLower {
+		reasons = append(reasons, "must contain a lowercase letter")
+	}
+	if p.options.RequireDigit && !hasDigit {
+		reasons = append(reasons, "must contain a digit")
+	}
+	if p.options.RequireSymbol && !hasSymbol {
+		reasons = append(reasons, "must contain a symbol")
+	}
+
+	if p.options.DictionaryCheck {
+		if reason := p.dictionaryCheck(username, password); reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+
+	if len(reasons) == 0 && p.options.HistoryCount > 0 {
+		reused, err := p.reused(ctx, username, password)
+		if err != nil {
+			return err
+		}
+		if reused {
+			reasons = append(reasons, fmt.Sprintf("must not be the same as any of the last %d passwords", p.options.HistoryCount))
+		}
+	}
+
+	if len(reasons) > 0 {
+		return &PasswordPolicyError{Reasons: reasons}
+	}
+	return nil
+}
+
+func (p *passwordPolicy) dictionaryCheck(username, password string) string {
+	lower := strings.ToLower(password)
+	for _, common := range commonPasswords {
+		if lower == common {
+			return "must not be a commonly used password"
+		}
+	}
+	if len(username) >= usernameCheckMinLength && strings.Contains(lower, strings.ToLower(username)) {
+		return "must not contain the username"
+	}
+	for _, word := range p.options.Dictionary {
+		if word != "" && strings.Contains(lower, strings.ToLower(word)) {
+			return fmt.Sprintf("must not contain the word %q", word)
+		}
+	}
+	return ""
+}
+
+// reused returns whether the password matches any of the recorded passwords of the user.
+func (p *passwordPolicy) reused(ctx context.Context, username, password string) (bool, error) {
+	hashes, err := p.history(ctx, username)
+	if err != nil {
+		return false, err
+	}
+	for i, hash := range hashes {
+		if i >= p.options.HistoryCount {
+			break
+		}
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (p *passwordPolicy) history(ctx context.Context, username string) ([]string, error) {
+	secret := &corev1.Secret{}
+	if err := p.client.Get(ctx, passwordHistoryKey(username), secret); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	data := strings.TrimSpace(string(secret.Data[secretKeyPasswordHistory]))
+	if data == "" {
+		return nil, nil
+	}
+	return strings.Split(data, "\n"), nil
+}
+
+func (p *passwordPolicy) RecordPassword(ctx context.Context, user *iamv1beta1.User) error {
+	if p.options.HistoryCount <= 0 || user.Spec.EncryptedPassword == "" {
+		return nil
+	}
+	hashes, err := p.history(ctx, user.Name)
+	if err != nil {
+		return err
+	}
+	// the latest first
+	hashes = append([]string{user.Spec.EncryptedPassword}, hashes...)
+	if len(hashes) > p.options.HistoryCount {
+		hashes = hashes[:p.options.HistoryCount]
+	}
+
+	key := passwordHistoryKey(user.Name)
+	secret := &corev1.Secret{}
+	if err = p.client.Get(ctx, key, secret); err != nil {
+		if !errors.IsNotFound(err) {
+			return err
+		}
+		secret = &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: key.Name, Namespace: key.Namespace}}
+	}
+	secret.Type = SecretTypePasswordHistory
+	secret.Labels = map[string]string{iamv1beta1.UserReferenceLabel: user.Name}
+	// the secret is garbage collected with the user
+	secret.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: iamv1beta1.SchemeGroupVersion.String(),
+		Kind:       iamv1beta1.ResourceKindUser,
+		Name:       user.Name,
+		UID:        user.UID,
+	}}
+	secret.Data = map[string][]byte{secretKeyPasswordHistory: []byte(strings.Join(hashes, "\n"))}
+	if secret.ResourceVersion == "" {
+		return p.client.Create(ctx, secret)
+	}
+	return p.client.Update(ctx, secret)
+}
+
+func passwordHistoryKey(username string) runtimeclient.ObjectKey {
+	return runtimeclient.ObjectKey{Namespace: constants.KubeSphereNamespace, Name: fmt.Sprintf(passwordHistoryNameFormat, username)}
+}
+
+// IsPasswordResetRequest returns whether the request is allowed for a user who must reset the password,
+// that is, describing the user itself or changing the password of its own.
+func IsPasswordResetRequest(ctx context.Context, username string) bool {
+	requestInfo, ok := request.RequestInfoFrom(ctx)
+	if !ok || !requestInfo.IsResourceRequest ||
+		requestInfo.Resource != iamv1beta1.ResourcesPluralUser || requestInfo.Name != username {
+		return false
+	}
+	return (requestInfo.Subresource == "" && requestInfo.Verb == "get") ||
+		(requestInfo.Subresource == "password" && requestInfo.Verb == "update")
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	runtimefakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication"
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	options := authentication.NewOptions().PasswordPolicy
+	options.RequireSymbol = true
+	options.DictionaryCheck = true
+	options.Dictionary = []string{"Acme"}
+	policy := NewPasswordPolicy(runtimefakeclient.NewClientBuilder().WithScheme(scheme.Scheme).Build(), &options)
+
+	tests := []struct {
+		password string
+		valid    bool
+	}{
+		{password: "Kub3-Sph3re", valid: true},
+		{password: "K3-s", valid: false},
+		{password: "kub3-sph3re", valid: false},
+		{password: "KUB3-SPH3RE", valid: false},
+		{password: "Kube-Sphere", valid: false},
+		{password: "Kub3Sph3re", valid: false},
+		{password: "P@88w0rd", valid: false},
+		{password: "Jane-Doe-123", valid: false},
+		{password: "acme-Corp-1", valid: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			err := policy.Validate(context.Background(), "jane", tt.password)
+			if tt.valid && err != nil {
+				t.Errorf("expected %s to be valid, got %v", tt.password, err)
+			}
+			var policyErr *PasswordPolicyError
+			if !tt.valid && !errors.As(err, &policyErr) {
+				t.Errorf("expected %s to violate the policy, got %v", tt.password, err)
+			}
+		})
+	}
+}
+
+func TestPasswordHistory(t *testing.T) {
+	options := authentication.NewOptions().PasswordPolicy
+	options.HistoryCount = 2
+	user := &iamv1beta1.User{ObjectMeta: metav1.ObjectMeta{Name: "jane", UID: "1"}}
+	policy := NewPasswordPolicy(runtimefakeclient.NewClientBuilder().WithScheme(scheme.Scheme).Build(), &options)
+
+	for _, password := range []string{"Passw0rd-1", "Passw0rd-2", "Passw0rd-3"} {
+		if err := policy.Validate(context.Background(), user.Name, password); err != nil {
+			t.Fatalf("expected %s to be valid, got %v", password, err)
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		user.Spec.EncryptedPassword = string(hash)
+		if err = policy.RecordPassword(context.Background(), user); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for password, reused := range map[string]bool{"Passw0rd-1": false, "Passw0rd-2": true, "Passw0rd-3": true} {
+		err := policy.Validate(context.Background(), user.Name, password)
+		if reused != (err != nil) {
+			t.Errorf("expected %s reused to be %v, got %v", password, reused, err)
+		}
+	}
+}
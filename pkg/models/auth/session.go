@@ -0,0 +1,242 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/token"
+	"kubesphere.io/kubesphere/pkg/simple/client/cache"
+)
+
+var (
+	SessionNotFoundError = errors.New("session not found")
+	SessionExpiredError  = errors.New("session expired due to inactivity")
+)
+
+const (
+	sessionCacheKeyFormat = "kubesphere:user:%s:session:%s"
+	// the last activity is stored apart from the session, so that recording the activity never recreates a revoked session
+	sessionActivityCacheKeyFormat = "kubesphere:user:%s:sessionactivity:%s"
+	sessionIDSize                 = 16
+	// sessionActivityUpdateInterval limits how often the last activity of a session is written to the cache.
+	sessionActivityUpdateInterval = time.Minute
+)
+
+// Session is a login session of a user, all the tokens issued in the session are
+// invalidated as soon as the session is revoked or expired.
+type Session struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+	// ClientID is the OAuth client the tokens are issued to.
+	ClientID string `json:"clientID,omitempty"`
+	// Provider, SourceIP and UserAgent are recorded in the LoginRecord of the login as well.
+	Provider     string     `json:"provider,omitempty"`
+	SourceIP     string     `json:"sourceIP,omitempty"`
+	UserAgent    string     `json:"userAgent,omitempty"`
+	IssueTime    time.Time  `json:"issueTime"`
+	LastActivity time.Time  `json:"lastActivity"`
+	ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+}
+
+// SessionManagementInterface manages the login sessions of the users.
+type SessionManagementInterface interface {
+	// SaveSession creates the session if the ID is empty, or updates the existing session.
+	SaveSession(session *Session) error
+	// ListSessions returns the active sessions of the user, the latest first.
+	ListSessions(username string) ([]*Session, error)
+	// DescribeSession returns the active session of the user.
+	DescribeSession(username, id string) (*Session, error)
+	// RevokeSession logs out the session, the tokens issued in the session are invalidated.
+	RevokeSession(username, id string) error
+	// RevokeAllSessions logs out all the sessions of the user.
+	RevokeAllSessions(username string) error
+}
+
+func (t *tokenOperator) SaveSession(session *Session) error {
+	now := time.Now()
+	if session.ID == "" {
+		id, err := generateSessionID()
+		if err != nil {
+			return err
+		}
+		session.ID = id
+	}
+	if session.IssueTime.IsZero() {
+		session.IssueTime = now
+	}
+	if session.LastActivity.IsZero() {
+		session.LastActivity = now
+	}
+
+	duration := cache.NeverExpire
+	if session.ExpiresAt != nil {
+		if duration = session.ExpiresAt.Sub(now); duration <= 0 {
+			return t.cache.Del(sessionCacheKey(session.Username, session.ID))
+		}
+	}
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return t.cache.Set(sessionCacheKey(session.Username, session.ID), string(data), duration)
+}
+
+func (t *tokenOperator) ListSessions(username string) ([]*Session, error) {
+	keys, err := t.cache.Keys(sessionCacheKey(username, "*"))
+	if err != nil {
+		return nil, err
+	}
+	sessions := make([]*Session, 0, len(keys))
+	for _, key := range keys {
+		session, err := t.getSession(key)
+		if err != nil {
+			// the session has expired after listing the keys
+			if errors.Is(err, SessionNotFoundError) {
+				continue
+			}
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].IssueTime.After(sessions[j].IssueTime)
+	})
+	return sessions, nil
+}
+
+func (t *tokenOperator) DescribeSession(username, id string) (*Session, error) {
+	return t.getSession(sessionCacheKey(username, id))
+}
+
+func (t *tokenOperator) RevokeSession(username, id string) error {
+	key := sessionCacheKey(username, id)
+	if exist, err := t.cache.Exists(key); err != nil {
+		return err
+	} else if !exist {
+		return SessionNotFoundError
+	}
+	return t.cache.Del(key, sessionActivityCacheKey(username, id))
+}
+
+func (t *tokenOperator) RevokeAllSessions(username string) error {
+	keys, err := t.cache.Keys(sessionCacheKey(username, "*"))
+	if err != nil {
+		return err
+	}
+	activityKeys, err := t.cache.Keys(sessionActivityCacheKey(username, "*"))
+	if err != nil {
+		return err
+	}
+	if keys = append(keys, activityKeys...); len(keys) > 0 {
+		return t.cache.Del(keys...)
+	}
+	return nil
+}
+
+func (t *tokenOperator) getSession(key string) (*Session, error) {
+	data, err := t.cache.Get(key)
+	if err != nil {
+		if errors.Is(err, cache.ErrNoSuchKey) {
+			return nil, SessionNotFoundError
+		}
+		return nil, err
+	}
+	session := &Session{}
+	if err = json.Unmarshal([]byte(data), session); err != nil {
+		return nil, err
+	}
+	activity, err := t.cache.Get(sessionActivityCacheKey(session.Username, session.ID))
+	if err != nil {
+		if errors.Is(err, cache.ErrNoSuchKey) {
+			return session, nil
+		}
+		return nil, err
+	}
+	if lastActivity, err := time.Parse(time.RFC3339Nano, activity); err == nil && lastActivity.After(session.LastActivity) {
+		session.LastActivity = lastActivity
+	}
+	return session, nil
+}
+
+// recordActivity records the last activity of the session, the record is removed if the session
+// has been revoked in the meantime, so that a revoked session is never brought back.
+func (t *tokenOperator) recordActivity(session *Session, now time.Time) error {
+	duration := cache.NeverExpire
+	if session.ExpiresAt != nil {
+		if duration = session.ExpiresAt.Sub(now); duration <= 0 {
+			return nil
+		}
+	}
+	key := sessionActivityCacheKey(session.Username, session.ID)
+	if err := t.cache.Set(key, now.Format(time.RFC3339Nano), duration); err != nil {
+		return err
+	}
+	exist, err := t.cache.Exists(sessionCacheKey(session.Username, session.ID))
+	if err != nil {
+		return err
+	}
+	if !exist {
+		return t.cache.Del(key)
+	}
+	return nil
+}
+
+// sessionValidate verifies that the session of the token is active, and records the activity
+// of the user if the token is an access token.
+func (t *tokenOperator) sessionValidate(username, id string, tokenType token.Type) error {
+	session, err := t.DescribeSession(username, id)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	idleTimeout := t.options.SessionIdleTimeout
+	if idleTimeout > 0 && now.Sub(session.LastActivity) > idleTimeout {
+		if err = t.RevokeSession(username, id); err != nil && !errors.Is(err, SessionNotFoundError) {
+			klog.Warningf("failed to revoke idle session %s of user %s: %s", id, username, err)
+		}
+		return SessionExpiredError
+	}
+	// the refresh token may be used by the client in the background, which isn't an activity of the user
+	if tokenType != token.AccessToken {
+		return nil
+	}
+	updateInterval := sessionActivityUpdateInterval
+	if idleTimeout > 0 && idleTimeout/10 < updateInterval {
+		updateInterval = idleTimeout / 10
+	}
+	if now.Sub(session.LastActivity) > updateInterval {
+		if err = t.recordActivity(session, now); err != nil {
+			klog.Warningf("failed to update the last activity of session %s of user %s: %s", id, username, err)
+		}
+	}
+	return nil
+}
+
+func sessionCacheKey(username, id string) string {
+	return fmt.Sprintf(sessionCacheKeyFormat, username, id)
+}
+
+func sessionActivityCacheKey(username, id string) string {
+	return fmt.Sprintf(sessionActivityCacheKeyFormat, username, id)
+}
+
+func generateSessionID() (string, error) {
+	b := make([]byte, sessionIDSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
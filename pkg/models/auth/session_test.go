@@ -0,0 +1,204 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apiserver/pkg/authentication/user"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication"
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/token"
+	"kubesphere.io/kubesphere/pkg/simple/client/cache"
+)
+
+func newTestTokenOperator(t *testing.T, stopCh <-chan struct{}) *tokenOperator {
+	inMemoryCache, err := cache.NewInMemoryCache(nil, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	options := authentication.NewOptions()
+	options.Issuer.JWTSecret = "kubesphere"
+	options.SessionIdleTimeout = 30 * time.Minute
+	operator, err := NewTokenOperator(inMemoryCache, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := token.GenerateSigningKeyData("ES256")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := token.ParseSigningKey(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	operator.Keyring().Update([]*token.Key{key})
+	return operator.(*tokenOperator)
+}
+
+func issueSessionToken(t *testing.T, operator *tokenOperator, username string, tokenType token.Type, session *Session) string {
+	tokenStr, err := operator.IssueTo(&token.IssueRequest{
+		User:      &user.DefaultInfo{Name: username},
+		Claims:    token.Claims{TokenType: tokenType, SessionID: session.ID},
+		ExpiresIn: time.Hour,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokenStr
+}
+
+func TestSessions(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	operator := newTestTokenOperator(t, stopCh)
+
+	expiresAt := time.Now().Add(time.Hour)
+	first := &Session{Username: "admin", SourceIP: "10.0.0.1", UserAgent: "curl", ExpiresAt: &expiresAt}
+	if err := operator.SaveSession(first); err != nil {
+		t.Fatal(err)
+	}
+	second := &Session{Username: "admin", SourceIP: "10.0.0.2", IssueTime: time.Now().Add(time.Second), ExpiresAt: &expiresAt}
+	if err := operator.SaveSession(second); err != nil {
+		t.Fatal(err)
+	}
+	if first.ID == "" || first.ID == second.ID {
+		t.Fatalf("expected unique session IDs, got %q and %q", first.ID, second.ID)
+	}
+
+	sessions, err := operator.ListSessions("admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 || sessions[0].ID != second.ID || sessions[1].SourceIP != "10.0.0.1" {
+		t.Fatalf("unexpected sessions: %+v", sessions)
+	}
+
+	accessToken := issueSessionToken(t, operator, "admin", token.AccessToken, first)
+	refreshToken := issueSessionToken(t, operator, "admin", token.RefreshToken, first)
+	otherToken := issueSessionToken(t, operator, "admin", token.AccessToken, second)
+	verified, err := operator.Verify(accessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if verified.SessionID != first.ID {
+		t.Errorf("expected session %s, got %s", first.ID, verified.SessionID)
+	}
+
+	if err = operator.RevokeSession("admin", first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err = operator.RevokeSession("admin", first.ID); err != SessionNotFoundError {
+		t.Errorf("expected %v, got %v", SessionNotFoundError, err)
+	}
+	if _, err = operator.Verify(accessToken); err != SessionNotFoundError {
+		t.Errorf("expected the access token of the revoked session to be invalid, got %v", err)
+	}
+	if _, err = operator.Verify(refreshToken); err != SessionNotFoundError {
+		t.Errorf("expected the refresh token of the revoked session to be invalid, got %v", err)
+	}
+	if _, err = operator.Verify(otherToken); err != nil {
+		t.Errorf("expected the token of the other session to be valid, got %v", err)
+	}
+
+	if err = operator.RevokeAllUserTokens("admin"); err != nil {
+		t.Fatal(err)
+	}
+	if sessions, err = operator.ListSessions("admin"); err != nil || len(sessions) != 0 {
+		t.Errorf("expected no sessions, got %v, %v", sessions, err)
+	}
+}
+
+func TestSessionIdleTimeout(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	operator := newTestTokenOperator(t, stopCh)
+
+	expiresAt := time.Now().Add(time.Hour)
+	idle := &Session{Username: "admin", LastActivity: time.Now().Add(-time.Hour), ExpiresAt: &expiresAt}
+	if err := operator.SaveSession(idle); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := operator.Verify(issueSessionToken(t, operator, "admin", token.AccessToken, idle)); err != SessionExpiredError {
+		t.Errorf("expected %v, got %v", SessionExpiredError, err)
+	}
+	if _, err := operator.DescribeSession("admin", idle.ID); err != SessionNotFoundError {
+		t.Errorf("expected the idle session to be removed, got %v", err)
+	}
+
+	lastActivity := time.Now().Add(-10 * time.Minute)
+	active := &Session{Username: "admin", LastActivity: lastActivity, ExpiresAt: &expiresAt}
+	if err := operator.SaveSession(active); err != nil {
+		t.Fatal(err)
+	}
+	// the refresh token isn't an activity of the user
+	if _, err := operator.Verify(issueSessionToken(t, operator, "admin", token.RefreshToken, active)); err != nil {
+		t.Fatal(err)
+	}
+	session, err := operator.DescribeSession("admin", active.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !session.LastActivity.Equal(lastActivity) {
+		t.Errorf("expected the last activity not to be updated by the refresh token")
+	}
+	if _, err = operator.Verify(issueSessionToken(t, operator, "admin", token.AccessToken, active)); err != nil {
+		t.Fatal(err)
+	}
+	if session, err = operator.DescribeSession("admin", active.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !session.LastActivity.After(lastActivity) {
+		t.Errorf("expected the last activity to be updated by the access token")
+	}
+}
+
+// revokingCache revokes the session right before the next write to the cache,
+// so that the revocation lands between the validation and the write of the last activity.
+type revokingCache struct {
+	cache.Interface
+	revoke func()
+}
+
+func (c *revokingCache) Set(key string, value string, duration time.Duration) error {
+	if revoke := c.revoke; revoke != nil {
+		c.revoke = nil
+		revoke()
+	}
+	return c.Interface.Set(key, value, duration)
+}
+
+func TestSessionRevokedWhileRecordingActivity(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	operator := newTestTokenOperator(t, stopCh)
+
+	expiresAt := time.Now().Add(time.Hour)
+	session := &Session{Username: "admin", LastActivity: time.Now().Add(-10 * time.Minute), ExpiresAt: &expiresAt}
+	if err := operator.SaveSession(session); err != nil {
+		t.Fatal(err)
+	}
+	accessToken := issueSessionToken(t, operator, "admin", token.AccessToken, session)
+
+	revokingCache := &revokingCache{Interface: operator.cache}
+	operator.cache = revokingCache
+	revokingCache.revoke = func() {
+		if err := operator.RevokeSession("admin", session.ID); err != nil {
+			t.Error(err)
+		}
+	}
+	if _, err := operator.Verify(accessToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := operator.DescribeSession("admin", session.ID); err != SessionNotFoundError {
+		t.Errorf("expected the revoked session not to be recreated, got %v", err)
+	}
+	if _, err := operator.Verify(accessToken); err != SessionNotFoundError {
+		t.Errorf("expected %v, got %v", SessionNotFoundError, err)
+	}
+}
@@ -31,6 +31,10 @@ type TokenManagementInterface interface {
 	RevokeAllUserTokens(username string) error
 	// Keys hold encryption and signing keys.
 	Keys() *token.Keys
+	// Keyring holds the keys signing the tokens.
+	Keyring() *token.Keyring
+	// SessionManagementInterface manages the login sessions the tokens are issued in.
+	SessionManagementInterface
 }
 
 type tokenOperator struct {
@@ -77,6 +81,11 @@ func (t *tokenOperator) Verify(tokenStr string) (*token.VerifiedResponse, error)
 	if err := t.tokenCacheValidate(response.User.GetName(), tokenStr); err != nil {
 		return nil, err
 	}
+	if response.SessionID != "" {
+		if err := t.sessionValidate(response.User.GetName(), response.SessionID, response.TokenType); err != nil {
+			return nil, err
+		}
+	}
 	return response, nil
 }
 
@@ -93,7 +102,7 @@ func (t *tokenOperator) IssueTo(request *token.IssueRequest) (string, error) {
 	return tokenStr, nil
 }
 
-// RevokeAllUserTokens revoke all user tokens in the cache
+// RevokeAllUserTokens revoke all user tokens and sessions in the cache
 func (t *tokenOperator) RevokeAllUserTokens(username string) error {
 	pattern := fmt.Sprintf("kubesphere:user:%s:token:*", username)
 	if keys, err := t.cache.Keys(pattern); err != nil {
@@ -103,13 +112,17 @@ func (t *tokenOperator) RevokeAllUserTokens(username string) error {
 			return err
 		}
 	}
-	return nil
+	return t.RevokeAllSessions(username)
 }
 
 func (t *tokenOperator) Keys() *token.Keys {
 	return t.issuer.Keys()
 }
 
+func (t *tokenOperator) Keyring() *token.Keyring {
+	return t.issuer.Keyring()
+}
+
 // tokenCacheValidate verify that the token is in the cache
 func (t *tokenOperator) tokenCacheValidate(username, token string) error {
 	key := fmt.Sprintf("kubesphere:user:%s:token:%s", username, token)
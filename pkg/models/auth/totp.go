@@ -0,0 +1,454 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication"
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/totp"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/simple/client/cache"
+)
+
+var (
+	IncorrectOTPError   = fmt.Errorf("incorrect one-time password")
+	TOTPNotEnabledError = fmt.Errorf("totp is not enabled")
+	MFARequiredError    = fmt.Errorf("multi-factor authentication is required")
+	// MFAEnrollmentRequiredError is returned if the user must enable TOTP before accessing the other APIs.
+	MFAEnrollmentRequiredError = fmt.Errorf("multi-factor authentication enrollment is required")
+	// IncorrectCurrentOTPError is returned if the user rebinds TOTP without a valid one-time password of the bound auth key.
+	IncorrectCurrentOTPError = fmt.Errorf("incorrect one-time password of the current auth key")
+	AuthKeyNotGeneratedError = fmt.Errorf("the auth key is expired or not generated")
+)
+
+const (
+	SecretTypeTOTPAuthKey corev1.SecretType = "iam.kubesphere.io/totp-auth-key"
+	RecoveryCodesCount                      = 10
+
+	totpAuthKeyNameFormat  = "totp-auth-key-%s"
+	secretKeyAuthKey       = "authKey"
+	secretKeyRecoveryCodes = "recoveryCodes"
+	recoveryCodeSize       = 5
+	// pendingAuthKeyTTL is how long a generated auth key can be bound
+	pendingAuthKeyTTL = 10 * time.Minute
+)
+
+type TOTPStatus struct {
+	Enabled                bool `json:"enabled" description:"whether the user logs in with a one-time password"`
+	Required               bool `json:"required" description:"whether the user is required to enable TOTP"`
+	RecoveryCodesRemaining int  `json:"recoveryCodesRemaining" description:"the number of unused recovery codes"`
+}
+
+// TOTPOperator manages the time-based one-time password (TOTP) second factor of users.
+// The auth key and the hashed recovery codes of a user are stored in a Secret
+// in the kubesphere-system namespace, which is referenced by an annotation of the user.
+type TOTPOperator interface {
+	// GenerateAuthKey generates an auth key URI for the user to enroll an authenticator app with,
+	// the auth key is kept until it's bound or expired.
+	GenerateAuthKey(username string) (string, error)
+	// Bind enables TOTP with the generated auth key once the one-time password generated by it is verified,
+	// and returns the recovery codes. If TOTP is already enabled, a one-time password or a recovery code
+	// of the bound auth key is required as the currentOTP.
+	Bind(ctx context.Context, username, otp, currentOTP string) ([]string, error)
+	// Unbind disables TOTP of the user.
+	Unbind(ctx context.Context, username string) error
+	// Enabled returns whether the user has enabled TOTP.
+	Enabled(ctx context.Context, username string) (bool, error)
+	// Required returns whether the MFA policy requires the user to enable TOTP,
+	// globally or as a member of a workspace that requires MFA.
+	Required(ctx context.Context, username string) (bool, error)
+	Status(ctx context.Context, username string) (*TOTPStatus, error)
+	// Verify verifies a one-time password or a recovery code of the user,
+	// each of them can only be used once.
+	Verify(ctx context.Context, username, otp string) error
+	// RegenerateRecoveryCodes replaces the recovery codes of the user.
+	RegenerateRecoveryCodes(ctx context.Context, username string) ([]string, error)
+}
+
+type totpOperator struct {
+	client  runtimeclient.Client
+	cache   cache.Interface
+	options *authentication.MultiFactorAuthOptions
+}
+
+func NewTOTPOperator(client runtimeclient.Client, cache cache.Interface, options *authentication.Options) TOTPOperator {
+	return &totpOperator{client: client, cache: cache, options: &options.MultiFactorAuthOptions}
+}
+
+func (t *totpOperator) GenerateAuthKey(username string) (string, error) {
+	key, err := totp.GenerateKey(t.options.Issuer, username)
+	if err != nil {
+		return "", err
+	}
+	if err = t.cache.Set(pendingAuthKey(username), key.URI(), pendingAuthKeyTTL); err != nil {
+		return "", err
+	}
+	return key.URI(), nil
+}
+
+func (t *totpOperator) Bind(ctx context.Context, username, otp, currentOTP string) ([]string, error) {
+	// only the auth key generated by the server can be bound
+	authKey, err := t.cache.Get(pendingAuthKey(username))
+	if err != nil {
+		if err == cache.ErrNoSuchKey {
+			return nil, AuthKeyNotGeneratedError
+		}
+		return nil, err
+	}
+	key, err := totp.ParseKey(authKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// the new one-time password is checked before the current one is consumed,
+	// so that a mistyped one doesn't waste the current one-time password or recovery code
+	if _, err = t.check(username, key, otp); err != nil {
+		return nil, err
+	}
+	enabled, err := t.Enabled(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	// the enrollment can't be replaced without the second factor of the current one
+	if enabled {
+		if err = t.Verify(ctx, username, currentOTP); err != nil {
+			if err == IncorrectOTPError {
+				return nil, IncorrectCurrentOTPError
+			}
+			return nil, err
+		}
+	}
+	if err = t.validate(username, key, otp); err != nil {
+		return nil, err
+	}
+
+	user := &iamv1beta1.User{}
+	if err = t.client.Get(ctx, runtimeclient.ObjectKey{Name: username}, user); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{
+		Name:      fmt.Sprintf(totpAuthKeyNameFormat, username),
+		Namespace: constants.KubeSphereNamespace,
+	}}
+	if err = t.client.Get(ctx, runtimeclient.ObjectKeyFromObject(secret), secret); err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	secret.Type = SecretTypeTOTPAuthKey
+	secret.Labels = map[string]string{iamv1beta1.UserReferenceLabel: username}
+	// the secret is garbage collected with the user
+	secret.OwnerReferences = []metav1.OwnerReference{{
+		APIVersion: iamv1beta1.SchemeGroupVersion.String(),
+		Kind:       iamv1beta1.ResourceKindUser,
+		Name:       user.Name,
+		UID:        user.UID,
+	}}
+	secret.Data = map[string][]byte{
+		secretKeyAuthKey:       []byte(key.URI()),
+		secretKeyRecoveryCodes: []byte(strings.Join(hashes, "\n")),
+	}
+	if secret.ResourceVersion == "" {
+		err = t.client.Create(ctx, secret)
+	} else {
+		err = t.client.Update(ctx, secret)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Annotations == nil {
+		user.Annotations = map[string]string{}
+	}
+	user.Annotations[iamv1beta1.TOTPAuthKeyRefAnnotation] = secret.Name
+	if err = t.client.Update(ctx, user); err != nil {
+		return nil, err
+	}
+	if err = t.cache.Del(pendingAuthKey(username)); err != nil {
+		klog.Warningf("failed to delete the pending totp auth key of user %s: %v", username, err)
+	}
+	return codes, nil
+}
+
+func (t *totpOperator) Unbind(ctx context.Context, username string) error {
+	user := &iamv1beta1.User{}
+	if err := t.client.Get(ctx, runtimeclient.ObjectKey{Name: username}, user); err != nil {
+		return err
+	}
+	secretName := user.Annotations[iamv1beta1.TOTPAuthKeyRefAnnotation]
+	if secretName == "" {
+		return nil
+	}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: constants.KubeSphereNamespace}}
+	if err := t.client.Delete(ctx, secret); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	delete(user.Annotations, iamv1beta1.TOTPAuthKeyRefAnnotation)
+	return t.client.Update(ctx, user)
+}
+
+func (t *totpOperator) Enabled(ctx context.Context, username string) (bool, error) {
+	secret, err := t.authKeySecret(ctx, username)
+	if err != nil {
+		if err == TOTPNotEnabledError {
+			return false, nil
+		}
+		return false, err
+	}
+	return secret != nil, nil
+}
+
+func (t *totpOperator) Required(ctx context.Context, username string) (bool, error) {
+	if t.options.Required {
+		return true, nil
+	}
+	roleBindings := &iamv1beta1.WorkspaceRoleBindingList{}
+	if err := t.client.List(ctx, roleBindings, runtimeclient.MatchingLabels{iamv1beta1.UserReferenceLabel: username}); err != nil {
+		return false, err
+	}
+	for _, roleBinding := range roleBindings.Items {
+		workspaceName := roleBinding.Labels[tenantv1beta1.WorkspaceLabel]
+		if workspaceName == "" {
+			continue
+		}
+		workspace := &tenantv1beta1.WorkspaceTemplate{}
+		if err := t.client.Get(ctx, runtimeclient.ObjectKey{Name: workspaceName}, workspace); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return false, err
+		}
+		if workspace.Annotations[iamv1beta1.MFARequiredAnnotation] == "true" {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (t *totpOperator) Status(ctx context.Context, username string) (*TOTPStatus, error) {
+	status := &TOTPStatus{}
+	required, err := t.Required(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	status.Required = required
+	secret, err := t.authKeySecret(ctx, username)
+	if err == TOTPNotEnabledError {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	status.Enabled = true
+	status.RecoveryCodesRemaining = len(recoveryCodeHashes(secret))
+	return status, nil
+}
+
+func (t *totpOperator) Verify(ctx context.Context, username, otp string) error {
+	user := &iamv1beta1.User{}
+	if err := t.client.Get(ctx, runtimeclient.ObjectKey{Name: username}, user); err != nil {
+		if errors.IsNotFound(err) {
+			return TOTPNotEnabledError
+		}
+		return err
+	}
+	// failed attempts are recorded as login records, the user is blocked
+	// in the same way as guessing the password
+	switch user.Status.State {
+	case iamv1beta1.UserAuthLimitExceeded:
+		return RateLimitExceededError
+	case iamv1beta1.UserDisabled:
+		return AccountIsNotActiveError
+	}
+
+	secret, err := t.authKeySecret(ctx, username)
+	if err != nil {
+		return err
+	}
+	if isPasscode(otp) {
+		key, err := totp.ParseKey(string(secret.Data[secretKeyAuthKey]))
+		if err != nil {
+			return err
+		}
+		return t.validate(username, key, otp)
+	}
+
+	hash := hashRecoveryCode(otp)
+	hashes := recoveryCodeHashes(secret)
+	for i, h := range hashes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			// the update fails on conflicts, so a recovery code can't be used twice concurrently
+			secret.Data[secretKeyRecoveryCodes] = []byte(strings.Join(append(hashes[:i:i], hashes[i+1:]...), "\n"))
+			return t.client.Update(ctx, secret)
+		}
+	}
+	return IncorrectOTPError
+}
+
+func (t *totpOperator) RegenerateRecoveryCodes(ctx context.Context, username string) ([]string, error) {
+	secret, err := t.authKeySecret(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	secret.Data[secretKeyRecoveryCodes] = []byte(strings.Join(hashes, "\n"))
+	if err = t.client.Update(ctx, secret); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// validate validates the one-time password, a password is rejected if it has been used.
+func (t *totpOperator) validate(username string, key *totp.Key, otp string) error {
+	usedKey, err := t.check(username, key, otp)
+	if err != nil {
+		return err
+	}
+	// the password is marked as used atomically, so that only one of the concurrent requests succeeds
+	unused, err := t.cache.SetNX(usedKey, otp, (2*totp.Skew+1)*totp.Period)
+	if err != nil {
+		return err
+	}
+	if !unused {
+		return IncorrectOTPError
+	}
+	return nil
+}
+
+// check returns the cache key marking the password as used if the password is valid and not used yet,
+// the passwords of the bound and the pending auth keys are tracked separately.
+func (t *totpOperator) check(username string, key *totp.Key, otp string) (string, error) {
+	step, ok := key.Validate(otp, time.Now())
+	if !ok {
+		return "", IncorrectOTPError
+	}
+	fingerprint := sha256.Sum256([]byte(key.Secret))
+	usedKey := fmt.Sprintf("kubesphere:user:%s:otp:%s:%d", username, hex.EncodeToString(fingerprint[:8]), step)
+	used, err := t.cache.Exists(usedKey)
+	if err != nil {
+		return "", err
+	}
+	if used {
+		return "", IncorrectOTPError
+	}
+	return usedKey, nil
+}
+
+func (t *totpOperator) authKeySecret(ctx context.Context, username string) (*corev1.Secret, error) {
+	user := &iamv1beta1.User{}
+	if err := t.client.Get(ctx, runtimeclient.ObjectKey{Name: username}, user); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, TOTPNotEnabledError
+		}
+		return nil, err
+	}
+	secretName := user.Annotations[iamv1beta1.TOTPAuthKeyRefAnnotation]
+	if secretName == "" {
+		return nil, TOTPNotEnabledError
+	}
+	secret := &corev1.Secret{}
+	if err := t.client.Get(ctx, runtimeclient.ObjectKey{Namespace: constants.KubeSphereNamespace, Name: secretName}, secret); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, TOTPNotEnabledError
+		}
+		return nil, err
+	}
+	if secret.Type != SecretTypeTOTPAuthKey || secret.Labels[iamv1beta1.UserReferenceLabel] != username {
+		return nil, TOTPNotEnabledError
+	}
+	return secret, nil
+}
+
+func pendingAuthKey(username string) string {
+	return fmt.Sprintf("kubesphere:user:%s:totp-auth-key", username)
+}
+
+func isPasscode(otp string) bool {
+	if len(otp) != totp.Digits {
+		return false
+	}
+	for _, c := range otp {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func recoveryCodeHashes(secret *corev1.Secret) []string {
+	var hashes []string
+	for _, hash := range strings.Split(string(secret.Data[secretKeyRecoveryCodes]), "\n") {
+		if hash != "" {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes
+}
+
+// generateRecoveryCodes generates recovery codes in the format of xxxx-xxxx and their hashes.
+func generateRecoveryCodes() ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, 0, RecoveryCodesCount)
+	hashes := make([]string, 0, RecoveryCodesCount)
+	for i := 0; i < RecoveryCodesCount; i++ {
+		buf := make([]byte, recoveryCodeSize)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(encoding.EncodeToString(buf))
+		code = code[:4] + "-" + code[4:]
+		codes = append(codes, code)
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsMFAEnrollmentRequest returns whether the request is allowed for a user who must enable TOTP,
+// that is, enrolling the TOTP of its own, or logging out.
+func IsMFAEnrollmentRequest(ctx context.Context, username string) bool {
+	requestInfo, ok := request.RequestInfoFrom(ctx)
+	if !ok {
+		return false
+	}
+	if !requestInfo.IsResourceRequest {
+		return requestInfo.Path == "/oauth/logout"
+	}
+	return requestInfo.APIGroup == iamv1beta1.SchemeGroupVersion.Group &&
+		requestInfo.Resource == iamv1beta1.ResourcesPluralUser && requestInfo.Name == username &&
+		requestInfo.Subresource == "totp"
+}
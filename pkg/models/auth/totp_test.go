@@ -0,0 +1,213 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package auth
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	runtimefakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication"
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/totp"
+	"kubesphere.io/kubesphere/pkg/scheme"
+	"kubesphere.io/kubesphere/pkg/simple/client/cache"
+)
+
+func newTestTOTPOperator(t *testing.T, options *authentication.Options) TOTPOperator {
+	client := runtimefakeclient.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(
+			&iamv1beta1.User{ObjectMeta: metav1.ObjectMeta{Name: "admin"}},
+			&tenantv1beta1.WorkspaceTemplate{ObjectMeta: metav1.ObjectMeta{
+				Name:        "ws1",
+				Annotations: map[string]string{iamv1beta1.MFARequiredAnnotation: "true"},
+			}},
+			&iamv1beta1.WorkspaceRoleBinding{ObjectMeta: metav1.ObjectMeta{
+				Name: "tester-ws1-viewer",
+				Labels: map[string]string{
+					iamv1beta1.UserReferenceLabel: "tester",
+					tenantv1beta1.WorkspaceLabel:  "ws1",
+				},
+			}},
+		).Build()
+	stopCh := make(chan struct{})
+	t.Cleanup(func() { close(stopCh) })
+	inMemoryCache, err := cache.NewInMemoryCache(nil, stopCh)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return NewTOTPOperator(client, inMemoryCache, options)
+}
+
+func TestTOTPOperator(t *testing.T) {
+	ctx := context.Background()
+	operator := newTestTOTPOperator(t, authentication.NewOptions())
+
+	authKey, err := operator.GenerateAuthKey("admin")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	key, err := totp.ParseKey(authKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Now()
+	otp, _ := key.Code(now)
+
+	if _, err = operator.Bind(ctx, "admin", "000000", ""); err != IncorrectOTPError {
+		t.Fatalf("expected incorrect otp error, got %v", err)
+	}
+	codes, err := operator.Bind(ctx, "admin", otp, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(codes) != RecoveryCodesCount {
+		t.Fatalf("got %d recovery codes, want %d", len(codes), RecoveryCodesCount)
+	}
+	if enabled, _ := operator.Enabled(ctx, "admin"); !enabled {
+		t.Fatalf("totp should be enabled")
+	}
+
+	// the otp used to bind can't be replayed
+	if err = operator.Verify(ctx, "admin", otp); err != IncorrectOTPError {
+		t.Errorf("expected incorrect otp error on replay, got %v", err)
+	}
+	next, _ := key.Code(now.Add(totp.Period))
+	if next != otp {
+		if err = operator.Verify(ctx, "admin", next); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	// recovery codes are accepted only once, in upper case as well
+	if err = operator.Verify(ctx, "admin", strings.ToUpper(codes[0])); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err = operator.Verify(ctx, "admin", codes[0]); err != IncorrectOTPError {
+		t.Errorf("expected incorrect otp error on a used recovery code, got %v", err)
+	}
+	status, err := operator.Status(ctx, "admin")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !status.Enabled || status.RecoveryCodesRemaining != RecoveryCodesCount-1 {
+		t.Errorf("unexpected status %+v", status)
+	}
+
+	if err = operator.Unbind(ctx, "admin"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err = operator.Verify(ctx, "admin", codes[1]); err != TOTPNotEnabledError {
+		t.Errorf("expected totp not enabled error, got %v", err)
+	}
+}
+
+func TestTOTPRebind(t *testing.T) {
+	ctx := context.Background()
+	operator := newTestTOTPOperator(t, authentication.NewOptions())
+
+	if _, err := operator.Bind(ctx, "admin", "000000", ""); err != AuthKeyNotGeneratedError {
+		t.Fatalf("expected auth key not generated error, got %v", err)
+	}
+	authKey, err := operator.GenerateAuthKey("admin")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	key, _ := totp.ParseKey(authKey)
+	now := time.Now()
+	otp, _ := key.Code(now)
+	codes, err := operator.Bind(ctx, "admin", otp, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the bound auth key can't be bound again
+	if _, err = operator.Bind(ctx, "admin", otp, codes[0]); err != AuthKeyNotGeneratedError {
+		t.Fatalf("expected auth key not generated error, got %v", err)
+	}
+
+	authKey, err = operator.GenerateAuthKey("admin")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	newKey, _ := totp.ParseKey(authKey)
+	newOTP, _ := newKey.Code(now)
+	if _, err = operator.Bind(ctx, "admin", newOTP, ""); err != IncorrectCurrentOTPError {
+		t.Fatalf("expected incorrect current otp error, got %v", err)
+	}
+	// the recovery code is kept if the new otp is incorrect
+	if _, err = operator.Bind(ctx, "admin", "000000", codes[0]); err != IncorrectOTPError {
+		t.Fatalf("expected incorrect otp error, got %v", err)
+	}
+	if _, err = operator.Bind(ctx, "admin", newOTP, codes[0]); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the recovery codes of the previous enrollment are replaced
+	if err = operator.Verify(ctx, "admin", codes[1]); err != IncorrectOTPError {
+		t.Errorf("expected incorrect otp error, got %v", err)
+	}
+}
+
+func TestTOTPReplayedConcurrently(t *testing.T) {
+	ctx := context.Background()
+	operator := newTestTOTPOperator(t, authentication.NewOptions())
+	authKey, err := operator.GenerateAuthKey("admin")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	key, err := totp.ParseKey(authKey)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	now := time.Now()
+	otp, _ := key.Code(now)
+	if _, err = operator.Bind(ctx, "admin", otp, ""); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	next, _ := key.Code(now.Add(totp.Period))
+	var accepted atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := operator.Verify(ctx, "admin", next); err == nil {
+				accepted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if accepted.Load() != 1 {
+		t.Errorf("expected the one-time password to be accepted once, got %d", accepted.Load())
+	}
+}
+
+func TestTOTPRequired(t *testing.T) {
+	ctx := context.Background()
+	operator := newTestTOTPOperator(t, authentication.NewOptions())
+	if required, _ := operator.Required(ctx, "admin"); required {
+		t.Errorf("admin is not a member of a workspace requiring mfa")
+	}
+	if required, _ := operator.Required(ctx, "tester"); !required {
+		t.Errorf("tester is a member of a workspace requiring mfa")
+	}
+
+	options := authentication.NewOptions()
+	options.MultiFactorAuthOptions.Required = true
+	operator = newTestTOTPOperator(t, options)
+	if required, _ := operator.Required(ctx, "admin"); !required {
+		t.Errorf("mfa is required globally")
+	}
+}
@@ -69,6 +69,11 @@ type AccessManagementInterface interface {
 	RemoveUserFromWorkspace(username string, workspace string) error
 	RemoveUserFromNamespace(username string, namespace string) error
 	RemoveUserFromCluster(username string) error
+
+	GetAccessRequest(name string) (*iamv1beta1.AccessRequest, error)
+	ListAccessRequests(username string) ([]iamv1beta1.AccessRequest, error)
+	CreateAccessRequest(accessRequest *iamv1beta1.AccessRequest) error
+	UpdateAccessRequest(accessRequest *iamv1beta1.AccessRequest) error
 }
 
 type amOperator struct {
@@ -738,3 +743,38 @@ func (am *amOperator) GetRoleTemplate(name string) (*iamv1beta1.RoleTemplate, er
 	}
 	return roleTemplate, nil
 }
+
+func (am *amOperator) GetAccessRequest(name string) (*iamv1beta1.AccessRequest, error) {
+	accessRequest := &iamv1beta1.AccessRequest{}
+	if err := am.resourceManager.Get(context.Background(), "", name, accessRequest); err != nil {
+		return nil, err
+	}
+	return accessRequest, nil
+}
+
+// ListAccessRequests lists the access requests of the user, or all the access requests if the username is empty.
+func (am *amOperator) ListAccessRequests(username string) ([]iamv1beta1.AccessRequest, error) {
+	accessRequests := &iamv1beta1.AccessRequestList{}
+	queryParam := query.New()
+	if username != "" {
+		if err := queryParam.AppendLabelSelector(map[string]string{iamv1beta1.UserReferenceLabel: username}); err != nil {
+			return nil, err
+		}
+	}
+	if err := am.resourceManager.List(context.Background(), "", queryParam, accessRequests); err != nil {
+		return nil, err
+	}
+	return accessRequests.Items, nil
+}
+
+func (am *amOperator) CreateAccessRequest(accessRequest *iamv1beta1.AccessRequest) error {
+	if accessRequest.Labels == nil {
+		accessRequest.Labels = make(map[string]string)
+	}
+	accessRequest.Labels[iamv1beta1.UserReferenceLabel] = accessRequest.Spec.Username
+	return am.resourceManager.Create(context.Background(), accessRequest)
+}
+
+func (am *amOperator) UpdateAccessRequest(accessRequest *iamv1beta1.AccessRequest) error {
+	return am.resourceManager.Update(context.Background(), accessRequest)
+}
@@ -221,3 +221,25 @@ func labelGroupWithWorkspaceName(namespace *iamv1beta1.Group, workspaceName stri
 
 	return namespace
 }
+
+// ListUserGroups returns the groups the user is bound to by the GroupBindings. The groups of the user
+// are resolved by the GroupBindings instead of the user, since the users are able to update themselves.
+func ListUserGroups(ctx context.Context, reader runtimeclient.Reader, username string) ([]string, error) {
+	groupBindings := &iamv1beta1.GroupBindingList{}
+	if err := reader.List(ctx, groupBindings); err != nil {
+		return nil, err
+	}
+	var groups []string
+	for _, groupBinding := range groupBindings.Items {
+		if groupBinding.DeletionTimestamp != nil {
+			continue
+		}
+		for _, user := range groupBinding.Users {
+			if user == username {
+				groups = append(groups, groupBinding.GroupRef.Name)
+				break
+			}
+		}
+	}
+	return groups, nil
+}
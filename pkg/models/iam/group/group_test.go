@@ -0,0 +1,43 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package group
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	runtimefakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func TestListUserGroups(t *testing.T) {
+	client := runtimefakeclient.NewClientBuilder().WithScheme(scheme.Scheme).WithObjects(
+		&iamv1beta1.User{
+			ObjectMeta: metav1.ObjectMeta{Name: "tester"},
+			// the groups of the user are ignored
+			Spec: iamv1beta1.UserSpec{Groups: []string{"platform-admins"}},
+		},
+		&iamv1beta1.GroupBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "developers-tester"},
+			GroupRef:   iamv1beta1.GroupRef{Name: "developers"},
+			Users:      []string{"someone", "tester"},
+		},
+		&iamv1beta1.GroupBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "testers-someone"},
+			GroupRef:   iamv1beta1.GroupRef{Name: "testers"},
+			Users:      []string{"someone"},
+		},
+	).Build()
+
+	groups, err := ListUserGroups(context.Background(), client, "tester")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"developers"}, groups)
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package group
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/v2"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/identityprovider"
+)
+
+// SyncIdentityProviderGroups binds the user to the groups mapped from the groups at the identity provider.
+// The mapped groups are created if they don't exist, and the group bindings created by the identity provider
+// are deleted if the user isn't a member of the external group any more. The group bindings created manually
+// are left untouched.
+func SyncIdentityProviderGroups(ctx context.Context, client runtimeclient.Client, provider, username string, groups []identityprovider.MappedGroup) error {
+	groupBindings := &iamv1beta1.GroupBindingList{}
+	if err := client.List(ctx, groupBindings, runtimeclient.MatchingLabels{
+		iamv1beta1.UserReferenceLabel:    username,
+		iamv1beta1.IdentityProviderLabel: provider,
+	}); err != nil {
+		return err
+	}
+
+	desired := make(map[string]identityprovider.MappedGroup, len(groups))
+	for _, group := range groups {
+		desired[group.Name] = group
+	}
+
+	bound := make(map[string]bool)
+	for i := range groupBindings.Items {
+		groupBinding := &groupBindings.Items[i]
+		if _, ok := desired[groupBinding.GroupRef.Name]; ok && !bound[groupBinding.GroupRef.Name] {
+			bound[groupBinding.GroupRef.Name] = true
+			continue
+		}
+		if err := client.Delete(ctx, groupBinding); runtimeclient.IgnoreNotFound(err) != nil {
+			return err
+		}
+		klog.V(4).Infof("user %s is unbound from group %s of identity provider %s", username, groupBinding.GroupRef.Name, provider)
+	}
+
+	for _, mapped := range groups {
+		if bound[mapped.Name] {
+			continue
+		}
+		group, err := ensureGroup(ctx, client, provider, mapped)
+		if err != nil {
+			return err
+		}
+		if group == nil {
+			klog.Warningf("group %s of identity provider %s is skipped, the group of the same name isn't created by the identity provider", mapped.Name, provider)
+			continue
+		}
+		if err = client.Create(ctx, NewGroupBinding(group, username, provider)); err != nil {
+			return err
+		}
+		klog.V(4).Infof("user %s is bound to group %s of identity provider %s", username, group.Name, provider)
+	}
+	return nil
+}
+
+// ensureGroup returns the group created by the identity provider, it's created if it doesn't exist.
+// Nil is returned if the group of the same name isn't created by the identity provider, which is not taken over.
+func ensureGroup(ctx context.Context, client runtimeclient.Client, provider string, mapped identityprovider.MappedGroup) (*iamv1beta1.Group, error) {
+	group := &iamv1beta1.Group{}
+	err := client.Get(ctx, runtimeclient.ObjectKey{Name: mapped.Name}, group)
+	if err == nil {
+		return ownedGroup(group, provider), nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	group = &iamv1beta1.Group{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        mapped.Name,
+			Labels:      map[string]string{iamv1beta1.IdentityProviderLabel: provider},
+			Annotations: map[string]string{iamv1beta1.ExternalGroupAnnotation: mapped.ExternalGroup},
+		},
+	}
+	if mapped.Workspace != "" {
+		group.Labels[tenantv1beta1.WorkspaceLabel] = mapped.Workspace
+	}
+	if err = client.Create(ctx, group); err != nil {
+		if errors.IsAlreadyExists(err) {
+			if err = client.Get(ctx, runtimeclient.ObjectKey{Name: mapped.Name}, group); err != nil {
+				return nil, err
+			}
+			return ownedGroup(group, provider), nil
+		}
+		return nil, err
+	}
+	return group, nil
+}
+
+// ownedGroup returns the group if it's labeled with the identity provider, otherwise nil.
+func ownedGroup(group *iamv1beta1.Group, provider string) *iamv1beta1.Group {
+	if group.Labels[iamv1beta1.IdentityProviderLabel] != provider {
+		return nil
+	}
+	return group
+}
+
+// NewGroupBinding returns the group binding of the user created by the identity provider.
+func NewGroupBinding(group *iamv1beta1.Group, username, provider string) *iamv1beta1.GroupBinding {
+	groupBinding := &iamv1beta1.GroupBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("%s-%s-", group.Name, username),
+			Labels: map[string]string{
+				iamv1beta1.UserReferenceLabel:    username,
+				iamv1beta1.GroupReferenceLabel:   group.Name,
+				iamv1beta1.IdentityProviderLabel: provider,
+			},
+		},
+		GroupRef: iamv1beta1.GroupRef{
+			APIGroup: iamv1beta1.SchemeGroupVersion.Group,
+			Kind:     iamv1beta1.ResourcePluralGroup,
+			Name:     group.Name,
+		},
+		Users: []string{username},
+	}
+	if workspace := group.Labels[tenantv1beta1.WorkspaceLabel]; workspace != "" {
+		groupBinding.Labels[tenantv1beta1.WorkspaceLabel] = workspace
+	}
+	return groupBinding
+}
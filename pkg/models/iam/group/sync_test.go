@@ -0,0 +1,87 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package group
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	runtimefakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication/identityprovider"
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func boundGroups(t *testing.T, client runtimeclient.Client, username string) []string {
+	groupBindings := &iamv1beta1.GroupBindingList{}
+	if err := client.List(context.Background(), groupBindings, runtimeclient.MatchingLabels{iamv1beta1.UserReferenceLabel: username}); err != nil {
+		t.Fatal(err)
+	}
+	groups := make([]string, 0)
+	for _, groupBinding := range groupBindings.Items {
+		groups = append(groups, groupBinding.GroupRef.Name)
+	}
+	sort.Strings(groups)
+	return groups
+}
+
+func TestSyncIdentityProviderGroups(t *testing.T) {
+	// the group binding created manually is left untouched
+	manual := &iamv1beta1.GroupBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "testers-user1",
+			Labels: map[string]string{iamv1beta1.UserReferenceLabel: "user1", iamv1beta1.GroupReferenceLabel: "testers"},
+		},
+		GroupRef: iamv1beta1.GroupRef{Name: "testers"},
+		Users:    []string{"user1"},
+	}
+	existing := &iamv1beta1.Group{ObjectMeta: metav1.ObjectMeta{Name: "admins", Labels: map[string]string{iamv1beta1.IdentityProviderLabel: "ldap"}}}
+	// the group of the same name created manually isn't taken over
+	handMade := &iamv1beta1.Group{ObjectMeta: metav1.ObjectMeta{Name: "operators"}}
+	client := runtimefakeclient.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithRuntimeObjects(manual, existing, handMade).
+		Build()
+	ctx := context.Background()
+
+	err := SyncIdentityProviderGroups(ctx, client, "ldap", "user1", []identityprovider.MappedGroup{
+		{Name: "developers", Workspace: "system-workspace", ExternalGroup: "cn=developers,dc=kubesphere,dc=io"},
+		{Name: "admins", ExternalGroup: "cn=admins,dc=kubesphere,dc=io"},
+		{Name: "operators", ExternalGroup: "cn=operators,dc=kubesphere,dc=io"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"admins", "developers", "testers"}, boundGroups(t, client, "user1"))
+
+	developers := &iamv1beta1.Group{}
+	assert.NoError(t, client.Get(ctx, runtimeclient.ObjectKey{Name: "developers"}, developers))
+	assert.Equal(t, "system-workspace", developers.Labels[tenantv1beta1.WorkspaceLabel])
+	assert.Equal(t, "ldap", developers.Labels[iamv1beta1.IdentityProviderLabel])
+	assert.Equal(t, "cn=developers,dc=kubesphere,dc=io", developers.Annotations[iamv1beta1.ExternalGroupAnnotation])
+
+	// synchronizing again is a no-op
+	err = SyncIdentityProviderGroups(ctx, client, "ldap", "user1", []identityprovider.MappedGroup{
+		{Name: "developers", Workspace: "system-workspace"},
+		{Name: "admins"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"admins", "developers", "testers"}, boundGroups(t, client, "user1"))
+
+	// the user is unbound from the groups removed at the identity provider
+	err = SyncIdentityProviderGroups(ctx, client, "ldap", "user1", []identityprovider.MappedGroup{{Name: "admins"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"admins", "testers"}, boundGroups(t, client, "user1"))
+
+	err = SyncIdentityProviderGroups(ctx, client, "ldap", "user1", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"testers"}, boundGroups(t, client, "user1"))
+}
@@ -12,6 +12,7 @@ import (
 	"strconv"
 	"time"
 
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -34,6 +35,11 @@ type IdentityManagementInterface interface {
 	UpdateUser(user *iamv1beta1.User) (*iamv1beta1.User, error)
 	DescribeUser(username string) (*iamv1beta1.User, error)
 	ModifyPassword(username string, password string) error
+	// ChangeOwnPassword modifies the password on behalf of the user, which completes the password reset
+	// required by the administrator.
+	ChangeOwnPassword(username string, password string) error
+	// RequirePasswordReset requires the user to change the password at next login, or cancels the requirement.
+	RequirePasswordReset(username string, required bool) error
 	ListLoginRecords(username string, query *query.Query) (*api.ListResult, error)
 	PasswordVerify(username string, password string) error
 }
@@ -62,6 +68,11 @@ func (im *imOperator) UpdateUser(new *iamv1beta1.User) (*iamv1beta1.User, error)
 	}
 	// keep encrypted password and user status
 	new.Spec.EncryptedPassword = old.Spec.EncryptedPassword
+	// the groups are populated by the GroupBindings, which can't be changed by the users themselves
+	new.Spec.Groups = old.Spec.Groups
+	// the password reset can't be cancelled and the TOTP can't be unbound by updating the user
+	keepAnnotation(old, new, iamv1beta1.PasswordResetRequiredAnnotation)
+	keepAnnotation(old, new, iamv1beta1.TOTPAuthKeyRefAnnotation)
 	status := old.Status
 	// only support enable or disable
 	if new.Status.State == iamv1beta1.UserDisabled || new.Status.State == iamv1beta1.UserActive {
@@ -77,6 +88,18 @@ func (im *imOperator) UpdateUser(new *iamv1beta1.User) (*iamv1beta1.User, error)
 	return new, nil
 }
 
+// keepAnnotation copies the annotation from the stored user, or removes it if the stored user doesn't have it.
+func keepAnnotation(old, new *iamv1beta1.User, key string) {
+	if value, ok := old.Annotations[key]; ok {
+		if new.Annotations == nil {
+			new.Annotations = make(map[string]string)
+		}
+		new.Annotations[key] = value
+	} else {
+		delete(new.Annotations, key)
+	}
+}
+
 func (im *imOperator) fetch(username string) (*iamv1beta1.User, error) {
 	user := &iamv1beta1.User{}
 	if err := im.client.Get(context.Background(), types.NamespacedName{Name: username}, user); err != nil {
@@ -86,17 +109,54 @@ func (im *imOperator) fetch(username string) (*iamv1beta1.User, error) {
 }
 
 func (im *imOperator) ModifyPassword(username string, password string) error {
+	return im.modifyPassword(username, password, false)
+}
+
+func (im *imOperator) ChangeOwnPassword(username string, password string) error {
+	return im.modifyPassword(username, password, true)
+}
+
+func (im *imOperator) modifyPassword(username string, password string, byUser bool) error {
+	// the user controller only encrypts the plain text password, a hashed password would be stored as is
+	// without being checked by the password policy
+	if auth.IsEncryptedPassword(password) {
+		return errors.NewBadRequest("the password must not be encrypted")
+	}
 	user, err := im.fetch(username)
 	if err != nil {
 		return err
 	}
 	user.Spec.EncryptedPassword = password
+	// the password reset is only done once the user has chosen a new password, a password
+	// set by the administrator must still be changed at next login
+	if byUser {
+		delete(user.Annotations, iamv1beta1.PasswordResetRequiredAnnotation)
+	}
 	if err := im.client.Update(context.Background(), user); err != nil {
 		return err
 	}
 	return nil
 }
 
+func (im *imOperator) RequirePasswordReset(username string, required bool) error {
+	user, err := im.fetch(username)
+	if err != nil {
+		return err
+	}
+	if required {
+		if user.Spec.EncryptedPassword == "" {
+			return errors.NewBadRequest("the user has no password")
+		}
+		if user.Annotations == nil {
+			user.Annotations = make(map[string]string)
+		}
+		user.Annotations[iamv1beta1.PasswordResetRequiredAnnotation] = "true"
+	} else {
+		delete(user.Annotations, iamv1beta1.PasswordResetRequiredAnnotation)
+	}
+	return im.client.Update(context.Background(), user)
+}
+
 func (im *imOperator) ListUsers(query *query.Query) (*api.ListResult, error) {
 	result, err := im.resourceManager.ListResources(context.Background(), iamv1beta1.SchemeGroupVersion.WithResource(iamv1beta1.ResourcesPluralUser), "", query)
 	if err != nil {
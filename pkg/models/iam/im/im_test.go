@@ -5,3 +5,141 @@
  */
 
 package im
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/crypto/bcrypt"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	runtimefakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func TestUpdateUserKeepsGroups(t *testing.T) {
+	client := runtimefakeclient.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(&iamv1beta1.User{
+			ObjectMeta: metav1.ObjectMeta{Name: "tester"},
+			Spec:       iamv1beta1.UserSpec{Groups: []string{"developers"}, EncryptedPassword: "encrypted"},
+		}).
+		Build()
+	operator := NewOperator(client, nil, nil)
+
+	user := &iamv1beta1.User{}
+	if err := client.Get(context.Background(), types.NamespacedName{Name: "tester"}, user); err != nil {
+		t.Fatal(err)
+	}
+	user.Spec.Groups = []string{"developers", "platform-admins"}
+	user.Spec.Email = "tester@kubesphere.io"
+	if _, err := operator.UpdateUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	updated := &iamv1beta1.User{}
+	if err := client.Get(context.Background(), types.NamespacedName{Name: "tester"}, updated); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"developers"}, updated.Spec.Groups); diff != "" {
+		t.Errorf("the groups of the user are changed: %s", diff)
+	}
+	if updated.Spec.Email != "tester@kubesphere.io" {
+		t.Errorf("expected the email to be updated, got %s", updated.Spec.Email)
+	}
+}
+
+func TestUpdateUserKeepsTOTPAuthKeyRef(t *testing.T) {
+	client := runtimefakeclient.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(&iamv1beta1.User{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "tester",
+				Annotations: map[string]string{iamv1beta1.TOTPAuthKeyRefAnnotation: "mfa-totp-tester"},
+			},
+		}).
+		Build()
+	operator := NewOperator(client, nil, nil)
+
+	user := &iamv1beta1.User{}
+	if err := client.Get(context.Background(), types.NamespacedName{Name: "tester"}, user); err != nil {
+		t.Fatal(err)
+	}
+	delete(user.Annotations, iamv1beta1.TOTPAuthKeyRefAnnotation)
+	if _, err := operator.UpdateUser(user); err != nil {
+		t.Fatal(err)
+	}
+
+	updated := &iamv1beta1.User{}
+	if err := client.Get(context.Background(), types.NamespacedName{Name: "tester"}, updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Annotations[iamv1beta1.TOTPAuthKeyRefAnnotation] != "mfa-totp-tester" {
+		t.Errorf("expected the TOTP auth key ref to be kept, got %v", updated.Annotations)
+	}
+}
+
+func TestModifyPasswordRejectsEncryptedPassword(t *testing.T) {
+	client := runtimefakeclient.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(&iamv1beta1.User{
+			ObjectMeta: metav1.ObjectMeta{Name: "tester"},
+			Spec:       iamv1beta1.UserSpec{EncryptedPassword: "encrypted"},
+		}).
+		Build()
+	operator := NewOperator(client, nil, nil)
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = operator.ModifyPassword("tester", string(hash)); !errors.IsBadRequest(err) {
+		t.Fatalf("expected a bad request error, got %v", err)
+	}
+	if err = operator.ModifyPassword("tester", "P@88w0rd"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChangeOwnPasswordCompletesPasswordReset(t *testing.T) {
+	client := runtimefakeclient.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(&iamv1beta1.User{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "tester",
+				Annotations: map[string]string{iamv1beta1.PasswordResetRequiredAnnotation: "true"},
+			},
+			Spec: iamv1beta1.UserSpec{EncryptedPassword: "encrypted"},
+		}).
+		Build()
+	operator := NewOperator(client, nil, nil)
+
+	// the password set by the administrator must still be changed by the user
+	if err := operator.ModifyPassword("tester", "P@88w0rd"); err != nil {
+		t.Fatal(err)
+	}
+	user := &iamv1beta1.User{}
+	if err := client.Get(context.Background(), types.NamespacedName{Name: "tester"}, user); err != nil {
+		t.Fatal(err)
+	}
+	if user.Annotations[iamv1beta1.PasswordResetRequiredAnnotation] != "true" {
+		t.Errorf("expected the password reset to be kept, got %v", user.Annotations)
+	}
+
+	if err := operator.ChangeOwnPassword("tester", "N3wP@88w0rd"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Get(context.Background(), types.NamespacedName{Name: "tester"}, user); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := user.Annotations[iamv1beta1.PasswordResetRequiredAnnotation]; ok {
+		t.Errorf("expected the password reset to be done, got %v", user.Annotations)
+	}
+	if user.Spec.EncryptedPassword != "N3wP@88w0rd" {
+		t.Errorf("expected the password to be changed, got %s", user.Spec.EncryptedPassword)
+	}
+}
@@ -0,0 +1,389 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package scim
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Filter matches the resources in the form of the JSON objects, https://datatracker.ietf.org/doc/html/rfc7644#section-3.4.2.2
+type Filter interface {
+	Matches(resource map[string]interface{}) bool
+}
+
+type logicalExpression struct {
+	and         bool
+	left, right Filter
+}
+
+func (e *logicalExpression) Matches(resource map[string]interface{}) bool {
+	if e.and {
+		return e.left.Matches(resource) && e.right.Matches(resource)
+	}
+	return e.left.Matches(resource) || e.right.Matches(resource)
+}
+
+type notExpression struct {
+	filter Filter
+}
+
+func (e *notExpression) Matches(resource map[string]interface{}) bool {
+	return !e.filter.Matches(resource)
+}
+
+// valuePathExpression matches the resources with an item of the multi-valued attribute matching the filter,
+// e.g. emails[type eq "work" and value co "@kubesphere.io"].
+type valuePathExpression struct {
+	attribute string
+	filter    Filter
+}
+
+func (e *valuePathExpression) Matches(resource map[string]interface{}) bool {
+	for _, item := range asSlice(lookup(resource, e.attribute)) {
+		if value, ok := item.(map[string]interface{}); ok && e.filter.Matches(value) {
+			return true
+		}
+	}
+	return false
+}
+
+type attributeExpression struct {
+	path     string
+	operator string
+	value    interface{}
+}
+
+func (e *attributeExpression) Matches(resource map[string]interface{}) bool {
+	for _, value := range attributeValues(resource, e.path) {
+		if e.operator == "pr" {
+			if value != nil && value != "" {
+				return true
+			}
+			continue
+		}
+		if compare(value, e.operator, e.value) {
+			return true
+		}
+	}
+	return false
+}
+
+// attributeValues returns the values of the attribute path, the values of the sub-attribute
+// "value" are returned for the multi-valued complex attributes, such as emails.
+func attributeValues(resource map[string]interface{}, path string) []interface{} {
+	attribute, subAttribute, _ := strings.Cut(path, ".")
+	values := make([]interface{}, 0)
+	for _, item := range asSlice(lookup(resource, attribute)) {
+		complexValue, ok := item.(map[string]interface{})
+		switch {
+		case ok && subAttribute != "":
+			values = append(values, lookup(complexValue, subAttribute))
+		case ok:
+			if value := lookup(complexValue, "value"); value != nil {
+				values = append(values, value)
+			} else if len(complexValue) > 0 {
+				// the single-valued complex attribute, e.g. name, is present if any sub-attribute is present
+				values = append(values, complexValue)
+			}
+		case subAttribute == "":
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+func compare(actual interface{}, operator string, expected interface{}) bool {
+	switch actualValue := actual.(type) {
+	case string:
+		expectedValue, ok := expected.(string)
+		if !ok {
+			return false
+		}
+		actualValue, expectedValue = strings.ToLower(actualValue), strings.ToLower(expectedValue)
+		switch operator {
+		case "eq":
+			return actualValue == expectedValue
+		case "ne":
+			return actualValue != expectedValue
+		case "co":
+			return strings.Contains(actualValue, expectedValue)
+		case "sw":
+			return strings.HasPrefix(actualValue, expectedValue)
+		case "ew":
+			return strings.HasSuffix(actualValue, expectedValue)
+		case "gt":
+			return actualValue > expectedValue
+		case "ge":
+			return actualValue >= expectedValue
+		case "lt":
+			return actualValue < expectedValue
+		case "le":
+			return actualValue <= expectedValue
+		}
+	case bool:
+		switch operator {
+		case "eq":
+			return actualValue == expected
+		case "ne":
+			return actualValue != expected
+		}
+	case float64:
+		expectedValue, ok := expected.(float64)
+		if !ok {
+			return false
+		}
+		switch operator {
+		case "eq":
+			return actualValue == expectedValue
+		case "ne":
+			return actualValue != expectedValue
+		case "gt":
+			return actualValue > expectedValue
+		case "ge":
+			return actualValue >= expectedValue
+		case "lt":
+			return actualValue < expectedValue
+		case "le":
+			return actualValue <= expectedValue
+		}
+	case nil:
+		switch operator {
+		case "eq":
+			return expected == nil
+		case "ne":
+			return expected != nil
+		}
+	}
+	return false
+}
+
+// lookup returns the attribute of the object, the attribute names are case-insensitive.
+func lookup(object map[string]interface{}, attribute string) interface{} {
+	if value, ok := object[attribute]; ok {
+		return value
+	}
+	for key, value := range object {
+		if strings.EqualFold(key, attribute) {
+			return value
+		}
+	}
+	return nil
+}
+
+func asSlice(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	default:
+		return []interface{}{v}
+	}
+}
+
+// trimSchema removes the schema URN prefix of the attribute path,
+// e.g. urn:ietf:params:scim:schemas:core:2.0:User:name.givenName.
+func trimSchema(path string) string {
+	if !strings.HasPrefix(strings.ToLower(path), "urn:") {
+		return path
+	}
+	if i := strings.LastIndex(path, ":"); i >= 0 {
+		return path[i+1:]
+	}
+	return path
+}
+
+var comparisonOperators = map[string]bool{
+	"eq": true, "ne": true, "co": true, "sw": true, "ew": true, "gt": true, "ge": true, "lt": true, "le": true,
+}
+
+// ParseFilter parses the filter expression, e.g. userName eq "admin" and (emails co "@kubesphere.io" or active pr).
+func ParseFilter(expression string) (Filter, error) {
+	tokens, err := tokenize(expression)
+	if err != nil {
+		return nil, NewBadRequest(ErrorInvalidFilter, "invalid filter %q: %s", expression, err)
+	}
+	p := &filterParser{tokens: tokens}
+	filter, err := p.parseOr()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	if err != nil {
+		return nil, NewBadRequest(ErrorInvalidFilter, "invalid filter %q: %s", expression, err)
+	}
+	return filter, nil
+}
+
+type filterParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *filterParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *filterParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *filterParser) expect(token string) error {
+	if next := p.next(); next != token {
+		return fmt.Errorf("expected %q, got %q", token, next)
+	}
+	return nil
+}
+
+func (p *filterParser) parseOr() (Filter, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpression{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (Filter, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for strings.EqualFold(p.peek(), "and") {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalExpression{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseUnary() (Filter, error) {
+	token := p.next()
+	switch {
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of the filter")
+	case strings.EqualFold(token, "not"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return &notExpression{filter: filter}, p.expect(")")
+	case token == "(":
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return filter, p.expect(")")
+	case isDelimiter(token) || token[0] == '"':
+		return nil, fmt.Errorf("unexpected %q", token)
+	}
+
+	path := trimSchema(token)
+	if p.peek() == "[" {
+		p.next()
+		filter, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		return &valuePathExpression{attribute: path, filter: filter}, p.expect("]")
+	}
+
+	operator := strings.ToLower(p.next())
+	if operator == "pr" {
+		return &attributeExpression{path: path, operator: operator}, nil
+	}
+	if !comparisonOperators[operator] {
+		return nil, fmt.Errorf("unknown operator %q", operator)
+	}
+	value, err := parseValue(p.next())
+	if err != nil {
+		return nil, err
+	}
+	return &attributeExpression{path: path, operator: operator, value: value}, nil
+}
+
+func parseValue(token string) (interface{}, error) {
+	switch strings.ToLower(token) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	case "":
+		return nil, fmt.Errorf("missing comparison value")
+	}
+	if token[0] == '"' {
+		var value string
+		if err := json.Unmarshal([]byte(token), &value); err != nil {
+			return nil, fmt.Errorf("invalid string %s", token)
+		}
+		return value, nil
+	}
+	value, err := strconv.ParseFloat(token, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q", token)
+	}
+	return value, nil
+}
+
+func isDelimiter(token string) bool {
+	return token == "(" || token == ")" || token == "[" || token == "]"
+}
+
+func tokenize(expression string) ([]string, error) {
+	tokens := make([]string, 0)
+	for i := 0; i < len(expression); {
+		c := expression[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case isDelimiter(string(c)):
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			j := i + 1
+			for ; j < len(expression) && expression[j] != '"'; j++ {
+				if expression[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(expression) {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, expression[i:j+1])
+			i = j + 1
+		default:
+			j := i
+			for ; j < len(expression) && !strings.ContainsRune(" \t\n()[]\"", rune(expression[j])); j++ {
+			}
+			tokens = append(tokens, expression[i:j])
+			i = j
+		}
+	}
+	return tokens, nil
+}
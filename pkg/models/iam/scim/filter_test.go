@@ -0,0 +1,79 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package scim
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseFilter(t *testing.T) {
+	resource := make(map[string]interface{})
+	if err := json.Unmarshal([]byte(`{
+		"userName": "Admin",
+		"active": true,
+		"name": {"givenName": "Jane", "familyName": "Doe"},
+		"emails": [
+			{"value": "jane@kubesphere.io", "type": "work", "primary": true},
+			{"value": "jane@example.com", "type": "home"}
+		],
+		"meta": {"resourceType": "User"}
+	}`), &resource); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		filter  string
+		matches bool
+		wantErr bool
+	}{
+		{filter: `userName eq "admin"`, matches: true},
+		{filter: `USERNAME Eq "ADMIN"`, matches: true},
+		{filter: `userName ne "admin"`, matches: false},
+		{filter: `userName sw "ad"`, matches: true},
+		{filter: `userName ew "min"`, matches: true},
+		{filter: `userName co "dmi"`, matches: true},
+		{filter: `userName gt "a" and userName lt "b"`, matches: true},
+		{filter: `active eq true`, matches: true},
+		{filter: `active eq false`, matches: false},
+		{filter: `name.givenName eq "Jane"`, matches: true},
+		{filter: `urn:ietf:params:scim:schemas:core:2.0:User:userName eq "admin"`, matches: true},
+		{filter: `emails co "example.com"`, matches: true},
+		{filter: `emails[type eq "work" and value co "@kubesphere.io"]`, matches: true},
+		{filter: `emails[type eq "home" and value co "@kubesphere.io"]`, matches: false},
+		{filter: `emails.type eq "home"`, matches: true},
+		{filter: `displayName pr`, matches: false},
+		{filter: `name pr or displayName pr`, matches: true},
+		{filter: `not (userName eq "admin")`, matches: false},
+		{filter: `userName eq "guest" or (active eq true and meta.resourceType eq "User")`, matches: true},
+		{filter: `userName eq "a\"b"`, matches: false},
+		{filter: ``, wantErr: true},
+		{filter: `userName`, wantErr: true},
+		{filter: `userName xx "admin"`, wantErr: true},
+		{filter: `userName eq "admin`, wantErr: true},
+		{filter: `(userName eq "admin"`, wantErr: true},
+		{filter: `userName eq "admin" active pr`, wantErr: true},
+		{filter: `emails[type eq "work"`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			filter, err := ParseFilter(tt.filter)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				if scimErr, ok := err.(*Error); !ok || scimErr.ScimType != ErrorInvalidFilter {
+					t.Errorf("ParseFilter() error = %v, want scimType %s", err, ErrorInvalidFilter)
+				}
+				return
+			}
+			if got := filter.Matches(resource); got != tt.matches {
+				t.Errorf("Matches() = %v, want %v", got, tt.matches)
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package scim
+
+import (
+	"strings"
+)
+
+// patchPath is the path of a PATCH operation, attribute[filter].subAttribute,
+// https://datatracker.ietf.org/doc/html/rfc7644#section-3.5.2
+type patchPath struct {
+	attribute    string
+	filter       Filter
+	subAttribute string
+}
+
+func parsePatchPath(path string) (*patchPath, error) {
+	p := &patchPath{}
+	if i := strings.Index(path, "["); i >= 0 {
+		j := strings.LastIndex(path, "]")
+		if j < i {
+			return nil, NewBadRequest(ErrorInvalidPath, "invalid path %q", path)
+		}
+		filter, err := ParseFilter(path[i+1 : j])
+		if err != nil {
+			return nil, NewBadRequest(ErrorInvalidPath, "invalid path %q: %s", path, err)
+		}
+		p.attribute = trimSchema(path[:i])
+		p.filter = filter
+		rest := path[j+1:]
+		if rest != "" {
+			if !strings.HasPrefix(rest, ".") || len(rest) == 1 {
+				return nil, NewBadRequest(ErrorInvalidPath, "invalid path %q", path)
+			}
+			p.subAttribute = rest[1:]
+		}
+	} else {
+		p.attribute, p.subAttribute, _ = strings.Cut(trimSchema(path), ".")
+	}
+	if p.attribute == "" {
+		return nil, NewBadRequest(ErrorInvalidPath, "invalid path %q", path)
+	}
+	return p, nil
+}
+
+// ApplyPatch applies the PATCH operations to the resource in the form of the JSON object.
+func ApplyPatch(resource map[string]interface{}, patch *PatchOp) error {
+	for _, operation := range patch.Operations {
+		if err := applyOperation(resource, operation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyOperation(resource map[string]interface{}, operation PatchOperation) error {
+	op := strings.ToLower(operation.Op)
+	if op != "add" && op != "replace" && op != "remove" {
+		return NewBadRequest(ErrorInvalidSyntax, "invalid operation %q", operation.Op)
+	}
+
+	if operation.Path == "" {
+		if op == "remove" {
+			return NewBadRequest(ErrorNoTarget, "the path is required by the remove operation")
+		}
+		values, ok := operation.Value.(map[string]interface{})
+		if !ok {
+			return NewBadRequest(ErrorInvalidValue, "the value of the %s operation without path must be an object", op)
+		}
+		// the attribute names may be paths, e.g. {"name.givenName": "Jane"}
+		for attribute, value := range values {
+			if err := applyOperation(resource, PatchOperation{Op: op, Path: attribute, Value: value}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	path, err := parsePatchPath(operation.Path)
+	if err != nil {
+		return err
+	}
+	key := canonicalKey(resource, path.attribute)
+
+	if path.filter != nil {
+		return applyFiltered(resource, key, path, op, operation.Value)
+	}
+
+	if path.subAttribute != "" {
+		switch current := resource[key].(type) {
+		case []interface{}:
+			// the sub-attribute of all the items of the multi-valued attribute
+			for _, item := range current {
+				if object, ok := item.(map[string]interface{}); ok {
+					setOrRemove(object, path.subAttribute, op, operation.Value)
+				}
+			}
+			if len(current) == 0 && op != "remove" {
+				resource[key] = []interface{}{map[string]interface{}{path.subAttribute: operation.Value}}
+			}
+		case map[string]interface{}:
+			setOrRemove(current, path.subAttribute, op, operation.Value)
+		default:
+			if op != "remove" {
+				resource[key] = map[string]interface{}{path.subAttribute: operation.Value}
+			}
+		}
+		return nil
+	}
+
+	current, isMultiValued := resource[key].([]interface{})
+	switch {
+	case op == "remove" && isMultiValued && operation.Value != nil:
+		// remove the items with the same value, e.g. {"op": "remove", "path": "members", "value": [{"value": "admin"}]}
+		removed := make(map[string]bool)
+		for _, item := range asSlice(operation.Value) {
+			removed[itemValue(item)] = true
+		}
+		remaining := make([]interface{}, 0, len(current))
+		for _, item := range current {
+			if !removed[itemValue(item)] {
+				remaining = append(remaining, item)
+			}
+		}
+		resource[key] = remaining
+	case op == "add" && (isMultiValued || multiValuedAttributes[strings.ToLower(key)]):
+		for _, item := range asSlice(operation.Value) {
+			if !containsValue(current, itemValue(item)) {
+				current = append(current, item)
+			}
+		}
+		resource[key] = current
+	default:
+		setOrRemove(resource, key, op, operation.Value)
+	}
+	return nil
+}
+
+// applyFiltered applies the operation to the items of the multi-valued attribute matching the filter.
+func applyFiltered(resource map[string]interface{}, key string, path *patchPath, op string, value interface{}) error {
+	items := asSlice(resource[key])
+	result := make([]interface{}, 0, len(items))
+	matched := false
+	for _, item := range items {
+		object, ok := item.(map[string]interface{})
+		if !ok || !path.filter.Matches(object) {
+			result = append(result, item)
+			continue
+		}
+		matched = true
+		switch {
+		case op == "remove" && path.subAttribute == "":
+			continue
+		case path.subAttribute != "":
+			setOrRemove(object, path.subAttribute, op, value)
+		default:
+			if replacement, ok := value.(map[string]interface{}); ok {
+				for k, v := range replacement {
+					object[canonicalKey(object, k)] = v
+				}
+			}
+		}
+		result = append(result, object)
+	}
+
+	if !matched {
+		if op == "remove" {
+			return nil
+		}
+		// add the item identified by the filter, e.g. emails[type eq "work"].value
+		item, ok := itemFromFilter(path.filter)
+		if !ok {
+			return NewBadRequest(ErrorNoTarget, "no item of %s matches the filter", path.attribute)
+		}
+		if path.subAttribute != "" {
+			item[path.subAttribute] = value
+		} else if replacement, ok := value.(map[string]interface{}); ok {
+			for k, v := range replacement {
+				item[k] = v
+			}
+		}
+		result = append(result, item)
+	}
+	resource[key] = result
+	return nil
+}
+
+// itemFromFilter returns the item satisfying the filter, if the filter is composed of the "eq" expressions
+// joined by "and".
+func itemFromFilter(filter Filter) (map[string]interface{}, bool) {
+	switch f := filter.(type) {
+	case *attributeExpression:
+		if f.operator != "eq" || strings.Contains(f.path, ".") {
+			return nil, false
+		}
+		return map[string]interface{}{f.path: f.value}, true
+	case *logicalExpression:
+		if !f.and {
+			return nil, false
+		}
+		left, ok := itemFromFilter(f.left)
+		if !ok {
+			return nil, false
+		}
+		right, ok := itemFromFilter(f.right)
+		if !ok {
+			return nil, false
+		}
+		for k, v := range right {
+			left[k] = v
+		}
+		return left, true
+	}
+	return nil, false
+}
+
+func setOrRemove(object map[string]interface{}, attribute, op string, value interface{}) {
+	key := canonicalKey(object, attribute)
+	if op == "remove" {
+		delete(object, key)
+		return
+	}
+	object[key] = value
+}
+
+// canonicalKey returns the existing key of the attribute, since the attribute names are case-insensitive.
+func canonicalKey(object map[string]interface{}, attribute string) string {
+	if _, ok := object[attribute]; ok {
+		return attribute
+	}
+	for key := range object {
+		if strings.EqualFold(key, attribute) {
+			return key
+		}
+	}
+	return attribute
+}
+
+func itemValue(item interface{}) string {
+	if object, ok := item.(map[string]interface{}); ok {
+		value, _ := lookup(object, "value").(string)
+		return value
+	}
+	value, _ := item.(string)
+	return value
+}
+
+func containsValue(items []interface{}, value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, item := range items {
+		if itemValue(item) == value {
+			return true
+		}
+	}
+	return false
+}
+
+var multiValuedAttributes = map[string]bool{"emails": true, "groups": true, "members": true}
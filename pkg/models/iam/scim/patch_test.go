@@ -0,0 +1,141 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package scim
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestApplyPatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		resource   string
+		operations string
+		want       string
+		wantErr    bool
+	}{
+		{
+			name:       "replace without path",
+			resource:   `{"userName": "jane", "active": true}`,
+			operations: `[{"op": "Replace", "value": {"active": false, "name.givenName": "Jane"}}]`,
+			want:       `{"userName": "jane", "active": false, "name": {"givenName": "Jane"}}`,
+		},
+		{
+			name:       "replace attribute case-insensitively",
+			resource:   `{"displayName": "Jane"}`,
+			operations: `[{"op": "replace", "path": "DISPLAYNAME", "value": "Jane Doe"}]`,
+			want:       `{"displayName": "Jane Doe"}`,
+		},
+		{
+			name:       "add members",
+			resource:   `{"displayName": "developers", "members": [{"value": "jane"}]}`,
+			operations: `[{"op": "add", "path": "members", "value": [{"value": "jane"}, {"value": "john"}]}]`,
+			want:       `{"displayName": "developers", "members": [{"value": "jane"}, {"value": "john"}]}`,
+		},
+		{
+			name:       "add members to group without members",
+			resource:   `{"displayName": "developers"}`,
+			operations: `[{"op": "add", "path": "members", "value": [{"value": "jane"}]}]`,
+			want:       `{"displayName": "developers", "members": [{"value": "jane"}]}`,
+		},
+		{
+			name:       "remove member by filter",
+			resource:   `{"members": [{"value": "jane"}, {"value": "john"}]}`,
+			operations: `[{"op": "remove", "path": "members[value eq \"jane\"]"}]`,
+			want:       `{"members": [{"value": "john"}]}`,
+		},
+		{
+			name:       "remove member by value",
+			resource:   `{"members": [{"value": "jane"}, {"value": "john"}]}`,
+			operations: `[{"op": "remove", "path": "members", "value": [{"value": "john"}]}]`,
+			want:       `{"members": [{"value": "jane"}]}`,
+		},
+		{
+			name:       "remove all members",
+			resource:   `{"displayName": "developers", "members": [{"value": "jane"}]}`,
+			operations: `[{"op": "remove", "path": "members"}]`,
+			want:       `{"displayName": "developers"}`,
+		},
+		{
+			name:       "replace sub-attribute of the filtered item",
+			resource:   `{"emails": [{"value": "jane@example.com", "type": "work"}, {"value": "jane@home.com", "type": "home"}]}`,
+			operations: `[{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "jane@kubesphere.io"}]`,
+			want:       `{"emails": [{"value": "jane@kubesphere.io", "type": "work"}, {"value": "jane@home.com", "type": "home"}]}`,
+		},
+		{
+			name:       "add item identified by filter",
+			resource:   `{"userName": "jane"}`,
+			operations: `[{"op": "add", "path": "emails[type eq \"work\"].value", "value": "jane@kubesphere.io"}]`,
+			want:       `{"userName": "jane", "emails": [{"value": "jane@kubesphere.io", "type": "work"}]}`,
+		},
+		{
+			name:       "remove sub-attribute",
+			resource:   `{"name": {"givenName": "Jane", "familyName": "Doe"}}`,
+			operations: `[{"op": "remove", "path": "name.familyName"}]`,
+			want:       `{"name": {"givenName": "Jane"}}`,
+		},
+		{
+			name:       "path with schema",
+			resource:   `{"active": true}`,
+			operations: `[{"op": "replace", "path": "urn:ietf:params:scim:schemas:core:2.0:User:active", "value": false}]`,
+			want:       `{"active": false}`,
+		},
+		{
+			name:       "invalid operation",
+			resource:   `{}`,
+			operations: `[{"op": "move", "path": "active"}]`,
+			wantErr:    true,
+		},
+		{
+			name:       "remove without path",
+			resource:   `{}`,
+			operations: `[{"op": "remove"}]`,
+			wantErr:    true,
+		},
+		{
+			name:       "invalid path",
+			resource:   `{}`,
+			operations: `[{"op": "add", "path": "emails[type eq ]", "value": "x"}]`,
+			wantErr:    true,
+		},
+		{
+			name:       "no target",
+			resource:   `{}`,
+			operations: `[{"op": "replace", "path": "emails[type eq \"work\" or primary eq true].value", "value": "x"}]`,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resource := make(map[string]interface{})
+			if err := json.Unmarshal([]byte(tt.resource), &resource); err != nil {
+				t.Fatal(err)
+			}
+			patch := &PatchOp{Schemas: []string{SchemaPatchOp}}
+			if err := json.Unmarshal([]byte(tt.operations), &patch.Operations); err != nil {
+				t.Fatal(err)
+			}
+			err := ApplyPatch(resource, patch)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyPatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			want := make(map[string]interface{})
+			if err = json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(want, resource); diff != "" {
+				t.Errorf("ApplyPatch() mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,564 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package scim
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/validation"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication"
+	"kubesphere.io/kubesphere/pkg/apiserver/query"
+	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/models/auth"
+	"kubesphere.io/kubesphere/pkg/models/iam/group"
+	"kubesphere.io/kubesphere/pkg/models/iam/im"
+)
+
+// BasePath is the root path of the SCIM API.
+const BasePath = "/scim/v2"
+
+var invalidGroupNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// Interface provisions the users and groups pushed by the identity providers through SCIM 2.0.
+// The users are backed by the KubeSphere users, and the groups are backed by the groups
+// and group bindings labeled as managed by SCIM.
+type Interface interface {
+	ListUsers(ctx context.Context, filter string, startIndex, count int) (*ListResponse, error)
+	GetUser(ctx context.Context, id string) (*User, error)
+	CreateUser(ctx context.Context, user *User) (*User, error)
+	ReplaceUser(ctx context.Context, id string, user *User) (*User, error)
+	PatchUser(ctx context.Context, id string, patch *PatchOp) (*User, error)
+	DeleteUser(ctx context.Context, id string) error
+	ListGroups(ctx context.Context, filter string, startIndex, count int) (*ListResponse, error)
+	GetGroup(ctx context.Context, id string) (*Group, error)
+	CreateGroup(ctx context.Context, group *Group) (*Group, error)
+	ReplaceGroup(ctx context.Context, id string, group *Group) (*Group, error)
+	PatchGroup(ctx context.Context, id string, patch *PatchOp) (*Group, error)
+	DeleteGroup(ctx context.Context, id string) error
+}
+
+func NewOperator(im im.IdentityManagementInterface, client runtimeclient.Client, options authentication.SCIMOptions) Interface {
+	return &operator{im: im, client: client, options: options}
+}
+
+type operator struct {
+	im      im.IdentityManagementInterface
+	client  runtimeclient.Client
+	options authentication.SCIMOptions
+}
+
+func (o *operator) ListUsers(_ context.Context, filter string, startIndex, count int) (*ListResponse, error) {
+	q := query.New()
+	q.LabelSelector = labels.SelectorFromSet(labels.Set{iamv1beta1.IdentityProviderLabel: Source}).String()
+	result, err := o.im.ListUsers(q)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]interface{}, 0, len(result.Items))
+	for _, item := range result.Items {
+		users = append(users, toUser(item.(*iamv1beta1.User)))
+	}
+	return listResponse(users, filter, startIndex, count)
+}
+
+func (o *operator) GetUser(_ context.Context, id string) (*User, error) {
+	user, err := o.getUser(id)
+	if err != nil {
+		return nil, err
+	}
+	return toUser(user), nil
+}
+
+func (o *operator) CreateUser(_ context.Context, scimUser *User) (*User, error) {
+	name := strings.ToLower(scimUser.UserName)
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return nil, NewBadRequest(ErrorInvalidValue, "invalid userName %q: %s", scimUser.UserName, strings.Join(errs, ", "))
+	}
+	if auth.IsEncryptedPassword(scimUser.Password) {
+		return nil, NewBadRequest(ErrorInvalidValue, "the password must not be encrypted")
+	}
+	user := &iamv1beta1.User{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{iamv1beta1.IdentityProviderLabel: Source},
+		},
+	}
+	o.applyUser(user, scimUser)
+	// password will be encrypted by mutating admission webhook
+	user.Spec.EncryptedPassword = scimUser.Password
+	if scimUser.Active != nil && !*scimUser.Active {
+		user.Status.State = iamv1beta1.UserDisabled
+	}
+	created, err := o.im.CreateUser(user)
+	if err != nil {
+		return nil, err
+	}
+	return toUser(created), nil
+}
+
+func (o *operator) ReplaceUser(_ context.Context, id string, scimUser *User) (*User, error) {
+	user, err := o.getUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if scimUser.UserName != "" && !strings.EqualFold(scimUser.UserName, user.Name) {
+		return nil, NewBadRequest(ErrorMutability, "userName %q can't be changed", user.Name)
+	}
+	if auth.IsEncryptedPassword(scimUser.Password) {
+		return nil, NewBadRequest(ErrorInvalidValue, "the password must not be encrypted")
+	}
+	o.applyUser(user, scimUser)
+	// only the transitions between disabled and active are provisioned, the identity providers resend
+	// active on every sync, which must not clear the lockouts and the password resets
+	if scimUser.Active != nil {
+		if !*scimUser.Active {
+			user.Status.State = iamv1beta1.UserDisabled
+		} else if user.Status.State == iamv1beta1.UserDisabled {
+			user.Status.State = iamv1beta1.UserActive
+		}
+	}
+	updated, err := o.im.UpdateUser(user)
+	if err != nil {
+		return nil, err
+	}
+	if scimUser.Password != "" {
+		if err = o.im.ModifyPassword(id, scimUser.Password); err != nil {
+			return nil, err
+		}
+	}
+	return toUser(updated), nil
+}
+
+func (o *operator) PatchUser(ctx context.Context, id string, patch *PatchOp) (*User, error) {
+	current, err := o.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	patched := &User{}
+	if err = applyPatch(current, patch, patched); err != nil {
+		return nil, err
+	}
+	return o.ReplaceUser(ctx, id, patched)
+}
+
+func (o *operator) DeleteUser(_ context.Context, id string) error {
+	if _, err := o.getUser(id); err != nil {
+		return err
+	}
+	return o.im.DeleteUser(id)
+}
+
+// getUser returns the user provisioned by SCIM, the other users are not found, so that
+// the local users such as admin can't be managed with the SCIM token.
+func (o *operator) getUser(id string) (*iamv1beta1.User, error) {
+	user, err := o.im.DescribeUser(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Labels[iamv1beta1.IdentityProviderLabel] != Source {
+		return nil, errors.NewNotFound(iamv1beta1.Resource(iamv1beta1.ResourcesPluralUser), id)
+	}
+	return user, nil
+}
+
+// applyUser sets the attributes of the SCIM user to the user, the attributes absent are cleared.
+func (o *operator) applyUser(user *iamv1beta1.User, scimUser *User) {
+	user.Spec.Email = primaryValue(scimUser.Emails)
+	user.Spec.Lang = scimUser.PreferredLanguage
+	user.Spec.DisplayName = scimUser.DisplayName
+	if user.Spec.DisplayName == "" && scimUser.Name != nil {
+		user.Spec.DisplayName = scimUser.Name.Formatted
+		if user.Spec.DisplayName == "" {
+			user.Spec.DisplayName = strings.TrimSpace(scimUser.Name.GivenName + " " + scimUser.Name.FamilyName)
+		}
+	}
+	if user.Annotations == nil {
+		user.Annotations = make(map[string]string)
+	}
+	if scimUser.ExternalID == "" {
+		delete(user.Annotations, iamv1beta1.SCIMExternalIDAnnotation)
+		return
+	}
+	user.Annotations[iamv1beta1.SCIMExternalIDAnnotation] = scimUser.ExternalID
+	// the user is found by the identity on login
+	if o.options.IdentityProvider != "" {
+		user.Annotations[fmt.Sprintf("%s.%s", iamv1beta1.IdentityProviderAnnotation, o.options.IdentityProvider)] = scimUser.ExternalID
+	}
+}
+
+func (o *operator) ListGroups(ctx context.Context, filter string, startIndex, count int) (*ListResponse, error) {
+	groups := &iamv1beta1.GroupList{}
+	if err := o.client.List(ctx, groups, runtimeclient.MatchingLabels{iamv1beta1.IdentityProviderLabel: Source}); err != nil {
+		return nil, err
+	}
+	groupBindings := &iamv1beta1.GroupBindingList{}
+	if err := o.client.List(ctx, groupBindings, runtimeclient.MatchingLabels{iamv1beta1.IdentityProviderLabel: Source}); err != nil {
+		return nil, err
+	}
+	members := make(map[string][]string)
+	for _, groupBinding := range groupBindings.Items {
+		members[groupBinding.GroupRef.Name] = append(members[groupBinding.GroupRef.Name], groupBinding.Users...)
+	}
+	resources := make([]interface{}, 0, len(groups.Items))
+	for i := range groups.Items {
+		resources = append(resources, toGroup(&groups.Items[i], members[groups.Items[i].Name]))
+	}
+	return listResponse(resources, filter, startIndex, count)
+}
+
+func (o *operator) GetGroup(ctx context.Context, id string) (*Group, error) {
+	g, err := o.getGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	groupBindings, err := o.listGroupBindings(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]string, 0, len(groupBindings))
+	for _, groupBinding := range groupBindings {
+		members = append(members, groupBinding.Users...)
+	}
+	return toGroup(g, members), nil
+}
+
+func (o *operator) CreateGroup(ctx context.Context, scimGroup *Group) (*Group, error) {
+	name := groupName(scimGroup.DisplayName)
+	if name == "" {
+		return nil, NewBadRequest(ErrorInvalidValue, "invalid displayName %q", scimGroup.DisplayName)
+	}
+	members, err := o.memberNames(ctx, scimGroup.Members)
+	if err != nil {
+		return nil, err
+	}
+	g := &iamv1beta1.Group{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Labels:      map[string]string{iamv1beta1.IdentityProviderLabel: Source},
+			Annotations: map[string]string{},
+		},
+	}
+	applyGroup(g, scimGroup)
+	if err = o.client.Create(ctx, g); err != nil {
+		return nil, err
+	}
+	if err = o.setMembers(ctx, g, members); err != nil {
+		return nil, err
+	}
+	return toGroup(g, members), nil
+}
+
+func (o *operator) ReplaceGroup(ctx context.Context, id string, scimGroup *Group) (*Group, error) {
+	g, err := o.getGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	members, err := o.memberNames(ctx, scimGroup.Members)
+	if err != nil {
+		return nil, err
+	}
+	applyGroup(g, scimGroup)
+	if err = o.client.Update(ctx, g); err != nil {
+		return nil, err
+	}
+	if err = o.setMembers(ctx, g, members); err != nil {
+		return nil, err
+	}
+	return toGroup(g, members), nil
+}
+
+func (o *operator) PatchGroup(ctx context.Context, id string, patch *PatchOp) (*Group, error) {
+	current, err := o.GetGroup(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	patched := &Group{}
+	if err = applyPatch(current, patch, patched); err != nil {
+		return nil, err
+	}
+	return o.ReplaceGroup(ctx, id, patched)
+}
+
+func (o *operator) DeleteGroup(ctx context.Context, id string) error {
+	g, err := o.getGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	// the group bindings and role bindings of the group are deleted by the group controller
+	return o.client.Delete(ctx, g)
+}
+
+// getGroup returns the group managed by SCIM.
+func (o *operator) getGroup(ctx context.Context, id string) (*iamv1beta1.Group, error) {
+	g := &iamv1beta1.Group{}
+	if err := o.client.Get(ctx, runtimeclient.ObjectKey{Name: id}, g); err != nil {
+		return nil, err
+	}
+	if g.Labels[iamv1beta1.IdentityProviderLabel] != Source {
+		return nil, errors.NewNotFound(iamv1beta1.Resource(iamv1beta1.ResourcePluralGroup), id)
+	}
+	return g, nil
+}
+
+func (o *operator) listGroupBindings(ctx context.Context, groupName string) ([]iamv1beta1.GroupBinding, error) {
+	groupBindings := &iamv1beta1.GroupBindingList{}
+	if err := o.client.List(ctx, groupBindings, runtimeclient.MatchingLabels{
+		iamv1beta1.GroupReferenceLabel:   groupName,
+		iamv1beta1.IdentityProviderLabel: Source,
+	}); err != nil {
+		return nil, err
+	}
+	return groupBindings.Items, nil
+}
+
+// memberNames returns the names of the members, the members must be the existing users.
+func (o *operator) memberNames(ctx context.Context, members []MultiValuedAttribute) ([]string, error) {
+	names := make([]string, 0, len(members))
+	for _, member := range members {
+		user := &iamv1beta1.User{}
+		if err := o.client.Get(ctx, runtimeclient.ObjectKey{Name: member.Value}, user); err != nil {
+			if errors.IsNotFound(err) {
+				return nil, NewBadRequest(ErrorInvalidValue, "member %q is not found", member.Value)
+			}
+			return nil, err
+		}
+		names = append(names, user.Name)
+	}
+	return names, nil
+}
+
+// setMembers creates or deletes the group bindings managed by SCIM, so that the members of the group are the users.
+func (o *operator) setMembers(ctx context.Context, g *iamv1beta1.Group, users []string) error {
+	groupBindings, err := o.listGroupBindings(ctx, g.Name)
+	if err != nil {
+		return err
+	}
+	desired := make(map[string]bool, len(users))
+	for _, user := range users {
+		desired[user] = true
+	}
+	bound := make(map[string]bool)
+	for i := range groupBindings {
+		groupBinding := &groupBindings[i]
+		if len(groupBinding.Users) == 1 && desired[groupBinding.Users[0]] && !bound[groupBinding.Users[0]] {
+			bound[groupBinding.Users[0]] = true
+			continue
+		}
+		if err = o.client.Delete(ctx, groupBinding); runtimeclient.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	for _, user := range users {
+		if bound[user] {
+			continue
+		}
+		if err = o.client.Create(ctx, group.NewGroupBinding(g, user, Source)); err != nil {
+			return err
+		}
+		bound[user] = true
+	}
+	return nil
+}
+
+func applyGroup(g *iamv1beta1.Group, scimGroup *Group) {
+	if g.Annotations == nil {
+		g.Annotations = make(map[string]string)
+	}
+	if scimGroup.DisplayName != "" {
+		g.Annotations[constants.DisplayNameAnnotationKey] = scimGroup.DisplayName
+	}
+	if scimGroup.ExternalID == "" {
+		delete(g.Annotations, iamv1beta1.SCIMExternalIDAnnotation)
+	} else {
+		g.Annotations[iamv1beta1.SCIMExternalIDAnnotation] = scimGroup.ExternalID
+	}
+}
+
+func toUser(user *iamv1beta1.User) *User {
+	active := user.Status.State != iamv1beta1.UserDisabled
+	scimUser := &User{
+		Schemas:           []string{SchemaUser},
+		ID:                user.Name,
+		ExternalID:        user.Annotations[iamv1beta1.SCIMExternalIDAnnotation],
+		UserName:          user.Name,
+		DisplayName:       user.Spec.DisplayName,
+		PreferredLanguage: user.Spec.Lang,
+		Active:            &active,
+		Meta:              newMeta(ResourceTypeUser, &user.ObjectMeta),
+	}
+	if user.Spec.Email != "" {
+		scimUser.Emails = []MultiValuedAttribute{{Value: user.Spec.Email, Type: "work", Primary: true}}
+	}
+	for _, g := range user.Spec.Groups {
+		scimUser.Groups = append(scimUser.Groups, MultiValuedAttribute{
+			Value:   g,
+			Display: g,
+			Ref:     fmt.Sprintf("%s/Groups/%s", BasePath, g),
+		})
+	}
+	if user.Status.LastTransitionTime != nil && user.Status.LastTransitionTime.After(*scimUser.Meta.LastModified) {
+		lastModified := user.Status.LastTransitionTime.Time
+		scimUser.Meta.LastModified = &lastModified
+	}
+	return scimUser
+}
+
+func toGroup(g *iamv1beta1.Group, members []string) *Group {
+	displayName := g.Annotations[constants.DisplayNameAnnotationKey]
+	if displayName == "" {
+		displayName = g.Name
+	}
+	scimGroup := &Group{
+		Schemas:     []string{SchemaGroup},
+		ID:          g.Name,
+		ExternalID:  g.Annotations[iamv1beta1.SCIMExternalIDAnnotation],
+		DisplayName: displayName,
+		Meta:        newMeta(ResourceTypeGroup, &g.ObjectMeta),
+	}
+	sort.Strings(members)
+	for _, member := range members {
+		scimGroup.Members = append(scimGroup.Members, MultiValuedAttribute{
+			Value:   member,
+			Display: member,
+			Ref:     fmt.Sprintf("%s/Users/%s", BasePath, member),
+		})
+	}
+	return scimGroup
+}
+
+func newMeta(resourceType string, object *metav1.ObjectMeta) *Meta {
+	created := object.CreationTimestamp.Time
+	return &Meta{
+		ResourceType: resourceType,
+		Created:      &created,
+		LastModified: &created,
+		Location:     fmt.Sprintf("%s/%ss/%s", BasePath, resourceType, object.Name),
+		Version:      fmt.Sprintf("W/%q", object.ResourceVersion),
+	}
+}
+
+// groupName converts the display name to a valid name, which is also a valid label value.
+func groupName(displayName string) string {
+	name := invalidGroupNameChars.ReplaceAllString(strings.ToLower(displayName), "-")
+	if len(name) > validation.DNS1123LabelMaxLength {
+		name = name[:validation.DNS1123LabelMaxLength]
+	}
+	return strings.Trim(name, "-")
+}
+
+func primaryValue(values []MultiValuedAttribute) string {
+	for _, value := range values {
+		if value.Primary {
+			return value.Value
+		}
+	}
+	if len(values) > 0 {
+		return values[0].Value
+	}
+	return ""
+}
+
+// applyPatch applies the PATCH operations to the resource in the form of the JSON object, and decodes the result into out.
+func applyPatch(resource interface{}, patch *PatchOp, out interface{}) error {
+	data, err := json.Marshal(resource)
+	if err != nil {
+		return err
+	}
+	object := make(map[string]interface{})
+	if err = json.Unmarshal(data, &object); err != nil {
+		return err
+	}
+	if err = ApplyPatch(object, patch); err != nil {
+		return err
+	}
+	// some identity providers send the boolean values as strings, e.g. {"active": "False"}
+	if key := canonicalKey(object, "active"); object[key] != nil {
+		if value, ok := object[key].(string); ok {
+			active, err := strconv.ParseBool(value)
+			if err != nil {
+				return NewBadRequest(ErrorInvalidValue, "invalid active %q", value)
+			}
+			object[key] = active
+		}
+	}
+	if data, err = json.Marshal(object); err != nil {
+		return err
+	}
+	if err = json.Unmarshal(data, out); err != nil {
+		return NewBadRequest(ErrorInvalidValue, "invalid value: %s", err)
+	}
+	return nil
+}
+
+// listResponse returns the page of the resources matching the filter, the startIndex is 1-based,
+// and all the resources from the startIndex are returned if count is negative.
+func listResponse(resources []interface{}, filter string, startIndex, count int) (*ListResponse, error) {
+	if filter != "" {
+		f, err := ParseFilter(filter)
+		if err != nil {
+			return nil, err
+		}
+		matched := make([]interface{}, 0, len(resources))
+		for _, resource := range resources {
+			data, err := json.Marshal(resource)
+			if err != nil {
+				return nil, err
+			}
+			object := make(map[string]interface{})
+			if err = json.Unmarshal(data, &object); err != nil {
+				return nil, err
+			}
+			if f.Matches(object) {
+				matched = append(matched, resource)
+			}
+		}
+		resources = matched
+	}
+	sort.SliceStable(resources, func(i, j int) bool {
+		return resourceID(resources[i]) < resourceID(resources[j])
+	})
+
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	page := make([]interface{}, 0)
+	if startIndex <= len(resources) {
+		page = resources[startIndex-1:]
+	}
+	if count >= 0 && count < len(page) {
+		page = page[:count]
+	}
+	return &ListResponse{
+		Schemas:      []string{SchemaListResponse},
+		TotalResults: len(resources),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	}, nil
+}
+
+func resourceID(resource interface{}) string {
+	switch r := resource.(type) {
+	case *User:
+		return r.ID
+	case *Group:
+		return r.ID
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package scim
+
+import (
+	"context"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
+	runtimefakeclient "sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authentication"
+	"kubesphere.io/kubesphere/pkg/models/iam/im"
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+func TestUsersScopedToSCIM(t *testing.T) {
+	client := runtimefakeclient.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(
+			&iamv1beta1.User{ObjectMeta: metav1.ObjectMeta{Name: "admin"}},
+			&iamv1beta1.User{ObjectMeta: metav1.ObjectMeta{
+				Name:   "jane",
+				Labels: map[string]string{iamv1beta1.IdentityProviderLabel: Source},
+			}},
+		).
+		Build()
+	operator := NewOperator(im.NewOperator(client, nil, nil), client, authentication.SCIMOptions{})
+	ctx := context.Background()
+
+	if _, err := operator.GetUser(ctx, "admin"); !errors.IsNotFound(err) {
+		t.Errorf("expected the user not provisioned by SCIM not to be found, got %v", err)
+	}
+	if _, err := operator.ReplaceUser(ctx, "admin", &User{DisplayName: "admin"}); !errors.IsNotFound(err) {
+		t.Errorf("expected the user not provisioned by SCIM not to be replaced, got %v", err)
+	}
+	if _, err := operator.PatchUser(ctx, "admin", &PatchOp{}); !errors.IsNotFound(err) {
+		t.Errorf("expected the user not provisioned by SCIM not to be patched, got %v", err)
+	}
+	if err := operator.DeleteUser(ctx, "admin"); !errors.IsNotFound(err) {
+		t.Errorf("expected the user not provisioned by SCIM not to be deleted, got %v", err)
+	}
+	if err := client.Get(ctx, runtimeclient.ObjectKey{Name: "admin"}, &iamv1beta1.User{}); err != nil {
+		t.Errorf("expected the user admin to be kept, got %v", err)
+	}
+
+	if _, err := operator.GetUser(ctx, "jane"); err != nil {
+		t.Error(err)
+	}
+	if err := operator.DeleteUser(ctx, "jane"); err != nil {
+		t.Error(err)
+	}
+
+	created, err := operator.CreateUser(ctx, &User{UserName: "John"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = operator.GetUser(ctx, created.ID); err != nil {
+		t.Errorf("expected the user created by SCIM to be found, got %v", err)
+	}
+}
+
+func TestReplaceUserKeepsState(t *testing.T) {
+	client := runtimefakeclient.NewClientBuilder().
+		WithScheme(scheme.Scheme).
+		WithObjects(
+			&iamv1beta1.User{
+				ObjectMeta: metav1.ObjectMeta{Name: "jane", Labels: map[string]string{iamv1beta1.IdentityProviderLabel: Source}},
+				Status:     iamv1beta1.UserStatus{State: iamv1beta1.UserAuthLimitExceeded},
+			},
+			&iamv1beta1.User{
+				ObjectMeta: metav1.ObjectMeta{Name: "john", Labels: map[string]string{iamv1beta1.IdentityProviderLabel: Source}},
+				Status:     iamv1beta1.UserStatus{State: iamv1beta1.UserDisabled},
+			},
+		).
+		Build()
+	operator := NewOperator(im.NewOperator(client, nil, nil), client, authentication.SCIMOptions{})
+	ctx := context.Background()
+	active := true
+
+	tests := map[string]iamv1beta1.UserState{
+		// the lockout isn't cleared by the active resent on every sync
+		"jane": iamv1beta1.UserAuthLimitExceeded,
+		"john": iamv1beta1.UserActive,
+	}
+	for name, want := range tests {
+		if _, err := operator.ReplaceUser(ctx, name, &User{UserName: name, Active: &active}); err != nil {
+			t.Fatal(err)
+		}
+		user := &iamv1beta1.User{}
+		if err := client.Get(ctx, runtimeclient.ObjectKey{Name: name}, user); err != nil {
+			t.Fatal(err)
+		}
+		if user.Status.State != want {
+			t.Errorf("expected the state of the user %s to be %s, got %s", name, want, user.Status.State)
+		}
+	}
+}
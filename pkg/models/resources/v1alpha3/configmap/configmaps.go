@@ -43,7 +43,7 @@ func (d *configmapsGetter) List(namespace string, query *query.Query) (*api.List
 	for _, item := range configMaps.Items {
 		result = append(result, item.DeepCopy())
 	}
-	return v1alpha3.DefaultList(result, query, d.compare, d.filter), nil
+	return v1alpha3.DefaultList(result, query, d.compare, d.Filter), nil
 }
 
 func (d *configmapsGetter) compare(left runtime.Object, right runtime.Object, field query.Field) bool {
@@ -60,7 +60,11 @@ func (d *configmapsGetter) compare(left runtime.Object, right runtime.Object, fi
 	return v1alpha3.DefaultObjectMetaCompare(leftCM.ObjectMeta, rightCM.ObjectMeta, field)
 }
 
-func (d *configmapsGetter) filter(object runtime.Object, filter query.Filter) bool {
+func (d *configmapsGetter) Object() runtimeclient.Object {
+	return &corev1.ConfigMap{}
+}
+
+func (d *configmapsGetter) Filter(object runtime.Object, filter query.Filter) bool {
 	configMap, ok := object.(*corev1.ConfigMap)
 	if !ok {
 		return false
@@ -11,7 +11,6 @@ import (
 	"strings"
 
 	"k8s.io/apimachinery/pkg/types"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	appsv1 "k8s.io/api/apps/v1"
@@ -43,15 +42,15 @@ func (d *daemonSetGetter) Get(namespace, name string) (runtime.Object, error) {
 
 func (d *daemonSetGetter) List(namespace string, query *query.Query) (*api.ListResult, error) {
 	daemonSets := &appsv1.DaemonSetList{}
-	if err := d.cache.List(context.Background(), daemonSets, client.InNamespace(namespace),
-		client.MatchingLabelsSelector{Selector: query.Selector()}); err != nil {
+	if err := d.cache.List(context.Background(), daemonSets,
+		v1alpha3.ListOptions(d.cache, &appsv1.DaemonSet{}, namespace, query, v1alpha3.ObjectMetaIndexes...)...); err != nil {
 		return nil, err
 	}
 	var result []runtime.Object
-	for _, item := range daemonSets.Items {
-		result = append(result, item.DeepCopy())
+	for i := range daemonSets.Items {
+		result = append(result, &daemonSets.Items[i])
 	}
-	return v1alpha3.DefaultList(result, query, d.compare, d.filter), nil
+	return v1alpha3.DefaultList(result, query, d.compare, d.Filter), nil
 }
 
 func (d *daemonSetGetter) compare(left runtime.Object, right runtime.Object, field query.Field) bool {
@@ -68,7 +67,22 @@ func (d *daemonSetGetter) compare(left runtime.Object, right runtime.Object, fie
 	return v1alpha3.DefaultObjectMetaCompare(leftDaemonSet.ObjectMeta, rightDaemonSet.ObjectMeta, field)
 }
 
-func (d *daemonSetGetter) filter(object runtime.Object, filter query.Filter) bool {
+func (d *daemonSetGetter) FieldValues(object runtime.Object, field query.Field) []string {
+	daemonSet, ok := object.(*appsv1.DaemonSet)
+	if !ok {
+		return nil
+	}
+	if field == query.FieldStatus {
+		return []string{daemonSetStatus(&daemonSet.Status)}
+	}
+	return v1alpha3.DefaultObjectMetaFieldValues(&daemonSet.ObjectMeta, field)
+}
+
+func (d *daemonSetGetter) Object() runtimeclient.Object {
+	return &appsv1.DaemonSet{}
+}
+
+func (d *daemonSetGetter) Filter(object runtime.Object, filter query.Filter) bool {
 	daemonSet, ok := object.(*appsv1.DaemonSet)
 	if !ok {
 		return false
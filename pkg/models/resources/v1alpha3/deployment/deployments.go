@@ -12,7 +12,6 @@ import (
 	"time"
 
 	"k8s.io/apimachinery/pkg/types"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"k8s.io/apimachinery/pkg/runtime"
@@ -45,15 +44,15 @@ func (d *deploymentsGetter) Get(namespace, name string) (runtime.Object, error)
 
 func (d *deploymentsGetter) List(namespace string, query *query.Query) (*api.ListResult, error) {
 	deployments := &appsv1.DeploymentList{}
-	if err := d.cache.List(context.Background(), deployments, client.InNamespace(namespace),
-		client.MatchingLabelsSelector{Selector: query.Selector()}); err != nil {
+	if err := d.cache.List(context.Background(), deployments,
+		v1alpha3.ListOptions(d.cache, &appsv1.Deployment{}, namespace, query, v1alpha3.ObjectMetaIndexes...)...); err != nil {
 		return nil, err
 	}
 	var result []runtime.Object
-	for _, item := range deployments.Items {
-		result = append(result, item.DeepCopy())
+	for i := range deployments.Items {
+		result = append(result, &deployments.Items[i])
 	}
-	return v1alpha3.DefaultList(result, query, d.compare, d.filter), nil
+	return v1alpha3.DefaultList(result, query, d.compare, d.Filter), nil
 }
 
 func (d *deploymentsGetter) compare(left runtime.Object, right runtime.Object, field query.Field) bool {
@@ -77,7 +76,22 @@ func (d *deploymentsGetter) compare(left runtime.Object, right runtime.Object, f
 	}
 }
 
-func (d *deploymentsGetter) filter(object runtime.Object, filter query.Filter) bool {
+func (d *deploymentsGetter) FieldValues(object runtime.Object, field query.Field) []string {
+	deployment, ok := object.(*appsv1.Deployment)
+	if !ok {
+		return nil
+	}
+	if field == query.FieldStatus {
+		return []string{deploymentStatus(deployment.Status)}
+	}
+	return v1alpha3.DefaultObjectMetaFieldValues(&deployment.ObjectMeta, field)
+}
+
+func (d *deploymentsGetter) Object() runtimeclient.Object {
+	return &appsv1.Deployment{}
+}
+
+func (d *deploymentsGetter) Filter(object runtime.Object, filter query.Filter) bool {
 	deployment, ok := object.(*appsv1.Deployment)
 	if !ok {
 		return false
@@ -0,0 +1,158 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package v1alpha3
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/query"
+)
+
+const (
+	// IndexLabel indexes the objects by each of their labels in the form of key=value
+	IndexLabel = "metadata.labels"
+	// IndexOwnerReference indexes the objects by the UIDs of their owners
+	IndexOwnerReference = "metadata.ownerReferences.uid"
+	// IndexStatus indexes the objects by the status the resource getter filters them by
+	IndexStatus = "status"
+)
+
+// FieldIndex is the field index registered in the cache which selects the objects matching the filter of the field.
+type FieldIndex struct {
+	Field query.Field
+	Name  string
+}
+
+// ObjectMetaIndexes are the field indexes registered by IndexObjectMeta.
+var ObjectMetaIndexes = []FieldIndex{
+	{Field: query.FieldOwnerReference, Name: IndexOwnerReference},
+	{Field: query.FieldLabel, Name: IndexLabel},
+}
+
+// FieldIndexes tells the field indexes registered in the cache.
+type FieldIndexes interface {
+	// HasIndex returns true if the field index of the objects is registered
+	HasIndex(obj client.Object, field string) bool
+}
+
+// IndexedCache is the cache recording the field indexes registered, the resource getters
+// only narrow down the objects listed by the field indexes registered in the cache.
+type IndexedCache struct {
+	cache.Cache
+	lock    sync.RWMutex
+	indexes map[reflect.Type]sets.Set[string]
+}
+
+var _ FieldIndexes = &IndexedCache{}
+
+func NewIndexedCache(c cache.Cache) *IndexedCache {
+	return &IndexedCache{Cache: c, indexes: make(map[reflect.Type]sets.Set[string])}
+}
+
+func (c *IndexedCache) IndexField(ctx context.Context, obj client.Object, field string, extractValue client.IndexerFunc) error {
+	if err := c.Cache.IndexField(ctx, obj, field, extractValue); err != nil {
+		return err
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	objType := reflect.TypeOf(obj)
+	if c.indexes[objType] == nil {
+		c.indexes[objType] = sets.New[string]()
+	}
+	c.indexes[objType].Insert(field)
+	return nil
+}
+
+func (c *IndexedCache) HasIndex(obj client.Object, field string) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.indexes[reflect.TypeOf(obj)].Has(field)
+}
+
+// IndexObjectMeta registers the label and owner reference indexes of the objects.
+func IndexObjectMeta(ctx context.Context, indexer client.FieldIndexer, obj client.Object) error {
+	if err := indexer.IndexField(ctx, obj, IndexLabel, labelIndexValues); err != nil {
+		return err
+	}
+	return indexer.IndexField(ctx, obj, IndexOwnerReference, ownerReferenceIndexValues)
+}
+
+func labelIndexValues(obj client.Object) []string {
+	values := make([]string, 0, len(obj.GetLabels()))
+	for key, value := range obj.GetLabels() {
+		values = append(values, key+"="+value)
+	}
+	return values
+}
+
+func ownerReferenceIndexValues(obj client.Object) []string {
+	values := make([]string, 0, len(obj.GetOwnerReferences()))
+	for _, ownerReference := range obj.GetOwnerReferences() {
+		values = append(values, string(ownerReference.UID))
+	}
+	return values
+}
+
+// labelIndexValue returns the label index value of the first label required to equal a single value by the selector.
+func labelIndexValue(selector string) (string, bool) {
+	parsed, err := labels.Parse(selector)
+	if err != nil {
+		return "", false
+	}
+	requirements, _ := parsed.Requirements()
+	for _, requirement := range requirements {
+		switch requirement.Operator() {
+		case selection.Equals, selection.DoubleEquals, selection.In:
+			if values := requirement.Values(); values.Len() == 1 {
+				return requirement.Key() + "=" + values.UnsortedList()[0], true
+			}
+		}
+	}
+	return "", false
+}
+
+// ListOptions returns the options listing the objects in the namespace matching the label selector of the query.
+// The objects are also narrowed down by the first field index registered in the cache which matches a filter or
+// the label selector, so that the objects not matched are not copied from the cache. The filters still apply to
+// the objects listed, the indexes must select a superset of the objects matching the filters of their fields.
+func ListOptions(reader client.Reader, obj client.Object, namespace string, q *query.Query, indexes ...FieldIndex) []client.ListOption {
+	options := []client.ListOption{client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: q.Selector()}}
+	fieldIndexes, ok := reader.(FieldIndexes)
+	if !ok {
+		return options
+	}
+	for _, index := range indexes {
+		if !fieldIndexes.HasIndex(obj, index.Name) {
+			continue
+		}
+		value, ok := q.Filters[index.Field]
+		if !ok {
+			continue
+		}
+		indexValue := string(value)
+		if index.Name == IndexLabel {
+			if indexValue, ok = labelIndexValue(indexValue); !ok {
+				continue
+			}
+		}
+		return append(options, client.MatchingFields{index.Name: indexValue})
+	}
+	if fieldIndexes.HasIndex(obj, IndexLabel) {
+		if indexValue, ok := labelIndexValue(q.LabelSelector); ok {
+			return append(options, client.MatchingFields{IndexLabel: indexValue})
+		}
+	}
+	return options
+}
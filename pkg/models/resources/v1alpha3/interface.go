@@ -12,8 +12,10 @@ import (
 
 	"kubesphere.io/kubesphere/pkg/constants"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/klog/v2"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
@@ -30,6 +32,43 @@ type Interface interface {
 	List(namespace string, query *query.Query) (*api.ListResult, error)
 }
 
+// Aggregator is implemented by the resource getters grouping the objects by the fields of their own,
+// the objects are grouped by the metadata fields by default.
+type Aggregator interface {
+	// FieldValues returns the values of the field of the object, the object is counted in the group of each value
+	FieldValues(object runtime.Object, field query.Field) []string
+}
+
+// Watchable is implemented by the resource getters whose objects can be watched, the objects are watched
+// from the informers of the cache the objects are listed from.
+type Watchable interface {
+	// Object returns an empty object of the resource
+	Object() client.Object
+
+	// Filter returns true if the object matches the filter, the same as the objects listed are filtered
+	Filter(object runtime.Object, filter query.Filter) bool
+}
+
+// Match returns true if the object is in the namespace, and matches the label selector and the filters of the query.
+func Match(watchable Watchable, object runtime.Object, namespace string, q *query.Query) bool {
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return false
+	}
+	if namespace != "" && accessor.GetNamespace() != namespace {
+		return false
+	}
+	if !q.Selector().Matches(labels.Set(accessor.GetLabels())) {
+		return false
+	}
+	for field, value := range q.Filters {
+		if !watchable.Filter(object, query.Filter{Field: field, Value: value}) {
+			return false
+		}
+	}
+	return true
+}
+
 // CompareFunc return true is left greater than right
 type CompareFunc func(runtime.Object, runtime.Object, query.Field) bool
 
@@ -58,24 +97,17 @@ func DefaultList(objects []runtime.Object, q *query.Query, compareFunc CompareFu
 	}
 
 	// sort by sortBy field
+	less := q.Order(compareFunc)
 	sort.Slice(filtered, func(i, j int) bool {
-		if !q.Ascending {
-			return compareFunc(filtered[i], filtered[j], q.SortBy)
-		}
-		return !compareFunc(filtered[i], filtered[j], q.SortBy)
+		return less(filtered[i], filtered[j])
 	})
 
-	total := len(filtered)
-
-	if q.Pagination == nil {
-		q.Pagination = query.NoPagination
-	}
-
-	start, end := q.Pagination.GetValidPagination(total)
+	start, end, next := q.Page(filtered, less)
 
 	return &api.ListResult{
 		TotalItems: len(filtered),
 		Items:      filtered[start:end],
+		Continue:   next,
 	}
 }
 
@@ -150,6 +182,32 @@ func DefaultObjectMetaFilter(item metav1.ObjectMeta, filter query.Filter) bool {
 	}
 }
 
+// DefaultObjectMetaFieldValues returns the values of the metadata field the objects are grouped by.
+func DefaultObjectMetaFieldValues(item metav1.Object, field query.Field) []string {
+	switch field {
+	case query.FieldName:
+		return []string{item.GetName()}
+	case query.FieldUID:
+		return []string{string(item.GetUID())}
+	case query.FieldNamespace:
+		return []string{item.GetNamespace()}
+	case query.FieldOwnerReference:
+		values := make([]string, 0, len(item.GetOwnerReferences()))
+		for _, ownerReference := range item.GetOwnerReferences() {
+			values = append(values, string(ownerReference.UID))
+		}
+		return values
+	case query.FieldOwnerKind:
+		values := make([]string, 0, len(item.GetOwnerReferences()))
+		for _, ownerReference := range item.GetOwnerReferences() {
+			values = append(values, ownerReference.Kind)
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
 func labelMatch(m map[string]string, filter string) bool {
 	labelSelector, err := labels.Parse(filter)
 	if err != nil {
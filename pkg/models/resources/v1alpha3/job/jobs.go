@@ -12,7 +12,6 @@ import (
 	"time"
 
 	"k8s.io/apimachinery/pkg/types"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	batchv1 "k8s.io/api/batch/v1"
@@ -45,15 +44,15 @@ func (d *jobsGetter) Get(namespace, name string) (runtime.Object, error) {
 
 func (d *jobsGetter) List(namespace string, query *query.Query) (*api.ListResult, error) {
 	jobs := &batchv1.JobList{}
-	if err := d.cache.List(context.Background(), jobs, client.InNamespace(namespace),
-		client.MatchingLabelsSelector{Selector: query.Selector()}); err != nil {
+	if err := d.cache.List(context.Background(), jobs,
+		v1alpha3.ListOptions(d.cache, &batchv1.Job{}, namespace, query, v1alpha3.ObjectMetaIndexes...)...); err != nil {
 		return nil, err
 	}
 	var result []runtime.Object
-	for _, item := range jobs.Items {
-		result = append(result, item.DeepCopy())
+	for i := range jobs.Items {
+		result = append(result, &jobs.Items[i])
 	}
-	return v1alpha3.DefaultList(result, query, d.compare, d.filter), nil
+	return v1alpha3.DefaultList(result, query, d.compare, d.Filter), nil
 }
 
 func (d *jobsGetter) compare(left runtime.Object, right runtime.Object, field query.Field) bool {
@@ -79,7 +78,22 @@ func (d *jobsGetter) compare(left runtime.Object, right runtime.Object, field qu
 	}
 }
 
-func (d *jobsGetter) filter(object runtime.Object, filter query.Filter) bool {
+func (d *jobsGetter) FieldValues(object runtime.Object, field query.Field) []string {
+	job, ok := object.(*batchv1.Job)
+	if !ok {
+		return nil
+	}
+	if field == query.FieldStatus {
+		return []string{jobStatus(job.Status)}
+	}
+	return v1alpha3.DefaultObjectMetaFieldValues(&job.ObjectMeta, field)
+}
+
+func (d *jobsGetter) Object() runtimeclient.Object {
+	return &batchv1.Job{}
+}
+
+func (d *jobsGetter) Filter(object runtime.Object, filter query.Filter) bool {
 	job, ok := object.(*batchv1.Job)
 	if !ok {
 		return false
@@ -43,10 +43,14 @@ func (n namespacesGetter) List(_ string, query *query.Query) (*api.ListResult, e
 	for _, item := range namespaces.Items {
 		result = append(result, item.DeepCopy())
 	}
-	return v1alpha3.DefaultList(result, query, n.compare, n.filter), nil
+	return v1alpha3.DefaultList(result, query, n.compare, n.Filter), nil
 }
 
-func (n namespacesGetter) filter(item runtime.Object, filter query.Filter) bool {
+func (n namespacesGetter) Object() runtimeclient.Object {
+	return &corev1.Namespace{}
+}
+
+func (n namespacesGetter) Filter(item runtime.Object, filter query.Filter) bool {
 	namespace, ok := item.(*corev1.Namespace)
 	if !ok {
 		return false
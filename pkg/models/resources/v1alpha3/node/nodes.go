@@ -60,7 +60,7 @@ func (c *nodesGetter) List(_ string, q *query.Query) (*api.ListResult, error) {
 	for _, item := range nodes.Items {
 		result = append(result, item.DeepCopy())
 	}
-	return v1alpha3.DefaultList(result, q, c.compare, c.filter), nil
+	return v1alpha3.DefaultList(result, q, c.compare, c.Filter), nil
 }
 
 func (c *nodesGetter) compare(left runtime.Object, right runtime.Object, field query.Field) bool {
@@ -77,7 +77,22 @@ func (c *nodesGetter) compare(left runtime.Object, right runtime.Object, field q
 	return v1alpha3.DefaultObjectMetaCompare(leftNode.ObjectMeta, rightNode.ObjectMeta, field)
 }
 
-func (c *nodesGetter) filter(object runtime.Object, filter query.Filter) bool {
+func (c *nodesGetter) FieldValues(object runtime.Object, field query.Field) []string {
+	node, ok := object.(*corev1.Node)
+	if !ok {
+		return nil
+	}
+	if field == query.FieldStatus {
+		return []string{getNodeStatus(node)}
+	}
+	return v1alpha3.DefaultObjectMetaFieldValues(&node.ObjectMeta, field)
+}
+
+func (c *nodesGetter) Object() runtimeclient.Object {
+	return &corev1.Node{}
+}
+
+func (c *nodesGetter) Filter(object runtime.Object, filter query.Filter) bool {
 	node, ok := object.(*corev1.Node)
 	if !ok {
 		return false
@@ -52,7 +52,7 @@ func (p *persistentVolumeClaimGetter) List(namespace string, query *query.Query)
 	for _, item := range persistentVolumeClaims.Items {
 		result = append(result, item.DeepCopy())
 	}
-	return v1alpha3.DefaultList(result, query, p.compare, p.filter), nil
+	return v1alpha3.DefaultList(result, query, p.compare, p.Filter), nil
 }
 
 func (p *persistentVolumeClaimGetter) compare(left, right runtime.Object, field query.Field) bool {
@@ -67,7 +67,11 @@ func (p *persistentVolumeClaimGetter) compare(left, right runtime.Object, field
 	return v1alpha3.DefaultObjectMetaCompare(leftPVC.ObjectMeta, rightPVC.ObjectMeta, field)
 }
 
-func (p *persistentVolumeClaimGetter) filter(object runtime.Object, filter query.Filter) bool {
+func (p *persistentVolumeClaimGetter) Object() runtimeclient.Object {
+	return &corev1.PersistentVolumeClaim{}
+}
+
+func (p *persistentVolumeClaimGetter) Filter(object runtime.Object, filter query.Filter) bool {
 	pvc, ok := object.(*corev1.PersistentVolumeClaim)
 	if !ok {
 		return false
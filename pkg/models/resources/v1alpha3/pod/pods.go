@@ -17,6 +17,7 @@ import (
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
@@ -57,16 +58,76 @@ func (p *podsGetter) Get(namespace, name string) (runtime.Object, error) {
 }
 
 func (p *podsGetter) List(namespace string, query *query.Query) (*api.ListResult, error) {
-	pods := &corev1.PodList{}
-	if err := p.cache.List(context.Background(), pods, client.InNamespace(namespace),
-		client.MatchingLabelsSelector{Selector: query.Selector()}); err != nil {
+	pods, err := p.listPods(namespace, query)
+	if err != nil {
 		return nil, err
 	}
 	var result []runtime.Object
-	for _, item := range pods.Items {
-		result = append(result, &item)
+	for i := range pods {
+		result = append(result, &pods[i])
 	}
-	return v1alpha3.DefaultList(result, query, p.compare, p.filter), nil
+	return v1alpha3.DefaultList(result, query, p.compare, p.Filter), nil
+}
+
+// AddIndexes registers the field indexes the pods listed are narrowed down by,
+// the replica sets are indexed to look up the pods owned by the deployments.
+func AddIndexes(ctx context.Context, indexer client.FieldIndexer) error {
+	if err := v1alpha3.IndexObjectMeta(ctx, indexer, &corev1.Pod{}); err != nil {
+		return err
+	}
+	if err := v1alpha3.IndexObjectMeta(ctx, indexer, &appsv1.ReplicaSet{}); err != nil {
+		return err
+	}
+	getter := &podsGetter{}
+	return indexer.IndexField(ctx, &corev1.Pod{}, v1alpha3.IndexStatus, func(obj client.Object) []string {
+		return []string{getter.getPodStatus(obj.(*corev1.Pod))}
+	})
+}
+
+func (p *podsGetter) listPods(namespace string, q *query.Query) ([]corev1.Pod, error) {
+	ownerUID, ok := q.Filters[fieldOwnerReference]
+	if !ok || !p.hasIndex(&corev1.Pod{}, v1alpha3.IndexOwnerReference) || !p.hasIndex(&appsv1.ReplicaSet{}, v1alpha3.IndexOwnerReference) {
+		pods := &corev1.PodList{}
+		if err := p.cache.List(context.Background(), pods, v1alpha3.ListOptions(p.cache, &corev1.Pod{}, namespace, q,
+			v1alpha3.FieldIndex{Field: fieldStatus, Name: v1alpha3.IndexStatus},
+			v1alpha3.FieldIndex{Field: query.FieldLabel, Name: v1alpha3.IndexLabel})...); err != nil {
+			return nil, err
+		}
+		return pods.Items, nil
+	}
+
+	// the pods are owned by the owner directly or by the replica sets owned by the owner
+	replicaSets := &appsv1.ReplicaSetList{}
+	if err := p.cache.List(context.Background(), replicaSets, client.InNamespace(namespace),
+		client.MatchingFields{v1alpha3.IndexOwnerReference: string(ownerUID)}); err != nil {
+		return nil, err
+	}
+	ownerUIDs := []string{string(ownerUID)}
+	for _, replicaSet := range replicaSets.Items {
+		ownerUIDs = append(ownerUIDs, string(replicaSet.UID))
+	}
+	var result []corev1.Pod
+	listed := sets.New[types.UID]()
+	for _, uid := range ownerUIDs {
+		pods := &corev1.PodList{}
+		if err := p.cache.List(context.Background(), pods, client.InNamespace(namespace),
+			client.MatchingLabelsSelector{Selector: q.Selector()},
+			client.MatchingFields{v1alpha3.IndexOwnerReference: uid}); err != nil {
+			return nil, err
+		}
+		for _, pod := range pods.Items {
+			if !listed.Has(pod.UID) {
+				listed.Insert(pod.UID)
+				result = append(result, pod)
+			}
+		}
+	}
+	return result, nil
+}
+
+func (p *podsGetter) hasIndex(obj client.Object, field string) bool {
+	fieldIndexes, ok := p.cache.(v1alpha3.FieldIndexes)
+	return ok && fieldIndexes.HasIndex(obj, field)
 }
 
 func (p *podsGetter) compare(left runtime.Object, right runtime.Object, field query.Field) bool {
@@ -83,7 +144,11 @@ func (p *podsGetter) compare(left runtime.Object, right runtime.Object, field qu
 	return v1alpha3.DefaultObjectMetaCompare(leftPod.ObjectMeta, rightPod.ObjectMeta, field)
 }
 
-func (p *podsGetter) filter(object runtime.Object, filter query.Filter) bool {
+func (p *podsGetter) Object() runtimeclient.Object {
+	return &corev1.Pod{}
+}
+
+func (p *podsGetter) Filter(object runtime.Object, filter query.Filter) bool {
 	pod, ok := object.(*corev1.Pod)
 
 	if !ok {
@@ -109,6 +174,23 @@ func (p *podsGetter) filter(object runtime.Object, filter query.Filter) bool {
 	}
 }
 
+func (p *podsGetter) FieldValues(object runtime.Object, field query.Field) []string {
+	pod, ok := object.(*corev1.Pod)
+	if !ok {
+		return nil
+	}
+	switch field {
+	case fieldNodeName:
+		return []string{pod.Spec.NodeName}
+	case fieldStatus:
+		return []string{p.getPodStatus(pod)}
+	case fieldPhase:
+		return []string{string(pod.Status.Phase)}
+	default:
+		return v1alpha3.DefaultObjectMetaFieldValues(&pod.ObjectMeta, field)
+	}
+}
+
 func (p *podsGetter) podWithIP(item *corev1.Pod, ipAddress string) bool {
 	for _, ip := range item.Status.PodIPs {
 		if strings.Contains(ip.String(), ipAddress) {
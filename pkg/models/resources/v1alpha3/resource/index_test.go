@@ -0,0 +1,317 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package resource
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	toolscache "k8s.io/client-go/tools/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/query"
+	"kubesphere.io/kubesphere/pkg/models/resources/v1alpha3"
+	"kubesphere.io/kubesphere/pkg/models/resources/v1alpha3/pod"
+)
+
+// indexerReader is the cache reader backed by the client-go indexers as the informer cache is,
+// which copies the objects listed and looks up the objects by the field indexes registered.
+type indexerReader struct {
+	indexers map[reflect.Type]toolscache.Indexer
+	indexes  map[reflect.Type]sets.Set[string]
+}
+
+var _ v1alpha3.FieldIndexes = &indexerReader{}
+
+// newIndexerReader returns the reader of the objects, the field indexes are registered if indexed.
+func newIndexerReader(indexed bool, objects ...client.Object) (*indexerReader, error) {
+	reader := &indexerReader{
+		indexers: make(map[reflect.Type]toolscache.Indexer),
+		indexes:  make(map[reflect.Type]sets.Set[string]),
+	}
+	if indexed {
+		if err := AddIndexes(context.Background(), reader); err != nil {
+			return nil, err
+		}
+	}
+	for _, obj := range objects {
+		if err := reader.indexer(obj).Add(obj); err != nil {
+			return nil, err
+		}
+	}
+	return reader, nil
+}
+
+func (r *indexerReader) indexer(obj runtime.Object) toolscache.Indexer {
+	objType := reflect.TypeOf(obj)
+	if r.indexers[objType] == nil {
+		r.indexers[objType] = toolscache.NewIndexer(toolscache.MetaNamespaceKeyFunc,
+			toolscache.Indexers{toolscache.NamespaceIndex: toolscache.MetaNamespaceIndexFunc})
+	}
+	return r.indexers[objType]
+}
+
+func (r *indexerReader) IndexField(_ context.Context, obj client.Object, field string, extractValue client.IndexerFunc) error {
+	objType := reflect.TypeOf(obj)
+	if r.indexes[objType] == nil {
+		r.indexes[objType] = sets.New[string]()
+	}
+	r.indexes[objType].Insert(field)
+	return r.indexer(obj).AddIndexers(toolscache.Indexers{field: func(obj interface{}) ([]string, error) {
+		return extractValue(obj.(client.Object)), nil
+	}})
+}
+
+func (r *indexerReader) HasIndex(obj client.Object, field string) bool {
+	return r.indexes[reflect.TypeOf(obj)].Has(field)
+}
+
+func (r *indexerReader) Get(_ context.Context, key client.ObjectKey, obj client.Object, _ ...client.GetOption) error {
+	item, exists, err := r.indexer(obj).GetByKey(key.String())
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.NewNotFound(schema.GroupResource{}, key.Name)
+	}
+	reflect.ValueOf(obj).Elem().Set(reflect.ValueOf(item.(runtime.Object).DeepCopyObject()).Elem())
+	return nil
+}
+
+func (r *indexerReader) List(_ context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	listOptions := &client.ListOptions{}
+	listOptions.ApplyOptions(opts)
+
+	var indexer toolscache.Indexer
+	switch list.(type) {
+	case *corev1.PodList:
+		indexer = r.indexer(&corev1.Pod{})
+	case *appsv1.ReplicaSetList:
+		indexer = r.indexer(&appsv1.ReplicaSet{})
+	default:
+		return fmt.Errorf("unsupported list %T", list)
+	}
+
+	var items []interface{}
+	var err error
+	if listOptions.FieldSelector != nil {
+		requirements := listOptions.FieldSelector.Requirements()
+		if len(requirements) != 1 {
+			return fmt.Errorf("non-exact field matches are not supported")
+		}
+		items, err = indexer.ByIndex(requirements[0].Field, requirements[0].Value)
+	} else if listOptions.Namespace != "" {
+		items, err = indexer.ByIndex(toolscache.NamespaceIndex, listOptions.Namespace)
+	} else {
+		items = indexer.List()
+	}
+	if err != nil {
+		return err
+	}
+
+	objects := make([]runtime.Object, 0, len(items))
+	for _, item := range items {
+		obj := item.(client.Object)
+		if listOptions.Namespace != "" && obj.GetNamespace() != listOptions.Namespace {
+			continue
+		}
+		if listOptions.LabelSelector != nil && !listOptions.LabelSelector.Matches(labels.Set(obj.GetLabels())) {
+			continue
+		}
+		objects = append(objects, obj.DeepCopyObject())
+	}
+	return meta.SetList(list, objects)
+}
+
+func TestListPodsByIndexes(t *testing.T) {
+	objects := benchmarkObjects(10, 20)
+	indexed, err := newIndexerReader(true, objects...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unindexed, err := newIndexerReader(false, objects...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		filters map[query.Field]query.Value
+		limit   int
+	}{
+		{
+			name:    "owner reference of the deployment",
+			filters: map[query.Field]query.Value{query.FieldOwnerReference: "deployment-3"},
+			limit:   5,
+		},
+		{
+			name:    "owner reference of the replica set",
+			filters: map[query.Field]query.Value{query.FieldOwnerReference: "replicaset-3"},
+			limit:   5,
+		},
+		{
+			name:    "status",
+			filters: map[query.Field]query.Value{query.FieldStatus: "Error"},
+			limit:   5,
+		},
+		{
+			name:    "label",
+			filters: map[query.Field]query.Value{query.FieldLabel: "app=app-7"},
+			limit:   5,
+		},
+		{
+			name:    "label and status",
+			filters: map[query.Field]query.Value{query.FieldLabel: "app=app-7", query.FieldStatus: "Running"},
+			limit:   -1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newQuery := func() *query.Query {
+				return &query.Query{
+					Pagination: &query.Pagination{Limit: tt.limit, Continuing: true},
+					SortBy:     query.FieldCreationTimeStamp,
+					Filters:    tt.filters,
+				}
+			}
+			expected, err := pod.New(unindexed).List("default", newQuery())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if expected.TotalItems == 0 {
+				t.Fatal("no pods listed")
+			}
+			got, err := pod.New(indexed).List("default", newQuery())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(expected, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+// BenchmarkListPods lists a page of the pods in a namespace holding a large number of pods,
+// the indexes only copy the pods matching the filters from the cache.
+func BenchmarkListPods(b *testing.B) {
+	objects := benchmarkObjects(100, 200)
+	filters := map[string]map[query.Field]query.Value{
+		"ownerReference": {query.FieldOwnerReference: "deployment-42"},
+		"status":         {query.FieldStatus: "Error"},
+		"label":          {query.FieldLabel: "app=app-7"},
+	}
+	for _, indexed := range []bool{false, true} {
+		reader, err := newIndexerReader(indexed, objects...)
+		if err != nil {
+			b.Fatal(err)
+		}
+		getter := pod.New(reader)
+		for _, name := range []string{"ownerReference", "status", "label"} {
+			b.Run(fmt.Sprintf("%s/indexed=%t", name, indexed), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					q := &query.Query{
+						Pagination: &query.Pagination{Limit: 10, Continuing: true},
+						SortBy:     query.FieldCreationTimeStamp,
+						Filters:    filters[name],
+					}
+					if _, err := getter.List("default", q); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkListPodPages pages through the pods by the offsets and the continue tokens.
+func BenchmarkListPodPages(b *testing.B) {
+	reader, err := newIndexerReader(true, benchmarkObjects(20, 100)...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	getter := pod.New(reader)
+	const limit = 100
+	b.Run("offset", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			q := &query.Query{
+				Pagination: &query.Pagination{Limit: limit, Offset: (i % 20) * limit},
+				SortBy:     query.FieldCreationTimeStamp,
+				Filters:    map[query.Field]query.Value{},
+			}
+			if _, err := getter.List("default", q); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("continue", func(b *testing.B) {
+		b.ReportAllocs()
+		next := ""
+		for i := 0; i < b.N; i++ {
+			q := &query.Query{
+				Pagination: &query.Pagination{Limit: limit, Continuing: true, Continue: next},
+				SortBy:     query.FieldCreationTimeStamp,
+				Filters:    map[query.Field]query.Value{},
+			}
+			result, err := getter.List("default", q)
+			if err != nil {
+				b.Fatal(err)
+			}
+			next = result.Continue
+		}
+	})
+}
+
+// benchmarkObjects returns the deployments each owning a replica set and the pods of it,
+// one in every ten pods is failed.
+func benchmarkObjects(deployments, podsPerDeployment int) []client.Object {
+	created := metav1.Date(2024, 1, 1, 0, 0, 0, 0, metav1.Now().Location())
+	var objects []client.Object
+	for i := 0; i < deployments; i++ {
+		replicaSet := &appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{
+			Name:            fmt.Sprintf("replicaset-%d", i),
+			Namespace:       "default",
+			UID:             types.UID(fmt.Sprintf("replicaset-%d", i)),
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Deployment", Name: fmt.Sprintf("deployment-%d", i), UID: types.UID(fmt.Sprintf("deployment-%d", i))}},
+		}}
+		objects = append(objects, replicaSet)
+		for j := 0; j < podsPerDeployment; j++ {
+			phase := corev1.PodRunning
+			if j%10 == 0 {
+				phase = corev1.PodFailed
+			}
+			objects = append(objects, &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              fmt.Sprintf("pod-%d-%d", i, j),
+					Namespace:         "default",
+					UID:               types.UID(fmt.Sprintf("pod-%d-%d", i, j)),
+					Labels:            map[string]string{"app": fmt.Sprintf("app-%d", i%10)},
+					CreationTimestamp: metav1.NewTime(created.Add(time.Duration(j) * time.Second)),
+					OwnerReferences:   []metav1.OwnerReference{{Kind: "ReplicaSet", Name: replicaSet.Name, UID: replicaSet.UID}},
+				},
+				Status: corev1.PodStatus{Phase: phase},
+			})
+		}
+	}
+	return objects
+}
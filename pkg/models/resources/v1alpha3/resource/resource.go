@@ -7,16 +7,21 @@
 package resource
 
 import (
+	"context"
 	"errors"
 
 	"github.com/Masterminds/semver/v3"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
 	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
 	"kubesphere.io/api/tenant/v1beta1"
 	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	runtimecache "sigs.k8s.io/controller-runtime/pkg/cache"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"kubesphere.io/kubesphere/pkg/api"
@@ -55,13 +60,20 @@ import (
 	"kubesphere.io/kubesphere/pkg/models/resources/v1alpha3/workspacerole"
 	"kubesphere.io/kubesphere/pkg/models/resources/v1alpha3/workspacerolebinding"
 	"kubesphere.io/kubesphere/pkg/models/resources/v1alpha3/workspacetemplate"
+	"kubesphere.io/kubesphere/pkg/models/resources/watcher"
+	"kubesphere.io/kubesphere/pkg/scheme"
 )
 
-var ErrResourceNotSupported = errors.New("resource is not supported")
+var (
+	ErrResourceNotSupported = errors.New("resource is not supported")
+	ErrWatchNotSupported    = errors.New("watch is not supported for the resource")
+)
 
 type Getter struct {
 	clusterResourceGetters    map[schema.GroupVersionResource]v1alpha3.Interface
 	namespacedResourceGetters map[schema.GroupVersionResource]v1alpha3.Interface
+	// broadcasters broadcast the events of the objects watched, it's nil if the cache has no informers
+	broadcasters *watcher.Broadcasters
 }
 
 func NewResourceGetter(cache runtimeclient.Reader, k8sVersion *semver.Version) *Getter {
@@ -105,10 +117,14 @@ func NewResourceGetter(cache runtimeclient.Reader, k8sVersion *semver.Version) *
 	clusterResourceGetters[clusterv1alpha1.SchemeGroupVersion.WithResource(clusterv1alpha1.ResourcesPluralCluster)] = cluster.New(cache)
 	clusterResourceGetters[clusterv1alpha1.SchemeGroupVersion.WithResource(clusterv1alpha1.ResourcesPluralLabel)] = label.New(cache)
 
-	return &Getter{
+	getter := &Getter{
 		namespacedResourceGetters: namespacedResourceGetters,
 		clusterResourceGetters:    clusterResourceGetters,
 	}
+	if informers, ok := cache.(runtimecache.Informers); ok {
+		getter.broadcasters = watcher.NewBroadcasters(informers, scheme.Scheme)
+	}
+	return getter
 }
 
 // TryResource will retrieve a getter with resource name, it doesn't guarantee find resource with correct group version
@@ -144,5 +160,74 @@ func (r *Getter) List(resource, namespace string, query *query.Query) (*api.List
 	if getter == nil {
 		return nil, ErrResourceNotSupported
 	}
+	if err := query.ValidateContinue(); err != nil {
+		return nil, err
+	}
 	return getter.List(namespace, query)
 }
+
+// Aggregate groups the objects matching the filters of the query, the pagination of the query is ignored.
+func (r *Getter) Aggregate(resource, namespace string, q *query.Query, aggregation *query.Aggregation) (*api.AggregationResult, error) {
+	clusterScope := namespace == ""
+	getter := r.TryResource(clusterScope, resource)
+	if getter == nil {
+		return nil, ErrResourceNotSupported
+	}
+	listQuery := *q
+	listQuery.Pagination = query.NoPagination
+	result, err := getter.List(namespace, &listQuery)
+	if err != nil {
+		return nil, err
+	}
+	fieldValues := func(object runtime.Object, field query.Field) []string {
+		if aggregator, ok := getter.(v1alpha3.Aggregator); ok {
+			return aggregator.FieldValues(object, field)
+		}
+		if accessor, err := meta.Accessor(object); err == nil {
+			return v1alpha3.DefaultObjectMetaFieldValues(accessor, field)
+		}
+		return nil
+	}
+	return aggregation.Aggregate(result.Items, fieldValues), nil
+}
+
+// Watch watches the objects matching the label selector and the filters of the query, the pagination and
+// the sorting of the query are ignored. The watch starts with the objects existing as the added events,
+// or resumes following the event of the last event ID if set.
+func (r *Getter) Watch(ctx context.Context, resource, namespace string, q *query.Query, lastEventID string) (<-chan watcher.Event, error) {
+	clusterScope := namespace == ""
+	getter := r.TryResource(clusterScope, resource)
+	if getter == nil {
+		return nil, ErrResourceNotSupported
+	}
+	watchable, ok := getter.(v1alpha3.Watchable)
+	if !ok || r.broadcasters == nil {
+		return nil, ErrWatchNotSupported
+	}
+	match := func(object runtime.Object) bool {
+		return v1alpha3.Match(watchable, object, namespace, q)
+	}
+	list := func() ([]runtime.Object, error) {
+		listQuery := *q
+		listQuery.Pagination = query.NoPagination
+		result, err := getter.List(namespace, &listQuery)
+		if err != nil {
+			return nil, err
+		}
+		return result.Items, nil
+	}
+	return r.broadcasters.Watch(ctx, watchable.Object(), lastEventID, match, list)
+}
+
+// AddIndexes registers the field indexes in the cache which narrow down the objects listed by the resource getters.
+func AddIndexes(ctx context.Context, indexer runtimeclient.FieldIndexer) error {
+	if err := pod.AddIndexes(ctx, indexer); err != nil {
+		return err
+	}
+	for _, obj := range []runtimeclient.Object{&appsv1.Deployment{}, &appsv1.StatefulSet{}, &appsv1.DaemonSet{}, &batchv1.Job{}} {
+		if err := v1alpha3.IndexObjectMeta(ctx, indexer, obj); err != nil {
+			return err
+		}
+	}
+	return nil
+}
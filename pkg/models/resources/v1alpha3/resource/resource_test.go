@@ -98,3 +98,61 @@ func prepare() *Getter {
 	k8sVersion120, _ := semver.NewVersion("1.20.0")
 	return NewResourceGetter(client, k8sVersion120)
 }
+
+func TestResourceAggregation(t *testing.T) {
+	reader, err := newIndexerReader(true, benchmarkObjects(3, 10)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k8sVersion120, _ := semver.NewVersion("1.20.0")
+	getter := NewResourceGetter(reader, k8sVersion120)
+
+	tests := []struct {
+		name        string
+		filters     map[query.Field]query.Value
+		aggregation *query.Aggregation
+		expected    *api.AggregationResult
+	}{
+		{
+			name:        "status of the pods filtered",
+			filters:     map[query.Field]query.Value{query.FieldOwnerReference: "deployment-1"},
+			aggregation: &query.Aggregation{Field: query.FieldStatus},
+			expected: &api.AggregationResult{
+				Buckets:    []api.AggregationBucket{{Value: "Running", Count: 9}, {Value: "Error", Count: 1}},
+				TotalItems: 10,
+			},
+		},
+		{
+			name:        "label",
+			filters:     map[query.Field]query.Value{query.FieldStatus: "Error"},
+			aggregation: &query.Aggregation{LabelKey: "app"},
+			expected: &api.AggregationResult{
+				Buckets: []api.AggregationBucket{
+					{Value: "app-0", Count: 1}, {Value: "app-1", Count: 1}, {Value: "app-2", Count: 1},
+				},
+				TotalItems: 3,
+			},
+		},
+		{
+			name:        "owner kind",
+			filters:     map[query.Field]query.Value{},
+			aggregation: &query.Aggregation{Field: query.FieldOwnerKind},
+			expected: &api.AggregationResult{
+				Buckets:    []api.AggregationBucket{{Value: "ReplicaSet", Count: 30}},
+				TotalItems: 30,
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			q := &query.Query{Pagination: &query.Pagination{Limit: 1}, Filters: test.filters}
+			result, err := getter.Aggregate("pods", "default", q, test.aggregation)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.expected, result); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
@@ -49,7 +49,7 @@ func (s *secretSearcher) List(namespace string, query *query.Query) (*api.ListRe
 	for _, item := range secrets.Items {
 		result = append(result, item.DeepCopy())
 	}
-	return v1alpha3.DefaultList(result, query, s.compare, s.filter), nil
+	return v1alpha3.DefaultList(result, query, s.compare, s.Filter), nil
 }
 
 func (s *secretSearcher) compare(left runtime.Object, right runtime.Object, field query.Field) bool {
@@ -66,7 +66,11 @@ func (s *secretSearcher) compare(left runtime.Object, right runtime.Object, fiel
 	return v1alpha3.DefaultObjectMetaCompare(leftSecret.ObjectMeta, rightSecret.ObjectMeta, field)
 }
 
-func (s *secretSearcher) filter(object runtime.Object, filter query.Filter) bool {
+func (s *secretSearcher) Object() runtimeclient.Object {
+	return &v1.Secret{}
+}
+
+func (s *secretSearcher) Filter(object runtime.Object, filter query.Filter) bool {
 	secret, ok := object.(*v1.Secret)
 	if !ok {
 		return false
@@ -43,7 +43,7 @@ func (d *servicesGetter) List(namespace string, query *query.Query) (*api.ListRe
 	for _, item := range services.Items {
 		result = append(result, item.DeepCopy())
 	}
-	return v1alpha3.DefaultList(result, query, d.compare, d.filter), nil
+	return v1alpha3.DefaultList(result, query, d.compare, d.Filter), nil
 }
 
 func (d *servicesGetter) compare(left runtime.Object, right runtime.Object, field query.Field) bool {
@@ -60,7 +60,11 @@ func (d *servicesGetter) compare(left runtime.Object, right runtime.Object, fiel
 	return v1alpha3.DefaultObjectMetaCompare(leftService.ObjectMeta, rightService.ObjectMeta, field)
 }
 
-func (d *servicesGetter) filter(object runtime.Object, filter query.Filter) bool {
+func (d *servicesGetter) Object() runtimeclient.Object {
+	return &corev1.Service{}
+}
+
+func (d *servicesGetter) Filter(object runtime.Object, filter query.Filter) bool {
 	service, ok := object.(*corev1.Service)
 	if !ok {
 		return false
@@ -12,7 +12,6 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
 
 	"kubesphere.io/kubesphere/pkg/api"
@@ -41,15 +40,15 @@ func (d *statefulSetGetter) Get(namespace, name string) (runtime.Object, error)
 
 func (d *statefulSetGetter) List(namespace string, query *query.Query) (*api.ListResult, error) {
 	statefulSets := &appsv1.StatefulSetList{}
-	if err := d.cache.List(context.Background(), statefulSets, client.InNamespace(namespace),
-		client.MatchingLabelsSelector{Selector: query.Selector()}); err != nil {
+	if err := d.cache.List(context.Background(), statefulSets,
+		v1alpha3.ListOptions(d.cache, &appsv1.StatefulSet{}, namespace, query, v1alpha3.ObjectMetaIndexes...)...); err != nil {
 		return nil, err
 	}
 	var result []runtime.Object
-	for _, item := range statefulSets.Items {
-		result = append(result, item.DeepCopy())
+	for i := range statefulSets.Items {
+		result = append(result, &statefulSets.Items[i])
 	}
-	return v1alpha3.DefaultList(result, query, d.compare, d.filter), nil
+	return v1alpha3.DefaultList(result, query, d.compare, d.Filter), nil
 }
 
 func (d *statefulSetGetter) compare(left runtime.Object, right runtime.Object, field query.Field) bool {
@@ -66,7 +65,22 @@ func (d *statefulSetGetter) compare(left runtime.Object, right runtime.Object, f
 	return v1alpha3.DefaultObjectMetaCompare(leftStatefulSet.ObjectMeta, rightStatefulSet.ObjectMeta, field)
 }
 
-func (d *statefulSetGetter) filter(object runtime.Object, filter query.Filter) bool {
+func (d *statefulSetGetter) FieldValues(object runtime.Object, field query.Field) []string {
+	statefulSet, ok := object.(*appsv1.StatefulSet)
+	if !ok {
+		return nil
+	}
+	if field == query.FieldStatus {
+		return []string{statefulSetStatus(statefulSet)}
+	}
+	return v1alpha3.DefaultObjectMetaFieldValues(&statefulSet.ObjectMeta, field)
+}
+
+func (d *statefulSetGetter) Object() runtimeclient.Object {
+	return &appsv1.StatefulSet{}
+}
+
+func (d *statefulSetGetter) Filter(object runtime.Object, filter query.Filter) bool {
 	statefulSet, ok := object.(*appsv1.StatefulSet)
 	if !ok {
 		return false
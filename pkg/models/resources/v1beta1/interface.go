@@ -14,6 +14,7 @@ import (
 	"sort"
 	"strings"
 
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/labels"
@@ -24,8 +25,11 @@ import (
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"kubesphere.io/kubesphere/pkg/api"
 	"kubesphere.io/kubesphere/pkg/apiserver/query"
 	"kubesphere.io/kubesphere/pkg/constants"
+	"kubesphere.io/kubesphere/pkg/models/resources/v1alpha3"
+	"kubesphere.io/kubesphere/pkg/models/resources/watcher"
 )
 
 type ResourceManager interface {
@@ -38,6 +42,8 @@ type ResourceManager interface {
 	DeleteResource(ctx context.Context, object client.Object) error
 	GetResource(ctx context.Context, gvr schema.GroupVersionResource, namespace string, name string) (client.Object, error)
 	ListResources(ctx context.Context, gvr schema.GroupVersionResource, namespace string, query *query.Query) (client.ObjectList, error)
+	AggregateResources(ctx context.Context, gvr schema.GroupVersionResource, namespace string, query *query.Query, aggregation *query.Aggregation) (*api.AggregationResult, error)
+	WatchResources(ctx context.Context, gvr schema.GroupVersionResource, namespace string, query *query.Query, lastEventID string) (<-chan watcher.Event, error)
 
 	Get(ctx context.Context, namespace, name string, object client.Object) error
 	List(ctx context.Context, namespace string, query *query.Query, object client.ObjectList) error
@@ -54,6 +60,12 @@ type FilterFunc func(runtime.Object, query.Filter) bool
 type TransformFunc func(runtime.Object) runtime.Object
 
 func DefaultList(objects []runtime.Object, q *query.Query, compareFunc CompareFunc, filterFunc FilterFunc, transformFuncs ...TransformFunc) ([]runtime.Object, int, int) {
+	items, remainingItemCount, totalCount, _ := DefaultPage(objects, q, compareFunc, filterFunc, transformFuncs...)
+	return items, remainingItemCount, totalCount
+}
+
+// DefaultPage works as DefaultList does, and also returns the continue token of the next page.
+func DefaultPage(objects []runtime.Object, q *query.Query, compareFunc CompareFunc, filterFunc FilterFunc, transformFuncs ...TransformFunc) ([]runtime.Object, int, int, string) {
 	// selected matched ones
 	var filtered []runtime.Object
 	if len(q.Filters) != 0 {
@@ -76,24 +88,17 @@ func DefaultList(objects []runtime.Object, q *query.Query, compareFunc CompareFu
 	}
 
 	// sort by sortBy field
+	less := q.Order(compareFunc)
 	sort.Slice(filtered, func(i, j int) bool {
-		if !q.Ascending {
-			return compareFunc(filtered[i], filtered[j], q.SortBy)
-		}
-		return !compareFunc(filtered[i], filtered[j], q.SortBy)
+		return less(filtered[i], filtered[j])
 	})
 
 	total := len(filtered)
-
-	if q.Pagination == nil {
-		q.Pagination = query.NoPagination
-	}
-
-	start, end := q.Pagination.GetValidPagination(total)
+	start, end, next := q.Page(filtered, less)
 	remainingItemCount := total - end
 	totalCount := total
 
-	return filtered[start:end], remainingItemCount, totalCount
+	return filtered[start:end], remainingItemCount, totalCount, next
 }
 
 func DefaultObjectMetaCompare(left, right metav1.Object, sortBy query.Field) bool {
@@ -245,31 +250,12 @@ func contains(object runtime.Object, queryValue query.Value) bool {
 		key := requirement.Field
 		value := requirement.Value
 
-		var input interface{}
-		data, err := json.Marshal(object)
+		rawValue, err := fieldValue(object, key)
 		if err != nil {
-			klog.V(4).Infof("failed marshal to JSON string: %s", err)
-			return false
-		}
-		if err = json.Unmarshal(data, &input); err != nil {
-			klog.V(4).Infof("failed unmarshal to map object: %s", err)
+			klog.V(4).Infof("failed lookup field %s: %s", key, err)
 			return false
 		}
 
-		buf := new(bytes.Buffer)
-		lookup := jsonpath.New("")
-		lookup.AllowMissingKeys(true)
-		if err = lookup.Parse(fmt.Sprintf("{.%s}", key)); err != nil {
-			klog.V(4).Infof("failed parse jsonpath: %s", err)
-			return false
-		}
-		if err = lookup.Execute(buf, input); err != nil {
-			klog.V(4).Infof("failed execute jsonpath: %s", err)
-			return false
-		}
-
-		rawValue := buf.String()
-
 		// Values prefixed with ~ support case insensitivity. (e.g., a=~b, can hit b, B)
 		if strings.HasPrefix(value, "~") {
 			value = strings.TrimPrefix(value, "~")
@@ -289,3 +275,44 @@ func contains(object runtime.Object, queryValue query.Value) bool {
 	}
 	return true
 }
+
+// fieldValue returns the value of the field of the object located by the JSON path, e.g. status.phase.
+func fieldValue(object runtime.Object, path string) (string, error) {
+	var input interface{}
+	data, err := json.Marshal(object)
+	if err != nil {
+		return "", err
+	}
+	if err = json.Unmarshal(data, &input); err != nil {
+		return "", err
+	}
+
+	buf := new(bytes.Buffer)
+	lookup := jsonpath.New("")
+	lookup.AllowMissingKeys(true)
+	if err = lookup.Parse(fmt.Sprintf("{.%s}", path)); err != nil {
+		return "", err
+	}
+	if err = lookup.Execute(buf, input); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// DefaultFieldValues returns the values of the metadata field of the object the objects are grouped by,
+// or the value of the field located by the JSON path otherwise, e.g. aggregateBy=status.phase.
+func DefaultFieldValues(object runtime.Object, field query.Field) []string {
+	item, err := meta.Accessor(object)
+	if err != nil {
+		return nil
+	}
+	if values := v1alpha3.DefaultObjectMetaFieldValues(item, field); values != nil {
+		return values
+	}
+	value, err := fieldValue(object, string(field))
+	if err != nil {
+		klog.V(4).Infof("failed lookup field %s: %s", field, err)
+		return nil
+	}
+	return []string{value}
+}
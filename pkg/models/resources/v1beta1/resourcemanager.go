@@ -22,6 +22,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	toolscache "k8s.io/client-go/tools/cache"
@@ -29,7 +30,9 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"kubesphere.io/kubesphere/pkg/api"
 	"kubesphere.io/kubesphere/pkg/apiserver/query"
+	"kubesphere.io/kubesphere/pkg/models/resources/watcher"
 )
 
 const (
@@ -51,8 +54,9 @@ func New(ctx context.Context, runtimeClient client.Client, runtimeCache cache.Ca
 	}
 
 	resourceManager := &resourceManager{
-		client: runtimeClient,
-		ctx:    ctx,
+		client:       runtimeClient,
+		ctx:          ctx,
+		broadcasters: watcher.NewBroadcasters(runtimeCache, runtimeClient.Scheme()),
 	}
 
 	_, err = secretInformer.AddEventHandlerWithResyncPeriod(toolscache.ResourceEventHandlerFuncs{
@@ -90,25 +94,17 @@ type resourceManager struct {
 	client                client.Client
 	ctx                   context.Context
 	customResourceFilters sync.Map
+	broadcasters          *watcher.Broadcasters
 }
 
 func (h *resourceManager) GetResource(ctx context.Context, gvr schema.GroupVersionResource, namespace, name string) (client.Object, error) {
-	var obj client.Object
 	gvk, err := h.getGVK(gvr)
 	if err != nil {
 		return nil, err
 	}
-
-	if h.client.Scheme().Recognizes(gvk) {
-		gvkObject, err := h.client.Scheme().New(gvk)
-		if err != nil {
-			return nil, err
-		}
-		obj = gvkObject.(client.Object)
-	} else {
-		u := &unstructured.Unstructured{}
-		u.SetGroupVersionKind(gvk)
-		obj = u
+	obj, err := h.newObject(gvk)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := h.Get(ctx, namespace, name, obj); err != nil {
@@ -118,22 +114,13 @@ func (h *resourceManager) GetResource(ctx context.Context, gvr schema.GroupVersi
 }
 
 func (h *resourceManager) CreateObjectFromRawData(gvr schema.GroupVersionResource, rawData []byte) (client.Object, error) {
-	var obj client.Object
 	gvk, err := h.getGVK(gvr)
 	if err != nil {
 		return nil, err
 	}
-
-	if h.client.Scheme().Recognizes(gvk) {
-		gvkObject, err := h.client.Scheme().New(gvk)
-		if err != nil {
-			return nil, err
-		}
-		obj = gvkObject.(client.Object)
-	} else {
-		u := &unstructured.Unstructured{}
-		u.SetGroupVersionKind(gvk)
-		obj = u
+	obj, err := h.newObject(gvk)
+	if err != nil {
+		return nil, err
 	}
 
 	err = json.Unmarshal(rawData, obj)
@@ -178,6 +165,63 @@ func (h *resourceManager) ListResources(ctx context.Context, gvr schema.GroupVer
 	return obj, nil
 }
 
+// AggregateResources groups the resources matching the filters of the query, the pagination of the query is ignored.
+func (h *resourceManager) AggregateResources(ctx context.Context, gvr schema.GroupVersionResource, namespace string, q *query.Query, aggregation *query.Aggregation) (*api.AggregationResult, error) {
+	listQuery := *q
+	listQuery.Pagination = query.NoPagination
+	list, err := h.ListResources(ctx, gvr, namespace, &listQuery)
+	if err != nil {
+		return nil, err
+	}
+	items, err := meta.ExtractList(list)
+	if err != nil {
+		return nil, err
+	}
+	return aggregation.Aggregate(items, DefaultFieldValues), nil
+}
+
+// WatchResources watches the resources matching the label selector and the filters of the query, including the
+// custom resource filters, the pagination and the sorting of the query are ignored.
+func (h *resourceManager) WatchResources(ctx context.Context, gvr schema.GroupVersionResource, namespace string, q *query.Query, lastEventID string) (<-chan watcher.Event, error) {
+	gvk, err := h.getGVK(gvr)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := h.newObject(gvk)
+	if err != nil {
+		return nil, err
+	}
+	selector := q.Selector()
+	match := func(object runtime.Object) bool {
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			return false
+		}
+		if namespace != "" && accessor.GetNamespace() != namespace {
+			return false
+		}
+		if !selector.Matches(labels.Set(accessor.GetLabels())) {
+			return false
+		}
+		for field, value := range q.Filters {
+			if !h.CustomResourceFilter(object, query.Filter{Field: field, Value: value}) {
+				return false
+			}
+		}
+		return true
+	}
+	list := func() ([]runtime.Object, error) {
+		listQuery := *q
+		listQuery.Pagination = query.NoPagination
+		list, err := h.ListResources(ctx, gvr, namespace, &listQuery)
+		if err != nil {
+			return nil, err
+		}
+		return meta.ExtractList(list)
+	}
+	return h.broadcasters.Watch(ctx, obj, lastEventID, match, list)
+}
+
 func (h *resourceManager) DeleteResource(ctx context.Context, object client.Object) error {
 	return h.Delete(ctx, object)
 }
@@ -219,6 +263,20 @@ func (h *resourceManager) getGVK(gvr schema.GroupVersionResource) (schema.GroupV
 	return gvk, nil
 }
 
+// newObject returns an empty object of the resource, the unstructured object if the kind isn't registered in the scheme.
+func (h *resourceManager) newObject(gvk schema.GroupVersionKind) (client.Object, error) {
+	if h.client.Scheme().Recognizes(gvk) {
+		gvkObject, err := h.client.Scheme().New(gvk)
+		if err != nil {
+			return nil, err
+		}
+		return gvkObject.(client.Object), nil
+	}
+	u := &unstructured.Unstructured{}
+	u.SetGroupVersionKind(gvk)
+	return u, nil
+}
+
 func (h *resourceManager) IsServed(gvr schema.GroupVersionResource) (bool, error) {
 	// well-known group version is already registered
 	if h.client.Scheme().IsVersionRegistered(gvr.GroupVersion()) {
@@ -245,6 +303,9 @@ func (h *resourceManager) Get(ctx context.Context, namespace, name string, objec
 }
 
 func (h *resourceManager) List(ctx context.Context, namespace string, query *query.Query, list client.ObjectList) error {
+	if err := query.ValidateContinue(); err != nil {
+		return err
+	}
 	listOpt := &client.ListOptions{
 		LabelSelector: query.Selector(),
 		Namespace:     namespace,
@@ -259,10 +320,15 @@ func (h *resourceManager) List(ctx context.Context, namespace string, query *que
 		return err
 	}
 
-	filtered, remainingItemCount, total := DefaultList(extractList, query, DefaultCompare, h.CustomResourceFilter)
+	filtered, remainingItemCount, total, next := DefaultPage(extractList, query, DefaultCompare, h.CustomResourceFilter)
 	remaining := int64(remainingItemCount)
 	list.SetRemainingItemCount(&remaining)
-	list.SetContinue(strconv.Itoa(total))
+	// the continue field carries the total count unless the pages are requested by the continue tokens
+	if query.Pagination != nil && query.Pagination.IsContinuing() {
+		list.SetContinue(next)
+	} else {
+		list.SetContinue(strconv.Itoa(total))
+	}
 	if err := meta.SetList(list, filtered); err != nil {
 		return err
 	}
@@ -0,0 +1,52 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package watcher
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"k8s.io/klog/v2"
+)
+
+const (
+	// MIMEEventStream is the content type of the server-sent events
+	MIMEEventStream = "text/event-stream"
+	// LastEventIDHeader is sent by the clients such as the EventSource when reconnecting, the watch resumes following it
+	LastEventIDHeader = "Last-Event-ID"
+)
+
+// ServeSSE writes the events as the server-sent events until the channel is closed, each event carries its ID,
+// the data of the event is the JSON encoded event as the kubernetes watch event is.
+func ServeSSE(w http.ResponseWriter, events <-chan Event) {
+	w.Header().Set("Content-Type", MIMEEventStream)
+	w.Header().Set("Cache-Control", "no-cache")
+	// disable the buffering of the reverse proxies such as nginx
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flush(w)
+
+	for event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			klog.Warningf("failed to encode the %s event: %s", event.Type, err)
+			continue
+		}
+		if _, err = fmt.Fprintf(w, "id: %s\ndata: %s\n\n", event.ID, data); err != nil {
+			klog.V(4).Infof("failed to write the %s event: %s", event.Type, err)
+			return
+		}
+		flush(w)
+	}
+}
+
+func flush(w http.ResponseWriter) {
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
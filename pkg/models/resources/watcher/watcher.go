@@ -0,0 +1,396 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package watcher
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	runtimecache "sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+const (
+	// InitialEventsAnnotationKey is set on the bookmark sent once the objects existing are sent as the added events
+	InitialEventsAnnotationKey = "k8s.io/initial-events-end"
+
+	// defaultHistorySize is the number of the recent events of the objects of a kind kept for resuming the watches
+	defaultHistorySize = 1000
+	// defaultBufferSize is the number of the events buffered for a watch, the watch is closed once overflowed
+	// and the client is expected to resume it from the last event received
+	defaultBufferSize = 256
+	// defaultBookmarkInterval is the interval of the bookmarks, which keep the connections alive and
+	// carry the latest position of the watch even if no object watched changes
+	defaultBookmarkInterval = 30 * time.Second
+)
+
+// Event is the event of an object watched, the ID of the event resumes the watch following it.
+type Event struct {
+	ID     string          `json:"-"`
+	Type   watch.EventType `json:"type"`
+	Object runtime.Object  `json:"object"`
+}
+
+// MatchFunc returns true if the object is watched.
+type MatchFunc func(object runtime.Object) bool
+
+// ListFunc lists the objects watched existing, they are sent as the added events when the watch starts.
+type ListFunc func() ([]runtime.Object, error)
+
+// Broadcasters broadcast the events of the objects from the informers to the watches,
+// the events of each kind are broadcast by a broadcaster registered on the informer of it.
+type Broadcasters struct {
+	informers    runtimecache.Informers
+	scheme       *runtime.Scheme
+	lock         sync.Mutex
+	broadcasters map[schema.GroupVersionKind]*broadcaster
+
+	historySize      int
+	bufferSize       int
+	bookmarkInterval time.Duration
+}
+
+func NewBroadcasters(informers runtimecache.Informers, scheme *runtime.Scheme) *Broadcasters {
+	return &Broadcasters{
+		informers:        informers,
+		scheme:           scheme,
+		broadcasters:     make(map[schema.GroupVersionKind]*broadcaster),
+		historySize:      defaultHistorySize,
+		bufferSize:       defaultBufferSize,
+		bookmarkInterval: defaultBookmarkInterval,
+	}
+}
+
+// Watch watches the objects of the kind of obj matching the match func until the context is done.
+// The watch resumes following the event of the last event ID if set, or starts with the objects listed
+// by the list func otherwise. The channel returned is closed once the watch is over, including when the
+// client doesn't receive the events in time, the client is expected to resume it by the last event ID.
+func (b *Broadcasters) Watch(ctx context.Context, obj client.Object, lastEventID string, match MatchFunc, list ListFunc) (<-chan Event, error) {
+	gvk, err := apiutil.GVKForObject(obj, b.scheme)
+	if err != nil {
+		return nil, err
+	}
+	broadcaster, err := b.broadcaster(ctx, gvk, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var resumeFrom uint64
+	if lastEventID != "" {
+		if resumeFrom, err = broadcaster.parseID(lastEventID); err != nil {
+			return nil, err
+		}
+	}
+	sub, backlog, err := broadcaster.subscribe(lastEventID != "", resumeFrom, b.bufferSize)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &watcher{
+		broadcaster: broadcaster,
+		sub:         sub,
+		match:       match,
+		result:      make(chan Event),
+		resumed:     lastEventID != "",
+		lastSeq:     sub.start,
+	}
+	var initial []runtime.Object
+	if !w.resumed {
+		if initial, err = list(); err != nil {
+			broadcaster.unsubscribe(sub)
+			return nil, err
+		}
+	}
+	go w.run(ctx, initial, backlog, b.bookmarkInterval)
+	return w.result, nil
+}
+
+func (b *Broadcasters) broadcaster(ctx context.Context, gvk schema.GroupVersionKind, obj client.Object) (*broadcaster, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if broadcaster, ok := b.broadcasters[gvk]; ok {
+		return broadcaster, nil
+	}
+	informer, err := b.informers.GetInformer(ctx, obj)
+	if err != nil {
+		return nil, err
+	}
+	broadcaster := newBroadcaster(gvk, obj, b.historySize)
+	if _, err = informer.AddEventHandler(broadcaster.handler()); err != nil {
+		return nil, err
+	}
+	b.broadcasters[gvk] = broadcaster
+	return broadcaster, nil
+}
+
+// rawEvent is the change of an object received from the informer.
+type rawEvent struct {
+	seq       uint64
+	eventType watch.EventType
+	oldObject runtime.Object
+	object    runtime.Object
+}
+
+type subscriber struct {
+	// start is the sequence of the last event before the subscriber is subscribed
+	start  uint64
+	events chan rawEvent
+}
+
+// broadcaster keeps the recent events of the objects of a kind, and broadcasts the events to the subscribers.
+// The events are numbered in sequence, the sequences are only valid within the epoch of the broadcaster.
+type broadcaster struct {
+	gvk         schema.GroupVersionKind
+	example     client.Object
+	epoch       string
+	historySize int
+
+	lock        sync.Mutex
+	seq         uint64
+	history     []rawEvent
+	subscribers map[*subscriber]struct{}
+}
+
+func newBroadcaster(gvk schema.GroupVersionKind, example client.Object, historySize int) *broadcaster {
+	return &broadcaster{
+		gvk:         gvk,
+		example:     example,
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		historySize: historySize,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+func (b *broadcaster) handler() toolscache.ResourceEventHandler {
+	return toolscache.ResourceEventHandlerDetailedFuncs{
+		AddFunc: func(obj interface{}, isInInitialList bool) {
+			// the objects existing are listed by the watches themselves
+			if !isInInitialList {
+				b.broadcast(watch.Added, nil, obj)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			// the resyncs don't change the objects
+			if oldAccessor, err := meta.Accessor(oldObj); err == nil {
+				if newAccessor, err := meta.Accessor(newObj); err == nil &&
+					oldAccessor.GetResourceVersion() == newAccessor.GetResourceVersion() {
+					return
+				}
+			}
+			b.broadcast(watch.Modified, oldObj, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			b.broadcast(watch.Deleted, nil, obj)
+		},
+	}
+}
+
+// object copies the object from the informer, which is shared and has no type meta set.
+func (b *broadcaster) object(obj interface{}) runtime.Object {
+	object, ok := obj.(runtime.Object)
+	if !ok || object == nil {
+		return nil
+	}
+	object = object.DeepCopyObject()
+	object.GetObjectKind().SetGroupVersionKind(b.gvk)
+	return object
+}
+
+func (b *broadcaster) broadcast(eventType watch.EventType, oldObj, obj interface{}) {
+	event := rawEvent{eventType: eventType, oldObject: b.object(oldObj), object: b.object(obj)}
+	if event.object == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.seq++
+	event.seq = b.seq
+	b.history = append(b.history, event)
+	if len(b.history) >= 2*b.historySize {
+		b.history = append(make([]rawEvent, 0, 2*b.historySize), b.history[len(b.history)-b.historySize:]...)
+	}
+	for sub := range b.subscribers {
+		select {
+		case sub.events <- event:
+		default:
+			// the subscriber is too slow to receive the events, it resumes later from the history
+			delete(b.subscribers, sub)
+			close(sub.events)
+		}
+	}
+}
+
+// subscribe subscribes the events following the event of the sequence if resumed, or the events following
+// the last one otherwise, the events kept in the history following the event are returned as the backlog.
+func (b *broadcaster) subscribe(resume bool, from uint64, bufferSize int) (*subscriber, []rawEvent, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	var backlog []rawEvent
+	if resume {
+		if from > b.seq {
+			return nil, nil, errors.NewBadRequest(fmt.Sprintf("unknown event ID %d", from))
+		}
+		// the events following the event are dropped from the history
+		oldest := b.seq - uint64(len(b.history)) + 1
+		if from+1 < oldest {
+			return nil, nil, errors.NewResourceExpired(fmt.Sprintf("the events following the event ID %s are too old", b.id(from)))
+		}
+		backlog = append(backlog, b.history[len(b.history)-int(b.seq-from):]...)
+	}
+	sub := &subscriber{start: b.seq, events: make(chan rawEvent, bufferSize)}
+	b.subscribers[sub] = struct{}{}
+	return sub, backlog, nil
+}
+
+func (b *broadcaster) unsubscribe(sub *subscriber) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+func (b *broadcaster) id(seq uint64) string {
+	return fmt.Sprintf("%s.%d", b.epoch, seq)
+}
+
+// parseID returns the sequence of the event ID, the event IDs issued by another broadcaster, such as the
+// one of another replica or before the server restarts, can't be resumed.
+func (b *broadcaster) parseID(id string) (uint64, error) {
+	epoch, seq, found := strings.Cut(id, ".")
+	if !found {
+		return 0, errors.NewBadRequest(fmt.Sprintf("invalid event ID %s", id))
+	}
+	if epoch != b.epoch {
+		return 0, errors.NewResourceExpired(fmt.Sprintf("the event ID %s is expired", id))
+	}
+	value, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil {
+		return 0, errors.NewBadRequest(fmt.Sprintf("invalid event ID %s", id))
+	}
+	return value, nil
+}
+
+// watcher translates the events broadcast into the events of the objects matching the watch.
+type watcher struct {
+	broadcaster *broadcaster
+	sub         *subscriber
+	match       MatchFunc
+	result      chan Event
+	resumed     bool
+	// lastSeq is the sequence of the last event handled, the watch resumes following it
+	lastSeq uint64
+	// lastResourceVersion is the resource version of the object of the last event handled
+	lastResourceVersion string
+}
+
+func (w *watcher) run(ctx context.Context, initial []runtime.Object, backlog []rawEvent, bookmarkInterval time.Duration) {
+	defer close(w.result)
+	defer w.broadcaster.unsubscribe(w.sub)
+
+	if !w.resumed {
+		for _, object := range initial {
+			if !w.send(ctx, Event{ID: w.broadcaster.id(w.lastSeq), Type: watch.Added, Object: object}) {
+				return
+			}
+		}
+		if !w.send(ctx, w.bookmark(true)) {
+			return
+		}
+	}
+	for _, event := range backlog {
+		if !w.handle(ctx, event) {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(bookmarkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.sub.events:
+			if !ok {
+				klog.V(4).Infof("watch of %s is closed as the events are not received in time", w.broadcaster.gvk)
+				return
+			}
+			if !w.handle(ctx, event) {
+				return
+			}
+		case <-ticker.C:
+			if !w.send(ctx, w.bookmark(false)) {
+				return
+			}
+		}
+	}
+}
+
+// handle sends the event if the object matches the watch, the object is regarded as deleted
+// once it no longer matches, and as added once it starts to match.
+func (w *watcher) handle(ctx context.Context, event rawEvent) bool {
+	w.lastSeq = event.seq
+	if accessor, err := meta.Accessor(event.object); err == nil {
+		w.lastResourceVersion = accessor.GetResourceVersion()
+	}
+	eventType := event.eventType
+	matched := w.match(event.object)
+	switch event.eventType {
+	case watch.Modified:
+		if event.oldObject != nil && !w.match(event.oldObject) {
+			eventType = watch.Added
+		} else if !matched {
+			eventType, matched = watch.Deleted, true
+		}
+	case watch.Added, watch.Deleted:
+	default:
+		return true
+	}
+	if !matched {
+		return true
+	}
+	return w.send(ctx, Event{ID: w.broadcaster.id(event.seq), Type: eventType, Object: event.object})
+}
+
+// bookmark returns the bookmark carrying the position of the watch, as the kubernetes watch bookmarks do,
+// the object of it only has the type and the resource version set.
+func (w *watcher) bookmark(initialEventsEnd bool) Event {
+	object := w.broadcaster.example.DeepCopyObject().(client.Object)
+	object.GetObjectKind().SetGroupVersionKind(w.broadcaster.gvk)
+	object.SetResourceVersion(w.lastResourceVersion)
+	if initialEventsEnd {
+		object.SetAnnotations(map[string]string{InitialEventsAnnotationKey: "true"})
+	}
+	return Event{ID: w.broadcaster.id(w.lastSeq), Type: watch.Bookmark, Object: object}
+}
+
+func (w *watcher) send(ctx context.Context, event Event) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case w.result <- event:
+		return true
+	}
+}
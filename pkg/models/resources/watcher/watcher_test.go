@@ -0,0 +1,197 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package watcher
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+)
+
+func newPod(name, resourceVersion, app string) *corev1.Pod {
+	return &corev1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Name:            name,
+		Namespace:       "default",
+		ResourceVersion: resourceVersion,
+		Labels:          map[string]string{"app": app},
+	}}
+}
+
+func matchApp(object runtime.Object) bool {
+	return object.(*corev1.Pod).Labels["app"] == "foo"
+}
+
+func newBroadcasters(t *testing.T) (*Broadcasters, *controllertest.FakeInformer) {
+	informers := &informertest.FakeInformers{Scheme: scheme.Scheme}
+	informer, err := informers.FakeInformerFor(context.Background(), &corev1.Pod{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewBroadcasters(informers, scheme.Scheme), informer
+}
+
+type received struct {
+	Type watch.EventType
+	Name string
+}
+
+func receive(t *testing.T, events <-chan Event, count int) ([]received, string) {
+	var result []received
+	var lastEventID string
+	for i := 0; i < count; i++ {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatalf("watch is closed after %d events", i)
+			}
+			result = append(result, received{Type: event.Type, Name: event.Object.(*corev1.Pod).Name})
+			lastEventID = event.ID
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the event %d", i)
+		}
+	}
+	return result, lastEventID
+}
+
+func TestWatch(t *testing.T) {
+	broadcasters, informer := newBroadcasters(t)
+	list := func() ([]runtime.Object, error) {
+		return []runtime.Object{newPod("existing", "1", "foo")}, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := broadcasters.Watch(ctx, &corev1.Pod{}, "", matchApp, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	initial, _ := receive(t, events, 2)
+	if diff := cmp.Diff([]received{{Type: watch.Added, Name: "existing"}, {Type: watch.Bookmark}}, initial); diff != "" {
+		t.Fatal(diff)
+	}
+
+	informer.Add(newPod("pod-1", "2", "foo"))
+	informer.Add(newPod("pod-2", "3", "bar"))
+	// the resync doesn't change the pod
+	informer.Update(newPod("pod-1", "2", "foo"), newPod("pod-1", "2", "foo"))
+	informer.Update(newPod("pod-1", "2", "foo"), newPod("pod-1", "4", "bar"))
+	informer.Update(newPod("pod-2", "3", "bar"), newPod("pod-2", "5", "foo"))
+	informer.Update(newPod("pod-2", "5", "foo"), newPod("pod-2", "6", "foo"))
+	informer.Delete(newPod("pod-1", "4", "bar"))
+	informer.Delete(newPod("pod-2", "6", "foo"))
+
+	got, lastEventID := receive(t, events, 4)
+	expected := []received{
+		{Type: watch.Added, Name: "pod-1"},
+		// the pods are deleted from the watch once they no longer match, and added once they start to match
+		{Type: watch.Deleted, Name: "pod-1"},
+		{Type: watch.Added, Name: "pod-2"},
+		{Type: watch.Modified, Name: "pod-2"},
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Fatal(diff)
+	}
+	cancel()
+
+	// the watch resumes following the last event received
+	informer.Add(newPod("pod-3", "7", "foo"))
+	events, err = broadcasters.Watch(context.Background(), &corev1.Pod{}, lastEventID, matchApp, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ = receive(t, events, 2)
+	expected = []received{
+		{Type: watch.Deleted, Name: "pod-2"},
+		{Type: watch.Added, Name: "pod-3"},
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Fatal(diff)
+	}
+}
+
+func TestWatchExpired(t *testing.T) {
+	broadcasters, informer := newBroadcasters(t)
+	broadcasters.historySize = 2
+	list := func() ([]runtime.Object, error) {
+		return nil, nil
+	}
+	events, err := broadcasters.Watch(context.Background(), &corev1.Pod{}, "", matchApp, list)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, lastEventID := receive(t, events, 1)
+	for _, name := range []string{"pod-1", "pod-2", "pod-3", "pod-4"} {
+		informer.Add(newPod(name, "1", "foo"))
+	}
+
+	for _, id := range []string{lastEventID, "another.1"} {
+		if _, err = broadcasters.Watch(context.Background(), &corev1.Pod{}, id, matchApp, list); !errors.IsResourceExpired(err) {
+			t.Errorf("expected the event ID %s to be expired, got %v", id, err)
+		}
+	}
+	if _, err = broadcasters.Watch(context.Background(), &corev1.Pod{}, "invalid", matchApp, list); !errors.IsBadRequest(err) {
+		t.Errorf("expected the event ID to be invalid, got %v", err)
+	}
+}
+
+func TestWatchOverflow(t *testing.T) {
+	broadcasters, informer := newBroadcasters(t)
+	broadcasters.bufferSize = 1
+	events, err := broadcasters.Watch(context.Background(), &corev1.Pod{}, "", matchApp, func() ([]runtime.Object, error) {
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, lastEventID := receive(t, events, 1)
+	for _, name := range []string{"pod-1", "pod-2", "pod-3", "pod-4"} {
+		informer.Add(newPod(name, "1", "foo"))
+	}
+	// the watch is closed once the events overflow, and resumes from the last event received
+	var received int
+	for range events {
+		received++
+	}
+	if received >= 4 {
+		t.Fatalf("expected the watch to be closed before receiving all the events, got %d", received)
+	}
+	events, err = broadcasters.Watch(context.Background(), &corev1.Pod{}, lastEventID, matchApp, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := receive(t, events, 4)
+	if got[3].Name != "pod-4" {
+		t.Errorf("expected the events to be resumed, got %v", got)
+	}
+}
+
+func TestServeSSE(t *testing.T) {
+	events := make(chan Event, 1)
+	events <- Event{ID: "epoch.1", Type: watch.Added, Object: newPod("pod-1", "1", "foo")}
+	close(events)
+	recorder := httptest.NewRecorder()
+	ServeSSE(recorder, events)
+
+	if contentType := recorder.Header().Get("Content-Type"); contentType != MIMEEventStream {
+		t.Errorf("unexpected content type %s", contentType)
+	}
+	body := recorder.Body.String()
+	if !strings.HasPrefix(body, "id: epoch.1\ndata: {\"type\":\"ADDED\",\"object\":{") || !strings.HasSuffix(body, "}\n\n") {
+		t.Errorf("unexpected event %q", body)
+	}
+}
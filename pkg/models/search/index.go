@@ -0,0 +1,368 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package search
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/google/btree"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/sets"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+
+	"kubesphere.io/kubesphere/pkg/api"
+	"kubesphere.io/kubesphere/pkg/constants"
+)
+
+// field is the set of the fields of an object a token occurs in.
+type field uint8
+
+const (
+	fieldName field = 1 << iota
+	fieldAlias
+	fieldLabels
+	fieldImages
+	fieldAnnotations
+)
+
+var fields = []struct {
+	field  field
+	name   string
+	weight int
+}{
+	{fieldName, "name", 16},
+	{fieldAlias, "alias", 12},
+	{fieldLabels, "labels", 6},
+	{fieldImages, "images", 4},
+	{fieldAnnotations, "annotations", 2},
+}
+
+// the annotations longer than it are usually the serialized objects, which are not worth indexing
+const maxAnnotationLength = 256
+
+var ignoredAnnotations = sets.New(corev1.LastAppliedConfigAnnotation, constants.DisplayNameAnnotationKey)
+
+// Resource is the kind of the objects indexed.
+type Resource struct {
+	schema.GroupVersionKind
+	// Resource is the plural name of the kind
+	Resource string
+}
+
+type document struct {
+	resource  Resource
+	namespace string
+	name      string
+	alias     string
+	// workspace is only set for the namespaces and the workspaces, the workspaces of the other objects are
+	// resolved by their namespaces when searching since the namespaces may be moved to another workspace
+	workspace string
+	tokens    map[string]field
+}
+
+// Options is the search terms and the scope of the objects searched.
+type Options struct {
+	// Terms are matched with the tokens of the objects exactly or by prefix, an object must match all the terms
+	Terms []string
+	// Resources limits the objects searched to the resources, all the resources are searched if empty
+	Resources sets.Set[string]
+	Workspace string
+	Namespace string
+}
+
+// Index is an inverted index of the objects from the tokens of their names, aliases, labels, annotations and images.
+type Index struct {
+	lock      sync.RWMutex
+	documents map[string]*document
+	// postings maps the tokens to the keys of the documents containing them
+	postings map[string]map[string]field
+	// tokens are the tokens of the postings in ascending order, the tokens prefixed with a term are adjacent
+	tokens *btree.BTreeG[string]
+	// workspaces maps the namespaces to their workspaces
+	workspaces map[string]string
+}
+
+func NewIndex() *Index {
+	return &Index{
+		documents:  make(map[string]*document),
+		postings:   make(map[string]map[string]field),
+		tokens:     btree.NewOrderedG[string](32),
+		workspaces: make(map[string]string),
+	}
+}
+
+func documentKey(resource Resource, namespace, name string) string {
+	return fmt.Sprintf("%s/%s/%s/%s", resource.Group, resource.Resource, namespace, name)
+}
+
+// Add indexes the object, the previous version of the object is replaced.
+func (i *Index) Add(resource Resource, obj metav1.Object) {
+	doc := &document{
+		resource:  resource,
+		namespace: obj.GetNamespace(),
+		name:      obj.GetName(),
+		alias:     obj.GetAnnotations()[constants.DisplayNameAnnotationKey],
+		tokens:    make(map[string]field),
+	}
+	doc.add(fieldName, doc.name)
+	doc.add(fieldAlias, doc.alias)
+	for key, value := range obj.GetLabels() {
+		doc.add(fieldLabels, key)
+		doc.add(fieldLabels, value)
+		doc.add(fieldLabels, key+"="+value)
+	}
+	for key, value := range obj.GetAnnotations() {
+		if !ignoredAnnotations.Has(key) && len(value) <= maxAnnotationLength {
+			doc.add(fieldAnnotations, value)
+		}
+	}
+	for _, image := range images(obj) {
+		doc.add(fieldImages, image)
+	}
+
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	key := documentKey(resource, doc.namespace, doc.name)
+	i.delete(key)
+	switch {
+	case resource.Group == "" && resource.Resource == "namespaces":
+		doc.workspace = obj.GetLabels()[tenantv1beta1.WorkspaceLabel]
+		i.workspaces[doc.name] = doc.workspace
+	case isWorkspace(resource):
+		doc.workspace = doc.name
+	}
+	i.documents[key] = doc
+	for token, set := range doc.tokens {
+		postings, ok := i.postings[token]
+		if !ok {
+			postings = make(map[string]field)
+			i.postings[token] = postings
+			i.tokens.ReplaceOrInsert(token)
+		}
+		postings[key] = set
+	}
+}
+
+// Delete removes the object from the index.
+func (i *Index) Delete(resource Resource, obj metav1.Object) {
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	i.delete(documentKey(resource, obj.GetNamespace(), obj.GetName()))
+	if resource.Group == "" && resource.Resource == "namespaces" {
+		delete(i.workspaces, obj.GetName())
+	}
+}
+
+func (i *Index) delete(key string) {
+	doc, ok := i.documents[key]
+	if !ok {
+		return
+	}
+	for token := range doc.tokens {
+		delete(i.postings[token], key)
+		if len(i.postings[token]) == 0 {
+			delete(i.postings, token)
+			i.tokens.Delete(token)
+		}
+	}
+	delete(i.documents, key)
+}
+
+// Search returns the objects matching all the terms, ranked by the fields and how exactly the terms are matched.
+func (i *Index) Search(options Options) []api.SearchHit {
+	terms := sets.New[string]()
+	for _, term := range options.Terms {
+		if term = strings.ToLower(strings.TrimSpace(term)); term != "" {
+			terms.Insert(term)
+		}
+	}
+	if terms.Len() == 0 {
+		return nil
+	}
+
+	i.lock.RLock()
+	defer i.lock.RUnlock()
+	var scores map[string]int
+	matches := make(map[string]field)
+	for _, term := range sets.List(terms) {
+		termScores := make(map[string]int)
+		i.tokens.AscendGreaterOrEqual(term, func(token string) bool {
+			if !strings.HasPrefix(token, term) {
+				return false
+			}
+			exact := token == term
+			for key, set := range i.postings[token] {
+				if score := i.score(key, term, set, exact); score > termScores[key] {
+					termScores[key] = score
+				}
+				matches[key] |= set
+			}
+			return true
+		})
+		// the objects must match all the terms
+		if scores != nil {
+			for key, score := range termScores {
+				if total, ok := scores[key]; ok {
+					termScores[key] = total + score
+				} else {
+					delete(termScores, key)
+				}
+			}
+		}
+		scores = termScores
+	}
+
+	hits := make([]api.SearchHit, 0, len(scores))
+	for key, score := range scores {
+		doc := i.documents[key]
+		if options.Resources.Len() > 0 && !options.Resources.Has(doc.resource.Resource) {
+			continue
+		}
+		workspace := doc.workspace
+		if doc.namespace != "" {
+			workspace = i.workspaces[doc.namespace]
+		}
+		if (options.Workspace != "" && workspace != options.Workspace) ||
+			(options.Namespace != "" && doc.namespace != options.Namespace) {
+			continue
+		}
+		hit := api.SearchHit{
+			APIVersion: doc.resource.GroupVersion().String(),
+			Kind:       doc.resource.Kind,
+			Resource:   doc.resource.Resource,
+			Workspace:  workspace,
+			Namespace:  doc.namespace,
+			Name:       doc.name,
+			Alias:      doc.alias,
+			Score:      score,
+			Matches:    []string{},
+		}
+		for _, f := range fields {
+			if matches[key]&f.field != 0 {
+				hit.Matches = append(hit.Matches, f.name)
+			}
+		}
+		hits = append(hits, hit)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		if hits[i].Resource != hits[j].Resource {
+			return hits[i].Resource < hits[j].Resource
+		}
+		if hits[i].Namespace != hits[j].Namespace {
+			return hits[i].Namespace < hits[j].Namespace
+		}
+		return hits[i].Name < hits[j].Name
+	})
+	return hits
+}
+
+// score is the score of the term matching a token of the fields, the prefix matches score half as the exact ones,
+// and the objects named exactly the term are ranked first.
+func (i *Index) score(key, term string, set field, exact bool) int {
+	var score int
+	for _, f := range fields {
+		if set&f.field == 0 {
+			continue
+		}
+		weight := f.weight
+		if !exact {
+			weight /= 2
+		}
+		score += weight
+	}
+	if exact && set&fieldName != 0 && strings.ToLower(i.documents[key].name) == term {
+		score += fields[0].weight
+	}
+	return score
+}
+
+// isWorkspace returns whether the objects of the resource are the workspaces, which are the workspaces of themselves.
+func isWorkspace(resource Resource) bool {
+	return resource.Group == tenantv1beta1.GroupName &&
+		(resource.Resource == tenantv1beta1.ResourcePluralWorkspace || resource.Resource == tenantv1beta1.ResourcePluralWorkspaceTemplate)
+}
+
+func (d *document) add(f field, value string) {
+	for _, token := range tokenize(value) {
+		d.tokens[token] |= f
+	}
+}
+
+// tokenize splits the value into the lowercase words, the whole value is a token as well.
+func tokenize(value string) []string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == "" {
+		return nil
+	}
+	tokens := []string{value}
+	words := strings.FieldsFunc(value, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if word != value {
+			tokens = append(tokens, word)
+		}
+	}
+	return tokens
+}
+
+// indexedFieldsChanged returns whether the fields indexed differ between the versions of the object,
+// most of the updates only change the status, which are not worth reindexing.
+func indexedFieldsChanged(oldObj, newObj metav1.Object) bool {
+	return oldObj.GetName() != newObj.GetName() ||
+		!maps.Equal(oldObj.GetLabels(), newObj.GetLabels()) ||
+		!maps.Equal(oldObj.GetAnnotations(), newObj.GetAnnotations()) ||
+		!slices.Equal(images(oldObj), images(newObj))
+}
+
+// images returns the images of the containers of the workloads.
+func images(obj metav1.Object) []string {
+	spec := podSpec(obj)
+	if spec == nil {
+		return nil
+	}
+	images := make([]string, 0, len(spec.InitContainers)+len(spec.Containers))
+	for _, container := range spec.InitContainers {
+		images = append(images, container.Image)
+	}
+	for _, container := range spec.Containers {
+		images = append(images, container.Image)
+	}
+	return images
+}
+
+func podSpec(obj metav1.Object) *corev1.PodSpec {
+	switch object := obj.(type) {
+	case *corev1.Pod:
+		return &object.Spec
+	case *appsv1.Deployment:
+		return &object.Spec.Template.Spec
+	case *appsv1.StatefulSet:
+		return &object.Spec.Template.Spec
+	case *appsv1.DaemonSet:
+		return &object.Spec.Template.Spec
+	case *batchv1.Job:
+		return &object.Spec.Template.Spec
+	case *batchv1.CronJob:
+		return &object.Spec.JobTemplate.Spec.Template.Spec
+	default:
+		return nil
+	}
+}
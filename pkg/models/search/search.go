@@ -0,0 +1,180 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package search
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apiserver/pkg/authentication/user"
+	toolscache "k8s.io/client-go/tools/cache"
+	"k8s.io/klog/v2"
+	clusterv1alpha1 "kubesphere.io/api/cluster/v1alpha1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+	runtimecache "sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"kubesphere.io/kubesphere/pkg/api"
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+	"kubesphere.io/kubesphere/pkg/apiserver/query"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+	"kubesphere.io/kubesphere/pkg/scheme"
+)
+
+// indexedResources are the resources searched, the resources not installed in the cluster are skipped.
+var indexedResources = []struct {
+	object   client.Object
+	resource string
+}{
+	{&corev1.Namespace{}, "namespaces"},
+	{&corev1.Pod{}, "pods"},
+	{&corev1.Service{}, "services"},
+	{&corev1.ConfigMap{}, "configmaps"},
+	{&corev1.Secret{}, "secrets"},
+	{&corev1.PersistentVolumeClaim{}, "persistentvolumeclaims"},
+	{&appsv1.Deployment{}, "deployments"},
+	{&appsv1.StatefulSet{}, "statefulsets"},
+	{&appsv1.DaemonSet{}, "daemonsets"},
+	{&batchv1.Job{}, "jobs"},
+	{&batchv1.CronJob{}, "cronjobs"},
+	{&networkingv1.Ingress{}, "ingresses"},
+	{&tenantv1beta1.Workspace{}, tenantv1beta1.ResourcePluralWorkspace},
+	// the global resources only exist in the host cluster
+	{&tenantv1beta1.WorkspaceTemplate{}, tenantv1beta1.ResourcePluralWorkspaceTemplate},
+	{&iamv1beta1.User{}, iamv1beta1.ResourcesPluralUser},
+	{&clusterv1alpha1.Cluster{}, clusterv1alpha1.ResourcesPluralCluster},
+}
+
+type Interface interface {
+	// Search returns the objects matching the options the user is authorized to list, ranked by the relevance.
+	Search(user user.Info, options Options, pagination *query.Pagination) (*api.SearchResult, error)
+}
+
+type searcher struct {
+	index      *Index
+	authorizer authorizer.Authorizer
+}
+
+// NewSearcher indexes the objects of the indexed resources from the informers, the objects are
+// indexed as soon as the informers are started.
+func NewSearcher(informers runtimecache.Informers, authorizer authorizer.Authorizer) Interface {
+	index := NewIndex()
+	for _, indexed := range indexedResources {
+		gvk, err := apiutil.GVKForObject(indexed.object, scheme.Scheme)
+		if err != nil {
+			klog.Warningf("failed to index %s: %s", indexed.resource, err)
+			continue
+		}
+		informer, err := informers.GetInformer(context.Background(), indexed.object, runtimecache.BlockUntilSynced(false))
+		if err != nil {
+			klog.Warningf("failed to index %s: %s", indexed.resource, err)
+			continue
+		}
+		if _, err = informer.AddEventHandler(index.handler(Resource{GroupVersionKind: gvk, Resource: indexed.resource})); err != nil {
+			klog.Warningf("failed to index %s: %s", indexed.resource, err)
+		}
+	}
+	return &searcher{index: index, authorizer: authorizer}
+}
+
+func (i *Index) handler(resource Resource) toolscache.ResourceEventHandler {
+	return toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if object, ok := obj.(metav1.Object); ok {
+				i.Add(resource, object)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			object, ok := newObj.(metav1.Object)
+			if !ok {
+				return
+			}
+			if old, ok := oldObj.(metav1.Object); ok && !indexedFieldsChanged(old, object) {
+				return
+			}
+			i.Add(resource, object)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if object, ok := obj.(metav1.Object); ok {
+				i.Delete(resource, object)
+			}
+		},
+	}
+}
+
+func (s *searcher) Search(user user.Info, options Options, pagination *query.Pagination) (*api.SearchResult, error) {
+	// the decisions are shared by the objects of the same resource in the same scope
+	decisions := make(map[string]bool)
+	result := &api.SearchResult{Items: []api.SearchHit{}}
+	var hits []api.SearchHit
+	for _, hit := range s.index.Search(options) {
+		attributes := listAttributes(user, hit)
+		key := fmt.Sprintf("%s/%s/%s/%s/%s/%s", attributes.Verb, attributes.APIGroup, attributes.Resource,
+			attributes.ResourceScope, attributes.Workspace, attributes.Namespace)
+		allowed, ok := decisions[key]
+		if !ok {
+			decision, _, err := s.authorizer.Authorize(attributes)
+			if err != nil {
+				klog.Error(err)
+				return nil, err
+			}
+			allowed = decision == authorizer.DecisionAllow
+			decisions[key] = allowed
+		}
+		if allowed {
+			hits = append(hits, hit)
+		}
+	}
+	result.TotalItems = len(hits)
+	start, end := pagination.GetValidPagination(len(hits))
+	result.Items = append(result.Items, hits[start:end]...)
+	return result, nil
+}
+
+// listAttributes returns the attributes authorizing the user to find the object, which is the permission
+// to list the objects of the resource in the scope of the object, or to get the namespace or workspace itself.
+func listAttributes(user user.Info, hit api.SearchHit) authorizer.AttributesRecord {
+	gvk := schema.FromAPIVersionAndKind(hit.APIVersion, hit.Kind)
+	attributes := authorizer.AttributesRecord{
+		User:              user,
+		Verb:              "list",
+		APIGroup:          gvk.Group,
+		APIVersion:        gvk.Version,
+		Resource:          hit.Resource,
+		KubernetesRequest: true,
+		ResourceRequest:   true,
+		ResourceScope:     request.ClusterScope,
+	}
+	switch {
+	case hit.Namespace != "":
+		attributes.ResourceScope = request.NamespaceScope
+		attributes.Namespace = hit.Namespace
+	case gvk.Group == "" && hit.Resource == "namespaces":
+		attributes.Verb = "get"
+		attributes.ResourceScope = request.NamespaceScope
+		attributes.Namespace = hit.Name
+	case isWorkspace(Resource{GroupVersionKind: gvk, Resource: hit.Resource}):
+		attributes.Verb = "get"
+		attributes.ResourceScope = request.WorkspaceScope
+		attributes.Workspace = hit.Name
+	case gvk.Group == iamv1beta1.GroupName && hit.Resource == iamv1beta1.ResourcesPluralUser,
+		gvk.Group == clusterv1alpha1.GroupName && hit.Resource == clusterv1alpha1.ResourcesPluralCluster:
+		attributes.ResourceScope = request.GlobalScope
+	}
+	return attributes
+}
@@ -0,0 +1,273 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package search
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/apiserver/pkg/authentication/user"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+	tenantv1beta1 "kubesphere.io/api/tenant/v1beta1"
+
+	"kubesphere.io/kubesphere/pkg/apiserver/authorization/authorizer"
+	"kubesphere.io/kubesphere/pkg/apiserver/query"
+	"kubesphere.io/kubesphere/pkg/apiserver/request"
+	"kubesphere.io/kubesphere/pkg/constants"
+)
+
+var (
+	namespaces  = Resource{GroupVersionKind: corev1.SchemeGroupVersion.WithKind("Namespace"), Resource: "namespaces"}
+	pods        = Resource{GroupVersionKind: corev1.SchemeGroupVersion.WithKind("Pod"), Resource: "pods"}
+	deployments = Resource{GroupVersionKind: appsv1.SchemeGroupVersion.WithKind("Deployment"), Resource: "deployments"}
+	workspaces  = Resource{GroupVersionKind: tenantv1beta1.SchemeGroupVersion.WithKind("Workspace"), Resource: "workspaces"}
+	users       = Resource{GroupVersionKind: iamv1beta1.SchemeGroupVersion.WithKind("User"), Resource: "users"}
+	templates   = Resource{GroupVersionKind: tenantv1beta1.SchemeGroupVersion.WithKind("WorkspaceTemplate"), Resource: "workspacetemplates"}
+)
+
+func newIndex() *Index {
+	index := NewIndex()
+	index.Add(workspaces, &tenantv1beta1.Workspace{ObjectMeta: metav1.ObjectMeta{Name: "payment"}})
+	index.Add(templates, &tenantv1beta1.WorkspaceTemplate{ObjectMeta: metav1.ObjectMeta{Name: "payment"}})
+	index.Add(users, &iamv1beta1.User{ObjectMeta: metav1.ObjectMeta{Name: "payer"}})
+	index.Add(namespaces, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "payment-prod",
+		Labels: map[string]string{tenantv1beta1.WorkspaceLabel: "payment"},
+	}})
+	index.Add(namespaces, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+		Name:   "shop",
+		Labels: map[string]string{tenantv1beta1.WorkspaceLabel: "retail"},
+	}})
+	index.Add(deployments, &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "checkout",
+			Namespace:   "shop",
+			Labels:      map[string]string{"app": "payment"},
+			Annotations: map[string]string{constants.DisplayNameAnnotationKey: "Checkout Service"},
+		},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Image: "registry.example.com/shop/checkout:v1"}},
+		}}},
+	})
+	index.Add(pods, &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "gateway-7d9f",
+			Namespace:   "payment-prod",
+			Annotations: map[string]string{corev1.LastAppliedConfigAnnotation: `{"metadata":{"name":"payments"}}`},
+		},
+		Spec: corev1.PodSpec{Containers: []corev1.Container{{Image: "registry.example.com/payments/gateway:v2"}}},
+	})
+	return index
+}
+
+type hit struct {
+	Resource  string
+	Workspace string
+	Namespace string
+	Name      string
+	Matches   []string
+}
+
+func TestSearch(t *testing.T) {
+	index := newIndex()
+	tests := []struct {
+		name     string
+		options  Options
+		expected []hit
+	}{
+		{
+			name:    "ranked by the fields matched",
+			options: Options{Terms: []string{"payment"}},
+			expected: []hit{
+				{Resource: "workspaces", Workspace: "payment", Name: "payment", Matches: []string{"name"}},
+				{Resource: "workspacetemplates", Workspace: "payment", Name: "payment", Matches: []string{"name"}},
+				{Resource: "namespaces", Workspace: "payment", Name: "payment-prod", Matches: []string{"name", "labels"}},
+				{Resource: "deployments", Workspace: "retail", Namespace: "shop", Name: "checkout", Matches: []string{"labels"}},
+				// the prefix of the words of the image
+				{Resource: "pods", Workspace: "payment", Namespace: "payment-prod", Name: "gateway-7d9f", Matches: []string{"images"}},
+			},
+		},
+		{
+			name:    "matched by prefix",
+			options: Options{Terms: []string{"PAY"}, Resources: sets.New("users", "namespaces")},
+			expected: []hit{
+				{Resource: "namespaces", Workspace: "payment", Name: "payment-prod", Matches: []string{"name", "labels"}},
+				{Resource: "users", Name: "payer", Matches: []string{"name"}},
+			},
+		},
+		{
+			name:    "all the terms matched",
+			options: Options{Terms: []string{"Checkout", "service"}},
+			expected: []hit{
+				{Resource: "deployments", Workspace: "retail", Namespace: "shop", Name: "checkout", Matches: []string{"name", "alias", "images"}},
+			},
+		},
+		{
+			name:    "filtered by the resources and the workspace",
+			options: Options{Terms: []string{"payment"}, Resources: sets.New("pods", "deployments"), Workspace: "payment"},
+			expected: []hit{
+				{Resource: "pods", Workspace: "payment", Namespace: "payment-prod", Name: "gateway-7d9f", Matches: []string{"images"}},
+			},
+		},
+		{
+			name:     "nothing matched",
+			options:  Options{Terms: []string{"payment", "nothing"}},
+			expected: []hit{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := []hit{}
+			for _, result := range index.Search(test.options) {
+				got = append(got, hit{Resource: result.Resource, Workspace: result.Workspace, Namespace: result.Namespace, Name: result.Name, Matches: result.Matches})
+			}
+			if diff := cmp.Diff(test.expected, got); diff != "" {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+func TestDelete(t *testing.T) {
+	index := newIndex()
+	index.Add(namespaces, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shop"}})
+	index.Delete(deployments, &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "checkout", Namespace: "shop"}})
+	if hits := index.Search(Options{Terms: []string{"checkout"}}); len(hits) != 0 {
+		t.Errorf("expected the deleted object not to be found, got %v", hits)
+	}
+	// the namespace is moved out of the workspace
+	if hits := index.Search(Options{Terms: []string{"shop"}, Workspace: "retail"}); len(hits) != 0 {
+		t.Errorf("expected the namespace not to be in the workspace, got %v", hits)
+	}
+	key := documentKey(deployments, "shop", "checkout")
+	for token, postings := range index.postings {
+		if _, ok := postings[key]; ok {
+			t.Errorf("expected the token %s of the deleted object to be removed", token)
+		}
+	}
+	if _, ok := index.postings["checkout"]; ok {
+		t.Errorf("expected the tokens no longer indexed to be removed")
+	}
+	if index.tokens.Len() != len(index.postings) {
+		t.Errorf("expected the tokens to match the postings, got %d tokens and %d postings", index.tokens.Len(), len(index.postings))
+	}
+	index.tokens.Ascend(func(token string) bool {
+		if _, ok := index.postings[token]; !ok {
+			t.Errorf("expected the token %s removed from the sorted tokens", token)
+		}
+		return true
+	})
+}
+
+func TestIndexedFieldsChanged(t *testing.T) {
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "checkout",
+			Namespace:   "shop",
+			Labels:      map[string]string{"app": "checkout"},
+			Annotations: map[string]string{constants.DisplayNameAnnotationKey: "Checkout Service"},
+		},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Image: "registry.example.com/shop/checkout:v1"}},
+		}}},
+	}
+	tests := []struct {
+		name     string
+		update   func(deployment *appsv1.Deployment)
+		expected bool
+	}{
+		{
+			name: "status updated",
+			update: func(deployment *appsv1.Deployment) {
+				deployment.ResourceVersion = "2"
+				deployment.Status.ReadyReplicas = 1
+			},
+			expected: false,
+		},
+		{
+			name: "alias changed",
+			update: func(deployment *appsv1.Deployment) {
+				deployment.Annotations[constants.DisplayNameAnnotationKey] = "Checkout"
+			},
+			expected: true,
+		},
+		{
+			name: "label added",
+			update: func(deployment *appsv1.Deployment) {
+				deployment.Labels["tier"] = "frontend"
+			},
+			expected: true,
+		},
+		{
+			name: "image changed",
+			update: func(deployment *appsv1.Deployment) {
+				deployment.Spec.Template.Spec.Containers[0].Image = "registry.example.com/shop/checkout:v2"
+			},
+			expected: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			updated := deployment.DeepCopy()
+			test.update(updated)
+			if changed := indexedFieldsChanged(deployment, updated); changed != test.expected {
+				t.Errorf("expected %v, got %v", test.expected, changed)
+			}
+		})
+	}
+}
+
+func TestSearcher(t *testing.T) {
+	var authorized []string
+	s := &searcher{
+		index: newIndex(),
+		authorizer: authorizer.AuthorizerFunc(func(a authorizer.Attributes) (authorizer.Decision, string, error) {
+			authorized = append(authorized, a.GetVerb()+" "+a.GetResource()+" "+a.GetResourceScope()+" "+a.GetWorkspace()+a.GetNamespace())
+			if a.GetNamespace() == "payment-prod" || a.GetWorkspace() == "payment" {
+				return authorizer.DecisionAllow, "", nil
+			}
+			return authorizer.DecisionNoOpinion, "", nil
+		}),
+	}
+	result, err := s.Search(&user.DefaultInfo{Name: "tester"}, Options{Terms: []string{"payment"}}, &query.Pagination{Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedAuthorized := []string{
+		"get workspaces " + request.WorkspaceScope + " payment",
+		"get workspacetemplates " + request.WorkspaceScope + " payment",
+		"get namespaces " + request.NamespaceScope + " payment-prod",
+		"list deployments " + request.NamespaceScope + " shop",
+		"list pods " + request.NamespaceScope + " payment-prod",
+	}
+	if diff := cmp.Diff(expectedAuthorized, authorized); diff != "" {
+		t.Error(diff)
+	}
+	if result.TotalItems != 4 {
+		t.Errorf("expected 4 objects authorized, got %d", result.TotalItems)
+	}
+	var names []string
+	for _, item := range result.Items {
+		names = append(names, item.Name)
+	}
+	if diff := cmp.Diff([]string{"payment", "payment-prod"}, names); diff != "" {
+		t.Error(diff)
+	}
+
+	// the global resources are authorized in the global scope
+	authorized = nil
+	if _, err = s.Search(&user.DefaultInfo{Name: "tester"}, Options{Terms: []string{"payer"}}, &query.Pagination{Limit: 10}); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]string{"list users " + request.GlobalScope + " "}, authorized); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -20,7 +20,7 @@ func (o DynamicOptions) MarshalJSON() ([]byte, error) {
 }
 
 var (
-	sensitiveKeys = [...]string{"password", "secret"}
+	sensitiveKeys = [...]string{"password", "secret", "privatekey"}
 )
 
 // isSensitiveData returns whether the input string contains sensitive information
@@ -29,6 +29,14 @@ type Interface interface {
 	// Set sets the value and living duration of the given key, zero duration means never expire
 	Set(key string, value string, duration time.Duration) error
 
+	// SetNX sets the value and living duration of the given key only if the key doesn't exist,
+	// it returns whether the value is set. The check and the set are atomic.
+	SetNX(key string, value string, duration time.Duration) (bool, error)
+
+	// Incr increments the counter of the given key and returns the new value, the living duration
+	// is set when the counter is created. The increment and the expiration are atomic.
+	Incr(key string, duration time.Duration) (int64, error)
+
 	// Del deletes the given key, no error returned if the key doesn't exist
 	Del(keys ...string) error
 
@@ -8,6 +8,7 @@ package cache
 
 import (
 	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -111,6 +112,35 @@ func (s *threadSafeStore) Set(key string, obj simpleObject) {
 	s.store[key] = obj
 }
 
+// SetIfAbsent sets the object only if the key doesn't exist or is expired.
+func (s *threadSafeStore) SetIfAbsent(key string, obj simpleObject) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if object, exist := s.store[key]; exist && !object.IsExpired() {
+		return false
+	}
+	s.store[key] = obj
+	return true
+}
+
+// Incr increments the counter of the key, a new counter expires with the object.
+func (s *threadSafeStore) Incr(key string, obj simpleObject) (int64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var count int64
+	if object, exist := s.store[key]; exist && !object.IsExpired() {
+		value, err := strconv.ParseInt(object.value, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		count, obj = value, object
+	}
+	count++
+	obj.value = strconv.FormatInt(count, 10)
+	s.store[key] = obj
+	return count, nil
+}
+
 func (s *threadSafeStore) Keys() []string {
 	var keys []string
 	s.mutex.RLock()
@@ -155,6 +185,23 @@ func (s *inMemoryCache) Set(key string, value string, duration time.Duration) er
 	return nil
 }
 
+func (s *inMemoryCache) SetNX(key string, value string, duration time.Duration) (bool, error) {
+	sobject := simpleObject{
+		value:       value,
+		neverExpire: duration == NeverExpire,
+		expiredAt:   time.Now().Add(duration),
+	}
+	return s.store.SetIfAbsent(key, sobject), nil
+}
+
+func (s *inMemoryCache) Incr(key string, duration time.Duration) (int64, error) {
+	sobject := simpleObject{
+		neverExpire: duration == NeverExpire,
+		expiredAt:   time.Now().Add(duration),
+	}
+	return s.store.Incr(key, sobject)
+}
+
 func (s *inMemoryCache) Del(keys ...string) error {
 	for _, key := range keys {
 		s.store.Delete(key)
@@ -128,3 +128,50 @@ func TestDeleteAndExpireCache(t *testing.T) {
 		})
 	}
 }
+
+func TestSetNX(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	client, err := NewInMemoryCache(nil, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := client.SetNX("foo", "val1", 100*time.Millisecond); err != nil || !ok {
+		t.Fatalf("expected the absent key to be set, got %v %v", ok, err)
+	}
+	if ok, err := client.SetNX("foo", "val2", NeverExpire); err != nil || ok {
+		t.Fatalf("expected the existing key not to be set, got %v %v", ok, err)
+	}
+	if val, _ := client.Get("foo"); val != "val1" {
+		t.Errorf("expected val1, got %s", val)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if ok, err := client.SetNX("foo", "val2", NeverExpire); err != nil || !ok {
+		t.Fatalf("expected the expired key to be set, got %v %v", ok, err)
+	}
+	if val, _ := client.Get("foo"); val != "val2" {
+		t.Errorf("expected val2, got %s", val)
+	}
+}
+
+func TestIncr(t *testing.T) {
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	client, err := NewInMemoryCache(nil, stopCh)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := int64(1); i <= 3; i++ {
+		if count, err := client.Incr("foo", 100*time.Millisecond); err != nil || count != i {
+			t.Fatalf("expected %d, got %d %v", i, count, err)
+		}
+	}
+	// the counter expires with the first increment
+	time.Sleep(200 * time.Millisecond)
+	if count, err := client.Incr("foo", 100*time.Millisecond); err != nil || count != 1 {
+		t.Fatalf("expected the counter to start over, got %d %v", count, err)
+	}
+}
@@ -20,6 +20,14 @@ import (
 
 const typeRedis = "redis"
 
+// incrScript increments the counter and sets the expiration of the new counter in a single step.
+var incrScript = redis.NewScript(`
+local count = redis.call("INCR", KEYS[1])
+if count == 1 and tonumber(ARGV[1]) > 0 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[1])
+end
+return count`)
+
 type redisClient struct {
 	client *redis.Client
 }
@@ -66,7 +74,11 @@ func NewRedisClient(option *redisOptions, stopCh <-chan struct{}) (Interface, er
 }
 
 func (r *redisClient) Get(key string) (string, error) {
-	return r.client.Get(key).Result()
+	value, err := r.client.Get(key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNoSuchKey
+	}
+	return value, err
 }
 
 func (r *redisClient) Keys(pattern string) ([]string, error) {
@@ -77,6 +89,14 @@ func (r *redisClient) Set(key string, value string, duration time.Duration) erro
 	return r.client.Set(key, value, duration).Err()
 }
 
+func (r *redisClient) SetNX(key string, value string, duration time.Duration) (bool, error) {
+	return r.client.SetNX(key, value, duration).Result()
+}
+
+func (r *redisClient) Incr(key string, duration time.Duration) (int64, error) {
+	return incrScript.Run(r.client, []string{key}, duration.Milliseconds()).Int64()
+}
+
 func (r *redisClient) Del(keys ...string) error {
 	return r.client.Del(keys...).Err()
 }
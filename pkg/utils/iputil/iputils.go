@@ -7,8 +7,10 @@
 package iputil
 
 import (
+	"fmt"
 	"net"
 	"net/http"
+	"strings"
 )
 
 const (
@@ -35,3 +37,72 @@ func RemoteIp(req *http.Request) string {
 
 	return remoteAddr
 }
+
+// ParseCIDRs parses the CIDRs or the IP addresses, an IP address is treated as a single host network.
+func ParseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", cidr)
+			}
+			bits := net.IPv6len * 8
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), net.IPv4len*8
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// ClientIP returns the IP address of the client. The X-Forwarded-For header is only honored if the request
+// comes from a trusted proxy, the first untrusted address from right to left is the client. The forwarding
+// headers are never honored if there is no trusted proxy, since they can be set by the clients.
+func ClientIP(req *http.Request, trustedProxies []*net.IPNet) string {
+	remoteAddr, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		remoteAddr = req.RemoteAddr
+	}
+	if !trusted(remoteAddr, trustedProxies) {
+		return remoteAddr
+	}
+	var forwarded []string
+	for _, value := range req.Header.Values(XForwardedFor) {
+		for _, ip := range strings.Split(value, ",") {
+			if ip = strings.TrimSpace(ip); ip != "" {
+				forwarded = append(forwarded, ip)
+			}
+		}
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		if net.ParseIP(forwarded[i]) == nil {
+			break
+		}
+		remoteAddr = forwarded[i]
+		if !trusted(remoteAddr, trustedProxies) {
+			break
+		}
+	}
+	return remoteAddr
+}
+
+func trusted(ip string, networks []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package iputil
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trustedProxies, err := ParseCIDRs([]string{"10.0.0.0/8", "192.168.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name           string
+		remoteAddr     string
+		xForwardedFor  []string
+		xClientIP      string
+		xRealIP        string
+		noTrustedProxy bool
+		expected       string
+	}{
+		{name: "direct", remoteAddr: "1.1.1.1:1234", expected: "1.1.1.1"},
+		{name: "untrusted proxy", remoteAddr: "1.1.1.1:1234", xForwardedFor: []string{"2.2.2.2"}, expected: "1.1.1.1"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.1:1234", xForwardedFor: []string{"2.2.2.2"}, expected: "2.2.2.2"},
+		{name: "spoofed", remoteAddr: "10.0.0.1:1234", xForwardedFor: []string{"3.3.3.3, 2.2.2.2, 192.168.0.1"}, expected: "2.2.2.2"},
+		{name: "multiple headers", remoteAddr: "10.0.0.1:1234", xForwardedFor: []string{"3.3.3.3", "2.2.2.2"}, expected: "2.2.2.2"},
+		{name: "invalid", remoteAddr: "10.0.0.1:1234", xForwardedFor: []string{"2.2.2.2, unknown"}, expected: "10.0.0.1"},
+		{name: "all trusted", remoteAddr: "10.0.0.1:1234", xForwardedFor: []string{"10.0.0.2"}, expected: "10.0.0.2"},
+		{name: "no trusted proxy", remoteAddr: "10.0.0.1:1234", xForwardedFor: []string{"2.2.2.2"}, noTrustedProxy: true, expected: "10.0.0.1"},
+		{name: "no trusted proxy with other headers", remoteAddr: "1.1.1.1:1234", xClientIP: "2.2.2.2", xRealIP: "3.3.3.3", noTrustedProxy: true, expected: "1.1.1.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &http.Request{RemoteAddr: tt.remoteAddr, Header: http.Header{}}
+			for _, value := range tt.xForwardedFor {
+				req.Header.Add(XForwardedFor, value)
+			}
+			if tt.xClientIP != "" {
+				req.Header.Set(XClientIP, tt.xClientIP)
+			}
+			if tt.xRealIP != "" {
+				req.Header.Set(XRealIP, tt.xRealIP)
+			}
+			proxies := trustedProxies
+			if tt.noTrustedProxy {
+				proxies = nil
+			}
+			if got := ClientIP(req, proxies); got != tt.expected {
+				t.Errorf("ClientIP() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
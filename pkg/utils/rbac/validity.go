@@ -0,0 +1,93 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package rbac
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+)
+
+// BindingValidity is the validity period of a time-bound role binding, either end of which is unbounded if not set.
+type BindingValidity struct {
+	NotBefore *time.Time
+	ExpiresAt *time.Time
+}
+
+// GetBindingValidity parses the validity period of the role binding from its annotations.
+func GetBindingValidity(binding metav1.Object) (BindingValidity, error) {
+	validity := BindingValidity{}
+	annotations := binding.GetAnnotations()
+	if value := annotations[iamv1beta1.BindingNotBeforeAnnotation]; value != "" {
+		notBefore, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return validity, fmt.Errorf("invalid annotation %s: %s", iamv1beta1.BindingNotBeforeAnnotation, err)
+		}
+		validity.NotBefore = &notBefore
+	}
+	if value := annotations[iamv1beta1.BindingExpiresAtAnnotation]; value != "" {
+		expiresAt, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return validity, fmt.Errorf("invalid annotation %s: %s", iamv1beta1.BindingExpiresAtAnnotation, err)
+		}
+		validity.ExpiresAt = &expiresAt
+	}
+	return validity, nil
+}
+
+// SetBindingValidity records the validity period in the annotations of the role binding.
+func SetBindingValidity(binding metav1.Object, validity BindingValidity) {
+	annotations := binding.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	delete(annotations, iamv1beta1.BindingNotBeforeAnnotation)
+	delete(annotations, iamv1beta1.BindingExpiresAtAnnotation)
+	if validity.NotBefore != nil {
+		annotations[iamv1beta1.BindingNotBeforeAnnotation] = validity.NotBefore.UTC().Format(time.RFC3339)
+	}
+	if validity.ExpiresAt != nil {
+		annotations[iamv1beta1.BindingExpiresAtAnnotation] = validity.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	binding.SetAnnotations(annotations)
+}
+
+// Active returns true if the time is in the validity period.
+func (v BindingValidity) Active(now time.Time) bool {
+	if v.NotBefore != nil && now.Before(*v.NotBefore) {
+		return false
+	}
+	return !v.Expired(now)
+}
+
+// Expired returns true if the validity period has ended at the time.
+func (v BindingValidity) Expired(now time.Time) bool {
+	return v.ExpiresAt != nil && !now.Before(*v.ExpiresAt)
+}
+
+// NextTransition returns the duration until the binding becomes active or expires, zero means no more transitions.
+func (v BindingValidity) NextTransition(now time.Time) time.Duration {
+	if v.NotBefore != nil && now.Before(*v.NotBefore) {
+		return v.NotBefore.Sub(now)
+	}
+	if v.ExpiresAt != nil && now.Before(*v.ExpiresAt) {
+		return v.ExpiresAt.Sub(now)
+	}
+	return 0
+}
+
+// IsBindingActive returns true if the role binding is in its validity period,
+// the binding with an invalid validity period never takes effect.
+func IsBindingActive(binding metav1.Object, now time.Time) bool {
+	validity, err := GetBindingValidity(binding)
+	if err != nil {
+		return false
+	}
+	return validity.Active(now)
+}
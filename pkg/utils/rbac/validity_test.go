@@ -0,0 +1,82 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package rbac
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	iamv1beta1 "kubesphere.io/api/iam/v1beta1"
+)
+
+func TestBindingValidity(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		annotations    map[string]string
+		active         bool
+		expired        bool
+		nextTransition time.Duration
+		invalid        bool
+	}{
+		{
+			name:   "permanent",
+			active: true,
+		},
+		{
+			name:           "not in effect yet",
+			annotations:    map[string]string{iamv1beta1.BindingNotBeforeAnnotation: "2024-06-01T13:00:00Z", iamv1beta1.BindingExpiresAtAnnotation: "2024-06-01T14:00:00Z"},
+			nextTransition: time.Hour,
+		},
+		{
+			name:           "in effect",
+			annotations:    map[string]string{iamv1beta1.BindingNotBeforeAnnotation: "2024-06-01T11:00:00Z", iamv1beta1.BindingExpiresAtAnnotation: "2024-06-01T12:30:00Z"},
+			active:         true,
+			nextTransition: 30 * time.Minute,
+		},
+		{
+			name:        "expired",
+			annotations: map[string]string{iamv1beta1.BindingExpiresAtAnnotation: "2024-06-01T12:00:00Z"},
+			expired:     true,
+		},
+		{
+			name:        "invalid",
+			annotations: map[string]string{iamv1beta1.BindingExpiresAtAnnotation: "tomorrow"},
+			invalid:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			binding := &iamv1beta1.GlobalRoleBinding{ObjectMeta: metav1.ObjectMeta{Annotations: tt.annotations}}
+			validity, err := GetBindingValidity(binding)
+			if (err != nil) != tt.invalid {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if active := IsBindingActive(binding, now); active != tt.active {
+				t.Errorf("expected active %v, got %v", tt.active, active)
+			}
+			if tt.invalid {
+				return
+			}
+			if expired := validity.Expired(now); expired != tt.expired {
+				t.Errorf("expected expired %v, got %v", tt.expired, expired)
+			}
+			if next := validity.NextTransition(now); next != tt.nextTransition {
+				t.Errorf("expected next transition in %v, got %v", tt.nextTransition, next)
+			}
+
+			// the validity period is recorded as is
+			copied := &iamv1beta1.GlobalRoleBinding{}
+			SetBindingValidity(copied, validity)
+			if copied.Annotations[iamv1beta1.BindingNotBeforeAnnotation] != tt.annotations[iamv1beta1.BindingNotBeforeAnnotation] ||
+				copied.Annotations[iamv1beta1.BindingExpiresAtAnnotation] != tt.annotations[iamv1beta1.BindingExpiresAtAnnotation] {
+				t.Errorf("expected annotations %v, got %v", tt.annotations, copied.Annotations)
+			}
+		})
+	}
+}
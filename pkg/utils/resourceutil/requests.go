@@ -0,0 +1,80 @@
+/*
+ * Copyright 2024 the KubeSphere Authors.
+ * Please refer to the LICENSE file in the root directory of the project.
+ * https://github.com/kubesphere/kubesphere/blob/master/LICENSE
+ */
+
+package resourceutil
+
+import (
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// PodRequests returns the effective resource requests of the pod as the scheduler computes, which is
+// the larger one of the sum of the requests of the containers and the requests of any init container,
+// plus the pod overhead.
+func PodRequests(spec *corev1.PodSpec) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	for _, container := range spec.Containers {
+		AddResourceList(requests, container.Resources.Requests)
+	}
+	for _, container := range spec.InitContainers {
+		for name, quantity := range container.Resources.Requests {
+			if value, ok := requests[name]; !ok || quantity.Cmp(value) > 0 {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	AddResourceList(requests, spec.Overhead)
+	return requests
+}
+
+// ObjectRequests returns the resource requests of the pods the object runs, the workloads request
+// the resources of their pod templates multiplied by the replicas. It returns nil if the object runs no pods.
+func ObjectRequests(object runtime.Object) corev1.ResourceList {
+	switch obj := object.(type) {
+	case *corev1.Pod:
+		return PodRequests(&obj.Spec)
+	case *appsv1.Deployment:
+		return replicaRequests(&obj.Spec.Template.Spec, obj.Spec.Replicas)
+	case *appsv1.StatefulSet:
+		return replicaRequests(&obj.Spec.Template.Spec, obj.Spec.Replicas)
+	case *appsv1.ReplicaSet:
+		return replicaRequests(&obj.Spec.Template.Spec, obj.Spec.Replicas)
+	case *appsv1.DaemonSet:
+		return replicaRequests(&obj.Spec.Template.Spec, &obj.Status.DesiredNumberScheduled)
+	case *batchv1.Job:
+		return replicaRequests(&obj.Spec.Template.Spec, obj.Spec.Parallelism)
+	default:
+		return nil
+	}
+}
+
+// replicaRequests returns the requests of the pods of the template, the replicas defaults to 1 if not set.
+func replicaRequests(spec *corev1.PodSpec, replicas *int32) corev1.ResourceList {
+	count := int64(1)
+	if replicas != nil {
+		count = int64(*replicas)
+	}
+	requests := PodRequests(spec)
+	for name, quantity := range requests {
+		requests[name] = *resource.NewMilliQuantity(quantity.MilliValue()*count, quantity.Format)
+	}
+	return requests
+}
+
+// AddResourceList adds the resources in the new list into the list.
+func AddResourceList(list, newList corev1.ResourceList) {
+	for name, quantity := range newList {
+		if value, ok := list[name]; !ok {
+			list[name] = quantity.DeepCopy()
+		} else {
+			value.Add(quantity)
+			list[name] = value
+		}
+	}
+}
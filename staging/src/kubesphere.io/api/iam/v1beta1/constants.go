@@ -47,6 +47,9 @@ const (
 	ResourcesKindCategory                 = "Category"
 	ResourcesSingularCategory             = "category"
 	ResourcesPluralCategories             = "categories"
+	ResourceKindAccessRequest             = "AccessRequest"
+	ResourcesSingularAccessRequest        = "accessrequest"
+	ResourcesPluralAccessRequest          = "accessrequests"
 	RegoOverrideAnnotation                = "iam.kubesphere.io/rego-override"
 	AggregationRolesAnnotation            = "iam.kubesphere.io/aggregation-roles"
 	GlobalRoleAnnotation                  = "iam.kubesphere.io/globalrole"
@@ -55,13 +58,25 @@ const (
 	GrantedClustersAnnotation             = "iam.kubesphere.io/granted-clusters"
 	UninitializedAnnotation               = "iam.kubesphere.io/uninitialized"
 	LastPasswordChangeTimeAnnotation      = "iam.kubesphere.io/last-password-change-time"
+	PasswordResetRequiredAnnotation       = "iam.kubesphere.io/password-reset-required"
+	TOTPAuthKeyRefAnnotation              = "iam.kubesphere.io/totp-auth-key-ref"
+	MFARequiredAnnotation                 = "iam.kubesphere.io/mfa-required"
+	SigningKeyRetiredAtAnnotation         = "iam.kubesphere.io/signing-key-retired-at"
+	SigningKeyActivatedAtAnnotation       = "iam.kubesphere.io/signing-key-activated-at"
+	LegacyTokenDeadlineAnnotation         = "iam.kubesphere.io/legacy-token-deadline"
 	RoleAnnotation                        = "iam.kubesphere.io/role"
 	RoleTemplateLabel                     = "iam.kubesphere.io/role-template"
 	ScopeLabel                            = "iam.kubesphere.io/scope"
 	UserReferenceLabel                    = "iam.kubesphere.io/user-ref"
 	RoleReferenceLabel                    = "iam.kubesphere.io/role-ref"
 	IdentityProviderAnnotation            = "iam.kubesphere.io/identity-provider"
+	IdentityProviderLabel                 = "iam.kubesphere.io/identity-provider"
+	ExternalGroupAnnotation               = "iam.kubesphere.io/external-group"
+	SCIMExternalIDAnnotation              = "iam.kubesphere.io/scim-external-id"
 	ServiceAccountReferenceLabel          = "iam.kubesphere.io/serviceaccount-ref"
+	AccessRequestReferenceLabel           = "iam.kubesphere.io/accessrequest-ref"
+	BindingNotBeforeAnnotation            = "iam.kubesphere.io/not-before"
+	BindingExpiresAtAnnotation            = "iam.kubesphere.io/expires-at"
 	FieldEmail                            = "email"
 	ExtraEmail                            = FieldEmail
 	ExtraIdentityProvider                 = "idp"
@@ -69,6 +84,11 @@ const (
 	ExtraUsername                         = "username"
 	ExtraDisplayName                      = "displayName"
 	ExtraUninitialized                    = "uninitialized"
+	ExtraMFAEnrollmentRequired            = "mfaEnrollmentRequired"
+	ExtraPasswordResetRequired            = "passwordResetRequired"
+	ExtraAuthenticationMethods            = "amr"
+	ExtraWorkspaceScope                   = "workspaceScope"
+	AuthenticationMethodOTP               = "otp"
 	InGroup                               = "ingroup"
 	NotInGroup                            = "notingroup"
 	AggregateTo                           = "aggregateTo"
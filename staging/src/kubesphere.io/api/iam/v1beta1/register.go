@@ -57,6 +57,8 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 		&GroupBindingList{},
 		&LoginRecord{},
 		&LoginRecordList{},
+		&AccessRequest{},
+		&AccessRequestList{},
 	)
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
 	return nil
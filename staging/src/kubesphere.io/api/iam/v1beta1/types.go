@@ -102,6 +102,9 @@ const (
 	UserDisabled UserState = "Disabled"
 	// UserAuthLimitExceeded means restrict user login.
 	UserAuthLimitExceeded UserState = "AuthLimitExceeded"
+	// UserPasswordResetRequired means the user must change the password before accessing other resources,
+	// because the password is expired or an administrator requires so.
+	UserPasswordResetRequired UserState = "PasswordResetRequired"
 
 	AuthenticatedSuccessfully = "authenticated successfully"
 )
@@ -425,6 +428,70 @@ type SubjectAccessReviewStatus struct {
 	EvaluationError string `json:"evaluationError,omitempty" protobuf:"bytes,3,opt,name=evaluationError"`
 }
 
+// +kubebuilder:object:root=true
+
+// ResourceAccessReview checks which users, groups and service accounts can perform an action,
+// it is the inverse of SubjectAccessReview.
+// NOTE: This type does not require crd, so we omit the metav1.ObjectMeta
+type ResourceAccessReview struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Spec holds information about the action being evaluated
+	Spec ResourceAccessReviewSpec `json:"spec"`
+
+	// Status is filled in by the server and lists the subjects allowed to perform the action
+	// +optional
+	Status ResourceAccessReviewStatus `json:"status,omitempty"`
+}
+
+// ResourceAccessReviewSpec is a description of the action. Exactly one of ResourceAttributes
+// and NonResourceAttributes must be set
+type ResourceAccessReviewSpec struct {
+	// ResourceAttributes describes information for a resource access request
+	// +optional
+	ResourceAttributes *ResourceAttributes `json:"resourceAttributes,omitempty"`
+	// NonResourceAttributes describes information for a non-resource access request
+	// +optional
+	NonResourceAttributes *NonResourceAttributes `json:"nonResourceAttributes,omitempty"`
+}
+
+// ResourceAccessReviewStatus lists the subjects allowed to perform the action
+type ResourceAccessReviewStatus struct {
+	// Users are the names of the users allowed to perform the action
+	// +optional
+	Users []string `json:"users,omitempty"`
+	// Groups are the names of the groups allowed to perform the action
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+	// ServiceAccounts are the service accounts allowed to perform the action, in the form of namespace/name
+	// +optional
+	ServiceAccounts []string `json:"serviceAccounts,omitempty"`
+	// Grants describe through which bindings and roles the subjects are allowed to perform the action
+	// +optional
+	Grants []AccessGrant `json:"grants,omitempty"`
+	// EvaluationError is an indication that some error occurred during the review, the subjects
+	// granted by the bindings failed to resolve are not listed.
+	// +optional
+	EvaluationError string `json:"evaluationError,omitempty"`
+}
+
+// AccessGrant describes a binding which allows its subject to perform an action
+type AccessGrant struct {
+	Subject rbacv1.Subject `json:"subject"`
+	// BindingKind is one of GlobalRoleBinding, WorkspaceRoleBinding, RoleBinding and ClusterRoleBinding
+	BindingKind string `json:"bindingKind"`
+	BindingName string `json:"bindingName"`
+	// +optional
+	BindingNamespace string         `json:"bindingNamespace,omitempty"`
+	RoleRef          rbacv1.RoleRef `json:"roleRef"`
+	// RegoPolicy indicates the action is allowed by the rego policy of the role rather than the rules
+	// +optional
+	RegoPolicy bool `json:"regoPolicy,omitempty"`
+	// RoleTemplates are the aggregated role templates of the role which allow the action
+	// +optional
+	RoleTemplates []string `json:"roleTemplates,omitempty"`
+}
+
 // +kubebuilder:object:root=true
 // +kubebuilder:resource:categories=iam,scope=Cluster
 // +kubebuilder:storageversion
@@ -672,3 +739,88 @@ type RoleTemplateList struct {
 	metav1.ListMeta `json:"metadata,omitempty"`
 	Items           []RoleTemplate `json:"items"`
 }
+
+// +kubebuilder:object:root=true
+// +kubebuilder:printcolumn:name="User",type="string",JSONPath=".spec.username"
+// +kubebuilder:printcolumn:name="Role",type="string",JSONPath=".spec.roleRef.name"
+// +kubebuilder:printcolumn:name="Duration",type="string",JSONPath=".spec.duration"
+// +kubebuilder:printcolumn:name="State",type="string",JSONPath=".status.state"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+// +kubebuilder:resource:categories=iam,scope=Cluster
+// +kubebuilder:storageversion
+
+// AccessRequest is the request of a user for a role for a limited duration,
+// a time-bound role binding is created for the user once the request is approved.
+type AccessRequest struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec AccessRequestSpec `json:"spec"`
+	// +optional
+	Status AccessRequestStatus `json:"status,omitempty"`
+}
+
+// AccessRequestSpec defines the requested access
+type AccessRequestSpec struct {
+	// Username is the user who requests the access.
+	Username string `json:"username"`
+	// RoleRef references the requested role, which can be a GlobalRole, a WorkspaceRole, a ClusterRole or a Role.
+	RoleRef rbacv1.RoleRef `json:"roleRef"`
+	// Workspace is the workspace of the requested WorkspaceRole.
+	// +optional
+	Workspace string `json:"workspace,omitempty"`
+	// Namespace is the namespace of the requested Role.
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+	// Duration is how long the access lasts once approved.
+	Duration metav1.Duration `json:"duration"`
+	// Justification explains why the access is required.
+	Justification string `json:"justification"`
+}
+
+type AccessRequestState string
+
+// These are the valid states of an access request.
+const (
+	// AccessRequestPending means the request is waiting for review.
+	AccessRequestPending AccessRequestState = "Pending"
+	// AccessRequestApproved means the access is granted until the request expires.
+	AccessRequestApproved AccessRequestState = "Approved"
+	// AccessRequestDenied means the request is rejected by the reviewer.
+	AccessRequestDenied AccessRequestState = "Denied"
+	// AccessRequestExpired means the access granted has expired.
+	AccessRequestExpired AccessRequestState = "Expired"
+)
+
+// AccessRequestStatus defines the observed state of AccessRequest
+type AccessRequestStatus struct {
+	// +optional
+	State AccessRequestState `json:"state,omitempty"`
+	// Reviewer is the user who approved or denied the request.
+	// +optional
+	Reviewer string `json:"reviewer,omitempty"`
+	// +optional
+	ReviewTime *metav1.Time `json:"reviewTime,omitempty"`
+	// Comment of the reviewer.
+	// +optional
+	Comment string `json:"comment,omitempty"`
+	// NotBefore is when the access granted starts.
+	// +optional
+	NotBefore *metav1.Time `json:"notBefore,omitempty"`
+	// ExpiresAt is when the access granted ends.
+	// +optional
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// BindingName is the name of the role binding created for the request.
+	// +optional
+	BindingName string `json:"bindingName,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:categories=iam,scope=Cluster
+
+// AccessRequestList contains a list of AccessRequest
+type AccessRequestList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []AccessRequest `json:"items"`
+}
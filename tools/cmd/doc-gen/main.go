@@ -36,6 +36,7 @@ import (
 	packagev1alpha1 "kubesphere.io/kubesphere/pkg/kapis/package/v1alpha1"
 	resourcesv1alpha2 "kubesphere.io/kubesphere/pkg/kapis/resources/v1alpha2"
 	resourcesv1alpha3 "kubesphere.io/kubesphere/pkg/kapis/resources/v1alpha3"
+	"kubesphere.io/kubesphere/pkg/kapis/search"
 	"kubesphere.io/kubesphere/pkg/kapis/static"
 	tenantv1alpha3 "kubesphere.io/kubesphere/pkg/kapis/tenant/v1alpha3"
 	tenantv1beta1 "kubesphere.io/kubesphere/pkg/kapis/tenant/v1beta1"
@@ -106,6 +107,7 @@ func generateSwaggerJson() []byte {
 		tenantv1alpha3.NewFakeHandler(),
 		appv2.NewFakeHandler(),
 		static.NewFakeHandler(),
+		search.NewFakeHandler(),
 	}
 
 	for _, handler := range handlers {